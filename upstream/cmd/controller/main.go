@@ -18,8 +18,15 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log"
+	"os"
+	"time"
+	// Embedded so that --display-timezone works in images without tzdata
+	_ "time/tzdata"
 
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/callback"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/labelcap"
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/reconciler/approvaltask"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/utils/clock"
@@ -39,13 +46,64 @@ const (
 func main() {
 	fmt.Println(features)
 	namespace := flag.String("namespace", corev1.NamespaceAll, "Namespace to restrict informer to. Optional, defaults to all namespaces.")
+	callbackURL := flag.String("callback-url", "", "URL notified with a signed POST when an ApprovalTask reaches a final state. Optional.")
+	callbackRetries := flag.Int("callback-retries", 5, "Number of delivery attempts for the final state callback.")
+	maxRequeueInterval := flag.Duration("max-requeue-interval", 0, "Upper bound on how long a pending ApprovalTask waits before being re-evaluated. Optional, defaults to waiting for the next time event.")
+	rejectInconsistentResponses := flag.Bool("reject-inconsistent-responses", false, "Reject ApprovalTasks whose status records responses that do not match their approvers, instead of repairing the status.")
+	adminAddress := flag.String("admin-address", "", "Address to serve the admin endpoints on over TLS, for example \":8081\". Optional, disabled when empty.")
+	adminTLSCertFile := flag.String("admin-tls-cert-file", "", "PEM encoded certificate the admin endpoints are served with. Required with --admin-address.")
+	adminTLSKeyFile := flag.String("admin-tls-key-file", "", "PEM encoded private key the admin endpoints are served with. Required with --admin-address.")
+	recomputeQPS := flag.Float64("recompute-qps", approvaltask.DefaultRecomputeQPS, "Number of pending ApprovalTasks a recompute enqueues per second.")
+	cleanupOnDelete := flag.Bool("cleanup-on-delete", false, "Notify the callback URL when an ApprovalTask is deleted, holding the deletion with a finalizer until the notification succeeds.")
+	cleanupTimeout := flag.Duration("cleanup-timeout", approvaltask.DefaultCleanupTimeout, "How long after its deletion the cleanup of an ApprovalTask is retried before its finalizer is removed anyway.")
+	carryForwardApprovals := flag.Bool("carry-forward-approvals", false, "Start ApprovalTasks with the approvals of the prior ApprovalTask carrying the same approval identity label, if both have the same approvers.")
+	defaultApprovalsRequired := flag.Int("default-approvals-required", 0, "Number of approvals required written into pending ApprovalTasks that set none. Optional, defaults to requiring every eligible approver.")
+	approversFromPipelineRun := flag.Bool("approvers-from-pipelinerun", false, "Watch PipelineRuns so that ApprovalTasks can take their approvers from the PipelineRun they are part of.")
+	metricsLabelCap := flag.Int("metrics-label-cap", labelcap.DefaultLimit, "Number of distinct namespaces the decision duration metric is labelled with. Decisions in further namespaces are recorded without the label.")
+	displayTimezone := flag.String("display-timezone", "", "IANA timezone, for example \"Europe/Berlin\", to render the key timestamps of the ApprovalTask status in. Optional.")
 
 	// This parses flags.
 	cfg := injection.ParseAndGetRESTConfigOrDie()
 
 	ctx := injection.WithNamespaceScope(signals.NewContext(), *namespace)
 	ctx = filteredinformerfactory.WithSelectors(ctx, v1alpha1.ManagedByLabelKey)
+	opts := approvaltask.Options{
+		MaxRequeueInterval:          *maxRequeueInterval,
+		RejectInconsistentResponses: *rejectInconsistentResponses,
+		AdminAddress:                *adminAddress,
+		AdminTLSCertFile:            *adminTLSCertFile,
+		AdminTLSKeyFile:             *adminTLSKeyFile,
+		RecomputeQPS:                *recomputeQPS,
+		CleanupTimeout:              *cleanupTimeout,
+		CarryForwardApprovals:       *carryForwardApprovals,
+		DefaultApprovalsRequired:    *defaultApprovalsRequired,
+		ApproversFromPipelineRun:    *approversFromPipelineRun,
+		MetricsLabelCap:             *metricsLabelCap,
+	}
+	if *adminAddress != "" && (*adminTLSCertFile == "" || *adminTLSKeyFile == "") {
+		log.Fatalf("--admin-address requires --admin-tls-cert-file and --admin-tls-key-file")
+	}
+	if *displayTimezone != "" {
+		location, err := time.LoadLocation(*displayTimezone)
+		if err != nil {
+			log.Fatalf("invalid display timezone %q: %v", *displayTimezone, err)
+		}
+		opts.DisplayLocation = location
+	}
+	if *callbackURL != "" {
+		opts.Callback = &callback.Notifier{
+			URL:     *callbackURL,
+			Secret:  []byte(os.Getenv("CALLBACK_HMAC_SECRET")),
+			Retries: *callbackRetries,
+		}
+		if *cleanupOnDelete {
+			opts.CleanupHook = opts.Callback
+		}
+	}
+
 	sharedmain.MainWithConfig(ctx, ControllerLogKey, cfg,
-		approvaltask.NewController(clock.RealClock{}),
+		approvaltask.NewController(clock.RealClock{}, opts),
+		approvaltask.NewFinalizerController(clock.RealClock{}, opts),
+		approvaltask.NewTTLController(clock.RealClock{}),
 	)
 }
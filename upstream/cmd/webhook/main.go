@@ -2,9 +2,20 @@ package main
 
 import (
 	"context"
+	"log"
 	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1beta1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/receipt"
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/reconciler/webhook"
+	userv1typedclient "github.com/openshift/client-go/user/clientset/versioned/typed/user/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/injection"
@@ -12,9 +23,10 @@ import (
 	"knative.dev/pkg/signals"
 	kwebhook "knative.dev/pkg/webhook"
 	"knative.dev/pkg/webhook/certificates"
+	"knative.dev/pkg/webhook/resourcesemantics/conversion"
 )
 
-func newValidationAdmissionController(name string) func(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+func newValidationAdmissionController(name string, opts webhook.Options) func(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
 	return func(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
 		return webhook.NewAdmissionController(ctx,
 			name,
@@ -23,10 +35,30 @@ func newValidationAdmissionController(name string) func(ctx context.Context, cmw
 				return ctx
 			},
 			true,
+			opts,
 		)
 	}
 }
 
+func newConversionController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+	return conversion.NewConversionController(ctx,
+		"/resource-conversion",
+		map[schema.GroupKind]conversion.GroupKindConversion{
+			v1alpha1.Kind("ApprovalTask"): {
+				DefinitionName: "approvaltasks.openshift-pipelines.org",
+				HubVersion:     v1alpha1.SchemeGroupVersion.Version,
+				Zygotes: map[string]conversion.ConvertibleObject{
+					v1alpha1.SchemeGroupVersion.Version: &v1alpha1.ApprovalTask{},
+					v1beta1.SchemeGroupVersion.Version:  &v1beta1.ApprovalTask{},
+				},
+			},
+		},
+		func(ctx context.Context) context.Context {
+			return ctx
+		},
+	)
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -35,15 +67,154 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return value
 }
 
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvBoolOrDefault(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvListOrDefault(key string, defaultValue []string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
+
 func main() {
 	serviceName := getEnvOrDefault("WEBHOOK_SERVICE_NAME", "manual-approval-webhook")
 	secretName := getEnvOrDefault("WEBHOOK_SECRET_NAME", "manual-approval-gate-webhook-certs")
 	webhookName := getEnvOrDefault("WEBHOOK_ADMISSION_CONTROLLER_NAME", "validation.webhook.manual-approval.openshift-pipelines.org")
 
+	opts := webhook.Options{
+		CoalesceWindow:                getEnvDurationOrDefault("WEBHOOK_RECONCILE_COALESCE_WINDOW", webhook.DefaultCoalesceWindow),
+		RateLimiterQPS:                float64(getEnvIntOrDefault("WEBHOOK_RECONCILE_QPS", webhook.DefaultRateLimiterQPS)),
+		RateLimiterBurst:              getEnvIntOrDefault("WEBHOOK_RECONCILE_BURST", webhook.DefaultRateLimiterBurst),
+		PrivilegedGroup:               getEnvOrDefault("WEBHOOK_PRIVILEGED_GROUP", webhook.DefaultPrivilegedGroup),
+		EligibilityAddress:            os.Getenv("WEBHOOK_ELIGIBILITY_ADDRESS"),
+		CABundleKey:                   os.Getenv("WEBHOOK_CA_BUNDLE_KEY"),
+		EnforceApprovalOrder:          getEnvBoolOrDefault("WEBHOOK_ENFORCE_APPROVAL_ORDER", false),
+		AllowFinalMetadataUpdates:     getEnvBoolOrDefault("WEBHOOK_ALLOW_FINAL_METADATA_UPDATES", false),
+		MaxApprovers:                  getEnvIntOrDefault("WEBHOOK_MAX_APPROVERS", 0),
+		RuleAPIGroups:                 getEnvListOrDefault("WEBHOOK_RULE_API_GROUPS", nil),
+		RuleAPIVersions:               getEnvListOrDefault("WEBHOOK_RULE_API_VERSIONS", nil),
+		RuleResources:                 getEnvListOrDefault("WEBHOOK_RULE_RESOURCES", nil),
+		RuleSubresources:              getEnvListOrDefault("WEBHOOK_RULE_SUBRESOURCES", nil),
+		RuleScope:                     os.Getenv("WEBHOOK_RULE_SCOPE"),
+		RequiredExtraClaim:            os.Getenv("WEBHOOK_REQUIRED_EXTRA_CLAIM"),
+		RequiredExtraClaimValue:       os.Getenv("WEBHOOK_REQUIRED_EXTRA_CLAIM_VALUE"),
+		MaxTokenAge:                   getEnvDurationOrDefault("WEBHOOK_MAX_TOKEN_AGE", 0),
+		TokenIssuedAtClaim:            getEnvOrDefault("WEBHOOK_TOKEN_ISSUED_AT_CLAIM", webhook.DefaultTokenIssuedAtClaim),
+		SourceIPClaim:                 getEnvOrDefault("WEBHOOK_SOURCE_IP_CLAIM", webhook.DefaultSourceIPClaim),
+		AllowedImpersonations:         getEnvListOrDefault("WEBHOOK_ALLOWED_IMPERSONATIONS", nil),
+		ImpersonatorClaim:             getEnvOrDefault("WEBHOOK_IMPERSONATOR_CLAIM", webhook.DefaultImpersonatorClaim),
+		MetricsLabelCap:               getEnvIntOrDefault("WEBHOOK_METRICS_LABEL_CAP", webhook.DefaultMetricsLabelCap),
+		MetricsTaskNames:              getEnvBoolOrDefault("WEBHOOK_METRICS_TASK_NAMES", false),
+		RequireCurrentResourceVersion: getEnvBoolOrDefault("WEBHOOK_REQUIRE_CURRENT_RESOURCE_VERSION", false),
+		ExplicitGroupMembership:       getEnvBoolOrDefault("WEBHOOK_EXPLICIT_GROUP_MEMBERSHIP", false),
+		GroupHierarchySeparator:       os.Getenv("WEBHOOK_GROUP_HIERARCHY_SEPARATOR"),
+		ForbidGroupSelfAdd:            getEnvBoolOrDefault("WEBHOOK_FORBID_GROUP_SELF_ADD", false),
+		UnknownApproverTypes:          getEnvOrDefault("WEBHOOK_UNKNOWN_APPROVER_TYPES", webhook.UnknownApproverTypesDeny),
+		Blocklist:                     getEnvListOrDefault("WEBHOOK_BLOCKLIST", nil),
+		UsernameRedaction:             getEnvOrDefault("WEBHOOK_USERNAME_REDACTION", webhook.UsernameRedactionOff),
+		ChecksAnnotation:              getEnvOrDefault("WEBHOOK_CHECKS_ANNOTATION", v1alpha1.ChecksStatusAnnotationKey),
+		ChecksGating:                  getEnvOrDefault("WEBHOOK_CHECKS_GATING", webhook.ChecksGatingFailing),
+		CIUsers:                       getEnvListOrDefault("WEBHOOK_CI_USERS", nil),
+		CIGroups:                      getEnvListOrDefault("WEBHOOK_CI_GROUPS", nil),
+		RequireChangeTicket:           getEnvBoolOrDefault("WEBHOOK_REQUIRE_CHANGE_TICKET", false),
+		QuarantineThreshold:           getEnvIntOrDefault("WEBHOOK_QUARANTINE_THRESHOLD", 0),
+		QuarantineDuration:            getEnvDurationOrDefault("WEBHOOK_QUARANTINE_DURATION", webhook.DefaultQuarantineDuration),
+		QuarantineHalfLife:            getEnvDurationOrDefault("WEBHOOK_QUARANTINE_HALF_LIFE", webhook.DefaultQuarantineHalfLife),
+		QuarantineStatusAnnotation:    getEnvBoolOrDefault("WEBHOOK_QUARANTINE_STATUS_ANNOTATION", false),
+		SignDecisions:                 getEnvBoolOrDefault("WEBHOOK_SIGN_DECISIONS", false),
+		DrainTimeout:                  getEnvDurationOrDefault("WEBHOOK_DRAIN_TIMEOUT", webhook.DefaultDrainTimeout),
+		MaxConcurrentResolutions:      getEnvIntOrDefault("WEBHOOK_MAX_CONCURRENT_RESOLUTIONS", webhook.DefaultMaxConcurrentResolutions),
+		InstanceName:                  os.Getenv("POD_NAME"),
+		InstanceNamespace:             os.Getenv("POD_NAMESPACE"),
+	}
+	if !slices.Contains(webhook.UnknownApproverTypeModes, opts.UnknownApproverTypes) {
+		log.Fatalf("invalid WEBHOOK_UNKNOWN_APPROVER_TYPES %q, must be one of %v", opts.UnknownApproverTypes, webhook.UnknownApproverTypeModes)
+	}
+	if !slices.Contains(webhook.ChecksGatings, opts.ChecksGating) {
+		log.Fatalf("invalid WEBHOOK_CHECKS_GATING %q, must be one of %v", opts.ChecksGating, webhook.ChecksGatings)
+	}
+	for _, allowed := range opts.AllowedImpersonations {
+		if strings.HasPrefix(allowed, "group:") {
+			log.Fatalf("invalid WEBHOOK_ALLOWED_IMPERSONATIONS entry %q: only users can be permitted, not groups", allowed)
+		}
+	}
+	changeTicketPattern, err := regexp.Compile(getEnvOrDefault("WEBHOOK_CHANGE_TICKET_PATTERN", webhook.DefaultChangeTicketPattern))
+	if err != nil {
+		log.Fatalf("invalid WEBHOOK_CHANGE_TICKET_PATTERN: %v", err)
+	}
+	opts.ChangeTicketPattern = changeTicketPattern
+	sourceRanges, err := webhook.ParseSourceRanges(getEnvListOrDefault("WEBHOOK_ALLOWED_SOURCE_RANGES", nil))
+	if err != nil {
+		log.Fatalf("invalid WEBHOOK_ALLOWED_SOURCE_RANGES: %v", err)
+	}
+	opts.AllowedSourceRanges = sourceRanges
+	if !slices.Contains(webhook.UsernameRedactions, opts.UsernameRedaction) {
+		log.Fatalf("invalid WEBHOOK_USERNAME_REDACTION %q, must be one of %v", opts.UsernameRedaction, webhook.UsernameRedactions)
+	}
+	if opts.UsernameRedaction == webhook.UsernameRedactionHash {
+		keyFile := os.Getenv("WEBHOOK_USERNAME_REDACTION_KEY_FILE")
+		if keyFile == "" {
+			log.Fatalf("WEBHOOK_USERNAME_REDACTION_KEY_FILE is required to hash usernames")
+		}
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			log.Fatalf("failed to read the username redaction key: %v", err)
+		}
+		opts.UsernameRedactionKey = key
+	}
+	if keyFile := os.Getenv("WEBHOOK_RECEIPT_KEY_FILE"); keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			log.Fatalf("failed to read the receipt signing key: %v", err)
+		}
+		if opts.ReceiptKey, err = receipt.ParsePrivateKey(data); err != nil {
+			log.Fatalf("failed to parse the receipt signing key: %v", err)
+		}
+	}
+
+	cfg := injection.ParseAndGetRESTConfigOrDie()
 	systemNamespace := os.Getenv("SYSTEM_NAMESPACE")
+	opts.ControllerUsername = getEnvOrDefault("WEBHOOK_CONTROLLER_USERNAME",
+		"system:serviceaccount:"+systemNamespace+":"+webhook.DefaultControllerServiceAccount)
 	// Scope informers to the webhook's namespace instead of cluster-wide
 	ctx := injection.WithNamespaceScope(signals.NewContext(), systemNamespace)
 
+	if getEnvBoolOrDefault("WEBHOOK_RESOLVE_OPENSHIFT_GROUPS", false) {
+		userClient, err := userv1typedclient.NewForConfig(cfg)
+		if err != nil {
+			log.Fatalf("failed to create the OpenShift user client: %v", err)
+		}
+		opts.GroupResolver = webhook.NewOpenShiftGroupResolver(ctx, userClient)
+	}
+
 	// Set up a signal context with our webhook options
 	ctx = kwebhook.WithOptions(ctx, kwebhook.Options{
 		ServiceName: serviceName,
@@ -51,9 +222,18 @@ func main() {
 		SecretName:  secretName,
 	})
 
+	// The admissions in flight are drained after the webhook stops serving
+	shutdown := &webhook.Shutdown{}
+	opts.Shutdown = shutdown
+
 	sharedmain.WebhookMainWithConfig(ctx, serviceName,
-		injection.ParseAndGetRESTConfigOrDie(),
+		cfg,
 		certificates.NewController,
-		newValidationAdmissionController(webhookName),
+		newValidationAdmissionController(webhookName, opts),
+		newConversionController,
 	)
+
+	if !shutdown.Wait(opts.ShutdownTimeout()) {
+		log.Printf("Exiting before the admissions in flight drained and their events were flushed")
+	}
 }
@@ -47,6 +47,11 @@ ${PREFIX}/deepcopy-gen \
   --go-header-file ${REPO_ROOT_DIR}/hack/boilerplate/boilerplate.go.txt \
   github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha
 
+${PREFIX}/deepcopy-gen \
+  --output-file zz_generated.deepcopy.go \
+  --go-header-file ${REPO_ROOT_DIR}/hack/boilerplate/boilerplate.go.txt \
+  github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1beta1
+
 # Knative Injection
 # This generates the knative injection packages for the resource package (v1alpha1).
 bash ${REPO_ROOT_DIR}/hack/generate-knative.sh "injection" \
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
 	"sync"
 
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
@@ -105,11 +106,21 @@ func update(gvr *schema.GroupVersionResource, dynamic dynamic.Interface, at *v1a
 
 	// First pass: Process all User type approvers to ensure User type takes precedence
 	for i, approver := range at.Spec.Approvers {
-		if v1alpha1.DefaultedApproverType(approver.Type) == "User" && approver.Name == opts.Username {
+		// Substitutes record their decision, and themselves in decidedBy, on
+		// the entry they stand in for; the webhook only accepts it while they
+		// are the one on duty
+		if v1alpha1.DefaultedApproverType(approver.Type) == "User" && (approver.Name == opts.Username || slices.Contains(approver.Substitutes, opts.Username)) {
 			at.Spec.Approvers[i].Input = opts.Input
+			at.Spec.Approvers[i].DecidedBy = ""
+			if approver.Name != opts.Username {
+				at.Spec.Approvers[i].DecidedBy = opts.Username
+			}
 			if opts.Message != "" {
 				at.Spec.Approvers[i].Message = opts.Message
 			}
+			if opts.Digest != "" {
+				at.Spec.Approvers[i].AcknowledgedDigest = opts.Digest
+			}
 			userProcessedAsIndividual = true
 		}
 	}
@@ -138,6 +149,9 @@ func update(gvr *schema.GroupVersionResource, dynamic dynamic.Interface, at *v1a
 								if existing.Message != opts.Message {
 									at.Spec.Approvers[i].Users[j].Message = opts.Message
 								}
+								if opts.Digest != "" {
+									at.Spec.Approvers[i].Users[j].AcknowledgedDigest = opts.Digest
+								}
 								break
 							}
 						}
@@ -146,6 +160,7 @@ func update(gvr *schema.GroupVersionResource, dynamic dynamic.Interface, at *v1a
 								Name:  opts.Username,
 								Input: opts.Input,
 								Message: opts.Message,
+								AcknowledgedDigest: opts.Digest,
 							}
 							at.Spec.Approvers[i].Users = append(at.Spec.Approvers[i].Users, newUser)
 						}
@@ -208,7 +223,7 @@ func containsUsername(approvers []v1alpha1.ApproverDetails, user *cli.Options) b
 	for _, approval := range approvers {
 		switch approval.Type {
 		case "User":
-			if approval.Name == user.Username {
+			if approval.Name == user.Username || slices.Contains(approval.Substitutes, user.Username) {
 				return true
 			}
 		case "Group":
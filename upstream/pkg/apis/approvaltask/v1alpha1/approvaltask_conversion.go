@@ -0,0 +1,279 @@
+/*
+Copyright 2022 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1beta1"
+	"knative.dev/pkg/apis"
+)
+
+// v1alpha1 is the storage version and acts as the conversion hub: every
+// other served version converts to and from it.
+var _ apis.Convertible = (*ApprovalTask)(nil)
+
+// ConvertTo implements apis.Convertible
+func (at *ApprovalTask) ConvertTo(ctx context.Context, to apis.Convertible) error {
+	switch sink := to.(type) {
+	case *v1beta1.ApprovalTask:
+		sink.ObjectMeta = at.ObjectMeta
+		at.Spec.convertTo(&sink.Spec)
+		at.Status.convertTo(&sink.Status)
+		return nil
+	default:
+		return fmt.Errorf("unknown version, got: %T", sink)
+	}
+}
+
+// ConvertFrom implements apis.Convertible
+func (at *ApprovalTask) ConvertFrom(ctx context.Context, from apis.Convertible) error {
+	switch source := from.(type) {
+	case *v1beta1.ApprovalTask:
+		at.ObjectMeta = source.ObjectMeta
+		at.Spec.convertFrom(&source.Spec)
+		at.Status.convertFrom(&source.Status)
+		return nil
+	default:
+		return fmt.Errorf("unknown version, got: %T", source)
+	}
+}
+
+func (ats *ApprovalTaskSpec) convertTo(sink *v1beta1.ApprovalTaskSpec) {
+	sink.NumberOfApprovalsRequired = ats.NumberOfApprovalsRequired
+	sink.ApprovalPercentage = ats.ApprovalPercentage
+	sink.Description = ats.Description
+	sink.EscrowGroup = ats.EscrowGroup
+	sink.MaxApprovalsPerGroup = ats.MaxApprovalsPerGroup
+	sink.RequesterInput = ats.RequesterInput
+	sink.Paused = ats.Paused
+	sink.ExpectedDigest = ats.ExpectedDigest
+	sink.RequireDigestAcknowledgment = ats.RequireDigestAcknowledgment
+	sink.ChangeTicket = ats.ChangeTicket
+	sink.MinApprovingTeams = ats.MinApprovingTeams
+	sink.MinApproverLevel = ats.MinApproverLevel
+	sink.TTLSecondsAfterFinished = ats.TTLSecondsAfterFinished
+	sink.MinReviewDuration = ats.MinReviewDuration
+	sink.Blocklist = ats.Blocklist
+	sink.MixedResolution = ats.MixedResolution
+	sink.ApproverChangePolicy = ats.ApproverChangePolicy
+	sink.CountRequesterApproval = ats.CountRequesterApproval
+	sink.RequiredRoles = ats.RequiredRoles
+	sink.RejectionReversalWindow = ats.RejectionReversalWindow
+	sink.EscalateAfter = ats.EscalateAfter
+	sink.MaxPendingLifetime = ats.MaxPendingLifetime
+	sink.QuorumSchedule = nil
+	for _, step := range ats.QuorumSchedule {
+		sink.QuorumSchedule = append(sink.QuorumSchedule, v1beta1.QuorumStep{After: step.After, NumberOfApprovalsRequired: step.NumberOfApprovalsRequired})
+	}
+	sink.Approvers = nil
+	for _, a := range ats.Approvers {
+		approver := v1beta1.ApproverDetails{
+			Name:                 a.Name,
+			Input:                a.Input,
+			Message:              a.Message,
+			Type:                 a.Type,
+			AllowedInputs:        a.AllowedInputs,
+			ApprovalExpiresAfter: a.ApprovalExpiresAfter,
+			RenewTime:            a.RenewTime,
+			Priority:             a.Priority,
+			Substitutes:          a.Substitutes,
+			DecidedBy:            a.DecidedBy,
+			WhenLabels:           a.WhenLabels,
+			Team:                 a.Team,
+			Level:                a.Level,
+			Role:                 a.Role,
+			AcknowledgedDigest:   a.AcknowledgedDigest,
+			Backup:               a.Backup,
+		}
+		for _, u := range a.Users {
+			approver.Users = append(approver.Users, v1beta1.UserDetails{
+				Name:               u.Name,
+				Input:              u.Input,
+				Message:            u.Message,
+				RenewTime:          u.RenewTime,
+				Team:               u.Team,
+				Level:              u.Level,
+				AcknowledgedDigest: u.AcknowledgedDigest,
+			})
+		}
+		sink.Approvers = append(sink.Approvers, approver)
+	}
+}
+
+func (ats *ApprovalTaskSpec) convertFrom(source *v1beta1.ApprovalTaskSpec) {
+	ats.NumberOfApprovalsRequired = source.NumberOfApprovalsRequired
+	ats.ApprovalPercentage = source.ApprovalPercentage
+	ats.Description = source.Description
+	ats.EscrowGroup = source.EscrowGroup
+	ats.MaxApprovalsPerGroup = source.MaxApprovalsPerGroup
+	ats.RequesterInput = source.RequesterInput
+	ats.Paused = source.Paused
+	ats.ExpectedDigest = source.ExpectedDigest
+	ats.RequireDigestAcknowledgment = source.RequireDigestAcknowledgment
+	ats.ChangeTicket = source.ChangeTicket
+	ats.MinApprovingTeams = source.MinApprovingTeams
+	ats.MinApproverLevel = source.MinApproverLevel
+	ats.TTLSecondsAfterFinished = source.TTLSecondsAfterFinished
+	ats.MinReviewDuration = source.MinReviewDuration
+	ats.Blocklist = source.Blocklist
+	ats.MixedResolution = source.MixedResolution
+	ats.ApproverChangePolicy = source.ApproverChangePolicy
+	ats.CountRequesterApproval = source.CountRequesterApproval
+	ats.RequiredRoles = source.RequiredRoles
+	ats.RejectionReversalWindow = source.RejectionReversalWindow
+	ats.EscalateAfter = source.EscalateAfter
+	ats.MaxPendingLifetime = source.MaxPendingLifetime
+	ats.QuorumSchedule = nil
+	for _, step := range source.QuorumSchedule {
+		ats.QuorumSchedule = append(ats.QuorumSchedule, QuorumStep{After: step.After, NumberOfApprovalsRequired: step.NumberOfApprovalsRequired})
+	}
+	ats.Approvers = nil
+	for _, a := range source.Approvers {
+		approver := ApproverDetails{
+			Name:                 a.Name,
+			Input:                a.Input,
+			Message:              a.Message,
+			Type:                 a.Type,
+			AllowedInputs:        a.AllowedInputs,
+			ApprovalExpiresAfter: a.ApprovalExpiresAfter,
+			RenewTime:            a.RenewTime,
+			Priority:             a.Priority,
+			Substitutes:          a.Substitutes,
+			DecidedBy:            a.DecidedBy,
+			WhenLabels:           a.WhenLabels,
+			Team:                 a.Team,
+			Level:                a.Level,
+			Role:                 a.Role,
+			AcknowledgedDigest:   a.AcknowledgedDigest,
+			Backup:               a.Backup,
+		}
+		for _, u := range a.Users {
+			approver.Users = append(approver.Users, UserDetails{
+				Name:               u.Name,
+				Input:              u.Input,
+				Message:            u.Message,
+				RenewTime:          u.RenewTime,
+				Team:               u.Team,
+				Level:              u.Level,
+				AcknowledgedDigest: u.AcknowledgedDigest,
+			})
+		}
+		ats.Approvers = append(ats.Approvers, approver)
+	}
+}
+
+func (ats *ApprovalTaskStatus) convertTo(sink *v1beta1.ApprovalTaskStatus) {
+	sink.Status = ats.Status
+	sink.State = ats.State
+	sink.Approvers = ats.Approvers
+	sink.StartTime = ats.StartTime
+	sink.ApprovalsRequired = ats.ApprovalsRequired
+	sink.ApprovalsReceived = ats.ApprovalsReceived
+	sink.LastDecisionAt = ats.LastDecisionAt
+	sink.StartTimeLocal = ats.StartTimeLocal
+	sink.LastDecisionAtLocal = ats.LastDecisionAtLocal
+	sink.CompletionTime = ats.CompletionTime
+	sink.RejectedAt = ats.RejectedAt
+	sink.Phase = ats.Phase
+	sink.EscalatedAt = ats.EscalatedAt
+	sink.Policy = nil
+	if ats.Policy != nil {
+		sink.Policy = &v1beta1.PolicyRequirements{
+			Policies:             ats.Policy.Policies,
+			MinApprovalsRequired: ats.Policy.MinApprovalsRequired,
+			RequiredGroups:       ats.Policy.RequiredGroups,
+		}
+	}
+	sink.ApproversResponse = nil
+	for _, r := range ats.ApproversResponse {
+		response := v1beta1.ApproverState{
+			Name:            r.Name,
+			Response:        r.Response,
+			Message:         r.Message,
+			Type:            r.Type,
+			RespondedAt:     r.RespondedAt,
+			ResponseLatency: r.ResponseLatency,
+			IdempotencyKey:  r.IdempotencyKey,
+			CarriedFrom:     r.CarriedFrom,
+			DecidedBy:       r.DecidedBy,
+			DelegationBasis: r.DelegationBasis,
+		}
+		for _, m := range r.GroupMembers {
+			response.GroupMembers = append(response.GroupMembers, v1beta1.GroupMemberState{
+				Name:            m.Name,
+				Response:        m.Response,
+				Message:         m.Message,
+				RespondedAt:     m.RespondedAt,
+				ResponseLatency: m.ResponseLatency,
+				IdempotencyKey:  m.IdempotencyKey,
+			})
+		}
+		sink.ApproversResponse = append(sink.ApproversResponse, response)
+	}
+}
+
+func (ats *ApprovalTaskStatus) convertFrom(source *v1beta1.ApprovalTaskStatus) {
+	ats.Status = source.Status
+	ats.State = source.State
+	ats.Approvers = source.Approvers
+	ats.StartTime = source.StartTime
+	ats.ApprovalsRequired = source.ApprovalsRequired
+	ats.ApprovalsReceived = source.ApprovalsReceived
+	ats.LastDecisionAt = source.LastDecisionAt
+	ats.StartTimeLocal = source.StartTimeLocal
+	ats.LastDecisionAtLocal = source.LastDecisionAtLocal
+	ats.CompletionTime = source.CompletionTime
+	ats.RejectedAt = source.RejectedAt
+	ats.Phase = source.Phase
+	ats.EscalatedAt = source.EscalatedAt
+	ats.Policy = nil
+	if source.Policy != nil {
+		ats.Policy = &PolicyRequirements{
+			Policies:             source.Policy.Policies,
+			MinApprovalsRequired: source.Policy.MinApprovalsRequired,
+			RequiredGroups:       source.Policy.RequiredGroups,
+		}
+	}
+	ats.ApproversResponse = nil
+	for _, r := range source.ApproversResponse {
+		response := ApproverState{
+			Name:            r.Name,
+			Response:        r.Response,
+			Message:         r.Message,
+			Type:            r.Type,
+			RespondedAt:     r.RespondedAt,
+			ResponseLatency: r.ResponseLatency,
+			IdempotencyKey:  r.IdempotencyKey,
+			CarriedFrom:     r.CarriedFrom,
+			DecidedBy:       r.DecidedBy,
+			DelegationBasis: r.DelegationBasis,
+		}
+		for _, m := range r.GroupMembers {
+			response.GroupMembers = append(response.GroupMembers, GroupMemberState{
+				Name:            m.Name,
+				Response:        m.Response,
+				Message:         m.Message,
+				RespondedAt:     m.RespondedAt,
+				ResponseLatency: m.ResponseLatency,
+				IdempotencyKey:  m.IdempotencyKey,
+			})
+		}
+		ats.ApproversResponse = append(ats.ApproversResponse, response)
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2022 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1beta1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+)
+
+func conversionTestApprovalTask() *ApprovalTask {
+	startTime := metav1.NewTime(time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC))
+	respondedAt := metav1.NewTime(startTime.Add(time.Minute))
+	return &ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-approval",
+			Namespace: "default",
+			Labels:    map[string]string{"tekton.dev/customRun": "example-approval"},
+		},
+		Spec: ApprovalTaskSpec{
+			NumberOfApprovalsRequired:   2,
+			Description:                 "Deploy to production",
+			EscrowGroup:                 "release-2024-01",
+			MaxApprovalsPerGroup:        1,
+			RequesterInput:              "withdraw",
+			Paused:                      true,
+			ExpectedDigest:              "sha256:4c1e2f",
+			RequireDigestAcknowledgment: true,
+			ChangeTicket:                "CHG0031234",
+			QuorumSchedule:              []QuorumStep{{After: metav1.Duration{Duration: time.Hour}, NumberOfApprovalsRequired: 1}},
+			MinApprovingTeams:           2,
+			MixedResolution:             MixedResolutionFailFast,
+			RequiredRoles:               []string{"manager", "peer"},
+			RejectionReversalWindow:     &metav1.Duration{Duration: 10 * time.Minute},
+			EscalateAfter:               &metav1.Duration{Duration: 4 * time.Hour},
+			MaxPendingLifetime:          &metav1.Duration{Duration: 24 * time.Hour},
+			Approvers: []ApproverDetails{
+				{
+					Name:                 "alice",
+					Type:                 "User",
+					Input:                "approve",
+					Message:              "lgtm",
+					AllowedInputs:        []string{"approve", "reject"},
+					ApprovalExpiresAfter: &metav1.Duration{Duration: time.Hour},
+					RenewTime:            &respondedAt,
+					Priority:             1,
+					Substitutes:          []string{"bob"},
+					DecidedBy:            "bob",
+					WhenLabels:           map[string]string{"risk": "high"},
+					Team:                 "payments",
+					Role:                 "manager",
+					AcknowledgedDigest:   "sha256:4c1e2f",
+				},
+				{
+					Name:   "platform",
+					Type:   "Group",
+					Input:  "pending",
+					Role:   "peer",
+					Backup: true,
+					Users:  []UserDetails{{Name: "bob", Input: "reject", Message: "not yet", RenewTime: &respondedAt, Team: "platform", AcknowledgedDigest: "sha256:4c1e2f"}},
+				},
+			},
+		},
+		Status: ApprovalTaskStatus{
+			State:     "pending",
+			Approvers: []string{"alice", "platform"},
+			ApproversResponse: []ApproverState{
+				{Name: "alice", Type: "User", Response: "approved", Message: "lgtm", RespondedAt: &respondedAt, IdempotencyKey: "retry-1", CarriedFrom: "example-approval-1", DecidedBy: "bob", DelegationBasis: DelegationBasisSubstitute},
+				{
+					Name:         "platform",
+					Type:         "Group",
+					Response:     "rejected",
+					RespondedAt:  &respondedAt,
+					GroupMembers: []GroupMemberState{{Name: "bob", Response: "rejected", Message: "not yet", RespondedAt: &respondedAt, IdempotencyKey: "retry-2"}},
+				},
+			},
+			StartTime:           &startTime,
+			ApprovalsRequired:   2,
+			ApprovalsReceived:   1,
+			LastDecisionAt:      &respondedAt,
+			StartTimeLocal:      "2024-01-15 11:00:00 +0100 CET",
+			LastDecisionAtLocal: "2024-01-15 11:30:00 +0100 CET",
+			RejectedAt:          &respondedAt,
+			Phase:               ApprovalTaskPhaseEscalated,
+			EscalatedAt:         &respondedAt,
+			Policy: &PolicyRequirements{
+				Policies:             []string{"production-minimum"},
+				MinApprovalsRequired: 2,
+				RequiredGroups:       []string{"security"},
+			},
+		},
+	}
+}
+
+func TestApprovalTaskConversionRoundTrip(t *testing.T) {
+	in := conversionTestApprovalTask()
+
+	beta := &v1beta1.ApprovalTask{}
+	if err := in.ConvertTo(context.Background(), beta); err != nil {
+		t.Fatalf("ConvertTo() = %v", err)
+	}
+	assert.Equal(t, in.Spec.Approvers[1].Users[0].Name, beta.Spec.Approvers[1].Users[0].Name)
+	assert.Equal(t, in.Status.ApproversResponse[1].GroupMembers[0].Response, beta.Status.ApproversResponse[1].GroupMembers[0].Response)
+
+	out := &ApprovalTask{}
+	if err := out.ConvertFrom(context.Background(), beta); err != nil {
+		t.Fatalf("ConvertFrom() = %v", err)
+	}
+	assert.Equal(t, in, out)
+}
+
+func TestApprovalTaskConversionRoundTripFromV1beta1(t *testing.T) {
+	hub := conversionTestApprovalTask()
+	in := &v1beta1.ApprovalTask{}
+	if err := hub.ConvertTo(context.Background(), in); err != nil {
+		t.Fatalf("ConvertTo() = %v", err)
+	}
+
+	intermediate := &ApprovalTask{}
+	if err := intermediate.ConvertFrom(context.Background(), in); err != nil {
+		t.Fatalf("ConvertFrom() = %v", err)
+	}
+	out := &v1beta1.ApprovalTask{}
+	if err := intermediate.ConvertTo(context.Background(), out); err != nil {
+		t.Fatalf("ConvertTo() = %v", err)
+	}
+	assert.Equal(t, in, out)
+}
+
+func TestApprovalTaskConversionUnknownVersion(t *testing.T) {
+	at := conversionTestApprovalTask()
+	bad := &ApprovalTask{}
+
+	assert.Error(t, at.ConvertTo(context.Background(), bad))
+	assert.Error(t, at.ConvertFrom(context.Background(), bad))
+
+	var _ apis.Convertible = &v1beta1.ApprovalTask{}
+	assert.Error(t, (&v1beta1.ApprovalTask{}).ConvertTo(context.Background(), at))
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import "knative.dev/pkg/apis"
+
+const (
+	// ApprovalTaskConditionQuorumMet is True once the task collected the
+	// number of approvals it requires.
+	ApprovalTaskConditionQuorumMet apis.ConditionType = "QuorumMet"
+	// ApprovalTaskConditionMandatoryMet is True while the approvals cover
+	// what the task requires on top of their number: the groups its policies
+	// require, its teams and its roles, with nobody requesting changes.
+	ApprovalTaskConditionMandatoryMet apis.ConditionType = "MandatoryMet"
+	// ApprovalTaskConditionNotExpired is False once the task timed out.
+	ApprovalTaskConditionNotExpired apis.ConditionType = "NotExpired"
+)
+
+// Phases of the lifecycle of an ApprovalTask, recorded in its Status.Phase: a
+// Pending task escalates to its backup approvers after its EscalateAfter, and
+// expires after its MaxPendingLifetime, unless it is Decided first.
+const (
+	ApprovalTaskPhasePending   = "Pending"
+	ApprovalTaskPhaseEscalated = "Escalated"
+	ApprovalTaskPhaseExpired   = "Expired"
+	ApprovalTaskPhaseDecided   = "Decided"
+)
+
+// approvalTaskCondSet derives the Ready condition of an ApprovalTask from the
+// conditions of its requirements: it is True once all of them are met.
+var approvalTaskCondSet = apis.NewLivingConditionSet(
+	ApprovalTaskConditionQuorumMet,
+	ApprovalTaskConditionMandatoryMet,
+	ApprovalTaskConditionNotExpired,
+)
+
+// GetConditionSet returns the set of conditions of an ApprovalTask.
+func (*ApprovalTask) GetConditionSet() apis.ConditionSet {
+	return approvalTaskCondSet
+}
+
+// InitializeConditions sets the conditions of the status that are not set yet
+// to Unknown.
+func (s *ApprovalTaskStatus) InitializeConditions() {
+	approvalTaskCondSet.Manage(s).InitializeConditions()
+}
+
+// MarkQuorumMet sets the QuorumMet condition to True.
+func (s *ApprovalTaskStatus) MarkQuorumMet() {
+	approvalTaskCondSet.Manage(s).MarkTrue(ApprovalTaskConditionQuorumMet)
+}
+
+// MarkQuorumNotMet sets the QuorumMet condition to False, and Ready with it.
+func (s *ApprovalTaskStatus) MarkQuorumNotMet(reason, messageFormat string, messageA ...interface{}) {
+	approvalTaskCondSet.Manage(s).MarkFalse(ApprovalTaskConditionQuorumMet, reason, messageFormat, messageA...)
+}
+
+// MarkMandatoryMet sets the MandatoryMet condition to True.
+func (s *ApprovalTaskStatus) MarkMandatoryMet() {
+	approvalTaskCondSet.Manage(s).MarkTrue(ApprovalTaskConditionMandatoryMet)
+}
+
+// MarkMandatoryNotMet sets the MandatoryMet condition to False, and Ready with
+// it.
+func (s *ApprovalTaskStatus) MarkMandatoryNotMet(reason, messageFormat string, messageA ...interface{}) {
+	approvalTaskCondSet.Manage(s).MarkFalse(ApprovalTaskConditionMandatoryMet, reason, messageFormat, messageA...)
+}
+
+// MarkNotExpired sets the NotExpired condition to True.
+func (s *ApprovalTaskStatus) MarkNotExpired() {
+	approvalTaskCondSet.Manage(s).MarkTrue(ApprovalTaskConditionNotExpired)
+}
+
+// MarkExpired sets the NotExpired condition to False, and Ready with it.
+func (s *ApprovalTaskStatus) MarkExpired(reason, messageFormat string, messageA ...interface{}) {
+	approvalTaskCondSet.Manage(s).MarkFalse(ApprovalTaskConditionNotExpired, reason, messageFormat, messageA...)
+}
+
+// MarkNotReady sets the Ready condition to False whatever the conditions of
+// the requirements, for tasks that were rejected or withdrawn.
+func (s *ApprovalTaskStatus) MarkNotReady(reason, messageFormat string, messageA ...interface{}) {
+	approvalTaskCondSet.Manage(s).MarkFalse(apis.ConditionReady, reason, messageFormat, messageA...)
+}
@@ -44,12 +44,171 @@ type ApprovalTaskSpec struct {
 	Approvers                 []ApproverDetails `json:"approvers"`
 	NumberOfApprovalsRequired int               `json:"numberOfApprovalsRequired"`
 	Description               string            `json:"description,omitempty"`
+	// ApprovalPercentage requires approvals from this percentage, between 1
+	// and 100, of the active approvers instead of NumberOfApprovalsRequired,
+	// which must then be left unset. The count is rounded up and follows the
+	// approvers as they are added, removed or become inactive.
+	// +optional
+	ApprovalPercentage int `json:"approvalPercentage,omitempty"`
+	// EscrowGroup links ApprovalTasks, possibly across namespaces, that must be
+	// approved together: none of them is marked approved until all of them
+	// have independently reached their quorum.
+	// +optional
+	EscrowGroup string `json:"escrowGroup,omitempty"`
+	// MaxApprovalsPerGroup caps how many approvals a single Group approver can
+	// contribute towards NumberOfApprovalsRequired. Zero means no cap.
+	// +optional
+	MaxApprovalsPerGroup int `json:"maxApprovalsPerGroup,omitempty"`
+	// RequesterInput is set to "withdraw" by the creator of the task to
+	// abandon it, for example when the change it gates has been dropped.
+	// +optional
+	RequesterInput string `json:"requesterInput,omitempty"`
+	// Paused freezes the task: no approver input can be changed while it is
+	// set. Only members of the webhook's privileged group can change it.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+	// ExpectedDigest is the digest of the artifact or commit the task gates.
+	// Approvals are rejected while the task's current-digest annotation
+	// claims a different digest.
+	// +optional
+	ExpectedDigest string `json:"expectedDigest,omitempty"`
+	// RequireDigestAcknowledgment requires each approval to echo
+	// ExpectedDigest in the acknowledgedDigest of the approving entry, so
+	// that every approver is shown to have reviewed the gated artifact.
+	// +optional
+	RequireDigestAcknowledgment bool `json:"requireDigestAcknowledgment,omitempty"`
+	// ChangeTicket references the change request, such as a ServiceNow or
+	// Jira ticket, the task is approving. The webhook can be configured to
+	// deny approvals on tasks without a valid reference.
+	// +optional
+	ChangeTicket string `json:"changeTicket,omitempty"`
+	// QuorumSchedule lowers NumberOfApprovalsRequired the longer the task
+	// stays pending, so that urgent low-risk changes are not blocked for
+	// long on a large quorum.
+	// +optional
+	QuorumSchedule []QuorumStep `json:"quorumSchedule,omitempty"`
+	// MinApprovingTeams requires the counted approvals to come from at
+	// least this many distinct teams, as recorded in the Team of the
+	// approving entries. Zero means approvals need not span teams.
+	// +optional
+	MinApprovingTeams int `json:"minApprovingTeams,omitempty"`
+	// MinApproverLevel only counts approvals recorded with at least this
+	// Level towards the quorum, so that the task needs at least one approval
+	// from an approver of that seniority. Zero means levels are ignored.
+	// +optional
+	MinApproverLevel int `json:"minApproverLevel,omitempty"`
+	// TTLSecondsAfterFinished deletes the task this many seconds after it
+	// reached a final state, like the field of the same name of Jobs. The
+	// task is never deleted automatically when it is nil.
+	// +optional
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+	// MinReviewDuration is the mandatory review period of the task:
+	// approvals submitted sooner than this after its creation are denied.
+	// Rejections are always allowed.
+	// +optional
+	MinReviewDuration *metav1.Duration `json:"minReviewDuration,omitempty"`
+	// Blocklist lists identities, usernames or emails, whose decisions on
+	// the task are always denied, even when they are approvers or members of
+	// an approving group. Only members of the webhook's privileged group can
+	// change it.
+	// +optional
+	Blocklist []string `json:"blocklist,omitempty"`
+	// MixedResolution turns rejections from a veto into votes against the
+	// task and decides when such a task fails: MixedResolutionWaitForAll or
+	// MixedResolutionFailFast. When it is empty, a single rejection rejects
+	// the task.
+	// +optional
+	MixedResolution string `json:"mixedResolution,omitempty"`
+	// ApproverChangePolicy decides what happens to the responses of a
+	// pending task when its approver set changes: ApproverChangePreserve
+	// keeps them, ApproverChangeReset resets every approver to pending.
+	// Empty means ApproverChangePreserve.
+	// +optional
+	ApproverChangePolicy string `json:"approverChangePolicy,omitempty"`
+	// CountRequesterApproval counts the approval of the user who requested
+	// the task, as recorded in its CreatedByAnnotationKey annotation, towards
+	// its quorum. By default the approval of the requester is recorded but
+	// not counted.
+	// +optional
+	CountRequesterApproval bool `json:"countRequesterApproval,omitempty"`
+	// RequiredRoles lists the roles that must each be covered by at least
+	// one counted approval, in addition to the number of approvals, as
+	// given by the Role of the approving entries. For example ["manager",
+	// "peer"] requires both a manager and a peer to approve.
+	// +optional
+	RequiredRoles []string `json:"requiredRoles,omitempty"`
+	// RejectionReversalWindow keeps a rejection by an approver reversible
+	// for this long: until it has passed, members of the privileged group
+	// can reset the rejecting inputs to pending, and the CustomRun is only
+	// failed once it has. Rejections are final at once when it is nil.
+	// +optional
+	RejectionReversalWindow *metav1.Duration `json:"rejectionReversalWindow,omitempty"`
+	// EscalateAfter is how long the task waits pending for its approvers
+	// before it escalates to its Backup approvers, counted from its start.
+	// Backup approvers never take part in the task when it is nil.
+	// +optional
+	EscalateAfter *metav1.Duration `json:"escalateAfter,omitempty"`
+	// MaxPendingLifetime is how long the task may stay pending, counted from
+	// its start, before it expires. It expires with the timeout of its
+	// CustomRun when that comes first.
+	// +optional
+	MaxPendingLifetime *metav1.Duration `json:"maxPendingLifetime,omitempty"`
+}
+
+const (
+	// MixedResolutionWaitForAll keeps a task that has not reached its quorum
+	// pending until every approver has responded.
+	MixedResolutionWaitForAll = "WaitForAll"
+	// MixedResolutionFailFast rejects a task as soon as the approvers that
+	// have not rejected it can no longer reach its quorum.
+	MixedResolutionFailFast = "FailFast"
+)
+
+// KnownMixedResolutions are the values accepted for the mixed resolution of
+// an ApprovalTask, besides the empty one.
+var KnownMixedResolutions = []string{MixedResolutionWaitForAll, MixedResolutionFailFast}
+
+const (
+	// ApproverChangePreserve keeps the responses of a task whose approver
+	// set changes.
+	ApproverChangePreserve = "Preserve"
+	// ApproverChangeReset resets the responses of a task whose approver set
+	// changes, so that every approver decides again.
+	ApproverChangeReset = "Reset"
+)
+
+// KnownApproverChangePolicies are the values accepted for the approver change
+// policy of an ApprovalTask, besides the empty one.
+var KnownApproverChangePolicies = []string{ApproverChangePreserve, ApproverChangeReset}
+
+// QuorumStep sets the number of approvals required once the task has been
+// pending for After.
+type QuorumStep struct {
+	After                     metav1.Duration `json:"after"`
+	NumberOfApprovalsRequired int             `json:"numberOfApprovalsRequired"`
 }
 
 type UserDetails struct {
 	Name  string `json:"name"`
 	Input string `json:"input"`
 	Message string `json:"message,omitempty"`
+	// RenewTime is bumped by the user to keep an expiring approval alive.
+	// +optional
+	RenewTime *metav1.Time `json:"renewTime,omitempty"`
+	// Team is the team of the user, recorded with their decision. The
+	// webhook only accepts one of the values of the user's "team" claim.
+	// +optional
+	Team string `json:"team,omitempty"`
+	// Level is the seniority level of the user, recorded with their
+	// decision. The webhook only accepts one of the values of the user's
+	// "level" claim.
+	// +optional
+	Level string `json:"level,omitempty"`
+	// AcknowledgedDigest is the digest of the artifact the user reviewed,
+	// echoed with their approval. Approvals acknowledging a digest other
+	// than Spec.ExpectedDigest are denied.
+	// +optional
+	AcknowledgedDigest string `json:"acknowledgedDigest,omitempty"`
 }
 
 type ApproverDetails struct {
@@ -58,6 +217,63 @@ type ApproverDetails struct {
 	Message string        `json:"message,omitempty"`
 	Type    string        `json:"type"`
 	Users   []UserDetails `json:"users,omitempty"`
+	// AllowedInputs restricts the decisions this approver may submit, for
+	// example ["reject"] for a blocker role that can never approve. Empty
+	// means every supported input is allowed.
+	// +optional
+	AllowedInputs []string `json:"allowedInputs,omitempty"`
+	// ApprovalExpiresAfter makes approvals from this approver (or, for a
+	// group, from its members) revert to pending unless they are renewed
+	// within the duration.
+	// +optional
+	ApprovalExpiresAfter *metav1.Duration `json:"approvalExpiresAfter,omitempty"`
+	// RenewTime is bumped by a User approver to keep an expiring approval
+	// alive. Group members renew through their entry in Users.
+	// +optional
+	RenewTime *metav1.Time `json:"renewTime,omitempty"`
+	// Priority orders approvals within the task: approvals from this
+	// approver only count once every approver with a lower, non-zero
+	// Priority has approved. Approvers without a Priority come last.
+	// +optional
+	Priority int `json:"priority,omitempty"`
+	// Substitutes is an ordered list of users standing in for a User
+	// approver. Only the first of the approver and its substitutes not
+	// listed in the namespace's unavailable-approvers annotation may decide.
+	// +optional
+	Substitutes []string `json:"substitutes,omitempty"`
+	// DecidedBy is the substitute who recorded the input of this approver on
+	// its behalf, set by the substitute along with the input. It is empty
+	// when the approver decided itself.
+	// +optional
+	DecidedBy string `json:"decidedBy,omitempty"`
+	// WhenLabels makes the approver conditional: it only takes part in the
+	// task while every one of these labels is set on the task with the given
+	// value. Inactive approvers neither count towards the quorum nor decide.
+	// +optional
+	WhenLabels map[string]string `json:"whenLabels,omitempty"`
+	// Team is the team of a User or Email approver, recorded with its
+	// decision. The webhook only accepts one of the values of the user's
+	// "team" claim. Group members record theirs in Users.
+	// +optional
+	Team string `json:"team,omitempty"`
+	// Level is the seniority level of a User or Email approver, recorded
+	// with its decision like Team, from the user's "level" claim.
+	// +optional
+	Level string `json:"level,omitempty"`
+	// Role is the role the approver approves in, such as "manager" or
+	// "peer", towards Spec.RequiredRoles. The members of a Group approver
+	// approve in the role of the group. It is set when the task is created.
+	// +optional
+	Role string `json:"role,omitempty"`
+	// AcknowledgedDigest is the digest of the artifact a User or Email
+	// approver reviewed, echoed with its approval like Team. Group members
+	// acknowledge theirs in Users.
+	// +optional
+	AcknowledgedDigest string `json:"acknowledgedDigest,omitempty"`
+	// Backup makes the approver a backup approver: it only takes part in the
+	// task once the task escalated (see ApprovalTaskSpec.EscalateAfter).
+	// +optional
+	Backup bool `json:"backup,omitempty"`
 }
 
 type ApprovalTaskStatus struct {
@@ -71,12 +287,52 @@ type ApprovalTaskStatus struct {
 	ApprovalsRequired int `json:"approvalsRequired,omitempty"`
 	// ApprovalsReceived is the number of approvals received so far
 	ApprovalsReceived int `json:"approvalsReceived,omitempty"`
+	// LastDecisionAt is when the most recent of the responses was first observed.
+	LastDecisionAt *metav1.Time `json:"lastDecisionAt,omitempty"`
+	// StartTimeLocal and LastDecisionAtLocal render StartTime and
+	// LastDecisionAt in the display timezone of the controller, for reading
+	// the status directly. They are empty unless a display timezone is set.
+	StartTimeLocal      string `json:"startTimeLocal,omitempty"`
+	LastDecisionAtLocal string `json:"lastDecisionAtLocal,omitempty"`
+	// Policy holds the requirements the ClusterApprovalPolicies selecting
+	// the task impose on top of its spec, as last evaluated by the
+	// controller. It is unset when no policy selects the task.
+	// +optional
+	Policy *PolicyRequirements `json:"policy,omitempty"`
+	// CompletionTime is when the controller first observed the task in a
+	// final state. It is only recorded for tasks with a
+	// TTLSecondsAfterFinished, which counts from it.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// RejectedAt is when the controller first observed the rejection of a
+	// task with a RejectionReversalWindow, which counts from it. It is
+	// cleared when the rejection is reversed.
+	// +optional
+	RejectedAt *metav1.Time `json:"rejectedAt,omitempty"`
+	// Phase is the phase of the lifecycle of the task: Pending, Escalated
+	// once it escalated to its backup approvers, then Expired, unless it is
+	// Decided first.
+	// +optional
+	Phase string `json:"phase,omitempty"`
+	// EscalatedAt is when the controller escalated the task to its backup
+	// approvers.
+	// +optional
+	EscalatedAt *metav1.Time `json:"escalatedAt,omitempty"`
 }
 
 type GroupMemberState struct {
 	Name     string `json:"name"`
 	Response string `json:"response"`
 	Message  string `json:"message,omitempty"`
+	// RespondedAt is when the controller first observed this response.
+	RespondedAt *metav1.Time `json:"respondedAt,omitempty"`
+	// ResponseLatency is how long after the creation of the task this
+	// response was first observed.
+	// +optional
+	ResponseLatency *metav1.Duration `json:"responseLatency,omitempty"`
+	// IdempotencyKey is the idempotency key of the update that recorded this
+	// response, if it carried one.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
 }
 
 type ApproverState struct {
@@ -85,10 +341,51 @@ type ApproverState struct {
 	Message      string             `json:"message,omitempty"`
 	Type         string             `json:"type"`
 	GroupMembers []GroupMemberState `json:"groupMembers,omitempty"`
+	// RespondedAt is when the controller first observed this response.
+	RespondedAt *metav1.Time `json:"respondedAt,omitempty"`
+	// ResponseLatency is how long after the creation of the task this
+	// response was first observed.
+	// +optional
+	ResponseLatency *metav1.Duration `json:"responseLatency,omitempty"`
+	// IdempotencyKey is the idempotency key of the update that recorded this
+	// response, if it carried one.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+	// CarriedFrom is the name of the prior ApprovalTask this approval was
+	// carried forward from (see ApprovalIdentityLabelKey). It counts as long
+	// as the approver has not decided on this task itself.
+	// +optional
+	CarriedFrom string `json:"carriedFrom,omitempty"`
+	// DecidedBy is the substitute who gave this response on behalf of the
+	// approver the state is named after, and DelegationBasis what entitled
+	// them to, such as DelegationBasisSubstitute.
+	// +optional
+	DecidedBy       string `json:"decidedBy,omitempty"`
+	DelegationBasis string `json:"delegationBasis,omitempty"`
+}
+
+// DelegationBasisSubstitute is the basis of responses given by a substitute
+// of a User approver, on duty while the approver is listed in the namespace's
+// unavailable-approvers annotation.
+const DelegationBasisSubstitute = "Substitute"
+
+// KnownApproverTypes are the values accepted for the type of an approver.
+var KnownApproverTypes = []string{"User", "Group", "Email"}
+
+// IsKnownApproverType reports whether the approver type, once defaulted, is
+// one of KnownApproverTypes. It is case and whitespace sensitive.
+func IsKnownApproverType(approverType string) bool {
+	t := DefaultedApproverType(approverType)
+	for _, known := range KnownApproverTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
 }
 
 // DefaultedApproverType returns "User" if the type field is empty (for v0.6.0 compatibility),
-// otherwise returns the provided type.
+// otherwise returns the provided type. It does not check that the type is
+// known; see IsKnownApproverType.
 func DefaultedApproverType(approverType string) string {
 	if approverType == "" {
 		return "User"
@@ -96,6 +393,14 @@ func DefaultedApproverType(approverType string) string {
 	return approverType
 }
 
+// IsIndividualApproverType reports whether approvers of the type stand for a
+// single person deciding through their own entry: "User" approvers, matched by
+// username, and "Email" approvers, matched by the email claim of the user.
+func IsIndividualApproverType(approverType string) bool {
+	t := DefaultedApproverType(approverType)
+	return t == "User" || t == "Email"
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // ApprovalTaskList contains a list of ApprovalTasks
@@ -131,6 +436,15 @@ const (
 	// ApprovalTaskRunReasonSucceeded indicates that all of the TaskRuns created from the Run completed successfully
 	ApprovalTaskRunReasonSucceeded ApprovalTaskRunReason = "Succeeded"
 
+	// ApprovalTaskRunReasonWithdrawn indicates that the ApprovalTask was withdrawn by its creator
+	ApprovalTaskRunReasonWithdrawn ApprovalTaskRunReason = "Withdrawn"
+
+	// ApprovalTaskRunReasonUnsatisfiable indicates that too few approvers remain able to approve the ApprovalTask
+	ApprovalTaskRunReasonUnsatisfiable ApprovalTaskRunReason = "Unsatisfiable"
+
+	// ApprovalTaskRunReasonInconsistentStatus indicates that the responses recorded in the ApprovalTask status do not match its approvers
+	ApprovalTaskRunReasonInconsistentStatus ApprovalTaskRunReason = "InconsistentStatus"
+
 	// ApprovalTaskRunReasonCouldntCancel indicates that a Run was cancelled but attempting to update
 	// the running TaskRun as cancelled failed.
 	ApprovalTaskRunReasonCouldntCancel ApprovalTaskRunReason = "ApprovalTaskRunCouldntCancel"
@@ -35,5 +35,10 @@ func (tg *ApprovalTask) Validate(ctx context.Context) *apis.FieldError {
 
 // Validate ApprovalTaskSpec
 func (tgs *ApprovalTaskSpec) Validate(ctx context.Context) (errs *apis.FieldError) {
-	return nil
+	for i, approver := range tgs.Approvers {
+		if !IsKnownApproverType(approver.Type) {
+			errs = errs.Also(apis.ErrInvalidValue(approver.Type, "type").ViaFieldIndex("approvers", i))
+		}
+	}
+	return errs
 }
@@ -0,0 +1,51 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsKnownApproverType(t *testing.T) {
+	for _, approverType := range []string{"", "User", "Group", "Email"} {
+		assert.True(t, IsKnownApproverType(approverType), "type %q should be known", approverType)
+	}
+	for _, approverType := range []string{"user", "user ", " User", "Grp", "Robot"} {
+		assert.False(t, IsKnownApproverType(approverType), "type %q should be unknown", approverType)
+	}
+}
+
+func TestApprovalTaskSpecValidateApproverTypes(t *testing.T) {
+	spec := ApprovalTaskSpec{Approvers: []ApproverDetails{
+		{Name: "alice", Type: "User"},
+		{Name: "bob"},
+		{Name: "carol", Type: "user "},
+		{Name: "platform", Type: "Grp"},
+	}}
+	err := spec.Validate(context.Background())
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "invalid value: user : approvers[2].type")
+		assert.Contains(t, err.Error(), "invalid value: Grp: approvers[3].type")
+		assert.NotContains(t, err.Error(), "approvers[1]")
+	}
+
+	spec.Approvers = spec.Approvers[:2]
+	assert.Nil(t, spec.Validate(context.Background()))
+}
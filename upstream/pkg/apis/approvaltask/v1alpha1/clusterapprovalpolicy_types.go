@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// ClusterApprovalPolicy imposes minimum approval requirements on the
+// ApprovalTasks it selects, across namespaces. It can only make a task
+// stricter than its own spec: the requirements in effect are the stricter
+// of the two.
+// +k8s:openapi-gen=true
+type ClusterApprovalPolicy struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec ClusterApprovalPolicySpec `json:"spec"`
+}
+
+type ClusterApprovalPolicySpec struct {
+	// Selector selects the ApprovalTasks the policy applies to by their
+	// labels. A nil selector selects every task.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// Namespaces restricts the policy to the tasks of these namespaces.
+	// Empty means every namespace.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+	// MinApprovalsRequired is the lowest NumberOfApprovalsRequired the
+	// selected tasks may use, at any step of their quorum schedule.
+	// +optional
+	MinApprovalsRequired int `json:"minApprovalsRequired,omitempty"`
+	// RequiredGroups are groups that must be Group approvers of the selected
+	// tasks, and contribute at least one approval before they are approved.
+	// +optional
+	RequiredGroups []string `json:"requiredGroups,omitempty"`
+}
+
+// PolicyRequirements are the requirements that the ClusterApprovalPolicies
+// selecting an ApprovalTask impose on it, combined so that the strictest
+// of them applies.
+type PolicyRequirements struct {
+	// Policies are the names of the policies the requirements come from.
+	Policies []string `json:"policies"`
+	// MinApprovalsRequired is the highest MinApprovalsRequired of the
+	// policies.
+	// +optional
+	MinApprovalsRequired int `json:"minApprovalsRequired,omitempty"`
+	// RequiredGroups are the RequiredGroups of all the policies.
+	// +optional
+	RequiredGroups []string `json:"requiredGroups,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterApprovalPolicyList contains a list of ClusterApprovalPolicies
+type ClusterApprovalPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterApprovalPolicy `json:"items"`
+}
@@ -25,6 +25,97 @@ import (
 
 const ManagedByLabelKey = "app.kubernetes.io/managed-by"
 
+// CreatedByAnnotationKey records the user who requested the approval. Only
+// this user may withdraw the ApprovalTask, and it cannot be changed once set.
+const CreatedByAnnotationKey = "openshift-pipelines.org/created-by"
+
+// ApproversAnnotationKey is set on a namespace to a comma separated list of
+// approvers ("alice,group:platform") that may act on every ApprovalTask in it.
+const ApproversAnnotationKey = "openshift-pipelines.org/approvers"
+
+// UnavailableApproversAnnotationKey is set on a namespace to a comma separated
+// list of users who are currently off duty, so that the substitutes of their
+// ApprovalTask approver entries decide in their place.
+const UnavailableApproversAnnotationKey = "openshift-pipelines.org/unavailable-approvers"
+
+// EscrowGroupsAnnotationKey is set on a namespace to a comma separated list of
+// the escrow groups its ApprovalTasks share with the other namespaces listing
+// them. Escrow groups not listed are local to the namespace.
+const EscrowGroupsAnnotationKey = "openshift-pipelines.org/escrow-groups"
+
+// DefaultApprovalsRequiredAnnotationKey is set on a namespace to the number of
+// approvals required by its ApprovalTasks that set neither
+// numberOfApprovalsRequired nor approvalPercentage.
+const DefaultApprovalsRequiredAnnotationKey = "openshift-pipelines.org/default-approvals-required"
+
+// IdempotencyKeyAnnotationKey is set by clients on the update that records a
+// decision. A retry of that update carrying the same key is admitted without
+// effect instead of being denied.
+const IdempotencyKeyAnnotationKey = "openshift-pipelines.org/idempotency-key"
+
+// CorrelationIDAnnotationKey carries the ID tracing an approval across the
+// pipeline system, the gate and downstream deployers. Set on a CustomRun, it
+// is copied to its ApprovalTask, which is given a generated one otherwise.
+// Clients may set it on the update that records a decision to trace that
+// decision instead.
+const CorrelationIDAnnotationKey = "openshift-pipelines.org/correlation-id"
+
+// ChangeTicketAnnotationKey carries the reference of the change ticket
+// tracking a change when it is not known to the pipeline. Set on a CustomRun,
+// it is copied to its ApprovalTask, where spec.changeTicket takes precedence
+// over it. Like spec.changeTicket, it cannot be changed once the task exists.
+const ChangeTicketAnnotationKey = "openshift-pipelines.org/change-ticket"
+
+// QuarantinedUsersAnnotationKey is set by the webhook in the status
+// annotations of an ApprovalTask, when configured to, to a JSON object
+// mapping the users quarantined on the task to the RFC 3339 time their
+// quarantine ends.
+const QuarantinedUsersAnnotationKey = "openshift-pipelines.org/quarantined-users"
+
+// DecisionSignaturesAnnotationKey is set by the webhook, when configured to
+// sign decisions, in the status annotations of an ApprovalTask to a JSON
+// array of the most recent signed statements of the requests it admitted for
+// the task, each with its "statement" and "signature" base64 encoded.
+const DecisionSignaturesAnnotationKey = "openshift-pipelines.org/decision-signatures"
+
+// CleanupFinalizer holds the deletion of an ApprovalTask until the controller
+// has notified external systems that the approval gate was removed.
+const CleanupFinalizer = "openshift-pipelines.org/cleanup"
+
+// CurrentDigestAnnotationKey is set on an ApprovalTask to the digest of the
+// artifact currently being promoted. Approvals are rejected while it differs
+// from the task's spec.expectedDigest.
+const CurrentDigestAnnotationKey = "openshift-pipelines.org/current-digest"
+
+// ChecksStatusAnnotationKey is set by CI on an ApprovalTask to the status of
+// the checks of the change it gates: "success", "pending", "failure" or
+// "conflict" when the change has merge conflicts. By default the webhook
+// denies approvals while it reports failure or conflict.
+const ChecksStatusAnnotationKey = "openshift-pipelines.org/checks-status"
+
+// ApproverSetHashAnnotationKey is set by the controller on an ApprovalTask
+// resetting its responses when its approver set changes (see
+// ApproverChangeReset) to the hash of the approver set the responses were
+// given for.
+const ApproverSetHashAnnotationKey = "openshift-pipelines.org/approver-set-hash"
+
+// ApprovalIdentityLabelKey is set on a CustomRun to a key that stays the same
+// when a pipeline retry recreates it. When the controller carries approvals
+// forward, an ApprovalTask created for the run starts with the approvals of
+// the most recent other ApprovalTask carrying the same key.
+const ApprovalIdentityLabelKey = "openshift-pipelines.org/approval-identity"
+
+// AwaitingApproverLabelPrefix prefixes the labels the controller sets on a
+// pending ApprovalTask for each approver who can still respond, named after a
+// hash of their identity, so that the tasks awaiting someone can be listed
+// with a label selector instead of a scan.
+const AwaitingApproverLabelPrefix = "awaiting.openshift-pipelines.org/"
+
+// AwaitingOverflowLabelKey is set by the controller, in place of the
+// awaiting approver labels, on ApprovalTasks awaiting too many approvers to
+// label each of them.
+const AwaitingOverflowLabelKey = "openshift-pipelines.org/awaiting-overflow"
+
 // SchemeGroupVersion is group version used to register these objects
 var SchemeGroupVersion = schema.GroupVersion{Group: approvaltask.GroupName, Version: "v1alpha1"}
 
@@ -50,6 +141,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&ApprovalTask{},
 		&ApprovalTaskList{},
+		&ClusterApprovalPolicy{},
+		&ClusterApprovalPolicyList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
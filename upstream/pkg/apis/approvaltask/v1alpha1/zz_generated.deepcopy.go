@@ -22,6 +22,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -117,6 +118,46 @@ func (in *ApprovalTaskSpec) DeepCopyInto(out *ApprovalTaskSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.QuorumSchedule != nil {
+		in, out := &in.QuorumSchedule, &out.QuorumSchedule
+		*out = make([]QuorumStep, len(*in))
+		copy(*out, *in)
+	}
+	if in.TTLSecondsAfterFinished != nil {
+		in, out := &in.TTLSecondsAfterFinished, &out.TTLSecondsAfterFinished
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MinReviewDuration != nil {
+		in, out := &in.MinReviewDuration, &out.MinReviewDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Blocklist != nil {
+		in, out := &in.Blocklist, &out.Blocklist
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RequiredRoles != nil {
+		in, out := &in.RequiredRoles, &out.RequiredRoles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RejectionReversalWindow != nil {
+		in, out := &in.RejectionReversalWindow, &out.RejectionReversalWindow
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.EscalateAfter != nil {
+		in, out := &in.EscalateAfter, &out.EscalateAfter
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MaxPendingLifetime != nil {
+		in, out := &in.MaxPendingLifetime, &out.MaxPendingLifetime
+		*out = new(v1.Duration)
+		**out = **in
+	}
 	return
 }
 
@@ -150,6 +191,27 @@ func (in *ApprovalTaskStatus) DeepCopyInto(out *ApprovalTaskStatus) {
 		in, out := &in.StartTime, &out.StartTime
 		*out = (*in).DeepCopy()
 	}
+	if in.LastDecisionAt != nil {
+		in, out := &in.LastDecisionAt, &out.LastDecisionAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Policy != nil {
+		in, out := &in.Policy, &out.Policy
+		*out = new(PolicyRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.RejectedAt != nil {
+		in, out := &in.RejectedAt, &out.RejectedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.EscalatedAt != nil {
+		in, out := &in.EscalatedAt, &out.EscalatedAt
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -169,8 +231,36 @@ func (in *ApproverDetails) DeepCopyInto(out *ApproverDetails) {
 	if in.Users != nil {
 		in, out := &in.Users, &out.Users
 		*out = make([]UserDetails, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AllowedInputs != nil {
+		in, out := &in.AllowedInputs, &out.AllowedInputs
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ApprovalExpiresAfter != nil {
+		in, out := &in.ApprovalExpiresAfter, &out.ApprovalExpiresAfter
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.RenewTime != nil {
+		in, out := &in.RenewTime, &out.RenewTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Substitutes != nil {
+		in, out := &in.Substitutes, &out.Substitutes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.WhenLabels != nil {
+		in, out := &in.WhenLabels, &out.WhenLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -190,7 +280,18 @@ func (in *ApproverState) DeepCopyInto(out *ApproverState) {
 	if in.GroupMembers != nil {
 		in, out := &in.GroupMembers, &out.GroupMembers
 		*out = make([]GroupMemberState, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RespondedAt != nil {
+		in, out := &in.RespondedAt, &out.RespondedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ResponseLatency != nil {
+		in, out := &in.ResponseLatency, &out.ResponseLatency
+		*out = new(v1.Duration)
+		**out = **in
 	}
 	return
 }
@@ -205,9 +306,109 @@ func (in *ApproverState) DeepCopy() *ApproverState {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterApprovalPolicy) DeepCopyInto(out *ClusterApprovalPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterApprovalPolicy.
+func (in *ClusterApprovalPolicy) DeepCopy() *ClusterApprovalPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterApprovalPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterApprovalPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterApprovalPolicyList) DeepCopyInto(out *ClusterApprovalPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterApprovalPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterApprovalPolicyList.
+func (in *ClusterApprovalPolicyList) DeepCopy() *ClusterApprovalPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterApprovalPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterApprovalPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterApprovalPolicySpec) DeepCopyInto(out *ClusterApprovalPolicySpec) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RequiredGroups != nil {
+		in, out := &in.RequiredGroups, &out.RequiredGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterApprovalPolicySpec.
+func (in *ClusterApprovalPolicySpec) DeepCopy() *ClusterApprovalPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterApprovalPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GroupMemberState) DeepCopyInto(out *GroupMemberState) {
 	*out = *in
+	if in.RespondedAt != nil {
+		in, out := &in.RespondedAt, &out.RespondedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ResponseLatency != nil {
+		in, out := &in.ResponseLatency, &out.ResponseLatency
+		*out = new(v1.Duration)
+		**out = **in
+	}
 	return
 }
 
@@ -221,9 +422,56 @@ func (in *GroupMemberState) DeepCopy() *GroupMemberState {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyRequirements) DeepCopyInto(out *PolicyRequirements) {
+	*out = *in
+	if in.Policies != nil {
+		in, out := &in.Policies, &out.Policies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RequiredGroups != nil {
+		in, out := &in.RequiredGroups, &out.RequiredGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyRequirements.
+func (in *PolicyRequirements) DeepCopy() *PolicyRequirements {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyRequirements)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuorumStep) DeepCopyInto(out *QuorumStep) {
+	*out = *in
+	out.After = in.After
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuorumStep.
+func (in *QuorumStep) DeepCopy() *QuorumStep {
+	if in == nil {
+		return nil
+	}
+	out := new(QuorumStep)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UserDetails) DeepCopyInto(out *UserDetails) {
 	*out = *in
+	if in.RenewTime != nil {
+		in, out := &in.RenewTime, &out.RenewTime
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
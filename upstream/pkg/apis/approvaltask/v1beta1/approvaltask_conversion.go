@@ -0,0 +1,38 @@
+/*
+Copyright 2022 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+
+	"knative.dev/pkg/apis"
+)
+
+var _ apis.Convertible = (*ApprovalTask)(nil)
+
+// ConvertTo implements apis.Convertible. Conversions are performed by the
+// v1alpha1 hub version, so this is never expected to be called.
+func (at *ApprovalTask) ConvertTo(ctx context.Context, sink apis.Convertible) error {
+	return fmt.Errorf("v1beta1 is not the hub version, cannot convert to %T", sink)
+}
+
+// ConvertFrom implements apis.Convertible. Conversions are performed by the
+// v1alpha1 hub version, so this is never expected to be called.
+func (at *ApprovalTask) ConvertFrom(ctx context.Context, source apis.Convertible) error {
+	return fmt.Errorf("v1beta1 is not the hub version, cannot convert from %T", source)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const inputReject = "reject"
+
+// Approve submits the decision of username on the named ApprovalTask. input is
+// either "approve" or "reject" and reason is recorded as the approver message.
+//
+// The decision is placed where the webhook expects it: on the user's own entry
+// when they are a User approver, otherwise on their entry in the users of any
+// Group approver listing them. The update is retried on conflicts, so
+// concurrent decisions from other approvers are never overwritten.
+func Approve(ctx context.Context, client versioned.Interface, namespace, name, username, input, reason string) error {
+	if input != inputApprove && input != inputReject {
+		return fmt.Errorf("invalid input value: '%s'. Supported values are 'approve' or 'reject'", input)
+	}
+
+	tasks := client.OpenshiftpipelinesV1alpha1().ApprovalTasks(namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		at, err := tasks.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if !setDecision(at, username, input, reason) {
+			return fmt.Errorf("approver: %s, is not present in the approvers list", username)
+		}
+		_, err = tasks.Update(ctx, at, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// setDecision records the decision of username in the spec of the approval
+// task, and reports whether username is one of its approvers.
+func setDecision(at *v1alpha1.ApprovalTask, username, input, reason string) bool {
+	// A user who is also an individual approver decides through their own entry
+	for i, approver := range at.Spec.Approvers {
+		if v1alpha1.DefaultedApproverType(approver.Type) == "User" && approver.Name == username {
+			at.Spec.Approvers[i].Input = input
+			at.Spec.Approvers[i].Message = reason
+			return true
+		}
+	}
+
+	found := false
+	for i, approver := range at.Spec.Approvers {
+		if v1alpha1.DefaultedApproverType(approver.Type) != "Group" {
+			continue
+		}
+		for j, user := range approver.Users {
+			if user.Name != username {
+				continue
+			}
+			// Group members only count while the group-level input is set
+			at.Spec.Approvers[i].Input = input
+			at.Spec.Approvers[i].Users[j].Input = input
+			at.Spec.Approvers[i].Users[j].Message = reason
+			found = true
+		}
+	}
+	return found
+}
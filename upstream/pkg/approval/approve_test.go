@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ktesting "k8s.io/client-go/testing"
+)
+
+func approvableTask() *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{
+					Name: "platform", Type: "Group", Input: "pending",
+					Users: []v1alpha1.UserDetails{{Name: "bob", Input: "pending"}, {Name: "alice", Input: "pending"}},
+				},
+			},
+		},
+	}
+}
+
+func getTask(t *testing.T, client *fake.Clientset) *v1alpha1.ApprovalTask {
+	t.Helper()
+	at, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.Background(), "deploy", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed getting approval task: %v", err)
+	}
+	return at
+}
+
+func TestApproveUserApprover(t *testing.T) {
+	client := fake.NewSimpleClientset(approvableTask())
+
+	assert.NoError(t, Approve(context.Background(), client, "production", "deploy", "alice", "approve", "lgtm"))
+
+	at := getTask(t, client)
+	assert.Equal(t, "approve", at.Spec.Approvers[0].Input)
+	assert.Equal(t, "lgtm", at.Spec.Approvers[0].Message)
+	assert.Equal(t, "pending", at.Spec.Approvers[1].Users[1].Input, "a User approver decides through their own entry only")
+}
+
+func TestApproveGroupMember(t *testing.T) {
+	client := fake.NewSimpleClientset(approvableTask())
+
+	assert.NoError(t, Approve(context.Background(), client, "production", "deploy", "bob", "reject", "not yet"))
+
+	at := getTask(t, client)
+	assert.Equal(t, "pending", at.Spec.Approvers[0].Input)
+	assert.Equal(t, "reject", at.Spec.Approvers[1].Input)
+	assert.Equal(t, v1alpha1.UserDetails{Name: "bob", Input: "reject", Message: "not yet"}, at.Spec.Approvers[1].Users[0])
+}
+
+func TestApproveRetriesOnConflict(t *testing.T) {
+	client := fake.NewSimpleClientset(approvableTask())
+	conflicts := 2
+	client.PrependReactor("update", "approvaltasks", func(action ktesting.Action) (bool, runtime.Object, error) {
+		if conflicts == 0 {
+			return false, nil, nil
+		}
+		conflicts--
+		// Another approver got in first
+		obj, err := client.Tracker().Get(v1alpha1.SchemeGroupVersion.WithResource("approvaltasks"), "production", "deploy")
+		if err != nil {
+			t.Fatalf("failed getting approval task from tracker: %v", err)
+		}
+		current := obj.(*v1alpha1.ApprovalTask).DeepCopy()
+		current.Spec.Approvers[1].Users[1].Input = "approve"
+		if err := client.Tracker().Update(v1alpha1.SchemeGroupVersion.WithResource("approvaltasks"), current, "production"); err != nil {
+			t.Fatalf("failed updating tracker: %v", err)
+		}
+		return true, nil, apierrors.NewConflict(v1alpha1.Resource("approvaltasks"), "deploy", nil)
+	})
+
+	assert.NoError(t, Approve(context.Background(), client, "production", "deploy", "bob", "approve", ""))
+
+	at := getTask(t, client)
+	assert.Equal(t, 0, conflicts)
+	assert.Equal(t, "approve", at.Spec.Approvers[1].Users[0].Input)
+	assert.Equal(t, "approve", at.Spec.Approvers[1].Users[1].Input, "concurrent decisions should not be overwritten")
+}
+
+func TestApproveErrors(t *testing.T) {
+	client := fake.NewSimpleClientset(approvableTask())
+
+	assert.EqualError(t, Approve(context.Background(), client, "production", "deploy", "mallory", "approve", ""),
+		"approver: mallory, is not present in the approvers list")
+	assert.Error(t, Approve(context.Background(), client, "production", "deploy", "alice", "maybe", ""))
+	assert.True(t, apierrors.IsNotFound(Approve(context.Background(), client, "production", "missing", "alice", "approve", "")))
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// MaxAwaitingLabels bounds the number of awaiting approver labels of an
+// approval task. Tasks awaiting more approvers carry the
+// v1alpha1.AwaitingOverflowLabelKey label instead.
+const MaxAwaitingLabels = 20
+
+// awaitingLabelValue is the value of the awaiting labels.
+const awaitingLabelValue = "true"
+
+// AwaitingIdentities returns the sorted identities of the approvers of a
+// pending, unpaused approval task who can still respond: the name of each
+// active User approver, the lowercased address of each active Email approver,
+// and "group:" and the name of each active Group approver, as long as they
+// have not responded. Group approvers are awaited until the group-level input
+// records a response, whichever members responded.
+func AwaitingIdentities(approvalTask v1alpha1.ApprovalTask) []string {
+	if (approvalTask.Status.State != "" && approvalTask.Status.State != "pending") || approvalTask.Spec.Paused {
+		return nil
+	}
+	var identities []string
+	for _, approver := range approvalTask.Spec.Approvers {
+		if respondedWith(approver.Input) || !ApproverActive(approvalTask, approver) {
+			continue
+		}
+		var identity string
+		switch v1alpha1.DefaultedApproverType(approver.Type) {
+		case "User":
+			identity = approver.Name
+		case "Email":
+			identity = strings.ToLower(approver.Name)
+		case "Group":
+			identity = "group:" + approver.Name
+		default:
+			continue
+		}
+		if !slices.Contains(identities, identity) {
+			identities = append(identities, identity)
+		}
+	}
+	slices.Sort(identities)
+	return identities
+}
+
+// AwaitingLabelKey returns the label a pending approval task awaiting the
+// identity carries: a username, a lowercased email, or "group:" and the name
+// of a group. The identity is hashed to fit the label syntax, so tasks
+// selected by the label are candidates whose approvers still need to be
+// checked.
+func AwaitingLabelKey(identity string) string {
+	sum := sha256.Sum256([]byte(identity))
+	return v1alpha1.AwaitingApproverLabelPrefix + hex.EncodeToString(sum[:8])
+}
+
+// IsAwaitingLabel reports whether the label is one of the awaiting labels
+// the controller maintains.
+func IsAwaitingLabel(key string) bool {
+	return strings.HasPrefix(key, v1alpha1.AwaitingApproverLabelPrefix) || key == v1alpha1.AwaitingOverflowLabelKey
+}
+
+// AwaitingLabels returns the awaiting labels the approval task should carry:
+// one per identity of AwaitingIdentities, or only the overflow label when
+// there are more than MaxAwaitingLabels.
+func AwaitingLabels(approvalTask v1alpha1.ApprovalTask) map[string]string {
+	identities := AwaitingIdentities(approvalTask)
+	if len(identities) > MaxAwaitingLabels {
+		return map[string]string{v1alpha1.AwaitingOverflowLabelKey: awaitingLabelValue}
+	}
+	labels := make(map[string]string, len(identities))
+	for _, identity := range identities {
+		labels[AwaitingLabelKey(identity)] = awaitingLabelValue
+	}
+	return labels
+}
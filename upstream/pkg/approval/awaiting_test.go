@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func awaitingApprovalTask() v1alpha1.ApprovalTask {
+	return v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "bob", Type: "User", Input: "approve"},
+				{Name: "Carol@Example.com", Type: "Email", Input: "pending"},
+				{Name: "platform", Type: "Group", Input: "pending"},
+				{Name: "dave", Type: "User", Input: "pending", Backup: true},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func TestAwaitingIdentities(t *testing.T) {
+	at := awaitingApprovalTask()
+	assert.Equal(t, []string{"alice", "carol@example.com", "group:platform"}, AwaitingIdentities(at),
+		"responded and inactive approvers are not awaited")
+
+	at.Spec.Approvers[0].Input = "reject"
+	assert.Equal(t, []string{"carol@example.com", "group:platform"}, AwaitingIdentities(at))
+
+	at.Spec.Paused = true
+	assert.Empty(t, AwaitingIdentities(at), "paused tasks await nobody")
+
+	at = awaitingApprovalTask()
+	at.Status.State = "approved"
+	assert.Empty(t, AwaitingIdentities(at), "final tasks await nobody")
+}
+
+func TestAwaitingLabels(t *testing.T) {
+	key := AwaitingLabelKey("alice")
+	assert.Equal(t, key, AwaitingLabelKey("alice"))
+	assert.NotEqual(t, key, AwaitingLabelKey("group:alice"))
+	assert.Empty(t, validation.IsQualifiedName(key))
+	assert.True(t, IsAwaitingLabel(key))
+	assert.True(t, IsAwaitingLabel(v1alpha1.AwaitingOverflowLabelKey))
+	assert.False(t, IsAwaitingLabel(v1alpha1.ApprovalIdentityLabelKey))
+
+	assert.Equal(t, map[string]string{
+		AwaitingLabelKey("alice"):             "true",
+		AwaitingLabelKey("carol@example.com"): "true",
+		AwaitingLabelKey("group:platform"):    "true",
+	}, AwaitingLabels(awaitingApprovalTask()))
+
+	at := v1alpha1.ApprovalTask{Status: v1alpha1.ApprovalTaskStatus{State: "pending"}}
+	for i := 0; i <= MaxAwaitingLabels; i++ {
+		at.Spec.Approvers = append(at.Spec.Approvers, v1alpha1.ApproverDetails{Name: fmt.Sprintf("user-%d", i), Type: "User", Input: "pending"})
+	}
+	assert.Equal(t, map[string]string{v1alpha1.AwaitingOverflowLabelKey: "true"}, AwaitingLabels(at))
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+const responseApproved = "approved"
+
+// CarriedApproval reports whether the individual (User or Email) approver
+// holds an approval carried forward from a prior approval task (see
+// v1alpha1.ApproverState.CarriedFrom). It only does while the approver has
+// not decided on the approval task itself.
+func CarriedApproval(approvalTask v1alpha1.ApprovalTask, approver v1alpha1.ApproverDetails) bool {
+	if !v1alpha1.IsIndividualApproverType(approver.Type) || approver.Input == inputApprove || approver.Input == inputReject || approver.Input == inputRequestChanges {
+		return false
+	}
+	for _, response := range approvalTask.Status.ApproversResponse {
+		if response.Name == approver.Name && v1alpha1.DefaultedApproverType(response.Type) == v1alpha1.DefaultedApproverType(approver.Type) {
+			return response.CarriedFrom != "" && response.Response == responseApproved
+		}
+	}
+	return false
+}
+
+// individualApproved reports whether the individual approver approved the
+// approval task, itself or through a carried approval.
+func individualApproved(approvalTask v1alpha1.ApprovalTask, approver v1alpha1.ApproverDetails) bool {
+	return approver.Input == inputApprove || CarriedApproval(approvalTask, approver)
+}
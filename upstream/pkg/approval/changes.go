@@ -0,0 +1,50 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// inputRequestChanges blocks the approval of a task without rejecting it,
+// until the approver who submitted it switches to another input.
+const inputRequestChanges = "request-changes"
+
+// ChangesRequestedBy returns, in the order of Spec.Approvers, the active
+// User and Email approvers and the members of active Group approvers whose
+// input is "request-changes". The approval task cannot reach its quorum
+// while any of them holds it, but it is not rejected either.
+func ChangesRequestedBy(approvalTask v1alpha1.ApprovalTask) []string {
+	var names []string
+	for _, approver := range approvalTask.Spec.Approvers {
+		if !ApproverActive(approvalTask, approver) {
+			continue
+		}
+		if v1alpha1.IsIndividualApproverType(approver.Type) {
+			if approver.Input == inputRequestChanges {
+				names = append(names, approver.Name)
+			}
+			continue
+		}
+		for _, user := range approver.Users {
+			if user.Input == inputRequestChanges {
+				names = append(names, user.Name)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func changesApprovalTask() v1alpha1.ApprovalTask {
+	return v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+				{Name: "bob", Type: "User", Input: "approve"},
+				{Name: "carol", Type: "User", Input: "request-changes"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func TestChangesRequestedBlockQuorum(t *testing.T) {
+	at := changesApprovalTask()
+	assert.Equal(t, []string{"carol"}, ChangesRequestedBy(at))
+	assert.Equal(t, 2, CountApprovals(at))
+	assert.False(t, QuorumReached(at), "carol requested changes")
+	assert.False(t, Unsatisfiable(at), "requesting changes is not a rejection")
+	assert.Equal(t, "2/2 approved, changes requested by carol.", StatusSummary(at))
+
+	at.Spec.Approvers[2].Input = "approve"
+	assert.Empty(t, ChangesRequestedBy(at))
+	assert.True(t, QuorumReached(at))
+}
+
+func TestChangesRequestedByGroupMember(t *testing.T) {
+	at := changesApprovalTask()
+	at.Spec.Approvers[2] = v1alpha1.ApproverDetails{Name: "platform", Type: "Group", Input: "request-changes", Users: []v1alpha1.UserDetails{
+		{Name: "dave", Input: "request-changes"},
+	}}
+	assert.Equal(t, []string{"dave"}, ChangesRequestedBy(at))
+	assert.False(t, QuorumReached(at))
+
+	// Inactive approvers do not block the task
+	at.Spec.Approvers[2].WhenLabels = map[string]string{"risk": "high"}
+	assert.Empty(t, ChangesRequestedBy(at))
+	assert.True(t, QuorumReached(at))
+}
+
+func TestChangesRequestedDoNotOutvote(t *testing.T) {
+	at := changesApprovalTask()
+	at.Spec.MixedResolution = v1alpha1.MixedResolutionWaitForAll
+	at.Spec.Approvers[1].Input = "reject"
+	assert.False(t, AllResponded(at), "carol may still approve")
+	assert.False(t, OutvotedAt(at, at.CreationTimestamp.Time))
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// ApproverActive reports whether the approver takes part in the approval
+// task: every label of its WhenLabels is set on the task with the same value.
+// Approvers without conditions are always active, unless their type is not
+// one of v1alpha1.KnownApproverTypes: these do not count until it is
+// corrected. Backup approvers are only active once the task escalated.
+func ApproverActive(approvalTask v1alpha1.ApprovalTask, approver v1alpha1.ApproverDetails) bool {
+	if !v1alpha1.IsKnownApproverType(approver.Type) {
+		return false
+	}
+	if approver.Backup && !Escalated(approvalTask) {
+		return false
+	}
+	for key, value := range approver.WhenLabels {
+		if actual, ok := approvalTask.Labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// HasConditionalApprovers reports whether any approver of the approval task
+// depends on its labels.
+func HasConditionalApprovers(approvalTask v1alpha1.ApprovalTask) bool {
+	for _, approver := range approvalTask.Spec.Approvers {
+		if len(approver.WhenLabels) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func conditionalApprovalTask(labels map[string]string) v1alpha1.ApprovalTask {
+	return v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Labels: labels},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+				{Name: "security", Type: "User", Input: "approve", WhenLabels: map[string]string{"risk": "high"}},
+				{Name: "bob", Type: "User", Input: "pending", AllowedInputs: []string{"reject"}},
+			},
+		},
+	}
+}
+
+func TestApproverActive(t *testing.T) {
+	approver := v1alpha1.ApproverDetails{Name: "security", WhenLabels: map[string]string{"risk": "high", "env": "prod"}}
+	assert.True(t, ApproverActive(conditionalApprovalTask(map[string]string{"risk": "high", "env": "prod", "team": "a"}), approver))
+	assert.False(t, ApproverActive(conditionalApprovalTask(map[string]string{"risk": "high"}), approver), "every label must match")
+	assert.False(t, ApproverActive(conditionalApprovalTask(map[string]string{"risk": "low", "env": "prod"}), approver))
+	assert.True(t, ApproverActive(conditionalApprovalTask(nil), v1alpha1.ApproverDetails{Name: "alice"}), "unconditional approvers are always active")
+}
+
+func TestInactiveApproversAreExcludedFromQuorum(t *testing.T) {
+	high := conditionalApprovalTask(map[string]string{"risk": "high"})
+	assert.Equal(t, 2, CountApprovals(high))
+	assert.True(t, QuorumReached(high))
+
+	low := conditionalApprovalTask(map[string]string{"risk": "low"})
+	assert.Equal(t, 1, CountApprovals(low), "the approval of the inactive security approver must not count")
+	assert.False(t, QuorumReached(low))
+	attainable, ok := MaxAttainableApprovals(low)
+	assert.True(t, ok)
+	assert.Equal(t, 1, attainable)
+	assert.True(t, Unsatisfiable(low))
+}
+
+func TestInactiveApproversDoNotBlock(t *testing.T) {
+	at := conditionalApprovalTask(nil)
+	at.Spec.Approvers[1].Input = "pending"
+	at.Spec.Approvers[1].Priority = 1
+
+	_, blocked := BlockingApproverAt(at, at.Spec.Approvers[0], time.Now())
+	assert.False(t, blocked, "an inactive approver of higher priority must not hold back approvals")
+
+	at.Labels = map[string]string{"risk": "high"}
+	_, blocked = BlockingApproverAt(at, at.Spec.Approvers[0], time.Now())
+	assert.True(t, blocked)
+}
+
+func TestUnknownApproverTypesDoNotCount(t *testing.T) {
+	at := v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+				{Name: "deploy-bot", Type: "Robot", Input: "approve"},
+			},
+		},
+	}
+	assert.False(t, ApproverActive(at, at.Spec.Approvers[1]))
+	assert.Equal(t, 1, CountApprovals(at))
+	assert.False(t, QuorumReached(at))
+
+	at.Spec.Approvers[1].Type = "User"
+	assert.True(t, QuorumReached(at), "the approval counts once the type is corrected")
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Lapsed reports whether an approval observed at respondedAt, and last
+// renewed at renewTime, has outlived expiresAfter at now. Approvals without
+// an expiry, or not yet observed by the controller, never lapse.
+func Lapsed(expiresAfter *metav1.Duration, respondedAt, renewTime *metav1.Time, now time.Time) bool {
+	lapsesAt, ok := lapseTime(expiresAfter, respondedAt, renewTime)
+	return ok && now.After(lapsesAt)
+}
+
+// lapseTime returns when an approval observed at respondedAt, and last renewed
+// at renewTime, lapses.
+func lapseTime(expiresAfter *metav1.Duration, respondedAt, renewTime *metav1.Time) (time.Time, bool) {
+	if expiresAfter == nil || expiresAfter.Duration <= 0 || respondedAt == nil {
+		return time.Time{}, false
+	}
+	last := respondedAt.Time
+	if renewTime != nil && renewTime.After(last) {
+		last = renewTime.Time
+	}
+	return last.Add(expiresAfter.Duration), true
+}
+
+// NextExpiry returns the earliest time after now at which one of the approvals
+// of the task lapses, if any approval is due to lapse.
+func NextExpiry(approvalTask v1alpha1.ApprovalTask, now time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	consider := func(at time.Time, ok bool) {
+		if ok && at.After(now) && (!found || at.Before(next)) {
+			next, found = at, true
+		}
+	}
+
+	for _, approver := range approvalTask.Spec.Approvers {
+		if approver.ApprovalExpiresAfter == nil {
+			continue
+		}
+		for _, response := range approvalTask.Status.ApproversResponse {
+			if response.Name != approver.Name || v1alpha1.DefaultedApproverType(response.Type) != v1alpha1.DefaultedApproverType(approver.Type) {
+				continue
+			}
+			switch v1alpha1.DefaultedApproverType(approver.Type) {
+			case "User", "Email":
+				if individualApproved(approvalTask, approver) {
+					consider(lapseTime(approver.ApprovalExpiresAfter, response.RespondedAt, approver.RenewTime))
+				}
+			case "Group":
+				for _, user := range approver.Users {
+					if user.Input != inputApprove {
+						continue
+					}
+					for _, member := range response.GroupMembers {
+						if member.Name == user.Name {
+							consider(lapseTime(approver.ApprovalExpiresAfter, member.RespondedAt, user.RenewTime))
+						}
+					}
+				}
+			}
+		}
+	}
+	return next, found
+}
+
+// UserApprovalLapsed reports whether the approval of an individual (User or
+// Email) approver has lapsed.
+func UserApprovalLapsed(approvalTask v1alpha1.ApprovalTask, approver v1alpha1.ApproverDetails, now time.Time) bool {
+	if approver.ApprovalExpiresAfter == nil {
+		return false
+	}
+	for _, response := range approvalTask.Status.ApproversResponse {
+		if response.Name == approver.Name && v1alpha1.DefaultedApproverType(response.Type) == v1alpha1.DefaultedApproverType(approver.Type) {
+			return Lapsed(approver.ApprovalExpiresAfter, response.RespondedAt, approver.RenewTime, now)
+		}
+	}
+	return false
+}
+
+// GroupMemberApprovalLapsed reports whether the approval of a member of a
+// Group approver has lapsed.
+func GroupMemberApprovalLapsed(approvalTask v1alpha1.ApprovalTask, approver v1alpha1.ApproverDetails, user v1alpha1.UserDetails, now time.Time) bool {
+	if approver.ApprovalExpiresAfter == nil {
+		return false
+	}
+	for _, response := range approvalTask.Status.ApproversResponse {
+		if response.Name != approver.Name || v1alpha1.DefaultedApproverType(response.Type) != "Group" {
+			continue
+		}
+		for _, member := range response.GroupMembers {
+			if member.Name == user.Name {
+				return Lapsed(approver.ApprovalExpiresAfter, member.RespondedAt, user.RenewTime, now)
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// ParseLevel returns the seniority level recorded with an approval. Levels
+// are non-negative integers; a missing, malformed or negative level is the
+// lowest level, zero.
+func ParseLevel(level string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(level))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// meetsMinLevel reports whether an approval recorded with level counts
+// towards the quorum of the approval task under Spec.MinApproverLevel.
+func meetsMinLevel(approvalTask v1alpha1.ApprovalTask, level string) bool {
+	return approvalTask.Spec.MinApproverLevel <= 0 || ParseLevel(level) >= approvalTask.Spec.MinApproverLevel
+}
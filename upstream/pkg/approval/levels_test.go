@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func levelApprovalTask() v1alpha1.ApprovalTask {
+	return v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			MinApproverLevel:          3,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve", Level: "2"},
+				{Name: "bob", Type: "User", Input: "approve"},
+				{Name: "platform", Type: "Group", Input: "pending", Users: []v1alpha1.UserDetails{
+					{Name: "carol", Input: "pending", Level: "4"},
+				}},
+				{Name: "dave", Type: "User", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	assert.Equal(t, 3, ParseLevel("3"))
+	assert.Equal(t, 3, ParseLevel(" 3 "))
+	assert.Equal(t, 0, ParseLevel(""))
+	assert.Equal(t, 0, ParseLevel("senior"))
+	assert.Equal(t, 0, ParseLevel("-2"))
+}
+
+func TestCountApprovalsMinApproverLevel(t *testing.T) {
+	at := levelApprovalTask()
+	assert.Equal(t, 0, CountApprovals(at), "alice is below the level and bob recorded none")
+	assert.False(t, QuorumReached(at))
+
+	at.Spec.Approvers[2].Input = "approve"
+	at.Spec.Approvers[2].Users[0].Input = "approve"
+	assert.Equal(t, 1, CountApprovals(at))
+	assert.False(t, QuorumReached(at), "a second approval of the level is required")
+
+	at.Spec.Approvers[3].Input = "approve"
+	at.Spec.Approvers[3].Level = "3"
+	assert.Equal(t, 2, CountApprovals(at))
+	assert.True(t, QuorumReached(at))
+
+	at.Spec.MinApproverLevel = 0
+	assert.Equal(t, 4, CountApprovals(at), "levels do not matter without MinApproverLevel")
+}
+
+func TestCountApprovalsMinApproverLevelMalformed(t *testing.T) {
+	at := levelApprovalTask()
+	at.Spec.MinApproverLevel = 1
+	at.Spec.Approvers[1].Level = "lead"
+	assert.Equal(t, 1, CountApprovals(at), "only alice's level parses, bob's counts as the lowest")
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EscalationDueAt returns when the approval task escalates to its backup
+// approvers, Spec.EscalateAfter after its start (see scheduleStart), if it
+// escalates at all.
+func EscalationDueAt(approvalTask v1alpha1.ApprovalTask) (time.Time, bool) {
+	return afterStart(approvalTask, approvalTask.Spec.EscalateAfter)
+}
+
+// LifetimeEndsAt returns when the approval task expires, Spec.MaxPendingLifetime
+// after its start, if it has a maximum pending lifetime.
+func LifetimeEndsAt(approvalTask v1alpha1.ApprovalTask) (time.Time, bool) {
+	return afterStart(approvalTask, approvalTask.Spec.MaxPendingLifetime)
+}
+
+// Escalated reports whether the controller escalated the approval task to
+// its backup approvers.
+func Escalated(approvalTask v1alpha1.ApprovalTask) bool {
+	return approvalTask.Status.EscalatedAt != nil
+}
+
+// activeOrPendingEscalation reports whether the approver takes part in the
+// approval task, or will once the task escalates: a backup approver of a task
+// that is yet to escalate is evaluated as if it had.
+func activeOrPendingEscalation(approvalTask v1alpha1.ApprovalTask, approver v1alpha1.ApproverDetails) bool {
+	if approver.Backup && !Escalated(approvalTask) && approvalTask.Spec.EscalateAfter != nil {
+		approvalTask.Status.EscalatedAt = &metav1.Time{}
+	}
+	return ApproverActive(approvalTask, approver)
+}
+
+// NextLifecycleTransition returns the earliest time after now at which the
+// approval task escalates or expires, if any is left.
+func NextLifecycleTransition(approvalTask v1alpha1.ApprovalTask, now time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	for _, due := range []func(v1alpha1.ApprovalTask) (time.Time, bool){EscalationDueAt, LifetimeEndsAt} {
+		if at, ok := due(approvalTask); ok && at.After(now) && (!found || at.Before(next)) {
+			next, found = at, true
+		}
+	}
+	return next, found
+}
+
+// afterStart returns the time d after the start of the approval task.
+func afterStart(approvalTask v1alpha1.ApprovalTask, d *metav1.Duration) (time.Time, bool) {
+	if d == nil {
+		return time.Time{}, false
+	}
+	start, ok := scheduleStart(approvalTask)
+	if !ok {
+		return time.Time{}, false
+	}
+	return start.Add(d.Duration), true
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func escalatingApprovalTask(start time.Time) v1alpha1.ApprovalTask {
+	return v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			EscalateAfter:             &metav1.Duration{Duration: time.Hour},
+			MaxPendingLifetime:        &metav1.Duration{Duration: 4 * time.Hour},
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "bob", Type: "User", Input: "approve", Backup: true},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{StartTime: &metav1.Time{Time: start}},
+	}
+}
+
+func TestLifecycleTransitions(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	at := escalatingApprovalTask(start)
+
+	escalation, ok := EscalationDueAt(at)
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(time.Hour), escalation)
+	end, ok := LifetimeEndsAt(at)
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(4*time.Hour), end)
+
+	next, ok := NextLifecycleTransition(at, start)
+	assert.True(t, ok)
+	assert.Equal(t, escalation, next)
+	next, ok = NextLifecycleTransition(at, escalation)
+	assert.True(t, ok)
+	assert.Equal(t, end, next, "the escalation is behind")
+	_, ok = NextLifecycleTransition(at, end)
+	assert.False(t, ok)
+
+	at.Spec.EscalateAfter, at.Spec.MaxPendingLifetime = nil, nil
+	_, ok = EscalationDueAt(at)
+	assert.False(t, ok)
+	_, ok = NextLifecycleTransition(at, start)
+	assert.False(t, ok)
+
+	_, ok = EscalationDueAt(v1alpha1.ApprovalTask{Spec: escalatingApprovalTask(start).Spec})
+	assert.False(t, ok, "tasks that did not start do not escalate")
+}
+
+func TestBackupApproversActiveOnceEscalated(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	at := escalatingApprovalTask(start)
+	backup := at.Spec.Approvers[1]
+
+	assert.False(t, Escalated(at))
+	assert.False(t, ApproverActive(at, backup))
+	assert.True(t, ApproverActive(at, at.Spec.Approvers[0]))
+	assert.Equal(t, 0, CountApprovalsAt(at, start.Add(2*time.Hour)), "backups do not count until the task escalates")
+
+	escalatedAt := metav1.NewTime(start.Add(time.Hour))
+	at.Status.EscalatedAt = &escalatedAt
+	assert.True(t, Escalated(at))
+	assert.True(t, ApproverActive(at, backup))
+	assert.Equal(t, 1, CountApprovalsAt(at, start.Add(2*time.Hour)))
+}
+
+func TestBackupApproversAttainableBeforeEscalation(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	at := escalatingApprovalTask(start)
+	at.Spec.NumberOfApprovalsRequired = 2
+	at.Spec.Approvers = []v1alpha1.ApproverDetails{
+		{Name: "alice", Type: "User", Input: "pending"},
+		{Name: "bob", Type: "User", Input: "pending", Backup: true},
+		{Name: "carol", Type: "User", Input: "pending", Backup: true},
+	}
+
+	attainable, ok := MaxAttainableApprovals(at)
+	assert.True(t, ok)
+	assert.Equal(t, 3, attainable, "the backups can still approve once the task escalates")
+	assert.False(t, Unsatisfiable(at))
+
+	at.Spec.Approvers[0].Input = "approve"
+	at.Spec.MixedResolution = v1alpha1.MixedResolutionWaitForAll
+	assert.False(t, AllResponded(at), "the backups have yet to respond")
+
+	// Without an escalation, backups never take part
+	at.Spec.EscalateAfter = nil
+	attainable, _ = MaxAttainableApprovals(at)
+	assert.Equal(t, 1, attainable)
+	assert.True(t, Unsatisfiable(at))
+	assert.True(t, AllResponded(at))
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// RejectionVetoes reports whether a single rejection rejects the approval
+// task, which is the case unless Spec.MixedResolution is set.
+func RejectionVetoes(approvalTask v1alpha1.ApprovalTask) bool {
+	return approvalTask.Spec.MixedResolution == ""
+}
+
+// FailsEarly reports whether the approval task is rejected as soon as it is
+// Unsatisfiable, rather than once every approver responded. Only tasks with
+// Spec.MixedResolution set to WaitForAll wait.
+func FailsEarly(approvalTask v1alpha1.ApprovalTask) bool {
+	return approvalTask.Spec.MixedResolution != v1alpha1.MixedResolutionWaitForAll
+}
+
+// AllResponded reports whether every active approver of the approval task
+// has approved or rejected it. A Group approver responds through the first
+// of its members to decide. Backup approvers have yet to respond while the
+// task is yet to escalate to them.
+func AllResponded(approvalTask v1alpha1.ApprovalTask) bool {
+	for _, approver := range approvalTask.Spec.Approvers {
+		if !activeOrPendingEscalation(approvalTask, approver) {
+			continue
+		}
+		if approver.Input == inputApprove || approver.Input == inputReject {
+			continue
+		}
+		if v1alpha1.IsIndividualApproverType(approver.Type) && CarriedApproval(approvalTask, approver) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// OutvotedAt reports whether the approval task, whose rejections do not veto
+// it (see RejectionVetoes), is rejected at now: every approver responded and
+// the approvals fall short of its quorum.
+func OutvotedAt(approvalTask v1alpha1.ApprovalTask, now time.Time) bool {
+	return !RejectionVetoes(approvalTask) && AllResponded(approvalTask) && !QuorumReachedAt(approvalTask, now)
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func mixedApprovalTask(resolution string, inputs ...string) v1alpha1.ApprovalTask {
+	at := v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			MixedResolution:           resolution,
+		},
+	}
+	for i, name := range []string{"alice", "bob", "carol"} {
+		at.Spec.Approvers = append(at.Spec.Approvers, v1alpha1.ApproverDetails{Name: name, Type: "User", Input: inputs[i]})
+	}
+	return at
+}
+
+func TestAllResponded(t *testing.T) {
+	assert.False(t, AllResponded(mixedApprovalTask("", "approve", "reject", "pending")))
+	assert.True(t, AllResponded(mixedApprovalTask("", "approve", "reject", "reject")))
+}
+
+func TestOutvotedAt(t *testing.T) {
+	now := time.Now()
+	assert.False(t, OutvotedAt(mixedApprovalTask(v1alpha1.MixedResolutionWaitForAll, "reject", "reject", "pending"), now), "carol has not responded yet")
+	assert.True(t, OutvotedAt(mixedApprovalTask(v1alpha1.MixedResolutionWaitForAll, "reject", "reject", "approve"), now))
+	assert.False(t, OutvotedAt(mixedApprovalTask(v1alpha1.MixedResolutionWaitForAll, "reject", "approve", "approve"), now), "the quorum was reached")
+	assert.False(t, OutvotedAt(mixedApprovalTask("", "reject", "reject", "approve"), now), "vetoed tasks are rejected by the veto")
+}
+
+func TestUnsatisfiableAfterRejections(t *testing.T) {
+	at := mixedApprovalTask(v1alpha1.MixedResolutionFailFast, "reject", "pending", "pending")
+	attainable, ok := MaxAttainableApprovals(at)
+	assert.True(t, ok)
+	assert.Equal(t, 2, attainable)
+	assert.False(t, Unsatisfiable(at))
+
+	at.Spec.Approvers[1].Input = "reject"
+	assert.True(t, Unsatisfiable(at), "carol alone cannot reach two approvals")
+
+	assert.True(t, FailsEarly(at))
+	assert.True(t, FailsEarly(mixedApprovalTask("", "pending", "pending", "pending")))
+	assert.False(t, FailsEarly(mixedApprovalTask(v1alpha1.MixedResolutionWaitForAll, "pending", "pending", "pending")))
+}
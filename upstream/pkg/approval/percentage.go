@@ -0,0 +1,58 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// BaseApprovalsRequired returns the number of approvals the approval task
+// requires before its quorum schedule and policy minimum apply:
+// Spec.NumberOfApprovalsRequired, or the share of its eligible approvers
+// Spec.ApprovalPercentage amounts to when set, rounded up and at least one.
+// Tasks setting neither, such as ones created before the field was
+// validated, require every eligible approver rather than none (see
+// ApprovalsRequiredUnset).
+func BaseApprovalsRequired(approvalTask v1alpha1.ApprovalTask) int {
+	percentage := approvalTask.Spec.ApprovalPercentage
+	if ApprovalsRequiredUnset(approvalTask.Spec) {
+		percentage = 100
+	} else if percentage <= 0 {
+		return approvalTask.Spec.NumberOfApprovalsRequired
+	}
+	required := (percentage*EligibleApprovers(approvalTask) + 99) / 100
+	return max(required, 1)
+}
+
+// ApprovalsRequiredUnset reports whether the spec sets neither
+// NumberOfApprovalsRequired nor ApprovalPercentage.
+func ApprovalsRequiredUnset(spec v1alpha1.ApprovalTaskSpec) bool {
+	return spec.NumberOfApprovalsRequired <= 0 && spec.ApprovalPercentage <= 0
+}
+
+// EligibleApprovers returns the number of approvers a percentage is computed
+// against: the active approvers of the approval task (see ApproverActive).
+// Each Group approver counts as one, like each User or Email approver.
+func EligibleApprovers(approvalTask v1alpha1.ApprovalTask) int {
+	eligible := 0
+	for _, approver := range approvalTask.Spec.Approvers {
+		if ApproverActive(approvalTask, approver) {
+			eligible++
+		}
+	}
+	return eligible
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func percentageApprovalTask(percentage int) v1alpha1.ApprovalTask {
+	return v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			ApprovalPercentage: percentage,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+				{Name: "bob", Type: "User", Input: "approve"},
+				{Name: "carol", Type: "User", Input: "pending"},
+				{Name: "dave", Type: "User", Input: "pending"},
+				{Name: "erin", Type: "User", Input: "pending"},
+			},
+		},
+	}
+}
+
+func TestBaseApprovalsRequired(t *testing.T) {
+	assert.Equal(t, 3, BaseApprovalsRequired(percentageApprovalTask(60)))
+	assert.Equal(t, 3, BaseApprovalsRequired(percentageApprovalTask(41)), "the count is rounded up")
+	assert.Equal(t, 5, BaseApprovalsRequired(percentageApprovalTask(100)))
+	assert.Equal(t, 1, BaseApprovalsRequired(percentageApprovalTask(1)), "at least one approval is required")
+
+	absolute := percentageApprovalTask(0)
+	absolute.Spec.NumberOfApprovalsRequired = 2
+	assert.Equal(t, 2, BaseApprovalsRequired(absolute))
+}
+
+func TestPercentageFollowsApprovers(t *testing.T) {
+	at := percentageApprovalTask(60)
+	assert.False(t, QuorumReached(at))
+
+	// Removing an approver lowers the count: 60% of 3 is 2
+	at.Spec.Approvers = at.Spec.Approvers[:3]
+	assert.Equal(t, 2, BaseApprovalsRequired(at))
+	assert.True(t, QuorumReached(at))
+
+	// Adding approvers raises it again: 60% of 6 is 4
+	at.Spec.Approvers = append(at.Spec.Approvers,
+		v1alpha1.ApproverDetails{Name: "frank", Type: "User", Input: "pending"},
+		v1alpha1.ApproverDetails{Name: "grace", Type: "User", Input: "pending"},
+		v1alpha1.ApproverDetails{Name: "release", Type: "Group", Input: "pending"},
+	)
+	assert.Equal(t, 4, BaseApprovalsRequired(at))
+	assert.False(t, QuorumReached(at))
+}
+
+func TestPercentageIgnoresInactiveApprovers(t *testing.T) {
+	at := percentageApprovalTask(60)
+	for i := 2; i < len(at.Spec.Approvers); i++ {
+		at.Spec.Approvers[i].WhenLabels = map[string]string{"risk": "high"}
+	}
+	assert.Equal(t, 2, EligibleApprovers(at))
+	assert.True(t, QuorumReached(at), "60% of the two active approvers is two")
+
+	at.ObjectMeta = metav1.ObjectMeta{Labels: map[string]string{"risk": "high"}}
+	assert.Equal(t, 5, EligibleApprovers(at))
+	assert.False(t, QuorumReached(at))
+}
+
+func TestPercentageRaisedToPolicyMinimum(t *testing.T) {
+	at := percentageApprovalTask(20)
+	assert.True(t, QuorumReached(at))
+	at.Status.Policy = &v1alpha1.PolicyRequirements{MinApprovalsRequired: 3}
+	assert.Equal(t, 3, MinRequiredApprovals(at))
+	assert.False(t, QuorumReached(at))
+
+	spec := at.Spec
+	ApplyPolicy(&spec, at.Status.Policy)
+	assert.Zero(t, spec.NumberOfApprovalsRequired, "the percentage stays the only requirement")
+	assert.Empty(t, PolicyShortfalls(spec, at.Status.Policy))
+}
+
+func TestUnsetApprovalsRequiredRequiresEveryApprover(t *testing.T) {
+	at := percentageApprovalTask(0)
+	assert.True(t, ApprovalsRequiredUnset(at.Spec))
+	assert.Equal(t, 5, BaseApprovalsRequired(at))
+	assert.False(t, QuorumReached(at), "a task setting no number must not be approved by the responses it has")
+
+	at.Spec.Approvers = at.Spec.Approvers[:2]
+	assert.True(t, QuorumReached(at))
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PolicySelects reports whether the cluster approval policy applies to the
+// approval task. A policy whose selector cannot be parsed selects nothing.
+func PolicySelects(policy *v1alpha1.ClusterApprovalPolicy, approvalTask v1alpha1.ApprovalTask) bool {
+	if len(policy.Spec.Namespaces) > 0 && !contains(policy.Spec.Namespaces, approvalTask.Namespace) {
+		return false
+	}
+	if policy.Spec.Selector == nil {
+		return true
+	}
+	selector, err := metav1.LabelSelectorAsSelector(policy.Spec.Selector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(approvalTask.Labels))
+}
+
+// PolicyRequirementsFor combines the policies that select the approval task:
+// the highest minimum number of approvals and every required group apply.
+// It returns nil when none of the policies selects the task.
+func PolicyRequirementsFor(approvalTask v1alpha1.ApprovalTask, policies []*v1alpha1.ClusterApprovalPolicy) *v1alpha1.PolicyRequirements {
+	var requirements *v1alpha1.PolicyRequirements
+	groups := make(map[string]bool)
+	for _, policy := range policies {
+		if !PolicySelects(policy, approvalTask) {
+			continue
+		}
+		if requirements == nil {
+			requirements = &v1alpha1.PolicyRequirements{}
+		}
+		requirements.Policies = append(requirements.Policies, policy.Name)
+		if policy.Spec.MinApprovalsRequired > requirements.MinApprovalsRequired {
+			requirements.MinApprovalsRequired = policy.Spec.MinApprovalsRequired
+		}
+		for _, group := range policy.Spec.RequiredGroups {
+			if !groups[group] {
+				groups[group] = true
+				requirements.RequiredGroups = append(requirements.RequiredGroups, group)
+			}
+		}
+	}
+	if requirements != nil {
+		sort.Strings(requirements.Policies)
+		sort.Strings(requirements.RequiredGroups)
+	}
+	return requirements
+}
+
+// PolicyShortfalls describes how the spec of an approval task falls short of
+// the policy requirements. It is empty when the spec is at least as strict.
+func PolicyShortfalls(spec v1alpha1.ApprovalTaskSpec, requirements *v1alpha1.PolicyRequirements) []string {
+	if requirements == nil {
+		return nil
+	}
+	var problems []string
+	// A percentage is raised to the minimum when evaluated instead (see
+	// RequiredApprovalsAt)
+	if spec.ApprovalPercentage <= 0 && spec.NumberOfApprovalsRequired < requirements.MinApprovalsRequired {
+		problems = append(problems, fmt.Sprintf("numberOfApprovalsRequired: must be at least %d, got %d",
+			requirements.MinApprovalsRequired, spec.NumberOfApprovalsRequired))
+	}
+	for i, step := range spec.QuorumSchedule {
+		if step.NumberOfApprovalsRequired < requirements.MinApprovalsRequired {
+			problems = append(problems, fmt.Sprintf("quorumSchedule[%d].numberOfApprovalsRequired: must be at least %d, got %d",
+				i, requirements.MinApprovalsRequired, step.NumberOfApprovalsRequired))
+		}
+	}
+	for _, group := range requirements.RequiredGroups {
+		if !hasGroupApprover(spec.Approvers, group) {
+			problems = append(problems, fmt.Sprintf("approvers: group '%s' must be an approver", group))
+		}
+	}
+	return problems
+}
+
+// ApplyPolicy tightens the spec of an approval task to the policy
+// requirements: it raises the number of approvals required, unless it is a
+// percentage, drops the steps
+// of the quorum schedule that would relax it below the minimum and adds the
+// required groups that are not approvers yet.
+func ApplyPolicy(spec *v1alpha1.ApprovalTaskSpec, requirements *v1alpha1.PolicyRequirements) {
+	if requirements == nil {
+		return
+	}
+	if spec.ApprovalPercentage <= 0 && spec.NumberOfApprovalsRequired < requirements.MinApprovalsRequired {
+		spec.NumberOfApprovalsRequired = requirements.MinApprovalsRequired
+	}
+	if len(spec.QuorumSchedule) > 0 {
+		steps := []v1alpha1.QuorumStep{}
+		for _, step := range spec.QuorumSchedule {
+			if step.NumberOfApprovalsRequired >= requirements.MinApprovalsRequired && step.NumberOfApprovalsRequired < spec.NumberOfApprovalsRequired {
+				steps = append(steps, step)
+			}
+		}
+		spec.QuorumSchedule = steps
+	}
+	for _, group := range requirements.RequiredGroups {
+		if !hasGroupApprover(spec.Approvers, group) {
+			spec.Approvers = append(spec.Approvers, v1alpha1.ApproverDetails{Name: group, Type: "Group", Input: "pending"})
+		}
+	}
+}
+
+// MissingRequiredGroupsAt returns the groups the policy requirements recorded
+// in the status of the approval task require an approval from, and that have
+// none that counts at now.
+func MissingRequiredGroupsAt(approvalTask v1alpha1.ApprovalTask, now time.Time) []string {
+	if approvalTask.Status.Policy == nil {
+		return nil
+	}
+	var missing []string
+	for _, group := range approvalTask.Status.Policy.RequiredGroups {
+		if !groupApprovedAt(approvalTask, group, now) {
+			missing = append(missing, group)
+		}
+	}
+	return missing
+}
+
+// groupApprovedAt reports whether a member of the Group approver of the given
+// name has an approval that counts at now.
+func groupApprovedAt(approvalTask v1alpha1.ApprovalTask, group string, now time.Time) bool {
+	for _, approver := range approvalTask.Spec.Approvers {
+		if approver.Name != group || v1alpha1.DefaultedApproverType(approver.Type) != "Group" || approver.Input != inputApprove {
+			continue
+		}
+		if !ApproverActive(approvalTask, approver) {
+			continue
+		}
+		if _, blocked := BlockingApproverAt(approvalTask, approver, now); blocked {
+			continue
+		}
+		for _, user := range approver.Users {
+			if user.Input == inputApprove && !GroupMemberApprovalLapsed(approvalTask, approver, user, now) && !RequesterExcluded(approvalTask, user.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// policyMinimum returns the minimum number of approvals the policy
+// requirements recorded in the status of the approval task impose.
+func policyMinimum(approvalTask v1alpha1.ApprovalTask) int {
+	if approvalTask.Status.Policy == nil {
+		return 0
+	}
+	return approvalTask.Status.Policy.MinApprovalsRequired
+}
+
+func hasGroupApprover(approvers []v1alpha1.ApproverDetails, group string) bool {
+	for _, approver := range approvers {
+		if approver.Name == group && v1alpha1.DefaultedApproverType(approver.Type) == "Group" {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func clusterPolicy(name string, spec v1alpha1.ClusterApprovalPolicySpec) *v1alpha1.ClusterApprovalPolicy {
+	return &v1alpha1.ClusterApprovalPolicy{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: spec}
+}
+
+func productionApprovalTask() v1alpha1.ApprovalTask {
+	return v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production", Labels: map[string]string{"tier": "critical"}},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+				{Name: "bob", Type: "User", Input: "pending"},
+				{Name: "security", Type: "Group", Input: "pending"},
+			},
+		},
+	}
+}
+
+func TestPolicyRequirementsForCombinesTheStrictest(t *testing.T) {
+	at := productionApprovalTask()
+	policies := []*v1alpha1.ClusterApprovalPolicy{
+		clusterPolicy("org-minimum", v1alpha1.ClusterApprovalPolicySpec{MinApprovalsRequired: 2}),
+		clusterPolicy("critical", v1alpha1.ClusterApprovalPolicySpec{
+			Selector:             &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "critical"}},
+			MinApprovalsRequired: 3,
+			RequiredGroups:       []string{"security", "sre"},
+		}),
+		clusterPolicy("staging", v1alpha1.ClusterApprovalPolicySpec{Namespaces: []string{"staging"}, MinApprovalsRequired: 5}),
+		clusterPolicy("sre", v1alpha1.ClusterApprovalPolicySpec{RequiredGroups: []string{"sre"}}),
+	}
+
+	assert.Equal(t, &v1alpha1.PolicyRequirements{
+		Policies:             []string{"critical", "org-minimum", "sre"},
+		MinApprovalsRequired: 3,
+		RequiredGroups:       []string{"security", "sre"},
+	}, PolicyRequirementsFor(at, policies))
+
+	at.Labels = nil
+	assert.Equal(t, &v1alpha1.PolicyRequirements{Policies: []string{"org-minimum", "sre"}, MinApprovalsRequired: 2, RequiredGroups: []string{"sre"}},
+		PolicyRequirementsFor(at, policies))
+
+	assert.Nil(t, PolicyRequirementsFor(at, policies[2:3]), "a policy of another namespace does not select the task")
+}
+
+func TestPolicyRaisesRequiredApprovals(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	at := scheduledApprovalTask(start)
+	assert.True(t, QuorumReachedAt(at, start.Add(5*time.Hour)))
+
+	at.Status.Policy = &v1alpha1.PolicyRequirements{Policies: []string{"org-minimum"}, MinApprovalsRequired: 3}
+	assert.Equal(t, 3, RequiredApprovalsAt(at, start.Add(5*time.Hour)), "the schedule cannot relax the quorum below the policy")
+	assert.Equal(t, 3, MinRequiredApprovals(at))
+	assert.False(t, QuorumReachedAt(at, start.Add(5*time.Hour)))
+
+	// A policy never lowers the requirement of the task
+	at.Status.Policy.MinApprovalsRequired = 1
+	assert.Equal(t, 3, RequiredApprovalsAt(at, start))
+}
+
+func TestPolicyRequiresGroupApproval(t *testing.T) {
+	now := time.Now()
+	at := productionApprovalTask()
+	assert.True(t, QuorumReachedAt(at, now))
+
+	at.Status.Policy = &v1alpha1.PolicyRequirements{Policies: []string{"critical"}, RequiredGroups: []string{"security"}}
+	assert.Equal(t, []string{"security"}, MissingRequiredGroupsAt(at, now))
+	assert.False(t, QuorumReachedAt(at, now), "the security group has not approved yet")
+
+	at.Spec.Approvers[2].Input = "approve"
+	at.Spec.Approvers[2].Users = []v1alpha1.UserDetails{{Name: "mallory", Input: "approve"}}
+	assert.Empty(t, MissingRequiredGroupsAt(at, now))
+	assert.True(t, QuorumReachedAt(at, now))
+}
+
+func TestPolicyShortfallsAndApplyPolicy(t *testing.T) {
+	requirements := &v1alpha1.PolicyRequirements{Policies: []string{"critical"}, MinApprovalsRequired: 2, RequiredGroups: []string{"security", "sre"}}
+	spec := productionApprovalTask().Spec
+	spec.NumberOfApprovalsRequired = 3
+	spec.QuorumSchedule = []v1alpha1.QuorumStep{
+		{After: metav1.Duration{Duration: time.Hour}, NumberOfApprovalsRequired: 2},
+		{After: metav1.Duration{Duration: 4 * time.Hour}, NumberOfApprovalsRequired: 1},
+	}
+
+	assert.Equal(t, []string{
+		"quorumSchedule[1].numberOfApprovalsRequired: must be at least 2, got 1",
+		"approvers: group 'sre' must be an approver",
+	}, PolicyShortfalls(spec, requirements))
+
+	ApplyPolicy(&spec, requirements)
+	assert.Empty(t, PolicyShortfalls(spec, requirements))
+	assert.Equal(t, 3, spec.NumberOfApprovalsRequired, "a stricter spec is kept")
+	assert.Equal(t, []v1alpha1.QuorumStep{{After: metav1.Duration{Duration: time.Hour}, NumberOfApprovalsRequired: 2}}, spec.QuorumSchedule)
+	assert.Len(t, spec.Approvers, 4)
+	assert.Equal(t, v1alpha1.ApproverDetails{Name: "sre", Type: "Group", Input: "pending"}, spec.Approvers[3])
+
+	assert.Empty(t, PolicyShortfalls(spec, nil))
+}
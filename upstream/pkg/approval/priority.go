@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"math"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// BlockingApproverAt returns the first active approver of the approval task
+// that ranks ahead of approver (see ApproverDetails.Priority) and has not
+// approved at now. Approvals from approver do not count while there is one.
+func BlockingApproverAt(approvalTask v1alpha1.ApprovalTask, approver v1alpha1.ApproverDetails, now time.Time) (v1alpha1.ApproverDetails, bool) {
+	rank := priorityRank(approver)
+	for _, other := range approvalTask.Spec.Approvers {
+		if priorityRank(other) < rank && ApproverActive(approvalTask, other) && !approvedAt(approvalTask, other, now) {
+			return other, true
+		}
+	}
+	return v1alpha1.ApproverDetails{}, false
+}
+
+// priorityRank orders approvers by Priority, lowest first, with approvers
+// without a Priority after all the others.
+func priorityRank(approver v1alpha1.ApproverDetails) int {
+	if approver.Priority > 0 {
+		return approver.Priority
+	}
+	return math.MaxInt
+}
+
+// approvedAt reports whether the approver has an approval in force at now:
+// its own for an individual approver, or one from any member for a group.
+func approvedAt(approvalTask v1alpha1.ApprovalTask, approver v1alpha1.ApproverDetails, now time.Time) bool {
+	if v1alpha1.IsIndividualApproverType(approver.Type) {
+		return individualApproved(approvalTask, approver) && !UserApprovalLapsed(approvalTask, approver, now)
+	}
+	if approver.Input != inputApprove {
+		return false
+	}
+	for _, user := range approver.Users {
+		if user.Input == inputApprove && !GroupMemberApprovalLapsed(approvalTask, approver, user, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// priorityUnreachable reports whether an approver ranking ahead of approver
+// can never approve, so approvals from approver can never count.
+func priorityUnreachable(approvalTask v1alpha1.ApprovalTask, approver v1alpha1.ApproverDetails) bool {
+	rank := priorityRank(approver)
+	for _, other := range approvalTask.Spec.Approvers {
+		if priorityRank(other) < rank && ApproverActive(approvalTask, other) && !canApprove(other) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func orderedApprovalTask() v1alpha1.ApprovalTask {
+	return v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "lead", Type: "User", Input: "pending", Priority: 1},
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "platform", Type: "Group", Input: "pending"},
+			},
+		},
+	}
+}
+
+func TestOutOfOrderApprovalsDoNotCount(t *testing.T) {
+	at := orderedApprovalTask()
+	at.Spec.Approvers[1].Input = "approve"
+	at.Spec.Approvers[2].Input = "approve"
+	at.Spec.Approvers[2].Users = []v1alpha1.UserDetails{{Name: "bob", Input: "approve"}}
+
+	assert.Equal(t, 0, CountApprovals(at), "approvals before the lead's must not count")
+	blocking, blocked := BlockingApproverAt(at, at.Spec.Approvers[1], time.Now())
+	assert.True(t, blocked)
+	assert.Equal(t, "lead", blocking.Name)
+
+	// Once the lead approves, the earlier approvals count as well
+	at.Spec.Approvers[0].Input = "approve"
+	assert.Equal(t, 3, CountApprovals(at))
+	assert.True(t, QuorumReached(at))
+}
+
+func TestApprovalOrderAcrossPriorities(t *testing.T) {
+	at := orderedApprovalTask()
+	at.Spec.Approvers[1].Priority = 2
+	at.Spec.Approvers[0].Input = "approve"
+	at.Spec.Approvers[2].Input = "approve"
+	at.Spec.Approvers[2].Users = []v1alpha1.UserDetails{{Name: "bob", Input: "approve"}}
+
+	// platform ranks behind alice, who has not approved
+	assert.Equal(t, 1, CountApprovals(at))
+
+	at.Spec.Approvers[1].Input = "approve"
+	assert.Equal(t, 3, CountApprovals(at))
+}
+
+func TestEqualPrioritiesAreUnordered(t *testing.T) {
+	at := orderedApprovalTask()
+	at.Spec.Approvers[1].Priority = 1
+	at.Spec.Approvers[1].Input = "approve"
+
+	_, blocked := BlockingApproverAt(at, at.Spec.Approvers[1], time.Now())
+	assert.False(t, blocked)
+	assert.Equal(t, 1, CountApprovals(at))
+}
+
+func TestApproversBehindRejectOnlyApproverCannotCount(t *testing.T) {
+	at := orderedApprovalTask()
+	at.Spec.Approvers[0].AllowedInputs = []string{"reject"}
+	at.Spec.Approvers[2].Type = "User"
+
+	attainable, ok := MaxAttainableApprovals(at)
+	assert.True(t, ok)
+	assert.Equal(t, 0, attainable)
+	assert.True(t, Unsatisfiable(at))
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package approval holds the quorum evaluation shared by the ApprovalTask
+// controller and the admission webhook, so both always agree on whether a
+// task has collected enough approvals, and the Approve helper for tooling
+// that submits decisions.
+package approval
+
+import (
+	"sort"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+const (
+	inputApprove = "approve"
+)
+
+// CountApprovals returns the number of unique users whose approval counts
+// towards the quorum of the approval task.
+//
+// Individual User and Email approvers are counted first so that a user who
+// is both an individual approver and a group member is attributed to their
+// own entry.
+// When Spec.MaxApprovalsPerGroup is set, each Group approver contributes at
+// most that many approvals, so a single large group cannot satisfy the
+// whole quorum on its own. Approvals from an approver ranked behind another
+// that has not approved yet (see ApproverDetails.Priority) are not counted,
+// and neither are those of inactive approvers (see ApproverActive).
+// Approvals carried forward from a prior task count like the approver's own
+// (see CarriedApproval).
+// When Spec.MinApproverLevel is set, only approvals recorded with at least
+// that level are counted (see ParseLevel), so the quorum can only be reached
+// with at least one approval of that level.
+// The approval of the user who requested the task is not counted either,
+// unless the task opts in (see RequesterExcluded).
+func CountApprovals(approvalTask v1alpha1.ApprovalTask) int {
+	return CountApprovalsAt(approvalTask, time.Now())
+}
+
+// CountApprovalsAt is CountApprovals evaluated at the given time: approvals
+// that have lapsed by now (see ApproverDetails.ApprovalExpiresAfter) are not
+// counted.
+func CountApprovalsAt(approvalTask v1alpha1.ApprovalTask, now time.Time) int {
+	return len(countedApprovalsAt(approvalTask, now))
+}
+
+// countedApproval is what a counted approval is credited with: the team
+// recorded with it and the role of its approver entry.
+type countedApproval struct {
+	team string
+	role string
+}
+
+// creditedAt returns the distinct non-empty values credit picks from the
+// approvals counted towards the quorum at the given time, sorted.
+func creditedAt(approvalTask v1alpha1.ApprovalTask, now time.Time, credit func(countedApproval) string) []string {
+	seen := make(map[string]bool)
+	var values []string
+	for _, counted := range countedApprovalsAt(approvalTask, now) {
+		value := credit(counted)
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// countedApprovalsAt returns the users whose approval counts towards the
+// quorum at the given time, mapped to what their approval is credited with.
+func countedApprovalsAt(approvalTask v1alpha1.ApprovalTask, now time.Time) map[string]countedApproval {
+	approvedUsers := make(map[string]countedApproval)
+
+	for _, approver := range approvalTask.Spec.Approvers {
+		if v1alpha1.IsIndividualApproverType(approver.Type) && individualApproved(approvalTask, approver) && ApproverActive(approvalTask, approver) {
+			if UserApprovalLapsed(approvalTask, approver, now) {
+				continue
+			}
+			if _, blocked := BlockingApproverAt(approvalTask, approver, now); blocked {
+				continue
+			}
+			if !meetsMinLevel(approvalTask, approver.Level) {
+				continue
+			}
+			if v1alpha1.DefaultedApproverType(approver.Type) == "User" && RequesterExcluded(approvalTask, approver.Name) {
+				continue
+			}
+			approvedUsers[approver.Name] = countedApproval{team: approver.Team, role: approver.Role}
+		}
+	}
+
+	maxPerGroup := approvalTask.Spec.MaxApprovalsPerGroup
+	for _, approver := range approvalTask.Spec.Approvers {
+		if approver.Input != inputApprove || v1alpha1.DefaultedApproverType(approver.Type) != "Group" || !ApproverActive(approvalTask, approver) {
+			continue
+		}
+		if _, blocked := BlockingApproverAt(approvalTask, approver, now); blocked {
+			continue
+		}
+		contributed := 0
+		for _, user := range approver.Users {
+			if _, counted := approvedUsers[user.Name]; user.Input != inputApprove || counted {
+				continue
+			}
+			if GroupMemberApprovalLapsed(approvalTask, approver, user, now) {
+				continue
+			}
+			if !meetsMinLevel(approvalTask, user.Level) {
+				continue
+			}
+			if RequesterExcluded(approvalTask, user.Name) {
+				continue
+			}
+			if maxPerGroup > 0 && contributed >= maxPerGroup {
+				break
+			}
+			approvedUsers[user.Name] = countedApproval{team: user.Team, role: approver.Role}
+			contributed++
+		}
+	}
+
+	return approvedUsers
+}
+
+// QuorumReached reports whether the approval task has collected the number
+// of approvals it requires.
+func QuorumReached(approvalTask v1alpha1.ApprovalTask) bool {
+	return QuorumReachedAt(approvalTask, time.Now())
+}
+
+// QuorumReachedAt is QuorumReached evaluated at the given time, against the
+// number of approvals required at that time (see RequiredApprovalsAt). Every
+// group the policy requirements in the status require must have approved as
+// well (see MissingRequiredGroupsAt), and the approvals must span
+// Spec.MinApprovingTeams teams (see ApprovingTeamsAt) and cover each of
+// Spec.RequiredRoles (see MissingRolesAt). No approver may be requesting
+// changes (see ChangesRequestedBy).
+func QuorumReachedAt(approvalTask v1alpha1.ApprovalTask, now time.Time) bool {
+	return CountApprovalsAt(approvalTask, now) >= RequiredApprovalsAt(approvalTask, now) &&
+		len(MissingRequiredGroupsAt(approvalTask, now)) == 0 &&
+		MissingTeamsAt(approvalTask, now) == 0 &&
+		len(MissingRolesAt(approvalTask, now)) == 0 &&
+		len(ChangesRequestedBy(approvalTask)) == 0
+}
+
+// MaxAttainableApprovals returns an upper bound of the approvals the approval
+// task can still collect, counting every approver allowed to approve as if it
+// did. The bound is only known when every Group approver that may approve is
+// capped by Spec.MaxApprovalsPerGroup: the members of an uncapped group are
+// not known in advance, so ok is false in that case.
+//
+// Lapsed approvals are counted too, since they can still be renewed, but
+// individual approvers that rejected the task and approvers ranked behind one
+// that can never approve are not, and neither is the requester of the task
+// when its approval does not count. Backup approvers count while the task is
+// yet to escalate to them.
+func MaxAttainableApprovals(approvalTask v1alpha1.ApprovalTask) (int, bool) {
+	users := make(map[string]bool)
+	groups := 0
+	for _, approver := range approvalTask.Spec.Approvers {
+		if !activeOrPendingEscalation(approvalTask, approver) || !canApprove(approver) || priorityUnreachable(approvalTask, approver) {
+			continue
+		}
+		switch v1alpha1.DefaultedApproverType(approver.Type) {
+		case "User", "Email":
+			if v1alpha1.DefaultedApproverType(approver.Type) == "User" && RequesterExcluded(approvalTask, approver.Name) {
+				continue
+			}
+			users[approver.Name] = true
+		case "Group":
+			if approvalTask.Spec.MaxApprovalsPerGroup <= 0 {
+				return 0, false
+			}
+			groups += approvalTask.Spec.MaxApprovalsPerGroup
+		}
+	}
+	return len(users) + groups, true
+}
+
+// Unsatisfiable reports whether the approval task can no longer reach its
+// quorum, however the remaining approvers decide and however far its quorum
+// schedule relaxes the requirement.
+func Unsatisfiable(approvalTask v1alpha1.ApprovalTask) bool {
+	attainable, ok := MaxAttainableApprovals(approvalTask)
+	return ok && attainable < MinRequiredApprovals(approvalTask)
+}
+
+// canApprove reports whether the approver is allowed to submit an approval.
+// An individual approver that rejected the task no longer is: rejections are
+// final.
+func canApprove(approver v1alpha1.ApproverDetails) bool {
+	if v1alpha1.IsIndividualApproverType(approver.Type) && approver.Input == inputReject {
+		return false
+	}
+	if len(approver.AllowedInputs) == 0 {
+		return true
+	}
+	for _, input := range approver.AllowedInputs {
+		if input == inputApprove {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func groupApprovalTask(maxPerGroup int) v1alpha1.ApprovalTask {
+	return v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 3,
+			MaxApprovalsPerGroup:      maxPerGroup,
+			Approvers: []v1alpha1.ApproverDetails{
+				{
+					Name:  "platform",
+					Type:  "Group",
+					Input: "approve",
+					Users: []v1alpha1.UserDetails{
+						{Name: "alice", Input: "approve"},
+						{Name: "bob", Input: "approve"},
+						{Name: "carol", Input: "approve"},
+					},
+				},
+				{Name: "dave", Type: "User", Input: "pending"},
+			},
+		},
+	}
+}
+
+func TestCountApprovalsWithoutGroupCap(t *testing.T) {
+	at := groupApprovalTask(0)
+	assert.Equal(t, 3, CountApprovals(at))
+	assert.True(t, QuorumReached(at))
+}
+
+func TestCountApprovalsWithGroupCap(t *testing.T) {
+	at := groupApprovalTask(2)
+	assert.Equal(t, 2, CountApprovals(at), "group contributions above the cap must not count")
+	assert.False(t, QuorumReached(at))
+
+	at.Spec.Approvers[1].Input = "approve"
+	assert.Equal(t, 3, CountApprovals(at), "individual approvers are not subject to the group cap")
+	assert.True(t, QuorumReached(at))
+}
+
+func TestCountApprovalsGroupCapSkipsIndividualApprovers(t *testing.T) {
+	at := groupApprovalTask(1)
+	at.Spec.Approvers[1] = v1alpha1.ApproverDetails{Name: "alice", Type: "User", Input: "approve"}
+
+	// alice is counted through her own entry, so the group can still
+	// contribute one more approval
+	assert.Equal(t, 2, CountApprovals(at))
+}
+
+func TestCountApprovalsAtSkipsLapsedApprovals(t *testing.T) {
+	respondedAt := metav1.NewTime(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	renewTime := metav1.NewTime(respondedAt.Add(45 * time.Minute))
+	at := v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve", ApprovalExpiresAfter: &metav1.Duration{Duration: time.Hour}},
+				{
+					Name: "platform", Type: "Group", Input: "approve", ApprovalExpiresAfter: &metav1.Duration{Duration: time.Hour},
+					Users: []v1alpha1.UserDetails{{Name: "bob", Input: "approve", RenewTime: &renewTime}},
+				},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{
+			ApproversResponse: []v1alpha1.ApproverState{
+				{Name: "alice", Type: "User", Response: "approved", RespondedAt: &respondedAt},
+				{
+					Name: "platform", Type: "Group", Response: "approved",
+					GroupMembers: []v1alpha1.GroupMemberState{{Name: "bob", Response: "approved", RespondedAt: &respondedAt}},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, 2, CountApprovalsAt(at, respondedAt.Add(30*time.Minute)))
+	assert.True(t, QuorumReachedAt(at, respondedAt.Add(30*time.Minute)))
+
+	// alice never renewed, bob renewed 45 minutes in
+	assert.Equal(t, 1, CountApprovalsAt(at, respondedAt.Add(90*time.Minute)))
+	assert.False(t, QuorumReachedAt(at, respondedAt.Add(90*time.Minute)))
+
+	assert.Equal(t, 0, CountApprovalsAt(at, respondedAt.Add(2*time.Hour)))
+}
+
+func TestCountApprovalsWithCarriedApprovals(t *testing.T) {
+	at := v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "bob", Type: "User", Input: "pending"},
+				{Name: "carol", Type: "User", Input: "approve"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{
+			ApproversResponse: []v1alpha1.ApproverState{
+				{Name: "alice", Type: "User", Response: "approved", CarriedFrom: "deploy-1"},
+				{Name: "bob", Type: "User", Response: "pending", CarriedFrom: "deploy-1"},
+			},
+		},
+	}
+	assert.True(t, CarriedApproval(at, at.Spec.Approvers[0]))
+	assert.False(t, CarriedApproval(at, at.Spec.Approvers[1]), "only carried approvals count")
+	assert.Equal(t, 2, CountApprovals(at))
+	assert.True(t, QuorumReached(at))
+
+	// Deciding on the task itself replaces the carried approval
+	at.Spec.Approvers[0].Input = "reject"
+	assert.False(t, CarriedApproval(at, at.Spec.Approvers[0]))
+	assert.Equal(t, 1, CountApprovals(at))
+}
+
+func TestMaxAttainableApprovals(t *testing.T) {
+	at := v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+				{Name: "bob", Type: "User", Input: "pending"},
+			},
+		},
+	}
+	attainable, ok := MaxAttainableApprovals(at)
+	assert.True(t, ok)
+	assert.Equal(t, 2, attainable)
+	assert.False(t, Unsatisfiable(at))
+
+	// A blocker that can only reject does not count towards the quorum
+	at.Spec.Approvers[1].AllowedInputs = []string{"reject"}
+	attainable, _ = MaxAttainableApprovals(at)
+	assert.Equal(t, 1, attainable)
+	assert.True(t, Unsatisfiable(at))
+}
+
+func TestMaxAttainableApprovalsWithGroups(t *testing.T) {
+	at := groupApprovalTask(0)
+	at.Spec.NumberOfApprovalsRequired = 10
+	_, ok := MaxAttainableApprovals(at)
+	assert.False(t, ok, "an uncapped group can have any number of members")
+	assert.False(t, Unsatisfiable(at))
+
+	at = groupApprovalTask(1)
+	attainable, ok := MaxAttainableApprovals(at)
+	assert.True(t, ok)
+	assert.Equal(t, 2, attainable, "one approval from the capped group and one from dave")
+	assert.True(t, Unsatisfiable(at))
+}
+
+func TestCountApprovalsEmailApprovers(t *testing.T) {
+	at := groupApprovalTask(2)
+	at.Spec.Approvers = append(at.Spec.Approvers, v1alpha1.ApproverDetails{Name: "erin@example.com", Type: "Email", Input: "approve"})
+	assert.Equal(t, 3, CountApprovals(at), "email approvers count like user approvers")
+	assert.True(t, QuorumReached(at))
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// RecordedAttribute is a value recorded by approvers with their decisions,
+// on their approver entry or on their entry in the users of a Group
+// approver, such as their team or their level.
+type RecordedAttribute struct {
+	OfApprover func(v1alpha1.ApproverDetails) string
+	OfMember   func(v1alpha1.UserDetails) string
+}
+
+var (
+	// TeamAttribute is the team recorded with a decision (see
+	// Spec.MinApprovingTeams).
+	TeamAttribute = RecordedAttribute{
+		OfApprover: func(approver v1alpha1.ApproverDetails) string { return approver.Team },
+		OfMember:   func(user v1alpha1.UserDetails) string { return user.Team },
+	}
+	// LevelAttribute is the seniority level recorded with a decision (see
+	// Spec.MinApproverLevel).
+	LevelAttribute = RecordedAttribute{
+		OfApprover: func(approver v1alpha1.ApproverDetails) string { return approver.Level },
+		OfMember:   func(user v1alpha1.UserDetails) string { return user.Level },
+	}
+)
+
+// AttributeChange is an entry whose recorded attribute an update changes.
+type AttributeChange struct {
+	// Approver is the approver entry before the update.
+	Approver v1alpha1.ApproverDetails
+	// Member is the name of the group member whose entry changed, or empty
+	// when the approver entry itself changed.
+	Member string
+	// Value is the attribute recorded by the update.
+	Value string
+}
+
+// Changes returns, in the order of Spec.Approvers, the entries of the
+// approvers kept by the update from oldTask to newTask whose attribute it
+// changes. A member entry the update adds changes the attribute from empty.
+func (a RecordedAttribute) Changes(oldTask, newTask v1alpha1.ApprovalTask) []AttributeChange {
+	var changes []AttributeChange
+	for i, approver := range newTask.Spec.Approvers {
+		if i >= len(oldTask.Spec.Approvers) {
+			break
+		}
+		oldApprover := oldTask.Spec.Approvers[i]
+		if value := a.OfApprover(approver); value != a.OfApprover(oldApprover) {
+			changes = append(changes, AttributeChange{Approver: oldApprover, Value: value})
+		}
+		for _, user := range approver.Users {
+			if value := a.OfMember(user); value != a.memberValue(oldApprover, user.Name) {
+				changes = append(changes, AttributeChange{Approver: oldApprover, Member: user.Name, Value: value})
+			}
+		}
+	}
+	return changes
+}
+
+// memberValue returns the attribute recorded for the named member of the
+// Group approver, or an empty string when it is not listed.
+func (a RecordedAttribute) memberValue(approver v1alpha1.ApproverDetails, name string) string {
+	for _, user := range approver.Users {
+		if user.Name == name {
+			return a.OfMember(user)
+		}
+	}
+	return ""
+}
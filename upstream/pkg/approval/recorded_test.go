@@ -0,0 +1,45 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordedAttributeChanges(t *testing.T) {
+	oldTask := v1alpha1.ApprovalTask{Spec: v1alpha1.ApprovalTaskSpec{Approvers: []v1alpha1.ApproverDetails{
+		{Name: "alice", Type: "User", Team: "payments", Level: "2"},
+		{Name: "platform", Type: "Group", Users: []v1alpha1.UserDetails{{Name: "bob", Team: "infra"}}},
+	}}}
+	newTask := *oldTask.DeepCopy()
+	newTask.Spec.Approvers[0].Team = "security"
+	newTask.Spec.Approvers[1].Users[0].Level = "3"
+	newTask.Spec.Approvers[1].Users = append(newTask.Spec.Approvers[1].Users, v1alpha1.UserDetails{Name: "carol", Team: "infra"})
+	newTask.Spec.Approvers = append(newTask.Spec.Approvers, v1alpha1.ApproverDetails{Name: "dave", Type: "User", Team: "infra"})
+
+	assert.Equal(t, []AttributeChange{
+		{Approver: oldTask.Spec.Approvers[0], Value: "security"},
+		{Approver: oldTask.Spec.Approvers[1], Member: "carol", Value: "infra"},
+	}, TeamAttribute.Changes(oldTask, newTask), "approvers added by the update are not compared")
+	assert.Equal(t, []AttributeChange{
+		{Approver: oldTask.Spec.Approvers[1], Member: "bob", Value: "3"},
+	}, LevelAttribute.Changes(oldTask, newTask))
+	assert.Empty(t, TeamAttribute.Changes(oldTask, oldTask))
+}
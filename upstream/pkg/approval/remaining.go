@@ -0,0 +1,49 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// RemainingApprovals returns how many more approvals the approval task needs
+// before it reaches its quorum, for example to drive a progress bar. It is 0
+// once the task has reached a final state, and never negative.
+func RemainingApprovals(approvalTask v1alpha1.ApprovalTask) int {
+	return RemainingApprovalsAt(approvalTask, time.Now())
+}
+
+// RemainingApprovalsAt is RemainingApprovals evaluated at the given time. It
+// follows QuorumReachedAt: approvals that do not count, because they lapsed,
+// exceed the cap of their group or wait on an approver of higher priority, are
+// still needed, and so is one approval from each group the policy
+// requirements mandate, from each team still missing (see MissingTeamsAt) and
+// from each role still missing (see MissingRolesAt). It is therefore 0
+// exactly when the quorum is reached, which a task waiting on its escrow
+// group may be while still pending.
+func RemainingApprovalsAt(approvalTask v1alpha1.ApprovalTask, now time.Time) int {
+	switch approvalTask.Status.State {
+	case "approved", "rejected", "withdrawn":
+		return 0
+	}
+	remaining := RequiredApprovalsAt(approvalTask, now) - CountApprovalsAt(approvalTask, now)
+	// Each missing group, team and role needs an approval of its own, even
+	// once the count is reached
+	return max(remaining, len(MissingRequiredGroupsAt(approvalTask, now)), MissingTeamsAt(approvalTask, now), len(MissingRolesAt(approvalTask, now)), 0)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemainingApprovals(t *testing.T) {
+	at := groupApprovalTask(0)
+	at.Spec.Approvers[0].Users[2].Input = "pending"
+	assert.Equal(t, 1, RemainingApprovals(at))
+
+	at.Spec.Approvers[0].Users[2].Input = "approve"
+	at.Spec.Approvers[1].Input = "approve"
+	assert.Equal(t, 0, RemainingApprovals(at), "approvals beyond the quorum do not make it negative")
+}
+
+func TestRemainingApprovalsOfFinalTasks(t *testing.T) {
+	for _, state := range []string{"approved", "rejected", "withdrawn"} {
+		at := groupApprovalTask(2)
+		at.Status.State = state
+		assert.Equal(t, 0, RemainingApprovals(at), state)
+	}
+
+	at := groupApprovalTask(2)
+	at.Status.State = "pending"
+	assert.Equal(t, 1, RemainingApprovals(at))
+}
+
+func TestRemainingApprovalsWithGroupCap(t *testing.T) {
+	at := groupApprovalTask(1)
+	assert.Equal(t, 2, RemainingApprovals(at), "the group contributes a single approval however many members approved")
+
+	at.Spec.Approvers[1].Input = "approve"
+	assert.Equal(t, 1, RemainingApprovals(at))
+}
+
+func TestRemainingApprovalsWithApprovalOrder(t *testing.T) {
+	at := orderedApprovalTask()
+	at.Spec.Approvers[1].Input = "approve"
+	assert.Equal(t, 2, RemainingApprovals(at), "alice's approval waits on the lead")
+
+	at.Spec.Approvers[0].Input = "approve"
+	assert.Equal(t, 0, RemainingApprovals(at))
+}
+
+func TestRemainingApprovalsFollowsScheduleAndPolicy(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	at := scheduledApprovalTask(start)
+	assert.Equal(t, 1, RemainingApprovalsAt(at, start))
+	assert.Equal(t, 0, RemainingApprovalsAt(at, start.Add(time.Hour)))
+
+	at.Status.Policy = &v1alpha1.PolicyRequirements{Policies: []string{"org-minimum"}, MinApprovalsRequired: 4}
+	assert.Equal(t, 2, RemainingApprovalsAt(at, start.Add(time.Hour)), "the schedule does not relax the policy minimum")
+}
+
+func TestRemainingApprovalsWithRequiredGroups(t *testing.T) {
+	now := time.Now()
+	at := productionApprovalTask()
+	at.Status.Policy = &v1alpha1.PolicyRequirements{Policies: []string{"critical"}, RequiredGroups: []string{"security", "sre"}}
+	assert.Equal(t, 2, RemainingApprovalsAt(at, now), "alice reached the count, but both groups still have to approve")
+
+	at.Spec.Approvers[2].Input = "approve"
+	at.Spec.Approvers[2].Users = []v1alpha1.UserDetails{{Name: "mallory", Input: "approve"}}
+	assert.Equal(t, 1, RemainingApprovalsAt(at, now))
+
+	at.Status.Policy.MinApprovalsRequired = 4
+	assert.Equal(t, 2, RemainingApprovalsAt(at, now), "the count falls short by more than the missing groups")
+}
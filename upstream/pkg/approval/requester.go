@@ -0,0 +1,34 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// RequesterExcluded reports whether the approval of username does not count
+// towards the quorum of the approval task because username requested it, as
+// recorded in its CreatedByAnnotationKey annotation. The approval is still
+// recorded. Tasks setting Spec.CountRequesterApproval, and tasks without a
+// recorded requester, count every approver.
+func RequesterExcluded(approvalTask v1alpha1.ApprovalTask, username string) bool {
+	if approvalTask.Spec.CountRequesterApproval {
+		return false
+	}
+	requester := approvalTask.Annotations[v1alpha1.CreatedByAnnotationKey]
+	return requester != "" && requester == username
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func requestedBy(requester string, approvers ...v1alpha1.ApproverDetails) v1alpha1.ApprovalTask {
+	return v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{v1alpha1.CreatedByAnnotationKey: requester}},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			Approvers:                 approvers,
+		},
+	}
+}
+
+func TestRequesterApprovalNotCounted(t *testing.T) {
+	at := requestedBy("alice",
+		v1alpha1.ApproverDetails{Name: "alice", Type: "User", Input: "approve"},
+		v1alpha1.ApproverDetails{Name: "bob", Type: "User", Input: "pending"},
+	)
+	assert.Equal(t, 0, CountApprovals(at), "the requester approving does not advance the count")
+	assert.False(t, QuorumReached(at))
+
+	at.Spec.Approvers[1].Input = "approve"
+	assert.Equal(t, 1, CountApprovals(at))
+	assert.True(t, QuorumReached(at))
+
+	at.Spec.CountRequesterApproval = true
+	assert.Equal(t, 2, CountApprovals(at), "tasks opting out count the requester")
+}
+
+func TestRequesterGroupApprovalNotCounted(t *testing.T) {
+	at := requestedBy("alice", v1alpha1.ApproverDetails{Name: "release", Type: "Group", Input: "approve", Users: []v1alpha1.UserDetails{
+		{Name: "alice", Input: "approve"},
+		{Name: "bob", Input: "approve"},
+	}})
+	at.Spec.MaxApprovalsPerGroup = 1
+	assert.Equal(t, 1, CountApprovals(at), "the requester does not take the place of another member")
+	assert.Equal(t, map[string]countedApproval{"bob": {}}, countedApprovalsAt(at, metav1.Now().Time))
+
+	at.Spec.Approvers[0].Users = at.Spec.Approvers[0].Users[:1]
+	at.Status.Policy = &v1alpha1.PolicyRequirements{RequiredGroups: []string{"release"}}
+	assert.Equal(t, 0, CountApprovals(at))
+	assert.Equal(t, []string{"release"}, MissingRequiredGroupsAt(at, metav1.Now().Time))
+}
+
+func TestRequesterExcludedFromAttainableApprovals(t *testing.T) {
+	at := requestedBy("alice",
+		v1alpha1.ApproverDetails{Name: "alice", Type: "User", Input: "pending"},
+		v1alpha1.ApproverDetails{Name: "bob", Type: "User", Input: "reject"},
+	)
+	attainable, ok := MaxAttainableApprovals(at)
+	assert.True(t, ok)
+	assert.Equal(t, 0, attainable)
+	assert.True(t, Unsatisfiable(at), "only the requester is left to approve")
+}
+
+func TestRequesterExcluded(t *testing.T) {
+	at := requestedBy("alice")
+	assert.True(t, RequesterExcluded(at, "alice"))
+	assert.False(t, RequesterExcluded(at, "bob"))
+
+	at.Spec.CountRequesterApproval = true
+	assert.False(t, RequesterExcluded(at, "alice"))
+
+	assert.False(t, RequesterExcluded(v1alpha1.ApprovalTask{}, ""), "tasks without a recorded requester count everyone")
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+const inputPending = "pending"
+
+// ResetsOnApproverChange reports whether the responses of the approval task
+// are reset when its approver set changes.
+func ResetsOnApproverChange(approvalTask v1alpha1.ApprovalTask) bool {
+	return approvalTask.Spec.ApproverChangePolicy == v1alpha1.ApproverChangeReset
+}
+
+// ApproverSetHash hashes who the approvers of the approval task are, their
+// types and names, regardless of their order and of their responses. The
+// members listed in the users of Group approvers are left out, since they
+// add themselves when they decide.
+func ApproverSetHash(approvers []v1alpha1.ApproverDetails) string {
+	entries := make([]string, 0, len(approvers))
+	for _, approver := range approvers {
+		entries = append(entries, v1alpha1.DefaultedApproverType(approver.Type)+"/"+approver.Name)
+	}
+	slices.Sort(entries)
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(entries, "\n"))))
+}
+
+// ResetApprovers returns approvers with every response reset to pending: the
+// input, message and renew time of each approver and of each member listed in
+// the users of Group approvers, who stay listed.
+func ResetApprovers(approvers []v1alpha1.ApproverDetails) []v1alpha1.ApproverDetails {
+	reset := make([]v1alpha1.ApproverDetails, len(approvers))
+	for i, approver := range approvers {
+		approver.DeepCopyInto(&reset[i])
+		reset[i].Input = inputPending
+		reset[i].Message = ""
+		reset[i].RenewTime = nil
+		for j := range reset[i].Users {
+			reset[i].Users[j].Input = inputPending
+			reset[i].Users[j].Message = ""
+			reset[i].Users[j].RenewTime = nil
+		}
+	}
+	return reset
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApproverSetHash(t *testing.T) {
+	approvers := []v1alpha1.ApproverDetails{
+		{Name: "alice", Type: "User", Input: "pending"},
+		{Name: "platform", Type: "Group", Input: "pending"},
+	}
+	hash := ApproverSetHash(approvers)
+
+	decided := []v1alpha1.ApproverDetails{
+		{Name: "platform", Type: "Group", Input: "approve", Users: []v1alpha1.UserDetails{{Name: "carol", Input: "approve"}}},
+		{Name: "alice", Input: "approve", Message: "lgtm"},
+	}
+	assert.Equal(t, hash, ApproverSetHash(decided), "order, responses, group members and the default type do not matter")
+
+	assert.NotEqual(t, hash, ApproverSetHash(append(approvers, v1alpha1.ApproverDetails{Name: "bob", Type: "User"})))
+	assert.NotEqual(t, hash, ApproverSetHash(approvers[:1]))
+	assert.NotEqual(t, hash, ApproverSetHash([]v1alpha1.ApproverDetails{
+		{Name: "alice", Type: "Group"},
+		{Name: "platform", Type: "Group"},
+	}), "the type of an approver matters")
+}
+
+func TestResetApprovers(t *testing.T) {
+	renewed := metav1.Now()
+	approvers := []v1alpha1.ApproverDetails{
+		{Name: "alice", Type: "User", Input: "approve", Message: "lgtm", RenewTime: &renewed, Team: "payments"},
+		{Name: "platform", Type: "Group", Input: "reject", Users: []v1alpha1.UserDetails{
+			{Name: "carol", Input: "reject", Message: "not yet", RenewTime: &renewed},
+		}},
+	}
+	original := []v1alpha1.ApproverDetails{*approvers[0].DeepCopy(), *approvers[1].DeepCopy()}
+
+	assert.Equal(t, []v1alpha1.ApproverDetails{
+		{Name: "alice", Type: "User", Input: "pending", Team: "payments"},
+		{Name: "platform", Type: "Group", Input: "pending", Users: []v1alpha1.UserDetails{
+			{Name: "carol", Input: "pending"},
+		}},
+	}, ResetApprovers(approvers))
+	assert.Equal(t, original, approvers, "the approvers passed in are left as they are")
+}
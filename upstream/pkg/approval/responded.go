@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"slices"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// HasResponded reports whether username, a member of groups, has a response
+// in force on the approval task, and returns it: "approve", "reject" or
+// "request-changes". UIs use it to tell users they already decided.
+//
+// Responses are resolved the way they are recorded and counted: a User
+// approver entry named after the user takes precedence, and only when there
+// is none does the user's entry among the users of a Group approver count,
+// for the groups the user is a member of. The group-level input of a Group
+// approver is not the user's response. Responses on inactive
+// approvers (see ApproverActive) and lapsed approvals (see
+// ApproverDetails.ApprovalExpiresAfter) are not in force.
+func HasResponded(approvalTask v1alpha1.ApprovalTask, username string, groups []string) (bool, string) {
+	return HasRespondedAt(approvalTask, username, groups, time.Now())
+}
+
+// HasRespondedAt is HasResponded evaluated at the given time.
+func HasRespondedAt(approvalTask v1alpha1.ApprovalTask, username string, groups []string, now time.Time) (bool, string) {
+	for _, approver := range approvalTask.Spec.Approvers {
+		if v1alpha1.DefaultedApproverType(approver.Type) != "User" || approver.Name != username || !ApproverActive(approvalTask, approver) {
+			continue
+		}
+		if !respondedWith(approver.Input) || (approver.Input == inputApprove && UserApprovalLapsed(approvalTask, approver, now)) {
+			return false, ""
+		}
+		return true, approver.Input
+	}
+
+	for _, approver := range approvalTask.Spec.Approvers {
+		if v1alpha1.DefaultedApproverType(approver.Type) != "Group" || !slices.Contains(groups, approver.Name) || !ApproverActive(approvalTask, approver) {
+			continue
+		}
+		for _, user := range approver.Users {
+			if user.Name != username || !respondedWith(user.Input) {
+				continue
+			}
+			if user.Input == inputApprove && GroupMemberApprovalLapsed(approvalTask, approver, user, now) {
+				continue
+			}
+			return true, user.Input
+		}
+	}
+	return false, ""
+}
+
+// respondedWith reports whether input is a response rather than the pending
+// placeholder of an approver that has not decided.
+func respondedWith(input string) bool {
+	return input != "" && input != inputPending
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func respondedApprovalTask() v1alpha1.ApprovalTask {
+	return v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+				{Name: "bob", Type: "User", Input: "pending"},
+				{Name: "platform", Type: "Group", Input: "reject", Users: []v1alpha1.UserDetails{
+					{Name: "carol", Input: "reject"},
+					{Name: "bob", Input: "approve"},
+				}},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func TestHasRespondedDirectly(t *testing.T) {
+	at := respondedApprovalTask()
+	responded, input := HasResponded(at, "alice", nil)
+	assert.True(t, responded)
+	assert.Equal(t, "approve", input)
+
+	responded, input = HasResponded(at, "bob", []string{"platform"})
+	assert.False(t, responded, "bob's own entry takes precedence over his entry in the group")
+	assert.Empty(t, input)
+}
+
+func TestHasRespondedThroughGroup(t *testing.T) {
+	at := respondedApprovalTask()
+	responded, input := HasResponded(at, "carol", []string{"platform"})
+	assert.True(t, responded)
+	assert.Equal(t, "reject", input)
+
+	responded, _ = HasResponded(at, "carol", []string{"payments"})
+	assert.False(t, responded, "carol is no longer a member of the group")
+
+	responded, _ = HasResponded(at, "dave", []string{"platform"})
+	assert.False(t, responded, "the group-level input is not dave's response")
+}
+
+func TestHasRespondedWithoutResponse(t *testing.T) {
+	at := respondedApprovalTask()
+	responded, input := HasResponded(at, "erin", []string{"platform"})
+	assert.False(t, responded)
+	assert.Empty(t, input)
+
+	// Lapsed approvals and inactive approvers have no response in force
+	respondedAt := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	at.Spec.Approvers[0].ApprovalExpiresAfter = &metav1.Duration{Duration: time.Hour}
+	at.Status.ApproversResponse = []v1alpha1.ApproverState{{Name: "alice", Type: "User", Response: "approved", RespondedAt: &respondedAt}}
+	responded, _ = HasResponded(at, "alice", nil)
+	assert.False(t, responded)
+
+	at.Spec.Approvers[2].WhenLabels = map[string]string{"risk": "high"}
+	responded, _ = HasResponded(at, "carol", []string{"platform"})
+	assert.False(t, responded)
+
+	before := at.DeepCopy()
+	HasResponded(at, "carol", []string{"platform"})
+	assert.Equal(t, *before, at, "the task is left untouched")
+}
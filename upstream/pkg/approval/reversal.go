@@ -0,0 +1,42 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// RejectionFinalAt returns when the rejection of the approval task becomes
+// final, Spec.RejectionReversalWindow after the controller observed it (see
+// ApprovalTaskStatus.RejectedAt). ok is false for tasks that are not
+// rejected, and for rejections that were final at once.
+func RejectionFinalAt(approvalTask v1alpha1.ApprovalTask) (time.Time, bool) {
+	window := approvalTask.Spec.RejectionReversalWindow
+	if approvalTask.Status.State != "rejected" || approvalTask.Status.RejectedAt == nil || window == nil || window.Duration <= 0 {
+		return time.Time{}, false
+	}
+	return approvalTask.Status.RejectedAt.Add(window.Duration), true
+}
+
+// RejectionReversibleAt reports whether the rejection of the approval task
+// can still be reversed at the given time.
+func RejectionReversibleAt(approvalTask v1alpha1.ApprovalTask, now time.Time) bool {
+	finalAt, ok := RejectionFinalAt(approvalTask)
+	return ok && now.Before(finalAt)
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRejectionReversibleWithinWindow(t *testing.T) {
+	rejectedAt := metav1.NewTime(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	at := v1alpha1.ApprovalTask{
+		Spec:   v1alpha1.ApprovalTaskSpec{RejectionReversalWindow: &metav1.Duration{Duration: 5 * time.Minute}},
+		Status: v1alpha1.ApprovalTaskStatus{State: "rejected", RejectedAt: &rejectedAt},
+	}
+
+	finalAt, ok := RejectionFinalAt(at)
+	assert.True(t, ok)
+	assert.Equal(t, rejectedAt.Add(5*time.Minute), finalAt)
+	assert.True(t, RejectionReversibleAt(at, rejectedAt.Add(4*time.Minute)))
+	assert.False(t, RejectionReversibleAt(at, rejectedAt.Add(5*time.Minute)))
+
+	at.Spec.RejectionReversalWindow = nil
+	_, ok = RejectionFinalAt(at)
+	assert.False(t, ok, "rejections are final at once without a window")
+
+	at.Spec.RejectionReversalWindow = &metav1.Duration{Duration: 5 * time.Minute}
+	at.Status.RejectedAt = nil
+	assert.False(t, RejectionReversibleAt(at, rejectedAt.Time), "a rejection not observed with the window, e.g. a timeout, is final")
+
+	at.Status.RejectedAt = &rejectedAt
+	at.Status.State = "pending"
+	_, ok = RejectionFinalAt(at)
+	assert.False(t, ok)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// ApprovingRoles returns the distinct roles of the approver entries whose
+// approvals are counted towards the quorum of the approval task, sorted.
+// Members of a Group approver approve in the role of the group. Approvals
+// from entries without a role count towards the number of approvals but
+// cover no role.
+func ApprovingRoles(approvalTask v1alpha1.ApprovalTask) []string {
+	return ApprovingRolesAt(approvalTask, time.Now())
+}
+
+// ApprovingRolesAt is ApprovingRoles evaluated at the given time (see
+// CountApprovalsAt).
+func ApprovingRolesAt(approvalTask v1alpha1.ApprovalTask, now time.Time) []string {
+	return creditedAt(approvalTask, now, func(counted countedApproval) string { return counted.role })
+}
+
+// MissingRolesAt returns the roles of Spec.RequiredRoles not yet covered by
+// a counted approval at the given time, in the order they are listed.
+func MissingRolesAt(approvalTask v1alpha1.ApprovalTask, now time.Time) []string {
+	if len(approvalTask.Spec.RequiredRoles) == 0 {
+		return nil
+	}
+	covered := make(map[string]bool)
+	for _, role := range ApprovingRolesAt(approvalTask, now) {
+		covered[role] = true
+	}
+	var missing []string
+	for _, role := range approvalTask.Spec.RequiredRoles {
+		if !covered[role] {
+			missing = append(missing, role)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func roleApprovalTask() v1alpha1.ApprovalTask {
+	return v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			RequiredRoles:             []string{"manager", "peer"},
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve", Role: "manager"},
+				{Name: "bob", Type: "User", Input: "approve", Role: "manager"},
+				{Name: "reviewers", Type: "Group", Input: "pending", Role: "peer", Users: []v1alpha1.UserDetails{
+					{Name: "carol", Input: "pending"},
+				}},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func TestRequiredRolesKeepManagerOnlyApprovalsPending(t *testing.T) {
+	at := roleApprovalTask()
+	assert.Equal(t, 2, CountApprovals(at))
+	assert.Equal(t, []string{"manager"}, ApprovingRoles(at))
+	assert.Equal(t, []string{"peer"}, MissingRolesAt(at, time.Now()))
+	assert.False(t, QuorumReached(at), "only managers approved, the peer requirement is not met")
+	assert.Equal(t, 1, RemainingApprovals(at), "a peer must still approve")
+
+	// A peer approving through the group covers the peer role
+	at.Spec.Approvers[2].Input = "approve"
+	at.Spec.Approvers[2].Users[0].Input = "approve"
+	assert.Equal(t, []string{"manager", "peer"}, ApprovingRoles(at))
+	assert.Empty(t, MissingRolesAt(at, time.Now()))
+	assert.True(t, QuorumReached(at))
+	assert.Equal(t, 0, RemainingApprovals(at))
+}
+
+func TestRequiredRolesComeOnTopOfTheCount(t *testing.T) {
+	at := roleApprovalTask()
+	at.Spec.NumberOfApprovalsRequired = 3
+	at.Spec.Approvers[1].Input = "pending"
+	at.Spec.Approvers[2].Input = "approve"
+	at.Spec.Approvers[2].Users[0].Input = "approve"
+	assert.Empty(t, MissingRolesAt(at, time.Now()))
+	assert.False(t, QuorumReached(at), "every role is covered but the count is not reached")
+	assert.Equal(t, 1, RemainingApprovals(at))
+
+	at.Spec.Approvers[1].Input = "approve"
+	assert.True(t, QuorumReached(at))
+}
+
+func TestRequiredRolesIgnoreUncountedApprovals(t *testing.T) {
+	at := roleApprovalTask()
+	at.Spec.Approvers[2].Input = "approve"
+	at.Spec.Approvers[2].Users[0].Input = "approve"
+	at.Spec.Approvers[2].WhenLabels = map[string]string{"risk": "high"}
+	assert.Equal(t, []string{"manager"}, ApprovingRoles(at), "the reviewers group is inactive")
+	assert.False(t, QuorumReached(at))
+
+	at.Spec.RequiredRoles = nil
+	assert.True(t, QuorumReached(at), "roles do not matter without RequiredRoles")
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// RequiredApprovalsAt returns the number of approvals the approval task
+// requires at now: BaseApprovalsRequired, replaced by the latest
+// step of Spec.QuorumSchedule the task has been pending long enough for, and
+// raised to the minimum of the policy requirements in its status.
+func RequiredApprovalsAt(approvalTask v1alpha1.ApprovalTask, now time.Time) int {
+	return max(scheduledApprovalsAt(approvalTask, now), policyMinimum(approvalTask))
+}
+
+// scheduledApprovalsAt is RequiredApprovalsAt without the policy minimum.
+func scheduledApprovalsAt(approvalTask v1alpha1.ApprovalTask, now time.Time) int {
+	required := BaseApprovalsRequired(approvalTask)
+	start, ok := scheduleStart(approvalTask)
+	if !ok {
+		return required
+	}
+
+	var latest time.Duration = -1
+	for _, step := range approvalTask.Spec.QuorumSchedule {
+		if !now.Before(start.Add(step.After.Duration)) && step.After.Duration > latest {
+			required, latest = step.NumberOfApprovalsRequired, step.After.Duration
+		}
+	}
+	return required
+}
+
+// MinRequiredApprovals returns the lowest number of approvals the approval
+// task will ever require, once its whole schedule has elapsed.
+func MinRequiredApprovals(approvalTask v1alpha1.ApprovalTask) int {
+	required := BaseApprovalsRequired(approvalTask)
+	for _, step := range approvalTask.Spec.QuorumSchedule {
+		if step.NumberOfApprovalsRequired < required {
+			required = step.NumberOfApprovalsRequired
+		}
+	}
+	return max(required, policyMinimum(approvalTask))
+}
+
+// NextQuorumChange returns the earliest time after now at which a step of the
+// quorum schedule of the approval task takes effect, if any is left.
+func NextQuorumChange(approvalTask v1alpha1.ApprovalTask, now time.Time) (time.Time, bool) {
+	start, ok := scheduleStart(approvalTask)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	var next time.Time
+	found := false
+	for _, step := range approvalTask.Spec.QuorumSchedule {
+		at := start.Add(step.After.Duration)
+		if at.After(now) && (!found || at.Before(next)) {
+			next, found = at, true
+		}
+	}
+	return next, found
+}
+
+// scheduleStart returns the time the quorum schedule is measured from: when
+// the controller started the task, or else when it was created.
+func scheduleStart(approvalTask v1alpha1.ApprovalTask) (time.Time, bool) {
+	if approvalTask.Status.StartTime != nil {
+		return approvalTask.Status.StartTime.Time, true
+	}
+	if !approvalTask.CreationTimestamp.IsZero() {
+		return approvalTask.CreationTimestamp.Time, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func scheduledApprovalTask(start time.Time) v1alpha1.ApprovalTask {
+	startTime := metav1.NewTime(start)
+	return v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 3,
+			QuorumSchedule: []v1alpha1.QuorumStep{
+				{After: metav1.Duration{Duration: time.Hour}, NumberOfApprovalsRequired: 2},
+				{After: metav1.Duration{Duration: 4 * time.Hour}, NumberOfApprovalsRequired: 1},
+			},
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+				{Name: "bob", Type: "User", Input: "approve"},
+				{Name: "carol", Type: "User", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{StartTime: &startTime},
+	}
+}
+
+func TestRequiredApprovalsAt(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	at := scheduledApprovalTask(start)
+
+	assert.Equal(t, 3, RequiredApprovalsAt(at, start))
+	assert.Equal(t, 3, RequiredApprovalsAt(at, start.Add(time.Hour-time.Second)))
+	assert.Equal(t, 2, RequiredApprovalsAt(at, start.Add(time.Hour)))
+	assert.Equal(t, 2, RequiredApprovalsAt(at, start.Add(3*time.Hour)))
+	assert.Equal(t, 1, RequiredApprovalsAt(at, start.Add(5*time.Hour)))
+	assert.Equal(t, 1, MinRequiredApprovals(at))
+
+	// Without a start the schedule has nothing to be measured from
+	at.Status.StartTime = nil
+	assert.Equal(t, 3, RequiredApprovalsAt(at, start.Add(5*time.Hour)))
+}
+
+func TestQuorumReachedAtFollowsSchedule(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	at := scheduledApprovalTask(start)
+
+	assert.False(t, QuorumReachedAt(at, start.Add(30*time.Minute)), "two approvals are not enough at first")
+	assert.True(t, QuorumReachedAt(at, start.Add(90*time.Minute)), "two approvals are enough after an hour")
+}
+
+func TestNextQuorumChange(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	at := scheduledApprovalTask(start)
+
+	next, ok := NextQuorumChange(at, start.Add(time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(time.Hour), next)
+
+	next, ok = NextQuorumChange(at, start.Add(time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(4*time.Hour), next)
+
+	_, ok = NextQuorumChange(at, start.Add(4*time.Hour))
+	assert.False(t, ok)
+}
+
+func TestUnsatisfiableUsesRelaxedQuorum(t *testing.T) {
+	at := scheduledApprovalTask(time.Now())
+	at.Spec.Approvers = at.Spec.Approvers[:1]
+	assert.False(t, Unsatisfiable(at), "one approver can still satisfy the relaxed quorum")
+
+	at.Spec.QuorumSchedule = nil
+	assert.True(t, Unsatisfiable(at))
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// PendingApprovers returns, in the order of Spec.Approvers, the active
+// approvers of the approval task that can still approve it but have no
+// approval in force, including those whose approval lapsed.
+func PendingApprovers(approvalTask v1alpha1.ApprovalTask) []string {
+	return PendingApproversAt(approvalTask, time.Now())
+}
+
+// PendingApproversAt is PendingApprovers evaluated at the given time.
+func PendingApproversAt(approvalTask v1alpha1.ApprovalTask, now time.Time) []string {
+	var pending []string
+	for _, approver := range approvalTask.Spec.Approvers {
+		if !ApproverActive(approvalTask, approver) || !canApprove(approver) || approver.Input == inputReject {
+			continue
+		}
+		if !approvedAt(approvalTask, approver, now) {
+			pending = append(pending, approver.Name)
+		}
+	}
+	return pending
+}
+
+// StatusSummary renders the progress of the approval task on a single line
+// for people, for example "2/3 approved, waiting on: alice, platform-team.",
+// "1/2 approved, changes requested by carol, waiting on: alice." or
+// "1/2 approved, rejected by bob.".
+func StatusSummary(approvalTask v1alpha1.ApprovalTask) string {
+	return StatusSummaryAt(approvalTask, time.Now())
+}
+
+// StatusSummaryAt is StatusSummary evaluated at the given time.
+func StatusSummaryAt(approvalTask v1alpha1.ApprovalTask, now time.Time) string {
+	var b strings.Builder
+	b.Grow(64)
+	b.WriteString(strconv.Itoa(CountApprovalsAt(approvalTask, now)))
+	b.WriteByte('/')
+	b.WriteString(strconv.Itoa(RequiredApprovalsAt(approvalTask, now)))
+	b.WriteString(" approved")
+
+	switch approvalTask.Status.State {
+	case "approved":
+	case "rejected":
+		b.WriteString(", rejected")
+		first := true
+		for _, response := range approvalTask.Status.ApproversResponse {
+			if response.Response != "rejected" {
+				continue
+			}
+			if first {
+				b.WriteString(" by ")
+				first = false
+			} else {
+				b.WriteString(", ")
+			}
+			b.WriteString(response.Name)
+		}
+	case "withdrawn":
+		b.WriteString(", withdrawn")
+	default:
+		changesRequested := ChangesRequestedBy(approvalTask)
+		for i, name := range changesRequested {
+			if i == 0 {
+				b.WriteString(", changes requested by ")
+			} else {
+				b.WriteString(", ")
+			}
+			b.WriteString(name)
+		}
+		first := true
+		for _, name := range PendingApproversAt(approvalTask, now) {
+			if slices.Contains(changesRequested, name) {
+				continue
+			}
+			if first {
+				b.WriteString(", waiting on: ")
+				first = false
+			} else {
+				b.WriteString(", ")
+			}
+			b.WriteString(name)
+		}
+	}
+	b.WriteByte('.')
+	return b.String()
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func summaryApprovalTask() v1alpha1.ApprovalTask {
+	return v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 3,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "bob", Type: "User", Input: "approve"},
+				{Name: "carol", Type: "User", Input: "approve"},
+				{Name: "platform-team", Type: "Group", Input: "pending"},
+				{Name: "auditor", Type: "User", Input: "pending", AllowedInputs: []string{"reject"}},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func TestStatusSummaryPending(t *testing.T) {
+	at := summaryApprovalTask()
+	assert.Equal(t, []string{"alice", "platform-team"}, PendingApprovers(at), "the auditor can only reject")
+	assert.Equal(t, "2/3 approved, waiting on: alice, platform-team.", StatusSummary(at))
+}
+
+func TestStatusSummaryApproved(t *testing.T) {
+	at := summaryApprovalTask()
+	at.Spec.Approvers[0].Input = "approve"
+	at.Status.State = "approved"
+	assert.Equal(t, "3/3 approved.", StatusSummary(at))
+}
+
+func TestStatusSummaryRejected(t *testing.T) {
+	at := summaryApprovalTask()
+	at.Spec.Approvers[4].Input = "reject"
+	at.Status.State = "rejected"
+	at.Status.ApproversResponse = []v1alpha1.ApproverState{
+		{Name: "bob", Type: "User", Response: "approved"},
+		{Name: "carol", Type: "User", Response: "approved"},
+		{Name: "auditor", Type: "User", Response: "rejected"},
+	}
+	assert.Equal(t, "2/3 approved, rejected by auditor.", StatusSummary(at))
+
+	at.Status.ApproversResponse = nil
+	assert.Equal(t, "2/3 approved, rejected.", StatusSummary(at), "a timed out task has no rejecting approver")
+
+	at.Status.State = "withdrawn"
+	assert.Equal(t, "2/3 approved, withdrawn.", StatusSummary(at))
+}
+
+func TestStatusSummaryExpiredApproval(t *testing.T) {
+	respondedAt := metav1.NewTime(time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC))
+	at := summaryApprovalTask()
+	at.Spec.Approvers[1].ApprovalExpiresAfter = &metav1.Duration{Duration: time.Hour}
+	at.Status.ApproversResponse = []v1alpha1.ApproverState{
+		{Name: "bob", Type: "User", Response: "approved", RespondedAt: &respondedAt},
+	}
+
+	assert.Equal(t, "2/3 approved, waiting on: alice, platform-team.", StatusSummaryAt(at, respondedAt.Add(30*time.Minute)))
+	assert.Equal(t, "1/3 approved, waiting on: alice, bob, platform-team.", StatusSummaryAt(at, respondedAt.Add(2*time.Hour)), "bob must renew")
+}
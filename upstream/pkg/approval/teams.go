@@ -0,0 +1,46 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// ApprovingTeams returns the distinct teams recorded with the approvals
+// counted towards the quorum of the approval task, sorted. Approvals
+// recorded without a team count towards the number of approvals but not
+// towards Spec.MinApprovingTeams.
+func ApprovingTeams(approvalTask v1alpha1.ApprovalTask) []string {
+	return ApprovingTeamsAt(approvalTask, time.Now())
+}
+
+// ApprovingTeamsAt is ApprovingTeams evaluated at the given time (see
+// CountApprovalsAt).
+func ApprovingTeamsAt(approvalTask v1alpha1.ApprovalTask, now time.Time) []string {
+	return creditedAt(approvalTask, now, func(counted countedApproval) string { return counted.team })
+}
+
+// MissingTeamsAt returns how many more teams must approve the approval task
+// at the given time for its approvals to span Spec.MinApprovingTeams teams.
+func MissingTeamsAt(approvalTask v1alpha1.ApprovalTask, now time.Time) int {
+	if approvalTask.Spec.MinApprovingTeams <= 0 {
+		return 0
+	}
+	return max(approvalTask.Spec.MinApprovingTeams-len(ApprovingTeamsAt(approvalTask, now)), 0)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func teamApprovalTask() v1alpha1.ApprovalTask {
+	return v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			MinApprovingTeams:         2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve", Team: "payments"},
+				{Name: "bob", Type: "User", Input: "approve", Team: "payments"},
+				{Name: "platform", Type: "Group", Input: "pending", Users: []v1alpha1.UserDetails{
+					{Name: "carol", Input: "pending", Team: "platform"},
+				}},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func TestApprovingTeamsShared(t *testing.T) {
+	at := teamApprovalTask()
+	assert.Equal(t, []string{"payments"}, ApprovingTeams(at))
+	assert.Equal(t, 2, CountApprovals(at))
+	assert.False(t, QuorumReached(at), "alice and bob are both on the payments team")
+	assert.Equal(t, 1, RemainingApprovals(at), "one more team must approve")
+
+	at.Spec.Approvers[2].Input = "approve"
+	at.Spec.Approvers[2].Users[0].Input = "approve"
+	assert.Equal(t, []string{"payments", "platform"}, ApprovingTeams(at))
+	assert.True(t, QuorumReached(at))
+	assert.Equal(t, 0, RemainingApprovals(at))
+}
+
+func TestApprovingTeamsIgnoreUncountedApprovals(t *testing.T) {
+	at := teamApprovalTask()
+	at.Spec.Approvers[1].Team = ""
+	at.Spec.Approvers[2].Input = "approve"
+	at.Spec.Approvers[2].Users[0].Input = "approve"
+	at.Spec.Approvers[2].WhenLabels = map[string]string{"risk": "high"}
+	assert.Equal(t, []string{"payments"}, ApprovingTeams(at), "the platform group is inactive and bob recorded no team")
+	assert.False(t, QuorumReached(at))
+
+	at.Labels = map[string]string{"risk": "high"}
+	assert.Equal(t, []string{"payments", "platform"}, ApprovingTeams(at))
+	assert.True(t, QuorumReached(at))
+
+	at.Spec.MinApprovingTeams = 0
+	at.Spec.Approvers[2].Users[0].Team = "payments"
+	assert.True(t, QuorumReached(at), "teams do not matter without MinApprovingTeams")
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package callback delivers a signed HTTP notification to an external
+// system when an ApprovalTask reaches a final state or is deleted.
+package callback
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/correlation"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	// SignatureHeader carries the hex encoded HMAC-SHA256 of the request body.
+	SignatureHeader = "X-Approval-Signature"
+
+	defaultRetries = 5
+	defaultBackoff = time.Second
+	defaultTimeout = 10 * time.Second
+)
+
+// Payload is the summary of a finalized ApprovalTask posted to the callback URL.
+type Payload struct {
+	Namespace         string                   `json:"namespace"`
+	Name              string                   `json:"name"`
+	State             string                   `json:"state"`
+	ApprovalsRequired int                      `json:"approvalsRequired"`
+	ApprovalsReceived int                      `json:"approvalsReceived"`
+	ApproversResponse []v1alpha1.ApproverState `json:"approversResponse,omitempty"`
+	// Deleted is set when the payload reports the deletion of the task.
+	Deleted bool `json:"deleted,omitempty"`
+	// CorrelationID is the correlation ID of the task, also sent in the
+	// correlation.Header header.
+	CorrelationID string `json:"correlationId,omitempty"`
+}
+
+// NewPayload builds the callback payload for an approval task.
+func NewPayload(approvalTask v1alpha1.ApprovalTask) Payload {
+	return Payload{
+		Namespace:         approvalTask.Namespace,
+		Name:              approvalTask.Name,
+		State:             approvalTask.Status.State,
+		ApprovalsRequired: approvalTask.Status.ApprovalsRequired,
+		ApprovalsReceived: approvalTask.Status.ApprovalsReceived,
+		ApproversResponse: approvalTask.Status.ApproversResponse,
+		CorrelationID:     correlation.Of(&approvalTask),
+	}
+}
+
+// Notifier posts payloads to URL, signing them with Secret.
+type Notifier struct {
+	URL    string
+	Secret []byte
+
+	// Retries is the number of delivery attempts before the payload is
+	// dead-lettered to the log. Defaults to 5.
+	Retries int
+	// Backoff is the delay before the first retry, doubled on every
+	// subsequent attempt. Defaults to one second.
+	Backoff time.Duration
+	// Client is the HTTP client used for delivery.
+	Client *http.Client
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body using secret.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notify delivers the payload in the background so that it never blocks the
+// caller. Persistent failures are dead-lettered to the log.
+func (n *Notifier) Notify(ctx context.Context, payload Payload) {
+	if n == nil || n.URL == "" {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := n.Deliver(ctx, payload); err != nil {
+			body, _ := json.Marshal(payload)
+			logging.FromContext(ctx).Errorf("dead-letter: failed to deliver approval callback for %s/%s: %v, payload: %s",
+				payload.Namespace, payload.Name, err, string(body))
+		}
+	}()
+}
+
+// Cleanup posts that the approval task was deleted, in a single attempt so
+// that the caller decides when to retry.
+func (n *Notifier) Cleanup(ctx context.Context, approvalTask v1alpha1.ApprovalTask) error {
+	if n == nil || n.URL == "" {
+		return nil
+	}
+	payload := NewPayload(approvalTask)
+	payload.Deleted = true
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return n.post(ctx, payload.CorrelationID, body)
+}
+
+// Deliver posts the payload synchronously, retrying with exponential backoff.
+func (n *Notifier) Deliver(ctx context.Context, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	retries := n.Retries
+	if retries <= 0 {
+		retries = defaultRetries
+	}
+	backoff := n.Backoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = n.post(ctx, payload.CorrelationID, body)
+		if err == nil || attempt >= retries {
+			return err
+		}
+		logging.FromContext(ctx).Warnf("approval callback attempt %d for %s/%s failed: %v", attempt, payload.Namespace, payload.Name, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, correlationID string, body []byte) error {
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.Secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(n.Secret, body))
+	}
+	if correlationID != "" {
+		req.Header.Set(correlation.Header, correlationID)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package callback
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func finalizedApprovalTask() v1alpha1.ApprovalTask {
+	return v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Status: v1alpha1.ApprovalTaskStatus{
+			State:             "approved",
+			ApprovalsRequired: 1,
+			ApprovalsReceived: 1,
+			ApproversResponse: []v1alpha1.ApproverState{{Name: "alice", Type: "User", Response: "approved"}},
+		},
+	}
+}
+
+func TestNotifierDeliverSignsPayload(t *testing.T) {
+	secret := []byte("s3cr3t")
+	received := make(chan Payload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "sha256="+Sign(secret, body), r.Header.Get(SignatureHeader))
+		var p Payload
+		assert.NoError(t, json.Unmarshal(body, &p))
+		received <- p
+	}))
+	defer server.Close()
+
+	n := &Notifier{URL: server.URL, Secret: secret}
+	assert.NoError(t, n.Deliver(context.Background(), NewPayload(finalizedApprovalTask())))
+
+	p := <-received
+	assert.Equal(t, "production", p.Namespace)
+	assert.Equal(t, "deploy", p.Name)
+	assert.Equal(t, "approved", p.State)
+	assert.Equal(t, "alice", p.ApproversResponse[0].Name)
+}
+
+func TestNotifierDeliverRetriesWithBackoff(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	n := &Notifier{URL: server.URL, Retries: 3, Backoff: time.Millisecond}
+	assert.NoError(t, n.Deliver(context.Background(), NewPayload(finalizedApprovalTask())))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestNotifierDeliverGivesUp(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	n := &Notifier{URL: server.URL, Retries: 2, Backoff: time.Millisecond}
+	assert.Error(t, n.Deliver(context.Background(), NewPayload(finalizedApprovalTask())))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestNotifierNotifyDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		close(done)
+	}))
+	defer server.Close()
+
+	n := &Notifier{URL: server.URL}
+	n.Notify(context.Background(), NewPayload(finalizedApprovalTask()))
+	close(release)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback was never delivered")
+	}
+
+	var nilNotifier *Notifier
+	nilNotifier.Notify(context.Background(), NewPayload(finalizedApprovalTask()))
+}
+
+func TestNotifierCleanupReportsDeletion(t *testing.T) {
+	received := make(chan Payload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p Payload
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&p))
+		received <- p
+	}))
+	defer server.Close()
+
+	n := &Notifier{URL: server.URL}
+	assert.NoError(t, n.Cleanup(context.Background(), finalizedApprovalTask()))
+	p := <-received
+	assert.True(t, p.Deleted)
+	assert.Equal(t, "deploy", p.Name)
+
+	var calls int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	n = &Notifier{URL: failing.URL, Retries: 3, Backoff: time.Millisecond}
+	assert.Error(t, n.Cleanup(context.Background(), finalizedApprovalTask()))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "cleanup is retried by the caller")
+}
+
+func TestNotifierDeliverCarriesCorrelationID(t *testing.T) {
+	type delivery struct {
+		header  string
+		payload Payload
+	}
+	received := make(chan delivery, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p Payload
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&p))
+		received <- delivery{header: r.Header.Get("X-Correlation-ID"), payload: p}
+	}))
+	defer server.Close()
+
+	at := finalizedApprovalTask()
+	at.Annotations = map[string]string{v1alpha1.CorrelationIDAnnotationKey: "release-42"}
+	n := &Notifier{URL: server.URL}
+	assert.NoError(t, n.Deliver(context.Background(), NewPayload(at)))
+	d := <-received
+	assert.Equal(t, "release-42", d.header)
+	assert.Equal(t, "release-42", d.payload.CorrelationID)
+
+	assert.NoError(t, n.Deliver(context.Background(), NewPayload(finalizedApprovalTask())))
+	d = <-received
+	assert.Empty(t, d.header, "tasks without a correlation ID send none")
+	assert.Empty(t, d.payload.CorrelationID)
+}
@@ -42,7 +42,7 @@ func Command(p cli.Params) *cobra.Command {
 				return err
 			}
 
-			message := opts.Message
+			message, digest := opts.Message, opts.Digest
 
 			opts = &cli.Options{
 				Name:      args[0],
@@ -50,6 +50,7 @@ func Command(p cli.Params) *cobra.Command {
 				Input:     "approve",
 				Username:  username,
 				Message:   message,
+				Digest:    digest,
 				Groups:    groups,
 			}
 
@@ -66,6 +67,7 @@ func Command(p cli.Params) *cobra.Command {
 	}
 
 	c.Flags().StringVarP(&opts.Message, "message", "m", "", "message while approving the approvalTask")
+	c.Flags().StringVar(&opts.Digest, "digest", "", "digest of the reviewed artifact, acknowledged with the approval")
 
 	flags.AddOptions(c)
 
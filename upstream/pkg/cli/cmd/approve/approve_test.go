@@ -1,6 +1,7 @@
 package approve
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -11,6 +12,8 @@ import (
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 )
 
@@ -269,3 +272,70 @@ func command(t *testing.T, approvaltasks []*v1alpha1.ApprovalTask, ns []*corev1.
 
 	return Command(p)
 }
+
+func TestApproveAcknowledgesDigest(t *testing.T) {
+	approvaltasks := []*v1alpha1.ApprovalTask{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "at-digest",
+				Namespace: "foo",
+			},
+			Spec: v1alpha1.ApprovalTaskSpec{
+				Approvers: []v1alpha1.ApproverDetails{
+					{
+						Name:  "tekton",
+						Input: "pending",
+						Type:  "User",
+					},
+					{
+						Name:  "admin-group",
+						Input: "pending",
+						Type:  "Group",
+					},
+				},
+				NumberOfApprovalsRequired: 2,
+				ExpectedDigest:            "sha256:4c1e2f",
+			},
+			Status: v1alpha1.ApprovalTaskStatus{
+				Approvers: []string{
+					"tekton",
+					"admin-group",
+				},
+				State: "pending",
+			},
+		},
+	}
+	ns := []*corev1.Namespace{{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}}
+
+	dc, err := testDynamic.Client(cb.UnstructuredV1alpha1(approvaltasks[0], "v1alpha1"))
+	if err != nil {
+		t.Fatalf("unable to create dynamic client: %v", err)
+	}
+	approvalTask := func() *v1alpha1.ApprovalTask {
+		gvr := schema.GroupVersionResource{Group: "openshift-pipelines.org", Version: "v1alpha1", Resource: "approvaltasks"}
+		obj, err := dc.Resource(gvr).Namespace("foo").Get(context.Background(), "at-digest", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unable to get approval task: %v", err)
+		}
+		at := &v1alpha1.ApprovalTask{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, at); err != nil {
+			t.Fatalf("unable to convert approval task: %v", err)
+		}
+		return at
+	}
+
+	if _, err := test.ExecuteCommand(command(t, approvaltasks, ns, dc, "tekton", []string{}), "at-digest", "-n", "foo", "--digest", "sha256:4c1e2f"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := approvalTask().Spec.Approvers[0].AcknowledgedDigest; got != "sha256:4c1e2f" {
+		t.Errorf("Expected the approver to acknowledge %q, but got %q", "sha256:4c1e2f", got)
+	}
+
+	if _, err := test.ExecuteCommand(command(t, approvaltasks, ns, dc, "bob", []string{"admin-group"}), "at-digest", "-n", "foo", "--digest", "sha256:4c1e2f"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	users := approvalTask().Spec.Approvers[1].Users
+	if len(users) != 1 || users[0].AcknowledgedDigest != "sha256:4c1e2f" {
+		t.Errorf("Expected the group member to acknowledge %q, but got %+v", "sha256:4c1e2f", users)
+	}
+}
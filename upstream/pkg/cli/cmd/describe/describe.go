@@ -8,6 +8,7 @@ import (
 
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/actions"
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/cli"
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/cli/flags"
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/cli/formatter"
@@ -38,7 +39,7 @@ Name	ApproverResponse	Message
 {{$user}}{{if gt (len $groups.Groups) 0}}({{$groups.GroupsStr}}){{end}}	{{response $groups.Response}}	{{message $groups.Message}}
 {{- end}}
 {{- range .ApprovalTask.Status.ApproversResponse}}
-{{- if eq .Type "User"}}
+{{- if or (eq .Type "User") (eq .Type "Email")}}
 {{.Name}}	{{response .Response}}	{{message .Message}}
 {{- end}}
 {{- end}}
@@ -59,7 +60,7 @@ func pendingApprovals(at *v1alpha1.ApprovalTask) int {
 	respondedUsers := make(map[string]bool)
 
 	for _, approver := range at.Status.ApproversResponse {
-		if v1alpha1.DefaultedApproverType(approver.Type) == "User" {
+		if v1alpha1.IsIndividualApproverType(approver.Type) {
 			respondedUsers[approver.Name] = true
 		} else if v1alpha1.DefaultedApproverType(approver.Type) == "Group" {
 			// Count individual group members who have responded
@@ -71,7 +72,7 @@ func pendingApprovals(at *v1alpha1.ApprovalTask) int {
 		}
 	}
 
-	return at.Spec.NumberOfApprovalsRequired - len(respondedUsers)
+	return approval.BaseApprovalsRequired(*at) - len(respondedUsers)
 }
 
 func pipelineRunRef(at *v1alpha1.ApprovalTask) string {
@@ -9,6 +9,7 @@ import (
 	"github.com/fatih/color"
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/actions"
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
 	cli "github.com/openshift-pipelines/manual-approval-gate/pkg/cli"
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/cli/flags"
 	"github.com/spf13/cobra"
@@ -45,7 +46,7 @@ func pendingApprovals(at *v1alpha1.ApprovalTask) int {
 	respondedUsers := make(map[string]bool)
 
 	for _, approver := range at.Status.ApproversResponse {
-		if v1alpha1.DefaultedApproverType(approver.Type) == "User" {
+		if v1alpha1.IsIndividualApproverType(approver.Type) {
 			respondedUsers[approver.Name] = true
 		} else if v1alpha1.DefaultedApproverType(approver.Type) == "Group" {
 			// Count individual group members who have responded
@@ -57,7 +58,7 @@ func pendingApprovals(at *v1alpha1.ApprovalTask) int {
 		}
 	}
 
-	return at.Spec.NumberOfApprovalsRequired - len(respondedUsers)
+	return approval.BaseApprovalsRequired(*at) - len(respondedUsers)
 }
 
 func rejected(at *v1alpha1.ApprovalTask) int {
@@ -65,7 +66,7 @@ func rejected(at *v1alpha1.ApprovalTask) int {
 	rejectedUsers := make(map[string]bool)
 
 	for _, approver := range at.Status.ApproversResponse {
-		if v1alpha1.DefaultedApproverType(approver.Type) == "User" && approver.Response == "rejected" {
+		if v1alpha1.IsIndividualApproverType(approver.Type) && approver.Response == "rejected" {
 			if !rejectedUsers[approver.Name] {
 				rejectedUsers[approver.Name] = true
 				count++
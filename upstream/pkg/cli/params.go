@@ -2,7 +2,6 @@ package cli
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned"
 	userv1typedclient "github.com/openshift/client-go/user/clientset/versioned/typed/user/v1"
@@ -36,6 +35,7 @@ type Options struct {
 	Input         string
 	Username      string
 	Message       string
+	Digest        string
 	AllNamespaces bool
 	Groups        []string
 }
@@ -209,9 +209,8 @@ func getUserInfo(authV1Client *authenticationv1client.AuthenticationV1Client, us
 	if err == nil {
 		username = res.Status.UserInfo.Username
 		return username, res.Status.UserInfo.Groups, nil
-	} else {
-		fmt.Errorf("selfsubjectreview request error %v, falling back to user object", err)
 	}
+	// Clusters without the SelfSubjectReview API fall back to the user object
 
 	user, err := userInterface.Users().Get(context.TODO(), "~", metav1.GetOptions{})
 	if err != nil {
@@ -29,6 +29,7 @@ import (
 type OpenshiftpipelinesV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	ApprovalTasksGetter
+	ClusterApprovalPoliciesGetter
 }
 
 // OpenshiftpipelinesV1alpha1Client is used to interact with features provided by the openshiftpipelines.org group.
@@ -40,6 +41,10 @@ func (c *OpenshiftpipelinesV1alpha1Client) ApprovalTasks(namespace string) Appro
 	return newApprovalTasks(c, namespace)
 }
 
+func (c *OpenshiftpipelinesV1alpha1Client) ClusterApprovalPolicies() ClusterApprovalPolicyInterface {
+	return newClusterApprovalPolicies(c)
+}
+
 // NewForConfig creates a new OpenshiftpipelinesV1alpha1Client for the given config.
 // NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
 // where httpClient was generated with rest.HTTPClientFor(c).
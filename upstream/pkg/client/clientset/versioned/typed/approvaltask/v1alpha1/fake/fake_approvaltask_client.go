@@ -32,6 +32,10 @@ func (c *FakeOpenshiftpipelinesV1alpha1) ApprovalTasks(namespace string) v1alpha
 	return newFakeApprovalTasks(c, namespace)
 }
 
+func (c *FakeOpenshiftpipelinesV1alpha1) ClusterApprovalPolicies() v1alpha1.ClusterApprovalPolicyInterface {
+	return newFakeClusterApprovalPolicies(c)
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeOpenshiftpipelinesV1alpha1) RESTClient() rest.Interface {
@@ -26,6 +26,8 @@ import (
 type Interface interface {
 	// ApprovalTasks returns a ApprovalTaskInformer.
 	ApprovalTasks() ApprovalTaskInformer
+	// ClusterApprovalPolicies returns a ClusterApprovalPolicyInformer.
+	ClusterApprovalPolicies() ClusterApprovalPolicyInformer
 }
 
 type version struct {
@@ -43,3 +45,8 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 func (v *version) ApprovalTasks() ApprovalTaskInformer {
 	return &approvalTaskInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// ClusterApprovalPolicies returns a ClusterApprovalPolicyInformer.
+func (v *version) ClusterApprovalPolicies() ClusterApprovalPolicyInformer {
+	return &clusterApprovalPolicyInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
@@ -55,6 +55,8 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	// Group=openshiftpipelines.org, Version=v1alpha1
 	case v1alpha1.SchemeGroupVersion.WithResource("approvaltasks"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Openshiftpipelines().V1alpha1().ApprovalTasks().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("clusterapprovalpolicies"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Openshiftpipelines().V1alpha1().ClusterApprovalPolicies().Informer()}, nil
 
 	}
 
@@ -25,3 +25,7 @@ type ApprovalTaskListerExpansion interface{}
 // ApprovalTaskNamespaceListerExpansion allows custom methods to be added to
 // ApprovalTaskNamespaceLister.
 type ApprovalTaskNamespaceListerExpansion interface{}
+
+// ClusterApprovalPolicyListerExpansion allows custom methods to be added to
+// ClusterApprovalPolicyLister.
+type ClusterApprovalPolicyListerExpansion interface{}
@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package correlation carries the correlation ID of an approval, recorded in
+// the CorrelationIDAnnotationKey annotation, into the logs, metric exemplars
+// and outbound requests of the gate.
+package correlation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"go.opencensus.io/metric/metricdata"
+	"go.opencensus.io/stats"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	// Header carries the correlation ID on outbound HTTP requests.
+	Header = "X-Correlation-ID"
+	// LogKey is the field of log entries holding the correlation ID.
+	LogKey = "correlationID"
+	// ExemplarKey is the attachment of metric exemplars holding the
+	// correlation ID.
+	ExemplarKey = "correlation_id"
+)
+
+// New returns a random correlation ID.
+func New() string {
+	b := make([]byte, 16)
+	// crypto/rand.Read never returns an error
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Of returns the correlation ID annotated on obj, or an empty string.
+func Of(obj metav1.Object) string {
+	return obj.GetAnnotations()[v1alpha1.CorrelationIDAnnotationKey]
+}
+
+// WithLogger returns ctx with its logger recording id in every entry. The
+// context is returned unchanged when id is empty.
+func WithLogger(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return logging.WithLogger(ctx, logging.FromContext(ctx).With(LogKey, id))
+}
+
+// Exemplar returns the recording option attaching id to the exemplar of a
+// distribution measurement.
+func Exemplar(id string) stats.Options {
+	if id == "" {
+		return stats.WithAttachments(nil)
+	}
+	return stats.WithAttachments(metricdata.Attachments{ExemplarKey: id})
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package correlation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
+)
+
+func TestNew(t *testing.T) {
+	id := New()
+	assert.Regexp(t, "^[0-9a-f]{32}$", id)
+	assert.NotEqual(t, id, New())
+}
+
+func TestOf(t *testing.T) {
+	at := &v1alpha1.ApprovalTask{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{v1alpha1.CorrelationIDAnnotationKey: "release-42"},
+	}}
+	assert.Equal(t, "release-42", Of(at))
+	assert.Equal(t, "", Of(&v1alpha1.ApprovalTask{}))
+}
+
+func TestWithLogger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	ctx := logging.WithLogger(context.Background(), zap.New(core).Sugar())
+
+	logging.FromContext(WithLogger(ctx, "release-42")).Info("traced")
+	logging.FromContext(WithLogger(ctx, "")).Info("untraced")
+
+	entries := logs.All()
+	assert.Equal(t, map[string]interface{}{LogKey: "release-42"}, entries[0].ContextMap())
+	assert.Empty(t, entries[1].ContextMap())
+}
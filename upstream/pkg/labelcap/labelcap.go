@@ -0,0 +1,56 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package labelcap bounds the cardinality of metric labels taking values,
+// such as namespaces, that are not known in advance.
+package labelcap
+
+import "sync"
+
+// DefaultLimit is the default number of distinct values a capped label takes
+// before further values are dropped.
+const DefaultLimit = 100
+
+// Cap remembers the values a label has taken and admits new ones only until
+// its limit of distinct values has been seen.
+type Cap struct {
+	mu     sync.Mutex
+	limit  int
+	values map[string]struct{}
+}
+
+// New returns a Cap admitting limit distinct values, or DefaultLimit when
+// limit is not positive.
+func New(limit int) *Cap {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	return &Cap{limit: limit, values: make(map[string]struct{})}
+}
+
+// Admit reports whether value may be used as a label value.
+func (c *Cap) Admit(value string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.values[value]; ok {
+		return true
+	}
+	if len(c.values) >= c.limit {
+		return false
+	}
+	c.values[value] = struct{}{}
+	return true
+}
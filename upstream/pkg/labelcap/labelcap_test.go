@@ -0,0 +1,40 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package labelcap
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCap(t *testing.T) {
+	c := New(2)
+	assert.True(t, c.Admit("production"))
+	assert.True(t, c.Admit("staging"))
+	assert.False(t, c.Admit("qa"), "values beyond the limit are dropped")
+	assert.True(t, c.Admit("production"), "values seen before the limit was reached are still admitted")
+}
+
+func TestCapDefaultLimit(t *testing.T) {
+	c := New(0)
+	for i := 0; i < DefaultLimit; i++ {
+		assert.True(t, c.Admit(strconv.Itoa(i)))
+	}
+	assert.False(t, c.Admit("one too many"))
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package receipt produces signed, self-contained receipts of finalized
+// ApprovalTasks that auditors can archive and verify away from the cluster.
+package receipt
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// Algorithm identifies the signature scheme of a receipt.
+const Algorithm = "ed25519"
+
+// Receipt is the canonical record of a finalized ApprovalTask.
+type Receipt struct {
+	Namespace         string     `json:"namespace"`
+	Name              string     `json:"name"`
+	UID               string     `json:"uid"`
+	State             string     `json:"state"`
+	ApprovalsRequired int        `json:"approvalsRequired"`
+	ApprovalsReceived int        `json:"approvalsReceived"`
+	StartTime         string     `json:"startTime,omitempty"`
+	CorrelationID     string     `json:"correlationId,omitempty"`
+	Approvers         []Approver `json:"approvers"`
+}
+
+// Approver is the recorded response of one approver of the task.
+type Approver struct {
+	Name         string     `json:"name"`
+	Type         string     `json:"type"`
+	Response     string     `json:"response"`
+	Message      string     `json:"message,omitempty"`
+	RespondedAt  string     `json:"respondedAt,omitempty"`
+	GroupMembers []Approver `json:"groupMembers,omitempty"`
+}
+
+// Signed is a receipt along with its signature. Receipt holds the exact
+// canonical bytes that were signed, so it can be verified as archived.
+type Signed struct {
+	Receipt   json.RawMessage `json:"receipt"`
+	Algorithm string          `json:"algorithm"`
+	Signature string          `json:"signature"`
+}
+
+// Canonical returns the canonical JSON form of the receipt of the approval
+// task. Approvers and group members are sorted and times are rendered in UTC,
+// so the same final state always produces the same bytes.
+func Canonical(approvalTask v1alpha1.ApprovalTask) ([]byte, error) {
+	if !isFinal(approvalTask.Status.State) {
+		return nil, fmt.Errorf("approval task %s/%s has not reached a final state", approvalTask.Namespace, approvalTask.Name)
+	}
+
+	r := Receipt{
+		Namespace:         approvalTask.Namespace,
+		Name:              approvalTask.Name,
+		UID:               string(approvalTask.UID),
+		State:             approvalTask.Status.State,
+		ApprovalsRequired: approvalTask.Status.ApprovalsRequired,
+		ApprovalsReceived: approvalTask.Status.ApprovalsReceived,
+		CorrelationID:     approvalTask.Annotations[v1alpha1.CorrelationIDAnnotationKey],
+		Approvers:         []Approver{},
+	}
+	if approvalTask.Status.StartTime != nil {
+		r.StartTime = formatTime(approvalTask.Status.StartTime.Time)
+	}
+	for _, response := range approvalTask.Status.ApproversResponse {
+		approver := Approver{
+			Name:     response.Name,
+			Type:     v1alpha1.DefaultedApproverType(response.Type),
+			Response: response.Response,
+			Message:  response.Message,
+		}
+		if response.RespondedAt != nil {
+			approver.RespondedAt = formatTime(response.RespondedAt.Time)
+		}
+		for _, member := range response.GroupMembers {
+			m := Approver{Name: member.Name, Type: "User", Response: member.Response, Message: member.Message}
+			if member.RespondedAt != nil {
+				m.RespondedAt = formatTime(member.RespondedAt.Time)
+			}
+			approver.GroupMembers = append(approver.GroupMembers, m)
+		}
+		sortApprovers(approver.GroupMembers)
+		r.Approvers = append(r.Approvers, approver)
+	}
+	sortApprovers(r.Approvers)
+
+	return json.Marshal(r)
+}
+
+// New returns the receipt of the approval task signed with key.
+func New(approvalTask v1alpha1.ApprovalTask, key ed25519.PrivateKey) (*Signed, error) {
+	canonical, err := Canonical(approvalTask)
+	if err != nil {
+		return nil, err
+	}
+	return &Signed{
+		Receipt:   canonical,
+		Algorithm: Algorithm,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, canonical)),
+	}, nil
+}
+
+// Verify checks that the receipt was signed by the private key of publicKey
+// and returns its decoded content.
+func Verify(signed Signed, publicKey ed25519.PublicKey) (*Receipt, error) {
+	if signed.Algorithm != Algorithm {
+		return nil, fmt.Errorf("unsupported receipt algorithm %q", signed.Algorithm)
+	}
+	signature, err := base64.StdEncoding.DecodeString(signed.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("malformed receipt signature: %w", err)
+	}
+	if !ed25519.Verify(publicKey, signed.Receipt, signature) {
+		return nil, errors.New("receipt signature is not valid")
+	}
+	r := &Receipt{}
+	if err := json.Unmarshal(signed.Receipt, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ParsePrivateKey parses a PEM encoded PKCS #8 ed25519 private key.
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded key found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("receipts are signed with ed25519 keys, got %T", key)
+	}
+	return edKey, nil
+}
+
+func isFinal(state string) bool {
+	return state == "approved" || state == "rejected" || state == "withdrawn"
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func sortApprovers(approvers []Approver) {
+	sort.Slice(approvers, func(i, j int) bool {
+		if approvers[i].Type != approvers[j].Type {
+			return approvers[i].Type < approvers[j].Type
+		}
+		return approvers[i].Name < approvers[j].Name
+	})
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package receipt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func approvedTask() v1alpha1.ApprovalTask {
+	start := metav1.NewTime(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	responded := metav1.NewTime(time.Date(2024, 1, 15, 11, 30, 0, 0, time.FixedZone("CET", 3600)))
+	return v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production", UID: "1234"},
+		Status: v1alpha1.ApprovalTaskStatus{
+			State:             "approved",
+			StartTime:         &start,
+			ApprovalsRequired: 2,
+			ApprovalsReceived: 2,
+			ApproversResponse: []v1alpha1.ApproverState{
+				{
+					Name:        "platform",
+					Type:        "Group",
+					Response:    "approved",
+					RespondedAt: &responded,
+					GroupMembers: []v1alpha1.GroupMemberState{
+						{Name: "carol", Response: "approved", RespondedAt: &responded},
+						{Name: "bob", Response: "approved", Message: "lgtm", RespondedAt: &responded},
+					},
+				},
+				{Name: "alice", Type: "User", Response: "approved", RespondedAt: &responded},
+			},
+		},
+	}
+}
+
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	return public, private
+}
+
+func TestCanonicalIsDeterministic(t *testing.T) {
+	at := approvedTask()
+	want, err := Canonical(at)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"namespace":"production","name":"deploy","uid":"1234","state":"approved","approvalsRequired":2,"approvalsReceived":2,"startTime":"2024-01-15T10:00:00Z","approvers":[`+
+		`{"name":"platform","type":"Group","response":"approved","respondedAt":"2024-01-15T10:30:00Z","groupMembers":[`+
+		`{"name":"bob","type":"User","response":"approved","message":"lgtm","respondedAt":"2024-01-15T10:30:00Z"},`+
+		`{"name":"carol","type":"User","response":"approved","respondedAt":"2024-01-15T10:30:00Z"}]},`+
+		`{"name":"alice","type":"User","response":"approved","respondedAt":"2024-01-15T10:30:00Z"}]}`, string(want))
+
+	// The order the controller recorded the responses in does not matter
+	reordered := approvedTask()
+	responses := reordered.Status.ApproversResponse
+	responses[0], responses[1] = responses[1], responses[0]
+	members := responses[1].GroupMembers
+	members[0], members[1] = members[1], members[0]
+	got, err := Canonical(reordered)
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestCanonicalRequiresFinalState(t *testing.T) {
+	at := approvedTask()
+	at.Status.State = "pending"
+	_, err := Canonical(at)
+	assert.Error(t, err)
+}
+
+func TestCanonicalCorrelationID(t *testing.T) {
+	at := approvedTask()
+	at.Annotations = map[string]string{v1alpha1.CorrelationIDAnnotationKey: "release-42"}
+	public, private := newKey(t)
+	signed, err := New(at, private)
+	assert.NoError(t, err)
+	r, err := Verify(*signed, public)
+	assert.NoError(t, err)
+	assert.Equal(t, "release-42", r.CorrelationID)
+}
+
+func TestSignAndVerify(t *testing.T) {
+	public, private := newKey(t)
+
+	signed, err := New(approvedTask(), private)
+	assert.NoError(t, err)
+	assert.Equal(t, Algorithm, signed.Algorithm)
+
+	r, err := Verify(*signed, public)
+	assert.NoError(t, err)
+	assert.Equal(t, "approved", r.State)
+	assert.Len(t, r.Approvers, 2)
+
+	otherPublic, _ := newKey(t)
+	_, err = Verify(*signed, otherPublic)
+	assert.Error(t, err, "a receipt must not verify against another key")
+
+	tampered := *signed
+	tampered.Receipt = []byte(string(signed.Receipt[:len(signed.Receipt)-2]) + " }")
+	_, err = Verify(tampered, public)
+	assert.Error(t, err, "a modified receipt must not verify")
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	public, private := newKey(t)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	assert.NoError(t, err)
+
+	parsed, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	assert.NoError(t, err)
+	assert.Equal(t, public, parsed.Public())
+
+	_, err = ParsePrivateKey([]byte("not a key"))
+	assert.Error(t, err)
+}
@@ -25,11 +25,16 @@ import (
 
 	"github.com/hashicorp/go-multierror"
 	approvaltaskv1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/callback"
 	approvaltaskclientset "github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned"
 	listersapprovaltask "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/correlation"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/labelcap"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	clientset "github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
 	customrunreconciler "github.com/tektoncd/pipeline/pkg/client/injection/reconciler/pipeline/v1beta1/customrun"
+	pipelinelisters "github.com/tektoncd/pipeline/pkg/client/listers/pipeline/v1"
 	listersalpha "github.com/tektoncd/pipeline/pkg/client/listers/pipeline/v1alpha1"
 	listers "github.com/tektoncd/pipeline/pkg/client/listers/pipeline/v1beta1"
 	"github.com/tektoncd/pipeline/pkg/reconciler/events"
@@ -37,6 +42,7 @@ import (
 	"gomodules.xyz/jsonpatch/v2"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/utils/clock"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/controller"
@@ -51,14 +57,45 @@ const (
 	// approvaltaskRunLabelKey is the label identifier for a Run.  This label is added to the Run's TaskRuns.
 	approvaltaskRunLabelKey = "/run"
 
-	pendingState      = "pending"
-	approvedState     = "approved"
-	rejectedState     = "rejected"
-	hasApproved       = "approve"
-	hasRejected       = "reject"
-	allApprovers      = "approvers"
-	approvalsRequired = "numberOfApprovalsRequired"
-	description       = "description"
+	pendingState   = "pending"
+	approvedState  = "approved"
+	rejectedState  = "rejected"
+	withdrawnState = "withdrawn"
+	hasApproved    = "approve"
+	hasRejected    = "reject"
+	hasWithdrawn   = "withdraw"
+
+	// hasRequestedChanges blocks the approval of a task without rejecting
+	// it. Approvers' responses record it as changesRequestedState.
+	hasRequestedChanges   = "request-changes"
+	changesRequestedState = "changes-requested"
+
+	allApprovers       = "approvers"
+	approvalsRequired  = "numberOfApprovalsRequired"
+	approvalPercentage = "approvalPercentage"
+	description        = "description"
+	escrowGroup        = "escrowGroup"
+
+	maxApprovalsPerGroup    = "maxApprovalsPerGroup"
+	approvalExpiresAfter    = "approvalExpiresAfter"
+	expectedDigest          = "expectedDigest"
+	minApprovingTeams       = "minApprovingTeams"
+	minApproverLevel        = "minApproverLevel"
+	ownersFile              = "owners"
+	pipelineRunApprovers    = "approversFromPipelineRun"
+	mixedResolution         = "mixedResolution"
+	ttlAfterFinished        = "ttlSecondsAfterFinished"
+	minReviewDuration       = "minReviewDuration"
+	approverChangePolicy    = "approverChangePolicy"
+	countRequesterApproval  = "countRequesterApproval"
+	rejectionReversalWindow = "rejectionReversalWindow"
+	requireDigestAck        = "requireDigestAcknowledgment"
+	escalateAfter           = "escalateAfter"
+	maxPendingLifetime      = "maxPendingLifetime"
+	backupApprovers         = "backupApprovers"
+	changeTicket            = "changeTicket"
+	requiredRoles           = "requiredRoles"
+	approverRoles           = "approverRoles"
 
 	// CustomRunLabelKey is used as the label identifier for a ApprovalTask
 	CustomRunLabelKey = "tekton.dev/customRun"
@@ -69,6 +106,7 @@ const (
 // Reconciler implements controller.Reconciler for Configuration resources.
 type Reconciler struct {
 	clock                 clock.PassiveClock
+	maxRequeueInterval    time.Duration
 	pipelineClientSet     clientset.Interface
 	kubeClientSet         kubernetes.Interface
 	approvaltaskClientSet approvaltaskclientset.Interface
@@ -76,6 +114,32 @@ type Reconciler struct {
 	customRunLister       listers.CustomRunLister
 	approvaltaskLister    listersapprovaltask.ApprovalTaskLister
 	taskRunLister         listers.TaskRunLister
+	pipelineRunLister     pipelinelisters.PipelineRunLister
+	configMapLister       corelisters.ConfigMapLister
+	namespaceLister       corelisters.NamespaceLister
+	callback              *callback.Notifier
+	// rejectInconsistentResponses rejects tasks whose status holds responses
+	// that do not match their approvers instead of repairing the status.
+	rejectInconsistentResponses bool
+	// displayLocation is the timezone the key timestamps of the status are
+	// rendered in. They are not rendered when it is nil.
+	displayLocation *time.Location
+	// cleanupHook is notified when an approval task is deleted. Tasks are
+	// only created with the cleanup finalizer when it is set.
+	cleanupHook CleanupHook
+	// policyLister lists the cluster approval policies the approval tasks
+	// are held to. No policy applies when it is nil.
+	policyLister listersapprovaltask.ClusterApprovalPolicyLister
+	// carryForwardApprovals seeds new approval tasks with the approvals of
+	// the prior task of the same identity.
+	carryForwardApprovals bool
+	// defaultApprovalsRequired is backfilled into pending approval tasks
+	// that set no number of approvals required. Zero backfills the number
+	// of their eligible approvers.
+	defaultApprovalsRequired int
+	// metricsNamespaces bounds the namespaces the decision duration metric
+	// is labelled with. Namespaces are not bounded when it is nil.
+	metricsNamespaces *labelcap.Cap
 }
 
 var (
@@ -101,6 +165,9 @@ func init() {
 // It then updates the Status block of the Run resource with the current status of the resource.
 func (c *Reconciler) ReconcileKind(ctx context.Context, run *v1beta1.CustomRun) pkgreconciler.Event {
 	var merr error
+	// The correlation ID of the approval task is copied to the run with its
+	// other annotations
+	ctx = correlation.WithLogger(ctx, correlation.Of(run))
 	logger := logging.FromContext(ctx)
 	logger.Infof("Reconciling Run %s/%s at %v", run.Namespace, run.Name, time.Now())
 
@@ -118,6 +185,28 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, run *v1beta1.CustomRun)
 		return nil
 	}
 
+	// Approvers listed in an OWNERS file or by the PipelineRun are
+	// validated with the others
+	if err := c.expandApprovers(ctx, run); err != nil {
+		if !controller.IsPermanentError(err) {
+			return err
+		}
+		detailedMsg := fmt.Sprintf("ApprovalTask validation failed: %s", err.Error())
+		run.Status.MarkCustomRunFailed(approvaltaskv1alpha1.ApprovalTaskRunReasonFailedValidation.String(),
+			detailedMsg)
+		logger.Errorf("Resolving the approvers of Run %s/%s failed: %v", run.Namespace, run.Name, err)
+		events.Emit(ctx, nil, &apis.Condition{
+			Type:    apis.ConditionSucceeded,
+			Status:  "False",
+			Reason:  approvaltaskv1alpha1.ApprovalTaskRunReasonFailedValidation.String(),
+			Message: detailedMsg,
+		}, run)
+		return nil
+	}
+
+	// Runs setting no number of approvals inherit the namespace default
+	c.applyNamespaceApprovalsRequired(ctx, run)
+
 	// Validate parameters early for fail-fast behavior
 	if err := ValidateCustomRunParameters(run); err != nil {
 		detailedMsg := fmt.Sprintf("ApprovalTask validation failed: %s", err.Error())
@@ -170,15 +259,31 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, run *v1beta1.CustomRun)
 	return merr
 }
 
-func (r *Reconciler) reconcile(ctx context.Context, run *v1beta1.CustomRun, status *approvaltaskv1alpha1.ApprovalTaskRunStatus) error {
+func (r *Reconciler) reconcile(ctx context.Context, run *v1beta1.CustomRun, status *approvaltaskv1alpha1.ApprovalTaskRunStatus) (err error) {
 	// Get the ApprovalTask referenced by the Run
 	logger := logging.FromContext(ctx)
-	approvalTask, err := getOrCreateApprovalTask(ctx, r.approvaltaskClientSet, run)
+	approvalTask, err := getOrCreateApprovalTask(ctx, r.approvaltaskClientSet, run, r.finalizers(), r.clusterPolicies(ctx), r.carryForwardApprovals)
 	if err != nil {
 		logger.Errorf("Error getting or creating the approval task: %v", err.Error())
 		return err
 	}
 
+	// Every step of the lifecycle the task takes is announced on its run, and
+	// the index of the approvers it awaits follows every decision
+	phase := approvalTask.Status.Phase
+	defer func() {
+		if isRequeue, _ := controller.IsRequeueKey(err); err == nil || isRequeue {
+			announcePhase(ctx, run, phase, approvalTask)
+			if err := r.syncAwaitingIndex(ctx, approvalTask); err != nil {
+				logger.Warnf("Failed to index the approvers approval task %s awaits: %v", approvalTask.Name, err)
+			}
+		}
+	}()
+
+	if err := r.repairOwnerReference(ctx, run, approvalTask); err != nil {
+		return err
+	}
+
 	approvalTaskMeta := &approvalTask.ObjectMeta
 	approvalTaskSpec := approvalTask.Spec
 
@@ -191,29 +296,93 @@ func (r *Reconciler) reconcile(ctx context.Context, run *v1beta1.CustomRun, stat
 	if !approvalTask.HasStarted() {
 		approvalTask.Status.StartTime = &approvalTask.CreationTimestamp
 	}
+	setDisplayTimes(&approvalTask.Status, r.displayLocation)
+
+	if err := r.backfillApprovalsRequired(ctx, approvalTask); err != nil {
+		return err
+	}
+
+	if err := r.resetOnApproverChange(ctx, approvalTask); err != nil {
+		return err
+	}
+
+	if err := r.applyPolicies(ctx, approvalTask); err != nil {
+		return err
+	}
 
 	timeout := run.Spec.Timeout
 	if timeout == nil {
 		timeout = &metav1.Duration{Duration: time.Duration(60) * time.Minute}
 	}
+	if approvalTask.Spec.RequesterInput == hasWithdrawn && approvalTask.Status.State == pendingState {
+		approvalTask.Status.State = withdrawnState
+		approvalTask.Status.ObservedGeneration = approvalTask.Generation
+		setConditions(approvalTask, r.clock.Now())
+		_, err := r.approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(approvalTask.Namespace).UpdateStatus(ctx, approvalTask, metav1.UpdateOptions{})
+		if err != nil {
+			return err
+		}
+		r.callback.Notify(ctx, callback.NewPayload(*approvalTask))
+		message := fmt.Sprintf("Approval task %s was withdrawn by %s", approvalTask.Name, approvalTask.Annotations[approvaltaskv1alpha1.CreatedByAnnotationKey])
+		run.Status.MarkCustomRunFailed(approvaltaskv1alpha1.ApprovalTaskRunReasonWithdrawn.String(), message)
+		r.recordDecisionDuration(ctx, approvalTask, withdrawnState)
+		return nil
+	}
+
 	if approvalTask.ApprovalTaskHasTimedOut(ctx, r.clock, timeout.Duration) {
-		approvalTask.Status.State = rejectedState
+		return r.expire(ctx, approvalTask, run, timeout.Duration, fmt.Sprintf("Approval task %s is failed because of timeout", approvalTask.Name))
+	}
+
+	// Paused tasks are left alone: resuming them is the way out. Tasks that
+	// wait for every approver are only decided once all of them responded.
+	if approvalTask.Status.State == pendingState && !approvalTask.Spec.Paused && approval.FailsEarly(*approvalTask) && approval.Unsatisfiable(*approvalTask) {
+		attainable, _ := approval.MaxAttainableApprovals(*approvalTask)
+		markRejected(approvalTask, r.clock.Now())
+		approvalTask.Status.ObservedGeneration = approvalTask.Generation
+		setConditions(approvalTask, r.clock.Now())
 		_, err := r.approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(approvalTask.Namespace).UpdateStatus(ctx, approvalTask, metav1.UpdateOptions{})
 		if err != nil {
 			return err
 		}
-		message := fmt.Sprintf("Approval task %s is failed because of timeout", approvalTask.Name)
-		run.Status.MarkCustomRunFailed(approvaltaskv1alpha1.ApprovalTaskRunReasonFailed.String(), message)
+		if finalAt, ok := approval.RejectionFinalAt(*approvalTask); ok {
+			return r.finalizeRejection(ctx, approvalTask, run, finalAt)
+		}
+		r.callback.Notify(ctx, callback.NewPayload(*approvalTask))
+		message := fmt.Sprintf("Approval task %s can no longer be approved: at most %d of the %d required approvals can still be given",
+			approvalTask.Name, attainable, approval.MinRequiredApprovals(*approvalTask))
+		run.Status.MarkCustomRunFailed(approvaltaskv1alpha1.ApprovalTaskRunReasonUnsatisfiable.String(), message)
+		r.recordDecisionDuration(ctx, approvalTask, rejectedState)
+		return nil
+	}
+
+	if rejected, err := r.checkResponseConsistency(ctx, approvalTask, run); err != nil || rejected {
+		return err
+	}
+
+	if err := r.checkIfUpdateRequired(ctx, approvalTask, run); err != nil {
+		return err
+	}
+
+	if err := r.observeGeneration(ctx, approvalTask); err != nil {
+		return err
+	}
+
+	// Final tasks have nothing left to wait for
+	if run.IsDone() {
 		return nil
 	}
 
-	if err := r.checkIfUpdateRequired(ctx, *approvalTask, run); err != nil {
+	if finalAt, ok := approval.RejectionFinalAt(*approvalTask); ok {
+		return r.finalizeRejection(ctx, approvalTask, run, finalAt)
+	}
+
+	// Only tasks still pending once their responses are evaluated escalate
+	// or expire
+	if expired, err := r.advanceLifecycle(ctx, approvalTask, run); err != nil || expired {
 		return err
 	}
 
-	if approvalTask.Status.StartTime != nil {
-		elapsed := r.clock.Since(approvalTask.Status.StartTime.Time)
-		waitTime := timeout.Duration - elapsed
+	if waitTime, ok := nextRequeue(*approvalTask, timeout.Duration, r.clock.Now(), r.maxRequeueInterval); ok {
 		return controller.NewRequeueAfter(waitTime)
 	}
 
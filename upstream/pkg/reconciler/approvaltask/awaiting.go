@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"encoding/json"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// syncAwaitingIndex brings the awaiting labels of the approval task in line
+// with the approvers it still awaits (see approval.AwaitingLabels), adding
+// the missing ones and removing the stale ones with a merge patch. Final
+// tasks lose them all. Tasks embedded in their CustomRun are not stored, so
+// they are left alone.
+func (r *Reconciler) syncAwaitingIndex(ctx context.Context, approvalTask *v1alpha1.ApprovalTask) error {
+	if approvalTask.Name == "" || approvalTask.DeletionTimestamp != nil {
+		return nil
+	}
+	desired := approval.AwaitingLabels(*approvalTask)
+	changes := map[string]interface{}{}
+	for key, value := range approvalTask.Labels {
+		if !approval.IsAwaitingLabel(key) {
+			continue
+		}
+		if want, ok := desired[key]; !ok {
+			changes[key] = nil
+		} else if want != value {
+			changes[key] = want
+		}
+	}
+	for key, value := range desired {
+		if _, ok := approvalTask.Labels[key]; !ok {
+			changes[key] = value
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": changes},
+	})
+	if err != nil {
+		return err
+	}
+	at, err := r.approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(approvalTask.Namespace).Patch(ctx, approvalTask.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return err
+	}
+	approvalTask.Labels = at.Labels
+	approvalTask.ResourceVersion = at.ResourceVersion
+	return nil
+}
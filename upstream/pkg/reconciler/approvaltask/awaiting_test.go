@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestSyncAwaitingIndex(t *testing.T) {
+	at := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production", Labels: map[string]string{"team": "payments"}},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "platform", Type: "Group", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: pendingState},
+	}
+	client := fake.NewSimpleClientset(at)
+	r := &Reconciler{approvaltaskClientSet: client}
+	stored := func() map[string]string {
+		at, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+		assert.NoError(t, err)
+		return at.Labels
+	}
+
+	assert.NoError(t, r.syncAwaitingIndex(context.TODO(), at))
+	assert.Equal(t, map[string]string{
+		"team":                             "payments",
+		approval.AwaitingLabelKey("alice"): "true",
+		approval.AwaitingLabelKey("group:platform"): "true",
+	}, stored())
+	assert.Equal(t, stored(), at.Labels)
+
+	// Nothing is written while the index is current
+	client.ClearActions()
+	assert.NoError(t, r.syncAwaitingIndex(context.TODO(), at))
+	assert.Empty(t, client.Actions())
+
+	// Approvers leave the index as they respond
+	at.Spec.Approvers[0].Input = "approve"
+	assert.NoError(t, r.syncAwaitingIndex(context.TODO(), at))
+	assert.Equal(t, map[string]string{
+		"team": "payments",
+		approval.AwaitingLabelKey("group:platform"): "true",
+	}, stored())
+
+	// Final tasks await nobody
+	at.Status.State = approvedState
+	assert.NoError(t, r.syncAwaitingIndex(context.TODO(), at))
+	assert.Equal(t, map[string]string{"team": "payments"}, stored())
+}
+
+func TestReconcileIndexesAwaitedApprovers(t *testing.T) {
+	at := escalatingApprovalTask(t)
+	client := fake.NewSimpleClientset(at)
+	r := &Reconciler{clock: clocktesting.NewFakePassiveClock(lifecycleStart.Add(time.Minute)), approvaltaskClientSet: client}
+
+	_ = r.reconcile(context.TODO(), lifecycleRun(), &v1alpha1.ApprovalTaskRunStatus{})
+	stored, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "true", stored.Labels[approval.AwaitingLabelKey("alice")])
+	assert.NotContains(t, stored.Labels, approval.AwaitingLabelKey("bob"), "backup approvers are awaited once the task escalates")
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
+)
+
+// backfillApprovalsRequired writes the number of approvals required into a
+// pending approval task that sets none, so that it is no longer approved with
+// whatever responses it has: the default of its namespace, the configured
+// default, or else the number of its eligible approvers (see
+// approval.BaseApprovalsRequired).
+func (r *Reconciler) backfillApprovalsRequired(ctx context.Context, approvalTask *v1alpha1.ApprovalTask) error {
+	if approvalTask.Status.State != "" && approvalTask.Status.State != pendingState {
+		return nil
+	}
+	if !approval.ApprovalsRequiredUnset(approvalTask.Spec) {
+		return nil
+	}
+
+	required := r.namespaceApprovalsRequired(ctx, approvalTask.Namespace)
+	if required <= 0 {
+		required = r.defaultApprovalsRequired
+	}
+	if required <= 0 {
+		required = approval.BaseApprovalsRequired(*approvalTask)
+	}
+	status := approvalTask.Status
+	approvalTask.Spec.NumberOfApprovalsRequired = required
+	at, err := r.approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(approvalTask.Namespace).Update(ctx, approvalTask, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	// The status of the update is the stored one, not the one built so far
+	*approvalTask = *at
+	approvalTask.Status = status
+	logging.FromContext(ctx).Infof("Approval task %s set no number of approvals required, requiring %d", approvalTask.Name, required)
+	return nil
+}
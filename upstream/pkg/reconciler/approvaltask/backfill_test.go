@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// legacyApprovalTask sets no number of approvals required, and has one
+// approval out of its three approvers.
+func legacyApprovalTask() *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+				{Name: "bob", Type: "User", Input: "pending"},
+				{Name: "carol", Type: "User", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: pendingState},
+	}
+}
+
+func TestBackfillApprovalsRequired(t *testing.T) {
+	tests := []struct {
+		name      string
+		defaults  int
+		namespace string
+		want      int
+	}{{
+		name: "every eligible approver by default",
+		want: 3,
+	}, {
+		name:     "configured default",
+		defaults: 2,
+		want:     2,
+	}, {
+		name:      "namespace default over the configured one",
+		defaults:  2,
+		namespace: "1",
+		want:      1,
+	}, {
+		name:      "malformed namespace default",
+		defaults:  2,
+		namespace: "none",
+		want:      2,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			at := legacyApprovalTask()
+			assert.False(t, approval.QuorumReached(*at), "a task setting no number is not approved by a single response")
+
+			client := fake.NewSimpleClientset(at.DeepCopy())
+			r := &Reconciler{
+				approvaltaskClientSet:    client,
+				namespaceLister:          namespaceLister(t, defaultingNamespace(tc.namespace)),
+				defaultApprovalsRequired: tc.defaults,
+			}
+			assert.NoError(t, r.backfillApprovalsRequired(context.TODO(), at))
+			assert.Equal(t, tc.want, at.Spec.NumberOfApprovalsRequired)
+			assert.Equal(t, pendingState, at.Status.State, "the status being reconciled is kept")
+
+			stored, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, stored.Spec.NumberOfApprovalsRequired)
+		})
+	}
+}
+
+func TestBackfillLeavesTasksAlone(t *testing.T) {
+	set := legacyApprovalTask()
+	set.Spec.NumberOfApprovalsRequired = 1
+	percentage := legacyApprovalTask()
+	percentage.Spec.ApprovalPercentage = 50
+	final := legacyApprovalTask()
+	final.Status.State = approvedState
+
+	for name, at := range map[string]*v1alpha1.ApprovalTask{
+		"number set":     set,
+		"percentage set": percentage,
+		"final":          final,
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(at.DeepCopy())
+			r := &Reconciler{
+				approvaltaskClientSet:    client,
+				namespaceLister:          namespaceLister(t, defaultingNamespace("3")),
+				defaultApprovalsRequired: 2,
+			}
+			want := at.Spec.NumberOfApprovalsRequired
+			assert.NoError(t, r.backfillApprovalsRequired(context.TODO(), at))
+			assert.Equal(t, want, at.Spec.NumberOfApprovalsRequired)
+			assert.Empty(t, client.Actions())
+		})
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"knative.dev/pkg/logging"
+)
+
+// priorApprovals returns the approvals to carry forward to the approval task
+// about to be created: those its individual approvers gave on the most recent
+// other approval task of its namespace with the same identity label (see
+// v1alpha1.ApprovalIdentityLabelKey). Nothing is carried forward when the
+// approvers of the two tasks differ, since the prior approvals were given for
+// another set of approvers. Group approvals are never carried forward.
+func priorApprovals(ctx context.Context, approvaltaskClientSet versioned.Interface, approvalTask v1alpha1.ApprovalTask) ([]v1alpha1.ApproverState, error) {
+	identity, ok := approvalTask.Labels[v1alpha1.ApprovalIdentityLabelKey]
+	if !ok || identity == "" {
+		return nil, nil
+	}
+	selector := labels.SelectorFromSet(labels.Set{v1alpha1.ApprovalIdentityLabelKey: identity})
+	tasks, err := approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(approvalTask.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	var prior *v1alpha1.ApprovalTask
+	for i := range tasks.Items {
+		task := &tasks.Items[i]
+		if task.Name == approvalTask.Name {
+			continue
+		}
+		if prior == nil || prior.CreationTimestamp.Before(&task.CreationTimestamp) {
+			prior = task
+		}
+	}
+	if prior == nil {
+		return nil, nil
+	}
+	if !sameApprovers(prior.Spec.Approvers, approvalTask.Spec.Approvers) {
+		logging.FromContext(ctx).Infof("Not carrying the approvals of %s forward to %s: their approvers differ", prior.Name, approvalTask.Name)
+		return nil, nil
+	}
+
+	var carried []v1alpha1.ApproverState
+	for _, response := range prior.Status.ApproversResponse {
+		if response.Response != approvedState || !v1alpha1.IsIndividualApproverType(response.Type) {
+			continue
+		}
+		carried = append(carried, v1alpha1.ApproverState{
+			Name:        response.Name,
+			Type:        v1alpha1.DefaultedApproverType(response.Type),
+			Response:    approvedState,
+			Message:     response.Message,
+			RespondedAt: response.RespondedAt,
+			CarriedFrom: prior.Name,
+		})
+	}
+	if len(carried) > 0 {
+		logging.FromContext(ctx).Infof("Carrying %d approvals of %s forward to %s", len(carried), prior.Name, approvalTask.Name)
+	}
+	return carried, nil
+}
+
+// sameApprovers reports whether both lists name the same approvers, whatever
+// their order and inputs.
+func sameApprovers(a, b []v1alpha1.ApproverDetails) bool {
+	return slices.Equal(approverKeys(a), approverKeys(b))
+}
+
+func approverKeys(approvers []v1alpha1.ApproverDetails) []string {
+	keys := make([]string, 0, len(approvers))
+	for _, approver := range approvers {
+		keys = append(keys, v1alpha1.DefaultedApproverType(approver.Type)+":"+approver.Name)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+// priorApprovalTask is the task of a first attempt, approved by alice only.
+func priorApprovalTask() *v1alpha1.ApprovalTask {
+	respondedAt := metav1.NewTime(time.Now().Add(-time.Hour))
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "deploy-1",
+			Namespace:         "production",
+			Labels:            map[string]string{v1alpha1.ApprovalIdentityLabelKey: "release-42"},
+			CreationTimestamp: respondedAt,
+		},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+				{Name: "bob", Type: "User", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{
+			State: "rejected",
+			ApproversResponse: []v1alpha1.ApproverState{
+				{Name: "alice", Type: "User", Response: "approved", RespondedAt: &respondedAt},
+			},
+		},
+	}
+}
+
+func retriedRun(approver string, approvers ...string) *v1beta1.CustomRun {
+	run := approvalTaskRun()
+	run.Labels = map[string]string{v1alpha1.ApprovalIdentityLabelKey: "release-42"}
+	run.Spec.Params = []v1beta1.Param{
+		{Name: "approvers", Value: *v1beta1.NewArrayOrString(approver, approvers...)},
+		{Name: "numberOfApprovalsRequired", Value: *v1beta1.NewArrayOrString("1")},
+	}
+	return run
+}
+
+func TestReconcileCarriesApprovalsForward(t *testing.T) {
+	client := fake.NewSimpleClientset(priorApprovalTask())
+	r := &Reconciler{
+		clock:                 clocktesting.NewFakePassiveClock(time.Now()),
+		approvaltaskClientSet: client,
+		carryForwardApprovals: true,
+	}
+
+	run := retriedRun("bob", "alice")
+	assert.NoError(t, r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{}))
+	assert.True(t, run.IsSuccessful(), "alice's approval of the first attempt reaches the quorum")
+
+	at, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "approved", at.Status.State)
+	assert.Equal(t, 1, at.Status.ApprovalsReceived)
+	assert.Len(t, at.Status.ApproversResponse, 1)
+	assert.Equal(t, "alice", at.Status.ApproversResponse[0].Name)
+	assert.Equal(t, "deploy-1", at.Status.ApproversResponse[0].CarriedFrom)
+	assert.Equal(t, "pending", at.Spec.Approvers[1].Input, "the spec only holds decisions given on the task itself")
+}
+
+func TestReconcileDoesNotCarryApprovalsForChangedApprovers(t *testing.T) {
+	client := fake.NewSimpleClientset(priorApprovalTask())
+	r := &Reconciler{
+		clock:                 clocktesting.NewFakePassiveClock(time.Now()),
+		approvaltaskClientSet: client,
+		carryForwardApprovals: true,
+	}
+
+	run := retriedRun("alice", "carol")
+	assert.NoError(t, r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{}))
+	assert.False(t, run.IsDone())
+
+	at, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", at.Status.State)
+	assert.Empty(t, at.Status.ApproversResponse)
+	assert.Equal(t, 0, at.Status.ApprovalsReceived)
+}
+
+func TestCreateApprovalTaskWithoutCarryForward(t *testing.T) {
+	client := fake.NewSimpleClientset(priorApprovalTask())
+
+	at, err := createApprovalTask(context.TODO(), client, retriedRun("alice", "bob"), nil, nil, false)
+	assert.NoError(t, err)
+	assert.Empty(t, at.Status.ApproversResponse)
+	assert.NotEmpty(t, at.Annotations[LastAppliedHashKey])
+}
+
+func TestUpdateApprovalStateReplacesCarriedApproval(t *testing.T) {
+	client := fake.NewSimpleClientset(priorApprovalTask())
+	at, err := createApprovalTask(context.TODO(), client, retriedRun("alice", "bob"), nil, nil, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, at.Status.ApprovalsReceived)
+
+	// alice changed her mind on the retry
+	at.Spec.Approvers[0].Input = "reject"
+	updated, err := updateApprovalState(context.TODO(), client, nil, clocktesting.NewFakePassiveClock(time.Now()), nil, &at)
+	assert.NoError(t, err)
+	assert.Equal(t, "rejected", updated.Status.State)
+	assert.Equal(t, "rejected", updated.Status.ApproversResponse[0].Response)
+	assert.Empty(t, updated.Status.ApproversResponse[0].CarriedFrom)
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestUpdateApprovalStateRequestChangesThenApprove(t *testing.T) {
+	approvalTask := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+				{Name: "bob", Type: "User", Input: "request-changes", Message: "bump the replica count"},
+				{Name: "platform", Type: "Group", Input: "request-changes", Users: []v1alpha1.UserDetails{
+					{Name: "carol", Input: "request-changes"},
+				}},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+	client := fake.NewSimpleClientset(approvalTask)
+	clock := clocktesting.NewFakePassiveClock(time.Now())
+
+	at, err := updateApprovalState(context.TODO(), client, nil, clock, nil, approvalTask)
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", at.Status.State, "requesting changes keeps the task pending")
+	responses := map[string]string{}
+	for _, response := range at.Status.ApproversResponse {
+		responses[response.Name] = response.Response
+	}
+	assert.Equal(t, map[string]string{"alice": "approved", "bob": "changes-requested", "platform": "changes-requested"}, responses)
+
+	// bob approves once the changes are made, carol still blocks the task
+	at.Spec.Approvers[1].Input = "approve"
+	at, err = updateApprovalState(context.TODO(), client, nil, clock, nil, &at)
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", at.Status.State)
+	assert.Equal(t, 2, at.Status.ApprovalsReceived)
+
+	at.Spec.Approvers[2].Input = "approve"
+	at.Spec.Approvers[2].Users[0].Input = "approve"
+	at, err = updateApprovalState(context.TODO(), client, nil, clock, nil, &at)
+	assert.NoError(t, err)
+	assert.Equal(t, "approved", at.Status.State)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"strings"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+)
+
+// Reasons of the conditions of an approval task that are not True.
+const (
+	awaitingApprovalsReason     = "AwaitingApprovals"
+	changesRequestedReason      = "ChangesRequested"
+	missingRequiredGroupsReason = "MissingRequiredGroups"
+	missingTeamsReason          = "MissingTeams"
+	missingRolesReason          = "MissingRoles"
+	timedOutReason              = "TimedOut"
+	rejectedReason              = "Rejected"
+	withdrawnReason             = "Withdrawn"
+)
+
+// setConditions sets the conditions of the status of the approval task from
+// the requirements it meets at now, as evaluated by approval.QuorumReachedAt:
+// QuorumMet for the number of approvals, MandatoryMet for everything else.
+// Ready follows them, except for rejected and withdrawn tasks, which are
+// never Ready. A task that timed out stays expired (see markTimedOut). The
+// phase of the task is set along with them (see setPhase).
+func setConditions(approvalTask *v1alpha1.ApprovalTask, now time.Time) {
+	setPhase(approvalTask)
+	status := &approvalTask.Status
+	status.InitializeConditions()
+
+	if received, required := approval.CountApprovalsAt(*approvalTask, now), approval.RequiredApprovalsAt(*approvalTask, now); received >= required {
+		status.MarkQuorumMet()
+	} else {
+		status.MarkQuorumNotMet(awaitingApprovalsReason, "%d of %d required approvals received", received, required)
+	}
+
+	if requesters := approval.ChangesRequestedBy(*approvalTask); len(requesters) > 0 {
+		status.MarkMandatoryNotMet(changesRequestedReason, "Changes requested by %s", strings.Join(requesters, ", "))
+	} else if groups := approval.MissingRequiredGroupsAt(*approvalTask, now); len(groups) > 0 {
+		status.MarkMandatoryNotMet(missingRequiredGroupsReason, "Awaiting an approval from the groups %s", strings.Join(groups, ", "))
+	} else if teams := approval.MissingTeamsAt(*approvalTask, now); teams > 0 {
+		status.MarkMandatoryNotMet(missingTeamsReason, "Awaiting approvals from %d more teams", teams)
+	} else if roles := approval.MissingRolesAt(*approvalTask, now); len(roles) > 0 {
+		status.MarkMandatoryNotMet(missingRolesReason, "Awaiting an approval in the roles %s", strings.Join(roles, ", "))
+	} else {
+		status.MarkMandatoryMet()
+	}
+
+	// Expiring is final, and explains why the task is not Ready over the
+	// rejection it leads to
+	if expired := status.GetCondition(v1alpha1.ApprovalTaskConditionNotExpired); expired.IsFalse() {
+		status.MarkExpired(expired.Reason, "%s", expired.Message)
+		return
+	}
+	status.MarkNotExpired()
+
+	switch status.State {
+	case rejectedState:
+		status.MarkNotReady(rejectedReason, "The approval task was rejected")
+	case withdrawnState:
+		status.MarkNotReady(withdrawnReason, "The approval task was withdrawn")
+	}
+}
+
+// markTimedOut sets the phase and the conditions of an approval task that
+// timed out after timeout.
+func markTimedOut(approvalTask *v1alpha1.ApprovalTask, timeout time.Duration, now time.Time) {
+	approvalTask.Status.Phase = v1alpha1.ApprovalTaskPhaseExpired
+	approvalTask.Status.MarkExpired(timedOutReason, "The approval task timed out after %v", timeout)
+	setConditions(approvalTask, now)
+}
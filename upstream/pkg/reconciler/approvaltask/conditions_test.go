@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/apis"
+)
+
+// conditionSummary returns the status and the reason of every condition of
+// the approval task.
+func conditionSummary(at v1alpha1.ApprovalTask) map[apis.ConditionType]string {
+	summary := map[apis.ConditionType]string{}
+	for _, c := range at.Status.Conditions {
+		summary[c.Type] = string(c.Status)
+		if c.Reason != "" {
+			summary[c.Type] += "/" + c.Reason
+		}
+	}
+	return summary
+}
+
+func TestUpdateApprovalStateConditions(t *testing.T) {
+	approvalTask := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+				{Name: "bob", Type: "User", Input: "request-changes"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+	client := fake.NewSimpleClientset(approvalTask)
+	clock := clocktesting.NewFakePassiveClock(time.Now())
+
+	at, err := updateApprovalState(context.TODO(), client, nil, clock, nil, approvalTask)
+	assert.NoError(t, err)
+	assert.Equal(t, map[apis.ConditionType]string{
+		apis.ConditionReady:                        "False/ChangesRequested",
+		v1alpha1.ApprovalTaskConditionQuorumMet:    "False/AwaitingApprovals",
+		v1alpha1.ApprovalTaskConditionMandatoryMet: "False/ChangesRequested",
+		v1alpha1.ApprovalTaskConditionNotExpired:   "True",
+	}, conditionSummary(at))
+	assert.Equal(t, "1 of 2 required approvals received", at.Status.GetCondition(v1alpha1.ApprovalTaskConditionQuorumMet).Message)
+	assert.Equal(t, "Changes requested by bob", at.Status.GetCondition(v1alpha1.ApprovalTaskConditionMandatoryMet).Message)
+
+	at.Spec.Approvers[1].Input = "approve"
+	at, err = updateApprovalState(context.TODO(), client, nil, clock, nil, &at)
+	assert.NoError(t, err)
+	assert.Equal(t, "approved", at.Status.State)
+	assert.Equal(t, map[apis.ConditionType]string{
+		apis.ConditionReady:                        "True",
+		v1alpha1.ApprovalTaskConditionQuorumMet:    "True",
+		v1alpha1.ApprovalTaskConditionMandatoryMet: "True",
+		v1alpha1.ApprovalTaskConditionNotExpired:   "True",
+	}, conditionSummary(at))
+
+	stored, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.True(t, stored.Status.GetCondition(apis.ConditionReady).IsTrue(), "the conditions are stored with the status")
+}
+
+func TestSetConditionsRejected(t *testing.T) {
+	at := &v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			Approvers:                 []v1alpha1.ApproverDetails{{Name: "alice", Type: "User", Input: "reject"}},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: rejectedState},
+	}
+	setConditions(at, time.Now())
+	ready := at.Status.GetCondition(apis.ConditionReady)
+	assert.Equal(t, corev1.ConditionFalse, ready.Status)
+	assert.Equal(t, "Rejected", ready.Reason)
+
+	at.Status.State = withdrawnState
+	setConditions(at, time.Now())
+	assert.Equal(t, "Withdrawn", at.Status.GetCondition(apis.ConditionReady).Reason)
+}
+
+func TestMarkTimedOut(t *testing.T) {
+	at := &v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			Approvers:                 []v1alpha1.ApproverDetails{{Name: "alice", Type: "User", Input: "pending"}},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: pendingState},
+	}
+	setConditions(at, time.Now())
+	assert.True(t, at.Status.GetCondition(v1alpha1.ApprovalTaskConditionNotExpired).IsTrue())
+
+	at.Status.State = rejectedState
+	markTimedOut(at, time.Hour, time.Now())
+	expired := at.Status.GetCondition(v1alpha1.ApprovalTaskConditionNotExpired)
+	assert.True(t, expired.IsFalse())
+	assert.Equal(t, "The approval task timed out after 1h0m0s", expired.Message)
+	assert.Equal(t, "TimedOut", at.Status.GetCondition(apis.ConditionReady).Reason, "the timeout explains the rejection")
+
+	// Approvals coming in late do not undo the expiry
+	at.Spec.Approvers[0].Input = "approve"
+	setConditions(at, time.Now())
+	assert.True(t, at.Status.GetCondition(v1alpha1.ApprovalTaskConditionQuorumMet).IsTrue())
+	assert.True(t, at.Status.GetCondition(v1alpha1.ApprovalTaskConditionNotExpired).IsFalse())
+	assert.Equal(t, "TimedOut", at.Status.GetCondition(apis.ConditionReady).Reason)
+}
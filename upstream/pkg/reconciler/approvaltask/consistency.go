@@ -0,0 +1,160 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/callback"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	// responsesRepairedReason is the reason of the event emitted after the
+	// controller dropped responses that do not match the approvers.
+	responsesRepairedReason = "ResponsesRepaired"
+	// responsesRejectedReason is the reason of the event emitted after the
+	// controller rejected a task because of such responses.
+	responsesRejectedReason = "ResponsesRejected"
+)
+
+// inconsistentResponses describes every response in the status of the
+// approval task that the spec does not account for: responses of approvers
+// that are not in Spec.Approvers, and group members that are not among the
+// users of their Group approver. Status corruption, for example from tools
+// writing the status directly, shows up here.
+func inconsistentResponses(approvalTask v1alpha1.ApprovalTask) []string {
+	var problems []string
+	for _, response := range approvalTask.Status.ApproversResponse {
+		responseType := v1alpha1.DefaultedApproverType(response.Type)
+		approver, ok := findApprover(approvalTask.Spec.Approvers, response.Name, responseType)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("response of %s approver '%s' does not match any approver", responseType, response.Name))
+			continue
+		}
+		for _, member := range response.GroupMembers {
+			if !hasGroupUser(approver, member.Name) {
+				problems = append(problems, fmt.Sprintf("response of group '%s' lists '%s', who is not one of its users", response.Name, member.Name))
+			}
+		}
+	}
+	return problems
+}
+
+// repairResponses drops the responses and group members reported by
+// inconsistentResponses and recomputes the approval count. An approved state
+// the remaining approvals do not support goes back to pending.
+func repairResponses(approvalTask *v1alpha1.ApprovalTask, now time.Time) {
+	responses := []v1alpha1.ApproverState{}
+	for _, response := range approvalTask.Status.ApproversResponse {
+		approver, ok := findApprover(approvalTask.Spec.Approvers, response.Name, v1alpha1.DefaultedApproverType(response.Type))
+		if !ok {
+			continue
+		}
+		if len(response.GroupMembers) > 0 {
+			members := []v1alpha1.GroupMemberState{}
+			for _, member := range response.GroupMembers {
+				if hasGroupUser(approver, member.Name) {
+					members = append(members, member)
+				}
+			}
+			response.GroupMembers = members
+		}
+		responses = append(responses, response)
+	}
+	approvalTask.Status.ApproversResponse = responses
+	approvalTask.Status.ApprovalsReceived = approval.CountApprovalsAt(*approvalTask, now)
+
+	if approvalTask.Status.State == approvedState && !approval.QuorumReachedAt(*approvalTask, now) {
+		approvalTask.Status.State = pendingState
+	}
+}
+
+// checkResponseConsistency repairs the status of the approval task when its
+// responses do not match its approvers or, when r.rejectInconsistentResponses
+// is set, rejects the task. It reports whether the task was rejected.
+func (r *Reconciler) checkResponseConsistency(ctx context.Context, approvalTask *v1alpha1.ApprovalTask, run *v1beta1.CustomRun) (bool, error) {
+	problems := inconsistentResponses(*approvalTask)
+	if len(problems) == 0 {
+		return false, nil
+	}
+	logger := logging.FromContext(ctx)
+	details := strings.Join(problems, "; ")
+
+	if r.rejectInconsistentResponses {
+		approvalTask.Status.State = rejectedState
+		approvalTask.Status.ObservedGeneration = approvalTask.Generation
+		setConditions(approvalTask, r.clock.Now())
+		if _, err := r.approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(approvalTask.Namespace).UpdateStatus(ctx, approvalTask, metav1.UpdateOptions{}); err != nil {
+			return false, err
+		}
+		r.callback.Notify(ctx, callback.NewPayload(*approvalTask))
+		logger.Warnf("Approval task %s rejected because its status is inconsistent: %s", approvalTask.Name, details)
+		recordEvent(ctx, run, responsesRejectedReason, "Rejected approval task %s: %s", approvalTask.Name, details)
+		run.Status.MarkCustomRunFailed(v1alpha1.ApprovalTaskRunReasonInconsistentStatus.String(),
+			"Approval task %s has responses that do not match its approvers: %s", approvalTask.Name, details)
+		r.recordDecisionDuration(ctx, approvalTask, rejectedState)
+		return true, nil
+	}
+
+	repairResponses(approvalTask, r.clock.Now())
+	setConditions(approvalTask, r.clock.Now())
+	at, err := r.approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(approvalTask.Namespace).UpdateStatus(ctx, approvalTask, metav1.UpdateOptions{})
+	if err != nil {
+		return false, err
+	}
+	*approvalTask = *at
+	logger.Warnf("Repaired the status of approval task %s: %s", approvalTask.Name, details)
+	recordEvent(ctx, run, responsesRepairedReason, "Repaired approval task %s: %s", approvalTask.Name, details)
+	return false, nil
+}
+
+// recordEvent emits a warning event about the run, if an event recorder is
+// available.
+func recordEvent(ctx context.Context, run *v1beta1.CustomRun, reason, messageFmt string, args ...interface{}) {
+	if recorder := controller.GetEventRecorder(ctx); recorder != nil {
+		recorder.Eventf(run, corev1.EventTypeWarning, reason, messageFmt, args...)
+	}
+}
+
+// findApprover returns the approver of the given name and type.
+func findApprover(approvers []v1alpha1.ApproverDetails, name, approverType string) (v1alpha1.ApproverDetails, bool) {
+	for _, approver := range approvers {
+		if approver.Name == name && v1alpha1.DefaultedApproverType(approver.Type) == approverType {
+			return approver, true
+		}
+	}
+	return v1alpha1.ApproverDetails{}, false
+}
+
+func hasGroupUser(approver v1alpha1.ApproverDetails, name string) bool {
+	for _, user := range approver.Users {
+		if user.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -17,41 +17,127 @@ package approvaltask
 
 import (
 	"context"
+	"net/http"
+	"time"
 
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask"
 	approvaltaskv1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/callback"
 	approvaltaskclient "github.com/openshift-pipelines/manual-approval-gate/pkg/client/injection/client"
 	approvaltaskinformer "github.com/openshift-pipelines/manual-approval-gate/pkg/client/injection/informers/approvaltask/v1alpha1/approvaltask"
+	policyinformer "github.com/openshift-pipelines/manual-approval-gate/pkg/client/injection/informers/approvaltask/v1alpha1/clusterapprovalpolicy"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/labelcap"
+	pipelineinformers "github.com/tektoncd/pipeline/pkg/client/informers/externalversions"
 	pipelineclient "github.com/tektoncd/pipeline/pkg/client/injection/client"
 	customruninformer "github.com/tektoncd/pipeline/pkg/client/injection/informers/pipeline/v1beta1/customrun"
 	customrunreconciler "github.com/tektoncd/pipeline/pkg/client/injection/reconciler/pipeline/v1beta1/customrun"
 	pipelinecontroller "github.com/tektoncd/pipeline/pkg/controller"
+	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/utils/clock"
 	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	nsinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/namespace"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
+	"knative.dev/pkg/injection"
 	"knative.dev/pkg/logging"
 )
 
+// Options holds the optional, controller-wide settings of the ApprovalTask controller.
+type Options struct {
+	// Callback, when set, is notified every time an ApprovalTask reaches a final state.
+	Callback *callback.Notifier
+	// MaxRequeueInterval, when set, bounds how long a pending ApprovalTask
+	// waits before it is re-evaluated, even if no time event is due earlier.
+	MaxRequeueInterval time.Duration
+	// RejectInconsistentResponses rejects ApprovalTasks whose status records
+	// responses that do not match their approvers. By default the offending
+	// responses are dropped from the status instead.
+	RejectInconsistentResponses bool
+	// AdminAddress is the address the admin endpoints, such as the recompute
+	// of every pending ApprovalTask, are served on over TLS, for example
+	// ":8081". They are disabled when it is empty.
+	AdminAddress string
+	// AdminTLSCertFile and AdminTLSKeyFile are the PEM encoded certificate
+	// and key the admin endpoints are served with. Both are required with
+	// AdminAddress.
+	AdminTLSCertFile string
+	AdminTLSKeyFile  string
+	// RecomputeQPS bounds how many pending ApprovalTasks a recompute enqueues
+	// per second. Defaults to DefaultRecomputeQPS.
+	RecomputeQPS float64
+	// DisplayLocation, when set, is the timezone the start time and the time
+	// of the last decision are rendered in next to their UTC value in the
+	// ApprovalTask status.
+	DisplayLocation *time.Location
+	// CleanupHook, when set, is notified when an ApprovalTask is deleted.
+	// ApprovalTasks are then created with v1alpha1.CleanupFinalizer, which
+	// the controller started by NewFinalizerController releases once the
+	// hook succeeded.
+	CleanupHook CleanupHook
+	// CleanupTimeout bounds how long after the deletion a failing cleanup is
+	// retried before the finalizer is released anyway. Defaults to
+	// DefaultCleanupTimeout.
+	CleanupTimeout time.Duration
+	// CarryForwardApprovals starts an ApprovalTask whose CustomRun carries
+	// v1alpha1.ApprovalIdentityLabelKey with the approvals given on the most
+	// recent other ApprovalTask with the same label, as long as both have the
+	// same approvers, so that approvers need not approve a retry again.
+	CarryForwardApprovals bool
+	// DefaultApprovalsRequired is written into pending ApprovalTasks that set
+	// neither NumberOfApprovalsRequired nor ApprovalPercentage, such as ones
+	// created before the webhook validated them. Zero, the default, requires
+	// every eligible approver, which such tasks are held to until then.
+	DefaultApprovalsRequired int
+	// ApproversFromPipelineRun watches PipelineRuns across the namespaces of
+	// the controller, so that ApprovalTasks can take their approvers from the
+	// PipelineRun they are part of with the approversFromPipelineRun param.
+	// Runs setting it fail when it is disabled.
+	ApproversFromPipelineRun bool
+	// MetricsLabelCap bounds the number of distinct namespaces the decision
+	// duration metric is labelled with. Decisions in further namespaces are
+	// recorded without the label. Defaults to labelcap.DefaultLimit.
+	MetricsLabelCap int
+}
+
+func (o Options) cleanupTimeout() time.Duration {
+	if o.CleanupTimeout <= 0 {
+		return DefaultCleanupTimeout
+	}
+	return o.CleanupTimeout
+}
+
 // NewController instantiates a new controller.Impl from knative.dev/pkg/controller
-func NewController(clock clock.PassiveClock) func(context.Context, configmap.Watcher) *controller.Impl {
+func NewController(clock clock.PassiveClock, opts Options) func(context.Context, configmap.Watcher) *controller.Impl {
 	return func(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
 
 		logger := logging.FromContext(ctx)
+		registerLatencyView()
 		kubeclientset := kubeclient.Get(ctx)
 		pipelineclientset := pipelineclient.Get(ctx)
 		approvaltaskclientset := approvaltaskclient.Get(ctx)
 		customRunInformer := customruninformer.Get(ctx)
 		approvaltaskInformer := approvaltaskinformer.Get(ctx)
+		policyInformer := policyinformer.Get(ctx)
+		namespaceInformer := nsinformer.Get(ctx)
 
 		c := &Reconciler{
-			clock:                 clock,
-			kubeClientSet:         kubeclientset,
-			pipelineClientSet:     pipelineclientset,
-			approvaltaskClientSet: approvaltaskclientset,
-			customRunLister:       customRunInformer.Lister(),
-			approvaltaskLister:    approvaltaskInformer.Lister(),
+			clock:                       clock,
+			kubeClientSet:               kubeclientset,
+			pipelineClientSet:           pipelineclientset,
+			approvaltaskClientSet:       approvaltaskclientset,
+			customRunLister:             customRunInformer.Lister(),
+			approvaltaskLister:          approvaltaskInformer.Lister(),
+			callback:                    opts.Callback,
+			maxRequeueInterval:          opts.MaxRequeueInterval,
+			rejectInconsistentResponses: opts.RejectInconsistentResponses,
+			displayLocation:             opts.DisplayLocation,
+			cleanupHook:                 opts.CleanupHook,
+			policyLister:                policyInformer.Lister(),
+			namespaceLister:             namespaceInformer.Lister(),
+			carryForwardApprovals:       opts.CarryForwardApprovals,
+			defaultApprovalsRequired:    opts.DefaultApprovalsRequired,
+			metricsNamespaces:           labelcap.New(opts.MetricsLabelCap),
 		}
 
 		impl := customrunreconciler.NewImpl(ctx, c, func(impl *controller.Impl) controller.Options {
@@ -70,6 +156,95 @@ func NewController(clock clock.PassiveClock) func(context.Context, configmap.Wat
 
 		approvaltaskInformer.Informer().AddEventHandler(controller.HandleAll(impl.Enqueue))
 
+		// Pending tasks are re-evaluated whenever a cluster approval policy
+		// changes, since any of them may select them
+		policyInformer.Informer().AddEventHandler(controller.HandleAll(func(interface{}) {
+			enqueuePendingRuns(ctx, approvaltaskInformer.Lister(), impl.EnqueueKey)
+		}))
+
+		// OWNERS files are read from ConfigMaps, which the injected
+		// informers do not include either
+		kubeInformers := kubeinformers.NewSharedInformerFactoryWithOptions(kubeclientset, controller.GetResyncPeriod(ctx),
+			kubeinformers.WithNamespace(injection.GetNamespaceScope(ctx)))
+		c.configMapLister = kubeInformers.Core().V1().ConfigMaps().Lister()
+		kubeInformers.Start(ctx.Done())
+
+		// Only approvers taken from a PipelineRun need PipelineRuns, which
+		// the injected informers do not include
+		if opts.ApproversFromPipelineRun {
+			pipelineInformers := pipelineinformers.NewSharedInformerFactory(pipelineclientset, controller.GetResyncPeriod(ctx))
+			c.pipelineRunLister = pipelineInformers.Tekton().V1().PipelineRuns().Lister()
+			pipelineInformers.Start(ctx.Done())
+		}
+
+		if opts.AdminAddress != "" {
+			mux := http.NewServeMux()
+			mux.Handle(RecomputePath, newRecomputeHandler(ctx, kubeclientset, approvaltaskInformer.Lister(), impl.EnqueueKey, opts.RecomputeQPS))
+			go serveAdmin(ctx, opts.AdminAddress, opts.AdminTLSCertFile, opts.AdminTLSKeyFile, mux)
+		}
+
+		return impl
+	}
+}
+
+// NewFinalizerController instantiates the controller releasing the cleanup
+// finalizer of deleted ApprovalTasks. It runs whether or not a cleanup hook is
+// configured, so that tasks created while one was are not left behind.
+func NewFinalizerController(clock clock.PassiveClock, opts Options) func(context.Context, configmap.Watcher) *controller.Impl {
+	return func(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+		logger := logging.FromContext(ctx)
+		approvaltaskInformer := approvaltaskinformer.Get(ctx)
+
+		f := &finalizerReconciler{
+			clock:                 clock,
+			approvaltaskClientSet: approvaltaskclient.Get(ctx),
+			approvaltaskLister:    approvaltaskInformer.Lister(),
+			hook:                  opts.CleanupHook,
+			timeout:               opts.cleanupTimeout(),
+		}
+		impl := controller.NewContext(ctx, f, controller.ControllerOptions{
+			WorkQueueName: "ApprovalTaskFinalizer",
+			Logger:        logger,
+		})
+
+		approvaltaskInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+			FilterFunc: func(obj interface{}) bool {
+				approvalTask, ok := obj.(*approvaltaskv1alpha1.ApprovalTask)
+				return ok && pendingCleanup(approvalTask)
+			},
+			Handler: controller.HandleAll(impl.Enqueue),
+		})
+
+		return impl
+	}
+}
+
+// NewTTLController instantiates the controller deleting finished
+// ApprovalTasks once their Spec.TTLSecondsAfterFinished elapsed.
+func NewTTLController(clock clock.PassiveClock) func(context.Context, configmap.Watcher) *controller.Impl {
+	return func(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+		logger := logging.FromContext(ctx)
+		approvaltaskInformer := approvaltaskinformer.Get(ctx)
+
+		t := &ttlReconciler{
+			clock:                 clock,
+			approvaltaskClientSet: approvaltaskclient.Get(ctx),
+			approvaltaskLister:    approvaltaskInformer.Lister(),
+			customRunLister:       customruninformer.Get(ctx).Lister(),
+		}
+		impl := controller.NewContext(ctx, t, controller.ControllerOptions{
+			WorkQueueName: "ApprovalTaskTTL",
+			Logger:        logger,
+		})
+
+		approvaltaskInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+			FilterFunc: func(obj interface{}) bool {
+				approvalTask, ok := obj.(*approvaltaskv1alpha1.ApprovalTask)
+				return ok && expiresAfterFinished(approvalTask)
+			},
+			Handler: controller.HandleAll(impl.Enqueue),
+		})
+
 		return impl
 	}
 }
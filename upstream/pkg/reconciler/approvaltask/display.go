@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DisplayTimeLayout is the layout of the timestamps rendered in the display
+// timezone. Both the offset and the zone abbreviation are included, so that
+// times on either side of a daylight saving change read unambiguously.
+const DisplayTimeLayout = "2006-01-02 15:04:05 -0700 MST"
+
+// lastDecisionAt returns when the most recent of the responses, including
+// those of group members, was first observed.
+func lastDecisionAt(responses []v1alpha1.ApproverState) *metav1.Time {
+	var last *metav1.Time
+	later := func(t *metav1.Time) {
+		if t != nil && (last == nil || last.Before(t)) {
+			last = t
+		}
+	}
+	for _, response := range responses {
+		later(response.RespondedAt)
+		for _, member := range response.GroupMembers {
+			later(member.RespondedAt)
+		}
+	}
+	return last
+}
+
+// displayTime renders t in location, or returns an empty string when either
+// is unset.
+func displayTime(t *metav1.Time, location *time.Location) string {
+	if t == nil || location == nil {
+		return ""
+	}
+	return t.In(location).Format(DisplayTimeLayout)
+}
+
+// setDisplayTimes renders the key timestamps of the status in location.
+func setDisplayTimes(status *v1alpha1.ApprovalTaskStatus, location *time.Location) {
+	status.StartTimeLocal = displayTime(status.StartTime, location)
+	status.LastDecisionAtLocal = displayTime(status.LastDecisionAt, location)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func berlin(t *testing.T) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("failed loading timezone: %v", err)
+	}
+	return location
+}
+
+func TestDisplayTimeAcrossDST(t *testing.T) {
+	location := berlin(t)
+	before := metav1.NewTime(time.Date(2024, 3, 31, 0, 30, 0, 0, time.UTC))
+	after := metav1.NewTime(time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC))
+	autumn := metav1.NewTime(time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC))
+	autumnLater := metav1.NewTime(time.Date(2024, 10, 27, 1, 30, 0, 0, time.UTC))
+
+	assert.Equal(t, "2024-03-31 01:30:00 +0100 CET", displayTime(&before, location))
+	assert.Equal(t, "2024-03-31 03:30:00 +0200 CEST", displayTime(&after, location))
+	// The repeated hour in autumn is told apart by its offset
+	assert.Equal(t, "2024-10-27 02:30:00 +0200 CEST", displayTime(&autumn, location))
+	assert.Equal(t, "2024-10-27 02:30:00 +0100 CET", displayTime(&autumnLater, location))
+
+	assert.Empty(t, displayTime(nil, location))
+	assert.Empty(t, displayTime(&before, nil))
+}
+
+func TestLastDecisionAt(t *testing.T) {
+	early := metav1.NewTime(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	late := metav1.NewTime(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	responses := []v1alpha1.ApproverState{
+		{Name: "alice", Type: "User", Response: "approved", RespondedAt: &early},
+		{Name: "platform", Type: "Group", Response: "approved", GroupMembers: []v1alpha1.GroupMemberState{{Name: "bob", Response: "approved", RespondedAt: &late}}},
+	}
+	assert.Equal(t, &late, lastDecisionAt(responses))
+	assert.Nil(t, lastDecisionAt(nil))
+}
+
+func TestUpdateApprovalStateRendersDisplayTimes(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	fakeClock := clocktesting.NewFakePassiveClock(time.Date(2024, 7, 1, 9, 15, 0, 0, time.UTC))
+	at := newExpiringApprovalTask(t, client, v1alpha1.ApproverDetails{Name: "alice", Type: "User", Input: "approve"})
+	startTime := metav1.NewTime(time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC))
+	at.Status.StartTime = &startTime
+
+	result, err := updateApprovalState(context.TODO(), client, nil, fakeClock, berlin(t), at)
+	assert.NoError(t, err)
+	assert.Equal(t, fakeClock.Now(), result.Status.LastDecisionAt.Time.UTC())
+	assert.Equal(t, "2024-07-01 11:00:00 +0200 CEST", result.Status.StartTimeLocal)
+	assert.Equal(t, "2024-07-01 11:15:00 +0200 CEST", result.Status.LastDecisionAtLocal)
+
+	// Without a display timezone only the UTC time is kept
+	result, err = updateApprovalState(context.TODO(), client, nil, fakeClock, nil, &result)
+	assert.NoError(t, err)
+	assert.NotNil(t, result.Status.LastDecisionAt)
+	assert.Empty(t, result.Status.StartTimeLocal)
+	assert.Empty(t, result.Status.LastDecisionAtLocal)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"errors"
+	"strings"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	listersapprovaltask "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+var errNoEscrowScope = errors.New("escrow groups cannot be released without listers")
+
+// escrowScope finds the ApprovalTasks sharing the escrow group of a task in
+// the informer caches. Escrow groups are local to the namespace of the task:
+// a task in another namespace only belongs to the group when both namespaces
+// list it in their v1alpha1.EscrowGroupsAnnotationKey annotation. Namespaces
+// are cluster scoped, so that tenants cannot join, hold or release the group
+// of another namespace by reusing its name.
+type escrowScope struct {
+	approvalTasks listersapprovaltask.ApprovalTaskLister
+	namespaces    corelisters.NamespaceLister
+}
+
+// siblings returns copies of the other ApprovalTasks of the escrow group of
+// the task.
+func (e *escrowScope) siblings(task *v1alpha1.ApprovalTask) ([]v1alpha1.ApprovalTask, error) {
+	if e == nil || e.approvalTasks == nil {
+		return nil, errNoEscrowScope
+	}
+	group := task.Spec.EscrowGroup
+	shared := map[string]bool{task.Namespace: e.sharesGroup(task.Namespace, group)}
+
+	var candidates []*v1alpha1.ApprovalTask
+	var err error
+	if shared[task.Namespace] {
+		candidates, err = e.approvalTasks.List(labels.Everything())
+	} else {
+		candidates, err = e.approvalTasks.ApprovalTasks(task.Namespace).List(labels.Everything())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var siblings []v1alpha1.ApprovalTask
+	for _, candidate := range candidates {
+		if candidate.Spec.EscrowGroup != group || (candidate.Namespace == task.Namespace && candidate.Name == task.Name) {
+			continue
+		}
+		if candidate.Namespace != task.Namespace {
+			if _, ok := shared[candidate.Namespace]; !ok {
+				shared[candidate.Namespace] = e.sharesGroup(candidate.Namespace, group)
+			}
+			if !shared[candidate.Namespace] {
+				continue
+			}
+		}
+		siblings = append(siblings, *candidate.DeepCopy())
+	}
+	return siblings, nil
+}
+
+// sharesGroup tells whether the namespace lists the escrow group in its
+// v1alpha1.EscrowGroupsAnnotationKey annotation.
+func (e *escrowScope) sharesGroup(namespace, group string) bool {
+	if e.namespaces == nil {
+		return false
+	}
+	ns, err := e.namespaces.Get(namespace)
+	if err != nil {
+		return false
+	}
+	for _, name := range strings.Split(ns.Annotations[v1alpha1.EscrowGroupsAnnotationKey], ",") {
+		if strings.TrimSpace(name) == group {
+			return true
+		}
+	}
+	return false
+}
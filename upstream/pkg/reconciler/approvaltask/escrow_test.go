@@ -0,0 +1,129 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	listersapprovaltask "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
+)
+
+// sharingNamespaces returns the named namespaces, each listing the escrow
+// group in its escrow groups annotation.
+func sharingNamespaces(group string, names ...string) []*corev1.Namespace {
+	var namespaces []*corev1.Namespace
+	for _, name := range names {
+		namespaces = append(namespaces, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: map[string]string{v1alpha1.EscrowGroupsAnnotationKey: "hotfix, " + group},
+		}})
+	}
+	return namespaces
+}
+
+// escrowScopeOf returns an escrow scope listing the ApprovalTasks of the
+// client, as the informer cache holds them, and the given namespaces.
+func escrowScopeOf(t *testing.T, client *fake.Clientset, namespaces ...*corev1.Namespace) *escrowScope {
+	t.Helper()
+	tasks, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("listing ApprovalTasks: %v", err)
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for i := range tasks.Items {
+		if err := indexer.Add(&tasks.Items[i]); err != nil {
+			t.Fatalf("adding ApprovalTask: %v", err)
+		}
+	}
+	return &escrowScope{
+		approvalTasks: listersapprovaltask.NewApprovalTaskLister(indexer),
+		namespaces:    namespaceLister(t, namespaces...),
+	}
+}
+
+// approveEscrowedApprovalTaskIn approves the escrowed task like
+// approveEscrowedApprovalTask, with the given namespaces.
+func approveEscrowedApprovalTaskIn(t *testing.T, client *fake.Clientset, at *v1alpha1.ApprovalTask, namespaces ...*corev1.Namespace) v1alpha1.ApprovalTask {
+	t.Helper()
+	at.Spec.Approvers[0].Input = "approve"
+	updated, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks(at.Namespace).Update(context.TODO(), at, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("failed updating approval task %s/%s: %v", at.Namespace, at.Name, err)
+	}
+	result, err := updateApprovalState(context.TODO(), client, escrowScopeOf(t, client, namespaces...), clock.RealClock{}, nil, updated)
+	if err != nil {
+		t.Fatalf("updateApprovalState returned an error: %v", err)
+	}
+	return result
+}
+
+func TestEscrowGroupIsLocalToTheNamespace(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	newEscrowedApprovalTask(t, client, "tenant", "deploy", "pending")
+	production := newEscrowedApprovalTask(t, client, "production", "deploy", "pending")
+
+	at := approveEscrowedApprovalTaskIn(t, client, production)
+	assert.Equal(t, "approved", at.Status.State, "the pending task of another namespace does not hold the group")
+
+	client = fake.NewSimpleClientset()
+	tenant := newEscrowedApprovalTask(t, client, "tenant", "deploy", "approve")
+	production = newEscrowedApprovalTask(t, client, "production", "deploy", "pending")
+	second := newEscrowedApprovalTask(t, client, "production", "verify", "pending")
+	at = approveEscrowedApprovalTaskIn(t, client, production)
+	assert.Equal(t, "pending", at.Status.State, "tasks of the same namespace share the group")
+	at = approveEscrowedApprovalTaskIn(t, client, tenant)
+	assert.Equal(t, "approved", at.Status.State)
+	assert.Equal(t, "pending", escrowedApprovalTaskState(t, client, "production", "deploy"), "another namespace cannot release the group")
+
+	at = approveEscrowedApprovalTaskIn(t, client, second)
+	assert.Equal(t, "approved", at.Status.State)
+	assert.Equal(t, "approved", escrowedApprovalTaskState(t, client, "production", "deploy"))
+}
+
+func TestEscrowGroupSharedByBothNamespaces(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	newEscrowedApprovalTask(t, client, "tenant", "deploy", "pending")
+	production := newEscrowedApprovalTask(t, client, "production", "deploy", "pending")
+
+	// Only production shares the group, so the tenant cannot join it
+	at := approveEscrowedApprovalTaskIn(t, client, production, sharingNamespaces("release", "production")...)
+	assert.Equal(t, "approved", at.Status.State)
+
+	client = fake.NewSimpleClientset()
+	tenant := newEscrowedApprovalTask(t, client, "tenant", "deploy", "pending")
+	production = newEscrowedApprovalTask(t, client, "production", "deploy", "pending")
+	at = approveEscrowedApprovalTaskIn(t, client, production, sharingNamespaces("release", "production", "tenant")...)
+	assert.Equal(t, "pending", at.Status.State, "both namespaces share the group")
+	at = approveEscrowedApprovalTaskIn(t, client, tenant, sharingNamespaces("release", "production", "tenant")...)
+	assert.Equal(t, "approved", at.Status.State)
+	assert.Equal(t, "approved", escrowedApprovalTaskState(t, client, "production", "deploy"))
+}
+
+func TestReleaseEscrowGroupWithoutScope(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	at := newEscrowedApprovalTask(t, client, "production", "deploy", "approve")
+	_, err := updateApprovalState(context.TODO(), client, nil, clock.RealClock{}, nil, at)
+	assert.ErrorIs(t, err, errNoEscrowScope)
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	approvaltaskclientset "github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned"
+	listersapprovaltask "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+)
+
+// DefaultCleanupTimeout is how long after its deletion the cleanup of an
+// ApprovalTask is retried before the finalizer is removed regardless.
+const DefaultCleanupTimeout = 10 * time.Minute
+
+// cleanupAbandonedReason is the reason of the event emitted when the cleanup
+// of a deleted approval task is given up on.
+const cleanupAbandonedReason = "CleanupAbandoned"
+
+// CleanupHook is notified when an ApprovalTask carrying the cleanup finalizer
+// is deleted, so that external state tied to it can be removed. Failures are
+// retried by the caller.
+type CleanupHook interface {
+	Cleanup(ctx context.Context, approvalTask v1alpha1.ApprovalTask) error
+}
+
+// finalizerReconciler runs the cleanup hook for deleted approval tasks and
+// then releases their v1alpha1.CleanupFinalizer. It reconciles approval
+// tasks rather than CustomRuns, because the run is usually gone by the time
+// its task is garbage collected.
+type finalizerReconciler struct {
+	clock                 clock.PassiveClock
+	approvaltaskClientSet approvaltaskclientset.Interface
+	approvaltaskLister    listersapprovaltask.ApprovalTaskLister
+	// hook may be nil, in which case the finalizer is released right away.
+	hook CleanupHook
+	// timeout bounds how long after the deletion a failing cleanup is retried.
+	timeout time.Duration
+}
+
+func (f *finalizerReconciler) Reconcile(ctx context.Context, key string) error {
+	logger := logging.FromContext(ctx)
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		logger.Errorf("Invalid resource key %s: %v", key, err)
+		return nil
+	}
+	approvalTask, err := f.approvaltaskLister.ApprovalTasks(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !pendingCleanup(approvalTask) {
+		return nil
+	}
+
+	if f.hook != nil {
+		if err := f.hook.Cleanup(ctx, *approvalTask); err != nil {
+			waited := f.clock.Since(approvalTask.DeletionTimestamp.Time)
+			if waited < f.timeout {
+				return fmt.Errorf("cleanup of approval task %s failed, retrying: %w", key, err)
+			}
+			logger.Errorf("Giving up the cleanup of approval task %s after %s: %v", key, waited.Round(time.Second), err)
+			if recorder := controller.GetEventRecorder(ctx); recorder != nil {
+				recorder.Eventf(approvalTask, corev1.EventTypeWarning, cleanupAbandonedReason,
+					"Removed the finalizer of approval task %s without cleaning up: %v", approvalTask.Name, err)
+			}
+		}
+	}
+	return f.releaseFinalizer(ctx, approvalTask)
+}
+
+// releaseFinalizer removes v1alpha1.CleanupFinalizer from the approval task.
+// The patch carries the resource version, so it fails rather than dropping
+// finalizers added concurrently.
+func (f *finalizerReconciler) releaseFinalizer(ctx context.Context, approvalTask *v1alpha1.ApprovalTask) error {
+	finalizers := slices.DeleteFunc(slices.Clone(approvalTask.Finalizers), func(finalizer string) bool {
+		return finalizer == v1alpha1.CleanupFinalizer
+	})
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"finalizers":      finalizers,
+			"resourceVersion": approvalTask.ResourceVersion,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = f.approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(approvalTask.Namespace).Patch(ctx, approvalTask.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// pendingCleanup reports whether the approval task is being deleted and
+// still carries the cleanup finalizer.
+func pendingCleanup(approvalTask *v1alpha1.ApprovalTask) bool {
+	return approvalTask.DeletionTimestamp != nil && slices.Contains(approvalTask.Finalizers, v1alpha1.CleanupFinalizer)
+}
+
+// finalizers returns the finalizers approval tasks are created with.
+func (r *Reconciler) finalizers() []string {
+	if r.cleanupHook == nil {
+		return nil
+	}
+	return []string{v1alpha1.CleanupFinalizer}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	listersapprovaltask "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/controller"
+)
+
+// fakeCleanupHook records the tasks it was called for and fails with err.
+type fakeCleanupHook struct {
+	calls []string
+	err   error
+}
+
+func (h *fakeCleanupHook) Cleanup(_ context.Context, approvalTask v1alpha1.ApprovalTask) error {
+	h.calls = append(h.calls, approvalTask.Name)
+	return h.err
+}
+
+var deletedAt = time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+func deletedApprovalTask() *v1alpha1.ApprovalTask {
+	deletionTimestamp := metav1.NewTime(deletedAt)
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "deploy",
+			Namespace:         "production",
+			DeletionTimestamp: &deletionTimestamp,
+			Finalizers:        []string{"example.com/other", v1alpha1.CleanupFinalizer},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "approved"},
+	}
+}
+
+func newFinalizerTestReconciler(t *testing.T, now time.Time, hook CleanupHook, tasks ...*v1alpha1.ApprovalTask) (*finalizerReconciler, *fake.Clientset) {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	client := fake.NewSimpleClientset()
+	for _, at := range tasks {
+		assert.NoError(t, indexer.Add(at))
+		assert.NoError(t, client.Tracker().Add(at))
+	}
+	return &finalizerReconciler{
+		clock:                 clocktesting.NewFakePassiveClock(now),
+		approvaltaskClientSet: client,
+		approvaltaskLister:    listersapprovaltask.NewApprovalTaskLister(indexer),
+		hook:                  hook,
+		timeout:               DefaultCleanupTimeout,
+	}, client
+}
+
+func finalizersOf(t *testing.T, client *fake.Clientset) []string {
+	t.Helper()
+	at, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	return at.Finalizers
+}
+
+func TestFinalizerRunsCleanupBeforeRelease(t *testing.T) {
+	hook := &fakeCleanupHook{}
+	f, client := newFinalizerTestReconciler(t, deletedAt.Add(time.Second), hook, deletedApprovalTask())
+
+	assert.NoError(t, f.Reconcile(context.TODO(), "production/deploy"))
+	assert.Equal(t, []string{"deploy"}, hook.calls)
+	assert.Equal(t, []string{"example.com/other"}, finalizersOf(t, client), "other finalizers are kept")
+}
+
+func TestFinalizerRetriesFailedCleanup(t *testing.T) {
+	hook := &fakeCleanupHook{err: errors.New("connection refused")}
+	f, client := newFinalizerTestReconciler(t, deletedAt.Add(time.Minute), hook, deletedApprovalTask())
+
+	err := f.Reconcile(context.TODO(), "production/deploy")
+	assert.ErrorContains(t, err, "connection refused")
+	assert.Contains(t, finalizersOf(t, client), v1alpha1.CleanupFinalizer, "the finalizer is kept while retrying")
+}
+
+func TestFinalizerForceReleasesAfterTimeout(t *testing.T) {
+	hook := &fakeCleanupHook{err: errors.New("connection refused")}
+	f, client := newFinalizerTestReconciler(t, deletedAt.Add(DefaultCleanupTimeout), hook, deletedApprovalTask())
+	recorder := record.NewFakeRecorder(10)
+	ctx := controller.WithEventRecorder(context.TODO(), recorder)
+
+	assert.NoError(t, f.Reconcile(ctx, "production/deploy"))
+	assert.Equal(t, []string{"example.com/other"}, finalizersOf(t, client))
+	assert.Contains(t, <-recorder.Events, "CleanupAbandoned")
+}
+
+func TestFinalizerWithoutHook(t *testing.T) {
+	f, client := newFinalizerTestReconciler(t, deletedAt, nil, deletedApprovalTask())
+	assert.NoError(t, f.Reconcile(context.TODO(), "production/deploy"))
+	assert.Equal(t, []string{"example.com/other"}, finalizersOf(t, client), "tasks are released once cleanup is turned off")
+}
+
+func TestFinalizerIgnoresLiveTasks(t *testing.T) {
+	live := deletedApprovalTask()
+	live.DeletionTimestamp = nil
+	hook := &fakeCleanupHook{}
+	f, client := newFinalizerTestReconciler(t, deletedAt, hook, live)
+
+	assert.NoError(t, f.Reconcile(context.TODO(), "production/deploy"))
+	assert.NoError(t, f.Reconcile(context.TODO(), "production/gone"))
+	assert.Empty(t, hook.calls)
+	assert.Contains(t, finalizersOf(t, client), v1alpha1.CleanupFinalizer)
+}
+
+func TestCreateApprovalTaskWithCleanupFinalizer(t *testing.T) {
+	run := approvalTaskRun()
+	run.Spec.Params = []v1beta1.Param{{Name: "approvers", Value: *v1beta1.NewArrayOrString("alice")}}
+	r := &Reconciler{cleanupHook: &fakeCleanupHook{}}
+
+	at, err := createApprovalTask(context.TODO(), fake.NewSimpleClientset(), run, r.finalizers(), nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{v1alpha1.CleanupFinalizer}, at.Finalizers)
+
+	assert.Nil(t, (&Reconciler{}).finalizers(), "no finalizer without a cleanup hook")
+}
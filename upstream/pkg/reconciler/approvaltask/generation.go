@@ -0,0 +1,42 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// observeGeneration records in the status of the approval task that its
+// latest spec was evaluated, so that clients can wait for
+// Status.ObservedGeneration to reach metadata.generation before trusting the
+// state. It is called once the reconcile succeeded, and only writes the
+// status when the state written during the reconcile did not record it.
+func (r *Reconciler) observeGeneration(ctx context.Context, approvalTask *v1alpha1.ApprovalTask) error {
+	if approvalTask.Status.ObservedGeneration == approvalTask.Generation {
+		return nil
+	}
+	approvalTask.Status.ObservedGeneration = approvalTask.Generation
+	at, err := r.approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(approvalTask.Namespace).UpdateStatus(ctx, approvalTask, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	*approvalTask = *at
+	return nil
+}
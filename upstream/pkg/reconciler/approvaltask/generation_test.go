@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestReconcileObservesGeneration(t *testing.T) {
+	approvalTask := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production", Generation: 1},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "bob", Type: "User", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: pendingState},
+	}
+	client := fake.NewSimpleClientset(approvalTask)
+	r := &Reconciler{clock: clocktesting.NewFakePassiveClock(time.Now()), approvaltaskClientSet: client}
+	tasks := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production")
+
+	// update stores a new spec under the next generation, as the API server
+	// would, and reconciles it
+	update := func(change func(*v1alpha1.ApprovalTask)) *v1alpha1.ApprovalTask {
+		t.Helper()
+		at, err := tasks.Get(context.TODO(), "deploy", metav1.GetOptions{})
+		assert.NoError(t, err)
+		if change != nil {
+			change(at)
+			at.Generation++
+			at, err = tasks.Update(context.TODO(), at, metav1.UpdateOptions{})
+			assert.NoError(t, err)
+		}
+		_ = r.reconcile(context.TODO(), approvalTaskRun(), &v1alpha1.ApprovalTaskRunStatus{})
+		at, err = tasks.Get(context.TODO(), "deploy", metav1.GetOptions{})
+		assert.NoError(t, err)
+		return at
+	}
+
+	at := update(nil)
+	assert.Equal(t, int64(1), at.Status.ObservedGeneration)
+
+	at = update(func(at *v1alpha1.ApprovalTask) { at.Spec.Description = "Deploy to production" })
+	assert.Equal(t, int64(2), at.Status.ObservedGeneration, "spec changes leaving the state alone are observed too")
+	assert.Equal(t, pendingState, at.Status.State)
+
+	at = update(func(at *v1alpha1.ApprovalTask) { at.Spec.Approvers[0].Input = "approve" })
+	assert.Equal(t, int64(3), at.Status.ObservedGeneration)
+	assert.Equal(t, 1, at.Status.ApprovalsReceived, "the state is written with the generation it was computed from")
+
+	at = update(func(at *v1alpha1.ApprovalTask) { at.Spec.Approvers[1].Input = "approve" })
+	assert.Equal(t, int64(4), at.Status.ObservedGeneration)
+	assert.Equal(t, approvedState, at.Status.State)
+}
+
+func TestObserveGenerationSkipsCurrentStatus(t *testing.T) {
+	approvalTask := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production", Generation: 3},
+		Status:     v1alpha1.ApprovalTaskStatus{State: pendingState},
+	}
+	approvalTask.Status.ObservedGeneration = 3
+	client := fake.NewSimpleClientset(approvalTask)
+	r := &Reconciler{approvaltaskClientSet: client}
+
+	assert.NoError(t, r.observeGeneration(context.TODO(), approvalTask))
+	assert.Empty(t, client.Actions(), "an up to date status is not written again")
+}
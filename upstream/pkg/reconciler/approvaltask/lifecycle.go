@@ -0,0 +1,140 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/callback"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+)
+
+// advanceLifecycle moves a pending approval task along its lifecycle: it
+// expires the task once its MaxPendingLifetime has passed, and otherwise
+// escalates it to its backup approvers once its EscalateAfter has passed. It
+// reports whether the task expired. Tasks that are no longer pending are left
+// alone, so that a decision ends the lifecycle in whichever phase it is.
+func (r *Reconciler) advanceLifecycle(ctx context.Context, approvalTask *v1alpha1.ApprovalTask, run *v1beta1.CustomRun) (bool, error) {
+	if approvalTask.Status.State != pendingState {
+		return false, nil
+	}
+	now := r.clock.Now()
+	if endsAt, ok := approval.LifetimeEndsAt(*approvalTask); ok && !now.Before(endsAt) {
+		lifetime := approvalTask.Spec.MaxPendingLifetime.Duration
+		message := fmt.Sprintf("Approval task %s expired: it was pending for longer than its maximum pending lifetime of %v", approvalTask.Name, lifetime)
+		return true, r.expire(ctx, approvalTask, run, lifetime, message)
+	}
+	if dueAt, ok := approval.EscalationDueAt(*approvalTask); ok && !now.Before(dueAt) && !approval.Escalated(*approvalTask) {
+		return false, r.escalate(ctx, approvalTask, now)
+	}
+	return false, nil
+}
+
+// escalate makes the backup approvers of the approval task take part in it.
+func (r *Reconciler) escalate(ctx context.Context, approvalTask *v1alpha1.ApprovalTask, now time.Time) error {
+	escalatedAt := metav1.NewTime(now)
+	approvalTask.Status.EscalatedAt = &escalatedAt
+	setConditions(approvalTask, now)
+	_, err := r.approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(approvalTask.Namespace).UpdateStatus(ctx, approvalTask, metav1.UpdateOptions{})
+	if err != nil {
+		approvalTask.Status.EscalatedAt = nil
+		return err
+	}
+	logging.FromContext(ctx).Infof("Approval task %s escalated to its backup approvers %s", approvalTask.Name, strings.Join(backupApproverNames(*approvalTask), ", "))
+	return nil
+}
+
+// expire rejects the approval task because it was pending for longer than
+// after, and fails its run with message. Expiring is final, even during the
+// reversal window of a rejection.
+func (r *Reconciler) expire(ctx context.Context, approvalTask *v1alpha1.ApprovalTask, run *v1beta1.CustomRun, after time.Duration, message string) error {
+	approvalTask.Status.State = rejectedState
+	approvalTask.Status.RejectedAt = nil
+	approvalTask.Status.ObservedGeneration = approvalTask.Generation
+	markTimedOut(approvalTask, after, r.clock.Now())
+	_, err := r.approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(approvalTask.Namespace).UpdateStatus(ctx, approvalTask, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	r.callback.Notify(ctx, callback.NewPayload(*approvalTask))
+	run.Status.MarkCustomRunFailed(v1alpha1.ApprovalTaskRunReasonFailed.String(), message)
+	r.recordDecisionDuration(ctx, approvalTask, expiredOutcome)
+	return nil
+}
+
+// setPhase sets the lifecycle phase of the approval task from its state: an
+// expired task stays Expired, a pending one is Pending or Escalated, and any
+// other is Decided.
+func setPhase(approvalTask *v1alpha1.ApprovalTask) {
+	status := &approvalTask.Status
+	switch {
+	case status.Phase == v1alpha1.ApprovalTaskPhaseExpired:
+	case status.State == "" || status.State == pendingState:
+		status.Phase = v1alpha1.ApprovalTaskPhasePending
+		if approval.Escalated(*approvalTask) {
+			status.Phase = v1alpha1.ApprovalTaskPhaseEscalated
+		}
+	default:
+		status.Phase = v1alpha1.ApprovalTaskPhaseDecided
+	}
+}
+
+// announcePhase emits an event on the run when the approval task left the
+// previous phase of its lifecycle. New tasks starting Pending are not
+// announced.
+func announcePhase(ctx context.Context, run *v1beta1.CustomRun, previous string, approvalTask *v1alpha1.ApprovalTask) {
+	phase := approvalTask.Status.Phase
+	if phase == previous || phase == "" || (previous == "" && phase == v1alpha1.ApprovalTaskPhasePending) {
+		return
+	}
+	recorder := controller.GetEventRecorder(ctx)
+	if recorder == nil {
+		return
+	}
+	switch phase {
+	case v1alpha1.ApprovalTaskPhaseEscalated:
+		recorder.Eventf(run, corev1.EventTypeNormal, phase, "Approval task %s escalated to its backup approvers %s",
+			approvalTask.Name, strings.Join(backupApproverNames(*approvalTask), ", "))
+	case v1alpha1.ApprovalTaskPhaseExpired:
+		recorder.Eventf(run, corev1.EventTypeWarning, phase, "Approval task %s expired without a decision", approvalTask.Name)
+	case v1alpha1.ApprovalTaskPhaseDecided:
+		recorder.Eventf(run, corev1.EventTypeNormal, phase, "Approval task %s was decided: %s", approvalTask.Name, approvalTask.Status.State)
+	default:
+		recorder.Eventf(run, corev1.EventTypeNormal, phase, "Approval task %s is pending again", approvalTask.Name)
+	}
+}
+
+// backupApproverNames returns the names of the backup approvers of the approval
+// task.
+func backupApproverNames(approvalTask v1alpha1.ApprovalTask) []string {
+	var names []string
+	for _, approver := range approvalTask.Spec.Approvers {
+		if approver.Backup {
+			names = append(names, approver.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+)
+
+var lifecycleStart = time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+// escalatingApprovalTask returns a pending approval task started at
+// lifecycleStart, which escalates from alice to her backup bob after an hour
+// and expires after four.
+func escalatingApprovalTask(t *testing.T) *v1alpha1.ApprovalTask {
+	t.Helper()
+	at := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			EscalateAfter:             &metav1.Duration{Duration: time.Hour},
+			MaxPendingLifetime:        &metav1.Duration{Duration: 4 * time.Hour},
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "bob", Type: "User", Input: "pending", Backup: true},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{
+			State:     "pending",
+			Phase:     v1alpha1.ApprovalTaskPhasePending,
+			StartTime: &metav1.Time{Time: lifecycleStart},
+		},
+	}
+	hash, err := approversHash(*at)
+	assert.NoError(t, err)
+	at.Annotations = map[string]string{LastAppliedHashKey: hash}
+	return at
+}
+
+// lifecycleRun returns the run of the escalating approval task, with a
+// timeout beyond its maximum pending lifetime.
+func lifecycleRun() *v1beta1.CustomRun {
+	run := approvalTaskRun()
+	run.Spec.Timeout = &metav1.Duration{Duration: 24 * time.Hour}
+	return run
+}
+
+func TestLifecycleEscalatesThenExpires(t *testing.T) {
+	client := fake.NewSimpleClientset(escalatingApprovalTask(t))
+	clock := clocktesting.NewFakePassiveClock(lifecycleStart.Add(59 * time.Minute))
+	r := &Reconciler{clock: clock, approvaltaskClientSet: client}
+	recorder := record.NewFakeRecorder(10)
+	ctx := controller.WithEventRecorder(context.TODO(), recorder)
+	run := lifecycleRun()
+	get := func() *v1alpha1.ApprovalTask {
+		at, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+		assert.NoError(t, err)
+		return at
+	}
+
+	// Just before T1 the task waits for its escalation
+	err := r.reconcile(ctx, run, &v1alpha1.ApprovalTaskRunStatus{})
+	ok, delay := controller.IsRequeueKey(err)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, delay)
+	assert.Equal(t, v1alpha1.ApprovalTaskPhasePending, get().Status.Phase)
+	assert.Nil(t, get().Status.EscalatedAt)
+	assert.Empty(t, recorder.Events)
+
+	// At T1 it escalates to bob, and waits for its expiry
+	clock.SetTime(lifecycleStart.Add(time.Hour))
+	err = r.reconcile(ctx, run, &v1alpha1.ApprovalTaskRunStatus{})
+	ok, delay = controller.IsRequeueKey(err)
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Hour, delay)
+	assert.False(t, run.IsDone())
+	at := get()
+	assert.Equal(t, v1alpha1.ApprovalTaskPhaseEscalated, at.Status.Phase)
+	if assert.NotNil(t, at.Status.EscalatedAt) {
+		assert.True(t, at.Status.EscalatedAt.Time.Equal(lifecycleStart.Add(time.Hour)))
+	}
+	if assert.Len(t, recorder.Events, 1) {
+		assert.Equal(t, "Normal Escalated Approval task deploy escalated to its backup approvers bob", <-recorder.Events)
+	}
+
+	// Escalating happens once
+	clock.SetTime(lifecycleStart.Add(2 * time.Hour))
+	err = r.reconcile(ctx, run, &v1alpha1.ApprovalTaskRunStatus{})
+	ok, delay = controller.IsRequeueKey(err)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Hour, delay)
+	assert.True(t, get().Status.EscalatedAt.Time.Equal(lifecycleStart.Add(time.Hour)))
+	assert.Empty(t, recorder.Events)
+
+	// At T2 it expires
+	clock.SetTime(lifecycleStart.Add(4 * time.Hour))
+	assert.NoError(t, r.reconcile(ctx, run, &v1alpha1.ApprovalTaskRunStatus{}))
+	assert.True(t, run.IsDone())
+	condition := run.Status.GetCondition(apis.ConditionSucceeded)
+	assert.Equal(t, v1alpha1.ApprovalTaskRunReasonFailed.String(), condition.Reason)
+	assert.Equal(t, "Approval task deploy expired: it was pending for longer than its maximum pending lifetime of 4h0m0s", condition.Message)
+	at = get()
+	assert.Equal(t, "rejected", at.Status.State)
+	assert.Equal(t, v1alpha1.ApprovalTaskPhaseExpired, at.Status.Phase)
+	assert.Equal(t, timedOutReason, at.Status.GetCondition(apis.ConditionReady).Reason)
+	if assert.Len(t, recorder.Events, 1) {
+		assert.Equal(t, "Warning Expired Approval task deploy expired without a decision", <-recorder.Events)
+	}
+}
+
+func TestLifecycleExpiresWithoutEscalatingWhenLate(t *testing.T) {
+	client := fake.NewSimpleClientset(escalatingApprovalTask(t))
+	r := &Reconciler{clock: clocktesting.NewFakePassiveClock(lifecycleStart.Add(5 * time.Hour)), approvaltaskClientSet: client}
+	run := lifecycleRun()
+
+	assert.NoError(t, r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{}))
+	assert.True(t, run.IsDone())
+	at, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, v1alpha1.ApprovalTaskPhaseExpired, at.Status.Phase)
+	assert.Nil(t, at.Status.EscalatedAt)
+}
+
+func TestLifecycleDecisionShortCircuits(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		now     time.Duration
+		approve func(*v1alpha1.ApprovalTask)
+	}{{
+		name:    "before the escalation",
+		now:     30 * time.Minute,
+		approve: func(at *v1alpha1.ApprovalTask) { at.Spec.Approvers[0].Input = "approve" },
+	}, {
+		name: "by the backup, past the maximum pending lifetime",
+		now:  5 * time.Hour,
+		approve: func(at *v1alpha1.ApprovalTask) {
+			escalatedAt := metav1.NewTime(lifecycleStart.Add(time.Hour))
+			at.Status.EscalatedAt = &escalatedAt
+			at.Status.Phase = v1alpha1.ApprovalTaskPhaseEscalated
+			at.Spec.Approvers[1].Input = "approve"
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			at := escalatingApprovalTask(t)
+			tc.approve(at)
+			client := fake.NewSimpleClientset(at)
+			r := &Reconciler{clock: clocktesting.NewFakePassiveClock(lifecycleStart.Add(tc.now)), approvaltaskClientSet: client}
+			recorder := record.NewFakeRecorder(10)
+			ctx := controller.WithEventRecorder(context.TODO(), recorder)
+			run := lifecycleRun()
+
+			assert.NoError(t, r.reconcile(ctx, run, &v1alpha1.ApprovalTaskRunStatus{}))
+			assert.True(t, run.Status.GetCondition(apis.ConditionSucceeded).IsTrue())
+			stored, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, "approved", stored.Status.State)
+			assert.Equal(t, v1alpha1.ApprovalTaskPhaseDecided, stored.Status.Phase)
+			if assert.Len(t, recorder.Events, 1) {
+				assert.Equal(t, "Normal Decided Approval task deploy was decided: approved", <-recorder.Events)
+			}
+		})
+	}
+}
+
+func TestLifecycleQuorumNeedingBackups(t *testing.T) {
+	at := escalatingApprovalTask(t)
+	at.Spec.NumberOfApprovalsRequired = 2
+	at.Spec.Approvers = append(at.Spec.Approvers, v1alpha1.ApproverDetails{Name: "carol", Type: "User", Input: "pending", Backup: true})
+	hash, err := approversHash(*at)
+	assert.NoError(t, err)
+	at.Annotations[LastAppliedHashKey] = hash
+	client := fake.NewSimpleClientset(at)
+	r := &Reconciler{clock: clocktesting.NewFakePassiveClock(lifecycleStart.Add(30 * time.Minute)), approvaltaskClientSet: client}
+	run := lifecycleRun()
+
+	// The quorum is only attainable with the backups, so the task waits for its escalation
+	err = r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{})
+	ok, delay := controller.IsRequeueKey(err)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Minute, delay)
+	assert.False(t, run.IsDone())
+	stored, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", stored.Status.State)
+}
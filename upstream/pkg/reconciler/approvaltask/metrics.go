@@ -0,0 +1,152 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/correlation"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/metrics"
+)
+
+const (
+	responseLatencyName  = "approvaltask_response_latency_seconds"
+	decisionDurationName = "approvaltask_decision_duration_seconds"
+)
+
+// roleGroupMember labels the latency of the responses of group members.
+const roleGroupMember = "group-member"
+
+// expiredOutcome labels the decision duration of approval tasks that timed out.
+const expiredOutcome = "expired"
+
+var (
+	responseLatencyM = stats.Float64(
+		responseLatencyName,
+		"The time from the creation of an ApprovalTask to each response of its approvers",
+		stats.UnitSeconds)
+
+	decisionDurationM = stats.Float64(
+		decisionDurationName,
+		"The time from the creation of an ApprovalTask to reaching a final state",
+		stats.UnitSeconds)
+
+	roleKey      = tag.MustNewKey("role")
+	outcomeKey   = tag.MustNewKey("outcome")
+	namespaceKey = tag.MustNewKey("namespace")
+
+	registerLatencyViewOnce sync.Once
+)
+
+// registerLatencyView registers the views of the response latency and
+// decision duration metrics with the metrics exporter. It is safe to call
+// more than once.
+func registerLatencyView() {
+	registerLatencyViewOnce.Do(func() {
+		// From a minute to a week
+		buckets := view.Distribution(60, 300, 900, 1800, 3600, 4*3600, 12*3600, 24*3600, 3*24*3600, 7*24*3600)
+		if err := view.Register(&view.View{
+			Description: responseLatencyM.Description(),
+			Measure:     responseLatencyM,
+			Aggregation: buckets,
+			TagKeys:     []tag.Key{roleKey},
+		}, &view.View{
+			Description: decisionDurationM.Description(),
+			Measure:     decisionDurationM,
+			Aggregation: buckets,
+			TagKeys:     []tag.Key{outcomeKey, namespaceKey},
+		}); err != nil {
+			panic(err)
+		}
+	})
+}
+
+// observedLatency is the latency of a response newly observed by the
+// controller, labelled by the role of the approver who gave it.
+type observedLatency struct {
+	role    string
+	latency time.Duration
+}
+
+// responseLatency returns how long after the creation of the approval task
+// a response observed at respondedAt came in, or nil when either time is
+// unknown.
+func responseLatency(approvalTask *v1alpha1.ApprovalTask, respondedAt *metav1.Time) *metav1.Duration {
+	if respondedAt == nil || approvalTask.CreationTimestamp.IsZero() {
+		return nil
+	}
+	latency := respondedAt.Sub(approvalTask.CreationTimestamp.Time)
+	if latency < 0 {
+		latency = 0
+	}
+	return &metav1.Duration{Duration: latency}
+}
+
+// approverRole returns the role label of an individual approver of the
+// given type, "user" or "email".
+func approverRole(approverType string) string {
+	return strings.ToLower(v1alpha1.DefaultedApproverType(approverType))
+}
+
+// recordResponseLatencies records the latency of the responses observed by a
+// status update of the approval task in the response latency histogram, with
+// the correlation ID of the task as exemplar.
+func recordResponseLatencies(ctx context.Context, approvalTask *v1alpha1.ApprovalTask, observed []observedLatency) {
+	for _, o := range observed {
+		tagged, err := tag.New(context.Background(), tag.Insert(roleKey, o.role))
+		if err != nil {
+			logging.FromContext(ctx).Warnf("Unable to tag the response latency metric: %v", err)
+			continue
+		}
+		metrics.Record(tagged, responseLatencyM.M(o.latency.Seconds()), correlation.Exemplar(correlation.Of(approvalTask)))
+	}
+}
+
+// recordDecisionDuration records how long after its creation the approval
+// task reached a final state with the given outcome: approved, rejected,
+// withdrawn or expired. It is called once, as the run of the task finishes.
+// The duration is labelled with the namespace of the task unless
+// r.metricsNamespaces is full, and the correlation ID of the task is recorded
+// as exemplar.
+func (r *Reconciler) recordDecisionDuration(ctx context.Context, approvalTask *v1alpha1.ApprovalTask, outcome string) {
+	if approvalTask.CreationTimestamp.IsZero() {
+		return
+	}
+	duration := r.clock.Now().Sub(approvalTask.CreationTimestamp.Time)
+	if duration < 0 {
+		duration = 0
+	}
+	mutators := []tag.Mutator{tag.Insert(outcomeKey, outcome)}
+	if r.metricsNamespaces == nil || r.metricsNamespaces.Admit(approvalTask.Namespace) {
+		mutators = append(mutators, tag.Insert(namespaceKey, approvalTask.Namespace))
+	}
+	tagged, err := tag.New(context.Background(), mutators...)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("Unable to tag the decision duration metric: %v", err)
+		return
+	}
+	metrics.Record(tagged, decisionDurationM.M(duration.Seconds()), correlation.Exemplar(correlation.Of(approvalTask)))
+}
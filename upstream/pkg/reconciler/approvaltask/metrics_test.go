@@ -0,0 +1,233 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/labelcap"
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats/view"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/metrics"
+)
+
+func init() {
+	metrics.InitForTesting()
+}
+
+// responseLatencyCount returns the number of latencies recorded for role.
+func responseLatencyCount(t *testing.T, role string) int64 {
+	t.Helper()
+	rows, err := view.RetrieveData(responseLatencyName)
+	assert.NoError(t, err)
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			if tag.Key == roleKey && tag.Value == role {
+				return row.Data.(*view.DistributionData).Count
+			}
+		}
+	}
+	return 0
+}
+
+func TestResponseLatency(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := &v1alpha1.ApprovalTask{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)}}
+
+	respondedAt := metav1.NewTime(created.Add(90 * time.Minute))
+	assert.Equal(t, &metav1.Duration{Duration: 90 * time.Minute}, responseLatency(at, &respondedAt))
+
+	early := metav1.NewTime(created.Add(-time.Second))
+	assert.Equal(t, &metav1.Duration{}, responseLatency(at, &early), "clock skew does not make latencies negative")
+
+	assert.Nil(t, responseLatency(at, nil))
+	assert.Nil(t, responseLatency(&v1alpha1.ApprovalTask{}, &respondedAt), "the creation time is unknown")
+}
+
+func TestUpdateApprovalStateRecordsResponseLatency(t *testing.T) {
+	registerLatencyView()
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	approvalTask := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production", CreationTimestamp: metav1.NewTime(created)},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 3,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+				{Name: "bob@example.com", Type: "Email", Input: "pending"},
+				{Name: "platform", Type: "Group", Input: "approve", Users: []v1alpha1.UserDetails{
+					{Name: "carol", Input: "approve"},
+				}},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+	client := fake.NewSimpleClientset(approvalTask)
+	clock := clocktesting.NewFakePassiveClock(created.Add(2 * time.Hour))
+	users, members := responseLatencyCount(t, "user"), responseLatencyCount(t, roleGroupMember)
+
+	at, err := updateApprovalState(context.TODO(), client, nil, clock, nil, approvalTask)
+	assert.NoError(t, err)
+	latencies := map[string]*metav1.Duration{}
+	for _, response := range at.Status.ApproversResponse {
+		latencies[response.Name] = response.ResponseLatency
+		for _, member := range response.GroupMembers {
+			latencies[member.Name] = member.ResponseLatency
+		}
+	}
+	assert.Equal(t, map[string]*metav1.Duration{
+		"alice":    {Duration: 2 * time.Hour},
+		"platform": nil,
+		"carol":    {Duration: 2 * time.Hour},
+	}, latencies)
+	assert.Equal(t, users+1, responseLatencyCount(t, "user"))
+	assert.Equal(t, members+1, responseLatencyCount(t, roleGroupMember))
+
+	// bob answers later, the latencies already observed are neither changed
+	// nor recorded again
+	emails := responseLatencyCount(t, "email")
+	clock.SetTime(created.Add(26 * time.Hour))
+	at.Spec.Approvers[1].Input = "approve"
+	at, err = updateApprovalState(context.TODO(), client, nil, clock, nil, &at)
+	assert.NoError(t, err)
+	assert.Equal(t, "approved", at.Status.State)
+	for _, response := range at.Status.ApproversResponse {
+		switch response.Name {
+		case "alice":
+			assert.Equal(t, 2*time.Hour, response.ResponseLatency.Duration)
+		case "bob@example.com":
+			assert.Equal(t, 26*time.Hour, response.ResponseLatency.Duration)
+		}
+	}
+	assert.Equal(t, users+1, responseLatencyCount(t, "user"))
+	assert.Equal(t, members+1, responseLatencyCount(t, roleGroupMember))
+	assert.Equal(t, emails+1, responseLatencyCount(t, "email"))
+}
+
+// decisionDurationCount returns the number of decision durations recorded
+// for the outcome in the namespace.
+func decisionDurationCount(t *testing.T, outcome, namespace string) int64 {
+	t.Helper()
+	rows, err := view.RetrieveData(decisionDurationName)
+	assert.NoError(t, err)
+	for _, row := range rows {
+		tags := map[string]string{}
+		for _, tag := range row.Tags {
+			tags[tag.Key.Name()] = tag.Value
+		}
+		if tags[outcomeKey.Name()] == outcome && tags[namespaceKey.Name()] == namespace {
+			return row.Data.(*view.DistributionData).Count
+		}
+	}
+	return 0
+}
+
+func TestReconcileRecordsDecisionDuration(t *testing.T) {
+	registerLatencyView()
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	approvalTask := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production", CreationTimestamp: metav1.NewTime(created)},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			Approvers:                 []v1alpha1.ApproverDetails{{Name: "alice", Type: "User", Input: "approve"}},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+	r := &Reconciler{clock: clocktesting.NewFakePassiveClock(created.Add(30 * time.Minute)), approvaltaskClientSet: fake.NewSimpleClientset(approvalTask)}
+	approved := decisionDurationCount(t, "approved", "production")
+
+	run := approvalTaskRun()
+	run.Spec.Timeout = &metav1.Duration{Duration: time.Hour}
+	assert.NoError(t, r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{}))
+	assert.True(t, run.IsSuccessful())
+	assert.Equal(t, approved+1, decisionDurationCount(t, "approved", "production"))
+
+	rows, err := view.RetrieveData(decisionDurationName)
+	assert.NoError(t, err)
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			if tag.Key == outcomeKey && tag.Value == "approved" {
+				assert.Equal(t, float64(1800), row.Data.(*view.DistributionData).Min, "measured from the creation of the task")
+			}
+		}
+	}
+}
+
+func TestReconcileRecordsExpiredDecisionDuration(t *testing.T) {
+	registerLatencyView()
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	approvalTask := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "staging", CreationTimestamp: metav1.NewTime(created)},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			Approvers:                 []v1alpha1.ApproverDetails{{Name: "alice", Type: "User", Input: "pending"}},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending", StartTime: &metav1.Time{Time: created}},
+	}
+	r := &Reconciler{clock: clocktesting.NewFakePassiveClock(created.Add(2 * time.Hour)), approvaltaskClientSet: fake.NewSimpleClientset(approvalTask)}
+	expired, rejected := decisionDurationCount(t, "expired", "staging"), decisionDurationCount(t, "rejected", "staging")
+
+	run := approvalTaskRun()
+	run.Namespace = "staging"
+	run.Spec.Timeout = &metav1.Duration{Duration: time.Hour}
+	assert.NoError(t, r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{}))
+	assert.True(t, run.IsDone())
+	assert.Equal(t, expired+1, decisionDurationCount(t, "expired", "staging"))
+	assert.Equal(t, rejected, decisionDurationCount(t, "rejected", "staging"), "timing out is not counted as a rejection")
+}
+
+func TestReconcileRecordsDecisionDurationOnceRejectionIsFinal(t *testing.T) {
+	registerLatencyView()
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	approvalTask := reversibleApprovalTask(created)
+	approvalTask.Namespace = "qa"
+	client := fake.NewSimpleClientset(approvalTask)
+	clock := clocktesting.NewFakePassiveClock(created)
+	r := &Reconciler{clock: clock, approvaltaskClientSet: client}
+	rejected := decisionDurationCount(t, "rejected", "qa")
+
+	run := approvalTaskRun()
+	run.Namespace = "qa"
+	_ = r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{})
+	assert.Equal(t, rejected, decisionDurationCount(t, "rejected", "qa"), "the rejection can still be reversed")
+
+	clock.SetTime(created.Add(time.Hour))
+	_ = r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{})
+	assert.True(t, run.IsDone())
+	assert.Equal(t, rejected+1, decisionDurationCount(t, "rejected", "qa"))
+}
+
+func TestRecordDecisionDurationLabelCap(t *testing.T) {
+	registerLatencyView()
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := &Reconciler{clock: clocktesting.NewFakePassiveClock(created.Add(time.Minute)), metricsNamespaces: labelcap.New(1)}
+	unlabelled := decisionDurationCount(t, "withdrawn", "")
+
+	for _, namespace := range []string{"metrics-cap-a", "metrics-cap-b", "metrics-cap-a"} {
+		r.recordDecisionDuration(context.TODO(), &v1alpha1.ApprovalTask{
+			ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: namespace, CreationTimestamp: metav1.NewTime(created)},
+		}, withdrawnState)
+	}
+	assert.Equal(t, int64(2), decisionDurationCount(t, "withdrawn", "metrics-cap-a"))
+	assert.Zero(t, decisionDurationCount(t, "withdrawn", "metrics-cap-b"))
+	assert.Equal(t, unlabelled+1, decisionDurationCount(t, "withdrawn", ""), "the second namespace is recorded without the label")
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/apis"
+)
+
+// reconcileMixed reconciles a task requiring two of alice, bob and carol,
+// who gave the inputs, and returns its run and the resulting state.
+func reconcileMixed(t *testing.T, resolution string, inputs ...string) (string, *apis.Condition) {
+	t.Helper()
+	approvalTask := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production", CreationTimestamp: metav1.NewTime(time.Now())},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			MixedResolution:           resolution,
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+	for i, name := range []string{"alice", "bob", "carol"} {
+		approvalTask.Spec.Approvers = append(approvalTask.Spec.Approvers, v1alpha1.ApproverDetails{Name: name, Type: "User", Input: inputs[i]})
+	}
+	client := fake.NewSimpleClientset(approvalTask)
+	r := &Reconciler{clock: clocktesting.NewFakePassiveClock(time.Now()), approvaltaskClientSet: client}
+
+	run := approvalTaskRun()
+	_ = r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{})
+	at, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	return at.Status.State, run.Status.GetCondition(apis.ConditionSucceeded)
+}
+
+func TestReconcileRejectionVetoes(t *testing.T) {
+	state, _ := reconcileMixed(t, "", "reject", "approve", "approve")
+	assert.Equal(t, "rejected", state)
+}
+
+func TestReconcileMixedResolutionFailFast(t *testing.T) {
+	state, _ := reconcileMixed(t, v1alpha1.MixedResolutionFailFast, "reject", "pending", "pending")
+	assert.Equal(t, "pending", state, "bob and carol can still approve")
+
+	state, condition := reconcileMixed(t, v1alpha1.MixedResolutionFailFast, "reject", "reject", "pending")
+	assert.Equal(t, "rejected", state)
+	assert.Equal(t, v1alpha1.ApprovalTaskRunReasonUnsatisfiable.String(), condition.Reason)
+
+	state, _ = reconcileMixed(t, v1alpha1.MixedResolutionFailFast, "reject", "approve", "approve")
+	assert.Equal(t, "approved", state)
+}
+
+func TestReconcileMixedResolutionWaitForAll(t *testing.T) {
+	state, condition := reconcileMixed(t, v1alpha1.MixedResolutionWaitForAll, "reject", "reject", "pending")
+	assert.Equal(t, "pending", state, "carol has not responded yet")
+	assert.False(t, condition.IsFalse())
+
+	state, condition = reconcileMixed(t, v1alpha1.MixedResolutionWaitForAll, "reject", "reject", "approve")
+	assert.Equal(t, "rejected", state)
+	assert.True(t, condition.IsFalse())
+
+	state, _ = reconcileMixed(t, v1alpha1.MixedResolutionWaitForAll, "reject", "approve", "approve")
+	assert.Equal(t, "approved", state)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"strconv"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"knative.dev/pkg/logging"
+)
+
+// namespaceApprovalsRequired returns the number of approvals that the
+// namespace requires by default through the
+// v1alpha1.DefaultApprovalsRequiredAnnotationKey annotation, or 0 when it
+// sets none. Namespaces that cannot be read and values that are not positive
+// integers are logged and ignored.
+func (c *Reconciler) namespaceApprovalsRequired(ctx context.Context, namespace string) int {
+	if c.namespaceLister == nil || namespace == "" {
+		return 0
+	}
+	logger := logging.FromContext(ctx)
+
+	ns, err := c.namespaceLister.Get(namespace)
+	if err != nil {
+		logger.Warnf("Unable to get namespace %s for approval defaults: %v", namespace, err)
+		return 0
+	}
+	value, ok := ns.Annotations[v1alpha1.DefaultApprovalsRequiredAnnotationKey]
+	if !ok {
+		return 0
+	}
+	required, err := strconv.Atoi(value)
+	if err != nil || required <= 0 {
+		logger.Warnf("Ignoring the %s annotation of namespace %s: '%s' is not a positive integer", v1alpha1.DefaultApprovalsRequiredAnnotationKey, namespace, value)
+		return 0
+	}
+	return required
+}
+
+// applyNamespaceApprovalsRequired sets the numberOfApprovalsRequired param of
+// a run that sets neither it nor approvalPercentage to the namespace default,
+// so that the ApprovalTask created for it inherits the default. Explicit
+// params always win. Runs whose ApprovalTask already exists are left alone:
+// the default only applies when the task is created.
+func (c *Reconciler) applyNamespaceApprovalsRequired(ctx context.Context, run *v1beta1.CustomRun) {
+	for _, param := range run.Spec.Params {
+		if param.Name == approvalsRequired || param.Name == approvalPercentage {
+			return
+		}
+	}
+	if c.approvaltaskLister != nil {
+		if _, err := c.approvaltaskLister.ApprovalTasks(run.Namespace).Get(run.Name); err == nil {
+			return
+		}
+	}
+	required := c.namespaceApprovalsRequired(ctx, run.Namespace)
+	if required == 0 {
+		return
+	}
+	run.Spec.Params = append(run.Spec.Params, v1beta1.Param{
+		Name:  approvalsRequired,
+		Value: *v1beta1.NewArrayOrString(strconv.Itoa(required)),
+	})
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	listersapprovaltask "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// defaultingNamespace returns the production namespace, annotated with the
+// given default number of approvals required unless it is empty.
+func defaultingNamespace(value string) *corev1.Namespace {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "production"}}
+	if value != "" {
+		ns.Annotations = map[string]string{v1alpha1.DefaultApprovalsRequiredAnnotationKey: value}
+	}
+	return ns
+}
+
+func namespaceLister(t *testing.T, namespaces ...*corev1.Namespace) corelisters.NamespaceLister {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, ns := range namespaces {
+		if err := indexer.Add(ns); err != nil {
+			t.Fatalf("adding Namespace: %v", err)
+		}
+	}
+	return corelisters.NewNamespaceLister(indexer)
+}
+
+func TestNamespaceApprovalsRequired(t *testing.T) {
+	for value, want := range map[string]int{
+		"":    0,
+		"2":   2,
+		"0":   0,
+		"-1":  0,
+		"two": 0,
+	} {
+		r := &Reconciler{namespaceLister: namespaceLister(t, defaultingNamespace(value))}
+		assert.Equal(t, want, r.namespaceApprovalsRequired(context.TODO(), "production"), "annotation %q", value)
+	}
+
+	r := &Reconciler{namespaceLister: namespaceLister(t)}
+	assert.Equal(t, 0, r.namespaceApprovalsRequired(context.TODO(), "production"), "missing namespaces set no default")
+}
+
+func TestRunsInheritNamespaceApprovalsRequired(t *testing.T) {
+	r := &Reconciler{namespaceLister: namespaceLister(t, defaultingNamespace("2"))}
+	run := approvalTaskRun()
+	run.Spec.Params = []v1beta1.Param{{Name: "approvers", Value: *v1beta1.NewArrayOrString("alice", "bob", "carol")}}
+
+	r.applyNamespaceApprovalsRequired(context.TODO(), run)
+	assert.NoError(t, ValidateCustomRunParameters(run))
+	at, err := createApprovalTask(context.TODO(), fake.NewSimpleClientset(), run, nil, nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, at.Spec.NumberOfApprovalsRequired)
+
+	r.applyNamespaceApprovalsRequired(context.TODO(), run)
+	assert.Len(t, run.Spec.Params, 2, "the default is added once")
+}
+
+func TestNamespaceApprovalsRequiredOnlyAtCreation(t *testing.T) {
+	run := approvalTaskRun()
+	run.Spec.Params = []v1beta1.Param{{Name: "approvers", Value: *v1beta1.NewArrayOrString("alice", "bob", "carol")}}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	assert.NoError(t, indexer.Add(&v1alpha1.ApprovalTask{ObjectMeta: metav1.ObjectMeta{Name: run.Name, Namespace: run.Namespace}}))
+	r := &Reconciler{
+		namespaceLister:    namespaceLister(t, defaultingNamespace("2")),
+		approvaltaskLister: listersapprovaltask.NewApprovalTaskLister(indexer),
+	}
+
+	r.applyNamespaceApprovalsRequired(context.TODO(), run)
+	assert.Len(t, run.Spec.Params, 1, "runs whose task exists do not read the namespace default")
+}
+
+func TestExplicitApprovalsRequiredOverrideNamespace(t *testing.T) {
+	r := &Reconciler{namespaceLister: namespaceLister(t, defaultingNamespace("2"))}
+	for name, param := range map[string]v1beta1.Param{
+		"number":     {Name: "numberOfApprovalsRequired", Value: *v1beta1.NewArrayOrString("1")},
+		"percentage": {Name: "approvalPercentage", Value: *v1beta1.NewArrayOrString("50")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			run := approvalTaskRun()
+			run.Spec.Params = []v1beta1.Param{
+				{Name: "approvers", Value: *v1beta1.NewArrayOrString("alice", "bob", "carol")},
+				param,
+			}
+			r.applyNamespaceApprovalsRequired(context.TODO(), run)
+			assert.Equal(t, v1beta1.Params{
+				{Name: "approvers", Value: *v1beta1.NewArrayOrString("alice", "bob", "carol")},
+				param,
+			}, run.Spec.Params)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
+)
+
+// repairOwnerReference makes the CustomRun being reconciled the controller of
+// its approval task when the task has none, for example because it was
+// restored from a backup along with its CustomRun, so that it is garbage
+// collected along with the CustomRun and its PipelineRun. Tasks are only
+// visited through their CustomRun, so tasks whose CustomRun is gone are not
+// repaired.
+func (r *Reconciler) repairOwnerReference(ctx context.Context, run *v1beta1.CustomRun, approvalTask *v1alpha1.ApprovalTask) error {
+	// Tasks embedded in their CustomRun are not stored
+	if approvalTask.Name == "" || approvalTask.DeletionTimestamp != nil || metav1.GetControllerOf(approvalTask) != nil {
+		return nil
+	}
+
+	status := approvalTask.Status
+	repaired := approvalTask.DeepCopy()
+	repaired.OwnerReferences = append(repaired.OwnerReferences, *metav1.NewControllerRef(run, gvk))
+	at, err := r.approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(approvalTask.Namespace).Update(ctx, repaired, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	*approvalTask = *at
+	approvalTask.Status = status
+	logging.FromContext(ctx).Infof("Approval task %s had no owner, it is now owned by CustomRun %s", approvalTask.Name, run.Name)
+	return nil
+}
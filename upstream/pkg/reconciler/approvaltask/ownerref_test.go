@@ -0,0 +1,71 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func orphanedApprovalTask() *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "deploy",
+			Namespace: "production",
+			Labels:    map[string]string{CustomRunLabelKey: "deploy"},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: pendingState},
+	}
+}
+
+func TestRepairOwnerReference(t *testing.T) {
+	run := &v1beta1.CustomRun{ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production", UID: "run-uid"}}
+	at := orphanedApprovalTask()
+	client := fake.NewSimpleClientset(at)
+	r := &Reconciler{approvaltaskClientSet: client}
+
+	assert.NoError(t, r.repairOwnerReference(context.TODO(), run, at))
+	stored, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	owner := metav1.GetControllerOf(stored)
+	if assert.NotNil(t, owner) {
+		assert.Equal(t, "CustomRun", owner.Kind)
+		assert.Equal(t, "deploy", owner.Name)
+		assert.Equal(t, "run-uid", string(owner.UID))
+	}
+	assert.Equal(t, stored.OwnerReferences, at.OwnerReferences, "the reconciled task is the repaired one")
+	assert.Equal(t, pendingState, at.Status.State)
+
+	// Owned tasks are left alone
+	client.ClearActions()
+	assert.NoError(t, r.repairOwnerReference(context.TODO(), run, at))
+	assert.Empty(t, client.Actions())
+}
+
+func TestRepairOwnerReferenceOfEmbeddedTask(t *testing.T) {
+	client := fake.NewSimpleClientset(orphanedApprovalTask())
+	r := &Reconciler{approvaltaskClientSet: client}
+	run := &v1beta1.CustomRun{ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"}}
+	assert.NoError(t, r.repairOwnerReference(context.TODO(), run, &v1alpha1.ApprovalTask{}))
+	assert.Empty(t, client.Actions())
+}
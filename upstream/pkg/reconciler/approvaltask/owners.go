@@ -0,0 +1,121 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultOwnersKey is the key of the OWNERS file in the ConfigMap named by
+// the owners param when the param does not name one.
+const DefaultOwnersKey = "OWNERS"
+
+// owners holds the sections of an OWNERS file approvers are taken from.
+// Other sections, such as options or emeritus_approvers, are ignored.
+type owners struct {
+	Approvers []string `json:"approvers,omitempty"`
+	Reviewers []string `json:"reviewers,omitempty"`
+}
+
+// parseOwners returns the approvers and then the reviewers of an OWNERS
+// file, without duplicates.
+func parseOwners(data []byte) ([]string, error) {
+	var o owners
+	if err := yaml.Unmarshal(data, &o); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range append(o.Approvers, o.Reviewers...) {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no approvers or reviewers listed")
+	}
+	return names, nil
+}
+
+// expandOwners adds the approvers and reviewers of the OWNERS file named by
+// the owners param of the run, "<configmap>" or "<configmap>/<key>", to its
+// approvers param. The expansion only lives for this reconcile: the spec of
+// the CustomRun is never written back. A missing ConfigMap lists no owners,
+// while a missing key or a malformed OWNERS file is reported as a permanent
+// error.
+func (c *Reconciler) expandOwners(ctx context.Context, run *v1beta1.CustomRun) error {
+	var ref string
+	for _, param := range run.Spec.Params {
+		if param.Name == ownersFile {
+			ref = param.Value.StringVal
+		}
+	}
+	if ref == "" {
+		return nil
+	}
+
+	name, key, found := strings.Cut(ref, "/")
+	if !found {
+		key = DefaultOwnersKey
+	}
+	cm, err := c.configMapLister.ConfigMaps(run.Namespace).Get(name)
+	if errors.IsNotFound(err) {
+		logging.FromContext(ctx).Infof("OWNERS ConfigMap %s/%s not found, adding no owners", run.Namespace, name)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	data, ok := cm.Data[key]
+	if !ok {
+		return controller.NewPermanentError(fmt.Errorf("invalid owners parameter: ConfigMap '%s' has no key '%s'", name, key))
+	}
+	names, err := parseOwners([]byte(data))
+	if err != nil {
+		return controller.NewPermanentError(fmt.Errorf("invalid owners parameter: ConfigMap '%s' key '%s': %v", name, key, err))
+	}
+
+	appendApprovers(run, names)
+	return nil
+}
+
+// appendApprovers adds names to the approvers param of the run, creating it
+// when the run has none.
+func appendApprovers(run *v1beta1.CustomRun, names []string) {
+	for i, param := range run.Spec.Params {
+		if param.Name == allApprovers {
+			run.Spec.Params[i].Value.Type = v1beta1.ParamTypeArray
+			run.Spec.Params[i].Value.ArrayVal = append(param.Value.ArrayVal, names...)
+			return
+		}
+	}
+	run.Spec.Params = append(run.Spec.Params, v1beta1.Param{
+		Name:  allApprovers,
+		Value: v1beta1.ParamValue{Type: v1beta1.ParamTypeArray, ArrayVal: names},
+	})
+}
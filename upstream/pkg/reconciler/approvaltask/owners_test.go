@@ -0,0 +1,149 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/pkg/controller"
+)
+
+const sampleOwners = `# See the OWNERS docs at https://go.k8s.io/owners
+options:
+  no_parent_owners: true
+approvers:
+  - alice
+  - group:release-managers
+reviewers:
+  - bob
+  - alice
+emeritus_approvers:
+  - mallory
+`
+
+func ownersConfigMap(data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "repo-owners", Namespace: "production"},
+		Data:       data,
+	}
+}
+
+func configMapLister(t *testing.T, cms ...*corev1.ConfigMap) corelisters.ConfigMapLister {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, cm := range cms {
+		if err := indexer.Add(cm); err != nil {
+			t.Fatalf("adding ConfigMap: %v", err)
+		}
+	}
+	return corelisters.NewConfigMapLister(indexer)
+}
+
+func TestParseOwners(t *testing.T) {
+	names, err := parseOwners([]byte(sampleOwners))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", "group:release-managers", "bob"}, names)
+}
+
+func TestParseOwnersErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{{
+		name:    "malformed yaml",
+		content: "approvers: [alice",
+		want:    "did not find expected ',' or ']'",
+	}, {
+		name:    "approvers is not a list",
+		content: "approvers: alice",
+		want:    "cannot unmarshal string",
+	}, {
+		name:    "no approvers",
+		content: "emeritus_approvers:\n  - mallory\n",
+		want:    "no approvers or reviewers listed",
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseOwners([]byte(tc.content))
+			assert.ErrorContains(t, err, tc.want)
+		})
+	}
+}
+
+func TestExpandOwners(t *testing.T) {
+	r := &Reconciler{configMapLister: configMapLister(t, ownersConfigMap(map[string]string{
+		DefaultOwnersKey: sampleOwners,
+		"deploy/OWNERS":  "approvers:\n  - carol\n",
+	}))}
+
+	run := approvalTaskRun()
+	run.Spec.Params = []v1beta1.Param{
+		{Name: "approvers", Value: *v1beta1.NewArrayOrString("dave", "erin")},
+		{Name: "owners", Value: *v1beta1.NewArrayOrString("repo-owners")},
+	}
+	assert.NoError(t, r.expandOwners(context.TODO(), run))
+	assert.Equal(t, []string{"dave", "erin", "alice", "group:release-managers", "bob"}, run.Spec.Params[0].Value.ArrayVal)
+
+	// The OWNERS file alone provides the approvers, from the named key
+	run.Spec.Params = []v1beta1.Param{{Name: "owners", Value: *v1beta1.NewArrayOrString("repo-owners/deploy/OWNERS")}}
+	assert.NoError(t, r.expandOwners(context.TODO(), run))
+	assert.Equal(t, v1beta1.Param{Name: "approvers", Value: v1beta1.ParamValue{Type: v1beta1.ParamTypeArray, ArrayVal: []string{"carol"}}}, run.Spec.Params[1])
+	assert.NoError(t, ValidateCustomRunParameters(run))
+
+	run.Spec.Params = nil
+	assert.NoError(t, r.expandOwners(context.TODO(), run), "runs without owners are left alone")
+	assert.Empty(t, run.Spec.Params)
+
+	// A missing ConfigMap lists no owners, leaving the approvers to validation
+	run.Spec.Params = []v1beta1.Param{
+		{Name: "approvers", Value: *v1beta1.NewArrayOrString("dave", "erin")},
+		{Name: "owners", Value: *v1beta1.NewArrayOrString("missing")},
+	}
+	assert.NoError(t, r.expandOwners(context.TODO(), run))
+	assert.Equal(t, []string{"dave", "erin"}, run.Spec.Params[0].Value.ArrayVal)
+}
+
+func TestExpandOwnersErrors(t *testing.T) {
+	r := &Reconciler{configMapLister: configMapLister(t, ownersConfigMap(map[string]string{DefaultOwnersKey: "approvers: alice"}))}
+	tests := []struct {
+		ref  string
+		want string
+	}{{
+		ref:  "repo-owners/OWNERS_ALIASES",
+		want: "invalid owners parameter: ConfigMap 'repo-owners' has no key 'OWNERS_ALIASES'",
+	}, {
+		ref:  "repo-owners",
+		want: "invalid owners parameter: ConfigMap 'repo-owners' key 'OWNERS': ",
+	}}
+	for _, tc := range tests {
+		t.Run(tc.ref, func(t *testing.T) {
+			run := approvalTaskRun()
+			run.Spec.Params = []v1beta1.Param{{Name: "owners", Value: *v1beta1.NewArrayOrString(tc.ref)}}
+			err := r.expandOwners(context.TODO(), run)
+			assert.ErrorContains(t, err, tc.want)
+			assert.True(t, controller.IsPermanentError(err), "the run cannot succeed until its OWNERS file is fixed")
+		})
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline"
+	pipelinev1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/controller"
+)
+
+// pipelineRunPendingRequeue is how long a run waits for its PipelineRun to
+// show up in the informer cache before its approvers are resolved again.
+const pipelineRunPendingRequeue = 5 * time.Second
+
+// expandApprovers adds the approvers listed in an OWNERS file and by the
+// owning PipelineRun to the approvers param of the run.
+func (c *Reconciler) expandApprovers(ctx context.Context, run *v1beta1.CustomRun) error {
+	if err := c.expandOwners(ctx, run); err != nil {
+		return err
+	}
+	return c.expandPipelineRunApprovers(run)
+}
+
+// expandPipelineRunApprovers adds the approvers listed by the PipelineRun
+// owning the run to its approvers param. The approversFromPipelineRun param
+// names the param of the PipelineRun holding them, as "<name>" or
+// "params.<name>". Like the OWNERS file, the expansion only lives for this
+// reconcile and the spec of the CustomRun is never written back. The run is
+// requeued while its PipelineRun is not in the informer cache yet, and fails
+// when the controller does not watch PipelineRuns.
+func (c *Reconciler) expandPipelineRunApprovers(run *v1beta1.CustomRun) error {
+	var ref string
+	for _, param := range run.Spec.Params {
+		if param.Name == pipelineRunApprovers {
+			ref = param.Value.StringVal
+		}
+	}
+	if ref == "" {
+		return nil
+	}
+	if err := validatePipelineRunApprovers(ref); err != nil {
+		return controller.NewPermanentError(err)
+	}
+	if c.pipelineRunLister == nil {
+		return controller.NewPermanentError(fmt.Errorf("invalid approversFromPipelineRun parameter: approvers from PipelineRuns are not enabled on this controller"))
+	}
+
+	owner := owningPipelineRun(run)
+	if owner == "" {
+		return controller.NewPermanentError(fmt.Errorf("invalid approversFromPipelineRun parameter: the CustomRun is not part of a PipelineRun"))
+	}
+	pr, err := c.pipelineRunLister.PipelineRuns(run.Namespace).Get(owner)
+	if errors.IsNotFound(err) {
+		return controller.NewRequeueAfter(pipelineRunPendingRequeue)
+	} else if err != nil {
+		return err
+	}
+
+	value, err := pipelineRunValue(pr, ref)
+	if err != nil {
+		return controller.NewPermanentError(fmt.Errorf("invalid approversFromPipelineRun parameter: %v", err))
+	}
+	names := splitApprovers(value)
+	if len(names) == 0 {
+		return controller.NewPermanentError(fmt.Errorf("invalid approversFromPipelineRun parameter: %s of PipelineRun '%s' lists no approvers", ref, owner))
+	}
+	appendApprovers(run, names)
+	return nil
+}
+
+// owningPipelineRun returns the name of the PipelineRun the run is part of,
+// from its controller reference or else the label Tekton sets on it, or an
+// empty string when it is not part of one.
+func owningPipelineRun(run *v1beta1.CustomRun) string {
+	if ref := metav1.GetControllerOf(run); ref != nil && ref.Kind == "PipelineRun" {
+		return ref.Name
+	}
+	return run.Labels[pipeline.PipelineRunLabelKey]
+}
+
+// pipelineRunValue returns the param of pr named by ref.
+func pipelineRunValue(pr *pipelinev1.PipelineRun, ref string) (pipelinev1.ParamValue, error) {
+	name := strings.TrimPrefix(ref, "params.")
+	for _, param := range pr.Spec.Params {
+		if param.Name == name {
+			return param.Value, nil
+		}
+	}
+	return pipelinev1.ParamValue{}, fmt.Errorf("PipelineRun '%s' has no param '%s'", pr.Name, name)
+}
+
+// splitApprovers returns the approvers listed by an array value, or by a
+// comma separated string value, without blanks or duplicates. Object values
+// list nobody.
+func splitApprovers(value pipelinev1.ParamValue) []string {
+	entries := value.ArrayVal
+	if value.Type == pipelinev1.ParamTypeString {
+		entries = strings.Split(value.StringVal, ",")
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range entries {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	pipelinev1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	pipelinelisters "github.com/tektoncd/pipeline/pkg/client/listers/pipeline/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/ptr"
+)
+
+func pipelineRunLister(t *testing.T, prs ...*pipelinev1.PipelineRun) pipelinelisters.PipelineRunLister {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, pr := range prs {
+		if err := indexer.Add(pr); err != nil {
+			t.Fatalf("adding PipelineRun: %v", err)
+		}
+	}
+	return pipelinelisters.NewPipelineRunLister(indexer)
+}
+
+func releasePipelineRun() *pipelinev1.PipelineRun {
+	pr := &pipelinev1.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "release-42", Namespace: "production"},
+		Spec: pipelinev1.PipelineRunSpec{Params: pipelinev1.Params{
+			{Name: "reviewers", Value: *pipelinev1.NewStructuredValues("alice", "group:release-managers")},
+			{Name: "owner", Value: *pipelinev1.NewStructuredValues("carol, dave,carol")},
+		}},
+	}
+	return pr
+}
+
+// pipelineRunTaskRun returns a run of the PipelineRun release-42 taking its
+// approvers from ref.
+func pipelineRunTaskRun(ref string) *v1beta1.CustomRun {
+	run := approvalTaskRun()
+	run.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: "tekton.dev/v1",
+		Kind:       "PipelineRun",
+		Name:       "release-42",
+		Controller: ptr.Bool(true),
+	}}
+	run.Spec.Params = []v1beta1.Param{{Name: "approversFromPipelineRun", Value: *v1beta1.NewArrayOrString(ref)}}
+	return run
+}
+
+func TestExpandPipelineRunApprovers(t *testing.T) {
+	r := &Reconciler{pipelineRunLister: pipelineRunLister(t, releasePipelineRun())}
+
+	run := pipelineRunTaskRun("reviewers")
+	run.Spec.Params = append(run.Spec.Params, v1beta1.Param{Name: "approvers", Value: *v1beta1.NewArrayOrString("bob", "frank")})
+	assert.NoError(t, r.expandPipelineRunApprovers(run))
+	assert.Equal(t, []string{"bob", "frank", "alice", "group:release-managers"}, run.Spec.Params[1].Value.ArrayVal)
+	assert.NoError(t, ValidateCustomRunParameters(run))
+
+	// The PipelineRun alone provides the approvers
+	run = pipelineRunTaskRun("params.owner")
+	assert.NoError(t, r.expandPipelineRunApprovers(run))
+	assert.Equal(t, v1beta1.Param{Name: "approvers", Value: v1beta1.ParamValue{Type: v1beta1.ParamTypeArray, ArrayVal: []string{"carol", "dave"}}}, run.Spec.Params[1])
+
+	// Tekton labels the runs of a PipelineRun even without an owner reference
+	run = pipelineRunTaskRun("reviewers")
+	run.OwnerReferences = nil
+	run.Labels = map[string]string{"tekton.dev/pipelineRun": "release-42"}
+	assert.NoError(t, r.expandPipelineRunApprovers(run))
+	assert.Equal(t, []string{"alice", "group:release-managers"}, run.Spec.Params[1].Value.ArrayVal)
+
+	run = approvalTaskRun()
+	assert.NoError(t, r.expandPipelineRunApprovers(run), "runs without the param are left alone")
+	assert.Empty(t, run.Spec.Params)
+}
+
+func TestExpandPipelineRunApproversOwnerNotSynced(t *testing.T) {
+	r := &Reconciler{pipelineRunLister: pipelineRunLister(t)}
+	err := r.expandPipelineRunApprovers(pipelineRunTaskRun("reviewers"))
+	ok, delay := controller.IsRequeueKey(err)
+	assert.True(t, ok, "the run waits for its PipelineRun to reach the cache")
+	assert.Equal(t, pipelineRunPendingRequeue, delay)
+	assert.False(t, controller.IsPermanentError(err))
+}
+
+func TestExpandPipelineRunApproversErrors(t *testing.T) {
+	empty := releasePipelineRun()
+	empty.Spec.Params = pipelinev1.Params{{Name: "reviewers", Value: *pipelinev1.NewStructuredValues(" , ")}}
+	r := &Reconciler{pipelineRunLister: pipelineRunLister(t, releasePipelineRun())}
+
+	tests := []struct {
+		name string
+		run  *v1beta1.CustomRun
+		r    *Reconciler
+		want string
+	}{{
+		name: "no param",
+		run:  pipelineRunTaskRun("approvers"),
+		want: "invalid approversFromPipelineRun parameter: PipelineRun 'release-42' has no param 'approvers'",
+	}, {
+		name: "result",
+		run:  pipelineRunTaskRun("results.approvers"),
+		want: "invalid approversFromPipelineRun parameter: 'results.approvers' names a result of the PipelineRun, which is only set once it completes; pass the task result as the approvers param instead",
+	}, {
+		name: "PipelineRuns not watched",
+		run:  pipelineRunTaskRun("reviewers"),
+		r:    &Reconciler{},
+		want: "invalid approversFromPipelineRun parameter: approvers from PipelineRuns are not enabled on this controller",
+	}, {
+		name: "no approvers",
+		run:  pipelineRunTaskRun("reviewers"),
+		r:    &Reconciler{pipelineRunLister: pipelineRunLister(t, empty)},
+		want: "invalid approversFromPipelineRun parameter: reviewers of PipelineRun 'release-42' lists no approvers",
+	}, {
+		name: "not part of a PipelineRun",
+		run: func() *v1beta1.CustomRun {
+			run := pipelineRunTaskRun("reviewers")
+			run.OwnerReferences = nil
+			return run
+		}(),
+		want: "invalid approversFromPipelineRun parameter: the CustomRun is not part of a PipelineRun",
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reconciler := r
+			if tc.r != nil {
+				reconciler = tc.r
+			}
+			err := reconciler.expandPipelineRunApprovers(tc.run)
+			assert.EqualError(t, err, tc.want)
+			assert.True(t, controller.IsPermanentError(err), "the run cannot succeed until its PipelineRun is fixed")
+		})
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	listersapprovaltask "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/logging"
+)
+
+// clusterPolicies returns the cluster approval policies known to the
+// controller. Listing errors are logged and treated as no policy.
+func (r *Reconciler) clusterPolicies(ctx context.Context) []*v1alpha1.ClusterApprovalPolicy {
+	if r.policyLister == nil {
+		return nil
+	}
+	policies, err := r.policyLister.List(labels.Everything())
+	if err != nil {
+		logging.FromContext(ctx).Errorf("Error listing cluster approval policies: %v", err)
+		return nil
+	}
+	return policies
+}
+
+// applyPolicies records in the status of a pending approval task the
+// requirements of the cluster approval policies that currently select it, so
+// that the quorum is evaluated against them (see approval.QuorumReachedAt).
+// The status is only updated when the requirements changed.
+func (r *Reconciler) applyPolicies(ctx context.Context, approvalTask *v1alpha1.ApprovalTask) error {
+	if approvalTask.Status.State != "" && approvalTask.Status.State != pendingState {
+		return nil
+	}
+	requirements := approval.PolicyRequirementsFor(*approvalTask, r.clusterPolicies(ctx))
+	if equality.Semantic.DeepEqual(requirements, approvalTask.Status.Policy) {
+		return nil
+	}
+
+	approvalTask.Status.Policy = requirements
+	approvalTask.Status.ApprovalsRequired = approval.RequiredApprovalsAt(*approvalTask, r.clock.Now())
+	setConditions(approvalTask, r.clock.Now())
+	at, err := r.approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(approvalTask.Namespace).UpdateStatus(ctx, approvalTask, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	*approvalTask = *at
+	if requirements == nil {
+		logging.FromContext(ctx).Infof("Approval task %s is no longer selected by any cluster approval policy", approvalTask.Name)
+	} else {
+		logging.FromContext(ctx).Infof("Approval task %s follows the cluster approval policies %v", approvalTask.Name, requirements.Policies)
+	}
+	return nil
+}
+
+// enqueuePendingRuns enqueues the CustomRun of every pending approval task,
+// for example after a cluster approval policy changed.
+func enqueuePendingRuns(ctx context.Context, approvalTasks listersapprovaltask.ApprovalTaskLister, enqueue func(types.NamespacedName)) {
+	keys, err := pendingRuns(approvalTasks)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("Error listing ApprovalTasks to re-evaluate: %v", err)
+		return
+	}
+	for _, key := range keys {
+		enqueue(key)
+	}
+}
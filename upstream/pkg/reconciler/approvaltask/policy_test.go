@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	listersapprovaltask "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func policyLister(t *testing.T, policies ...*v1alpha1.ClusterApprovalPolicy) listersapprovaltask.ClusterApprovalPolicyLister {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, policy := range policies {
+		assert.NoError(t, indexer.Add(policy))
+	}
+	return listersapprovaltask.NewClusterApprovalPolicyLister(indexer)
+}
+
+func productionPolicy() *v1alpha1.ClusterApprovalPolicy {
+	return &v1alpha1.ClusterApprovalPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "production-minimum"},
+		Spec: v1alpha1.ClusterApprovalPolicySpec{
+			Namespaces:           []string{"production"},
+			MinApprovalsRequired: 2,
+			RequiredGroups:       []string{"security"},
+		},
+	}
+}
+
+func TestCreateApprovalTaskFollowsClusterPolicies(t *testing.T) {
+	run := approvalTaskRun()
+	run.Spec.Params = []v1beta1.Param{
+		{Name: "approvers", Value: *v1beta1.NewArrayOrString("alice", "bob")},
+		{Name: "numberOfApprovalsRequired", Value: *v1beta1.NewArrayOrString("1")},
+	}
+	r := &Reconciler{policyLister: policyLister(t, productionPolicy())}
+
+	at, err := createApprovalTask(context.TODO(), fake.NewSimpleClientset(), run, nil, r.clusterPolicies(context.TODO()), false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, at.Spec.NumberOfApprovalsRequired)
+	assert.Equal(t, []v1alpha1.ApproverDetails{
+		{Name: "alice", Type: "User", Input: "pending"},
+		{Name: "bob", Type: "User", Input: "pending"},
+		{Name: "security", Type: "Group", Input: "pending"},
+	}, at.Spec.Approvers)
+	assert.Equal(t, []string{"alice", "bob", "security"}, at.Status.Approvers)
+	assert.Equal(t, 2, at.Status.ApprovalsRequired)
+	assert.Equal(t, &v1alpha1.PolicyRequirements{
+		Policies:             []string{"production-minimum"},
+		MinApprovalsRequired: 2,
+		RequiredGroups:       []string{"security"},
+	}, at.Status.Policy)
+}
+
+func TestReconcileHoldsPendingTasksToClusterPolicies(t *testing.T) {
+	creation := metav1.NewTime(time.Now())
+	approvalTask := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production", CreationTimestamp: creation},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+				{Name: "security", Type: "Group", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+	client := fake.NewSimpleClientset(approvalTask)
+	policy := productionPolicy()
+	policy.Spec.MinApprovalsRequired = 0
+	r := &Reconciler{
+		clock:                 clocktesting.NewFakePassiveClock(time.Now()),
+		approvaltaskClientSet: client,
+		policyLister:          policyLister(t, policy),
+	}
+
+	run := approvalTaskRun()
+	_ = r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{})
+	assert.False(t, run.IsDone(), "alice's approval reaches the quorum of the task but the policy requires the security group too")
+	at, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", at.Status.State)
+	assert.Equal(t, []string{"production-minimum"}, at.Status.Policy.Policies)
+
+	// Once the policy is gone, the task is held to its own spec again
+	r.policyLister = policyLister(t)
+	assert.NoError(t, r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{}))
+	assert.True(t, run.IsDone())
+	at, err = client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "approved", at.Status.State)
+	assert.Nil(t, at.Status.Policy)
+}
+
+func TestApplyPoliciesRaisesRequiredApprovals(t *testing.T) {
+	approvalTask := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec:       v1alpha1.ApprovalTaskSpec{NumberOfApprovalsRequired: 1},
+		Status:     v1alpha1.ApprovalTaskStatus{State: "pending", ApprovalsRequired: 1},
+	}
+	client := fake.NewSimpleClientset(approvalTask)
+	policy := productionPolicy()
+	policy.Spec.MinApprovalsRequired = 3
+	r := &Reconciler{clock: clocktesting.NewFakePassiveClock(time.Now()), approvaltaskClientSet: client, policyLister: policyLister(t, policy)}
+
+	assert.NoError(t, r.applyPolicies(context.TODO(), approvalTask))
+	assert.Equal(t, 3, approvalTask.Status.ApprovalsRequired)
+
+	// Final tasks are left alone
+	approved := approvalTask.DeepCopy()
+	approved.Status.State = "approved"
+	approved.Status.Policy = nil
+	assert.NoError(t, r.applyPolicies(context.TODO(), approved))
+	assert.Nil(t, approved.Status.Policy)
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask"
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	listersapprovaltask "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	"golang.org/x/time/rate"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"knative.dev/pkg/logging"
+)
+
+// RecomputePath is the path of the admin endpoint that re-evaluates every
+// pending ApprovalTask.
+const RecomputePath = "/recompute"
+
+// DefaultRecomputeQPS is how many pending ApprovalTasks a recompute enqueues
+// per second unless configured otherwise.
+const DefaultRecomputeQPS = 10
+
+// RecomputeResult is the response of the recompute endpoint.
+type RecomputeResult struct {
+	// Pending is the number of pending ApprovalTasks being enqueued.
+	Pending int `json:"pending"`
+}
+
+// recomputeHandler enqueues the CustomRun of every pending ApprovalTask on
+// POST, so that their state is recomputed after a configuration change. Only
+// callers allowed to update ApprovalTasks in every namespace may start one.
+// The enqueueing is rate limited and happens in the background; only one
+// recompute runs at a time.
+type recomputeHandler struct {
+	ctx           context.Context
+	client        kubernetes.Interface
+	approvalTasks listersapprovaltask.ApprovalTaskLister
+	enqueue       func(types.NamespacedName)
+	limiter       *rate.Limiter
+	running       atomic.Bool
+}
+
+func newRecomputeHandler(ctx context.Context, client kubernetes.Interface, approvalTasks listersapprovaltask.ApprovalTaskLister, enqueue func(types.NamespacedName), qps float64) *recomputeHandler {
+	if qps <= 0 {
+		qps = DefaultRecomputeQPS
+	}
+	return &recomputeHandler{
+		ctx:           ctx,
+		client:        client,
+		approvalTasks: approvalTasks,
+		enqueue:       enqueue,
+		limiter:       rate.NewLimiter(rate.Limit(qps), 1),
+	}
+}
+
+func (h *recomputeHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	if code, err := h.authorize(req); err != nil {
+		http.Error(w, err.Error(), code)
+		return
+	}
+	if !h.running.CompareAndSwap(false, true) {
+		http.Error(w, "a recompute is already in progress", http.StatusConflict)
+		return
+	}
+
+	keys, err := pendingRuns(h.approvalTasks)
+	if err != nil {
+		h.running.Store(false)
+		logging.FromContext(h.ctx).Errorf("Error listing ApprovalTasks to recompute: %v", err)
+		http.Error(w, "failed to list the approval tasks", http.StatusInternalServerError)
+		return
+	}
+
+	go func() {
+		defer h.running.Store(false)
+		h.enqueueAll(h.ctx, keys)
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(RecomputeResult{Pending: len(keys)}); err != nil {
+		logging.FromContext(h.ctx).Errorf("Error writing recompute response: %v", err)
+	}
+}
+
+// authorize checks that the bearer token of the request belongs to a user
+// allowed to update ApprovalTasks in every namespace, since a recompute
+// re-evaluates all of them. It returns the status code to answer with
+// otherwise.
+func (h *recomputeHandler) authorize(req *http.Request) (int, error) {
+	header := req.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return http.StatusUnauthorized, errors.New("a bearer token is required")
+	}
+	review, err := h.client.AuthenticationV1().TokenReviews().Create(req.Context(), &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: strings.TrimPrefix(header, "Bearer ")},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		logging.FromContext(h.ctx).Errorf("Error reviewing the bearer token of a recompute: %v", err)
+		return http.StatusInternalServerError, errors.New("failed to review the bearer token")
+	}
+	if !review.Status.Authenticated {
+		return http.StatusUnauthorized, errors.New("the bearer token is not valid")
+	}
+
+	user := review.Status.User
+	extra := map[string]authorizationv1.ExtraValue{}
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	access, err := h.client.AuthorizationV1().SubjectAccessReviews().Create(req.Context(), &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:     "update",
+				Group:    approvaltask.GroupName,
+				Resource: "approvaltasks",
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		logging.FromContext(h.ctx).Errorf("Error reviewing access of %s to recompute: %v", user.Username, err)
+		return http.StatusInternalServerError, errors.New("failed to review access to the approval tasks")
+	}
+	if !access.Status.Allowed {
+		return http.StatusForbidden, errors.New("not allowed to update approval tasks in every namespace")
+	}
+	return 0, nil
+}
+
+// pendingRuns returns the keys of the CustomRuns of the ApprovalTasks that
+// have not reached a final state.
+func pendingRuns(approvalTasks listersapprovaltask.ApprovalTaskLister) ([]types.NamespacedName, error) {
+	tasks, err := approvalTasks.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var keys []types.NamespacedName
+	for _, at := range tasks {
+		if at.Status.State != "" && at.Status.State != pendingState {
+			continue
+		}
+		keys = append(keys, types.NamespacedName{Namespace: at.Namespace, Name: customRunName(at)})
+	}
+	return keys, nil
+}
+
+// enqueueAll enqueues the keys no faster than the limiter allows.
+func (h *recomputeHandler) enqueueAll(ctx context.Context, keys []types.NamespacedName) {
+	logger := logging.FromContext(ctx)
+	for _, key := range keys {
+		if err := h.limiter.Wait(ctx); err != nil {
+			logger.Warnf("Recompute stopped before enqueueing every pending ApprovalTask: %v", err)
+			return
+		}
+		h.enqueue(key)
+	}
+	logger.Infof("Enqueued %d pending ApprovalTasks for recompute", len(keys))
+}
+
+// customRunName returns the name of the CustomRun the approval task belongs
+// to. The task is named after it as well.
+func customRunName(at *v1alpha1.ApprovalTask) string {
+	if name, ok := at.Labels[CustomRunLabelKey]; ok && name != "" {
+		return name
+	}
+	return at.Name
+}
+
+// serveAdmin serves the admin endpoints over TLS on address until ctx is
+// done, with the certificate and key read from certFile and keyFile.
+func serveAdmin(ctx context.Context, address, certFile, keyFile string, handler http.Handler) {
+	logger := logging.FromContext(ctx)
+	server := &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Errorf("Error shutting down the admin server: %v", err)
+		}
+	}()
+
+	logger.Infof("Serving the admin endpoints on %s", address)
+	if err := server.ListenAndServeTLS(certFile, keyFile); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Errorf("Admin server failed: %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	listersapprovaltask "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+// recordingEnqueue collects the keys a recompute enqueues.
+type recordingEnqueue struct {
+	mu   sync.Mutex
+	keys []types.NamespacedName
+}
+
+func (e *recordingEnqueue) enqueue(key types.NamespacedName) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.keys = append(e.keys, key)
+}
+
+func (e *recordingEnqueue) enqueued() []types.NamespacedName {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]types.NamespacedName{}, e.keys...)
+}
+
+// newRecomputeTestHandler returns a handler whose token reviews authenticate
+// every token but "invalid" as the user of the same name, and whose access
+// reviews only let admin update ApprovalTasks.
+func newRecomputeTestHandler(t *testing.T, qps float64, tasks ...*v1alpha1.ApprovalTask) (*recomputeHandler, *recordingEnqueue) {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, at := range tasks {
+		assert.NoError(t, indexer.Add(at))
+	}
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action ktesting.Action) (bool, runtime.Object, error) {
+		review := action.(ktesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if review.Spec.Token != "invalid" {
+			review.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: review.Spec.Token}}
+		}
+		return true, review, nil
+	})
+	client.PrependReactor("create", "subjectaccessreviews", func(action ktesting.Action) (bool, runtime.Object, error) {
+		review := action.(ktesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		review.Status.Allowed = review.Spec.User == "admin" && attributes.Verb == "update" && attributes.Resource == "approvaltasks" && attributes.Namespace == ""
+		return true, review, nil
+	})
+	recorder := &recordingEnqueue{}
+	return newRecomputeHandler(context.Background(), client, listersapprovaltask.NewApprovalTaskLister(indexer), recorder.enqueue, qps), recorder
+}
+
+// recomputeRequest returns a recompute request carrying token.
+func recomputeRequest(method, token string) *http.Request {
+	req := httptest.NewRequest(method, RecomputePath, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func recomputeTask(namespace, name, state string) *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{CustomRunLabelKey: name}},
+		Status:     v1alpha1.ApprovalTaskStatus{State: state},
+	}
+}
+
+func TestRecomputeEnqueuesPendingTasks(t *testing.T) {
+	h, recorder := newRecomputeTestHandler(t, 1000,
+		recomputeTask("production", "deploy", "pending"),
+		recomputeTask("staging", "deploy", "pending"),
+		recomputeTask("production", "fresh", ""),
+		recomputeTask("production", "done", "approved"),
+		recomputeTask("production", "denied", "rejected"),
+	)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, recomputeRequest(http.MethodPost, "admin"))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	var result RecomputeResult
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, 3, result.Pending)
+
+	assert.Eventually(t, func() bool { return len(recorder.enqueued()) == 3 }, 5*time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []types.NamespacedName{
+		{Namespace: "production", Name: "deploy"},
+		{Namespace: "staging", Name: "deploy"},
+		{Namespace: "production", Name: "fresh"},
+	}, recorder.enqueued())
+	assert.Eventually(t, func() bool { return !h.running.Load() }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRecomputeIsRateLimited(t *testing.T) {
+	h, recorder := newRecomputeTestHandler(t, 5)
+
+	start := time.Now()
+	h.enqueueAll(context.Background(), []types.NamespacedName{{Name: "a"}, {Name: "b"}, {Name: "c"}})
+	assert.Len(t, recorder.enqueued(), 3)
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond, "at 5 per second the second and third keys wait 200ms each")
+}
+
+func TestRecomputeRejectsConcurrentRuns(t *testing.T) {
+	h, _ := newRecomputeTestHandler(t, 0)
+	h.running.Store(true)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, recomputeRequest(http.MethodPost, "admin"))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, recomputeRequest(http.MethodGet, "admin"))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestRecomputeRequiresAuthorization(t *testing.T) {
+	h, recorder := newRecomputeTestHandler(t, 1000, recomputeTask("production", "deploy", "pending"))
+
+	for token, code := range map[string]int{
+		"":        http.StatusUnauthorized,
+		"invalid": http.StatusUnauthorized,
+		"alice":   http.StatusForbidden,
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, recomputeRequest(http.MethodPost, token))
+		assert.Equal(t, code, rec.Code, "token %q", token)
+	}
+	assert.False(t, h.running.Load())
+	assert.Empty(t, recorder.enqueued(), "denied requests recompute nothing")
+}
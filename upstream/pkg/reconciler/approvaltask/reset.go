@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
+)
+
+// resetOnApproverChange resets every response of a pending approval task
+// following ApproverChangeReset once its approver set no longer hashes to the
+// one recorded in its ApproverSetHashAnnotationKey annotation, since the
+// approvals given so far were given for another set of approvers. The spec is
+// written with every approver pending and the new hash, then the status
+// without any response, carried approvals included. Tasks without the
+// annotation, created before the policy existed, are left alone.
+func (r *Reconciler) resetOnApproverChange(ctx context.Context, approvalTask *v1alpha1.ApprovalTask) error {
+	if approvalTask.Status.State != "" && approvalTask.Status.State != pendingState {
+		return nil
+	}
+	if !approval.ResetsOnApproverChange(*approvalTask) {
+		return nil
+	}
+	recorded, ok := approvalTask.Annotations[v1alpha1.ApproverSetHashAnnotationKey]
+	current := approval.ApproverSetHash(approvalTask.Spec.Approvers)
+	if !ok || recorded == current {
+		return nil
+	}
+
+	status := approvalTask.Status
+	approvalTask.Spec.Approvers = approval.ResetApprovers(approvalTask.Spec.Approvers)
+	approvalTask.Annotations[v1alpha1.ApproverSetHashAnnotationKey] = current
+	at, err := r.approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(approvalTask.Namespace).Update(ctx, approvalTask, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+
+	*approvalTask = *at
+	approvalTask.Status = status
+	approvalTask.Status.ApproversResponse = nil
+	approvalTask.Status.ApprovalsReceived = 0
+	approvalTask.Status.LastDecisionAt = nil
+	approvalTask.Status.LastDecisionAtLocal = ""
+	setConditions(approvalTask, r.clock.Now())
+	at, err = r.approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(approvalTask.Namespace).UpdateStatus(ctx, approvalTask, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	*approvalTask = *at
+	logging.FromContext(ctx).Infof("Approval task %s changed approvers, its responses were reset", approvalTask.Name)
+	return nil
+}
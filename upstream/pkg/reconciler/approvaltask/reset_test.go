@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+// partiallyApprovedTask has one approval out of two required, and a third
+// approver added after alice approved.
+func partiallyApprovedTask(policy string) *v1alpha1.ApprovalTask {
+	at := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			ApproverChangePolicy:      policy,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+				{Name: "bob", Type: "User", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{
+			State:             pendingState,
+			ApprovalsReceived: 1,
+			ApproversResponse: []v1alpha1.ApproverState{{Name: "alice", Type: "User", Response: approvedState}},
+		},
+	}
+	at.Annotations = map[string]string{v1alpha1.ApproverSetHashAnnotationKey: approval.ApproverSetHash(at.Spec.Approvers)}
+	at.Spec.Approvers = append(at.Spec.Approvers, v1alpha1.ApproverDetails{Name: "security", Type: "Group", Input: "pending"})
+	return at
+}
+
+func TestResetOnApproverChange(t *testing.T) {
+	at := partiallyApprovedTask(v1alpha1.ApproverChangeReset)
+	client := fake.NewSimpleClientset(at.DeepCopy())
+	r := &Reconciler{approvaltaskClientSet: client, clock: clocktesting.NewFakePassiveClock(time.Now())}
+
+	assert.NoError(t, r.resetOnApproverChange(context.TODO(), at))
+	for _, approver := range at.Spec.Approvers {
+		assert.Equal(t, "pending", approver.Input, approver.Name)
+	}
+	assert.Equal(t, approval.ApproverSetHash(at.Spec.Approvers), at.Annotations[v1alpha1.ApproverSetHashAnnotationKey])
+	assert.Empty(t, at.Status.ApproversResponse)
+	assert.Equal(t, 0, at.Status.ApprovalsReceived)
+	assert.Equal(t, pendingState, at.Status.State)
+
+	stored, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", stored.Spec.Approvers[0].Input)
+	assert.Empty(t, stored.Status.ApproversResponse)
+
+	// Once reset, the task is left alone until its approvers change again
+	actions := len(client.Actions())
+	assert.NoError(t, r.resetOnApproverChange(context.TODO(), at))
+	assert.Len(t, client.Actions(), actions)
+}
+
+func TestResetOnApproverChangeLeavesTasksAlone(t *testing.T) {
+	preserve := partiallyApprovedTask(v1alpha1.ApproverChangePreserve)
+	unset := partiallyApprovedTask("")
+	unrecorded := partiallyApprovedTask(v1alpha1.ApproverChangeReset)
+	unrecorded.Annotations = nil
+	final := partiallyApprovedTask(v1alpha1.ApproverChangeReset)
+	final.Status.State = approvedState
+
+	for name, at := range map[string]*v1alpha1.ApprovalTask{
+		"preserve":              preserve,
+		"no policy":             unset,
+		"no recorded approvers": unrecorded,
+		"final":                 final,
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(at.DeepCopy())
+			r := &Reconciler{approvaltaskClientSet: client, clock: clocktesting.NewFakePassiveClock(time.Now())}
+			assert.NoError(t, r.resetOnApproverChange(context.TODO(), at))
+			assert.Equal(t, "approve", at.Spec.Approvers[0].Input, "the approval of alice is kept")
+			assert.Len(t, at.Status.ApproversResponse, 1)
+			assert.Empty(t, client.Actions())
+		})
+	}
+}
+
+func TestValidateApproverChangePolicy(t *testing.T) {
+	assert.NoError(t, validateApproverChangePolicy(""))
+	assert.NoError(t, validateApproverChangePolicy("Preserve"))
+	assert.NoError(t, validateApproverChangePolicy("Reset"))
+	assert.EqualError(t, validateApproverChangePolicy("reset"),
+		"invalid approverChangePolicy parameter: must be one of Preserve, Reset, got 'reset'")
+}
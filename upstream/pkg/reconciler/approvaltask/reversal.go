@@ -0,0 +1,60 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/callback"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+)
+
+// markRejected rejects the approval task at now. Tasks with a
+// RejectionReversalWindow record when the rejection was first observed, which
+// the window starts from.
+func markRejected(approvalTask *v1alpha1.ApprovalTask, now time.Time) {
+	approvalTask.Status.State = rejectedState
+	window := approvalTask.Spec.RejectionReversalWindow
+	if window != nil && window.Duration > 0 && approvalTask.Status.RejectedAt == nil {
+		rejectedAt := metav1.NewTime(now)
+		approvalTask.Status.RejectedAt = &rejectedAt
+	}
+}
+
+// finalizeRejection fails the run of an approval task whose rejection became
+// final at finalAt, once its RejectionReversalWindow has passed. Until then
+// the run is requeued for the end of the window, during which a privileged
+// user may still reverse the rejection.
+func (r *Reconciler) finalizeRejection(ctx context.Context, approvalTask *v1alpha1.ApprovalTask, run *v1beta1.CustomRun, finalAt time.Time) error {
+	if wait := finalAt.Sub(r.clock.Now()); wait > 0 {
+		if r.maxRequeueInterval > 0 && wait > r.maxRequeueInterval {
+			wait = r.maxRequeueInterval
+		}
+		return controller.NewRequeueAfter(wait)
+	}
+
+	logging.FromContext(ctx).Infof("Approval task %s is rejected, its reversal window has passed", approvalTask.Name)
+	run.Status.MarkCustomRunFailed(v1alpha1.ApprovalTaskRunReasonFailed.String(), "Approval Task denied")
+	r.recordDecisionDuration(ctx, approvalTask, rejectedState)
+	r.callback.Notify(ctx, callback.NewPayload(*approvalTask))
+	return nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+)
+
+// reversibleApprovalTask returns a pending task created at created, requiring
+// the approval of alice and bob, whose rejections can be reversed for ten
+// minutes.
+func reversibleApprovalTask(created time.Time) *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production", CreationTimestamp: metav1.NewTime(created)},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			RejectionReversalWindow:   &metav1.Duration{Duration: 10 * time.Minute},
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "bob", Type: "User", Input: "reject"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func TestReconcileRejectionWithinReversalWindow(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	client := fake.NewSimpleClientset(reversibleApprovalTask(now))
+	r := &Reconciler{clock: clocktesting.NewFakePassiveClock(now), approvaltaskClientSet: client}
+
+	run := approvalTaskRun()
+	err := r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{})
+	ok, delay := controller.IsRequeueKey(err)
+	assert.True(t, ok, "the run is requeued for the end of the window")
+	assert.Equal(t, 10*time.Minute, delay)
+	assert.False(t, run.IsDone(), "the rejection can still be reversed")
+
+	at, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "rejected", at.Status.State)
+	assert.Equal(t, now, at.Status.RejectedAt.Time)
+
+	// A privileged user reverses the rejection
+	at.Spec.Approvers[1].Input = "pending"
+	_, err = client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Update(context.TODO(), at, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+
+	_ = r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{})
+	assert.False(t, run.IsDone())
+	at, err = client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", at.Status.State)
+	assert.Nil(t, at.Status.RejectedAt)
+}
+
+func TestReconcileRejectionAfterReversalWindow(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	client := fake.NewSimpleClientset(reversibleApprovalTask(now))
+	clock := clocktesting.NewFakePassiveClock(now)
+	r := &Reconciler{clock: clock, approvaltaskClientSet: client}
+
+	run := approvalTaskRun()
+	_ = r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{})
+	assert.False(t, run.IsDone())
+
+	clock.SetTime(now.Add(11 * time.Minute))
+	err := r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{})
+	assert.NoError(t, err)
+	condition := run.Status.GetCondition(apis.ConditionSucceeded)
+	assert.True(t, condition.IsFalse(), "the rejection is final once the window has passed")
+	assert.Equal(t, v1alpha1.ApprovalTaskRunReasonFailed.String(), condition.Reason)
+}
+
+func TestReconcileRejectionWithoutReversalWindow(t *testing.T) {
+	now := time.Now()
+	approvalTask := reversibleApprovalTask(now)
+	approvalTask.Spec.RejectionReversalWindow = nil
+	client := fake.NewSimpleClientset(approvalTask)
+	r := &Reconciler{clock: clocktesting.NewFakePassiveClock(now), approvaltaskClientSet: client}
+
+	run := approvalTaskRun()
+	_ = r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{})
+	assert.True(t, run.Status.GetCondition(apis.ConditionSucceeded).IsFalse())
+
+	at, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Nil(t, at.Status.RejectedAt)
+}
+
+func TestValidateRejectionReversalWindow(t *testing.T) {
+	assert.NoError(t, validateRejectionReversalWindow("15m"))
+	assert.EqualError(t, validateRejectionReversalWindow("a while"), "invalid rejectionReversalWindow parameter: 'a while' is not a valid duration")
+	assert.EqualError(t, validateRejectionReversalWindow("-5m"), "invalid rejectionReversalWindow parameter: must not be negative, got -5m")
+}
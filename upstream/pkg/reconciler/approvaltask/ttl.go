@@ -0,0 +1,134 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	approvaltaskclientset "github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned"
+	listersapprovaltask "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	listers "github.com/tektoncd/pipeline/pkg/client/listers/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	// ttlExpiredReason is the reason of the event emitted when a finished
+	// approval task is deleted after its TTLSecondsAfterFinished.
+	ttlExpiredReason = "TTLExpired"
+
+	// runPendingRequeue is how long the deletion of an expired approval task
+	// waits for its CustomRun to be marked done, so that the task is not
+	// created again for a run still in progress.
+	runPendingRequeue = 5 * time.Second
+)
+
+// ttlReconciler deletes approval tasks once Spec.TTLSecondsAfterFinished
+// elapsed after they reached a final state. Like the finalizer reconciler it
+// reconciles approval tasks rather than CustomRuns, which are left alone
+// once done.
+type ttlReconciler struct {
+	clock                 clock.PassiveClock
+	approvaltaskClientSet approvaltaskclientset.Interface
+	approvaltaskLister    listersapprovaltask.ApprovalTaskLister
+	customRunLister       listers.CustomRunLister
+}
+
+func (t *ttlReconciler) Reconcile(ctx context.Context, key string) error {
+	logger := logging.FromContext(ctx)
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		logger.Errorf("Invalid resource key %s: %v", key, err)
+		return nil
+	}
+	approvalTask, err := t.approvaltaskLister.ApprovalTasks(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !expiresAfterFinished(approvalTask) {
+		return nil
+	}
+
+	// The TTL counts from when the task was first seen final
+	if approvalTask.Status.CompletionTime == nil {
+		approvalTask = approvalTask.DeepCopy()
+		completionTime := metav1.NewTime(t.clock.Now())
+		approvalTask.Status.CompletionTime = &completionTime
+		if _, err := t.approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(namespace).UpdateStatus(ctx, approvalTask, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	ttl := time.Duration(*approvalTask.Spec.TTLSecondsAfterFinished) * time.Second
+	if remaining := approvalTask.Status.CompletionTime.Add(ttl).Sub(t.clock.Now()); remaining > 0 {
+		return controller.NewRequeueAfter(remaining)
+	}
+	if t.runInProgress(approvalTask) {
+		return controller.NewRequeueAfter(runPendingRequeue)
+	}
+
+	// Finalizers still run: the deletion only sets the deletion timestamp
+	// of tasks carrying them
+	uid := approvalTask.UID
+	err = t.approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(namespace).Delete(ctx, name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &uid},
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	logger.Infof("Deleted approval task %s %s after it finished", key, ttl)
+	if recorder := controller.GetEventRecorder(ctx); recorder != nil {
+		recorder.Eventf(approvalTask, corev1.EventTypeNormal, ttlExpiredReason,
+			"Deleted approval task %s %s after it finished", approvalTask.Name, ttl)
+	}
+	return nil
+}
+
+// runInProgress reports whether the CustomRun of the approval task exists
+// and is not done yet.
+func (t *ttlReconciler) runInProgress(approvalTask *v1alpha1.ApprovalTask) bool {
+	runName := approvalTask.Labels[CustomRunLabelKey]
+	if t.customRunLister == nil || runName == "" {
+		return false
+	}
+	run, err := t.customRunLister.CustomRuns(approvalTask.Namespace).Get(runName)
+	return err == nil && !run.IsDone()
+}
+
+// expiresAfterFinished reports whether the approval task is in a final state,
+// not being deleted yet, and has a TTLSecondsAfterFinished.
+func expiresAfterFinished(approvalTask *v1alpha1.ApprovalTask) bool {
+	if approvalTask.Spec.TTLSecondsAfterFinished == nil || approvalTask.DeletionTimestamp != nil {
+		return false
+	}
+	switch approvalTask.Status.State {
+	case approvedState, rejectedState, withdrawnState:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approvaltask
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	listersapprovaltask "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	listers "github.com/tektoncd/pipeline/pkg/client/listers/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/ptr"
+)
+
+var finishedAt = time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+func finishedApprovalTask(ttlSeconds int32) *v1alpha1.ApprovalTask {
+	completionTime := metav1.NewTime(finishedAt)
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "deploy",
+			Namespace: "production",
+			UID:       "deploy-uid",
+			Labels:    map[string]string{CustomRunLabelKey: "deploy"},
+		},
+		Spec: v1alpha1.ApprovalTaskSpec{TTLSecondsAfterFinished: ptr.Int32(ttlSeconds)},
+		Status: v1alpha1.ApprovalTaskStatus{
+			State:          "approved",
+			CompletionTime: &completionTime,
+		},
+	}
+}
+
+func newTTLTestReconciler(t *testing.T, now time.Time, runs []*v1beta1.CustomRun, tasks ...*v1alpha1.ApprovalTask) (*ttlReconciler, *fake.Clientset) {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	runIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	client := fake.NewSimpleClientset()
+	for _, at := range tasks {
+		assert.NoError(t, indexer.Add(at))
+		assert.NoError(t, client.Tracker().Add(at))
+	}
+	for _, run := range runs {
+		assert.NoError(t, runIndexer.Add(run))
+	}
+	return &ttlReconciler{
+		clock:                 clocktesting.NewFakePassiveClock(now),
+		approvaltaskClientSet: client,
+		approvaltaskLister:    listersapprovaltask.NewApprovalTaskLister(indexer),
+		customRunLister:       listers.NewCustomRunLister(runIndexer),
+	}, client
+}
+
+func getDeployTask(client *fake.Clientset) (*v1alpha1.ApprovalTask, error) {
+	return client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+}
+
+func TestTTLRequeuesBeforeExpiry(t *testing.T) {
+	r, client := newTTLTestReconciler(t, finishedAt.Add(59*time.Second), nil, finishedApprovalTask(60))
+
+	err := r.Reconcile(context.TODO(), "production/deploy")
+	ok, delay := controller.IsRequeueKey(err)
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, delay)
+	_, err = getDeployTask(client)
+	assert.NoError(t, err, "the task is kept until its TTL elapsed")
+}
+
+func TestTTLDeletesAtExpiry(t *testing.T) {
+	r, client := newTTLTestReconciler(t, finishedAt.Add(time.Minute), nil, finishedApprovalTask(60))
+	recorder := record.NewFakeRecorder(10)
+	ctx := controller.WithEventRecorder(context.TODO(), recorder)
+
+	assert.NoError(t, r.Reconcile(ctx, "production/deploy"))
+	_, err := getDeployTask(client)
+	assert.True(t, apierrors.IsNotFound(err))
+	event := <-recorder.Events
+	assert.Contains(t, event, corev1.EventTypeNormal)
+	assert.Contains(t, event, ttlExpiredReason)
+}
+
+func TestTTLZeroDeletesImmediately(t *testing.T) {
+	r, client := newTTLTestReconciler(t, finishedAt, nil, finishedApprovalTask(0))
+
+	assert.NoError(t, r.Reconcile(context.TODO(), "production/deploy"))
+	_, err := getDeployTask(client)
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func TestTTLRecordsCompletionTime(t *testing.T) {
+	at := finishedApprovalTask(60)
+	at.Status.CompletionTime = nil
+	now := finishedAt.Add(time.Hour)
+	r, client := newTTLTestReconciler(t, now, nil, at)
+
+	err := r.Reconcile(context.TODO(), "production/deploy")
+	ok, delay := controller.IsRequeueKey(err)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, delay, "the TTL counts from when the task was first seen final")
+	stored, err := getDeployTask(client)
+	assert.NoError(t, err)
+	assert.True(t, stored.Status.CompletionTime.Time.Equal(now))
+}
+
+func TestTTLWaitsForRunToFinish(t *testing.T) {
+	run := &v1beta1.CustomRun{ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"}}
+	r, client := newTTLTestReconciler(t, finishedAt.Add(time.Hour), []*v1beta1.CustomRun{run}, finishedApprovalTask(60))
+
+	err := r.Reconcile(context.TODO(), "production/deploy")
+	ok, delay := controller.IsRequeueKey(err)
+	assert.True(t, ok)
+	assert.Equal(t, runPendingRequeue, delay)
+	_, err = getDeployTask(client)
+	assert.NoError(t, err)
+
+	run.Status.SetCondition(&apis.Condition{Type: apis.ConditionSucceeded, Status: corev1.ConditionTrue})
+	assert.NoError(t, r.Reconcile(context.TODO(), "production/deploy"))
+	_, err = getDeployTask(client)
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func TestTTLIgnoresTasks(t *testing.T) {
+	pending := finishedApprovalTask(0)
+	pending.Status.State = "pending"
+	withoutTTL := finishedApprovalTask(0)
+	withoutTTL.Spec.TTLSecondsAfterFinished = nil
+	deleting := finishedApprovalTask(0)
+	deletionTimestamp := metav1.NewTime(finishedAt)
+	deleting.DeletionTimestamp = &deletionTimestamp
+
+	for name, at := range map[string]*v1alpha1.ApprovalTask{
+		"pending":     pending,
+		"without TTL": withoutTTL,
+		"deleting":    deleting,
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, client := newTTLTestReconciler(t, finishedAt.Add(time.Hour), nil, at)
+			assert.NoError(t, r.Reconcile(context.TODO(), "production/deploy"))
+			assert.NoError(t, r.Reconcile(context.TODO(), "production/gone"))
+			_, err := getDeployTask(client)
+			assert.NoError(t, err)
+		})
+	}
+}
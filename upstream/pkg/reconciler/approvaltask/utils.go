@@ -22,20 +22,27 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask"
 	v1alpha1 "github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/callback"
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/correlation"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	"github.com/tektoncd/pipeline/pkg/reconciler/events"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/logging"
+	"knative.dev/pkg/ptr"
 )
 
 var (
@@ -73,9 +80,8 @@ func validateGroupSyntax(paramValue string, paramIndex int) error {
 	if strings.TrimSpace(groupName) == "" {
 		return fmt.Errorf("approvers[%d]: group name cannot be empty", paramIndex)
 	}
-	if strings.Contains(groupName, ":") {
-		return fmt.Errorf("approvers[%d]: group name '%s' cannot contain colons", paramIndex, groupName)
-	}
+	// Colons are left to the webhook, which allows them as the separator of
+	// hierarchical group names when it is configured so
 	if strings.Contains(groupName, " ") {
 		return fmt.Errorf("approvers[%d]: group name '%s' cannot contain spaces", paramIndex, groupName)
 	}
@@ -96,6 +102,11 @@ func validateUserSyntax(paramValue string, paramIndex int) error {
 // ValidateCustomRunParameters validates CustomRun parameters for early error detection.
 func ValidateCustomRunParameters(run *v1beta1.CustomRun) error {
 	var hasApprovers bool
+	var hasApprovalsRequired, hasApprovalPercentage bool
+	var hasExpectedDigest, requiresDigestAck bool
+	var hasBackupApprovers bool
+	var listedApprovers, roledApprovers []string
+	var escalation, lifetime time.Duration
 	var approversCount int
 	var validationErrors []string
 
@@ -106,13 +117,125 @@ func ValidateCustomRunParameters(run *v1beta1.CustomRun) error {
 			count, errs := validateApproversParam(param)
 			approversCount = count
 			validationErrors = append(validationErrors, errs...)
+			listedApprovers = append(listedApprovers, param.Value.ArrayVal...)
 		case approvalsRequired:
+			hasApprovalsRequired = true
 			if err := validateApprovalsRequired(param.Value.StringVal); err != nil {
 				return err
 			}
+		case approvalPercentage:
+			hasApprovalPercentage = true
+			if err := validateApprovalPercentage(param.Value.StringVal); err != nil {
+				return err
+			}
+		case maxApprovalsPerGroup:
+			if err := validateMaxApprovalsPerGroup(param.Value.StringVal); err != nil {
+				return err
+			}
+		case minApprovingTeams:
+			if err := validateMinApprovingTeams(param.Value.StringVal); err != nil {
+				return err
+			}
+		case minApproverLevel:
+			if err := validateMinApproverLevel(param.Value.StringVal); err != nil {
+				return err
+			}
+		case ttlAfterFinished:
+			if err := validateTTLSecondsAfterFinished(param.Value.StringVal); err != nil {
+				return err
+			}
+		case approvalExpiresAfter:
+			if err := validateApprovalExpiresAfter(param.Value.StringVal); err != nil {
+				return err
+			}
+		case mixedResolution:
+			if err := validateMixedResolution(param.Value.StringVal); err != nil {
+				return err
+			}
+		case minReviewDuration:
+			if err := validateMinReviewDuration(param.Value.StringVal); err != nil {
+				return err
+			}
+		case approverChangePolicy:
+			if err := validateApproverChangePolicy(param.Value.StringVal); err != nil {
+				return err
+			}
+		case countRequesterApproval:
+			if err := validateCountRequesterApproval(param.Value.StringVal); err != nil {
+				return err
+			}
+		case rejectionReversalWindow:
+			if err := validateRejectionReversalWindow(param.Value.StringVal); err != nil {
+				return err
+			}
+		case pipelineRunApprovers:
+			if err := validatePipelineRunApprovers(param.Value.StringVal); err != nil {
+				return err
+			}
+		case requiredRoles:
+			if err := validateRequiredRoles(param); err != nil {
+				return err
+			}
+		case approverRoles:
+			roles, err := parseApproverRoles(param)
+			if err != nil {
+				return err
+			}
+			for approver := range roles {
+				roledApprovers = append(roledApprovers, approver)
+			}
+		case escalateAfter:
+			d, err := validateLifecycleDuration(escalateAfter, param.Value.StringVal)
+			if err != nil {
+				return err
+			}
+			escalation = d
+		case maxPendingLifetime:
+			d, err := validateLifecycleDuration(maxPendingLifetime, param.Value.StringVal)
+			if err != nil {
+				return err
+			}
+			lifetime = d
+		case backupApprovers:
+			count, errs := validateApproversParam(param)
+			if len(errs) > 0 {
+				return fmt.Errorf("invalid backupApprovers parameter: %s", errs[0])
+			}
+			hasBackupApprovers = count > 0
+			listedApprovers = append(listedApprovers, param.Value.ArrayVal...)
+		case expectedDigest:
+			hasExpectedDigest = param.Value.StringVal != ""
+		case requireDigestAck:
+			required, err := strconv.ParseBool(param.Value.StringVal)
+			if err != nil {
+				return fmt.Errorf("invalid requireDigestAcknowledgment parameter: '%s' is not a valid boolean", param.Value.StringVal)
+			}
+			requiresDigestAck = required
+		}
+	}
+
+	for _, approver := range roledApprovers {
+		if !slices.Contains(listedApprovers, approver) {
+			return fmt.Errorf("invalid approverRoles parameter: '%s' is not one of the approvers or backupApprovers", approver)
 		}
 	}
 
+	if hasBackupApprovers && escalation == 0 {
+		return fmt.Errorf("invalid backupApprovers parameter: requires the escalateAfter parameter")
+	}
+
+	if escalation > 0 && lifetime > 0 && escalation >= lifetime {
+		return fmt.Errorf("invalid escalateAfter parameter: must be shorter than maxPendingLifetime, got %s", escalation)
+	}
+
+	if requiresDigestAck && !hasExpectedDigest {
+		return fmt.Errorf("invalid requireDigestAcknowledgment parameter: requires the expectedDigest parameter")
+	}
+
+	if hasApprovalsRequired && hasApprovalPercentage {
+		return fmt.Errorf("invalid approvalPercentage parameter: cannot be combined with numberOfApprovalsRequired")
+	}
+
 	if len(validationErrors) > 0 {
 		return fmt.Errorf("invalid approvers parameter: %s", validationErrors[0])
 	}
@@ -136,14 +259,30 @@ func validateApproversParam(param v1beta1.Param) (int, []string) {
 	// Parse approvers list from different formats
 	approverList := parseApproversList(param, &validationErrors)
 
+	// Index of the first user and group approver of each name, to detect
+	// a name used for both
+	users := make(map[string]int)
+	groups := make(map[string]int)
+
 	// Validate each approver
 	for i, approver := range approverList {
 		switch val := approver.(type) {
 		case string:
 			if err := validateApproverParameter(val, i); err != nil {
 				validationErrors = append(validationErrors, err.Error())
-			} else {
-				approversCount++
+				continue
+			}
+			approversCount++
+			if name, isGroup := strings.CutPrefix(val, "group:"); isGroup {
+				if j, ok := users[name]; ok {
+					validationErrors = append(validationErrors, fmt.Sprintf("approvers[%d]: group '%s' has the same name as the user at approvers[%d]", i, name, j))
+				} else if _, ok := groups[name]; !ok {
+					groups[name] = i
+				}
+			} else if j, ok := groups[val]; ok {
+				validationErrors = append(validationErrors, fmt.Sprintf("approvers[%d]: user '%s' has the same name as the group at approvers[%d]", i, val, j))
+			} else if _, ok := users[val]; !ok {
+				users[val] = i
 			}
 		case map[string]interface{}:
 			validateMalformedObjectApprover(val, i, &validationErrors)
@@ -220,6 +359,192 @@ func validateApprovalsRequired(value string) error {
 	return nil
 }
 
+// validateApprovalPercentage validates the approvalPercentage parameter value.
+func validateApprovalPercentage(value string) error {
+	percentage, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid approvalPercentage parameter: '%s' is not a valid integer", value)
+	}
+	if percentage < 1 || percentage > 100 {
+		return fmt.Errorf("invalid approvalPercentage parameter: must be between 1 and 100, got %d", percentage)
+	}
+	return nil
+}
+
+// validateMaxApprovalsPerGroup validates the maxApprovalsPerGroup parameter value.
+func validateMaxApprovalsPerGroup(value string) error {
+	maxPerGroup, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid maxApprovalsPerGroup parameter: '%s' is not a valid integer", value)
+	}
+	if maxPerGroup < 0 {
+		return fmt.Errorf("invalid maxApprovalsPerGroup parameter: must not be negative, got %d", maxPerGroup)
+	}
+	return nil
+}
+
+// validateMinApprovingTeams validates the minApprovingTeams parameter value.
+func validateMinApprovingTeams(value string) error {
+	teams, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid minApprovingTeams parameter: '%s' is not a valid integer", value)
+	}
+	if teams < 0 {
+		return fmt.Errorf("invalid minApprovingTeams parameter: must not be negative, got %d", teams)
+	}
+	return nil
+}
+
+// validateMinApproverLevel validates the minApproverLevel parameter value.
+func validateMinApproverLevel(value string) error {
+	level, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid minApproverLevel parameter: '%s' is not a valid integer", value)
+	}
+	if level < 0 {
+		return fmt.Errorf("invalid minApproverLevel parameter: must not be negative, got %d", level)
+	}
+	return nil
+}
+
+// validateTTLSecondsAfterFinished validates the ttlSecondsAfterFinished
+// parameter value.
+func validateTTLSecondsAfterFinished(value string) error {
+	seconds, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid ttlSecondsAfterFinished parameter: '%s' is not a valid integer", value)
+	}
+	if seconds < 0 {
+		return fmt.Errorf("invalid ttlSecondsAfterFinished parameter: must not be negative, got %d", seconds)
+	}
+	return nil
+}
+
+// validateApprovalExpiresAfter validates the approvalExpiresAfter parameter value.
+func validateApprovalExpiresAfter(value string) error {
+	expiresAfter, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid approvalExpiresAfter parameter: '%s' is not a valid duration", value)
+	}
+	if expiresAfter <= 0 {
+		return fmt.Errorf("invalid approvalExpiresAfter parameter: must be greater than 0, got %s", value)
+	}
+	return nil
+}
+
+// validateMinReviewDuration validates the minReviewDuration parameter value.
+func validateMinReviewDuration(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid minReviewDuration parameter: '%s' is not a valid duration", value)
+	}
+	if d < 0 {
+		return fmt.Errorf("invalid minReviewDuration parameter: must not be negative, got %s", value)
+	}
+	return nil
+}
+
+// validateRejectionReversalWindow validates the rejectionReversalWindow
+// parameter value.
+func validateRejectionReversalWindow(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid rejectionReversalWindow parameter: '%s' is not a valid duration", value)
+	}
+	if d < 0 {
+		return fmt.Errorf("invalid rejectionReversalWindow parameter: must not be negative, got %s", value)
+	}
+	return nil
+}
+
+// validateLifecycleDuration validates the value of the escalateAfter or
+// maxPendingLifetime parameter, named name, and returns it.
+func validateLifecycleDuration(name, value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s parameter: '%s' is not a valid duration", name, value)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid %s parameter: must be positive, got %s", name, value)
+	}
+	return d, nil
+}
+
+// validateMixedResolution validates the mixedResolution parameter value.
+func validateMixedResolution(value string) error {
+	if value == "" || slices.Contains(v1alpha1.KnownMixedResolutions, value) {
+		return nil
+	}
+	return fmt.Errorf("invalid mixedResolution parameter: must be one of %s, got '%s'", strings.Join(v1alpha1.KnownMixedResolutions, ", "), value)
+}
+
+// validateApproverChangePolicy validates the approverChangePolicy parameter
+// value.
+func validateApproverChangePolicy(value string) error {
+	if value == "" || slices.Contains(v1alpha1.KnownApproverChangePolicies, value) {
+		return nil
+	}
+	return fmt.Errorf("invalid approverChangePolicy parameter: must be one of %s, got '%s'", strings.Join(v1alpha1.KnownApproverChangePolicies, ", "), value)
+}
+
+// validateCountRequesterApproval validates the countRequesterApproval
+// parameter value.
+func validateCountRequesterApproval(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("invalid countRequesterApproval parameter: '%s' is not a valid boolean", value)
+	}
+	return nil
+}
+
+// validatePipelineRunApprovers validates the approversFromPipelineRun
+// parameter value. Results of the PipelineRun are rejected: they are only set
+// once it has completed, which it cannot while it waits for the approval.
+func validatePipelineRunApprovers(value string) error {
+	if strings.HasPrefix(value, "results.") {
+		return fmt.Errorf("invalid approversFromPipelineRun parameter: '%s' names a result of the PipelineRun, which is only set once it completes; pass the task result as the approvers param instead", value)
+	}
+	return nil
+}
+
+// validateRequiredRoles validates the requiredRoles parameter: an array of
+// roles, each named once.
+func validateRequiredRoles(param v1beta1.Param) error {
+	if param.Value.Type != v1beta1.ParamTypeArray {
+		return fmt.Errorf("invalid requiredRoles parameter: must be an array of roles")
+	}
+	for i, role := range param.Value.ArrayVal {
+		if strings.TrimSpace(role) == "" {
+			return fmt.Errorf("invalid requiredRoles parameter: requiredRoles[%d] cannot be empty", i)
+		}
+		if slices.Contains(param.Value.ArrayVal[:i], role) {
+			return fmt.Errorf("invalid requiredRoles parameter: duplicate role '%s'", role)
+		}
+	}
+	return nil
+}
+
+// parseApproverRoles parses the approverRoles parameter, an array of
+// "approver=role" entries naming approvers as the approvers parameter does,
+// e.g. "alice=manager" or "group:reviewers=peer". It returns the role of each
+// approver.
+func parseApproverRoles(param v1beta1.Param) (map[string]string, error) {
+	if param.Value.Type != v1beta1.ParamTypeArray {
+		return nil, fmt.Errorf("invalid approverRoles parameter: must be an array of approver=role entries")
+	}
+	roles := make(map[string]string, len(param.Value.ArrayVal))
+	for i, entry := range param.Value.ArrayVal {
+		approver, role, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(approver) == "" || strings.TrimSpace(role) == "" {
+			return nil, fmt.Errorf("invalid approverRoles parameter: approverRoles[%d] '%s' is not of the form approver=role", i, entry)
+		}
+		if _, ok := roles[approver]; ok {
+			return nil, fmt.Errorf("invalid approverRoles parameter: approver '%s' is given more than one role", approver)
+		}
+		roles[approver] = role
+	}
+	return roles, nil
+}
+
 func checkCustomRunReferencesApprovalTask(run *v1beta1.CustomRun) error {
 	var apiVersion, kind string
 	if run.Spec.CustomRef != nil {
@@ -257,7 +582,11 @@ func initializeCustomRun(ctx context.Context, run *v1beta1.CustomRun) {
 	}
 }
 
-func getOrCreateApprovalTask(ctx context.Context, approvaltaskClientSet versioned.Interface, run *v1beta1.CustomRun) (*v1alpha1.ApprovalTask, error) {
+// getOrCreateApprovalTask returns the approval task of the run, creating it
+// with the given finalizers if it does not exist yet. When carryForward is
+// set, a created task starts with the approvals of its prior task (see
+// priorApprovals).
+func getOrCreateApprovalTask(ctx context.Context, approvaltaskClientSet versioned.Interface, run *v1beta1.CustomRun, finalizers []string, policies []*v1alpha1.ClusterApprovalPolicy, carryForward bool) (*v1alpha1.ApprovalTask, error) {
 	approvalTask := v1alpha1.ApprovalTask{}
 
 	if run.Spec.CustomRef != nil {
@@ -267,7 +596,7 @@ func getOrCreateApprovalTask(ctx context.Context, approvaltaskClientSet versione
 		tl, err := approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(run.Namespace).Get(ctx, run.Name, metav1.GetOptions{})
 		if err != nil {
 			if errors.IsNotFound(err) {
-				at, err := createApprovalTask(ctx, approvaltaskClientSet, run)
+				at, err := createApprovalTask(ctx, approvaltaskClientSet, run, finalizers, policies, carryForward)
 				if err != nil {
 					return nil, err
 				}
@@ -301,6 +630,10 @@ func propagateApprovalTaskLabelsAndAnnotations(run *v1beta1.CustomRun, approvalt
 		run.ObjectMeta.Labels = make(map[string]string, len(approvaltaskMeta.Labels)+1)
 	}
 	for key, value := range approvaltaskMeta.Labels {
+		// The index of awaited approvers only describes the task
+		if approval.IsAwaitingLabel(key) {
+			continue
+		}
 		run.ObjectMeta.Labels[key] = value
 	}
 	run.ObjectMeta.Labels[approvaltask.GroupName+approvaltaskLabelKey] = approvaltaskMeta.Name
@@ -337,11 +670,31 @@ func (c *Reconciler) updateLabelsAndAnnotations(ctx context.Context, run *v1beta
 	return nil
 }
 
-func createApprovalTask(ctx context.Context, approvaltaskClientSet versioned.Interface, run *v1beta1.CustomRun) (v1alpha1.ApprovalTask, error) {
+func createApprovalTask(ctx context.Context, approvaltaskClientSet versioned.Interface, run *v1beta1.CustomRun, finalizers []string, policies []*v1alpha1.ClusterApprovalPolicy, carryForward bool) (v1alpha1.ApprovalTask, error) {
 	var (
 		approvers      []v1alpha1.ApproverDetails
 		users          []string
 		desc           string
+		escrow         string
+		maxPerGroup    int
+		minTeams       int
+		minLevel       int
+		percentage     int
+		mixed          string
+		changePolicy   string
+		countRequester bool
+		requireAck     bool
+		ttl            *int32
+		expiresAfter   *metav1.Duration
+		reviewDuration *metav1.Duration
+		reversalWindow *metav1.Duration
+		escalation     *metav1.Duration
+		lifetime       *metav1.Duration
+		backups        []string
+		roles          []string
+		approverRole   map[string]string
+		digest         string
+		ticket         string
 		err            error
 		approverExists = make(map[string]bool)
 		userExists     = make(map[string]bool)
@@ -387,8 +740,122 @@ func createApprovalTask(ctx context.Context, approvaltaskClientSet versioned.Int
 				return v1alpha1.ApprovalTask{}, err
 			}
 			numberOfApprovalsRequired = tempApproversRequired
+		} else if v.Name == approvalPercentage {
+			percentage, err = strconv.Atoi(v.Value.StringVal)
+			if err != nil {
+				return v1alpha1.ApprovalTask{}, err
+			}
+			numberOfApprovalsRequired = 0
 		} else if v.Name == description {
 			desc = v.Value.StringVal
+		} else if v.Name == escrowGroup {
+			escrow = v.Value.StringVal
+		} else if v.Name == maxApprovalsPerGroup {
+			maxPerGroup, err = strconv.Atoi(v.Value.StringVal)
+			if err != nil {
+				return v1alpha1.ApprovalTask{}, err
+			}
+		} else if v.Name == minApprovingTeams {
+			minTeams, err = strconv.Atoi(v.Value.StringVal)
+			if err != nil {
+				return v1alpha1.ApprovalTask{}, err
+			}
+		} else if v.Name == minApproverLevel {
+			minLevel, err = strconv.Atoi(v.Value.StringVal)
+			if err != nil {
+				return v1alpha1.ApprovalTask{}, err
+			}
+		} else if v.Name == ttlAfterFinished {
+			seconds, err := strconv.ParseInt(v.Value.StringVal, 10, 32)
+			if err != nil {
+				return v1alpha1.ApprovalTask{}, err
+			}
+			ttl = ptr.Int32(int32(seconds))
+		} else if v.Name == approvalExpiresAfter {
+			d, err := time.ParseDuration(v.Value.StringVal)
+			if err != nil {
+				return v1alpha1.ApprovalTask{}, err
+			}
+			expiresAfter = &metav1.Duration{Duration: d}
+		} else if v.Name == minReviewDuration {
+			d, err := time.ParseDuration(v.Value.StringVal)
+			if err != nil {
+				return v1alpha1.ApprovalTask{}, err
+			}
+			reviewDuration = &metav1.Duration{Duration: d}
+		} else if v.Name == rejectionReversalWindow {
+			d, err := time.ParseDuration(v.Value.StringVal)
+			if err != nil {
+				return v1alpha1.ApprovalTask{}, err
+			}
+			reversalWindow = &metav1.Duration{Duration: d}
+		} else if v.Name == escalateAfter {
+			d, err := time.ParseDuration(v.Value.StringVal)
+			if err != nil {
+				return v1alpha1.ApprovalTask{}, err
+			}
+			escalation = &metav1.Duration{Duration: d}
+		} else if v.Name == maxPendingLifetime {
+			d, err := time.ParseDuration(v.Value.StringVal)
+			if err != nil {
+				return v1alpha1.ApprovalTask{}, err
+			}
+			lifetime = &metav1.Duration{Duration: d}
+		} else if v.Name == backupApprovers {
+			backups = append(backups, v.Value.ArrayVal...)
+		} else if v.Name == requiredRoles {
+			roles = v.Value.ArrayVal
+		} else if v.Name == approverRoles {
+			approverRole, err = parseApproverRoles(v)
+			if err != nil {
+				return v1alpha1.ApprovalTask{}, err
+			}
+		} else if v.Name == expectedDigest {
+			digest = v.Value.StringVal
+		} else if v.Name == changeTicket {
+			ticket = v.Value.StringVal
+		} else if v.Name == mixedResolution {
+			mixed = v.Value.StringVal
+		} else if v.Name == approverChangePolicy {
+			changePolicy = v.Value.StringVal
+		} else if v.Name == countRequesterApproval {
+			countRequester, err = strconv.ParseBool(v.Value.StringVal)
+			if err != nil {
+				return v1alpha1.ApprovalTask{}, err
+			}
+		} else if v.Name == requireDigestAck {
+			requireAck, err = strconv.ParseBool(v.Value.StringVal)
+			if err != nil {
+				return v1alpha1.ApprovalTask{}, err
+			}
+		}
+	}
+
+	// Backup approvers come after the others, which they cannot duplicate
+	for _, name := range backups {
+		approver := v1alpha1.ApproverDetails{Name: name, Input: pendingState, Type: "User", Backup: true}
+		if group, isGroup := strings.CutPrefix(name, "group:"); isGroup {
+			approver.Name, approver.Type = group, "Group"
+		}
+		if !approverExists[approver.Name] {
+			approvers = append(approvers, approver)
+			approverExists[approver.Name] = true
+			users = append(users, approver.Name)
+		}
+	}
+
+	for i, approver := range approvers {
+		name := approver.Name
+		if approver.Type == "Group" {
+			name = "group:" + name
+		}
+		approvers[i].Role = approverRole[name]
+	}
+
+	if expiresAfter != nil {
+		for i := range approvers {
+			d := *expiresAfter
+			approvers[i].ApprovalExpiresAfter = &d
 		}
 	}
 
@@ -405,20 +872,73 @@ func createApprovalTask(ctx context.Context, approvaltaskClientSet versioned.Int
 			Namespace:       run.Namespace,
 			Labels:          labels,
 			OwnerReferences: []metav1.OwnerReference{ownerRef},
+			Finalizers:      finalizers,
 		},
 		Spec: v1alpha1.ApprovalTaskSpec{
-			Approvers:                 approvers,
-			NumberOfApprovalsRequired: numberOfApprovalsRequired,
-			Description:               desc,
+			Approvers:                   approvers,
+			NumberOfApprovalsRequired:   numberOfApprovalsRequired,
+			ApprovalPercentage:          percentage,
+			Description:                 desc,
+			EscrowGroup:                 escrow,
+			MaxApprovalsPerGroup:        maxPerGroup,
+			ExpectedDigest:              digest,
+			RequireDigestAcknowledgment: requireAck,
+			ChangeTicket:                ticket,
+			MinApprovingTeams:           minTeams,
+			MinApproverLevel:            minLevel,
+			MixedResolution:             mixed,
+			TTLSecondsAfterFinished:     ttl,
+			MinReviewDuration:           reviewDuration,
+			ApproverChangePolicy:        changePolicy,
+			CountRequesterApproval:      countRequester,
+			RejectionReversalWindow:     reversalWindow,
+			EscalateAfter:               escalation,
+			MaxPendingLifetime:          lifetime,
+			RequiredRoles:               roles,
 		},
 	}
 
-	approverSpecHash, err := Compute(approvalTask.Spec.Approvers)
+	// The task is created as strict as the cluster approval policies
+	// selecting it require, so that the webhook admits it
+	requirements := approval.PolicyRequirementsFor(*approvalTask, policies)
+	approval.ApplyPolicy(&approvalTask.Spec, requirements)
+	for _, approver := range approvalTask.Spec.Approvers[len(approvers):] {
+		users = append(users, approver.Name)
+	}
+
+	var carried []v1alpha1.ApproverState
+	if carryForward {
+		carried, err = priorApprovals(ctx, approvaltaskClientSet, *approvalTask)
+		if err != nil {
+			return v1alpha1.ApprovalTask{}, err
+		}
+	}
+
+	approverSpecHash, err := approversHash(*approvalTask)
 	if err != nil {
 		return v1alpha1.ApprovalTask{}, err
 	}
-	approvalTask.Annotations = map[string]string{
-		LastAppliedHashKey: approverSpecHash,
+	approvalTask.Annotations = map[string]string{}
+	// Without the hash, a task starting with carried approvals has its
+	// quorum evaluated on the first reconcile
+	if len(carried) == 0 {
+		approvalTask.Annotations[LastAppliedHashKey] = approverSpecHash
+	}
+	if createdBy, ok := run.Annotations[v1alpha1.CreatedByAnnotationKey]; ok {
+		approvalTask.Annotations[v1alpha1.CreatedByAnnotationKey] = createdBy
+	}
+	if currentDigest, ok := run.Annotations[v1alpha1.CurrentDigestAnnotationKey]; ok {
+		approvalTask.Annotations[v1alpha1.CurrentDigestAnnotationKey] = currentDigest
+	}
+	if changeTicket, ok := run.Annotations[v1alpha1.ChangeTicketAnnotationKey]; ok {
+		approvalTask.Annotations[v1alpha1.ChangeTicketAnnotationKey] = changeTicket
+	}
+	approvalTask.Annotations[v1alpha1.CorrelationIDAnnotationKey] = correlation.Of(run)
+	if approvalTask.Annotations[v1alpha1.CorrelationIDAnnotationKey] == "" {
+		approvalTask.Annotations[v1alpha1.CorrelationIDAnnotationKey] = correlation.New()
+	}
+	if approval.ResetsOnApproverChange(*approvalTask) {
+		approvalTask.Annotations[v1alpha1.ApproverSetHashAnnotationKey] = approval.ApproverSetHash(approvalTask.Spec.Approvers)
 	}
 
 	_, err = approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(run.Namespace).Create(ctx, approvalTask, metav1.CreateOptions{})
@@ -437,11 +957,23 @@ func createApprovalTask(ctx context.Context, approvaltaskClientSet versioned.Int
 		State:             pendingState,
 		Approvers:         users,
 		ApproversResponse: []v1alpha1.ApproverState{},
-		ApprovalsRequired: numberOfApprovalsRequired,
+		ApprovalsRequired: approval.BaseApprovalsRequired(*approvalTask),
 		ApprovalsReceived: 0, // Initially no approvals received
+		Policy:            requirements,
 	}
+	// The webhook may already have recorded annotations, such as signed
+	// decisions, in the status
+	status.Annotations = at.Status.Annotations
 
 	at.Status = status
+	// ApplyPolicy leaves percentages alone, the policy minimum in the
+	// status raises them once evaluated
+	at.Status.ApprovalsRequired = approval.RequiredApprovalsAt(*at, at.CreationTimestamp.Time)
+	if len(carried) > 0 {
+		at.Status.ApproversResponse = carried
+		at.Status.ApprovalsReceived = approval.CountApprovals(*at)
+	}
+	setConditions(at, at.CreationTimestamp.Time)
 	_, err = approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(run.Namespace).UpdateStatus(ctx, at, metav1.UpdateOptions{})
 	if err != nil {
 		return v1alpha1.ApprovalTask{}, err
@@ -450,67 +982,48 @@ func createApprovalTask(ctx context.Context, approvaltaskClientSet versioned.Int
 	return *at, nil
 }
 
+// approvalTaskHasFalseInput reports whether an approver vetoed the approval
+// task. Rejections only veto tasks without a mixed resolution.
 func approvalTaskHasFalseInput(approvalTask v1alpha1.ApprovalTask) bool {
+	if !approval.RejectionVetoes(approvalTask) {
+		return false
+	}
 	for _, approver := range approvalTask.Spec.Approvers {
-		if approver.Input == hasRejected {
+		if approver.Input == hasRejected && approval.ApproverActive(approvalTask, approver) {
 			return true // Found an input that is "reject"
 		}
 	}
 	return false
 }
 
-func approvalTaskHasTrueInput(approvalTask v1alpha1.ApprovalTask) bool {
-	// Count approvers with input "approve"
-	requiredApprovals := approvalTask.Spec.NumberOfApprovalsRequired
-
-	approvedUsers := make(map[string]bool)
-
-	for _, approver := range approvalTask.Spec.Approvers {
-		if approver.Input != hasApproved {
-			continue
-		}
-
-		if v1alpha1.DefaultedApproverType(approver.Type) == "User" {
-			approvedUsers[approver.Name] = true
-		} else if v1alpha1.DefaultedApproverType(approver.Type) == "Group" {
-			for _, user := range approver.Users {
-				if user.Input == hasApproved {
-					approvedUsers[user.Name] = true
-				}
-			}
-		}
-	}
-
-	return len(approvedUsers) >= requiredApprovals
+// decidedInput reports whether input is a decision of an approver, as
+// opposed to "pending".
+func decidedInput(input string) bool {
+	return input == hasApproved || input == hasRejected || input == hasRequestedChanges
 }
 
-func countApprovalsReceived(approvalTask v1alpha1.ApprovalTask) int {
-	// Count unique users who have approved
-	approvedUsers := make(map[string]bool)
-
-	for _, approver := range approvalTask.Spec.Approvers {
-		if approver.Input != hasApproved {
-			continue
-		}
-
-		if v1alpha1.DefaultedApproverType(approver.Type) == "User" {
-			approvedUsers[approver.Name] = true
-		} else if v1alpha1.DefaultedApproverType(approver.Type) == "Group" {
-			for _, user := range approver.Users {
-				if user.Input == hasApproved {
-					approvedUsers[user.Name] = true
-				}
-			}
-		}
+// inputResponse returns the response recorded in the status for a decided
+// input, or an empty string for any other input.
+func inputResponse(input string) string {
+	switch input {
+	case hasApproved:
+		return approvedState
+	case hasRejected:
+		return rejectedState
+	case hasRequestedChanges:
+		return changesRequestedState
 	}
+	return ""
+}
 
-	return len(approvedUsers)
+func approvalTaskHasTrueInput(approvalTask v1alpha1.ApprovalTask) bool {
+	return approval.QuorumReached(approvalTask)
 }
 
-func (r *Reconciler) checkIfUpdateRequired(ctx context.Context, approvalTask v1alpha1.ApprovalTask, run *v1beta1.CustomRun) error {
+func (r *Reconciler) checkIfUpdateRequired(ctx context.Context, approvalTask *v1alpha1.ApprovalTask, run *v1beta1.CustomRun) error {
 	logger := logging.FromContext(ctx)
 
-	expectedHash, err := Compute(approvalTask.Spec.Approvers)
+	expectedHash, err := approversHash(*approvalTask)
 	if err != nil {
 		logger.Errorf("Unable to compute the hash")
 		return err
@@ -518,27 +1031,55 @@ func (r *Reconciler) checkIfUpdateRequired(ctx context.Context, approvalTask v1a
 	lastAppliedHash := approvalTask.GetAnnotations()[LastAppliedHashKey]
 
 	if expectedHash != lastAppliedHash {
-		if _, err := updateApprovalState(ctx, r.approvaltaskClientSet, &approvalTask); err != nil {
+		// The state is recomputed from the current spec, so the write
+		// records it as observed
+		observed := approvalTask.Status.ObservedGeneration
+		approvalTask.Status.ObservedGeneration = approvalTask.Generation
+		escrow := &escrowScope{approvalTasks: r.approvaltaskLister, namespaces: r.namespaceLister}
+		at, err := updateApprovalState(ctx, r.approvaltaskClientSet, escrow, r.clock, r.displayLocation, approvalTask)
+		if err != nil {
 			return err
 		}
+		if at.Name != "" {
+			*approvalTask = at
+		} else {
+			approvalTask.Status.ObservedGeneration = observed
+		}
 
 		switch approvalTask.Status.State {
 		case pendingState:
 			logger.Infof("Approval task %s is in pending state", approvalTask.Name)
 		case rejectedState:
+			// Reversible rejections are only final once their window has passed
+			if finalAt, ok := approval.RejectionFinalAt(*approvalTask); ok && r.clock.Now().Before(finalAt) {
+				logger.Infof("Approval task %s is rejected, reversible until %s", approvalTask.Name, finalAt)
+				break
+			}
 			logger.Infof("Approval task %s is rejected", approvalTask.Name)
 			run.Status.MarkCustomRunFailed(v1alpha1.ApprovalTaskRunReasonFailed.String(), "Approval Task denied")
+			r.recordDecisionDuration(ctx, approvalTask, rejectedState)
+			r.callback.Notify(ctx, callback.NewPayload(*approvalTask))
 		case approvedState:
 			logger.Infof("Approval task %s is approved", approvalTask.Name)
 			run.Status.MarkCustomRunSucceeded(v1alpha1.ApprovalTaskRunReasonSucceeded.String(),
 				"TaskRun succeeded")
+			r.recordDecisionDuration(ctx, approvalTask, approvedState)
+			r.callback.Notify(ctx, callback.NewPayload(*approvalTask))
 		}
 	}
 
 	return nil
 }
 
-func updateApprovalState(ctx context.Context, approvaltaskClientSet versioned.Interface, approvalTask *v1alpha1.ApprovalTask) (v1alpha1.ApprovalTask, error) {
+func updateApprovalState(ctx context.Context, approvaltaskClientSet versioned.Interface, escrow *escrowScope, clock clock.PassiveClock, displayLocation *time.Location, approvalTask *v1alpha1.ApprovalTask) (v1alpha1.ApprovalTask, error) {
+	now := clock.Now()
+	// Keep the previous responses around so that the time a response was first
+	// observed survives the rebuild below.
+	previousResponses := approvalTask.Status.ApproversResponse
+	// The latencies of the responses first observed now, recorded once the
+	// status is updated
+	var observed []observedLatency
+
 	// Updating the approvedBy field in the status
 	// Temp map to hold current approvers with approve and reject input
 	currentApprovers := make(map[string]v1alpha1.ApproverState)
@@ -547,21 +1088,50 @@ func updateApprovalState(ctx context.Context, approvaltaskClientSet versioned.In
 	// to avoid duplicate entries when they are also group members
 	processedUserApprovers := make(map[string]bool)
 	
-	// First pass: Process all User type approvers
+	// First pass: Process all individual (User and Email) approvers. Inactive
+	// approvers (see ApproverDetails.WhenLabels) are left out of both passes.
 	for _, approver := range approvalTask.Spec.Approvers {
-		if (approver.Input == hasApproved || approver.Input == hasRejected) && v1alpha1.DefaultedApproverType(approver.Type) == "User" {
-			response := ""
-			if approver.Input == hasApproved {
-				response = approvedState
-			} else if approver.Input == hasRejected {
-				response = rejectedState
-			}
-			
+		if !approval.ApproverActive(*approvalTask, approver) {
+			continue
+		}
+		// A carried approval stands until the approver decides on this task
+		if previous, ok := findApproverState(previousResponses, approver.Name, v1alpha1.DefaultedApproverType(approver.Type)); ok && previous.CarriedFrom != "" &&
+			!decidedInput(approver.Input) && v1alpha1.IsIndividualApproverType(approver.Type) {
+			if previous.Response == approvedState && approval.Lapsed(approver.ApprovalExpiresAfter, previous.RespondedAt, approver.RenewTime, now) {
+				previous.Response = pendingState
+			}
+			currentApprovers[approver.Name] = previous
+			processedUserApprovers[approver.Name] = true
+			continue
+		}
+		if decidedInput(approver.Input) && v1alpha1.IsIndividualApproverType(approver.Type) {
+			response := inputResponse(approver.Input)
+
+			var previous *v1alpha1.ApproverState
+			if p, ok := findApproverState(previousResponses, approver.Name, v1alpha1.DefaultedApproverType(approver.Type)); ok {
+				previous = &p
+			}
+			respondedAt := carryRespondedAt(previous, response, now)
+			// An approval that was not renewed in time silently reverts to pending
+			if response == approvedState && approval.Lapsed(approver.ApprovalExpiresAfter, respondedAt, approver.RenewTime, now) {
+				response = pendingState
+			}
+
+			latency := responseLatency(approvalTask, respondedAt)
+			if latency != nil && response != pendingState && (previous == nil || previous.RespondedAt != respondedAt) {
+				observed = append(observed, observedLatency{role: approverRole(approver.Type), latency: latency.Duration})
+			}
+
 			currentApprovers[approver.Name] = v1alpha1.ApproverState{
-				Name:     approver.Name,
-				Type:     "User",
-				Response: response,
-				Message:  approver.Message,
+				Name:            approver.Name,
+				Type:            v1alpha1.DefaultedApproverType(approver.Type),
+				Response:        response,
+				Message:         approver.Message,
+				RespondedAt:     respondedAt,
+				ResponseLatency: latency,
+				IdempotencyKey:  carryIdempotencyKey(*approvalTask, previous, respondedAt),
+				DecidedBy:       approver.DecidedBy,
+				DelegationBasis: delegationBasis(approver),
 			}
 			// Mark this user as processed to avoid duplication in group processing
 			processedUserApprovers[approver.Name] = true
@@ -570,11 +1140,17 @@ func updateApprovalState(ctx context.Context, approvaltaskClientSet versioned.In
 	
 	// Second pass: Process Group type approvers, excluding users already processed as individuals
 	for _, approver := range approvalTask.Spec.Approvers {
-		if (approver.Input == hasApproved || approver.Input == hasRejected) && v1alpha1.DefaultedApproverType(approver.Type) == "Group" {
+		if !approval.ApproverActive(*approvalTask, approver) {
+			continue
+		}
+		if decidedInput(approver.Input) && v1alpha1.DefaultedApproverType(approver.Type) == "Group" {
 			groupMembers := []v1alpha1.GroupMemberState{}
 			groupResponse := ""
 			hasApprovals := false
 			hasRejections := false
+			hasChangeRequests := false
+			hasLapsed := false
+			previousGroup, _ := findApproverState(previousResponses, approver.Name, "Group")
 
 			for _, user := range approver.Users {
 				// Skip users who have already been processed as individual approvers
@@ -583,20 +1159,43 @@ func updateApprovalState(ctx context.Context, approvaltaskClientSet versioned.In
 					continue
 				}
 				
-				userResponse := ""
-				if user.Input == hasApproved {
-					userResponse = approvedState
-					hasApprovals = true
-				} else if user.Input == hasRejected {
-					userResponse = rejectedState
-					hasRejections = true
-				}
+				userResponse := inputResponse(user.Input)
 
 				if userResponse != "" {
+					var previous *v1alpha1.ApproverState
+					for _, member := range previousGroup.GroupMembers {
+						if member.Name == user.Name {
+							previous = &v1alpha1.ApproverState{Response: member.Response, RespondedAt: member.RespondedAt, IdempotencyKey: member.IdempotencyKey}
+						}
+					}
+					respondedAt := carryRespondedAt(previous, userResponse, now)
+					if userResponse == approvedState && approval.Lapsed(approver.ApprovalExpiresAfter, respondedAt, user.RenewTime, now) {
+						userResponse = pendingState
+					}
+
+					latency := responseLatency(approvalTask, respondedAt)
+					if latency != nil && userResponse != pendingState && (previous == nil || previous.RespondedAt != respondedAt) {
+						observed = append(observed, observedLatency{role: roleGroupMember, latency: latency.Duration})
+					}
+
+					switch userResponse {
+					case approvedState:
+						hasApprovals = true
+					case rejectedState:
+						hasRejections = true
+					case changesRequestedState:
+						hasChangeRequests = true
+					case pendingState:
+						hasLapsed = true
+					}
+
 					groupMembers = append(groupMembers, v1alpha1.GroupMemberState{
-						Name:     user.Name,
-						Response: userResponse,
-						Message:  user.Message, // Inherit message from user level
+						Name:            user.Name,
+						Response:        userResponse,
+						Message:         user.Message, // Inherit message from user level
+						RespondedAt:     respondedAt,
+						ResponseLatency: latency,
+						IdempotencyKey:  carryIdempotencyKey(*approvalTask, previous, respondedAt),
 					})
 				}
 			}
@@ -604,8 +1203,12 @@ func updateApprovalState(ctx context.Context, approvaltaskClientSet versioned.In
 			// Determine group response based on individual user responses
 			if hasRejections {
 				groupResponse = rejectedState
+			} else if hasChangeRequests {
+				groupResponse = changesRequestedState
 			} else if hasApprovals {
 				groupResponse = approvedState
+			} else if hasLapsed {
+				groupResponse = pendingState
 			}
 
 			if groupResponse != "" {
@@ -620,7 +1223,10 @@ func updateApprovalState(ctx context.Context, approvaltaskClientSet versioned.In
 		}
 	}
 
-	if len(currentApprovers) != 0 {
+	// Responses that disappeared, e.g. because their approver became
+	// inactive or a rejection was reversed, must be cleared from the status
+	// as well
+	if len(currentApprovers) != 0 || len(previousResponses) != 0 || approvalTask.Status.RejectedAt != nil {
 		// Filter the ApprovedBy to only include those that are still true
 		filteredApprovedBy := []v1alpha1.ApproverState{}
 		for _, approver := range currentApprovers {
@@ -631,29 +1237,159 @@ func updateApprovalState(ctx context.Context, approvaltaskClientSet versioned.In
 		approvalTask.Status.ApproversResponse = filteredApprovedBy
 
 		// Update the approvals count fields
-		approvalTask.Status.ApprovalsRequired = approvalTask.Spec.NumberOfApprovalsRequired
-		approvalTask.Status.ApprovalsReceived = countApprovalsReceived(*approvalTask)
+		approvalTask.Status.ApprovalsRequired = approval.RequiredApprovalsAt(*approvalTask, now)
+		approvalTask.Status.ApprovalsReceived = approval.CountApprovalsAt(*approvalTask, now)
+		approvalTask.Status.LastDecisionAt = lastDecisionAt(approvalTask.Status.ApproversResponse)
+		setDisplayTimes(&approvalTask.Status, displayLocation)
 
 		// Update the approvalState
-		// Reject scenario: Check if there is one false and if found mark the approvalstate to false
+		// Reject scenario: Check if there is one false and if found mark the approvalstate to false,
+		// or, without a veto, if everyone responded without reaching the quorum
 		// Approve scenario: Check if the input value from the user is true and is equal to the approvalsRequired
-		if approvalTaskHasFalseInput(*approvalTask) {
-			approvalTask.Status.State = rejectedState
-		} else if approvalTaskHasTrueInput(*approvalTask) {
-			approvalTask.Status.State = approvedState
+		rejected := approvalTaskHasFalseInput(*approvalTask) || approval.OutvotedAt(*approvalTask, now)
+		if !rejected && approvalTask.Status.State == rejectedState && approvalTask.Status.RejectedAt != nil {
+			// The rejection was reversed within its window
+			approvalTask.Status.State = pendingState
+			approvalTask.Status.RejectedAt = nil
+		}
+		if rejected {
+			markRejected(approvalTask, now)
+		} else if approval.QuorumReachedAt(*approvalTask, now) {
+			if approvalTask.Spec.EscrowGroup == "" {
+				approvalTask.Status.State = approvedState
+			} else if err := releaseEscrowGroup(ctx, approvaltaskClientSet, escrow, approvalTask, now); err != nil {
+				return v1alpha1.ApprovalTask{}, err
+			}
 		}
 
+		setConditions(approvalTask, now)
+
 		// Update the status finally
 		at, err := approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(approvalTask.Namespace).UpdateStatus(ctx, approvalTask, metav1.UpdateOptions{})
 		if err != nil {
 			return v1alpha1.ApprovalTask{}, err
 		}
+		recordResponseLatencies(ctx, approvalTask, observed)
 		return *at, nil
 	}
 
 	return v1alpha1.ApprovalTask{}, nil
 }
 
+// nextRequeue returns how long to wait before the pending approval task must be
+// re-evaluated: until its timeout, the first lapse of one of its approvals,
+// the next step of its quorum schedule or the next step of its lifecycle,
+// whichever comes first, bounded by maxInterval when that is set.
+func nextRequeue(approvalTask v1alpha1.ApprovalTask, timeout time.Duration, now time.Time, maxInterval time.Duration) (time.Duration, bool) {
+	var waitTime time.Duration
+	found := false
+	if !approvalTask.Status.StartTime.IsZero() {
+		waitTime = approvalTask.Status.StartTime.Add(timeout).Sub(now)
+		found = true
+	}
+	for _, next := range []func(v1alpha1.ApprovalTask, time.Time) (time.Time, bool){approval.NextExpiry, approval.NextQuorumChange, approval.NextLifecycleTransition} {
+		if at, ok := next(approvalTask, now); ok {
+			if until := at.Sub(now); !found || until < waitTime {
+				waitTime = until
+				found = true
+			}
+		}
+	}
+	if maxInterval > 0 && (!found || waitTime > maxInterval) {
+		waitTime = maxInterval
+		found = true
+	}
+	return waitTime, found
+}
+
+// findApproverState returns the recorded response of the named approver.
+func findApproverState(responses []v1alpha1.ApproverState, name, approverType string) (v1alpha1.ApproverState, bool) {
+	for _, response := range responses {
+		if response.Name == name && v1alpha1.DefaultedApproverType(response.Type) == approverType {
+			return response, true
+		}
+	}
+	return v1alpha1.ApproverState{}, false
+}
+
+// carryRespondedAt keeps the time a response was first observed as long as the
+// response has not changed. A lapsed approval that is still "approve" in the
+// spec keeps its original time too, so that only a renewal can revive it.
+func carryRespondedAt(previous *v1alpha1.ApproverState, response string, now time.Time) *metav1.Time {
+	if previous != nil && previous.RespondedAt != nil &&
+		(previous.Response == response || (previous.Response == pendingState && response == approvedState)) {
+		return previous.RespondedAt
+	}
+	respondedAt := metav1.NewTime(now)
+	return &respondedAt
+}
+
+// delegationBasis returns what entitled the user recorded in the DecidedBy
+// of the approver to decide on its behalf, or an empty string when the
+// approver decided itself.
+func delegationBasis(approver v1alpha1.ApproverDetails) string {
+	if approver.DecidedBy == "" || !slices.Contains(approver.Substitutes, approver.DecidedBy) {
+		return ""
+	}
+	return v1alpha1.DelegationBasisSubstitute
+}
+
+// carryIdempotencyKey returns the idempotency key to record with a response.
+// A response carried over from previous keeps its key; a newly observed one
+// takes the key of the update that is now on the approval task.
+func carryIdempotencyKey(approvalTask v1alpha1.ApprovalTask, previous *v1alpha1.ApproverState, respondedAt *metav1.Time) string {
+	if previous != nil && previous.RespondedAt != nil && previous.RespondedAt == respondedAt {
+		return previous.IdempotencyKey
+	}
+	return approvalTask.Annotations[v1alpha1.IdempotencyKeyAnnotationKey]
+}
+
+// releaseEscrowGroup marks the approval task approved only once every task sharing
+// its escrow group, as the escrow scope finds them, has independently reached
+// quorum, and then flips the pending siblings to approved as well so the whole
+// group finalizes together.
+func releaseEscrowGroup(ctx context.Context, approvaltaskClientSet versioned.Interface, escrow *escrowScope, approvalTask *v1alpha1.ApprovalTask, now time.Time) error {
+	logger := logging.FromContext(ctx)
+
+	siblings, err := escrow.siblings(approvalTask)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range siblings {
+		if task.Status.State == rejectedState || task.Status.State == withdrawnState || approvalTaskHasFalseInput(task) || !approval.QuorumReachedAt(task, now) {
+			logger.Infof("Approval task %s is waiting on escrow group %s", approvalTask.Name, approvalTask.Spec.EscrowGroup)
+			return nil
+		}
+	}
+
+	approvalTask.Status.State = approvedState
+	for i := range siblings {
+		if siblings[i].Status.State == approvedState {
+			continue
+		}
+		siblings[i].Status.State = approvedState
+		if _, err := approvaltaskClientSet.OpenshiftpipelinesV1alpha1().ApprovalTasks(siblings[i].Namespace).UpdateStatus(ctx, &siblings[i], metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+	logger.Infof("Escrow group %s released with %d approval tasks", approvalTask.Spec.EscrowGroup, len(siblings)+1)
+	return nil
+}
+
+// approversHash hashes what the responses of the approval task are computed
+// from: its approvers and, when some of them are conditional, its labels, so
+// that toggling a label recomputes the responses.
+func approversHash(approvalTask v1alpha1.ApprovalTask) (string, error) {
+	if !approval.HasConditionalApprovers(approvalTask) {
+		return Compute(approvalTask.Spec.Approvers)
+	}
+	return Compute(struct {
+		Approvers []v1alpha1.ApproverDetails `json:"approvers"`
+		Labels    map[string]string          `json:"labels"`
+	}{approvalTask.Spec.Approvers, approvalTask.Labels})
+}
+
 // Compute generates an unique hash/string for the object pass to it.
 // with sha256
 func Compute(obj interface{}) (string, error) {
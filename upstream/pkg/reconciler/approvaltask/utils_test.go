@@ -19,6 +19,7 @@ package approvaltask
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask"
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
@@ -27,6 +28,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/controller"
 )
 
 func TestCheckCustomRunReferencesApprovalTaskValidReferences(t *testing.T) {
@@ -103,6 +109,21 @@ func TestPropagateApprovalTaskLabelsAndAnnotations(t *testing.T) {
 	assert.Equal(t, len(run.Labels), 1)
 }
 
+func TestPropagateApprovalTaskLabelsSkipsAwaitingIndex(t *testing.T) {
+	run := &v1beta1.CustomRun{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo"}}
+	approvalTaskMeta := &metav1.ObjectMeta{
+		Name: "foo-bar",
+		Labels: map[string]string{
+			"team": "payments",
+			approvaltaskv1alpha1.AwaitingApproverLabelPrefix + "2bd806c97f0e00af": "true",
+			approvaltaskv1alpha1.AwaitingOverflowLabelKey:                         "true",
+		},
+	}
+
+	propagateApprovalTaskLabelsAndAnnotations(run, approvalTaskMeta)
+	assert.Equal(t, map[string]string{"team": "payments", "openshift-pipelines.org/approvaltask": "foo-bar"}, run.Labels)
+}
+
 func TestCreateApprovalTask(t *testing.T) {
 	run := &v1beta1.CustomRun{
 		ObjectMeta: metav1.ObjectMeta{
@@ -129,7 +150,7 @@ func TestCreateApprovalTask(t *testing.T) {
 
 	client := fake.NewSimpleClientset()
 
-	approvalTask, err := createApprovalTask(context.TODO(), client, run)
+	approvalTask, err := createApprovalTask(context.TODO(), client, run, nil, nil, false)
 	if err != nil {
 		t.Fatalf("createApprovalTask returned an error: %v", err)
 	}
@@ -173,7 +194,7 @@ func TestCreateApprovalTaskWithoutApprovalsRequiredProvided(t *testing.T) {
 
 	client := fake.NewSimpleClientset()
 
-	approvalTask, err := createApprovalTask(context.TODO(), client, run)
+	approvalTask, err := createApprovalTask(context.TODO(), client, run, nil, nil, false)
 	if err != nil {
 		t.Fatalf("createApprovalTask returned an error: %v", err)
 	}
@@ -210,7 +231,7 @@ func TestUpdateApprovalTaskFalseState(t *testing.T) {
 
 	client := fake.NewSimpleClientset()
 
-	approvalTask, err := createApprovalTask(context.TODO(), client, run)
+	approvalTask, err := createApprovalTask(context.TODO(), client, run, nil, nil, false)
 	if err != nil {
 		t.Fatalf("createApprovalTask returned an error: %v", err)
 	}
@@ -224,7 +245,7 @@ func TestUpdateApprovalTaskFalseState(t *testing.T) {
 		t.Fatalf("failed updating the input value for for")
 	}
 
-	at1, err := updateApprovalState(context.TODO(), client, at)
+	at1, err := updateApprovalState(context.TODO(), client, nil, clock.RealClock{}, nil, at)
 	if err != nil {
 		t.Fatalf("updateApprovalTask returned an error: %v", err)
 	}
@@ -260,7 +281,7 @@ func TestUpdateApprovalTaskWaitState(t *testing.T) {
 
 	client := fake.NewSimpleClientset()
 
-	approvalTask, err := createApprovalTask(context.TODO(), client, run)
+	approvalTask, err := createApprovalTask(context.TODO(), client, run, nil, nil, false)
 	if err != nil {
 		t.Fatalf("createApprovalTask returned an error: %v", err)
 	}
@@ -273,7 +294,7 @@ func TestUpdateApprovalTaskWaitState(t *testing.T) {
 		t.Fatalf("failed updating the input value for for")
 	}
 
-	at1, err := updateApprovalState(context.TODO(), client, at)
+	at1, err := updateApprovalState(context.TODO(), client, nil, clock.RealClock{}, nil, at)
 	if err != nil {
 		t.Fatalf("updateApprovalTask returned an error: %v", err)
 	}
@@ -311,7 +332,7 @@ func TestUpdateApprovalTaskTrueState(t *testing.T) {
 
 	client := fake.NewSimpleClientset()
 
-	approvalTask, err := createApprovalTask(context.TODO(), client, run)
+	approvalTask, err := createApprovalTask(context.TODO(), client, run, nil, nil, false)
 	if err != nil {
 		t.Fatalf("createApprovalTask returned an error: %v", err)
 	}
@@ -326,7 +347,7 @@ func TestUpdateApprovalTaskTrueState(t *testing.T) {
 		t.Fatalf("failed updating the input value for for")
 	}
 
-	at1, err := updateApprovalState(context.TODO(), client, at)
+	at1, err := updateApprovalState(context.TODO(), client, nil, clock.RealClock{}, nil, at)
 	if err != nil {
 		t.Fatalf("updateApprovalTask returned an error: %v", err)
 	}
@@ -352,7 +373,7 @@ func TestUpdateApprovalTaskWithNoApprovalsProvided(t *testing.T) {
 
 	client := fake.NewSimpleClientset()
 
-	approvalTask, err := createApprovalTask(context.TODO(), client, run)
+	approvalTask, err := createApprovalTask(context.TODO(), client, run, nil, nil, false)
 	if err != nil {
 		t.Fatalf("createApprovalTask returned an error: %v", err)
 	}
@@ -372,7 +393,7 @@ func TestUpdateApprovalTaskWithNoApprovalsProvided(t *testing.T) {
 		t.Fatalf("failed updating the input value for for")
 	}
 
-	at1, err := updateApprovalState(context.TODO(), client, at)
+	at1, err := updateApprovalState(context.TODO(), client, nil, clock.RealClock{}, nil, at)
 	if err != nil {
 		t.Fatalf("updateApprovalTask returned an error: %v", err)
 	}
@@ -382,6 +403,21 @@ func TestUpdateApprovalTaskWithNoApprovalsProvided(t *testing.T) {
 	assert.Equal(t, len(at1.Status.ApproversResponse), 1, "foo has approved it")
 }
 
+func TestCreateApprovalTaskWithApprovalPercentage(t *testing.T) {
+	run := approvalTaskRun()
+	run.Spec.Params = []v1beta1.Param{
+		{Name: "approvers", Value: *v1beta1.NewArrayOrString("alice", "bob", "carol")},
+		{Name: "approvalPercentage", Value: *v1beta1.NewArrayOrString("50")},
+	}
+	client := fake.NewSimpleClientset()
+
+	approvalTask, err := createApprovalTask(context.TODO(), client, run, nil, nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 50, approvalTask.Spec.ApprovalPercentage)
+	assert.Zero(t, approvalTask.Spec.NumberOfApprovalsRequired, "the percentage replaces the default count")
+	assert.Equal(t, 2, approvalTask.Status.ApprovalsRequired, "50% of 3 approvers rounds up to 2")
+}
+
 func TestApprovalTaskHasFalseInputWithOneApproval(t *testing.T) {
 	approvaltask := v1alpha1.ApprovalTask{
 		ObjectMeta: metav1.ObjectMeta{
@@ -660,6 +696,21 @@ func TestValidateCustomRunParameters(t *testing.T) {
 		expectError bool
 		errorMsg    string
 	}{
+		{
+			name: "approvers from a PipelineRun result",
+			params: []v1beta1.Param{
+				{
+					Name:  "approvers",
+					Value: *v1beta1.NewArrayOrString("user1"),
+				},
+				{
+					Name:  "approversFromPipelineRun",
+					Value: *v1beta1.NewArrayOrString("results.oncall"),
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid approversFromPipelineRun parameter: 'results.oncall' names a result of the PipelineRun, which is only set once it completes; pass the task result as the approvers param instead",
+		},
 		{
 			name: "invalid group with space",
 			params: []v1beta1.Param{
@@ -774,6 +825,175 @@ func TestValidateCustomRunParameters(t *testing.T) {
 			expectError: true,
 			errorMsg:    "invalid approvers parameter: approvers[1]: invalid object format {\"invalid\":\"format\"} - approver must be a string, not an object",
 		},
+		{
+			name: "user and group with the same name",
+			params: []v1beta1.Param{
+				{
+					Name:  "approvers",
+					Value: *v1beta1.NewArrayOrString("release", "alice", "group:release"),
+				},
+				{
+					Name:  "numberOfApprovalsRequired",
+					Value: *v1beta1.NewArrayOrString("1"),
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid approvers parameter: approvers[2]: group 'release' has the same name as the user at approvers[0]",
+		},
+		{
+			name: "group and user with the same name",
+			params: []v1beta1.Param{
+				{
+					Name:  "approvers",
+					Value: *v1beta1.NewArrayOrString("group:release", "release"),
+				},
+				{
+					Name:  "numberOfApprovalsRequired",
+					Value: *v1beta1.NewArrayOrString("1"),
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid approvers parameter: approvers[1]: user 'release' has the same name as the group at approvers[0]",
+		},
+		{
+			name: "negative minApprovingTeams",
+			params: []v1beta1.Param{
+				{
+					Name:  "approvers",
+					Value: *v1beta1.NewArrayOrString("user1", "user2"),
+				},
+				{
+					Name:  "minApprovingTeams",
+					Value: *v1beta1.NewArrayOrString("-1"),
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid minApprovingTeams parameter: must not be negative, got -1",
+		},
+		{
+			name: "non-integer minApproverLevel",
+			params: []v1beta1.Param{
+				{
+					Name:  "approvers",
+					Value: *v1beta1.NewArrayOrString("user1", "user2"),
+				},
+				{
+					Name:  "minApproverLevel",
+					Value: *v1beta1.NewArrayOrString("senior"),
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid minApproverLevel parameter: 'senior' is not a valid integer",
+		},
+		{
+			name: "approvalPercentage out of range",
+			params: []v1beta1.Param{
+				{
+					Name:  "approvers",
+					Value: *v1beta1.NewArrayOrString("user1", "user2"),
+				},
+				{
+					Name:  "approvalPercentage",
+					Value: *v1beta1.NewArrayOrString("150"),
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid approvalPercentage parameter: must be between 1 and 100, got 150",
+		},
+		{
+			name: "approvalPercentage with numberOfApprovalsRequired",
+			params: []v1beta1.Param{
+				{
+					Name:  "approvers",
+					Value: *v1beta1.NewArrayOrString("user1", "user2"),
+				},
+				{
+					Name:  "numberOfApprovalsRequired",
+					Value: *v1beta1.NewArrayOrString("1"),
+				},
+				{
+					Name:  "approvalPercentage",
+					Value: *v1beta1.NewArrayOrString("50"),
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid approvalPercentage parameter: cannot be combined with numberOfApprovalsRequired",
+		},
+		{
+			name: "negative ttlSecondsAfterFinished",
+			params: []v1beta1.Param{
+				{
+					Name:  "approvers",
+					Value: *v1beta1.NewArrayOrString("user1", "user2"),
+				},
+				{
+					Name:  "ttlSecondsAfterFinished",
+					Value: *v1beta1.NewArrayOrString("-60"),
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid ttlSecondsAfterFinished parameter: must not be negative, got -60",
+		},
+		{
+			name: "unknown mixedResolution",
+			params: []v1beta1.Param{
+				{
+					Name:  "approvers",
+					Value: *v1beta1.NewArrayOrString("user1", "user2"),
+				},
+				{
+					Name:  "mixedResolution",
+					Value: *v1beta1.NewArrayOrString("Majority"),
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid mixedResolution parameter: must be one of WaitForAll, FailFast, got 'Majority'",
+		},
+		{
+			name: "invalid countRequesterApproval",
+			params: []v1beta1.Param{
+				{
+					Name:  "approvers",
+					Value: *v1beta1.NewArrayOrString("user1", "user2"),
+				},
+				{
+					Name:  "countRequesterApproval",
+					Value: *v1beta1.NewArrayOrString("sometimes"),
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid countRequesterApproval parameter: 'sometimes' is not a valid boolean",
+		},
+		{
+			name: "requireDigestAcknowledgment without expectedDigest",
+			params: []v1beta1.Param{
+				{
+					Name:  "approvers",
+					Value: *v1beta1.NewArrayOrString("user1", "user2"),
+				},
+				{
+					Name:  "requireDigestAcknowledgment",
+					Value: *v1beta1.NewArrayOrString("true"),
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid requireDigestAcknowledgment parameter: requires the expectedDigest parameter",
+		},
+		{
+			name: "invalid minReviewDuration",
+			params: []v1beta1.Param{
+				{
+					Name:  "approvers",
+					Value: *v1beta1.NewArrayOrString("user1", "user2"),
+				},
+				{
+					Name:  "minReviewDuration",
+					Value: *v1beta1.NewArrayOrString("a day"),
+				},
+			},
+			expectError: true,
+			errorMsg:    "invalid minReviewDuration parameter: 'a day' is not a valid duration",
+		},
 		{
 			name: "valid parameters",
 			params: []v1beta1.Param{
@@ -817,3 +1037,785 @@ func TestValidateCustomRunParameters(t *testing.T) {
 }
 
 
+
+func newEscrowedApprovalTask(t *testing.T, client *fake.Clientset, namespace, name, input string) *v1alpha1.ApprovalTask {
+	t.Helper()
+	at := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: input},
+			},
+			NumberOfApprovalsRequired: 1,
+			EscrowGroup:               "release",
+		},
+		Status: v1alpha1.ApprovalTaskStatus{
+			State: "pending",
+		},
+	}
+	created, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks(namespace).Create(context.TODO(), at, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("failed creating approval task %s/%s: %v", namespace, name, err)
+	}
+	return created
+}
+
+func approveEscrowedApprovalTask(t *testing.T, client *fake.Clientset, at *v1alpha1.ApprovalTask) v1alpha1.ApprovalTask {
+	t.Helper()
+	at.Spec.Approvers[0].Input = "approve"
+	updated, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks(at.Namespace).Update(context.TODO(), at, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("failed updating approval task %s/%s: %v", at.Namespace, at.Name, err)
+	}
+	escrow := escrowScopeOf(t, client, sharingNamespaces("release", "dev", "staging", "production")...)
+	result, err := updateApprovalState(context.TODO(), client, escrow, clock.RealClock{}, nil, updated)
+	if err != nil {
+		t.Fatalf("updateApprovalState returned an error: %v", err)
+	}
+	return result
+}
+
+func escrowedApprovalTaskState(t *testing.T, client *fake.Clientset, namespace, name string) string {
+	t.Helper()
+	at, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed getting approval task %s/%s: %v", namespace, name, err)
+	}
+	return at.Status.State
+}
+
+func TestUpdateApprovalStateEscrowGroupOfTwo(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	first := newEscrowedApprovalTask(t, client, "staging", "deploy", "pending")
+	second := newEscrowedApprovalTask(t, client, "production", "deploy", "pending")
+
+	at := approveEscrowedApprovalTask(t, client, first)
+	assert.Equal(t, "pending", at.Status.State, "escrowed task should wait for the rest of the group")
+	assert.Equal(t, 1, at.Status.ApprovalsReceived)
+
+	at = approveEscrowedApprovalTask(t, client, second)
+	assert.Equal(t, "approved", at.Status.State)
+	assert.Equal(t, "approved", escrowedApprovalTaskState(t, client, "staging", "deploy"), "sibling should be released together")
+}
+
+func TestUpdateApprovalStateEscrowGroupOfThree(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	first := newEscrowedApprovalTask(t, client, "dev", "deploy", "pending")
+	second := newEscrowedApprovalTask(t, client, "staging", "deploy", "pending")
+	third := newEscrowedApprovalTask(t, client, "production", "deploy", "pending")
+
+	assert.Equal(t, "pending", approveEscrowedApprovalTask(t, client, first).Status.State)
+	assert.Equal(t, "pending", approveEscrowedApprovalTask(t, client, third).Status.State)
+	assert.Equal(t, "pending", escrowedApprovalTaskState(t, client, "dev", "deploy"))
+
+	assert.Equal(t, "approved", approveEscrowedApprovalTask(t, client, second).Status.State)
+	assert.Equal(t, "approved", escrowedApprovalTaskState(t, client, "dev", "deploy"))
+	assert.Equal(t, "approved", escrowedApprovalTaskState(t, client, "production", "deploy"))
+}
+
+func TestUpdateApprovalStateEscrowGroupWithRejectedSibling(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	newEscrowedApprovalTask(t, client, "staging", "deploy", "reject")
+	second := newEscrowedApprovalTask(t, client, "production", "deploy", "pending")
+
+	at := approveEscrowedApprovalTask(t, client, second)
+	assert.Equal(t, "pending", at.Status.State, "a rejected sibling must keep the escrow group from releasing")
+}
+
+func newExpiringApprovalTask(t *testing.T, client *fake.Clientset, approvers ...v1alpha1.ApproverDetails) *v1alpha1.ApprovalTask {
+	t.Helper()
+	at := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "deploy",
+			Namespace: "production",
+		},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			Approvers:                 approvers,
+			NumberOfApprovalsRequired: 1,
+		},
+		Status: v1alpha1.ApprovalTaskStatus{
+			State: "pending",
+		},
+	}
+	created, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks(at.Namespace).Create(context.TODO(), at, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("failed creating approval task: %v", err)
+	}
+	return created
+}
+
+func TestUpdateApprovalStateExpiresLapsedUserApproval(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	fakeClock := clocktesting.NewFakePassiveClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	at := newExpiringApprovalTask(t, client, v1alpha1.ApproverDetails{
+		Name:                 "alice",
+		Type:                 "User",
+		Input:                "approve",
+		ApprovalExpiresAfter: &metav1.Duration{Duration: time.Hour},
+	})
+
+	result, err := updateApprovalState(context.TODO(), client, nil, fakeClock, nil, at)
+	assert.NoError(t, err)
+	assert.Equal(t, "approved", result.Status.State)
+
+	// Recompute as if the task had not been finalized yet, once before and once after expiry.
+	result.Status.State = "pending"
+	fakeClock.SetTime(fakeClock.Now().Add(59 * time.Minute))
+	result, err = updateApprovalState(context.TODO(), client, nil, fakeClock, nil, &result)
+	assert.NoError(t, err)
+	assert.Equal(t, "approved", result.Status.State)
+	assert.Equal(t, 1, result.Status.ApprovalsReceived)
+
+	result.Status.State = "pending"
+	fakeClock.SetTime(fakeClock.Now().Add(2 * time.Minute))
+	result, err = updateApprovalState(context.TODO(), client, nil, fakeClock, nil, &result)
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", result.Status.State, "lapsed approval should revert to pending")
+	assert.Equal(t, 0, result.Status.ApprovalsReceived)
+	assert.Equal(t, "pending", result.Status.ApproversResponse[0].Response)
+
+	// A lapsed approval stays lapsed on subsequent reconciles until it is renewed.
+	result, err = updateApprovalState(context.TODO(), client, nil, fakeClock, nil, &result)
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", result.Status.State)
+
+	renewTime := metav1.NewTime(fakeClock.Now())
+	result.Spec.Approvers[0].RenewTime = &renewTime
+	result, err = updateApprovalState(context.TODO(), client, nil, fakeClock, nil, &result)
+	assert.NoError(t, err)
+	assert.Equal(t, "approved", result.Status.State, "renewed approval should count again")
+}
+
+func TestUpdateApprovalStateExpiresLapsedGroupMemberApproval(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	fakeClock := clocktesting.NewFakePassiveClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	at := newExpiringApprovalTask(t, client, v1alpha1.ApproverDetails{
+		Name:                 "platform",
+		Type:                 "Group",
+		Input:                "approve",
+		ApprovalExpiresAfter: &metav1.Duration{Duration: time.Hour},
+		Users:                []v1alpha1.UserDetails{{Name: "bob", Input: "approve"}},
+	})
+
+	result, err := updateApprovalState(context.TODO(), client, nil, fakeClock, nil, at)
+	assert.NoError(t, err)
+	assert.Equal(t, "approved", result.Status.State)
+
+	result.Status.State = "pending"
+	fakeClock.SetTime(fakeClock.Now().Add(2 * time.Hour))
+	result, err = updateApprovalState(context.TODO(), client, nil, fakeClock, nil, &result)
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", result.Status.State)
+	assert.Equal(t, "pending", result.Status.ApproversResponse[0].GroupMembers[0].Response)
+}
+
+func TestNextRequeue(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	startTime := metav1.NewTime(now.Add(-10 * time.Minute))
+	respondedAt := metav1.NewTime(now.Add(-5 * time.Minute))
+	pending := v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve", ApprovalExpiresAfter: &metav1.Duration{Duration: 15 * time.Minute}},
+				{Name: "bob", Type: "User", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{
+			State:             "pending",
+			StartTime:         &startTime,
+			ApproversResponse: []v1alpha1.ApproverState{{Name: "alice", Type: "User", Response: "approved", RespondedAt: &respondedAt}},
+		},
+	}
+	notExpiring := pending.DeepCopy()
+	notExpiring.Spec.Approvers[0].ApprovalExpiresAfter = nil
+	notStarted := notExpiring.DeepCopy()
+	notStarted.Status.StartTime = nil
+	scheduled := notExpiring.DeepCopy()
+	scheduled.Spec.QuorumSchedule = []v1alpha1.QuorumStep{{After: metav1.Duration{Duration: 30 * time.Minute}, NumberOfApprovalsRequired: 1}}
+
+	tests := []struct {
+		name        string
+		task        v1alpha1.ApprovalTask
+		maxInterval time.Duration
+		want        time.Duration
+		wantOK      bool
+	}{
+		{name: "timeout only", task: *notExpiring, want: 50 * time.Minute, wantOK: true},
+		{name: "approval lapses before the timeout", task: pending, want: 10 * time.Minute, wantOK: true},
+		{name: "capped by the max interval", task: pending, maxInterval: time.Minute, want: time.Minute, wantOK: true},
+		{name: "max interval above the next event", task: pending, maxInterval: time.Hour, want: 10 * time.Minute, wantOK: true},
+		{name: "no time event", task: *notStarted},
+		{name: "no time event but a max interval", task: *notStarted, maxInterval: time.Minute, want: time.Minute, wantOK: true},
+		{name: "quorum schedule step before the timeout", task: *scheduled, want: 20 * time.Minute, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := nextRequeue(tt.task, time.Hour, now, tt.maxInterval)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReconcileDoesNotRequeueFinalTask(t *testing.T) {
+	fakeClock := clocktesting.NewFakePassiveClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	client := fake.NewSimpleClientset()
+	r := &Reconciler{clock: fakeClock, approvaltaskClientSet: client, maxRequeueInterval: time.Minute}
+	run := &v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1beta1.CustomRunSpec{
+			CustomRef: &v1beta1.TaskRef{
+				APIVersion: approvaltaskv1alpha1.SchemeGroupVersion.String(),
+				Kind:       approvaltask.ControllerName,
+			},
+			Params: []v1beta1.Param{
+				{Name: "approvers", Value: *v1beta1.NewArrayOrString("alice", "bob")},
+				{Name: "numberOfApprovalsRequired", Value: *v1beta1.NewArrayOrString("1")},
+			},
+		},
+	}
+	run.Status.InitializeConditions()
+
+	err := r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{})
+	requeue, wait := controller.IsRequeueKey(err)
+	assert.True(t, requeue, "pending task should be requeued")
+	assert.Equal(t, time.Minute, wait)
+
+	at, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	at.Spec.Approvers[0].Input = "approve"
+	_, err = client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Update(context.TODO(), at, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{}), "final task should not be requeued")
+	assert.True(t, run.IsDone())
+}
+
+func TestCreateApprovalTaskWithExpectedDigest(t *testing.T) {
+	run := &v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "bar",
+			Namespace:   "foo",
+			Annotations: map[string]string{v1alpha1.CurrentDigestAnnotationKey: "sha256:aaaa"},
+		},
+		Spec: v1beta1.CustomRunSpec{
+			Params: []v1beta1.Param{
+				{Name: "approvers", Value: *v1beta1.NewArrayOrString("foo", "bar")},
+				{Name: "expectedDigest", Value: *v1beta1.NewArrayOrString("sha256:aaaa")},
+			},
+		},
+	}
+
+	approvalTask, err := createApprovalTask(context.TODO(), fake.NewSimpleClientset(), run, nil, nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:aaaa", approvalTask.Spec.ExpectedDigest)
+	assert.Equal(t, "sha256:aaaa", approvalTask.Annotations[v1alpha1.CurrentDigestAnnotationKey])
+}
+
+func TestCreateApprovalTaskCorrelationID(t *testing.T) {
+	run := &v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "bar",
+			Namespace:   "foo",
+			Annotations: map[string]string{v1alpha1.CorrelationIDAnnotationKey: "release-42"},
+		},
+		Spec: v1beta1.CustomRunSpec{
+			Params: []v1beta1.Param{{Name: "approvers", Value: *v1beta1.NewArrayOrString("foo", "bar")}},
+		},
+	}
+	approvalTask, err := createApprovalTask(context.TODO(), fake.NewSimpleClientset(), run, nil, nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "release-42", approvalTask.Annotations[v1alpha1.CorrelationIDAnnotationKey])
+
+	// Runs without one get a generated correlation ID
+	run.Annotations = nil
+	approvalTask, err = createApprovalTask(context.TODO(), fake.NewSimpleClientset(), run, nil, nil, false)
+	assert.NoError(t, err)
+	assert.Regexp(t, "^[0-9a-f]{32}$", approvalTask.Annotations[v1alpha1.CorrelationIDAnnotationKey])
+}
+
+func TestReconcileRejectsUnsatisfiableTask(t *testing.T) {
+	fakeClock := clocktesting.NewFakePassiveClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	client := fake.NewSimpleClientset()
+	r := &Reconciler{clock: fakeClock, approvaltaskClientSet: client}
+	run := &v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1beta1.CustomRunSpec{
+			CustomRef: &v1beta1.TaskRef{
+				APIVersion: approvaltaskv1alpha1.SchemeGroupVersion.String(),
+				Kind:       approvaltask.ControllerName,
+			},
+			Params: []v1beta1.Param{
+				{Name: "approvers", Value: *v1beta1.NewArrayOrString("alice", "bob")},
+				{Name: "numberOfApprovalsRequired", Value: *v1beta1.NewArrayOrString("2")},
+			},
+		},
+	}
+	run.Status.InitializeConditions()
+
+	_ = r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{})
+	assert.False(t, run.IsDone(), "two eligible approvers can meet the quorum")
+
+	// bob becomes a blocker who can only reject: the quorum is out of reach
+	at, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	at.Spec.Approvers[1].AllowedInputs = []string{"reject"}
+	_, err = client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Update(context.TODO(), at, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{}))
+	assert.True(t, run.IsDone())
+	condition := run.Status.GetCondition(apis.ConditionSucceeded)
+	assert.Equal(t, v1alpha1.ApprovalTaskRunReasonUnsatisfiable.String(), condition.Reason)
+	assert.Equal(t, "Approval task deploy can no longer be approved: at most 1 of the 2 required approvals can still be given", condition.Message)
+
+	at, err = client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "rejected", at.Status.State)
+}
+
+func TestReconcileLeavesPausedUnsatisfiableTaskPending(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Paused:                    true,
+			Approvers:                 []v1alpha1.ApproverDetails{{Name: "alice", Type: "User", Input: "pending"}},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	})
+	r := &Reconciler{clock: clocktesting.NewFakePassiveClock(time.Now()), approvaltaskClientSet: client}
+	run := &v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1beta1.CustomRunSpec{
+			CustomRef: &v1beta1.TaskRef{
+				APIVersion: approvaltaskv1alpha1.SchemeGroupVersion.String(),
+				Kind:       approvaltask.ControllerName,
+			},
+		},
+	}
+	run.Status.InitializeConditions()
+
+	_ = r.reconcile(context.TODO(), run, &v1alpha1.ApprovalTaskRunStatus{})
+	assert.False(t, run.IsDone())
+}
+
+func TestUpdateApprovalStateRelaxesQuorumOverTime(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	fakeClock := clocktesting.NewFakePassiveClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	at := newExpiringApprovalTask(t, client,
+		v1alpha1.ApproverDetails{Name: "alice", Type: "User", Input: "approve"},
+		v1alpha1.ApproverDetails{Name: "bob", Type: "User", Input: "pending"},
+	)
+	startTime := metav1.NewTime(fakeClock.Now())
+	at.Status.StartTime = &startTime
+	at.Spec.NumberOfApprovalsRequired = 2
+	at.Spec.QuorumSchedule = []v1alpha1.QuorumStep{{After: metav1.Duration{Duration: time.Hour}, NumberOfApprovalsRequired: 1}}
+
+	result, err := updateApprovalState(context.TODO(), client, nil, fakeClock, nil, at)
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", result.Status.State)
+	assert.Equal(t, 2, result.Status.ApprovalsRequired)
+
+	fakeClock.SetTime(fakeClock.Now().Add(59 * time.Minute))
+	result, err = updateApprovalState(context.TODO(), client, nil, fakeClock, nil, &result)
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", result.Status.State)
+
+	fakeClock.SetTime(fakeClock.Now().Add(time.Minute))
+	result, err = updateApprovalState(context.TODO(), client, nil, fakeClock, nil, &result)
+	assert.NoError(t, err)
+	assert.Equal(t, "approved", result.Status.State, "one approval is enough once the schedule relaxes")
+	assert.Equal(t, 1, result.Status.ApprovalsRequired)
+}
+
+func corruptedApprovalTask(t *testing.T) *v1alpha1.ApprovalTask {
+	t.Helper()
+	approvers := []v1alpha1.ApproverDetails{
+		{Name: "alice", Type: "User", Input: "approve"},
+		{Name: "carol", Type: "User", Input: "pending"},
+		{Name: "platform", Type: "Group", Input: "approve", Users: []v1alpha1.UserDetails{{Name: "bob", Input: "approve"}}},
+	}
+	hash, err := Compute(approvers)
+	assert.NoError(t, err)
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production", Annotations: map[string]string{LastAppliedHashKey: hash}},
+		Spec:       v1alpha1.ApprovalTaskSpec{NumberOfApprovalsRequired: 3, Approvers: approvers},
+		Status: v1alpha1.ApprovalTaskStatus{
+			State:             "approved",
+			ApprovalsRequired: 3,
+			ApprovalsReceived: 4,
+			ApproversResponse: []v1alpha1.ApproverState{
+				{Name: "alice", Type: "User", Response: "approved"},
+				{Name: "mallory", Type: "User", Response: "approved"},
+				{Name: "platform", Type: "Group", Response: "approved", GroupMembers: []v1alpha1.GroupMemberState{
+					{Name: "bob", Response: "approved"},
+					{Name: "eve", Response: "approved"},
+				}},
+			},
+		},
+	}
+}
+
+func approvalTaskRun() *v1beta1.CustomRun {
+	run := &v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1beta1.CustomRunSpec{
+			CustomRef: &v1beta1.TaskRef{
+				APIVersion: approvaltaskv1alpha1.SchemeGroupVersion.String(),
+				Kind:       approvaltask.ControllerName,
+			},
+		},
+	}
+	run.Status.InitializeConditions()
+	return run
+}
+
+func TestInconsistentResponses(t *testing.T) {
+	assert.Equal(t, []string{
+		"response of User approver 'mallory' does not match any approver",
+		"response of group 'platform' lists 'eve', who is not one of its users",
+	}, inconsistentResponses(*corruptedApprovalTask(t)))
+
+	consistent := corruptedApprovalTask(t)
+	consistent.Status.ApproversResponse = consistent.Status.ApproversResponse[:1]
+	assert.Empty(t, inconsistentResponses(*consistent))
+}
+
+func TestReconcileRepairsInconsistentResponses(t *testing.T) {
+	client := fake.NewSimpleClientset(corruptedApprovalTask(t))
+	r := &Reconciler{clock: clocktesting.NewFakePassiveClock(time.Now()), approvaltaskClientSet: client}
+	recorder := record.NewFakeRecorder(10)
+	ctx := controller.WithEventRecorder(context.TODO(), recorder)
+	run := approvalTaskRun()
+
+	_ = r.reconcile(ctx, run, &v1alpha1.ApprovalTaskRunStatus{})
+	assert.False(t, run.IsDone())
+
+	at, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", at.Status.State, "alice and bob alone do not reach the quorum of 3")
+	assert.Equal(t, 2, at.Status.ApprovalsReceived)
+	assert.Equal(t, []v1alpha1.ApproverState{
+		{Name: "alice", Type: "User", Response: "approved"},
+		{Name: "platform", Type: "Group", Response: "approved", GroupMembers: []v1alpha1.GroupMemberState{{Name: "bob", Response: "approved"}}},
+	}, at.Status.ApproversResponse)
+
+	assert.Len(t, recorder.Events, 1)
+	event := <-recorder.Events
+	assert.Contains(t, event, "Warning ResponsesRepaired Repaired approval task deploy")
+	assert.Contains(t, event, "'mallory' does not match any approver")
+}
+
+func TestReconcileRejectsInconsistentResponses(t *testing.T) {
+	client := fake.NewSimpleClientset(corruptedApprovalTask(t))
+	r := &Reconciler{clock: clocktesting.NewFakePassiveClock(time.Now()), approvaltaskClientSet: client, rejectInconsistentResponses: true}
+	recorder := record.NewFakeRecorder(10)
+	ctx := controller.WithEventRecorder(context.TODO(), recorder)
+	run := approvalTaskRun()
+
+	assert.NoError(t, r.reconcile(ctx, run, &v1alpha1.ApprovalTaskRunStatus{}))
+	assert.True(t, run.IsDone())
+	condition := run.Status.GetCondition(apis.ConditionSucceeded)
+	assert.Equal(t, v1alpha1.ApprovalTaskRunReasonInconsistentStatus.String(), condition.Reason)
+
+	at, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks("production").Get(context.TODO(), "deploy", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "rejected", at.Status.State)
+	assert.Len(t, recorder.Events, 2)
+	assert.Contains(t, <-recorder.Events, "Warning ResponsesRejected")
+	assert.Contains(t, <-recorder.Events, "Normal Decided Approval task deploy was decided: rejected")
+}
+
+func TestUpdateApprovalStateFollowsApproverConditions(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	fakeClock := clocktesting.NewFakePassiveClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	at := newExpiringApprovalTask(t, client,
+		v1alpha1.ApproverDetails{Name: "alice", Type: "User", Input: "approve"},
+		v1alpha1.ApproverDetails{Name: "security", Type: "User", Input: "reject", WhenLabels: map[string]string{"risk": "high"}},
+	)
+
+	result, err := updateApprovalState(context.TODO(), client, nil, fakeClock, nil, at)
+	assert.NoError(t, err)
+	assert.Equal(t, "approved", result.Status.State, "the rejection of an inactive approver must not count")
+	assert.Equal(t, []v1alpha1.ApproverState{{Name: "alice", Type: "User", Response: "approved", RespondedAt: result.Status.ApproversResponse[0].RespondedAt}},
+		result.Status.ApproversResponse)
+
+	result.Status.State = "pending"
+	result.Labels = map[string]string{"risk": "high"}
+	result, err = updateApprovalState(context.TODO(), client, nil, fakeClock, nil, &result)
+	assert.NoError(t, err)
+	assert.Equal(t, "rejected", result.Status.State, "the security approver takes part once the label is set")
+	assert.Len(t, result.Status.ApproversResponse, 2)
+}
+
+func TestUpdateApprovalStateDoesNotCountRequester(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	fakeClock := clocktesting.NewFakePassiveClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	at := newExpiringApprovalTask(t, client,
+		v1alpha1.ApproverDetails{Name: "alice", Type: "User", Input: "approve"},
+		v1alpha1.ApproverDetails{Name: "bob", Type: "User", Input: "pending"},
+	)
+	at.Annotations = map[string]string{v1alpha1.CreatedByAnnotationKey: "alice"}
+
+	result, err := updateApprovalState(context.TODO(), client, nil, fakeClock, nil, at)
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", result.Status.State, "the requester approving does not reach the quorum")
+	assert.Equal(t, 0, result.Status.ApprovalsReceived)
+	assert.Equal(t, "approved", result.Status.ApproversResponse[0].Response, "the approval of the requester is still recorded")
+
+	optOut := result.DeepCopy()
+	optOut.Spec.CountRequesterApproval = true
+	result, err = updateApprovalState(context.TODO(), client, nil, fakeClock, nil, optOut)
+	assert.NoError(t, err)
+	assert.Equal(t, "approved", result.Status.State)
+	assert.Equal(t, 1, result.Status.ApprovalsReceived)
+}
+
+func TestCreateApprovalTaskCountingRequester(t *testing.T) {
+	run := &v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo"},
+		Spec: v1beta1.CustomRunSpec{
+			Params: []v1beta1.Param{
+				{Name: "approvers", Value: *v1beta1.NewArrayOrString("foo", "bar")},
+				{Name: "countRequesterApproval", Value: *v1beta1.NewArrayOrString("true")},
+			},
+		},
+	}
+
+	approvalTask, err := createApprovalTask(context.TODO(), fake.NewSimpleClientset(), run, nil, nil, false)
+	assert.NoError(t, err)
+	assert.True(t, approvalTask.Spec.CountRequesterApproval)
+}
+
+func TestCreateApprovalTaskRequiringDigestAcknowledgment(t *testing.T) {
+	run := &v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo"},
+		Spec: v1beta1.CustomRunSpec{
+			Params: []v1beta1.Param{
+				{Name: "approvers", Value: *v1beta1.NewArrayOrString("foo", "bar")},
+				{Name: "expectedDigest", Value: *v1beta1.NewArrayOrString("sha256:4c1e2f")},
+				{Name: "requireDigestAcknowledgment", Value: *v1beta1.NewArrayOrString("true")},
+			},
+		},
+	}
+	assert.NoError(t, ValidateCustomRunParameters(run))
+
+	approvalTask, err := createApprovalTask(context.TODO(), fake.NewSimpleClientset(), run, nil, nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:4c1e2f", approvalTask.Spec.ExpectedDigest)
+	assert.True(t, approvalTask.Spec.RequireDigestAcknowledgment)
+}
+
+func TestApproversHashCoversConditionLabels(t *testing.T) {
+	at := v1alpha1.ApprovalTask{Spec: v1alpha1.ApprovalTaskSpec{Approvers: []v1alpha1.ApproverDetails{{Name: "alice", Type: "User", Input: "pending"}}}}
+	unconditional, err := approversHash(at)
+	assert.NoError(t, err)
+	plain, err := Compute(at.Spec.Approvers)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, unconditional, "tasks without conditions keep their hash")
+
+	at.Spec.Approvers[0].WhenLabels = map[string]string{"risk": "high"}
+	before, err := approversHash(at)
+	assert.NoError(t, err)
+	at.Labels = map[string]string{"risk": "high"}
+	after, err := approversHash(at)
+	assert.NoError(t, err)
+	assert.NotEqual(t, before, after, "toggling a label must trigger a recompute")
+}
+
+func TestUpdateApprovalStateRecordsIdempotencyKeys(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	fakeClock := clocktesting.NewFakePassiveClock(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	at := newExpiringApprovalTask(t, client,
+		v1alpha1.ApproverDetails{Name: "alice", Type: "User", Input: "approve"},
+		v1alpha1.ApproverDetails{Name: "platform", Type: "Group", Input: "pending", Users: []v1alpha1.UserDetails{{Name: "bob", Input: "pending"}}},
+	)
+	at.Spec.NumberOfApprovalsRequired = 2
+	at.Annotations = map[string]string{v1alpha1.IdempotencyKeyAnnotationKey: "alice-1"}
+
+	result, err := updateApprovalState(context.TODO(), client, nil, fakeClock, nil, at)
+	assert.NoError(t, err)
+	alice, _ := findApproverState(result.Status.ApproversResponse, "alice", "User")
+	assert.Equal(t, "alice-1", alice.IdempotencyKey)
+
+	// A later decision by someone else does not take over the key of alice's response
+	result.Annotations[v1alpha1.IdempotencyKeyAnnotationKey] = "bob-1"
+	result.Spec.Approvers[1].Input = "approve"
+	result.Spec.Approvers[1].Users[0].Input = "approve"
+	result, err = updateApprovalState(context.TODO(), client, nil, fakeClock, nil, &result)
+	assert.NoError(t, err)
+	alice, _ = findApproverState(result.Status.ApproversResponse, "alice", "User")
+	assert.Equal(t, "alice-1", alice.IdempotencyKey)
+	platform, _ := findApproverState(result.Status.ApproversResponse, "platform", "Group")
+	assert.Equal(t, []v1alpha1.GroupMemberState{{Name: "bob", Response: "approved", RespondedAt: platform.GroupMembers[0].RespondedAt, IdempotencyKey: "bob-1"}},
+		platform.GroupMembers)
+}
+
+func TestUpdateApprovalStateLinksSubstituteToPrincipal(t *testing.T) {
+	approvalTask := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve", Substitutes: []string{"bob"}, DecidedBy: "bob"},
+				{Name: "lead", Type: "User", Input: "approve"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+	client := fake.NewSimpleClientset(approvalTask)
+
+	result, err := updateApprovalState(context.TODO(), client, nil, clocktesting.NewFakePassiveClock(time.Now()), nil, approvalTask)
+	assert.NoError(t, err)
+	responses := map[string]v1alpha1.ApproverState{}
+	for _, response := range result.Status.ApproversResponse {
+		responses[response.Name] = response
+	}
+	assert.Equal(t, "bob", responses["alice"].DecidedBy)
+	assert.Equal(t, v1alpha1.DelegationBasisSubstitute, responses["alice"].DelegationBasis)
+	assert.Empty(t, responses["lead"].DecidedBy)
+	assert.Empty(t, responses["lead"].DelegationBasis)
+}
+
+func TestValidateCustomRunParametersLifecycle(t *testing.T) {
+	run := func(values map[string]string) *v1beta1.CustomRun {
+		params := []v1beta1.Param{{Name: "approvers", Value: *v1beta1.NewArrayOrString("alice", "dave")}}
+		for _, name := range []string{escalateAfter, maxPendingLifetime} {
+			if value, ok := values[name]; ok {
+				params = append(params, v1beta1.Param{Name: name, Value: *v1beta1.NewArrayOrString(value)})
+			}
+		}
+		if value, ok := values[backupApprovers]; ok {
+			params = append(params, v1beta1.Param{Name: backupApprovers, Value: *v1beta1.NewArrayOrString(value, "group:on-call")})
+		}
+		return &v1beta1.CustomRun{Spec: v1beta1.CustomRunSpec{Params: params}}
+	}
+
+	assert.NoError(t, ValidateCustomRunParameters(run(map[string]string{escalateAfter: "1h", maxPendingLifetime: "4h", backupApprovers: "bob"})))
+	assert.NoError(t, ValidateCustomRunParameters(run(map[string]string{maxPendingLifetime: "4h"})))
+	assert.EqualError(t, ValidateCustomRunParameters(run(map[string]string{escalateAfter: "soon"})), "invalid escalateAfter parameter: 'soon' is not a valid duration")
+	assert.EqualError(t, ValidateCustomRunParameters(run(map[string]string{maxPendingLifetime: "0s"})), "invalid maxPendingLifetime parameter: must be positive, got 0s")
+	assert.EqualError(t, ValidateCustomRunParameters(run(map[string]string{escalateAfter: "4h", maxPendingLifetime: "4h"})), "invalid escalateAfter parameter: must be shorter than maxPendingLifetime, got 4h0m0s")
+	assert.EqualError(t, ValidateCustomRunParameters(run(map[string]string{backupApprovers: "bob"})), "invalid backupApprovers parameter: requires the escalateAfter parameter")
+}
+
+func TestCreateApprovalTaskWithBackupApprovers(t *testing.T) {
+	run := &v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo"},
+		Spec: v1beta1.CustomRunSpec{
+			Params: []v1beta1.Param{
+				{Name: "approvers", Value: *v1beta1.NewArrayOrString("alice", "bob")},
+				{Name: escalateAfter, Value: *v1beta1.NewArrayOrString("1h")},
+				{Name: maxPendingLifetime, Value: *v1beta1.NewArrayOrString("4h")},
+				{Name: backupApprovers, Value: *v1beta1.NewArrayOrString("bob", "carol", "group:on-call")},
+			},
+		},
+	}
+	approvalTask, err := createApprovalTask(context.TODO(), fake.NewSimpleClientset(), run, nil, nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, approvalTask.Spec.EscalateAfter.Duration)
+	assert.Equal(t, 4*time.Hour, approvalTask.Spec.MaxPendingLifetime.Duration)
+
+	backups := map[string]bool{}
+	for _, approver := range approvalTask.Spec.Approvers {
+		backups[approver.Type+":"+approver.Name] = approver.Backup
+	}
+	assert.Equal(t, map[string]bool{
+		"User:alice":    false,
+		"User:bob":      false,
+		"User:carol":    true,
+		"Group:on-call": true,
+	}, backups, "backups that are primary approvers stay primary")
+}
+
+func TestCreateApprovalTaskWithChangeTicket(t *testing.T) {
+	run := &v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo"},
+		Spec: v1beta1.CustomRunSpec{
+			Params: []v1beta1.Param{
+				{Name: "approvers", Value: *v1beta1.NewArrayOrString("alice", "bob")},
+				{Name: changeTicket, Value: *v1beta1.NewArrayOrString("CHG0031234")},
+			},
+		},
+	}
+	approvalTask, err := createApprovalTask(context.TODO(), fake.NewSimpleClientset(), run, nil, nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "CHG0031234", approvalTask.Spec.ChangeTicket)
+}
+
+func TestCreateApprovalTaskWithChangeTicketAnnotation(t *testing.T) {
+	run := &v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "bar",
+			Namespace:   "foo",
+			Annotations: map[string]string{v1alpha1.ChangeTicketAnnotationKey: "OPS-42"},
+		},
+		Spec: v1beta1.CustomRunSpec{
+			Params: []v1beta1.Param{
+				{Name: "approvers", Value: *v1beta1.NewArrayOrString("alice", "bob")},
+			},
+		},
+	}
+	approvalTask, err := createApprovalTask(context.TODO(), fake.NewSimpleClientset(), run, nil, nil, false)
+	assert.NoError(t, err)
+	assert.Empty(t, approvalTask.Spec.ChangeTicket)
+	assert.Equal(t, "OPS-42", approvalTask.Annotations[v1alpha1.ChangeTicketAnnotationKey])
+}
+
+func TestValidateCustomRunParametersRoles(t *testing.T) {
+	run := func(params ...v1beta1.Param) *v1beta1.CustomRun {
+		params = append(params, v1beta1.Param{Name: "approvers", Value: *v1beta1.NewArrayOrString("alice", "group:reviewers")})
+		return &v1beta1.CustomRun{Spec: v1beta1.CustomRunSpec{Params: params}}
+	}
+	roles := func(values ...string) v1beta1.Param {
+		return v1beta1.Param{Name: requiredRoles, Value: v1beta1.ParamValue{Type: v1beta1.ParamTypeArray, ArrayVal: values}}
+	}
+	approverRolesParam := func(values ...string) v1beta1.Param {
+		return v1beta1.Param{Name: approverRoles, Value: v1beta1.ParamValue{Type: v1beta1.ParamTypeArray, ArrayVal: values}}
+	}
+
+	assert.NoError(t, ValidateCustomRunParameters(run(roles("manager", "peer"), approverRolesParam("alice=manager", "group:reviewers=peer"))))
+	assert.NoError(t, ValidateCustomRunParameters(run(roles("manager"))), "required roles no approver has are left to the webhook warning")
+	assert.EqualError(t, ValidateCustomRunParameters(run(v1beta1.Param{Name: requiredRoles, Value: *v1beta1.NewArrayOrString("manager")})),
+		"invalid requiredRoles parameter: must be an array of roles")
+	assert.EqualError(t, ValidateCustomRunParameters(run(roles("manager", " "))), "invalid requiredRoles parameter: requiredRoles[1] cannot be empty")
+	assert.EqualError(t, ValidateCustomRunParameters(run(roles("peer", "peer"))), "invalid requiredRoles parameter: duplicate role 'peer'")
+	assert.EqualError(t, ValidateCustomRunParameters(run(approverRolesParam("alice", "group:reviewers=peer"))),
+		"invalid approverRoles parameter: approverRoles[0] 'alice' is not of the form approver=role")
+	assert.EqualError(t, ValidateCustomRunParameters(run(approverRolesParam("alice=manager", "alice=peer"))),
+		"invalid approverRoles parameter: approver 'alice' is given more than one role")
+	assert.EqualError(t, ValidateCustomRunParameters(run(approverRolesParam("alice=manager", "reviewers=peer"))),
+		"invalid approverRoles parameter: 'reviewers' is not one of the approvers or backupApprovers")
+}
+
+func TestCreateApprovalTaskWithRoles(t *testing.T) {
+	run := &v1beta1.CustomRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "foo"},
+		Spec: v1beta1.CustomRunSpec{
+			Params: []v1beta1.Param{
+				{Name: "approvers", Value: *v1beta1.NewArrayOrString("alice", "bob", "group:reviewers")},
+				{Name: requiredRoles, Value: *v1beta1.NewArrayOrString("manager", "peer")},
+				{Name: approverRoles, Value: *v1beta1.NewArrayOrString("alice=manager", "group:reviewers=peer")},
+			},
+		},
+	}
+	approvalTask, err := createApprovalTask(context.TODO(), fake.NewSimpleClientset(), run, nil, nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"manager", "peer"}, approvalTask.Spec.RequiredRoles)
+
+	roles := map[string]string{}
+	for _, approver := range approvalTask.Spec.Approvers {
+		roles[approver.Type+":"+approver.Name] = approver.Role
+	}
+	assert.Equal(t, map[string]string{
+		"User:alice":      "manager",
+		"User:bob":        "",
+		"Group:reviewers": "peer",
+	}, roles)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// validateDigestAcknowledgments denies updates submitting an approval whose
+// entry acknowledges a digest other than the expected digest of the task, or,
+// with Spec.RequireDigestAcknowledgment, acknowledges none. Where
+// validateDigest checks the artifact being promoted, this checks the artifact
+// each approver says they reviewed. Group members acknowledge in their own
+// entry in the users of the group. Tasks without an expected digest are not
+// checked.
+func validateDigestAcknowledgments(oldObj, newObj *v1alpha1.ApprovalTask) string {
+	expected := oldObj.Spec.ExpectedDigest
+	if expected == "" {
+		return ""
+	}
+	required := oldObj.Spec.RequireDigestAcknowledgment
+	for i, approver := range newObj.Spec.Approvers {
+		if i >= len(oldObj.Spec.Approvers) {
+			break
+		}
+		oldApprover := oldObj.Spec.Approvers[i]
+		if v1alpha1.IsIndividualApproverType(approver.Type) && approver.Input == "approve" && oldApprover.Input != "approve" {
+			if denyMsg := acknowledgmentProblem(fmt.Sprintf("approver '%s'", approver.Name), approver.AcknowledgedDigest, expected, required); denyMsg != "" {
+				return denyMsg
+			}
+		}
+
+		for _, user := range approver.Users {
+			if user.Input != "approve" || memberInput(oldApprover, user.Name) == "approve" {
+				continue
+			}
+			if denyMsg := acknowledgmentProblem(fmt.Sprintf("member '%s' of group '%s'", user.Name, approver.Name), user.AcknowledgedDigest, expected, required); denyMsg != "" {
+				return denyMsg
+			}
+		}
+	}
+	return ""
+}
+
+// acknowledgmentProblem returns the denial message of an approval by who
+// acknowledging the digest acknowledged, or an empty string if it is allowed.
+func acknowledgmentProblem(who, acknowledged, expected string, required bool) string {
+	if acknowledged == "" {
+		if required {
+			return fmt.Sprintf("Cannot approve: %s must acknowledge the expected digest '%s'", who, expected)
+		}
+		return ""
+	}
+	if acknowledged != expected {
+		return fmt.Sprintf("Cannot approve: %s acknowledged digest '%s', which does not match the expected digest '%s'", who, acknowledged, expected)
+	}
+	return ""
+}
+
+// memberInput returns the input recorded for the named member of the Group
+// approver, or an empty string when it is not listed.
+func memberInput(approver v1alpha1.ApproverDetails, name string) string {
+	for _, user := range approver.Users {
+		if user.Name == name {
+			return user.Input
+		}
+	}
+	return ""
+}
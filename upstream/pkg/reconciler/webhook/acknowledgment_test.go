@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+// acknowledgingApprovalTask requires its approvals to acknowledge its
+// expected digest.
+func acknowledgingApprovalTask() *v1alpha1.ApprovalTask {
+	at := digestApprovalTask("")
+	at.Spec.RequireDigestAcknowledgment = true
+	return at
+}
+
+func TestAdmitApprovalAcknowledgingDigest(t *testing.T) {
+	oldObj := acknowledgingApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	newObj.Spec.Approvers[0].AcknowledgedDigest = "sha256:aaaa"
+	assert.True(t, admitUpdate(t, oldObj, newObj, "alice").Allowed)
+
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+	newObj.Spec.Approvers[1].Users = []v1alpha1.UserDetails{{Name: "bob", Input: "approve", AcknowledgedDigest: "sha256:aaaa"}}
+	assert.True(t, admitUpdate(t, oldObj, newObj, "bob", "platform").Allowed)
+}
+
+func TestAdmitApprovalMismatchingAcknowledgedDigest(t *testing.T) {
+	for name, oldObj := range map[string]*v1alpha1.ApprovalTask{
+		"required":     acknowledgingApprovalTask(),
+		"not required": digestApprovalTask(""),
+	} {
+		t.Run(name, func(t *testing.T) {
+			newObj := oldObj.DeepCopy()
+			newObj.Spec.Approvers[0].Input = "approve"
+			newObj.Spec.Approvers[0].AcknowledgedDigest = "sha256:bbbb"
+			resp := admitUpdate(t, oldObj, newObj, "alice")
+			assert.False(t, resp.Allowed)
+			assert.Equal(t, "Cannot approve: approver 'alice' acknowledged digest 'sha256:bbbb', which does not match the expected digest 'sha256:aaaa'", resp.Result.Message)
+
+			newObj = oldObj.DeepCopy()
+			newObj.Spec.Approvers[1].Input = "approve"
+			newObj.Spec.Approvers[1].Users = []v1alpha1.UserDetails{{Name: "bob", Input: "approve", AcknowledgedDigest: "sha256:bbbb"}}
+			resp = admitUpdate(t, oldObj, newObj, "bob", "platform")
+			assert.False(t, resp.Allowed)
+			assert.Equal(t, "Cannot approve: member 'bob' of group 'platform' acknowledged digest 'sha256:bbbb', which does not match the expected digest 'sha256:aaaa'", resp.Result.Message)
+		})
+	}
+}
+
+func TestAdmitApprovalWithoutAcknowledgment(t *testing.T) {
+	oldObj := acknowledgingApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	resp := admitUpdate(t, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Cannot approve: approver 'alice' must acknowledge the expected digest 'sha256:aaaa'", resp.Result.Message)
+
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "reject"
+	assert.True(t, admitUpdate(t, oldObj, newObj, "alice").Allowed, "rejections need no acknowledgment")
+
+	oldObj = digestApprovalTask("")
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	assert.True(t, admitUpdate(t, oldObj, newObj, "alice").Allowed, "acknowledging is optional unless required")
+}
+
+func TestAdmitAcknowledgmentOfAnotherApprover(t *testing.T) {
+	oldObj := acknowledgingApprovalTask()
+	oldObj.Spec.Approvers = append(oldObj.Spec.Approvers, v1alpha1.ApproverDetails{Name: "bob", Type: "User", Input: "pending"})
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	newObj.Spec.Approvers[0].AcknowledgedDigest = "sha256:aaaa"
+	newObj.Spec.Approvers[2].AcknowledgedDigest = "sha256:aaaa"
+
+	resp := admitUpdate(t, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "approvers[2].acknowledgedDigest")
+}
+
+func TestAdmitDigestAcknowledgmentRequirement(t *testing.T) {
+	at := sizedApprovalTask(2)
+	at.Spec.RequireDigestAcknowledgment = true
+	resp := admitCreateWith(t, &reconciler{}, at)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "validation failed: spec validation failed: requireDigestAcknowledgment: requires expectedDigest to be set", resp.Result.Message)
+
+	oldObj := digestApprovalTask("")
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.RequireDigestAcknowledgment = true
+	resp = admitUpdate(t, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "The digest acknowledgment requirement of an ApprovalTask cannot be changed", resp.Result.Message)
+}
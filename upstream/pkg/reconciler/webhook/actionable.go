@@ -0,0 +1,166 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	approvaltasklisters "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	"k8s.io/apimachinery/pkg/labels"
+	"knative.dev/pkg/logging"
+)
+
+// ActionablePath is the path the endpoint listing the ApprovalTasks awaiting
+// a decision of the caller is served on.
+const ActionablePath = "/actionable"
+
+const (
+	defaultActionableLimit = 50
+	maxActionableLimit     = 500
+)
+
+// ActionableTask is an ApprovalTask awaiting a decision of the caller.
+type ActionableTask struct {
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Role        string `json:"role"`
+	CanApprove  bool   `json:"canApprove"`
+	CanReject   bool   `json:"canReject"`
+}
+
+// ActionableList is a page of the ApprovalTasks awaiting a decision of the
+// caller. Continue is set when more remain, and fetches the next page when
+// passed back as the continue query parameter.
+type ActionableList struct {
+	Items    []ActionableTask `json:"items"`
+	Continue string           `json:"continue,omitempty"`
+}
+
+// actionableHandler lists, for the user owning the bearer token of the
+// request, the ApprovalTasks they can approve or reject and have not
+// responded to yet, optionally restricted to the namespace query parameter.
+// Tasks are read from an informer cache and ordered by namespace and name.
+type actionableHandler struct {
+	admission *reconciler
+	tasks     approvaltasklisters.ApprovalTaskLister
+	synced    func() bool
+}
+
+func (h *actionableHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := bearerToken(req)
+	if token == "" {
+		http.Error(w, "a bearer token is required", http.StatusUnauthorized)
+		return
+	}
+
+	query := req.URL.Query()
+	limit := defaultActionableLimit
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxActionableLimit {
+			http.Error(w, "the limit query parameter must be between 1 and "+strconv.Itoa(maxActionableLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	ctx := req.Context()
+	userInfo, err := h.admission.authenticate(ctx, token)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if h.synced != nil && !h.synced() {
+		http.Error(w, "the approval tasks are not loaded yet, try again", http.StatusServiceUnavailable)
+		return
+	}
+
+	tasks, err := h.list(query.Get("namespace"))
+	if err != nil {
+		logging.FromContext(ctx).Errorf("Error listing ApprovalTasks: %v", err)
+		http.Error(w, "failed to list the approval tasks", http.StatusInternalServerError)
+		return
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return actionableKey(tasks[i].Namespace, tasks[i].Name) < actionableKey(tasks[j].Namespace, tasks[j].Name)
+	})
+
+	// Groups are looked up once for the whole listing
+	memoized := newMemoizedGroupResolver(h.admission.groupResolver)
+	var resolver GroupResolver
+	if memoized != nil {
+		resolver = memoized
+	}
+
+	after := query.Get("continue")
+	list := ActionableList{Items: []ActionableTask{}}
+	for _, at := range tasks {
+		key := actionableKey(at.Namespace, at.Name)
+		if after != "" && key <= after {
+			continue
+		}
+		eligibility := h.admission.eligibilityWith(ctx, at, userInfo, resolver)
+		if eligibility.AlreadyResponded || (!eligibility.CanApprove && !eligibility.CanReject) {
+			continue
+		}
+		if len(list.Items) == limit {
+			list.Continue = actionableKey(list.Items[limit-1].Namespace, list.Items[limit-1].Name)
+			break
+		}
+		list.Items = append(list.Items, ActionableTask{
+			Namespace:   at.Namespace,
+			Name:        at.Name,
+			Description: at.Spec.Description,
+			Role:        eligibility.Role,
+			CanApprove:  eligibility.CanApprove,
+			CanReject:   eligibility.CanReject,
+		})
+	}
+	if memoized.failed() {
+		http.Error(w, groupMembershipUnverifiedMsg, http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		logging.FromContext(ctx).Errorf("Error writing actionable tasks response: %v", err)
+	}
+}
+
+// list returns the approval tasks of namespace, or of every namespace when
+// it is empty.
+func (h *actionableHandler) list(namespace string) ([]*v1alpha1.ApprovalTask, error) {
+	if namespace == "" {
+		return h.tasks.List(labels.Everything())
+	}
+	return h.tasks.ApprovalTasks(namespace).List(labels.Everything())
+}
+
+// actionableKey orders approval tasks by namespace, then name.
+func actionableKey(namespace, name string) string {
+	return namespace + "/" + name
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	approvaltasklisters "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/cache"
+)
+
+// actionableApprovalTasks returns tasks across two namespaces:
+//   - production/deploy, awaiting alice and the platform group, approved by bob
+//   - production/migrate, awaiting bob
+//   - staging/deploy, awaiting alice, restricted to rejections
+//   - staging/done, approved already
+func actionableApprovalTasks() []*v1alpha1.ApprovalTask {
+	deploy := eligibilityApprovalTask()
+
+	migrate := eligibilityApprovalTask()
+	migrate.Name = "migrate"
+	migrate.Spec.Approvers = []v1alpha1.ApproverDetails{{Name: "bob", Type: "User", Input: "pending"}}
+	migrate.Status.ApproversResponse = nil
+
+	staging := eligibilityApprovalTask()
+	staging.Namespace = "staging"
+	staging.Spec.Approvers = []v1alpha1.ApproverDetails{{Name: "alice", Type: "User", Input: "pending", AllowedInputs: []string{"reject"}}}
+	staging.Status.ApproversResponse = nil
+
+	done := eligibilityApprovalTask()
+	done.Namespace, done.Name = "staging", "done"
+	done.Status.State = "approved"
+
+	return []*v1alpha1.ApprovalTask{deploy, migrate, staging, done}
+}
+
+func newActionableHandler(t *testing.T, groups map[string][]string, tasks ...*v1alpha1.ApprovalTask) *actionableHandler {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, at := range tasks {
+		assert.NoError(t, indexer.Add(at))
+	}
+	return &actionableHandler{
+		admission: newEligibilityHandler(groups).admission,
+		tasks:     approvaltasklisters.NewApprovalTaskLister(indexer),
+	}
+}
+
+func getActionable(t *testing.T, h *actionableHandler, token, query string) (int, ActionableList) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, ActionablePath+query, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var got ActionableList
+	if rec.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	}
+	return rec.Code, got
+}
+
+func actionableNames(list ActionableList) []string {
+	names := []string{}
+	for _, item := range list.Items {
+		names = append(names, item.Namespace+"/"+item.Name)
+	}
+	return names
+}
+
+func TestActionable(t *testing.T) {
+	groups := map[string][]string{"carol": {"platform"}}
+	h := newActionableHandler(t, groups, actionableApprovalTasks()...)
+
+	tests := []struct {
+		name  string
+		token string
+		query string
+		want  []string
+	}{{
+		name:  "user approver of several tasks",
+		token: "alice",
+		want:  []string{"production/deploy", "staging/deploy"},
+	}, {
+		name:  "responded tasks are left out",
+		token: "bob",
+		want:  []string{"production/migrate"},
+	}, {
+		name:  "group member",
+		token: "carol",
+		want:  []string{"production/deploy"},
+	}, {
+		name:  "non approver",
+		token: "mallory",
+		want:  []string{},
+	}, {
+		name:  "restricted to a namespace",
+		token: "alice",
+		query: "?namespace=staging",
+		want:  []string{"staging/deploy"},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			code, got := getActionable(t, h, tc.token, tc.query)
+			assert.Equal(t, http.StatusOK, code)
+			assert.Equal(t, tc.want, actionableNames(got))
+			assert.Empty(t, got.Continue)
+		})
+	}
+
+	_, got := getActionable(t, h, "alice", "?namespace=staging")
+	assert.Equal(t, ActionableTask{Namespace: "staging", Name: "deploy", Role: roleUser, CanReject: true}, got.Items[0])
+}
+
+func TestActionablePagination(t *testing.T) {
+	h := newActionableHandler(t, nil, actionableApprovalTasks()...)
+
+	code, first := getActionable(t, h, "alice", "?limit=1")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"production/deploy"}, actionableNames(first))
+	assert.Equal(t, "production/deploy", first.Continue)
+
+	code, second := getActionable(t, h, "alice", "?limit=1&continue="+first.Continue)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"staging/deploy"}, actionableNames(second))
+	assert.Empty(t, second.Continue, "no task is left after the last page")
+}
+
+func TestActionableRequestErrors(t *testing.T) {
+	h := newActionableHandler(t, nil, actionableApprovalTasks()...)
+
+	code, _ := getActionable(t, h, "", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = getActionable(t, h, "invalid", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = getActionable(t, h, "alice", "?limit=0")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	h.synced = func() bool { return false }
+	code, _ = getActionable(t, h, "alice", "")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+func TestActionableResolvesGroupsOnce(t *testing.T) {
+	resolver := &fakeGroupResolver{groups: map[string][]string{"carol": {"platform"}}}
+	h := newActionableHandler(t, nil, actionableApprovalTasks()...)
+	h.admission.groupResolver = resolver
+
+	_, got := getActionable(t, h, "carol", "")
+	assert.Equal(t, []string{"production/deploy"}, actionableNames(got))
+	assert.Equal(t, 1, resolver.calls)
+
+	h.admission.groupResolver = &fakeGroupResolver{err: errors.New("connection refused")}
+	code, _ := getActionable(t, h, "carol", "")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/system"
+	certresources "knative.dev/pkg/webhook/certificates/resources"
+)
+
+// bearerToken returns the bearer token of the request, if any.
+func bearerToken(req *http.Request) string {
+	header := req.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return ""
+	}
+	return strings.TrimPrefix(header, "Bearer ")
+}
+
+// authenticate resolves the user owning token through a TokenReview, so that
+// the groups used for the checks are the ones the API server would send to
+// Admit.
+func (r *reconciler) authenticate(ctx context.Context, token string) (authenticationv1.UserInfo, error) {
+	review, err := r.client.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return authenticationv1.UserInfo{}, errors.New("failed to review the bearer token")
+	}
+	if !review.Status.Authenticated {
+		return authenticationv1.UserInfo{}, errors.New("the bearer token is not valid")
+	}
+	return review.Status.User, nil
+}
+
+// canGet reports whether the user may get the ApprovalTask, which callers of
+// the endpoints disclosing it must be.
+func (r *reconciler) canGet(ctx context.Context, userInfo authenticationv1.UserInfo, namespace, name string) (bool, error) {
+	extra := map[string]authorizationv1.ExtraValue{}
+	for k, v := range userInfo.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	review, err := r.client.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   userInfo.Username,
+			Groups: userInfo.Groups,
+			UID:    userInfo.UID,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "get",
+				Group:     Group,
+				Resource:  "approvaltasks",
+				Name:      name,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+// serverCertificate returns the serving certificate of the webhook, so that
+// the approval API is served with the same certificate as admission reviews
+// and follows its rotation.
+func (r *reconciler) serverCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	secret, err := r.secretlister.Secrets(system.Namespace()).Get(r.secretName)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.X509KeyPair(secret.Data[certresources.ServerCert], secret.Data[certresources.ServerKey])
+	if err != nil {
+		return nil, fmt.Errorf("secret %q does not hold a valid serving certificate: %w", r.secretName, err)
+	}
+	return &cert, nil
+}
+
+// serveAPI serves the eligibility and receipt endpoints over TLS on address
+// until ctx is done, with the certificates returned by getCertificate.
+func serveAPI(ctx context.Context, address string, handler http.Handler, getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)) {
+	logger := logging.FromContext(ctx)
+	server := &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: getCertificate,
+		},
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Errorf("Error shutting down the approval API server: %v", err)
+		}
+	}()
+
+	logger.Infof("Serving the approval API on %s", address)
+	if err := server.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Errorf("Approval API server failed: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+const (
+	// Audit annotation keys set on every admission response. The API server
+	// records them in its audit log prefixed with the webhook name.
+	auditDecisionKey = "decision"
+	auditUserKey     = "user"
+	auditReasonKey   = "reason"
+	auditInstanceKey = "instance"
+	// auditCorrelationIDKey records the correlation ID of the request, see
+	// requestCorrelationID.
+	auditCorrelationIDKey = "correlation-id"
+
+	// Audit annotation keys set on updates recording the decision of a
+	// substitute: the approvers decided for, the substitute and the basis of
+	// the delegation.
+	auditPrincipalKey       = "principal"
+	auditDelegateKey        = "delegate"
+	auditDelegationBasisKey = "delegation-basis"
+
+	// maxAuditValueLength bounds the length of audit annotation values, in
+	// bytes, so that long messages do not bloat audit events.
+	maxAuditValueLength = 256
+)
+
+// annotateAudit records the decision on the request, the user who made it
+// and the reason, the denial message or the warnings, along with the
+// correlation ID of the request, in the audit annotations of the response.
+// When instance is set, it records the webhook replica that admitted the
+// request as well.
+func annotateAudit(request *admissionv1.AdmissionRequest, response *admissionv1.AdmissionResponse, instance, correlationID string) {
+	decision := "denied"
+	reason := ""
+	if response.Allowed {
+		decision = "allowed"
+		reason = strings.Join(response.Warnings, "; ")
+	} else if response.Result != nil {
+		reason = response.Result.Message
+	}
+
+	if response.AuditAnnotations == nil {
+		response.AuditAnnotations = make(map[string]string)
+	}
+	response.AuditAnnotations[auditDecisionKey] = decision
+	response.AuditAnnotations[auditUserKey] = truncateAuditValue(request.UserInfo.Username)
+	if reason != "" {
+		response.AuditAnnotations[auditReasonKey] = truncateAuditValue(reason)
+	}
+	if instance != "" {
+		response.AuditAnnotations[auditInstanceKey] = instance
+	}
+	if correlationID != "" {
+		response.AuditAnnotations[auditCorrelationIDKey] = truncateAuditValue(correlationID)
+	}
+}
+
+// instanceIdentity returns the identity of a webhook replica recorded in the
+// audit annotations, "namespace/name" of its pod, or an empty string when
+// the pod name is unknown.
+func instanceIdentity(namespace, name string) string {
+	if name == "" || namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+// truncateAuditValue cuts value to maxAuditValueLength bytes, without
+// splitting a UTF-8 sequence, marking the cut with an ellipsis.
+func truncateAuditValue(value string) string {
+	if len(value) <= maxAuditValueLength {
+		return value
+	}
+	const ellipsis = "..."
+	cut := maxAuditValueLength - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut] + ellipsis
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdmitAuditAnnotations(t *testing.T) {
+	pending := keyedApprovalTask()
+	pending.Annotations = map[string]string{v1alpha1.CorrelationIDAnnotationKey: "release-42"}
+	approved := pending.DeepCopy()
+	approved.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdate(t, pending, approved, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	assert.Equal(t, map[string]string{
+		"decision":       "allowed",
+		"user":           "alice",
+		"reason":         "counted as User approver alice",
+		"correlation-id": "release-42",
+	}, resp.AuditAnnotations)
+
+	resp = admitUpdate(t, pending, approved, "mallory")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, map[string]string{
+		"decision":       "denied",
+		"user":           "mallory",
+		"reason":         "User does not exist in the approval list",
+		"correlation-id": "release-42",
+	}, resp.AuditAnnotations)
+}
+
+func TestAdmitAuditAnnotationsWithoutReason(t *testing.T) {
+	resp := admitCreateWith(t, &reconciler{}, keyedApprovalTask())
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	assert.Equal(t, "allowed", resp.AuditAnnotations["decision"])
+	assert.NotContains(t, resp.AuditAnnotations, "reason")
+}
+
+func TestAdmitAuditAnnotationsInstance(t *testing.T) {
+	r := &reconciler{instance: instanceIdentity("openshift-pipelines", "manual-approval-webhook-7d9f-x2k4q")}
+	pending := keyedApprovalTask()
+	approved := pending.DeepCopy()
+	approved.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdateWith(t, r, pending, approved, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	assert.Equal(t, "openshift-pipelines/manual-approval-webhook-7d9f-x2k4q", resp.AuditAnnotations["instance"])
+
+	resp = admitUpdateWith(t, r, pending, approved, "mallory")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "openshift-pipelines/manual-approval-webhook-7d9f-x2k4q", resp.AuditAnnotations["instance"])
+
+	resp = admitUpdate(t, pending, approved, "alice")
+	assert.NotContains(t, resp.AuditAnnotations, "instance", "the identity is only recorded when known")
+}
+
+func TestInstanceIdentity(t *testing.T) {
+	assert.Equal(t, "ns/pod", instanceIdentity("ns", "pod"))
+	assert.Equal(t, "pod", instanceIdentity("", "pod"))
+	assert.Equal(t, "", instanceIdentity("ns", ""))
+}
+
+func TestTruncateAuditValue(t *testing.T) {
+	assert.Equal(t, "short", truncateAuditValue("short"))
+
+	long := truncateAuditValue(strings.Repeat("a", 1000))
+	assert.Len(t, long, maxAuditValueLength)
+	assert.True(t, strings.HasSuffix(long, "..."))
+
+	// A multi-byte character straddling the cut is dropped whole
+	multi := truncateAuditValue(strings.Repeat("a", maxAuditValueLength-4) + strings.Repeat("é", 10))
+	assert.Equal(t, strings.Repeat("a", maxAuditValueLength-4)+"...", multi)
+}
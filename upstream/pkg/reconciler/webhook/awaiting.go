@@ -0,0 +1,51 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// isAwaitingIndexUpdate reports whether the update is the controller
+// changing the awaiting labels of a task (see approval.AwaitingLabels), and
+// nothing else. It is admitted whatever the state of the task, so that final
+// tasks lose their labels.
+func (r *reconciler) isAwaitingIndexUpdate(oldObj, newObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest) bool {
+	if r.controllerUsername == "" || request.UserInfo.Username != r.controllerUsername {
+		return false
+	}
+	if !isMetadataOnlyUpdate(oldObj, newObj) || !equality.Semantic.DeepEqual(oldObj.Annotations, newObj.Annotations) ||
+		!equality.Semantic.DeepEqual(oldObj.OwnerReferences, newObj.OwnerReferences) || !equality.Semantic.DeepEqual(oldObj.Finalizers, newObj.Finalizers) {
+		return false
+	}
+	return !equality.Semantic.DeepEqual(oldObj.Labels, newObj.Labels) &&
+		equality.Semantic.DeepEqual(withoutAwaitingLabels(oldObj.Labels), withoutAwaitingLabels(newObj.Labels))
+}
+
+// withoutAwaitingLabels returns the labels other than the awaiting labels.
+func withoutAwaitingLabels(labels map[string]string) map[string]string {
+	others := map[string]string{}
+	for key, value := range labels {
+		if !approval.IsAwaitingLabel(key) {
+			others[key] = value
+		}
+	}
+	return others
+}
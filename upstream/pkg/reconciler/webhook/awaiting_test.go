@@ -0,0 +1,43 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdmitAwaitingIndexUpdate(t *testing.T) {
+	r := &reconciler{controllerUsername: DefaultControllerServiceAccount}
+	oldObj := keyedApprovalTask()
+	oldObj.Labels = map[string]string{"team": "payments", approval.AwaitingLabelKey("alice"): "true"}
+	oldObj.Status.State = "approved"
+
+	newObj := oldObj.DeepCopy()
+	delete(newObj.Labels, approval.AwaitingLabelKey("alice"))
+	resp := admitUpdateWith(t, r, oldObj, newObj, DefaultControllerServiceAccount)
+	assert.True(t, resp.Allowed, "the controller clears the index of final tasks")
+
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed, "only the controller maintains the index")
+
+	newObj.Labels["team"] = "checkout"
+	resp = admitUpdateWith(t, r, oldObj, newObj, DefaultControllerServiceAccount)
+	assert.False(t, resp.Allowed, "the index is maintained on its own")
+}
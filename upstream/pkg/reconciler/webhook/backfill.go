@@ -0,0 +1,43 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// isApprovalsRequiredBackfill reports whether the update is the controller
+// setting the number of approvals required of a task that sets none, and
+// changing nothing else in its spec, labels or annotations. Nobody else may, since whoever sets it
+// decides how many approvals the task needs.
+func (r *reconciler) isApprovalsRequiredBackfill(oldObj, newObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest) bool {
+	if r.controllerUsername == "" || request.UserInfo.Username != r.controllerUsername {
+		return false
+	}
+	if !approval.ApprovalsRequiredUnset(oldObj.Spec) || newObj.Spec.NumberOfApprovalsRequired <= 0 {
+		return false
+	}
+	if !equality.Semantic.DeepEqual(oldObj.Labels, newObj.Labels) || !equality.Semantic.DeepEqual(oldObj.Annotations, newObj.Annotations) {
+		return false
+	}
+	backfilled := oldObj.Spec.DeepCopy()
+	backfilled.NumberOfApprovalsRequired = newObj.Spec.NumberOfApprovalsRequired
+	return equality.Semantic.DeepEqual(*backfilled, newObj.Spec)
+}
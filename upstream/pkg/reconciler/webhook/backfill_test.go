@@ -0,0 +1,50 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testControllerUsername = "system:serviceaccount:tekton-pipelines:manual-approval-gate-controller"
+
+func TestAdmitApprovalsRequiredBackfill(t *testing.T) {
+	r := &reconciler{controllerUsername: testControllerUsername}
+	oldObj := withdrawableApprovalTask()
+	oldObj.Spec.NumberOfApprovalsRequired = 0
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.NumberOfApprovalsRequired = 1
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, testControllerUsername)
+	assert.True(t, resp.Allowed, "the controller backfills tasks that set no number of approvals required")
+
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed, "approvers cannot pick the number of approvals they need")
+
+	sneaky := newObj.DeepCopy()
+	sneaky.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdateWith(t, r, oldObj, sneaky, testControllerUsername)
+	assert.False(t, resp.Allowed, "nothing else may change with the backfill")
+
+	set := withdrawableApprovalTask()
+	raised := set.DeepCopy()
+	raised.Spec.NumberOfApprovalsRequired = 2
+	resp = admitUpdateWith(t, r, set, raised, testControllerUsername)
+	assert.False(t, resp.Allowed, "a number of approvals required already set is not backfilled")
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// auditBlocklistedKey records, in the audit annotations of a denial, the
+// blocklisted identity the user was denied as.
+const auditBlocklistedKey = "blocklisted"
+
+// blocklisted returns the identity of the user, their username or one of the
+// emails of their extra claims, that the webhook-wide blocklist or the
+// blocklist of the task lists. Emails compare case-insensitively, like those
+// of Email approvers.
+func (r *reconciler) blocklisted(at *v1alpha1.ApprovalTask, userInfo authenticationv1.UserInfo) (string, bool) {
+	for _, list := range [][]string{r.blocklist, at.Spec.Blocklist} {
+		for _, identity := range list {
+			if identity == userInfo.Username {
+				return identity, true
+			}
+			for _, email := range userInfo.Extra[emailExtraKey] {
+				if strings.EqualFold(identity, email) {
+					return identity, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// denyBlocklisted denies the request of a blocklisted user, recording the
+// identity they were denied as in the audit annotations. It returns nil when
+// the user is not blocklisted.
+func (r *reconciler) denyBlocklisted(at *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	identity, ok := r.blocklisted(at, request.UserInfo)
+	if !ok {
+		return nil
+	}
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Message: fmt.Sprintf("User '%s' is blocklisted and cannot decide on approval tasks", identity),
+		},
+		AuditAnnotations: map[string]string{auditBlocklistedKey: truncateAuditValue(identity)},
+	}
+}
+
+// validateBlocklistChange checks that the blocklist of a task is only changed
+// by the privileged group, on its own. It returns the denial message, or an
+// empty string if the change is allowed.
+func (r *reconciler) validateBlocklistChange(oldObj, newObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest) string {
+	if r.privilegedGroup == "" || !webhookContains(request.UserInfo.Groups, r.privilegedGroup) {
+		return "Only members of the privileged group can change the blocklist of an approval task"
+	}
+
+	changed := newObj.Spec.DeepCopy()
+	changed.Blocklist = oldObj.Spec.Blocklist
+	if !reflect.DeepEqual(oldObj.Spec, *changed) {
+		return "Changing the blocklist of an approval task cannot change any other field"
+	}
+	return ""
+}
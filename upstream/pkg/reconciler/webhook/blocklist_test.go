@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+func TestAdmitBlocklistedUser(t *testing.T) {
+	oldObj := groupApprovalTask("production")
+	oldObj.Spec.Blocklist = []string{"alice"}
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdate(t, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed, "blocklisted users are denied although they are approvers")
+	assert.Equal(t, "User 'alice' is blocklisted and cannot decide on approval tasks", resp.Result.Message)
+	assert.Equal(t, "alice", resp.AuditAnnotations[auditBlocklistedKey])
+
+	// Rejecting is a decision too
+	newObj.Spec.Approvers[0].Input = "reject"
+	assert.False(t, admitUpdate(t, oldObj, newObj, "alice").Allowed)
+
+	eligibility := (&reconciler{}).eligibility(context.Background(), oldObj, authenticationv1.UserInfo{Username: "alice"})
+	assert.Equal(t, roleUser, eligibility.Role)
+	assert.False(t, eligibility.CanApprove)
+	assert.False(t, eligibility.CanReject)
+}
+
+func TestAdmitBlocklistedGroupMember(t *testing.T) {
+	r := &reconciler{blocklist: []string{"Carol@Example.com"}}
+	oldObj := groupApprovalTask("production")
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+	newObj.Spec.Approvers[1].Users = []v1alpha1.UserDetails{{Name: "carol", Input: "approve"}}
+
+	carol := authenticationv1.UserInfo{
+		Username: "carol",
+		Groups:   []string{"platform"},
+		Extra:    map[string]authenticationv1.ExtraValue{emailExtraKey: {"carol@example.com"}},
+	}
+	resp := admitUpdateAs(t, r, oldObj, newObj, carol)
+	assert.False(t, resp.Allowed, "the group claim of an offboarded user does not let them decide")
+	assert.Equal(t, "User 'Carol@Example.com' is blocklisted and cannot decide on approval tasks", resp.Result.Message)
+	assert.False(t, r.eligibility(context.Background(), oldObj, carol).CanApprove)
+
+	// Other members of the group still decide
+	newObj.Spec.Approvers[1].Users = []v1alpha1.UserDetails{{Name: "dave", Input: "approve"}}
+	resp = admitUpdateWith(t, r, oldObj, newObj, "dave", "platform")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+}
+
+func TestAdmitBlocklistChange(t *testing.T) {
+	r := &reconciler{privilegedGroup: DefaultPrivilegedGroup}
+	oldObj := groupApprovalTask("production")
+	blocked := oldObj.DeepCopy()
+	blocked.Spec.Blocklist = []string{"alice"}
+
+	resp := admitUpdateWith(t, r, oldObj, blocked, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Only members of the privileged group can change the blocklist of an approval task", resp.Result.Message)
+
+	resp = admitUpdateWith(t, r, blocked, oldObj, "alice")
+	assert.False(t, resp.Allowed, "blocklisted users cannot remove themselves")
+
+	resp = admitUpdateWith(t, r, oldObj, blocked, "admin", DefaultPrivilegedGroup)
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	blocked.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdateWith(t, r, oldObj, blocked, "admin", DefaultPrivilegedGroup)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Changing the blocklist of an approval task cannot change any other field", resp.Result.Message)
+
+	empty := oldObj.DeepCopy()
+	empty.Spec.Blocklist = []string{" "}
+	assert.EqualError(t, validateApprovalTaskSpec(&empty.Spec, context.Background()), "blocklist[0]: cannot be empty")
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// DefaultChangeTicketPattern is the pattern change ticket references must
+// match by default: a project key or prefix followed by a number, such as
+// "OPS-1234" or "CHG0031234".
+const DefaultChangeTicketPattern = `^[A-Z][A-Z0-9]*-?[0-9]+$`
+
+var defaultChangeTicketPattern = regexp.MustCompile(DefaultChangeTicketPattern)
+
+// validateChangeTicket denies, when r.requireChangeTicket is set, updates
+// submitting an approval on a task whose change ticket (see changeTicketOf) is
+// missing or does not match r.changeTicketPattern. Rejections are always allowed. It returns
+// the denial message, or an empty string if the update is allowed.
+func (r *reconciler) validateChangeTicket(oldObj, newObj *v1alpha1.ApprovalTask) string {
+	if !r.requireChangeTicket || !addsApproval(oldObj.Spec.Approvers, newObj.Spec.Approvers) {
+		return ""
+	}
+	pattern := r.changeTicketPattern
+	if pattern == nil {
+		pattern = defaultChangeTicketPattern
+	}
+	ticket := changeTicketOf(oldObj)
+	if ticket == "" {
+		return "Cannot approve: the approval task does not reference a change ticket"
+	}
+	if !pattern.MatchString(ticket) {
+		return fmt.Sprintf("Cannot approve: the change ticket '%s' does not match the pattern '%s'", ticket, pattern)
+	}
+	return ""
+}
+
+// changeTicketOf returns the change ticket of the task: Spec.ChangeTicket, or
+// the ChangeTicketAnnotationKey annotation when the spec does not set one.
+func changeTicketOf(approvalTask *v1alpha1.ApprovalTask) string {
+	if approvalTask.Spec.ChangeTicket != "" {
+		return approvalTask.Spec.ChangeTicket
+	}
+	return approvalTask.Annotations[v1alpha1.ChangeTicketAnnotationKey]
+}
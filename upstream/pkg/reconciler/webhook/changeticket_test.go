@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func ticketedApprovalTask(ticket string) *v1alpha1.ApprovalTask {
+	at := keyedApprovalTask()
+	at.Spec.ChangeTicket = ticket
+	return at
+}
+
+func TestValidateChangeTicket(t *testing.T) {
+	r := &reconciler{requireChangeTicket: true}
+	approve := func(oldObj *v1alpha1.ApprovalTask) string {
+		newObj := oldObj.DeepCopy()
+		newObj.Spec.Approvers[0].Input = "approve"
+		return r.validateChangeTicket(oldObj, newObj)
+	}
+
+	assert.Empty(t, approve(ticketedApprovalTask("CHG0031234")))
+	assert.Empty(t, approve(ticketedApprovalTask("OPS-42")))
+	assert.Equal(t, "Cannot approve: the approval task does not reference a change ticket", approve(ticketedApprovalTask("")))
+	assert.Equal(t, "Cannot approve: the change ticket 'see slack' does not match the pattern '^[A-Z][A-Z0-9]*-?[0-9]+$'",
+		approve(ticketedApprovalTask("see slack")))
+
+	r.changeTicketPattern = regexp.MustCompile(`^INC[0-9]{7}$`)
+	assert.Empty(t, approve(ticketedApprovalTask("INC0001234")))
+	assert.NotEmpty(t, approve(ticketedApprovalTask("OPS-42")), "the configured pattern replaces the default one")
+
+	annotated := ticketedApprovalTask("")
+	annotated.Annotations = map[string]string{v1alpha1.ChangeTicketAnnotationKey: "INC0004321"}
+	assert.Empty(t, approve(annotated), "the annotation stands in for a missing spec.changeTicket")
+	annotated.Spec.ChangeTicket = "OPS-42"
+	assert.NotEmpty(t, approve(annotated), "spec.changeTicket takes precedence over the annotation")
+
+	oldObj := ticketedApprovalTask("")
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "reject"
+	assert.Empty(t, r.validateChangeTicket(oldObj, newObj), "rejections do not need a change ticket")
+
+	newObj.Spec.Approvers[0].Input = "approve"
+	assert.Empty(t, (&reconciler{}).validateChangeTicket(oldObj, newObj), "change tickets are not required by default")
+}
+
+func TestAdmitChangeTicket(t *testing.T) {
+	r := &reconciler{requireChangeTicket: true}
+	oldObj := ticketedApprovalTask("")
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Cannot approve: the approval task does not reference a change ticket", resp.Result.Message)
+
+	oldObj = ticketedApprovalTask("CHG0031234")
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	// The ticket cannot be attached along with the approval
+	newObj = ticketedApprovalTask("CHG0031234")
+	newObj.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdateWith(t, r, ticketedApprovalTask(""), newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "The change ticket of an ApprovalTask cannot be changed", resp.Result.Message)
+
+	// Nor through the annotation
+	newObj = ticketedApprovalTask("")
+	newObj.Annotations = map[string]string{v1alpha1.ChangeTicketAnnotationKey: "CHG0031234"}
+	newObj.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdateWith(t, r, ticketedApprovalTask(""), newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "The change ticket of an ApprovalTask cannot be changed", resp.Result.Message)
+
+	oldObj = ticketedApprovalTask("")
+	oldObj.Annotations = map[string]string{v1alpha1.ChangeTicketAnnotationKey: "CHG0031234"}
+	newObj = oldObj.DeepCopy()
+	delete(newObj.Annotations, v1alpha1.ChangeTicketAnnotationKey)
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "The change ticket of an ApprovalTask cannot be changed", resp.Result.Message)
+
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+const (
+	// ChecksGatingFailing denies approvals while the checks status
+	// annotation reports failing checks or merge conflicts. Tasks without
+	// the annotation are not gated.
+	ChecksGatingFailing = "failing"
+	// ChecksGatingRequirePassing denies approvals unless the checks status
+	// annotation reports passing checks.
+	ChecksGatingRequirePassing = "require-passing"
+	// ChecksGatingOff ignores the checks status annotation.
+	ChecksGatingOff = "off"
+
+	checksSuccess  = "success"
+	checksFailure  = "failure"
+	checksConflict = "conflict"
+)
+
+// ChecksGatings lists the accepted values of Options.ChecksGating.
+var ChecksGatings = []string{ChecksGatingFailing, ChecksGatingRequirePassing, ChecksGatingOff}
+
+// validateChecks denies updates submitting an approval while the checks
+// status annotation of the task, before or after the update, reports checks
+// that do not allow it under r.checksGating. Like validateDigest, it looks at
+// both so that an approver cannot clear the annotation along with their
+// approval, and rejections are always allowed. It returns the denial
+// message, or an empty string if the update is allowed.
+func (r *reconciler) validateChecks(oldObj, newObj *v1alpha1.ApprovalTask) string {
+	if r.checksGating == ChecksGatingOff || !addsApproval(oldObj.Spec.Approvers, newObj.Spec.Approvers) {
+		return ""
+	}
+	key := r.checksAnnotationKey()
+	for _, obj := range []*v1alpha1.ApprovalTask{oldObj, newObj} {
+		status, ok := obj.Annotations[key]
+		status = strings.ToLower(strings.TrimSpace(status))
+		switch {
+		case status == checksFailure || status == checksConflict:
+			return fmt.Sprintf("Cannot approve while checks are failing: '%s' reports '%s'", key, status)
+		case r.checksGating == ChecksGatingRequirePassing && !ok:
+			return fmt.Sprintf("Cannot approve until checks pass: '%s' is not set", key)
+		case r.checksGating == ChecksGatingRequirePassing && status != checksSuccess:
+			return fmt.Sprintf("Cannot approve until checks pass: '%s' reports '%s'", key, status)
+		}
+	}
+	return ""
+}
+
+// checksAnnotationKey returns the annotation CI reports the checks in.
+func (r *reconciler) checksAnnotationKey() string {
+	if r.checksAnnotation == "" {
+		return v1alpha1.ChecksStatusAnnotationKey
+	}
+	return r.checksAnnotation
+}
+
+// ciAnnotations returns the annotations CI reports on ApprovalTasks: the
+// status of the checks and the digest of the artifact.
+func (r *reconciler) ciAnnotations() []string {
+	return []string{r.checksAnnotationKey(), v1alpha1.CurrentDigestAnnotationKey}
+}
+
+// isCIAnnotationUpdate reports whether the update is a CI identity, one of
+// r.ciUsers or a member of one of r.ciGroups, reporting on a pending task
+// through the annotations of ciAnnotations, and nothing else. It is admitted
+// whoever the approvers of the task are.
+func (r *reconciler) isCIAnnotationUpdate(oldObj, newObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest) bool {
+	if !webhookContains(r.ciUsers, request.UserInfo.Username) && !containsAny(r.ciGroups, request.UserInfo.Groups) {
+		return false
+	}
+	if !isApprovalRequired(*oldObj) || !isMetadataOnlyUpdate(oldObj, newObj) || !equality.Semantic.DeepEqual(oldObj.Labels, newObj.Labels) ||
+		!equality.Semantic.DeepEqual(oldObj.Finalizers, newObj.Finalizers) || !equality.Semantic.DeepEqual(oldObj.OwnerReferences, newObj.OwnerReferences) {
+		return false
+	}
+	oldRest, newRest := maps.Clone(oldObj.Annotations), maps.Clone(newObj.Annotations)
+	for _, key := range r.ciAnnotations() {
+		delete(oldRest, key)
+		delete(newRest, key)
+	}
+	return equality.Semantic.DeepEqual(oldRest, newRest)
+}
+
+// containsAny reports whether any of values is in list.
+func containsAny(list, values []string) bool {
+	for _, value := range values {
+		if webhookContains(list, value) {
+			return true
+		}
+	}
+	return false
+}
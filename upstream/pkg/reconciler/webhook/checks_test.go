@@ -0,0 +1,177 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+// checksApprovalTask returns a task whose checks report status, or that has
+// no checks status when status is empty.
+func checksApprovalTask(status string) *v1alpha1.ApprovalTask {
+	at := digestApprovalTask("")
+	at.Spec.ExpectedDigest = ""
+	if status != "" {
+		at.Annotations = map[string]string{v1alpha1.ChecksStatusAnnotationKey: status}
+	}
+	return at
+}
+
+func TestValidateChecks(t *testing.T) {
+	tests := []struct {
+		name   string
+		gating string
+		status string
+		want   string
+	}{{
+		name:   "passing",
+		status: "success",
+	}, {
+		name:   "pending",
+		status: "pending",
+	}, {
+		name: "not reported",
+	}, {
+		name:   "failing",
+		status: "failure",
+		want:   "Cannot approve while checks are failing: 'openshift-pipelines.org/checks-status' reports 'failure'",
+	}, {
+		name:   "merge conflict",
+		status: " Conflict",
+		want:   "Cannot approve while checks are failing: 'openshift-pipelines.org/checks-status' reports 'conflict'",
+	}, {
+		name:   "require passing with passing checks",
+		gating: ChecksGatingRequirePassing,
+		status: "SUCCESS",
+	}, {
+		name:   "require passing with pending checks",
+		gating: ChecksGatingRequirePassing,
+		status: "pending",
+		want:   "Cannot approve until checks pass: 'openshift-pipelines.org/checks-status' reports 'pending'",
+	}, {
+		name:   "require passing without checks",
+		gating: ChecksGatingRequirePassing,
+		want:   "Cannot approve until checks pass: 'openshift-pipelines.org/checks-status' is not set",
+	}, {
+		name:   "off",
+		gating: ChecksGatingOff,
+		status: "failure",
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &reconciler{checksGating: tc.gating}
+			oldObj := checksApprovalTask(tc.status)
+			newObj := oldObj.DeepCopy()
+			newObj.Spec.Approvers[0].Input = "approve"
+			assert.Equal(t, tc.want, r.validateChecks(oldObj, newObj))
+
+			newObj = oldObj.DeepCopy()
+			newObj.Spec.Approvers[0].Input = "reject"
+			assert.Empty(t, r.validateChecks(oldObj, newObj), "rejections are always allowed")
+		})
+	}
+}
+
+func TestValidateChecksAnnotation(t *testing.T) {
+	oldObj := checksApprovalTask("")
+	oldObj.Annotations = map[string]string{"ci.example.com/status": "failure"}
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	assert.Empty(t, (&reconciler{}).validateChecks(oldObj, newObj))
+	r := &reconciler{checksAnnotation: "ci.example.com/status"}
+	assert.Equal(t, "Cannot approve while checks are failing: 'ci.example.com/status' reports 'failure'", r.validateChecks(oldObj, newObj))
+}
+
+func TestAdmitFailingChecks(t *testing.T) {
+	oldObj := checksApprovalTask("failure")
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdate(t, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Cannot approve while checks are failing: 'openshift-pipelines.org/checks-status' reports 'failure'", resp.Result.Message)
+
+	// Approvers cannot report the checks as passing along with their approval
+	newObj.Annotations[v1alpha1.ChecksStatusAnnotationKey] = "success"
+	assert.False(t, admitUpdate(t, oldObj, newObj, "alice").Allowed)
+
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "reject"
+	assert.True(t, admitUpdate(t, oldObj, newObj, "alice").Allowed, "a failing change can still be rejected")
+
+	passing := checksApprovalTask("success")
+	newObj = passing.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	assert.True(t, admitUpdate(t, passing, newObj, "alice").Allowed)
+
+	r := &reconciler{privilegedGroup: DefaultPrivilegedGroup}
+	eligibility := r.eligibility(context.Background(), oldObj, authenticationv1.UserInfo{Username: "alice"})
+	assert.False(t, eligibility.CanApprove)
+	assert.True(t, eligibility.CanReject)
+}
+
+func TestAdmitChecksReportedByCI(t *testing.T) {
+	r := &reconciler{ciUsers: []string{"system:serviceaccount:ci:reporter"}, ciGroups: []string{"ci-bots"}}
+	failing := checksApprovalTask("failure")
+	ci := authenticationv1.UserInfo{Username: "system:serviceaccount:ci:reporter"}
+
+	approved := failing.DeepCopy()
+	approved.Spec.Approvers[0].Input = "approve"
+	resp := admitUpdateWith(t, r, failing, approved, "alice")
+	assert.False(t, resp.Allowed, "checks are failing")
+
+	passing := failing.DeepCopy()
+	passing.Annotations[v1alpha1.ChecksStatusAnnotationKey] = "success"
+	resp = admitUpdateAs(t, r, failing, passing, ci)
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	resp = admitUpdateAs(t, r, failing, passing, authenticationv1.UserInfo{Username: "bot", Groups: []string{"ci-bots"}})
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	approved = passing.DeepCopy()
+	approved.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdateWith(t, r, passing, approved, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	// Only CI reports the checks
+	resp = admitUpdateWith(t, r, failing, passing, "alice")
+	assert.False(t, resp.Allowed)
+	resp = admitUpdateWith(t, &reconciler{}, failing, passing, "system:serviceaccount:ci:reporter")
+	assert.False(t, resp.Allowed, "no CI identity is configured")
+
+	// And nothing else
+	relabeled := passing.DeepCopy()
+	relabeled.Labels = map[string]string{"env": "staging"}
+	resp = admitUpdateAs(t, r, failing, relabeled, ci)
+	assert.False(t, resp.Allowed)
+	reapproved := passing.DeepCopy()
+	reapproved.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdateAs(t, r, failing, reapproved, ci)
+	assert.False(t, resp.Allowed)
+
+	final := approved.DeepCopy()
+	final.Status.State = "approved"
+	refailed := final.DeepCopy()
+	refailed.Annotations[v1alpha1.ChecksStatusAnnotationKey] = "failure"
+	resp = admitUpdateAs(t, r, final, refailed, ci)
+	assert.False(t, resp.Allowed, "final tasks are not reported on")
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// checkResourceVersion denies, when current resource versions are required,
+// updates changing the spec or status of an ApprovalTask that do not carry
+// the resourceVersion of the live resource. The live resource is the old
+// object of the request, or the webhook's cached copy when the old object
+// carries no resourceVersion. Metadata-only updates, such as retries of an
+// applied decision, are not checked.
+func (r *reconciler) checkResourceVersion(oldObj, newObj *v1alpha1.ApprovalTask) *admissionv1.AdmissionResponse {
+	if !r.requireCurrentVersion || isMetadataOnlyUpdate(oldObj, newObj) {
+		return nil
+	}
+	if newObj.ResourceVersion == "" {
+		return conflictStatus("conflict: updates must carry the resourceVersion of the approval task they are based on; refetch it and retry")
+	}
+	live := r.liveResourceVersion(oldObj)
+	if live == "" || live == newObj.ResourceVersion {
+		return nil
+	}
+	return conflictStatus(fmt.Sprintf("conflict: approval task %s changed since it was read (resourceVersion %s, current %s); refetch it and retry",
+		newObj.Name, newObj.ResourceVersion, live))
+}
+
+// liveResourceVersion returns the resourceVersion of the live approval task,
+// or an empty string when it is unknown.
+func (r *reconciler) liveResourceVersion(oldObj *v1alpha1.ApprovalTask) string {
+	if oldObj.ResourceVersion != "" || r.tasklister == nil {
+		return oldObj.ResourceVersion
+	}
+	at, err := r.tasklister.ApprovalTasks(oldObj.Namespace).Get(oldObj.Name)
+	if err != nil {
+		return ""
+	}
+	return at.ResourceVersion
+}
+
+// conflictStatus denies a request with a Conflict status, which clients
+// treat as a signal to refetch the object and retry.
+func conflictStatus(message string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusConflict,
+			Reason:  metav1.StatusReasonConflict,
+			Message: message,
+		},
+	}
+}
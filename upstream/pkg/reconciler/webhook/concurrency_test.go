@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	approvalpolicylisters "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newVersionCheckingReconciler(t *testing.T, tasks ...*v1alpha1.ApprovalTask) *reconciler {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, at := range tasks {
+		assert.NoError(t, indexer.Add(at))
+	}
+	return &reconciler{requireCurrentVersion: true, tasklister: approvalpolicylisters.NewApprovalTaskLister(indexer)}
+}
+
+func TestAdmitStaleDecisionConflicts(t *testing.T) {
+	r := newVersionCheckingReconciler(t)
+
+	// alice read the task at version 1, bob's approval moved it to version 2
+	live := keyedApprovalTask()
+	live.ResourceVersion = "2"
+	live.Spec.Approvers[1].Input = "approve"
+	live.Spec.Approvers[1].Users[0].Input = "approve"
+
+	stale := keyedApprovalTask()
+	stale.ResourceVersion = "1"
+	stale.Spec.Approvers[0].Input = "approve"
+	resp := admitUpdateWith(t, r, live, stale, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, int32(http.StatusConflict), resp.Result.Code)
+	assert.Equal(t, metav1.StatusReasonConflict, resp.Result.Reason)
+	assert.Equal(t, "conflict: approval task deploy changed since it was read (resourceVersion 1, current 2); refetch it and retry", resp.Result.Message)
+
+	// After refetching, alice's decision is based on the live task
+	current := live.DeepCopy()
+	current.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdateWith(t, r, live, current, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	unversioned := current.DeepCopy()
+	unversioned.ResourceVersion = ""
+	resp = admitUpdateWith(t, r, live, unversioned, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "conflict: updates must carry the resourceVersion of the approval task they are based on; refetch it and retry", resp.Result.Message)
+
+	unchanged := keyedApprovalTask()
+	unchanged.ResourceVersion = "2"
+	resp = admitUpdateWith(t, &reconciler{}, unchanged, stale, "alice")
+	assert.True(t, resp.Allowed, "resource versions are only checked when required: %v", resp.Result)
+}
+
+func TestAdmitStaleDecisionAgainstCachedTask(t *testing.T) {
+	cached := keyedApprovalTask()
+	cached.ResourceVersion = "7"
+	r := newVersionCheckingReconciler(t, cached)
+
+	// Without a resourceVersion on the old object the cached copy is the live one
+	old := keyedApprovalTask()
+	stale := keyedApprovalTask()
+	stale.ResourceVersion = "6"
+	stale.Spec.Approvers[0].Input = "approve"
+	resp := admitUpdateWith(t, r, old, stale, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "conflict: approval task deploy changed since it was read (resourceVersion 6, current 7); refetch it and retry", resp.Result.Message)
+
+	stale.ResourceVersion = "7"
+	resp = admitUpdateWith(t, r, old, stale, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+}
+
+func TestAdmitStaleMetadataUpdate(t *testing.T) {
+	r := newVersionCheckingReconciler(t)
+	live := keyedApprovalTask()
+	live.ResourceVersion = "2"
+
+	relabeled := keyedApprovalTask()
+	relabeled.ResourceVersion = "1"
+	relabeled.Labels = map[string]string{"team": "platform"}
+	assert.Nil(t, r.checkResourceVersion(live, relabeled), "metadata-only updates cannot overwrite a decision")
+}
@@ -2,28 +2,413 @@ package webhook
 
 import (
 	"context"
+	"crypto/ed25519"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
+	approvaltaskscheme "github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/scheme"
+	approvaltaskinformers "github.com/openshift-pipelines/manual-approval-gate/pkg/client/informers/externalversions"
+	approvaltaskclient "github.com/openshift-pipelines/manual-approval-gate/pkg/client/injection/client"
+	approvaltaskinformer "github.com/openshift-pipelines/manual-approval-gate/pkg/client/injection/informers/approvaltask/v1alpha1/approvaltask"
+	policyinformer "github.com/openshift-pipelines/manual-approval-gate/pkg/client/injection/informers/approvaltask/v1alpha1/clusterapprovalpolicy"
+	"golang.org/x/time/rate"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
 	kubeclient "knative.dev/pkg/client/injection/kube/client"
 	vwhinformer "knative.dev/pkg/client/injection/kube/informers/admissionregistration/v1/validatingwebhookconfiguration"
+	nsinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/namespace"
 	"knative.dev/pkg/controller"
 	secretinformer "knative.dev/pkg/injection/clients/namespacedkube/informers/core/v1/secret"
 	"knative.dev/pkg/logging"
 	pkgreconciler "knative.dev/pkg/reconciler"
 	"knative.dev/pkg/system"
 	"knative.dev/pkg/webhook"
+	certresources "knative.dev/pkg/webhook/certificates/resources"
 )
 
+const (
+	// DefaultCoalesceWindow is the default delay used to collapse bursts of
+	// secret and webhook configuration events into a single reconcile.
+	DefaultCoalesceWindow = time.Second
+	// DefaultRateLimiterQPS is the default overall reconcile rate of the work queue.
+	DefaultRateLimiterQPS = 5
+	// DefaultRateLimiterBurst is the default burst of the work queue rate limiter.
+	DefaultRateLimiterBurst = 10
+	// DefaultPrivilegedGroup is the default group allowed to perform
+	// administrative changes to ApprovalTasks, such as pausing them.
+	DefaultPrivilegedGroup = "system:masters"
+	// DefaultControllerServiceAccount is the service account the controller
+	// runs as in the shipped manifests.
+	DefaultControllerServiceAccount = "manual-approval-gate-controller"
+	// DefaultDrainTimeout is the default time admissions in flight are given
+	// to complete when the webhook shuts down. Along with
+	// DefaultEventFlushTimeout, it stays below the terminationGracePeriodSeconds
+	// of 30 the shipped manifests give the webhook pod, so that it exits
+	// before it is killed.
+	DefaultDrainTimeout = 20 * time.Second
+	// DefaultEventFlushTimeout is the time the webhook waits on shutdown, on
+	// top of the drain timeout, for the events of the drained admissions to
+	// be flushed.
+	DefaultEventFlushTimeout = 5 * time.Second
+	// DefaultMaxConcurrentResolutions is the default number of resolutions,
+	// such as group lookups, that admissions run at once.
+	DefaultMaxConcurrentResolutions = 32
+)
+
+// Options holds the optional settings of the approval admission controller.
+// Zero values fall back to the defaults above.
+type Options struct {
+	// CoalesceWindow delays reconciles triggered by informer events so that
+	// events arriving within the window result in a single reconcile.
+	CoalesceWindow time.Duration
+	// RateLimiterQPS and RateLimiterBurst bound how often the validating
+	// webhook configuration can be reconciled, and therefore updated.
+	RateLimiterQPS   float64
+	RateLimiterBurst int
+	// PrivilegedGroup is the group whose members may perform administrative
+	// changes to ApprovalTasks, such as pausing them.
+	PrivilegedGroup string
+	// EligibilityAddress is the address the approver eligibility endpoint is
+	// served on over TLS, with the serving certificate of the webhook, for
+	// example ":8080". The endpoint is disabled when empty.
+	EligibilityAddress string
+	// ReceiptKey signs the receipts of finalized ApprovalTasks served next to
+	// the eligibility endpoint. Receipts are not served when it is nil.
+	ReceiptKey ed25519.PrivateKey
+	// CABundleKey is the key of the webhook secret holding the CA bundle to
+	// publish in the webhook configuration. It may concatenate several PEM
+	// encoded CAs during a rotation. Defaults to the certificates controller's
+	// single CA cert key.
+	CABundleKey string
+	// EnforceApprovalOrder denies approvals submitted before every approver
+	// of higher priority has approved. Otherwise they are accepted but not
+	// counted until then.
+	EnforceApprovalOrder bool
+	// AllowFinalMetadataUpdates lets updates to ApprovalTasks that have
+	// reached a final state through when they only change labels,
+	// annotations or other metadata. Spec and status changes are still
+	// denied.
+	AllowFinalMetadataUpdates bool
+	// MaxApprovers caps the number of approvers of an ApprovalTask, counting
+	// each approver entry and each listed group member. Zero means no limit.
+	MaxApprovers int
+	// RuleAPIGroups, RuleAPIVersions and RuleResources select the requests
+	// the webhook is registered for, and RuleScope is "Namespaced", "Cluster"
+	// or "*". RuleSubresources adds the given subresources, e.g. "status", of
+	// every resource. They default to the ApprovalTask resource.
+	RuleAPIGroups    []string
+	RuleAPIVersions  []string
+	RuleResources    []string
+	RuleSubresources []string
+	RuleScope        string
+	// RequiredExtraClaim, when set, denies decisions from users whose
+	// UserInfo.Extra lacks the claim, for example a verified-email marker
+	// set by the identity provider. RequiredExtraClaimValue additionally
+	// requires one of the claim's values to equal it.
+	RequiredExtraClaim      string
+	RequiredExtraClaimValue string
+	// MaxTokenAge, when set, denies decisions from users whose token was
+	// issued longer ago, according to the TokenIssuedAtClaim extra claim,
+	// so that sensitive gates need a fresh login. Tokens without the claim
+	// are denied too. TokenIssuedAtClaim defaults to
+	// DefaultTokenIssuedAtClaim.
+	MaxTokenAge        time.Duration
+	TokenIssuedAtClaim string
+	// AllowedSourceRanges, when set, denies decisions originating from
+	// addresses outside the ranges, according to the SourceIPClaim extra
+	// claim populated by an authenticating proxy. Decisions without the
+	// claim are denied too. SourceIPClaim defaults to DefaultSourceIPClaim.
+	AllowedSourceRanges []netip.Prefix
+	SourceIPClaim       string
+	// AllowedImpersonations lists the users that may decide through
+	// impersonation, for example the identities approval bots impersonate.
+	// Requests are impersonated when they carry the ImpersonatorClaim extra
+	// claim, which defaults to DefaultImpersonatorClaim. Decisions through
+	// impersonation of anyone else are denied. The check is advisory: it
+	// relies on impersonators sending the claim, which only RBAC on the
+	// impersonate verb can enforce.
+	AllowedImpersonations []string
+	ImpersonatorClaim     string
+	// ChecksAnnotation is the annotation CI reports the checks of the
+	// change an ApprovalTask gates in. Defaults to
+	// v1alpha1.ChecksStatusAnnotationKey. ChecksGating is one of
+	// ChecksGatings and decides which reported statuses deny approvals.
+	// Defaults to ChecksGatingFailing.
+	ChecksAnnotation string
+	ChecksGating     string
+	// CIUsers and the members of CIGroups may update the annotations CI
+	// reports on pending ApprovalTasks, ChecksAnnotation and
+	// v1alpha1.CurrentDigestAnnotationKey, in updates changing nothing else.
+	CIUsers  []string
+	CIGroups []string
+	// RequireChangeTicket denies approvals on ApprovalTasks whose
+	// changeTicket is missing or does not match ChangeTicketPattern, so
+	// that every approval is tied to a tracked change. ChangeTicketPattern
+	// defaults to DefaultChangeTicketPattern.
+	RequireChangeTicket bool
+	ChangeTicketPattern *regexp.Regexp
+	// MetricsLabelCap bounds the number of distinct namespaces, and of task
+	// names, labelling the admission decision metrics. Further values are
+	// recorded without the label. Defaults to DefaultMetricsLabelCap.
+	MetricsLabelCap int
+	// MetricsTaskNames adds the task name label to the admission decision
+	// metrics.
+	MetricsTaskNames bool
+	// RequireCurrentResourceVersion denies updates that change the spec or
+	// status of an ApprovalTask unless they carry its current
+	// resourceVersion, so that a decision based on a stale copy is refused
+	// with a conflict instead of overwriting another approver's.
+	RequireCurrentResourceVersion bool
+	// ExplicitGroupMembership only counts users listed in the users of a
+	// Group approver as its members, ignoring the groups asserted by their
+	// token. Group members then cannot add themselves to the list.
+	ExplicitGroupMembership bool
+	// GroupHierarchySeparator separates the levels of hierarchical group
+	// names, such as ":" in "org:dept:team". When set, a Group approver
+	// also matches the members of its descendant groups, so that "org:dept"
+	// can be decided by a user in "org:dept:team". Ignored with
+	// ExplicitGroupMembership.
+	GroupHierarchySeparator string
+	// UnknownApproverTypes decides how ApprovalTasks with approvers of a
+	// type that is not one of v1alpha1.KnownApproverTypes are admitted, as
+	// one of UnknownApproverTypeModes. Such approvers never count towards
+	// the quorum. Defaults to UnknownApproverTypesDeny.
+	UnknownApproverTypes string
+	// ForbidGroupSelfAdd stops members of a group asserted by their token
+	// from adding themselves to the users of its Group approver, for
+	// organizations that curate these lists. Only the users already listed
+	// can then record a decision of their own.
+	ForbidGroupSelfAdd bool
+	// Blocklist lists identities, usernames or emails, whose decisions on
+	// any ApprovalTask are denied, in addition to the blocklist of each task.
+	Blocklist []string
+	// UsernameRedaction redacts the usernames written to the logs, as one
+	// of UsernameRedactions. The audit annotations keep them in full.
+	// Defaults to UsernameRedactionOff.
+	UsernameRedaction string
+	// UsernameRedactionKey keys the hash of usernames with
+	// UsernameRedactionHash, so that they cannot be recovered by hashing
+	// candidate names.
+	UsernameRedactionKey []byte
+	// GroupResolver looks up group memberships not asserted by the token of
+	// the user. Decisions depending on a membership it fails to look up are
+	// denied as unverified. Ignored with ExplicitGroupMembership.
+	GroupResolver GroupResolver
+	// MaxConcurrentResolutions bounds the resolutions calling out of the
+	// webhook, such as the lookups of the GroupResolver, that admissions run
+	// at once. Decisions needing one while all are running are denied at
+	// once with a Too Many Requests status, for the client to retry.
+	// Defaults to DefaultMaxConcurrentResolutions.
+	MaxConcurrentResolutions int
+	// DecisionInterceptors rearranges the chain of interceptors deciding on
+	// updates to ApprovalTasks. It is given the built-in chain and returns
+	// the chain to run, for example with InsertInterceptor adding a check
+	// before one of the built-ins. The built-in chain runs when it is nil.
+	DecisionInterceptors func(defaults []DecisionInterceptor) []DecisionInterceptor
+	// InstanceName and InstanceNamespace identify the pod of this webhook
+	// replica in the audit annotations of its admission responses, so that
+	// decisions can be traced to the replica that admitted them. The
+	// identity is not recorded when InstanceName is empty.
+	InstanceName      string
+	InstanceNamespace string
+	// ControllerUsername is the user the controller authenticates as. Only
+	// it may set the number of approvals required of an ApprovalTask that
+	// sets none. Such tasks cannot be backfilled when it is empty.
+	ControllerUsername string
+	// QuarantineThreshold turns on the quarantine of users submitting
+	// repeated invalid changes to an ApprovalTask: once the denied updates
+	// of a user to a task, halving in weight every QuarantineHalfLife, add
+	// up to the threshold, all their updates to it are denied for
+	// QuarantineDuration. Zero turns the quarantine off. The controller and
+	// the members of the privileged group are never quarantined.
+	QuarantineThreshold int
+	QuarantineDuration  time.Duration
+	QuarantineHalfLife  time.Duration
+	// QuarantineStatusAnnotation flags the users quarantined on an
+	// ApprovalTask in the QuarantinedUsersAnnotationKey annotation of its
+	// status.
+	QuarantineStatusAnnotation bool
+	// SignDecisions signs the statement of each allowed request with the
+	// private key of the webhook's serving certificate, in the background,
+	// recording both in the DecisionSignaturesAnnotationKey status
+	// annotation of the task, so that decisions can be verified against the
+	// certificate.
+	SignDecisions bool
+	// DrainTimeout bounds the time admissions in flight are given to
+	// complete when the webhook shuts down, before the events they emit are
+	// flushed. Defaults to DefaultDrainTimeout. It must leave room for
+	// DefaultEventFlushTimeout within the terminationGracePeriodSeconds of
+	// the webhook pod.
+	DrainTimeout time.Duration
+	// Shutdown, when set, tracks the draining of the admissions in flight
+	// and the flushing of their events once the webhook stops, for the main
+	// function to wait for them before exiting.
+	Shutdown *Shutdown
+}
+
+func (o Options) rateLimiter() workqueue.TypedRateLimiter[any] {
+	qps, burst := o.RateLimiterQPS, o.RateLimiterBurst
+	if qps <= 0 {
+		qps = DefaultRateLimiterQPS
+	}
+	if burst <= 0 {
+		burst = DefaultRateLimiterBurst
+	}
+	return workqueue.NewTypedMaxOfRateLimiter(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[any](5*time.Millisecond, 1000*time.Second),
+		&workqueue.TypedBucketRateLimiter[any]{Limiter: rate.NewLimiter(rate.Limit(qps), burst)},
+	)
+}
+
+func (o Options) privilegedGroup() string {
+	if o.PrivilegedGroup == "" {
+		return DefaultPrivilegedGroup
+	}
+	return o.PrivilegedGroup
+}
+
+func (o Options) caBundleKey() string {
+	if o.CABundleKey == "" {
+		return certresources.CACert
+	}
+	return o.CABundleKey
+}
+
+// rules returns the rules written into the validating webhook configuration.
+func (o Options) rules() []admissionregistrationv1.RuleWithOperations {
+	apiGroups, apiVersions, resources := o.RuleAPIGroups, o.RuleAPIVersions, o.RuleResources
+	if len(apiGroups) == 0 {
+		apiGroups = []string{Group}
+	}
+	if len(apiVersions) == 0 {
+		apiVersions = []string{Version}
+	}
+	if len(resources) == 0 {
+		resources = []string{"approvaltask", "approvaltasks"}
+	}
+	ruleResources := append([]string{}, resources...)
+	for _, resource := range resources {
+		for _, subresource := range o.RuleSubresources {
+			ruleResources = append(ruleResources, resource+"/"+subresource)
+		}
+	}
+
+	rule := admissionregistrationv1.Rule{
+		APIGroups:   apiGroups,
+		APIVersions: apiVersions,
+		Resources:   ruleResources,
+	}
+	if o.RuleScope != "" {
+		scope := admissionregistrationv1.ScopeType(o.RuleScope)
+		rule.Scope = &scope
+	}
+	return []admissionregistrationv1.RuleWithOperations{{
+		Operations: []admissionregistrationv1.OperationType{
+			admissionregistrationv1.Create,
+			admissionregistrationv1.Update,
+		},
+		Rule: rule,
+	}}
+}
+
+// canonicalRules returns a copy of rules with their operations, API groups,
+// versions and resources, and the rules themselves, in a canonical order, so
+// that rule sets differing only in ordering compare equal.
+func canonicalRules(rules []admissionregistrationv1.RuleWithOperations) []admissionregistrationv1.RuleWithOperations {
+	canonical := make([]admissionregistrationv1.RuleWithOperations, 0, len(rules))
+	for _, rule := range rules {
+		rule = *rule.DeepCopy()
+		slices.Sort(rule.Operations)
+		slices.Sort(rule.APIGroups)
+		slices.Sort(rule.APIVersions)
+		slices.Sort(rule.Resources)
+		canonical = append(canonical, rule)
+	}
+	slices.SortStableFunc(canonical, func(a, b admissionregistrationv1.RuleWithOperations) int {
+		return strings.Compare(ruleKey(a), ruleKey(b))
+	})
+	return canonical
+}
+
+// ruleKey orders canonical rules in canonicalRules.
+func ruleKey(rule admissionregistrationv1.RuleWithOperations) string {
+	scope := ""
+	if rule.Scope != nil {
+		scope = string(*rule.Scope)
+	}
+	operations := make([]string, 0, len(rule.Operations))
+	for _, operation := range rule.Operations {
+		operations = append(operations, string(operation))
+	}
+	return strings.Join([]string{
+		strings.Join(rule.APIGroups, ","),
+		strings.Join(rule.APIVersions, ","),
+		strings.Join(rule.Resources, ","),
+		scope,
+		strings.Join(operations, ","),
+	}, "|")
+}
+
+func (o Options) coalesceWindow() time.Duration {
+	if o.CoalesceWindow <= 0 {
+		return DefaultCoalesceWindow
+	}
+	return o.CoalesceWindow
+}
+
+func (o Options) maxConcurrentResolutions() int {
+	if o.MaxConcurrentResolutions <= 0 {
+		return DefaultMaxConcurrentResolutions
+	}
+	return o.MaxConcurrentResolutions
+}
+
+func (o Options) drainTimeout() time.Duration {
+	if o.DrainTimeout <= 0 {
+		return DefaultDrainTimeout
+	}
+	return o.DrainTimeout
+}
+
+// ShutdownTimeout is the longest the shutdown tracked by Shutdown takes: the
+// drain timeout followed by DefaultEventFlushTimeout.
+func (o Options) ShutdownTimeout() time.Duration {
+	return o.drainTimeout() + DefaultEventFlushTimeout
+}
+
+// enqueueCoalesced returns an event handler that enqueues the singleton key
+// after window. The work queue keeps a single entry for a key that is already
+// waiting, so a burst of events collapses into one reconcile.
+func enqueueCoalesced(impl *controller.Impl, key types.NamespacedName, window time.Duration) func(interface{}) {
+	return func(interface{}) {
+		impl.EnqueueKeyAfter(key, window)
+	}
+}
+
 func NewAdmissionController(ctx context.Context,
 	name, path string,
 	wc func(context.Context) context.Context,
 	disallowUnknownFields bool,
+	opts Options,
 ) *controller.Impl {
 
 	client := kubeclient.Get(ctx)
 	vwhInformer := vwhinformer.Get(ctx)
 	secretInformer := secretinformer.Get(ctx)
+	nsInformer := nsinformer.Get(ctx)
+	policyInformer := policyinformer.Get(ctx)
+	approvalTaskInformer := approvaltaskinformer.Get(ctx)
 	options := webhook.GetOptions(ctx)
 
 	key := types.NamespacedName{
@@ -31,6 +416,11 @@ func NewAdmissionController(ctx context.Context,
 		Name:      name,
 	}
 
+	// Admissions in flight when ctx is done still decide, and emit events
+	admissions := &admissionTracker{}
+	recorder := newEventRecorder(ctx, client, name, func() {
+		admissions.drain(ctx, opts.drainTimeout())
+	}, opts.Shutdown.add())
 	c := &reconciler{
 		LeaderAwareFuncs: pkgreconciler.LeaderAwareFuncs{
 			// Have this reconciler enqueue our singleton whenever it becomes leader.
@@ -46,21 +436,79 @@ func NewAdmissionController(ctx context.Context,
 		withContext:           wc,
 		disallowUnknownFields: disallowUnknownFields,
 		secretName:            options.SecretName,
+		caBundleKey:           opts.caBundleKey(),
+		privilegedGroup:       opts.privilegedGroup(),
+		enforceApprovalOrder:  opts.EnforceApprovalOrder,
+		allowFinalMetadata:    opts.AllowFinalMetadataUpdates,
+		maxApprovers:          opts.MaxApprovers,
+		rules:                 opts.rules(),
+		requiredClaim:         opts.RequiredExtraClaim,
+		requiredClaimValue:    opts.RequiredExtraClaimValue,
+		maxTokenAge:           opts.MaxTokenAge,
+		tokenIssuedAtClaim:    opts.TokenIssuedAtClaim,
+		sourceRanges:          opts.AllowedSourceRanges,
+		sourceIPClaim:         opts.SourceIPClaim,
+		allowedImpersonations: opts.AllowedImpersonations,
+		impersonatorClaim:     opts.ImpersonatorClaim,
+		checksAnnotation:      opts.ChecksAnnotation,
+		checksGating:          opts.ChecksGating,
+		ciUsers:               opts.CIUsers,
+		ciGroups:              opts.CIGroups,
+		requireChangeTicket:   opts.RequireChangeTicket,
+		changeTicketPattern:   opts.ChangeTicketPattern,
+		requireCurrentVersion: opts.RequireCurrentResourceVersion,
+		explicitGroupMembers:  opts.ExplicitGroupMembership,
+		groupSeparator:        opts.GroupHierarchySeparator,
+		unknownApproverTypes:  opts.UnknownApproverTypes,
+		forbidGroupSelfAdd:    opts.ForbidGroupSelfAdd,
+		blocklist:             opts.Blocklist,
+		usernames:             usernameRedactor{mode: opts.UsernameRedaction, key: opts.UsernameRedactionKey},
+		groupResolver:         opts.GroupResolver,
+		resolutions:           newResolutionLimiter(opts.maxConcurrentResolutions()),
+		interceptors:          opts.DecisionInterceptors,
+		instance:              instanceIdentity(opts.InstanceNamespace, opts.InstanceName),
+		controllerUsername:    opts.ControllerUsername,
+		decisions:             newDecisionReporter(opts.MetricsLabelCap, opts.MetricsTaskNames),
+		clock:                 clock.RealClock{},
+		quarantine:            newQuarantine(opts, clock.RealClock{}, recorder),
+		recorder:              recorder,
+		admissions:            admissions,
 
 		client:       client,
 		vwhlister:    vwhInformer.Lister(),
 		secretlister: secretInformer.Lister(),
+		nslister:     nsInformer.Lister(),
+		policylister: policyInformer.Lister(),
+		tasklister:   approvalTaskInformer.Lister(),
+	}
+
+	if c.quarantine != nil && opts.QuarantineStatusAnnotation {
+		c.quarantine.flag = statusFlagger(ctx, approvaltaskclient.Get(ctx), c.quarantine.clock)
+	}
+
+	if opts.SignDecisions {
+		c.signer = newDecisionSigner(func() (*corev1.Secret, error) {
+			return c.secretlister.Secrets(system.Namespace()).Get(c.secretName)
+		}, approvaltaskclient.Get(ctx))
+		go c.signer.run(ctx, func() {
+			admissions.drain(ctx, opts.drainTimeout())
+		}, DefaultEventFlushTimeout, opts.Shutdown.add())
 	}
 
 	logger := logging.FromContext(ctx)
-	cont := controller.NewContext(ctx, c, controller.ControllerOptions{WorkQueueName: "ValidatingWebhook", Logger: logger})
+	cont := controller.NewContext(ctx, c, controller.ControllerOptions{
+		WorkQueueName: "ValidatingWebhook",
+		Logger:        logger,
+		RateLimiter:   opts.rateLimiter(),
+	})
+	enqueue := enqueueCoalesced(cont, key, opts.coalesceWindow())
 
 	// Reconcile when the named ValidatingWebhookConfiguration changes.
 	if _, err := vwhInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
 		FilterFunc: controller.FilterWithName(name),
 		// It doesn't matter what we enqueue because we will always Reconcile
 		// the named MWH resource.
-		Handler: controller.HandleAll(cont.Enqueue),
+		Handler: controller.HandleAll(enqueue),
 	}); err != nil {
 		logger.Panicf("couldn't register ValidatingWebhookConfiguration informer event handler: %w", err)
 	}
@@ -70,10 +518,41 @@ func NewAdmissionController(ctx context.Context,
 		FilterFunc: controller.FilterWithNameAndNamespace(system.Namespace(), c.secretName),
 		// It doesn't matter what we enqueue because we will always Reconcile
 		// the named MWH resource.
-		Handler: controller.HandleAll(cont.Enqueue),
+		Handler: controller.HandleAll(enqueue),
 	}); err != nil {
 		logger.Panicf("couldn't register Secret informer event handler: %w", err)
 	}
 
+	if opts.EligibilityAddress != "" {
+		approvalTasks := approvaltaskclient.Get(ctx)
+		mux := http.NewServeMux()
+		mux.Handle(EligibilityPath, &eligibilityHandler{admission: c, approvalTasks: approvalTasks})
+		// The listing needs the tasks of every namespace, while the informers
+		// of the webhook are scoped to its own
+		factory := approvaltaskinformers.NewSharedInformerFactory(approvalTasks, controller.GetResyncPeriod(ctx))
+		tasks := factory.Openshiftpipelines().V1alpha1().ApprovalTasks()
+		mux.Handle(ActionablePath, &actionableHandler{admission: c, tasks: tasks.Lister(), synced: tasks.Informer().HasSynced})
+		factory.Start(ctx.Done())
+		if opts.ReceiptKey != nil {
+			mux.Handle(ReceiptPath, &receiptHandler{admission: c, approvalTasks: approvalTasks, key: opts.ReceiptKey})
+		}
+		go serveAPI(ctx, opts.EligibilityAddress, mux, c.serverCertificate)
+	}
+
 	return cont
 }
+
+// newEventRecorder returns a recorder emitting the events of the webhook as
+// component until ctx is done, and drain returns. The events recorded by
+// then are flushed to the sink, after which done is called.
+func newEventRecorder(ctx context.Context, client kubernetes.Interface, component string, drain, done func()) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	go func() {
+		defer done()
+		<-ctx.Done()
+		drain()
+		broadcaster.Shutdown()
+	}()
+	return broadcaster.NewRecorder(approvaltaskscheme.Scheme, corev1.EventSource{Component: component})
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	admissionlisters "k8s.io/client-go/listers/admissionregistration/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	ktesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	pkgreconciler "knative.dev/pkg/reconciler"
+	certresources "knative.dev/pkg/webhook/certificates/resources"
+)
+
+const (
+	testNamespace   = "tekton-pipelines"
+	testWebhookName = "validation.webhook.manual-approval.openshift-pipelines.org"
+	testSecretName  = "manual-approval-gate-webhook-certs"
+)
+
+func testValidatingWebhook() *admissionregistrationv1.ValidatingWebhookConfiguration {
+	return &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: testWebhookName},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+			Name: testWebhookName,
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{Namespace: testNamespace, Name: "manual-approval-webhook"},
+			},
+		}},
+	}
+}
+
+func testWebhookSecret(data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testSecretName},
+		Data:       data,
+	}
+}
+
+// newTestReconciler builds a webhook reconciler backed by a fake client and
+// static listers, which never observe the reconciler's own updates.
+func newTestReconciler(t *testing.T, vwh *admissionregistrationv1.ValidatingWebhookConfiguration, secret *corev1.Secret) (*reconciler, *fake.Clientset) {
+	t.Helper()
+	t.Setenv("SYSTEM_NAMESPACE", testNamespace)
+
+	client := fake.NewSimpleClientset(vwh, secret)
+
+	vwhIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	assert.NoError(t, vwhIndexer.Add(vwh))
+	secretIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	assert.NoError(t, secretIndexer.Add(secret))
+
+	key := types.NamespacedName{Namespace: testNamespace, Name: testWebhookName}
+	r := &reconciler{
+		LeaderAwareFuncs: pkgreconciler.LeaderAwareFuncs{
+			PromoteFunc: func(bkt pkgreconciler.Bucket, enq func(pkgreconciler.Bucket, types.NamespacedName)) error {
+				return nil
+			},
+		},
+		key:          key,
+		path:         "/approval-validation",
+		client:       client,
+		vwhlister:    admissionlisters.NewValidatingWebhookConfigurationLister(vwhIndexer),
+		secretlister: corelisters.NewSecretLister(secretIndexer),
+		secretName:   testSecretName,
+	}
+	assert.NoError(t, r.Promote(pkgreconciler.UniversalBucket(), func(pkgreconciler.Bucket, types.NamespacedName) {}))
+	return r, client
+}
+
+func countWebhookUpdates(client *fake.Clientset) *int32 {
+	var updates int32
+	client.PrependReactor("update", "validatingwebhookconfigurations", func(action ktesting.Action) (bool, runtime.Object, error) {
+		atomic.AddInt32(&updates, 1)
+		return false, nil, nil
+	})
+	return &updates
+}
+
+func TestEnqueueCoalescedBoundsWebhookUpdates(t *testing.T) {
+	r, client := newTestReconciler(t, testValidatingWebhook(), testWebhookSecret(map[string][]byte{certresources.CACert: []byte("ca")}))
+	updates := countWebhookUpdates(client)
+
+	ctx, cancel := context.WithCancel(logging.WithLogger(context.Background(), logging.FromContext(context.Background())))
+	defer cancel()
+
+	opts := Options{CoalesceWindow: 100 * time.Millisecond}
+	impl := controller.NewContext(ctx, r, controller.ControllerOptions{
+		WorkQueueName: "ValidatingWebhook",
+		Logger:        logging.FromContext(ctx),
+		RateLimiter:   opts.rateLimiter(),
+	})
+	enqueue := enqueueCoalesced(impl, r.key, opts.coalesceWindow())
+
+	// Flood the queue with secret and webhook configuration events.
+	for i := 0; i < 500; i++ {
+		enqueue(testWebhookSecret(nil))
+	}
+
+	go func() {
+		_ = impl.RunContext(ctx, 1)
+	}()
+	time.Sleep(time.Second)
+
+	// The static lister never reflects the update, so every reconcile
+	// would update the webhook again: the burst must collapse into one.
+	assert.Equal(t, int32(1), atomic.LoadInt32(updates))
+}
+
+func TestOptionsDefaults(t *testing.T) {
+	assert.Equal(t, DefaultCoalesceWindow, Options{}.coalesceWindow())
+	assert.Equal(t, 2*time.Second, Options{CoalesceWindow: 2 * time.Second}.coalesceWindow())
+	assert.NotNil(t, Options{RateLimiterQPS: 1, RateLimiterBurst: 1}.rateLimiter())
+	assert.Equal(t, DefaultPrivilegedGroup, Options{}.privilegedGroup())
+	assert.Equal(t, "release-admins", Options{PrivilegedGroup: "release-admins"}.privilegedGroup())
+	assert.Equal(t, certresources.CACert, Options{}.caBundleKey())
+}
+
+func TestReconcilePublishesCABundleDuringRotation(t *testing.T) {
+	notAfter := time.Now().Add(time.Hour)
+	_, _, oldCA, err := certresources.CreateCerts(context.Background(), "manual-approval-webhook", testNamespace, notAfter)
+	assert.NoError(t, err)
+	_, _, newCA, err := certresources.CreateCerts(context.Background(), "manual-approval-webhook", testNamespace, notAfter)
+	assert.NoError(t, err)
+	bundle := append(append([]byte{}, oldCA...), newCA...)
+
+	r, client := newTestReconciler(t, testValidatingWebhook(), testWebhookSecret(map[string][]byte{
+		certresources.CACert: newCA,
+		"ca-bundle.crt":      bundle,
+	}))
+	r.caBundleKey = "ca-bundle.crt"
+	assert.Equal(t, 2, countPEMCertificates(bundle))
+
+	assert.NoError(t, r.Reconcile(context.Background(), testNamespace+"/"+testWebhookName))
+	vwh, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(context.Background(), testWebhookName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, bundle, vwh.Webhooks[0].ClientConfig.CABundle, "both CAs should be published while rotating")
+}
+
+func TestReconcileCABundleFallsBackToCACert(t *testing.T) {
+	r, client := newTestReconciler(t, testValidatingWebhook(), testWebhookSecret(map[string][]byte{certresources.CACert: []byte("ca")}))
+	r.caBundleKey = "ca-bundle.crt"
+
+	assert.NoError(t, r.Reconcile(context.Background(), testNamespace+"/"+testWebhookName))
+	vwh, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(context.Background(), testWebhookName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ca"), vwh.Webhooks[0].ClientConfig.CABundle)
+}
+
+func TestReconcileRejectsMalformedCABundle(t *testing.T) {
+	r, _ := newTestReconciler(t, testValidatingWebhook(), testWebhookSecret(map[string][]byte{
+		certresources.CACert: []byte("ca"),
+		"ca-bundle.crt":      []byte("not a certificate"),
+	}))
+	r.caBundleKey = "ca-bundle.crt"
+
+	assert.Error(t, r.Reconcile(context.Background(), testNamespace+"/"+testWebhookName))
+}
+
+func TestOptionsDefaultRules(t *testing.T) {
+	rules := Options{}.rules()
+	assert.Len(t, rules, 1)
+	assert.Equal(t, []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update}, rules[0].Operations)
+	assert.Equal(t, admissionregistrationv1.Rule{
+		APIGroups:   []string{"openshift-pipelines.org"},
+		APIVersions: []string{"v1alpha1"},
+		Resources:   []string{"approvaltask", "approvaltasks"},
+	}, rules[0].Rule)
+}
+
+func TestReconcileWritesCustomRules(t *testing.T) {
+	r, client := newTestReconciler(t, testValidatingWebhook(), testWebhookSecret(map[string][]byte{certresources.CACert: []byte("ca")}))
+	r.rules = Options{
+		RuleAPIGroups:    []string{"approvals.example.com"},
+		RuleAPIVersions:  []string{"v1alpha1", "v1beta1"},
+		RuleResources:    []string{"approvaltasks"},
+		RuleSubresources: []string{"status"},
+		RuleScope:        "Namespaced",
+	}.rules()
+
+	assert.NoError(t, r.Reconcile(context.Background(), testNamespace+"/"+testWebhookName))
+	vwh, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(context.Background(), testWebhookName, metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	scope := admissionregistrationv1.NamespacedScope
+	assert.Equal(t, []admissionregistrationv1.RuleWithOperations{{
+		Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+		Rule: admissionregistrationv1.Rule{
+			APIGroups:   []string{"approvals.example.com"},
+			APIVersions: []string{"v1alpha1", "v1beta1"},
+			Resources:   []string{"approvaltasks", "approvaltasks/status"},
+			Scope:       &scope,
+		},
+	}}, vwh.Webhooks[0].Rules)
+
+	// Reconciling again with the written rules leaves the webhook alone
+	updates := countWebhookUpdates(client)
+	r.vwhlister = admissionlisters.NewValidatingWebhookConfigurationLister(indexerWith(t, vwh))
+	assert.NoError(t, r.Reconcile(context.Background(), testNamespace+"/"+testWebhookName))
+	assert.Equal(t, int32(0), atomic.LoadInt32(updates))
+}
+
+func TestReconcileIgnoresRuleOrder(t *testing.T) {
+	r, client := newTestReconciler(t, testValidatingWebhook(), testWebhookSecret(map[string][]byte{certresources.CACert: []byte("ca")}))
+	r.rules = []admissionregistrationv1.RuleWithOperations{{
+		Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+		Rule: admissionregistrationv1.Rule{
+			APIGroups:   []string{"openshift-pipelines.org"},
+			APIVersions: []string{"v1alpha1", "v1beta1"},
+			Resources:   []string{"approvaltasks", "approvaltasks/status"},
+		},
+	}, {
+		Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Delete},
+		Rule: admissionregistrationv1.Rule{
+			APIGroups:   []string{"openshift-pipelines.org"},
+			APIVersions: []string{"v1alpha1"},
+			Resources:   []string{"approvaltasks"},
+		},
+	}}
+
+	assert.NoError(t, r.Reconcile(context.Background(), testNamespace+"/"+testWebhookName))
+	vwh, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(context.Background(), testWebhookName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, canonicalRules(r.rules), vwh.Webhooks[0].Rules)
+
+	// The same rules, configured in a different order, leave the webhook alone
+	r.rules = []admissionregistrationv1.RuleWithOperations{{
+		Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Delete},
+		Rule: admissionregistrationv1.Rule{
+			APIGroups:   []string{"openshift-pipelines.org"},
+			APIVersions: []string{"v1alpha1"},
+			Resources:   []string{"approvaltasks"},
+		},
+	}, {
+		Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Update, admissionregistrationv1.Create},
+		Rule: admissionregistrationv1.Rule{
+			APIGroups:   []string{"openshift-pipelines.org"},
+			APIVersions: []string{"v1beta1", "v1alpha1"},
+			Resources:   []string{"approvaltasks/status", "approvaltasks"},
+		},
+	}}
+	updates := countWebhookUpdates(client)
+	r.vwhlister = admissionlisters.NewValidatingWebhookConfigurationLister(indexerWith(t, vwh))
+	assert.NoError(t, r.Reconcile(context.Background(), testNamespace+"/"+testWebhookName))
+	assert.Equal(t, int32(0), atomic.LoadInt32(updates))
+
+	// Rules written in another order, for example by hand, are not rewritten either
+	shuffled := vwh.DeepCopy()
+	shuffled.Webhooks[0].Rules = r.rules
+	r.vwhlister = admissionlisters.NewValidatingWebhookConfigurationLister(indexerWith(t, shuffled))
+	assert.NoError(t, r.Reconcile(context.Background(), testNamespace+"/"+testWebhookName))
+	assert.Equal(t, int32(0), atomic.LoadInt32(updates))
+}
+
+func indexerWith(t *testing.T, objs ...interface{}) cache.Indexer {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, obj := range objs {
+		assert.NoError(t, indexer.Add(obj))
+	}
+	return indexer
+}
+
+func TestReconcileValidatingWebhookResult(t *testing.T) {
+	ctx := context.Background()
+	r, client := newTestReconciler(t, testValidatingWebhook(), testWebhookSecret(nil))
+
+	result, err := r.reconcileValidatingWebhook(ctx, []byte("ca"))
+	assert.NoError(t, err)
+	assert.Equal(t, WebhookUpdated, result)
+
+	vwh, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, testWebhookName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	r.vwhlister = admissionlisters.NewValidatingWebhookConfigurationLister(indexerWith(t, vwh))
+	result, err = r.reconcileValidatingWebhook(ctx, []byte("ca"))
+	assert.NoError(t, err)
+	assert.Equal(t, WebhookUnchanged, result)
+
+	// A rotated CA is written again
+	result, err = r.reconcileValidatingWebhook(ctx, []byte("rotated"))
+	assert.NoError(t, err)
+	assert.Equal(t, WebhookUpdated, result)
+}
+
+func TestReconcileValidatingWebhookFailures(t *testing.T) {
+	ctx := context.Background()
+
+	r, _ := newTestReconciler(t, testValidatingWebhook(), testWebhookSecret(nil))
+	r.vwhlister = admissionlisters.NewValidatingWebhookConfigurationLister(indexerWith(t))
+	result, err := r.reconcileValidatingWebhook(ctx, []byte("ca"))
+	assert.Error(t, err, "the configuration is installed with the webhook, not created")
+	assert.Equal(t, WebhookFailed, result)
+
+	withoutService := testValidatingWebhook()
+	withoutService.Webhooks[0].ClientConfig.Service = nil
+	r, _ = newTestReconciler(t, withoutService, testWebhookSecret(nil))
+	result, err = r.reconcileValidatingWebhook(ctx, []byte("ca"))
+	assert.EqualError(t, err, "missing service reference for webhook: "+testWebhookName)
+	assert.Equal(t, WebhookFailed, result)
+
+	r, client := newTestReconciler(t, testValidatingWebhook(), testWebhookSecret(nil))
+	client.PrependReactor("update", "validatingwebhookconfigurations", func(action ktesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("conflict")
+	})
+	result, err = r.reconcileValidatingWebhook(ctx, []byte("ca"))
+	assert.EqualError(t, err, "failed to update webhook: conflict")
+	assert.Equal(t, WebhookFailed, result)
+}
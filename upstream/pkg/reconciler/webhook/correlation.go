@@ -0,0 +1,46 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/correlation"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// requestCorrelationID returns the correlation ID the admission of request is
+// logged and audited with: the one annotated on the object being replaced,
+// which updates cannot change, or else on the object of the request when it
+// is created. A request carrying none, such as an update to a task
+// created before correlation IDs, is given a generated one.
+func requestCorrelationID(request *admissionv1.AdmissionRequest) string {
+	for _, raw := range [][]byte{request.OldObject.Raw, request.Object.Raw} {
+		if len(raw) == 0 {
+			continue
+		}
+		var obj metav1.PartialObjectMetadata
+		if json.Unmarshal(raw, &obj) != nil {
+			continue
+		}
+		if id := correlation.Of(&obj); id != "" {
+			return id
+		}
+	}
+	return correlation.New()
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	admissionv1 "k8s.io/api/admission/v1"
+	"knative.dev/pkg/logging"
+)
+
+// loggingReconciler returns a reconciler whose decisions log through the
+// returned observer, with an interceptor logging every update it sees.
+func loggingReconciler() (*reconciler, *observer.ObservedLogs) {
+	core, logs := observer.New(zap.InfoLevel)
+	logged := NewDecisionInterceptor("log", func(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse {
+		logging.FromContext(ctx).Info("Deciding")
+		return nil
+	})
+	return &reconciler{
+		withContext: func(ctx context.Context) context.Context {
+			return logging.WithLogger(ctx, zap.New(core).Sugar())
+		},
+		interceptors: func(defaults []DecisionInterceptor) []DecisionInterceptor {
+			return append([]DecisionInterceptor{logged}, defaults...)
+		},
+	}, logs
+}
+
+func loggedCorrelationIDs(logs *observer.ObservedLogs) []string {
+	var ids []string
+	for _, entry := range logs.FilterMessage("Deciding").All() {
+		ids = append(ids, entry.ContextMap()["correlationID"].(string))
+	}
+	return ids
+}
+
+func TestAdmitCorrelationID(t *testing.T) {
+	r, logs := loggingReconciler()
+	oldObj := keyedApprovalTask()
+	oldObj.Annotations = map[string]string{v1alpha1.CorrelationIDAnnotationKey: "release-42"}
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	assert.Equal(t, "release-42", resp.AuditAnnotations["correlation-id"])
+	assert.Equal(t, []string{"release-42"}, loggedCorrelationIDs(logs))
+
+	// The ID of the task cannot be replaced, even to trace a decision
+	newObj.Annotations[v1alpha1.CorrelationIDAnnotationKey] = "approve-7"
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "The correlation ID of an ApprovalTask cannot be changed", resp.Result.Message)
+	assert.Equal(t, "release-42", resp.AuditAnnotations["correlation-id"])
+	assert.Equal(t, []string{"release-42", "release-42"}, loggedCorrelationIDs(logs))
+}
+
+func TestAdmitGeneratesCorrelationID(t *testing.T) {
+	r, logs := loggingReconciler()
+	oldObj := keyedApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	generated := resp.AuditAnnotations["correlation-id"]
+	assert.Regexp(t, "^[0-9a-f]{32}$", generated)
+	assert.Equal(t, []string{generated}, loggedCorrelationIDs(logs), "the generated ID is logged as audited")
+
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.NotEqual(t, generated, resp.AuditAnnotations["correlation-id"], "every request is given its own ID")
+}
+
+func TestRequestCorrelationIDOfDeletion(t *testing.T) {
+	at := keyedApprovalTask()
+	at.Annotations = map[string]string{v1alpha1.CorrelationIDAnnotationKey: "release-42"}
+	raw, err := json.Marshal(at)
+	assert.NoError(t, err)
+	request := &admissionv1.AdmissionRequest{Operation: admissionv1.Delete}
+	request.OldObject.Raw = raw
+	assert.Equal(t, "release-42", requestCorrelationID(request))
+}
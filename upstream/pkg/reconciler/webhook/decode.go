@@ -0,0 +1,210 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// unknownFieldErrorPrefix starts the error encoding/json returns for unknown
+// fields when DisallowUnknownFields is set.
+const unknownFieldErrorPrefix = `json: unknown field "`
+
+var (
+	approvalTaskType    = reflect.TypeOf(v1alpha1.ApprovalTask{})
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// describeDecodeError rewrites an error returned while decoding an
+// ApprovalTask from data so that it names the offending field, e.g.
+// "spec.approvers[2].type: must be a string, got number". Errors that cannot
+// be tied to a field are returned unchanged.
+func describeDecodeError(data []byte, err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return fmt.Errorf("%s: must be %s, got %s", fieldPath(typeErr.Field), jsonKind(typeErr.Type), typeErr.Value)
+	}
+
+	// The decoder does not report where an unknown field is, so look for it
+	// in the document
+	if name, ok := strings.CutPrefix(err.Error(), unknownFieldErrorPrefix); ok {
+		var doc interface{}
+		if json.Unmarshal(data, &doc) == nil {
+			if path, found := findUnknownField(doc, approvalTaskType, strings.TrimSuffix(name, `"`), ""); found {
+				return fmt.Errorf("%s: unknown field", path)
+			}
+		}
+	}
+	return err
+}
+
+// fieldPath turns the dotted field of a decode error, in which array indices
+// are segments of their own, into "spec.approvers[2].type".
+func fieldPath(field string) string {
+	var b strings.Builder
+	for i, segment := range strings.Split(field, ".") {
+		if _, err := strconv.Atoi(segment); err == nil && i > 0 {
+			fmt.Fprintf(&b, "[%s]", segment)
+			continue
+		}
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(segment)
+	}
+	return b.String()
+}
+
+// jsonKind names the JSON value expected for a Go type.
+func jsonKind(t reflect.Type) string {
+	if t == nil {
+		return "a value"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	}
+	return "a " + t.String()
+}
+
+// findUnknownField walks the decoded document alongside the Go type it is
+// decoded into and returns the path of the first key named name, in sorted
+// key order, that does not belong to the type.
+func findUnknownField(doc interface{}, t reflect.Type, name, path string) (string, bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return "", false
+	}
+
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := jsonFields(t)
+			for _, key := range keys {
+				fieldType, ok := lookupField(fields, key)
+				if !ok {
+					if key == name {
+						return joinPath(path, key), true
+					}
+					continue
+				}
+				if p, found := findUnknownField(v[key], fieldType, name, joinPath(path, key)); found {
+					return p, true
+				}
+			}
+		case reflect.Map:
+			for _, key := range keys {
+				if p, found := findUnknownField(v[key], t.Elem(), name, joinPath(path, key)); found {
+					return p, true
+				}
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, item := range v {
+				if p, found := findUnknownField(item, t.Elem(), name, fmt.Sprintf("%s[%d]", path, i)); found {
+					return p, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// jsonFields returns the types of the fields of a struct by JSON name,
+// including those of embedded structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for n, ft := range jsonFields(embedded) {
+					fields[n] = ft
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// lookupField matches a key to a field the way encoding/json does, preferring
+// an exact match over a case-insensitive one.
+func lookupField(fields map[string]reflect.Type, key string) (reflect.Type, bool) {
+	if t, ok := fields[key]; ok {
+		return t, true
+	}
+	for name, t := range fields {
+		if strings.EqualFold(name, key) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestDecodeErrorFieldPaths(t *testing.T) {
+	r := &reconciler{disallowUnknownFields: true}
+	tests := []struct {
+		name string
+		obj  string
+		want string
+	}{{
+		name: "wrong type in an approver",
+		obj:  `{"spec":{"approvers":[{"name":"alice"},{"name":"bob"},{"name":"carol","type":5}]}}`,
+		want: "spec.approvers[2].type: must be a string, got number",
+	}, {
+		name: "wrong type of a spec field",
+		obj:  `{"spec":{"numberOfApprovalsRequired":"two"}}`,
+		want: "spec.numberOfApprovalsRequired: must be a number, got string",
+	}, {
+		name: "object instead of an array",
+		obj:  `{"spec":{"approvers":{"name":"alice"}}}`,
+		want: "spec.approvers: must be an array, got object",
+	}, {
+		name: "unknown approver field",
+		obj:  `{"spec":{"approvers":[{"name":"alice"},{"name":"bob","tpye":"User"}]}}`,
+		want: "spec.approvers[1].tpye: unknown field",
+	}, {
+		name: "unknown group member field",
+		obj:  `{"spec":{"approvers":[{"name":"platform","type":"Group","users":[{"name":"bob","inptu":"approve"}]}]}}`,
+		want: "spec.approvers[0].users[0].inptu: unknown field",
+	}, {
+		name: "unknown top level field next to embedded metadata",
+		obj:  `{"apiVersion":"openshift-pipelines.org/v1alpha1","metadata":{"name":"deploy"},"specs":{}}`,
+		want: "specs: unknown field",
+	}, {
+		name: "fields are matched case-insensitively",
+		obj:  `{"Spec":{"Approvers":[{"Name":"alice","extra":true}]}}`,
+		want: "Spec.Approvers[0].extra: unknown field",
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := r.decodeNewObject([]byte(tc.obj))
+			assert.EqualError(t, err, tc.want)
+		})
+	}
+}
+
+func TestDecodeErrorWithoutFieldIsUnchanged(t *testing.T) {
+	r := &reconciler{}
+	_, err := r.decodeNewObject([]byte(`{"spec":`))
+	assert.EqualError(t, err, "unexpected EOF")
+}
+
+func TestAdmitMalformedObjectNamesField(t *testing.T) {
+	r := &reconciler{disallowUnknownFields: true}
+	request := &admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Kind:      metav1.GroupVersionKind{Group: Group, Version: Version, Kind: Kind},
+		Object:    runtime.RawExtension{Raw: []byte(`{"spec":{"approvers":[{"name":"alice","type":"User","input":"pending","color":"red"}]}}`)},
+	}
+
+	resp := r.Admit(context.Background(), request)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "cannot decode incoming new object: spec.approvers[0].color: unknown field", resp.Result.Message)
+}
+
+func TestAdmitUpdateWithoutOldObject(t *testing.T) {
+	r := &reconciler{}
+	newObj := `{"metadata":{"name":"deploy","namespace":"production"},"spec":{"numberOfApprovalsRequired":1,"approvers":[{"name":"alice","type":"User","input":"approve"}]}}`
+	for _, old := range []string{"", "null", " "} {
+		request := &admissionv1.AdmissionRequest{
+			Operation: admissionv1.Update,
+			Kind:      metav1.GroupVersionKind{Group: Group, Version: Version, Kind: Kind},
+			UserInfo:  admissionRequestFor("alice").UserInfo,
+			Object:    runtime.RawExtension{Raw: []byte(newObj)},
+			OldObject: runtime.RawExtension{Raw: []byte(old)},
+		}
+
+		resp := r.Admit(context.Background(), request)
+		assert.False(t, resp.Allowed, "old object %q", old)
+		assert.Equal(t, "missing prior state: the update does not carry the old ApprovalTask", resp.Result.Message)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// decisionChanged reports whether the update changes the decision recorded
+// on a User approver it keeps at the same index, or who recorded it.
+func decisionChanged(oldObj, newObj *v1alpha1.ApprovalTask, i int) bool {
+	if i >= len(oldObj.Spec.Approvers) || v1alpha1.DefaultedApproverType(newObj.Spec.Approvers[i].Type) != "User" {
+		return false
+	}
+	oldApprover, approver := oldObj.Spec.Approvers[i], newObj.Spec.Approvers[i]
+	return sameApprover(oldApprover, approver) && (oldApprover.Input != approver.Input || oldApprover.DecidedBy != approver.DecidedBy)
+}
+
+// validateDelegation checks the decidedBy of the User approvers whose
+// decision the update changes, on the tasks with the approvers on duty
+// substituted: a substitute deciding for an unavailable approver records
+// themselves in its decidedBy, so that the decision stays linked to both, and
+// an approver deciding itself clears it. It returns the denial message, or an
+// empty string if the update is allowed.
+func validateDelegation(oldObj, newObj *v1alpha1.ApprovalTask) string {
+	for i, approver := range newObj.Spec.Approvers {
+		if !decisionChanged(oldObj, newObj, i) {
+			continue
+		}
+		// The entry of an unavailable approver is named after the substitute on duty
+		if webhookContains(approver.Substitutes, approver.Name) {
+			if approver.DecidedBy != approver.Name {
+				return fmt.Sprintf("Substitutes must record themselves in approvers[%d].decidedBy when deciding for another approver, set it to '%s'", i, approver.Name)
+			}
+		} else if approver.DecidedBy != "" {
+			return fmt.Sprintf("approvers[%d].decidedBy: only a substitute deciding for the approver can be recorded, got '%s'", i, approver.DecidedBy)
+		}
+	}
+	return ""
+}
+
+// annotateDelegation records, in the audit annotations of an allowed update,
+// the principal approvers a substitute decided for, the substitute and the
+// basis of the delegation, next to the user recorded by annotateAudit.
+func (r *reconciler) annotateDelegation(request *admissionv1.AdmissionRequest, response *admissionv1.AdmissionResponse) {
+	if !response.Allowed || request.Operation != admissionv1.Update {
+		return
+	}
+	oldObj, err := r.decodeOldObject(request.OldObject.Raw)
+	if err != nil {
+		return
+	}
+	newObj, err := r.decodeNewObject(request.Object.Raw)
+	if err != nil {
+		return
+	}
+
+	var principals, delegates []string
+	for i, approver := range newObj.Spec.Approvers {
+		if approver.DecidedBy == "" || !decisionChanged(oldObj, newObj, i) {
+			continue
+		}
+		principals = append(principals, approver.Name)
+		if !webhookContains(delegates, approver.DecidedBy) {
+			delegates = append(delegates, approver.DecidedBy)
+		}
+	}
+	if len(principals) == 0 {
+		return
+	}
+
+	if response.AuditAnnotations == nil {
+		response.AuditAnnotations = make(map[string]string)
+	}
+	response.AuditAnnotations[auditPrincipalKey] = truncateAuditValue(strings.Join(principals, ","))
+	response.AuditAnnotations[auditDelegateKey] = truncateAuditValue(strings.Join(delegates, ","))
+	response.AuditAnnotations[auditDelegationBasisKey] = truncateAuditValue(fmt.Sprintf("%s: %s listed in the %s annotation of namespace %s",
+		v1alpha1.DelegationBasisSubstitute, strings.Join(principals, ", "), v1alpha1.UnavailableApproversAnnotationKey, newObj.Namespace))
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditDelegatedDecision(t *testing.T) {
+	r, _ := newNamespaceReconciler(t, namespaceWithUnavailable("alice"))
+	r.privilegedGroup = DefaultPrivilegedGroup
+
+	oldObj := onCallApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	newObj.Spec.Approvers[0].DecidedBy = "bob"
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "bob")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	assert.Equal(t, "bob", resp.AuditAnnotations[auditUserKey])
+	assert.Equal(t, "alice", resp.AuditAnnotations[auditPrincipalKey])
+	assert.Equal(t, "bob", resp.AuditAnnotations[auditDelegateKey])
+	assert.Equal(t, "Substitute: alice listed in the openshift-pipelines.org/unavailable-approvers annotation of namespace production",
+		resp.AuditAnnotations[auditDelegationBasisKey])
+}
+
+func TestAuditOwnDecisionHasNoDelegation(t *testing.T) {
+	r, _ := newNamespaceReconciler(t, namespaceWithUnavailable("dave"))
+	r.privilegedGroup = DefaultPrivilegedGroup
+
+	oldObj := onCallApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	assert.NotContains(t, resp.AuditAnnotations, auditPrincipalKey)
+	assert.NotContains(t, resp.AuditAnnotations, auditDelegateKey)
+}
+
+func TestAdmitDelegationRequiresDecidedBy(t *testing.T) {
+	r, _ := newNamespaceReconciler(t, namespaceWithUnavailable("alice"))
+	r.privilegedGroup = DefaultPrivilegedGroup
+
+	oldObj := onCallApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "bob")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Substitutes must record themselves in approvers[0].decidedBy when deciding for another approver, set it to 'bob'", resp.Result.Message)
+
+	newObj.Spec.Approvers[0].DecidedBy = "carol"
+	resp = admitUpdateWith(t, r, oldObj, newObj, "bob")
+	assert.False(t, resp.Allowed, "substitutes cannot record the decision as another's")
+}
+
+func TestAdmitOwnDecisionClearsDecidedBy(t *testing.T) {
+	r, _ := newNamespaceReconciler(t, namespaceWithUnavailable("dave"))
+	r.privilegedGroup = DefaultPrivilegedGroup
+
+	oldObj := onCallApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	newObj.Spec.Approvers[0].DecidedBy = "bob"
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "approvers[0].decidedBy: only a substitute deciding for the approver can be recorded, got 'bob'", resp.Result.Message)
+}
+
+func TestAdmitDecidedByOfOtherApprover(t *testing.T) {
+	oldObj := onCallApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+	newObj.Spec.Approvers[0].DecidedBy = "lead"
+
+	resp := admitUpdate(t, oldObj, newObj, "lead")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "approvers[0].decidedBy: only a substitute deciding for the approver can be recorded, got 'lead'", resp.Result.Message)
+}
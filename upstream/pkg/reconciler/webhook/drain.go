@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"knative.dev/pkg/logging"
+)
+
+// admissionTracker counts the admissions in flight, so that shutting down
+// the webhook waits for the decisions being made instead of dropping them
+// along with the events they emit.
+type admissionTracker struct {
+	mu       sync.Mutex
+	inflight int
+	// idle is closed once no admission is in flight, while drain waits
+	idle chan struct{}
+}
+
+// begin records the start of an admission and returns the function
+// recording its end.
+func (t *admissionTracker) begin() func() {
+	if t == nil {
+		return func() {}
+	}
+	t.mu.Lock()
+	t.inflight++
+	t.mu.Unlock()
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.inflight--
+		if t.inflight == 0 && t.idle != nil {
+			close(t.idle)
+			t.idle = nil
+		}
+	}
+}
+
+// drain waits up to timeout for the admissions in flight to complete, and
+// returns the number of those still in flight when it gave up. Admissions
+// starting while it waits are waited for too.
+func (t *admissionTracker) drain(ctx context.Context, timeout time.Duration) int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	if t.inflight == 0 {
+		t.mu.Unlock()
+		return 0
+	}
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	idle, inflight := t.idle, t.inflight
+	t.mu.Unlock()
+
+	logger := logging.FromContext(ctx)
+	logger.Infof("Waiting up to %v for %d in-flight admissions", timeout, inflight)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-idle:
+		return 0
+	case <-timer.C:
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	logger.Warnf("Stopped waiting for %d in-flight admissions after %v", t.inflight, timeout)
+	return t.inflight
+}
+
+// Shutdown lets the main function of the webhook wait, once the webhook
+// stopped serving, for the admissions in flight to drain and for the events
+// they emitted to be flushed. Both happen in the background, and would be
+// cut short by the process exiting.
+type Shutdown struct {
+	wg sync.WaitGroup
+}
+
+// add records a step of the shutdown and returns the function recording its
+// completion.
+func (s *Shutdown) add() func() {
+	if s == nil {
+		return func() {}
+	}
+	s.wg.Add(1)
+	return s.wg.Done
+}
+
+// Wait waits up to timeout for the shutdown to complete, and reports whether
+// it did.
+func (s *Shutdown) Wait(timeout time.Duration) bool {
+	if s == nil {
+		return true
+	}
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
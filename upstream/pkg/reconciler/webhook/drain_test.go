@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// blockingReconciler returns a reconciler whose admissions of updates wait
+// for release, signalling entered once they are in flight.
+func blockingReconciler(entered chan<- struct{}, release <-chan struct{}) *reconciler {
+	block := NewDecisionInterceptor("block", func(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse {
+		entered <- struct{}{}
+		<-release
+		return nil
+	})
+	return &reconciler{
+		admissions: &admissionTracker{},
+		interceptors: func(defaults []DecisionInterceptor) []DecisionInterceptor {
+			return append([]DecisionInterceptor{block}, defaults...)
+		},
+	}
+}
+
+func TestDrainWaitsForInflightAdmissions(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	r := blockingReconciler(entered, release)
+	oldObj := withdrawableApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	responses := make(chan *admissionv1.AdmissionResponse)
+	go func() { responses <- admitUpdateWith(t, r, oldObj, newObj, "alice") }()
+	<-entered
+
+	// The webhook shuts down while the decision is being made
+	drained := make(chan int)
+	go func() { drained <- r.admissions.drain(context.Background(), time.Minute) }()
+	select {
+	case <-drained:
+		t.Fatal("drain returned with an admission in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	assert.True(t, (<-responses).Allowed, "the in-flight decision completes")
+	assert.Equal(t, 0, <-drained)
+}
+
+func TestDrainTimeout(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	r := blockingReconciler(entered, release)
+	oldObj := withdrawableApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	done := make(chan struct{})
+	go func() {
+		admitUpdateWith(t, r, oldObj, newObj, "alice")
+		close(done)
+	}()
+	<-entered
+	assert.Equal(t, 1, r.admissions.drain(context.Background(), 10*time.Millisecond), "draining gives up at the deadline")
+
+	close(release)
+	<-done
+	assert.Equal(t, 0, r.admissions.drain(context.Background(), time.Minute))
+}
+
+func TestDrainWithoutAdmissions(t *testing.T) {
+	assert.Equal(t, 0, (&admissionTracker{}).drain(context.Background(), time.Minute))
+
+	var untracked *admissionTracker
+	untracked.begin()()
+	assert.Equal(t, 0, untracked.drain(context.Background(), time.Minute), "reconcilers built without a tracker drain at once")
+}
+
+func TestShutdownWaitsForDrainAndFlush(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	r := blockingReconciler(entered, release)
+	oldObj := withdrawableApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	shutdown := &Shutdown{}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.recorder = newEventRecorder(ctx, fake.NewSimpleClientset(), "test", func() {
+		r.admissions.drain(ctx, time.Minute)
+	}, shutdown.add())
+
+	go admitUpdateWith(t, r, oldObj, newObj, "alice")
+	<-entered
+	cancel()
+	assert.False(t, shutdown.Wait(50*time.Millisecond), "the shutdown waits for the admission in flight")
+
+	r.recorder.Event(oldObj, corev1.EventTypeNormal, "Decided", "recorded by the admission in flight")
+	close(release)
+	assert.True(t, shutdown.Wait(time.Minute), "the shutdown completes once the admission drained and the events were flushed")
+
+	var untracked *Shutdown
+	assert.True(t, untracked.Wait(time.Minute))
+	assert.Equal(t, DefaultDrainTimeout+DefaultEventFlushTimeout, Options{}.ShutdownTimeout())
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/client-go/tools/record"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/metrics"
+)
+
+// webhookDriftReason is the reason of the events reporting external changes
+// to the ValidatingWebhookConfiguration.
+const webhookDriftReason = "WebhookConfigurationDrift"
+
+// webhookDrift remembers the webhooks of the ValidatingWebhookConfiguration
+// as the reconciler last applied them, or last found them valid, so that
+// changes it did not make can be told apart from its own. Nothing is
+// remembered across restarts or leader changes: the first reconcile of a
+// leader takes the configuration as it finds it.
+//
+// Reconciles of the singleton key never run concurrently, so the state is
+// not guarded.
+type webhookDrift struct {
+	applied []admissionregistrationv1.ValidatingWebhook
+	// replaced is the resource version the last update of the reconciler
+	// replaced, which the lister may still return until it observes the
+	// update.
+	replaced string
+}
+
+// drifted reports whether the webhooks of the configuration differ from the
+// ones last applied.
+func (d *webhookDrift) drifted(configured *admissionregistrationv1.ValidatingWebhookConfiguration) bool {
+	if d.applied == nil {
+		return false
+	}
+	if d.replaced != "" && configured.ResourceVersion == d.replaced {
+		// A stale copy from before our own update
+		return false
+	}
+	return !equality.Semantic.DeepEqual(configured.Webhooks, d.applied)
+}
+
+// observe records the webhooks of the configuration as applied, replacing
+// the resource version replaced.
+func (d *webhookDrift) observe(configuration *admissionregistrationv1.ValidatingWebhookConfiguration, replaced string) {
+	d.applied = configuration.DeepCopy().Webhooks
+	if d.applied == nil {
+		d.applied = []admissionregistrationv1.ValidatingWebhook{}
+	}
+	d.replaced = replaced
+}
+
+// reportDrift emits a Warning event on the configuration and counts the
+// drift, before the reconciler applies its configuration again.
+func reportDrift(ctx context.Context, recorder record.EventRecorder, configured *admissionregistrationv1.ValidatingWebhookConfiguration) {
+	logging.FromContext(ctx).Warnf("ValidatingWebhookConfiguration %s was changed outside of the webhook, reapplying it", configured.Name)
+	metrics.Record(context.Background(), webhookDriftM.M(1))
+	if recorder == nil {
+		return
+	}
+	// The typed clients' scheme does not know the configuration's kind, so
+	// the event refers to it explicitly
+	recorder.Eventf(&corev1.ObjectReference{
+		APIVersion:      admissionregistrationv1.SchemeGroupVersion.String(),
+		Kind:            "ValidatingWebhookConfiguration",
+		Name:            configured.Name,
+		UID:             configured.UID,
+		ResourceVersion: configured.ResourceVersion,
+	}, corev1.EventTypeWarning, webhookDriftReason,
+		"ValidatingWebhookConfiguration %s was changed outside of the manual approval webhook, its configuration is applied again", configured.Name)
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats/view"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	admissionlisters "k8s.io/client-go/listers/admissionregistration/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func webhookDrifts(t *testing.T) int64 {
+	t.Helper()
+	rows, err := view.RetrieveData(webhookDriftName)
+	assert.NoError(t, err)
+	if len(rows) == 0 {
+		return 0
+	}
+	return rows[0].Data.(*view.CountData).Value
+}
+
+// observeWebhook points the lister of r at the stored configuration, at the
+// given resource version, as the informer would once it observed it.
+func observeWebhook(t *testing.T, r *reconciler, vwh *admissionregistrationv1.ValidatingWebhookConfiguration, version string) {
+	t.Helper()
+	vwh = vwh.DeepCopy()
+	vwh.ResourceVersion = version
+	r.vwhlister = admissionlisters.NewValidatingWebhookConfigurationLister(indexerWith(t, vwh))
+}
+
+func storedWebhook(t *testing.T, r *reconciler) *admissionregistrationv1.ValidatingWebhookConfiguration {
+	t.Helper()
+	vwh, err := r.client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(context.Background(), testWebhookName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	return vwh
+}
+
+func TestReconcileReportsExternalDrift(t *testing.T) {
+	registerDecisionViews()
+	ctx := context.Background()
+	initial := testValidatingWebhook()
+	initial.ResourceVersion = "1"
+	r, client := newTestReconciler(t, initial, testWebhookSecret(nil))
+	recorder := record.NewFakeRecorder(10)
+	r.recorder = recorder
+	drifts := webhookDrifts(t)
+
+	// The first reconcile takes the configuration as it finds it
+	result, err := r.reconcileValidatingWebhook(ctx, []byte("ca"))
+	assert.NoError(t, err)
+	assert.Equal(t, WebhookUpdated, result)
+
+	// Our own update is not drift, even while the lister lags behind it
+	result, err = r.reconcileValidatingWebhook(ctx, []byte("ca"))
+	assert.NoError(t, err)
+	assert.Equal(t, WebhookUpdated, result)
+	observeWebhook(t, r, storedWebhook(t, r), "2")
+	result, err = r.reconcileValidatingWebhook(ctx, []byte("ca"))
+	assert.NoError(t, err)
+	assert.Equal(t, WebhookUnchanged, result)
+	// Nor is a rotation of the CA
+	result, err = r.reconcileValidatingWebhook(ctx, []byte("rotated"))
+	assert.NoError(t, err)
+	assert.Equal(t, WebhookUpdated, result)
+	observeWebhook(t, r, storedWebhook(t, r), "3")
+	result, err = r.reconcileValidatingWebhook(ctx, []byte("rotated"))
+	assert.NoError(t, err)
+	assert.Equal(t, WebhookUnchanged, result)
+	assert.Empty(t, recorder.Events)
+	assert.Equal(t, drifts, webhookDrifts(t))
+
+	// Someone points the webhook at another CA
+	tampered := storedWebhook(t, r)
+	tampered.Webhooks[0].ClientConfig.CABundle = []byte("attacker")
+	_, err = client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Update(ctx, tampered, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	observeWebhook(t, r, tampered, "4")
+	result, err = r.reconcileValidatingWebhook(ctx, []byte("rotated"))
+	assert.NoError(t, err)
+	assert.Equal(t, WebhookUpdated, result, "the configuration is applied again")
+	assert.Equal(t, []byte("rotated"), storedWebhook(t, r).Webhooks[0].ClientConfig.CABundle)
+	assert.Equal(t, drifts+1, webhookDrifts(t))
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Warning WebhookConfigurationDrift ValidatingWebhookConfiguration "+testWebhookName+" was changed outside")
+}
+
+func TestReconcileReportsDriftOutsideManagedFields(t *testing.T) {
+	registerDecisionViews()
+	ctx := context.Background()
+	r, _ := newTestReconciler(t, testValidatingWebhook(), testWebhookSecret(nil))
+	recorder := record.NewFakeRecorder(10)
+	r.recorder = recorder
+	drifts := webhookDrifts(t)
+
+	_, err := r.reconcileValidatingWebhook(ctx, []byte("ca"))
+	assert.NoError(t, err)
+	observeWebhook(t, r, storedWebhook(t, r), "2")
+
+	// A failure policy is not reconciled, yet changing it is reported, once
+	ignore := admissionregistrationv1.Ignore
+	tampered := storedWebhook(t, r)
+	tampered.Webhooks[0].FailurePolicy = &ignore
+	observeWebhook(t, r, tampered, "3")
+	result, err := r.reconcileValidatingWebhook(ctx, []byte("ca"))
+	assert.NoError(t, err)
+	assert.Equal(t, WebhookUnchanged, result)
+	result, err = r.reconcileValidatingWebhook(ctx, []byte("ca"))
+	assert.NoError(t, err)
+	assert.Equal(t, WebhookUnchanged, result)
+	assert.Equal(t, drifts+1, webhookDrifts(t))
+	assert.Len(t, recorder.Events, 1)
+}
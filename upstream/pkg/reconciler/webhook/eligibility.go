@@ -0,0 +1,251 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
+)
+
+// EligibilityPath is the path the eligibility endpoint is served on.
+const EligibilityPath = "/eligibility"
+
+const (
+	roleUser        = "User"
+	roleGroupMember = "GroupMember"
+	roleNone        = "None"
+)
+
+// Eligibility describes what a user can currently do on an ApprovalTask.
+type Eligibility struct {
+	CanApprove       bool   `json:"canApprove"`
+	CanReject        bool   `json:"canReject"`
+	AlreadyResponded bool   `json:"alreadyResponded"`
+	Role             string `json:"role"`
+}
+
+// eligibilityHandler answers, for the user owning the bearer token of the
+// request, whether they can approve or reject the ApprovalTask named by the
+// namespace and name query parameters, if they may get it. Nothing is ever
+// written to the task.
+type eligibilityHandler struct {
+	admission     *reconciler
+	approvalTasks versioned.Interface
+}
+
+func (h *eligibilityHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := bearerToken(req)
+	if token == "" {
+		http.Error(w, "a bearer token is required", http.StatusUnauthorized)
+		return
+	}
+
+	namespace, name := req.URL.Query().Get("namespace"), req.URL.Query().Get("name")
+	if namespace == "" || name == "" {
+		http.Error(w, "the namespace and name query parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := req.Context()
+	userInfo, err := h.admission.authenticate(ctx, token)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if allowed, err := h.admission.canGet(ctx, userInfo, namespace, name); err != nil {
+		logging.FromContext(ctx).Errorf("Error reviewing access to ApprovalTask %s/%s: %v", namespace, name, err)
+		http.Error(w, "failed to review access to the approval task", http.StatusInternalServerError)
+		return
+	} else if !allowed {
+		http.Error(w, "not allowed to get the approval task", http.StatusForbidden)
+		return
+	}
+
+	at, err := h.approvalTasks.OpenshiftpipelinesV1alpha1().ApprovalTasks(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		logging.FromContext(ctx).Errorf("Error fetching ApprovalTask %s/%s: %v", namespace, name, err)
+		http.Error(w, "failed to fetch the approval task", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.admission.eligibility(ctx, at, userInfo)); err != nil {
+		logging.FromContext(ctx).Errorf("Error writing eligibility response: %v", err)
+	}
+}
+
+// eligibility runs the checks Admit applies to an approver input change
+// against a copy of the task carrying each possible decision of the user.
+func (r *reconciler) eligibility(ctx context.Context, at *v1alpha1.ApprovalTask, userInfo authenticationv1.UserInfo) Eligibility {
+	return r.eligibilityWith(ctx, at, userInfo, r.groupResolver)
+}
+
+// eligibilityWith is eligibility resolving the groups of the user with
+// resolver.
+func (r *reconciler) eligibilityWith(ctx context.Context, at *v1alpha1.ApprovalTask, userInfo authenticationv1.UserInfo, resolver GroupResolver) Eligibility {
+	request := r.approverRequest(&admissionv1.AdmissionRequest{UserInfo: userInfo})
+	at = r.resolveSubstitutes(ctx, at)
+	result := Eligibility{
+		Role:             approverRole(at.Spec.Approvers, request),
+		AlreadyResponded: hasResponded(at, userInfo),
+	}
+
+	if _, blocked := r.blocklisted(at, userInfo); blocked {
+		return result
+	}
+	if _, missing := r.missingClaim(userInfo); missing || r.staleSession(userInfo, r.now()) || r.outsideSourceRanges(userInfo) != "" || r.forbiddenImpersonation(userInfo) != "" {
+		return result
+	}
+	approvers := r.effectiveApprovers(ctx, at)
+	request, err := r.resolveGroupsWith(ctx, resolver, request, approvers)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("Unable to verify group membership: %v", err)
+		return result
+	}
+	if !isApprovalRequired(*at) || at.Spec.Paused || !ifUserExists(approvers, request) {
+		return result
+	}
+	result.CanApprove = r.decisionAllowed(ctx, at, request, "approve")
+	result.CanReject = r.decisionAllowed(ctx, at, request, "reject")
+	return result
+}
+
+// decisionAllowed reports whether Admit would let the user submit input,
+// running the decision rules and the foreign change check of Admit on the
+// update recording it.
+func (r *reconciler) decisionAllowed(ctx context.Context, oldObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest, input string) bool {
+	newObj := oldObj.DeepCopy()
+	applyDecision(newObj, request, input)
+
+	if _, changed := inactiveApproverChanged(oldObj, newObj); changed {
+		return false
+	}
+	d := &Decision{Request: request, Old: oldObj, New: newObj}
+	if r.interceptDecisionRules(ctx, d) != nil {
+		return false
+	}
+	return r.interceptForeignChange(ctx, d).Allowed
+}
+
+// applyDecision records input for the user the way the CLI does: on their own
+// entry when they are a User or Email approver, otherwise on the group-level input and
+// on their entry in the users of every Group approver they belong to.
+// Approvals acknowledge the expected digest of the task, and substitutes
+// record themselves in decidedBy.
+func applyDecision(at *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest, input string) {
+	username := request.UserInfo.Username
+	var digest string
+	if input == "approve" {
+		digest = at.Spec.ExpectedDigest
+	}
+	for i, approver := range at.Spec.Approvers {
+		if isIndividualApprover(approver.Type, approver.Name, request.UserInfo) {
+			at.Spec.Approvers[i].Input = input
+			if digest != "" {
+				at.Spec.Approvers[i].AcknowledgedDigest = digest
+			}
+			if webhookContains(approver.Substitutes, approver.Name) {
+				at.Spec.Approvers[i].DecidedBy = approver.Name
+			}
+			return
+		}
+	}
+
+	for i, approver := range at.Spec.Approvers {
+		if v1alpha1.DefaultedApproverType(approver.Type) != "Group" || !isGroupMember(approver, request) {
+			continue
+		}
+		at.Spec.Approvers[i].Input = input
+		listed := false
+		for j, user := range approver.Users {
+			if user.Name == username {
+				at.Spec.Approvers[i].Users[j].Input = input
+				if digest != "" {
+					at.Spec.Approvers[i].Users[j].AcknowledgedDigest = digest
+				}
+				listed = true
+			}
+		}
+		if !listed {
+			at.Spec.Approvers[i].Users = append(at.Spec.Approvers[i].Users, v1alpha1.UserDetails{Name: username, Input: input, AcknowledgedDigest: digest})
+		}
+	}
+}
+
+// approverRole tells whether the user approves as an individual, as a member
+// of a Group approver, or not at all.
+func approverRole(approvers []v1alpha1.ApproverDetails, request *admissionv1.AdmissionRequest) string {
+	role := roleNone
+	for _, approver := range approvers {
+		switch v1alpha1.DefaultedApproverType(approver.Type) {
+		case "User", "Email":
+			if isIndividualApprover(approver.Type, approver.Name, request.UserInfo) {
+				return roleUser
+			}
+		case "Group":
+			if isGroupMember(approver, request) {
+				role = roleGroupMember
+			}
+		}
+	}
+	return role
+}
+
+func isGroupMember(approver v1alpha1.ApproverDetails, request *admissionv1.AdmissionRequest) bool {
+	if webhookContains(request.UserInfo.Groups, approver.Name) {
+		return true
+	}
+	for _, user := range approver.Users {
+		if user.Name == request.UserInfo.Username {
+			return true
+		}
+	}
+	return false
+}
+
+// hasResponded reports whether the controller has recorded a decision of the
+// user in the status of the task.
+func hasResponded(at *v1alpha1.ApprovalTask, userInfo authenticationv1.UserInfo) bool {
+	for _, response := range at.Status.ApproversResponse {
+		if isIndividualApprover(response.Type, response.Name, userInfo) {
+			return response.Response == "approved" || response.Response == "rejected"
+		}
+		for _, member := range response.GroupMembers {
+			if member.Name == userInfo.Username && (member.Response == "approved" || member.Response == "rejected") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	approvaltaskfake "github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+)
+
+func eligibilityApprovalTask() *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "bob", Type: "User", Input: "approve"},
+				{Name: "platform", Type: "Group", Input: "pending"},
+				{Name: "auditors", Type: "Group", Input: "pending", AllowedInputs: []string{"reject"}},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{
+			State: "pending",
+			ApproversResponse: []v1alpha1.ApproverState{
+				{Name: "bob", Type: "User", Response: "approved"},
+			},
+		},
+	}
+}
+
+// newEligibilityHandler returns a handler whose token reviews authenticate
+// every token as the user of the same name, in the given groups, and whose
+// access reviews let everyone but outsider get ApprovalTasks.
+func newEligibilityHandler(groups map[string][]string, tasks ...runtime.Object) *eligibilityHandler {
+	kubeClient := fake.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "tokenreviews", func(action ktesting.Action) (bool, runtime.Object, error) {
+		review := action.(ktesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if review.Spec.Token == "invalid" {
+			return true, &authenticationv1.TokenReview{}, nil
+		}
+		review.Status = authenticationv1.TokenReviewStatus{
+			Authenticated: true,
+			User:          authenticationv1.UserInfo{Username: review.Spec.Token, Groups: groups[review.Spec.Token]},
+		}
+		return true, review, nil
+	})
+	kubeClient.PrependReactor("create", "subjectaccessreviews", func(action ktesting.Action) (bool, runtime.Object, error) {
+		review := action.(ktesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		review.Status.Allowed = review.Spec.User != "outsider" && review.Spec.ResourceAttributes.Verb == "get"
+		return true, review, nil
+	})
+	return &eligibilityHandler{
+		admission:     &reconciler{client: kubeClient},
+		approvalTasks: approvaltaskfake.NewSimpleClientset(tasks...),
+	}
+}
+
+func getEligibility(t *testing.T, h *eligibilityHandler, token, query string) (int, Eligibility) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, EligibilityPath+query, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var got Eligibility
+	if rec.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	}
+	return rec.Code, got
+}
+
+func TestEligibility(t *testing.T) {
+	groups := map[string][]string{"carol": {"platform"}, "dave": {"auditors"}}
+	h := newEligibilityHandler(groups, eligibilityApprovalTask())
+
+	tests := []struct {
+		name  string
+		token string
+		want  Eligibility
+	}{{
+		name:  "user approver",
+		token: "alice",
+		want:  Eligibility{CanApprove: true, CanReject: true, Role: roleUser},
+	}, {
+		name:  "user approver who already approved",
+		token: "bob",
+		want:  Eligibility{CanReject: true, AlreadyResponded: true, Role: roleUser},
+	}, {
+		name:  "group member",
+		token: "carol",
+		want:  Eligibility{CanApprove: true, CanReject: true, Role: roleGroupMember},
+	}, {
+		name:  "group member restricted to reject",
+		token: "dave",
+		want:  Eligibility{CanReject: true, Role: roleGroupMember},
+	}, {
+		name:  "non approver",
+		token: "mallory",
+		want:  Eligibility{Role: roleNone},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			code, got := getEligibility(t, h, tc.token, "?namespace=production&name=deploy")
+			assert.Equal(t, http.StatusOK, code)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestEligibilityFinalTask(t *testing.T) {
+	at := eligibilityApprovalTask()
+	at.Status.State = "rejected"
+	h := newEligibilityHandler(nil, at)
+
+	code, got := getEligibility(t, h, "alice", "?namespace=production&name=deploy")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, Eligibility{Role: roleUser}, got)
+}
+
+func TestEligibilityDoesNotMutateTask(t *testing.T) {
+	h := newEligibilityHandler(map[string][]string{"carol": {"platform"}}, eligibilityApprovalTask())
+
+	getEligibility(t, h, "carol", "?namespace=production&name=deploy")
+
+	for _, action := range h.approvalTasks.(*approvaltaskfake.Clientset).Actions() {
+		assert.Equal(t, "get", action.GetVerb(), "eligibility must only read the task")
+	}
+}
+
+func TestEligibilityRequestErrors(t *testing.T) {
+	h := newEligibilityHandler(nil, eligibilityApprovalTask())
+
+	code, _ := getEligibility(t, h, "", "?namespace=production&name=deploy")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = getEligibility(t, h, "invalid", "?namespace=production&name=deploy")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = getEligibility(t, h, "alice", "?namespace=production")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = getEligibility(t, h, "outsider", "?namespace=production&name=deploy")
+	assert.Equal(t, http.StatusForbidden, code, "callers must be allowed to get the task")
+
+	code, _ = getEligibility(t, h, "alice", "?namespace=production&name=missing")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestEligibilityEmailApprover(t *testing.T) {
+	at := eligibilityApprovalTask()
+	at.Spec.Approvers = append(at.Spec.Approvers, v1alpha1.ApproverDetails{Name: "erin@example.com", Type: "Email", Input: "pending"})
+	at.Status.ApproversResponse = append(at.Status.ApproversResponse, v1alpha1.ApproverState{Name: "erin@example.com", Type: "Email", Response: "rejected"})
+	userInfo := authenticationv1.UserInfo{
+		Username: "u-1234",
+		Extra:    map[string]authenticationv1.ExtraValue{"email": {"erin@example.com"}},
+	}
+
+	got := (&reconciler{}).eligibility(context.Background(), at, userInfo)
+	assert.Equal(t, Eligibility{AlreadyResponded: true, Role: roleUser}, got)
+}
+
+func TestEligibilityRequiresClaim(t *testing.T) {
+	h := newEligibilityHandler(nil, eligibilityApprovalTask())
+	h.admission.requiredClaim = "email_verified"
+
+	code, got := getEligibility(t, h, "alice", "?namespace=production&name=deploy")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, Eligibility{Role: roleUser}, got, "without the claim alice can neither approve nor reject")
+}
+
+func TestEligibilityExplicitGroupMembership(t *testing.T) {
+	at := eligibilityApprovalTask()
+	at.Spec.Approvers[2].Users = []v1alpha1.UserDetails{{Name: "erin", Input: "pending"}}
+	groups := map[string][]string{"carol": {"platform"}}
+	h := newEligibilityHandler(groups, at)
+	h.admission.explicitGroupMembers = true
+
+	_, got := getEligibility(t, h, "carol", "?namespace=production&name=deploy")
+	assert.Equal(t, Eligibility{Role: roleNone}, got, "carol's token groups are ignored")
+
+	_, got = getEligibility(t, h, "erin", "?namespace=production&name=deploy")
+	assert.Equal(t, Eligibility{CanApprove: true, CanReject: true, Role: roleGroupMember}, got)
+}
+
+func TestEligibilityDecisionRules(t *testing.T) {
+	at := eligibilityApprovalTask()
+	at.Spec.ExpectedDigest = "sha256:4c1e2f"
+	at.Spec.RequireDigestAcknowledgment = true
+	h := newEligibilityHandler(map[string][]string{"carol": {"platform"}}, at)
+
+	_, got := getEligibility(t, h, "alice", "?namespace=production&name=deploy")
+	assert.Equal(t, Eligibility{CanApprove: true, CanReject: true, Role: roleUser}, got, "approvals acknowledge the expected digest")
+	_, got = getEligibility(t, h, "carol", "?namespace=production&name=deploy")
+	assert.Equal(t, Eligibility{CanApprove: true, CanReject: true, Role: roleGroupMember}, got)
+
+	at.Annotations = map[string]string{v1alpha1.CurrentDigestAnnotationKey: "sha256:9f8e7d"}
+	h = newEligibilityHandler(nil, at)
+	_, got = getEligibility(t, h, "alice", "?namespace=production&name=deploy")
+	assert.Equal(t, Eligibility{CanReject: true, Role: roleUser}, got, "approvals of another artifact are denied")
+
+	at = eligibilityApprovalTask()
+	h = newEligibilityHandler(nil, at)
+	h.admission.requireChangeTicket = true
+	_, got = getEligibility(t, h, "alice", "?namespace=production&name=deploy")
+	assert.Equal(t, Eligibility{CanReject: true, Role: roleUser}, got, "approvals need a change ticket")
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	userv1 "github.com/openshift/api/user/v1"
+	userv1typedclient "github.com/openshift/client-go/user/clientset/versioned/typed/user/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// groupMembershipUnverifiedMsg denies decisions that depend on a group
+// membership the GroupResolver failed to look up. Unlike "User does not
+// exist in the approval list" it tells the user the failure is transient.
+const groupMembershipUnverifiedMsg = "Could not verify group membership, try again"
+
+// groupsByUserIndex indexes the OpenShift Groups by the users they list.
+const groupsByUserIndex = "byUser"
+
+// errGroupsNotSynced is returned while the OpenShift Groups are still being
+// listed, so that the decisions depending on them are retried.
+var errGroupsNotSynced = errors.New("the OpenShift groups are not synced yet")
+
+// GroupResolver looks up the groups of a user in a directory, in addition to
+// the groups asserted by their token.
+type GroupResolver interface {
+	Groups(ctx context.Context, username string) ([]string, error)
+}
+
+type openShiftGroupResolver struct {
+	groups cache.Indexer
+	synced cache.InformerSynced
+}
+
+// NewOpenShiftGroupResolver returns a GroupResolver reading the users of the
+// OpenShift Group objects of the cluster from an informer, indexed by user,
+// which it runs until ctx is done. Admissions then look up the groups of a
+// user in memory instead of listing every group of the cluster.
+func NewOpenShiftGroupResolver(ctx context.Context, groups userv1typedclient.GroupsGetter) GroupResolver {
+	informer := cache.NewSharedIndexInformer(&cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return groups.Groups().List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return groups.Groups().Watch(ctx, options)
+		},
+	}, &userv1.Group{}, 0, cache.Indexers{groupsByUserIndex: groupUsers})
+	go informer.Run(ctx.Done())
+	return &openShiftGroupResolver{groups: informer.GetIndexer(), synced: informer.HasSynced}
+}
+
+// groupUsers returns the users an OpenShift Group lists, indexing it under
+// each of them.
+func groupUsers(obj interface{}) ([]string, error) {
+	group, ok := obj.(*userv1.Group)
+	if !ok {
+		return nil, fmt.Errorf("expected an OpenShift Group, got %T", obj)
+	}
+	return group.Users, nil
+}
+
+func (o *openShiftGroupResolver) Groups(ctx context.Context, username string) ([]string, error) {
+	if !o.synced() {
+		return nil, errGroupsNotSynced
+	}
+	objs, err := o.groups.ByIndex(groupsByUserIndex, username)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		groups = append(groups, obj.(*userv1.Group).Name)
+	}
+	return groups, nil
+}
+
+// resolveGroups returns request with the groups the GroupResolver knows the
+// user to be in added to its UserInfo. The resolver is only asked when one of
+// approvers is a group the user does not already belong to. Its error is
+// returned when the user is not an individual approver either, since the
+// decision then depends on the group membership it failed to look up.
+func (r *reconciler) resolveGroups(ctx context.Context, request *admissionv1.AdmissionRequest, approvers []v1alpha1.ApproverDetails) (*admissionv1.AdmissionRequest, error) {
+	return r.resolveGroupsWith(ctx, r.groupResolver, request, approvers)
+}
+
+// resolveGroupsWith is resolveGroups asking resolver instead of the
+// GroupResolver of the webhook.
+func (r *reconciler) resolveGroupsWith(ctx context.Context, resolver GroupResolver, request *admissionv1.AdmissionRequest, approvers []v1alpha1.ApproverDetails) (*admissionv1.AdmissionRequest, error) {
+	if resolver == nil || r.explicitGroupMembers || !hasUnresolvedGroup(approvers, request) {
+		return request, nil
+	}
+
+	groups, err := r.resolveGroupsOf(ctx, resolver, request.UserInfo.Username)
+	if err != nil {
+		for _, approver := range approvers {
+			if isIndividualApprover(approver.Type, approver.Name, request.UserInfo) {
+				return request, nil
+			}
+		}
+		return nil, fmt.Errorf("resolving the groups of %q: %w", r.usernames.redact(request.UserInfo.Username), err)
+	}
+
+	resolved := request.DeepCopy()
+	for _, group := range groups {
+		if !webhookContains(resolved.UserInfo.Groups, group) {
+			resolved.UserInfo.Groups = append(resolved.UserInfo.Groups, group)
+		}
+	}
+	return r.withAncestorGroups(resolved), nil
+}
+
+// resolveGroupsOf asks resolver for the groups of username within the limit
+// of concurrent resolutions of the webhook.
+func (r *reconciler) resolveGroupsOf(ctx context.Context, resolver GroupResolver, username string) ([]string, error) {
+	release, ok := r.resolutions.acquire()
+	if !ok {
+		return nil, errResolutionsSaturated
+	}
+	defer release()
+	return resolver.Groups(ctx, username)
+}
+
+// hasUnresolvedGroup reports whether a Group approver neither lists the user
+// in its users nor is one of the groups of their token.
+func hasUnresolvedGroup(approvers []v1alpha1.ApproverDetails, request *admissionv1.AdmissionRequest) bool {
+	for _, approver := range approvers {
+		if v1alpha1.DefaultedApproverType(approver.Type) != "Group" || webhookContains(request.UserInfo.Groups, approver.Name) {
+			continue
+		}
+		listed := false
+		for _, user := range approver.Users {
+			if user.Name == request.UserInfo.Username {
+				listed = true
+				break
+			}
+		}
+		if !listed {
+			return true
+		}
+	}
+	return false
+}
+
+// memoizedGroupResolver asks its resolver at most once per user, so that
+// checks against many approval tasks in a single request do not each look up
+// the same groups.
+type memoizedGroupResolver struct {
+	resolver GroupResolver
+
+	mu      sync.Mutex
+	results map[string]memoizedGroups
+}
+
+type memoizedGroups struct {
+	groups []string
+	err    error
+}
+
+// newMemoizedGroupResolver returns resolver memoized, or nil when resolver is
+// nil so that group resolution stays turned off.
+func newMemoizedGroupResolver(resolver GroupResolver) *memoizedGroupResolver {
+	if resolver == nil {
+		return nil
+	}
+	return &memoizedGroupResolver{resolver: resolver, results: map[string]memoizedGroups{}}
+}
+
+func (m *memoizedGroupResolver) Groups(ctx context.Context, username string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result, ok := m.results[username]
+	if !ok {
+		result.groups, result.err = m.resolver.Groups(ctx, username)
+		m.results[username] = result
+	}
+	return result.groups, result.err
+}
+
+// failed reports whether looking up the groups of a user failed.
+func (m *memoizedGroupResolver) failed() bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, result := range m.results {
+		if result.err != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	userv1 "github.com/openshift/api/user/v1"
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats/view"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+type fakeGroupResolver struct {
+	groups map[string][]string
+	err    error
+	calls  int
+}
+
+func (f *fakeGroupResolver) Groups(_ context.Context, username string) ([]string, error) {
+	f.calls++
+	return f.groups[username], f.err
+}
+
+func groupApprovalTask(namespace string) *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: namespace},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "platform", Type: "Group", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func groupResolutionFailures(t *testing.T, namespace string) int64 {
+	t.Helper()
+	rows, err := view.RetrieveData(groupResolutionFailuresName)
+	assert.NoError(t, err)
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			if tag.Key.Name() == "namespace" && tag.Value == namespace {
+				return row.Data.(*view.CountData).Value
+			}
+		}
+	}
+	return 0
+}
+
+func TestAdmitWithResolvedGroups(t *testing.T) {
+	resolver := &fakeGroupResolver{groups: map[string][]string{"carol": {"platform"}}}
+	r := &reconciler{groupResolver: resolver}
+	oldObj := groupApprovalTask("groups-resolved")
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+	newObj.Spec.Approvers[1].Users = []v1alpha1.UserDetails{{Name: "carol", Input: "approve"}}
+
+	// The token of carol does not carry the group, the resolver knows it
+	resp := admitUpdateWith(t, r, oldObj, newObj, "carol")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	resp = admitUpdateWith(t, r, oldObj, newObj, "dave")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "User does not exist in the approval list", resp.Result.Message)
+
+	// The resolver is not asked when the token already carries the group
+	calls := resolver.calls
+	resp = admitUpdateWith(t, r, oldObj, newObj, "carol", "platform")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	assert.Equal(t, calls, resolver.calls)
+}
+
+func TestAdmitWithFailingGroupResolver(t *testing.T) {
+	r := &reconciler{
+		groupResolver: &fakeGroupResolver{err: errors.New("directory unavailable")},
+		decisions:     newDecisionReporter(10, false),
+	}
+	oldObj := groupApprovalTask("groups-failing")
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+	newObj.Spec.Approvers[1].Users = []v1alpha1.UserDetails{{Name: "carol", Input: "approve"}}
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "carol")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Could not verify group membership, try again", resp.Result.Message)
+	assert.Equal(t, int64(1), groupResolutionFailures(t, "groups-failing"))
+
+	// Individual approvers decide without the resolver
+	approved := oldObj.DeepCopy()
+	approved.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdateWith(t, r, oldObj, approved, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	assert.Equal(t, int64(1), groupResolutionFailures(t, "groups-failing"))
+}
+
+func TestOpenShiftGroupResolver(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{groupsByUserIndex: groupUsers})
+	for _, group := range []*userv1.Group{
+		{ObjectMeta: metav1.ObjectMeta{Name: "platform"}, Users: userv1.OptionalNames{"alice", "bob"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "security"}, Users: userv1.OptionalNames{"bob"}},
+	} {
+		assert.NoError(t, indexer.Add(group))
+	}
+	synced := false
+	resolver := &openShiftGroupResolver{groups: indexer, synced: func() bool { return synced }}
+
+	_, err := resolver.Groups(context.Background(), "bob")
+	assert.ErrorIs(t, err, errGroupsNotSynced, "the groups are not looked up before the informer synced")
+
+	synced = true
+	groups, err := resolver.Groups(context.Background(), "bob")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"platform", "security"}, groups)
+	groups, err = resolver.Groups(context.Background(), "carol")
+	assert.NoError(t, err)
+	assert.Empty(t, groups)
+}
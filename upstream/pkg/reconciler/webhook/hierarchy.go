@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+type groupSeparatorKey struct{}
+
+// withGroupSeparator returns ctx carrying the separator of hierarchical group
+// names, for the validation of the approvers of a task.
+func withGroupSeparator(ctx context.Context, separator string) context.Context {
+	return context.WithValue(ctx, groupSeparatorKey{}, separator)
+}
+
+// groupSeparatorFrom returns the separator of hierarchical group names
+// carried by ctx, or an empty string when group names are flat.
+func groupSeparatorFrom(ctx context.Context) string {
+	separator, _ := ctx.Value(groupSeparatorKey{}).(string)
+	return separator
+}
+
+// groupAncestors returns the ancestors of a hierarchical group, closest
+// first: "org:dept:team" has the ancestors "org:dept" and "org" with the
+// separator ":". Sibling groups are unrelated: "org:department" is not a
+// descendant of "org:dept". Groups have no ancestors when separator is empty.
+func groupAncestors(group, separator string) []string {
+	if separator == "" {
+		return nil
+	}
+	var ancestors []string
+	for i := strings.LastIndex(group, separator); i > 0; i = strings.LastIndex(group, separator) {
+		group = group[:i]
+		ancestors = append(ancestors, group)
+	}
+	return ancestors
+}
+
+// withAncestorGroups returns request with the ancestors of the groups of the
+// user added to its UserInfo, so that the approver checks matching Group
+// approvers by name let the members of "org:dept:team" decide for "org:dept".
+// The request is returned unchanged unless r.groupSeparator is set.
+func (r *reconciler) withAncestorGroups(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionRequest {
+	var ancestors []string
+	for _, group := range request.UserInfo.Groups {
+		for _, ancestor := range groupAncestors(group, r.groupSeparator) {
+			if !webhookContains(request.UserInfo.Groups, ancestor) && !webhookContains(ancestors, ancestor) {
+				ancestors = append(ancestors, ancestor)
+			}
+		}
+	}
+	if len(ancestors) == 0 {
+		return request
+	}
+	expanded := request.DeepCopy()
+	expanded.UserInfo.Groups = append(expanded.UserInfo.Groups, ancestors...)
+	return expanded
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+func TestGroupAncestors(t *testing.T) {
+	assert.Equal(t, []string{"org:dept", "org"}, groupAncestors("org:dept:team", ":"))
+	assert.Equal(t, []string{"org"}, groupAncestors("org/dept", "/"))
+	assert.Empty(t, groupAncestors("org", ":"))
+	assert.Empty(t, groupAncestors(":org", ":"), "a leading separator is not an ancestor")
+	assert.Empty(t, groupAncestors("org:dept:team", ""), "hierarchies are off without a separator")
+}
+
+func TestWithAncestorGroups(t *testing.T) {
+	r := &reconciler{groupSeparator: ":"}
+	request := admissionRequestFor("carol", "org:dept:team", "org:ops")
+	expanded := r.withAncestorGroups(request)
+	assert.Equal(t, []string{"org:dept:team", "org:ops", "org:dept", "org"}, expanded.UserInfo.Groups)
+	assert.Equal(t, []string{"org:dept:team", "org:ops"}, request.UserInfo.Groups, "the request is not modified")
+
+	request = admissionRequestFor("carol", "platform")
+	assert.Same(t, request, r.withAncestorGroups(request))
+}
+
+// hierarchyApprovalTask returns a task with the Group approver org:dept.
+func hierarchyApprovalTask() *v1alpha1.ApprovalTask {
+	at := groupApprovalTask("groups-hierarchy")
+	at.Spec.NumberOfApprovalsRequired = 1
+	at.Spec.Approvers[1].Name = "org:dept"
+	return at
+}
+
+func TestAdmitDescendantGroupMember(t *testing.T) {
+	oldObj := hierarchyApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+	newObj.Spec.Approvers[1].Users = []v1alpha1.UserDetails{{Name: "carol", Input: "approve"}}
+
+	r := &reconciler{groupSeparator: ":"}
+	resp := admitUpdateWith(t, r, oldObj, newObj, "carol", "org:dept:team")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	resp = admitUpdateWith(t, r, oldObj, newObj, "carol", "org:department")
+	assert.False(t, resp.Allowed, "sibling groups sharing a prefix do not match")
+	assert.Equal(t, "User does not exist in the approval list", resp.Result.Message)
+
+	resp = admitUpdateWith(t, r, oldObj, newObj, "carol", "org")
+	assert.False(t, resp.Allowed, "ancestor groups do not match their descendants")
+
+	resp = admitUpdateWith(t, &reconciler{}, oldObj, newObj, "carol", "org:dept:team")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "validation failed: spec validation failed: approvers[1].name: group name cannot contain colons", resp.Result.Message)
+
+	r = &reconciler{groupSeparator: "/"}
+	oldObj.Spec.Approvers[1].Name = "org/dept"
+	newObj.Spec.Approvers[1].Name = "org/dept"
+	assert.True(t, admitUpdateWith(t, r, oldObj, newObj, "carol", "org/dept/team").Allowed)
+	assert.False(t, admitUpdateWith(t, r, oldObj, newObj, "carol", "org/department").Allowed)
+}
+
+func TestValidateHierarchicalGroupName(t *testing.T) {
+	assert.NoError(t, validateGroupName("org:dept", ":"))
+	assert.EqualError(t, validateGroupName("org:dept", ""), "group name cannot contain colons")
+	assert.EqualError(t, validateGroupName("org:dept", "/"), "group name cannot contain colons")
+	assert.EqualError(t, validateGroupName("org::dept", ":"), "group name cannot have empty levels, got 'org::dept'")
+	assert.EqualError(t, validateGroupName("org/dept/", "/"), "group name cannot have empty levels, got 'org/dept/'")
+}
+
+func TestEligibilityDescendantGroupMember(t *testing.T) {
+	at := hierarchyApprovalTask()
+	userInfo := authenticationv1.UserInfo{Username: "carol", Groups: []string{"org:dept:team"}}
+
+	eligibility := (&reconciler{groupSeparator: ":"}).eligibility(context.Background(), at, userInfo)
+	assert.Equal(t, roleGroupMember, eligibility.Role)
+	assert.True(t, eligibility.CanApprove)
+
+	userInfo.Groups = []string{"org:department:team"}
+	eligibility = (&reconciler{groupSeparator: ":"}).eligibility(context.Background(), at, userInfo)
+	assert.False(t, eligibility.CanApprove)
+}
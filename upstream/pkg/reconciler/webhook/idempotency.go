@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// alreadyAppliedWarning is returned when a retried decision is admitted
+// without effect.
+const alreadyAppliedWarning = "already applied"
+
+// isDecisionReplay reports whether the update is a retry of a decision that
+// was already applied: it carries an idempotency key, changes nothing but
+// metadata, and the key is recorded on the response of an approver the user
+// decides for. Until the controller has recorded the response, the key on
+// the approval task itself is matched instead.
+func (r *reconciler) isDecisionReplay(ctx context.Context, oldObj, newObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest) bool {
+	key := newObj.Annotations[v1alpha1.IdempotencyKeyAnnotationKey]
+	if key == "" || !isMetadataOnlyUpdate(oldObj, newObj) {
+		return false
+	}
+
+	resolved := r.resolveSubstitutes(ctx, oldObj)
+	recorded := false
+	for _, response := range resolved.Status.ApproversResponse {
+		if v1alpha1.IsIndividualApproverType(response.Type) {
+			if response.IdempotencyKey == key {
+				if isIndividualApprover(response.Type, response.Name, request.UserInfo) {
+					return true
+				}
+				recorded = true
+			}
+			continue
+		}
+		for _, member := range response.GroupMembers {
+			if member.IdempotencyKey == key {
+				if member.Name == request.UserInfo.Username {
+					return true
+				}
+				recorded = true
+			}
+		}
+	}
+
+	return !recorded && oldObj.Annotations[v1alpha1.IdempotencyKeyAnnotationKey] == key &&
+		hasDecided(r.effectiveApprovers(ctx, resolved), request)
+}
+
+// hasDecided reports whether the approvers include a decision of the user.
+func hasDecided(approvers []v1alpha1.ApproverDetails, request *admissionv1.AdmissionRequest) bool {
+	decided := func(input string) bool { return input == "approve" || input == "reject" || input == "request-changes" }
+	for _, approver := range approvers {
+		if isIndividualApprover(approver.Type, approver.Name, request.UserInfo) && decided(approver.Input) {
+			return true
+		}
+		if v1alpha1.DefaultedApproverType(approver.Type) != "Group" {
+			continue
+		}
+		for _, user := range approver.Users {
+			if user.Name == request.UserInfo.Username && decided(user.Input) {
+				return true
+			}
+		}
+	}
+	return false
+}
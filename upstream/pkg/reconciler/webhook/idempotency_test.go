@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func keyedApprovalTask() *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "platform", Type: "Group", Input: "pending", Users: []v1alpha1.UserDetails{{Name: "bob", Input: "pending"}}},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+// withKey returns a copy of the approval task carrying the idempotency key.
+func withKey(at *v1alpha1.ApprovalTask, key string) *v1alpha1.ApprovalTask {
+	keyed := at.DeepCopy()
+	if keyed.Annotations == nil {
+		keyed.Annotations = map[string]string{}
+	}
+	keyed.Annotations[v1alpha1.IdempotencyKeyAnnotationKey] = key
+	return keyed
+}
+
+func TestAdmitReplayedDecision(t *testing.T) {
+	pending := keyedApprovalTask()
+	approved := withKey(pending, "alice-1")
+	approved.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdate(t, pending, approved, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	assert.NotContains(t, resp.Warnings, "already applied")
+
+	// The retry arrives before the controller recorded the response
+	resp = admitUpdate(t, approved, approved.DeepCopy(), "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	assert.Equal(t, []string{"already applied"}, resp.Warnings)
+
+	resp = admitUpdate(t, approved, approved.DeepCopy(), "bob")
+	assert.False(t, resp.Allowed, "bob has not decided anything the key could belong to")
+
+	// and after, once someone else's keyed decision replaced the key on the task
+	recorded := withKey(approved, "bob-1")
+	recorded.Status.ApproversResponse = []v1alpha1.ApproverState{{Name: "alice", Type: "User", Response: "approved", IdempotencyKey: "alice-1"}}
+	replay := withKey(recorded, "alice-1")
+	resp = admitUpdate(t, recorded, replay, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	assert.Equal(t, []string{"already applied"}, resp.Warnings)
+
+	resp = admitUpdate(t, recorded, replay, "bob")
+	assert.False(t, resp.Allowed, "the key is alice's")
+}
+
+func TestAdmitReplayedGroupDecisionOnFinalTask(t *testing.T) {
+	final := withKey(keyedApprovalTask(), "bob-1")
+	final.Spec.Approvers[1].Input = "approve"
+	final.Spec.Approvers[1].Users[0].Input = "approve"
+	final.Status.State = "approved"
+	final.Status.ApproversResponse = []v1alpha1.ApproverState{{
+		Name: "platform", Type: "Group", Response: "approved",
+		GroupMembers: []v1alpha1.GroupMemberState{{Name: "bob", Response: "approved", IdempotencyKey: "bob-1"}},
+	}}
+
+	resp := admitUpdate(t, final, final.DeepCopy(), "bob")
+	assert.True(t, resp.Allowed, "a retry of the decision that made the task final is harmless: %v", resp.Result)
+	assert.Equal(t, []string{"already applied"}, resp.Warnings)
+
+	resp = admitUpdate(t, final, final.DeepCopy(), "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "ApprovalTask has already reached it's final state", resp.Result.Message)
+}
+
+func TestAdmitKeyedUpdateWithChanges(t *testing.T) {
+	approved := withKey(keyedApprovalTask(), "alice-1")
+	approved.Spec.Approvers[0].Input = "approve"
+	approved.Status.ApproversResponse = []v1alpha1.ApproverState{{Name: "alice", Type: "User", Response: "approved", IdempotencyKey: "alice-1"}}
+
+	// Reusing the key for a different decision is not a retry
+	rejected := approved.DeepCopy()
+	rejected.Spec.Approvers[0].Input = "reject"
+	resp := admitUpdate(t, approved, rejected, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	assert.NotContains(t, resp.Warnings, "already applied")
+
+	// and neither is an unkeyed no-op
+	unkeyed := approved.DeepCopy()
+	delete(unkeyed.Annotations, v1alpha1.IdempotencyKeyAnnotationKey)
+	resp = admitUpdate(t, unkeyed, unkeyed.DeepCopy(), "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "User has already approved", resp.Result.Message)
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+// DefaultImpersonatorClaim is the UserInfo extra claim the identity
+// impersonating the user is read from by default, as set by the impersonator
+// with an Impersonate-Extra header.
+const DefaultImpersonatorClaim = "impersonator"
+
+// forbiddenImpersonation returns the denial message of a decision made
+// through impersonation, which the r.impersonatorClaim extra claim tells
+// apart, unless the impersonated user is listed in r.allowedImpersonations.
+// Groups are not matched: the impersonator chooses the groups it sends along
+// with the user.
+func (r *reconciler) forbiddenImpersonation(userInfo authenticationv1.UserInfo) string {
+	claim := r.impersonatorClaim
+	if claim == "" {
+		claim = DefaultImpersonatorClaim
+	}
+	impersonators, ok := userInfo.Extra[claim]
+	if !ok {
+		return ""
+	}
+	if webhookContains(r.allowedImpersonations, userInfo.Username) {
+		return ""
+	}
+	return fmt.Sprintf("Decisions through impersonation are not permitted for user '%s', impersonated by '%s'", userInfo.Username, strings.Join(impersonators, ", "))
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+func impersonated(username, impersonator string, groups ...string) authenticationv1.UserInfo {
+	return authenticationv1.UserInfo{
+		Username: username,
+		Groups:   groups,
+		Extra:    map[string]authenticationv1.ExtraValue{DefaultImpersonatorClaim: {impersonator}},
+	}
+}
+
+func TestForbiddenImpersonation(t *testing.T) {
+	r := &reconciler{allowedImpersonations: []string{"release-bot", "group:approval-bots"}}
+
+	assert.Empty(t, r.forbiddenImpersonation(authenticationv1.UserInfo{Username: "alice"}), "requests that are not impersonated are left alone")
+	assert.Empty(t, r.forbiddenImpersonation(impersonated("release-bot", "chat-relay")))
+	assert.Equal(t, "Decisions through impersonation are not permitted for user 'alice', impersonated by 'chat-relay'",
+		r.forbiddenImpersonation(impersonated("alice", "chat-relay", "approval-bots")), "the impersonator chooses the groups, which are not trusted")
+	assert.Equal(t, "Decisions through impersonation are not permitted for user 'alice', impersonated by 'chat-relay'",
+		r.forbiddenImpersonation(impersonated("alice", "chat-relay", "developers")))
+
+	assert.NotEmpty(t, (&reconciler{}).forbiddenImpersonation(impersonated("release-bot", "chat-relay")), "no impersonation is permitted by default")
+
+	r.impersonatorClaim = "acting-for"
+	assert.Empty(t, r.forbiddenImpersonation(impersonated("alice", "chat-relay")), "only the configured claim is read")
+}
+
+func TestAdmitImpersonatedDecision(t *testing.T) {
+	r := &reconciler{allowedImpersonations: []string{"alice"}}
+	oldObj := keyedApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdateAs(t, r, oldObj, newObj, impersonated("alice", "chat-relay"))
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	r.allowedImpersonations = []string{"group:approval-bots"}
+	resp = admitUpdateAs(t, r, oldObj, newObj, impersonated("alice", "chat-relay", "approval-bots"))
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Decisions through impersonation are not permitted for user 'alice', impersonated by 'chat-relay'", resp.Result.Message)
+
+	eligibility := r.eligibility(context.TODO(), oldObj, impersonated("alice", "chat-relay"))
+	assert.False(t, eligibility.CanApprove)
+}
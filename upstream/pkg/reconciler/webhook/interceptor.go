@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Names of the built-in interceptors of the default decision chain, in the
+// order they run.
+const (
+	InterceptorStructure      = "structure"
+	InterceptorReplay         = "replay"
+	InterceptorFinalState     = "final-state"
+	InterceptorAdministrative = "administrative"
+	InterceptorIdentity       = "identity"
+	InterceptorExistence      = "existence"
+	InterceptorRenewal        = "renewal"
+	InterceptorDecisionRules  = "decision-rules"
+	InterceptorForeignChange  = "foreign-change"
+)
+
+// undecidedMsg denies updates no interceptor of the chain decided on.
+const undecidedMsg = "No decision interceptor admitted the update"
+
+// Decision is the update of an ApprovalTask the decision chain decides on.
+// Interceptors may replace its fields for the interceptors after them, as
+// the existence interceptor does once it resolved the groups of the user.
+type Decision struct {
+	Request *admissionv1.AdmissionRequest
+	Old     *v1alpha1.ApprovalTask
+	New     *v1alpha1.ApprovalTask
+}
+
+// DecisionInterceptor is a step of the chain deciding on updates to
+// ApprovalTasks. Intercept returns the response allowing or denying the
+// update, or nil to continue with the next interceptor.
+type DecisionInterceptor interface {
+	Name() string
+	Intercept(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse
+}
+
+type decisionInterceptorFunc struct {
+	name      string
+	intercept func(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse
+}
+
+// NewDecisionInterceptor returns a DecisionInterceptor named name running
+// intercept.
+func NewDecisionInterceptor(name string, intercept func(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse) DecisionInterceptor {
+	return &decisionInterceptorFunc{name: name, intercept: intercept}
+}
+
+func (f *decisionInterceptorFunc) Name() string {
+	return f.name
+}
+
+func (f *decisionInterceptorFunc) Intercept(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse {
+	return f.intercept(ctx, d)
+}
+
+// defaultInterceptors returns the built-in decision chain. The foreign-change
+// interceptor closing it always decides, so interceptors appended after it
+// only run once it is removed.
+func (r *reconciler) defaultInterceptors() []DecisionInterceptor {
+	return []DecisionInterceptor{
+		NewDecisionInterceptor(InterceptorStructure, r.interceptStructure),
+		NewDecisionInterceptor(InterceptorReplay, r.interceptReplay),
+		NewDecisionInterceptor(InterceptorFinalState, r.interceptFinalState),
+		NewDecisionInterceptor(InterceptorAdministrative, r.interceptAdministrative),
+		NewDecisionInterceptor(InterceptorIdentity, r.interceptIdentity),
+		NewDecisionInterceptor(InterceptorExistence, r.interceptExistence),
+		NewDecisionInterceptor(InterceptorRenewal, r.interceptRenewal),
+		NewDecisionInterceptor(InterceptorDecisionRules, r.interceptDecisionRules),
+		NewDecisionInterceptor(InterceptorForeignChange, r.interceptForeignChange),
+	}
+}
+
+// decisionChain returns the interceptors deciding on updates: the default
+// chain, as rearranged by the configured DecisionInterceptors option.
+func (r *reconciler) decisionChain() []DecisionInterceptor {
+	defaults := r.defaultInterceptors()
+	if r.interceptors == nil {
+		return defaults
+	}
+	return r.interceptors(defaults)
+}
+
+// decide runs the decision chain in order, returning the response of the
+// first interceptor that decides. An update no interceptor decided on is
+// denied.
+func (r *reconciler) decide(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse {
+	for _, interceptor := range r.decisionChain() {
+		if response := interceptor.Intercept(ctx, d); response != nil {
+			return response
+		}
+	}
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Message: undecidedMsg,
+		},
+	}
+}
+
+// InsertInterceptor returns chain with interceptor inserted before the
+// interceptor named before. It is appended when no interceptor has that name.
+func InsertInterceptor(chain []DecisionInterceptor, before string, interceptor DecisionInterceptor) []DecisionInterceptor {
+	inserted := make([]DecisionInterceptor, 0, len(chain)+1)
+	for i, existing := range chain {
+		if existing.Name() == before {
+			inserted = append(inserted, interceptor)
+			return append(inserted, chain[i:]...)
+		}
+		inserted = append(inserted, existing)
+	}
+	return append(inserted, interceptor)
+}
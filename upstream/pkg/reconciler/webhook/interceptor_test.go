@@ -0,0 +1,142 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func chainNames(chain []DecisionInterceptor) []string {
+	names := make([]string, 0, len(chain))
+	for _, interceptor := range chain {
+		names = append(names, interceptor.Name())
+	}
+	return names
+}
+
+func TestDefaultDecisionChain(t *testing.T) {
+	r := &reconciler{}
+	assert.Equal(t, []string{
+		InterceptorStructure,
+		InterceptorReplay,
+		InterceptorFinalState,
+		InterceptorAdministrative,
+		InterceptorIdentity,
+		InterceptorExistence,
+		InterceptorRenewal,
+		InterceptorDecisionRules,
+		InterceptorForeignChange,
+	}, chainNames(r.decisionChain()))
+
+	oldObj := withdrawableApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	assert.True(t, admitUpdateWith(t, r, oldObj, newObj, "alice").Allowed)
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "mallory")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "User does not exist in the approval list", resp.Result.Message)
+
+	oldObj.Status.State = "approved"
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "ApprovalTask has already reached it's final state", resp.Result.Message)
+}
+
+func TestDecisionChainCustomDeny(t *testing.T) {
+	freeze := NewDecisionInterceptor("change-freeze", func(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse {
+		if d.Old.Namespace != "production" {
+			return nil
+		}
+		return &admissionv1.AdmissionResponse{Allowed: false, Result: &metav1.Status{Message: "production is frozen"}}
+	})
+	r := &reconciler{interceptors: func(defaults []DecisionInterceptor) []DecisionInterceptor {
+		return InsertInterceptor(defaults, InterceptorExistence, freeze)
+	}}
+	assert.Equal(t, "change-freeze", chainNames(r.decisionChain())[5])
+
+	oldObj := withdrawableApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	resp := admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "production is frozen", resp.Result.Message)
+
+	oldObj.Status.State = "approved"
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.Equal(t, "ApprovalTask has already reached it's final state", resp.Result.Message,
+		"the interceptors before it decide first")
+
+	staging := withdrawableApprovalTask()
+	staging.Namespace = "staging"
+	approved := staging.DeepCopy()
+	approved.Spec.Approvers[0].Input = "approve"
+	assert.True(t, admitUpdateWith(t, r, staging, approved, "alice").Allowed, "continuing leaves the decision to the built-ins")
+}
+
+func TestDecisionChainShortCircuits(t *testing.T) {
+	var ran []string
+	record := func(name string, response *admissionv1.AdmissionResponse) DecisionInterceptor {
+		return NewDecisionInterceptor(name, func(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse {
+			ran = append(ran, name)
+			return response
+		})
+	}
+	r := &reconciler{interceptors: func(defaults []DecisionInterceptor) []DecisionInterceptor {
+		return []DecisionInterceptor{
+			record("first", nil),
+			record("break-glass", &admissionv1.AdmissionResponse{Allowed: true}),
+			record("never", &admissionv1.AdmissionResponse{Allowed: false}),
+		}
+	}}
+
+	oldObj := withdrawableApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	assert.True(t, admitUpdateWith(t, r, oldObj, newObj, "mallory").Allowed)
+	assert.Equal(t, []string{"first", "break-glass"}, ran)
+}
+
+func TestDecisionChainUndecided(t *testing.T) {
+	r := &reconciler{interceptors: func(defaults []DecisionInterceptor) []DecisionInterceptor {
+		return defaults[:len(defaults)-1]
+	}}
+	oldObj := withdrawableApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, undecidedMsg, resp.Result.Message)
+}
+
+func TestInsertInterceptor(t *testing.T) {
+	named := func(name string) DecisionInterceptor {
+		return NewDecisionInterceptor(name, func(context.Context, *Decision) *admissionv1.AdmissionResponse { return nil })
+	}
+	chain := []DecisionInterceptor{named("a"), named("b")}
+
+	assert.Equal(t, []string{"a", "x", "b"}, chainNames(InsertInterceptor(chain, "b", named("x"))))
+	assert.Equal(t, []string{"x", "a", "b"}, chainNames(InsertInterceptor(chain, "a", named("x"))))
+	assert.Equal(t, []string{"a", "b", "x"}, chainNames(InsertInterceptor(chain, "missing", named("x"))))
+	assert.Equal(t, []string{"a", "b"}, chainNames(chain), "the chain passed in is left as it is")
+}
@@ -0,0 +1,38 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// levelExtraKey is the UserInfo extra claim the seniority level of an
+// approver is taken from.
+const levelExtraKey = "level"
+
+// validateApproverLevels denies updates changing the level recorded on an
+// approver entry, or on a group member's entry, which does not belong to the
+// user, or recording a level which is not one of the values of the user's
+// level claim, the same way validateApproverTeams guards teams. Approvals
+// towards Spec.MinApproverLevel therefore always carry the level the
+// identity provider asserts.
+func validateApproverLevels(oldObj, newObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest) string {
+	return validateRecordedAttribute(approval.LevelAttribute, oldObj, newObj, request, levelExtraKey, "level",
+		"Level '%s' is not the level of the user")
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func levelApprovalTask() *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			MinApproverLevel:          3,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve", Level: "2"},
+				{Name: "bob", Type: "User", Input: "pending"},
+				{Name: "platform", Type: "Group", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func levelUser(name string, levels ...string) authenticationv1.UserInfo {
+	return authenticationv1.UserInfo{Username: name, Extra: map[string]authenticationv1.ExtraValue{levelExtraKey: levels}}
+}
+
+func TestApprovalRequiredUntilLevelApproves(t *testing.T) {
+	at := levelApprovalTask()
+	at.Spec.Approvers[1].Input = "approve"
+	at.Spec.Approvers[1].Level = "1"
+	assert.True(t, isApprovalRequired(*at), "neither alice nor bob meet the level")
+
+	at.Spec.Approvers[0].Level = "3"
+	at.Spec.Approvers[1].Level = "5"
+	assert.False(t, isApprovalRequired(*at))
+}
+
+func TestAdmitApprovalRecordingLevel(t *testing.T) {
+	r := &reconciler{}
+	oldObj := levelApprovalTask()
+
+	approved := oldObj.DeepCopy()
+	approved.Spec.Approvers[1].Input = "approve"
+	approved.Spec.Approvers[1].Level = "4"
+	resp := admitUpdateAs(t, r, oldObj, approved, levelUser("bob", "4"))
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	resp = admitUpdateAs(t, r, oldObj, approved, levelUser("bob", "2"))
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Level '4' is not the level of the user", resp.Result.Message)
+
+	resp = admitUpdateAs(t, r, oldObj, approved, levelUser("bob"))
+	assert.False(t, resp.Allowed, "bob has no level claim")
+}
+
+func TestAdmitGroupMemberRecordingLevel(t *testing.T) {
+	r := &reconciler{}
+	oldObj := levelApprovalTask()
+
+	approved := oldObj.DeepCopy()
+	approved.Spec.Approvers[2].Input = "approve"
+	approved.Spec.Approvers[2].Users = []v1alpha1.UserDetails{{Name: "carol", Input: "approve", Level: "3"}}
+	userInfo := levelUser("carol", "3")
+	userInfo.Groups = []string{"platform"}
+	resp := admitUpdateAs(t, r, oldObj, approved, userInfo)
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	userInfo.Extra[levelExtraKey] = []string{"1"}
+	resp = admitUpdateAs(t, r, oldObj, approved, userInfo)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Level '3' is not the level of the user", resp.Result.Message)
+}
+
+func TestAdmitChangingAnotherApproversLevel(t *testing.T) {
+	oldObj := levelApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Level = "5"
+	newObj.Spec.Approvers[1].Input = "approve"
+
+	resp := admitUpdateAs(t, &reconciler{}, oldObj, newObj, levelUser("bob", "5"))
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "User can only record their own level, not the level of approver 'alice'", resp.Result.Message)
+}
+
+func TestAdmitLoweringMinApproverLevel(t *testing.T) {
+	oldObj := levelApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.MinApproverLevel = 0
+	newObj.Spec.Approvers[1].Input = "approve"
+
+	resp := admitUpdateAs(t, &reconciler{}, oldObj, newObj, levelUser("bob", "1"))
+	assert.False(t, resp.Allowed, "lowering the level along with an approval would reach quorum without it")
+	assert.Equal(t, "The minimum approver level of an ApprovalTask cannot be changed", resp.Result.Message)
+}
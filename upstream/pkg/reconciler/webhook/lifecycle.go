@@ -0,0 +1,63 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// validateLifecycle checks the escalation and the maximum pending lifetime of
+// the spec: both must be positive, the task must escalate before it expires,
+// and backup approvers need an escalation to ever take part.
+func validateLifecycle(spec *v1alpha1.ApprovalTaskSpec) error {
+	if spec.EscalateAfter != nil && spec.EscalateAfter.Duration <= 0 {
+		return fmt.Errorf("escalateAfter: must be positive, got %s", spec.EscalateAfter.Duration)
+	}
+	if spec.MaxPendingLifetime != nil && spec.MaxPendingLifetime.Duration <= 0 {
+		return fmt.Errorf("maxPendingLifetime: must be positive, got %s", spec.MaxPendingLifetime.Duration)
+	}
+	if spec.EscalateAfter != nil && spec.MaxPendingLifetime != nil && spec.EscalateAfter.Duration >= spec.MaxPendingLifetime.Duration {
+		return fmt.Errorf("escalateAfter: must be shorter than maxPendingLifetime, got %s", spec.EscalateAfter.Duration)
+	}
+	if spec.EscalateAfter == nil {
+		for i, approver := range spec.Approvers {
+			if approver.Backup {
+				return fmt.Errorf("approvers[%d]: backup approver '%s' requires escalateAfter to be set", i, approver.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// lifecycleChanged reports whether the update changes when the task escalates
+// or expires, or which of its approvers are backup approvers.
+func lifecycleChanged(oldObj, newObj *v1alpha1.ApprovalTask) bool {
+	if !reflect.DeepEqual(oldObj.Spec.EscalateAfter, newObj.Spec.EscalateAfter) || !reflect.DeepEqual(oldObj.Spec.MaxPendingLifetime, newObj.Spec.MaxPendingLifetime) {
+		return true
+	}
+	for _, approver := range newObj.Spec.Approvers {
+		for _, oldApprover := range oldObj.Spec.Approvers {
+			if sameApprover(approver, oldApprover) && approver.Backup != oldApprover.Backup {
+				return true
+			}
+		}
+	}
+	return false
+}
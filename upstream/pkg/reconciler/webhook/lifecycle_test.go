@@ -0,0 +1,97 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func escalatingApprovalTask() *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			EscalateAfter:             &metav1.Duration{Duration: time.Hour},
+			MaxPendingLifetime:        &metav1.Duration{Duration: 4 * time.Hour},
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "bob", Type: "User", Input: "pending", Backup: true},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func TestValidateLifecycle(t *testing.T) {
+	spec := escalatingApprovalTask().Spec
+	assert.NoError(t, validateLifecycle(&spec))
+
+	spec.EscalateAfter = &metav1.Duration{Duration: 4 * time.Hour}
+	assert.EqualError(t, validateLifecycle(&spec), "escalateAfter: must be shorter than maxPendingLifetime, got 4h0m0s")
+
+	spec.MaxPendingLifetime = &metav1.Duration{Duration: -time.Minute}
+	assert.EqualError(t, validateLifecycle(&spec), "maxPendingLifetime: must be positive, got -1m0s")
+
+	spec = escalatingApprovalTask().Spec
+	spec.EscalateAfter = nil
+	assert.EqualError(t, validateLifecycle(&spec), "approvers[1]: backup approver 'bob' requires escalateAfter to be set")
+
+	spec.Approvers[1].Backup = false
+	assert.NoError(t, validateLifecycle(&spec), "a maximum pending lifetime alone is valid")
+}
+
+func TestBackupApproverDecidesOnceEscalated(t *testing.T) {
+	r := &reconciler{}
+	oldObj := escalatingApprovalTask()
+
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+	resp := admitUpdateWith(t, r, oldObj, newObj, "bob")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Approver 'bob' is a backup approver: it cannot decide until the task escalates", resp.Result.Message)
+
+	escalatedAt := metav1.Now()
+	oldObj.Status.EscalatedAt = &escalatedAt
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+	resp = admitUpdateWith(t, r, oldObj, newObj, "bob")
+	assert.True(t, resp.Allowed)
+}
+
+func TestLifecycleCannotBeChanged(t *testing.T) {
+	r := &reconciler{}
+	oldObj := escalatingApprovalTask()
+	message := "The escalation and the maximum pending lifetime of an ApprovalTask cannot be changed"
+
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.MaxPendingLifetime = &metav1.Duration{Duration: 48 * time.Hour}
+	resp := admitUpdateWith(t, r, oldObj, newObj, "alice", "system:masters")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, message, resp.Result.Message)
+
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Backup = false
+	newObj.Spec.Approvers[1].Input = "approve"
+	resp = admitUpdateWith(t, r, oldObj, newObj, "bob")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, message, resp.Result.Message)
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/correlation"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"knative.dev/pkg/logging"
+)
+
+// validateLinkedApprovals denies an approval of a user who already approved
+// another approval task linked to this one, that is another task of the
+// namespace sharing its correlation ID. A change split into several tasks
+// thus needs a different approver for each of them. Tasks without a
+// correlation ID are not linked to any other. The ID is read from the task
+// being updated, since the update cannot change it.
+func (r *reconciler) validateLinkedApprovals(ctx context.Context, oldObj, newObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest) string {
+	id := correlation.Of(oldObj)
+	if r.tasklister == nil || id == "" || !addsApproval(oldObj.Spec.Approvers, newObj.Spec.Approvers) {
+		return ""
+	}
+	tasks, err := r.tasklister.ApprovalTasks(newObj.Namespace).List(labels.Everything())
+	if err != nil {
+		logging.FromContext(ctx).Errorf("Unable to list the approval tasks linked to %s: %v", newObj.Name, err)
+		return ""
+	}
+	var approved []string
+	for _, at := range tasks {
+		if at.Name != newObj.Name && correlation.Of(at) == id && approvedBy(at, request.UserInfo) {
+			approved = append(approved, at.Name)
+		}
+	}
+	if len(approved) == 0 {
+		return ""
+	}
+	sort.Strings(approved)
+	return fmt.Sprintf("User has already approved the linked approval task %s: linked tasks must be approved by different users", approved[0])
+}
+
+// approvedBy reports whether the user approved the approval task, as an
+// individual approver or as a member of a Group approver.
+func approvedBy(approvalTask *v1alpha1.ApprovalTask, userInfo authenticationv1.UserInfo) bool {
+	for _, approver := range approvalTask.Spec.Approvers {
+		if isIndividualApprover(approver.Type, approver.Name, userInfo) && approver.Input == "approve" {
+			return true
+		}
+		if v1alpha1.DefaultedApproverType(approver.Type) != "Group" {
+			continue
+		}
+		for _, user := range approver.Users {
+			if user.Name == userInfo.Username && user.Input == "approve" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	approvalpolicylisters "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/cache"
+)
+
+// linkedApprovalTask returns a pending approval task named name, carrying
+// the correlation ID.
+func linkedApprovalTask(name, id string) *v1alpha1.ApprovalTask {
+	at := keyedApprovalTask()
+	at.Name = name
+	if id != "" {
+		at.Annotations = map[string]string{v1alpha1.CorrelationIDAnnotationKey: id}
+	}
+	return at
+}
+
+func newLinkingReconciler(t *testing.T, tasks ...*v1alpha1.ApprovalTask) *reconciler {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, at := range tasks {
+		assert.NoError(t, indexer.Add(at))
+	}
+	return &reconciler{tasklister: approvalpolicylisters.NewApprovalTaskLister(indexer)}
+}
+
+func TestAdmitApprovalOfLinkedTasks(t *testing.T) {
+	// alice approved the database migration of the change
+	migration := linkedApprovalTask("migrate-database", "change-42")
+	migration.Spec.Approvers[0].Input = "approve"
+	rollout := linkedApprovalTask("deploy", "change-42")
+	r := newLinkingReconciler(t, migration, rollout)
+
+	approved := rollout.DeepCopy()
+	approved.Spec.Approvers[0].Input = "approve"
+	resp := admitUpdateWith(t, r, rollout, approved, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "User has already approved the linked approval task migrate-database: linked tasks must be approved by different users", resp.Result.Message)
+
+	// bob approves the rollout as member of the platform group
+	memberApproved := rollout.DeepCopy()
+	memberApproved.Spec.Approvers[1].Users[0].Input = "approve"
+	resp = admitUpdateWith(t, r, rollout, memberApproved, "bob")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	// alice can still reject it
+	rejected := rollout.DeepCopy()
+	rejected.Spec.Approvers[0].Input = "reject"
+	resp = admitUpdateWith(t, r, rollout, rejected, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+}
+
+func TestAdmitApprovalUnlinkingTask(t *testing.T) {
+	migration := linkedApprovalTask("migrate-database", "change-42")
+	migration.Spec.Approvers[0].Input = "approve"
+	rollout := linkedApprovalTask("deploy", "change-42")
+	r := newLinkingReconciler(t, migration, rollout)
+
+	// Changing the ID along with the approval does not unlink the task
+	relinked := rollout.DeepCopy()
+	relinked.Spec.Approvers[0].Input = "approve"
+	relinked.Annotations[v1alpha1.CorrelationIDAnnotationKey] = "change-43"
+	assert.Equal(t, "User has already approved the linked approval task migrate-database: linked tasks must be approved by different users",
+		r.validateLinkedApprovals(context.TODO(), rollout, relinked, admissionRequestFor("alice")))
+	resp := admitUpdateWith(t, r, rollout, relinked, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "The correlation ID of an ApprovalTask cannot be changed", resp.Result.Message)
+
+	// Nor does removing it
+	unlinked := relinked.DeepCopy()
+	delete(unlinked.Annotations, v1alpha1.CorrelationIDAnnotationKey)
+	assert.NotEmpty(t, r.validateLinkedApprovals(context.TODO(), rollout, unlinked, admissionRequestFor("alice")))
+	resp = admitUpdateWith(t, r, rollout, unlinked, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "The correlation ID of an ApprovalTask cannot be changed", resp.Result.Message)
+}
+
+func TestAdmitApprovalOfLinkedTasksAsGroupMember(t *testing.T) {
+	migration := linkedApprovalTask("migrate-database", "change-42")
+	migration.Spec.Approvers[1].Users[0].Input = "approve"
+	rollout := linkedApprovalTask("deploy", "change-42")
+	r := newLinkingReconciler(t, migration, rollout)
+
+	approved := rollout.DeepCopy()
+	approved.Spec.Approvers[1].Users[0].Input = "approve"
+	resp := admitUpdateWith(t, r, rollout, approved, "bob", "platform")
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "linked approval task migrate-database")
+}
+
+func TestAdmitApprovalOfUnlinkedTasks(t *testing.T) {
+	other := linkedApprovalTask("migrate-database", "change-41")
+	other.Spec.Approvers[0].Input = "approve"
+	uncorrelated := linkedApprovalTask("migrate-cache", "")
+	uncorrelated.Spec.Approvers[0].Input = "approve"
+	rollout := linkedApprovalTask("deploy", "change-42")
+	r := newLinkingReconciler(t, other, uncorrelated, rollout)
+
+	approved := rollout.DeepCopy()
+	approved.Spec.Approvers[0].Input = "approve"
+	resp := admitUpdateWith(t, r, rollout, approved, "alice")
+	assert.True(t, resp.Allowed, "tasks of other changes are not linked: %v", resp.Result)
+
+	// Nor are tasks without a correlation ID to each other
+	lone := linkedApprovalTask("deploy", "")
+	r = newLinkingReconciler(t, uncorrelated, lone)
+	approved = lone.DeepCopy()
+	approved.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdateWith(t, r, lone, approved, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// lintWarningPrefix marks the admission warnings listing lint findings.
+const lintWarningPrefix = "lint: "
+
+// isLintRequest reports whether the request is a dry-run create, which CI
+// sends to validate an ApprovalTask manifest without persisting it.
+func isLintRequest(request *admissionv1.AdmissionRequest) bool {
+	return request.Operation == admissionv1.Create && request.DryRun != nil && *request.DryRun
+}
+
+// lint returns every problem found in the ApprovalTask of a dry-run create as
+// admission warnings, where the response only carries the first problem
+// denying it. Beyond what creating the task is denied for, it flags quorums
+// the approvers cannot reach, required roles no approver has, groups no one
+// can decide for, and approvers that cannot approve. Other requests are not linted.
+func (r *reconciler) lint(ctx context.Context, request *admissionv1.AdmissionRequest) []string {
+	if !isLintRequest(request) {
+		return nil
+	}
+	approvalTask, err := r.decodeNewObject(request.Object.Raw)
+	if err != nil {
+		// The response already tells why the object cannot be decoded
+		return nil
+	}
+
+	var warnings []string
+	seen := make(map[string]bool)
+	add := func(err error) {
+		if err == nil || seen[err.Error()] {
+			return
+		}
+		seen[err.Error()] = true
+		warnings = append(warnings, lintWarningPrefix+err.Error())
+	}
+
+	spec := &approvalTask.Spec
+	add(validateApprovalTaskSpec(spec, ctx))
+	keys := make(map[string]int)
+	for i, approver := range spec.Approvers {
+		fieldPath := fmt.Sprintf("approvers[%d]", i)
+		add(validateApproverIn(ctx, approver, fieldPath))
+		if existingIndex, exists := keys[approverKey(approver)]; exists {
+			add(duplicateApproverError(fieldPath, approver, existingIndex))
+			continue
+		}
+		keys[approverKey(approver)] = i
+
+		if r.explicitGroupMembers && v1alpha1.DefaultedApproverType(approver.Type) == "Group" && len(approver.Users) == 0 {
+			add(fmt.Errorf("%s.users: group '%s' lists no users, and only listed users count as its members", fieldPath, approver.Name))
+		}
+		if len(approver.AllowedInputs) > 0 && !webhookContains(approver.AllowedInputs, "approve") {
+			add(fmt.Errorf("%s.allowedInputs: approver '%s' is not allowed to approve", fieldPath, approver.Name))
+		}
+	}
+	for _, err := range approverInputProblems(approvalTask) {
+		add(err)
+	}
+	for _, err := range approverIdentityProblems(spec) {
+		add(err)
+	}
+	for _, err := range unheldRoleProblems(spec) {
+		add(err)
+	}
+	add(r.validateApproverCount(nil, approvalTask))
+	add(r.validateClusterPolicies(ctx, approvalTask))
+
+	if attainable, ok := approval.MaxAttainableApprovals(*approvalTask); ok {
+		if required := approval.MinRequiredApprovals(*approvalTask); attainable < required {
+			add(fmt.Errorf("numberOfApprovalsRequired: unreachable, the approvers can give at most %d approvals but %d are required", attainable, required))
+		}
+	}
+	return warnings
+}
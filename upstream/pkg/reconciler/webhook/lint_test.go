@@ -0,0 +1,92 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"knative.dev/pkg/ptr"
+)
+
+// lintCreate admits the dry-run create of obj, the way CI lints a manifest.
+func lintCreate(t *testing.T, r *reconciler, obj *v1alpha1.ApprovalTask) *admissionv1.AdmissionResponse {
+	t.Helper()
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("failed marshaling object: %v", err)
+	}
+	return r.Admit(context.Background(), &admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		DryRun:    ptr.Bool(true),
+		Kind:      metav1.GroupVersionKind{Group: Group, Version: Version, Kind: Kind},
+		Object:    runtime.RawExtension{Raw: raw},
+	})
+}
+
+func TestLintAggregatesWarnings(t *testing.T) {
+	r := &reconciler{explicitGroupMembers: true}
+	at := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 3,
+			MaxApprovalsPerGroup:      1,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "release", Type: "Group", Input: "pending"},
+				{Name: "bob", Type: "User", Input: "pending", AllowedInputs: []string{"reject"}},
+			},
+		},
+	}
+
+	resp := lintCreate(t, r, at)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, []string{
+		"lint: approvers[1].name: duplicate approver 'alice' (also found at approvers[0])",
+		"lint: approvers[2].users: group 'release' lists no users, and only listed users count as its members",
+		"lint: approvers[3].allowedInputs: approver 'bob' is not allowed to approve",
+		"lint: approvers[0].input: must be 'pending' for new ApprovalTask, got 'approve'",
+		"lint: numberOfApprovalsRequired: unreachable, the approvers can give at most 2 approvals but 3 are required",
+	}, resp.Warnings)
+}
+
+func TestLintValidManifest(t *testing.T) {
+	resp := lintCreate(t, &reconciler{}, sizedApprovalTask(2))
+	assert.True(t, resp.Allowed)
+	assert.Empty(t, resp.Warnings)
+}
+
+func TestLintOnlyDryRunCreates(t *testing.T) {
+	at := sizedApprovalTask(0)
+	at.Spec.NumberOfApprovalsRequired = 5
+	at.Spec.MaxApprovalsPerGroup = 1
+
+	resp := lintCreate(t, &reconciler{}, at)
+	assert.True(t, resp.Allowed, "lint findings do not deny what enforcement admits")
+	assert.Equal(t, []string{"lint: numberOfApprovalsRequired: unreachable, the approvers can give at most 2 approvals but 5 are required"}, resp.Warnings)
+
+	resp = admitCreateWith(t, &reconciler{}, at)
+	assert.True(t, resp.Allowed)
+	assert.Empty(t, resp.Warnings, "creates that persist are not linted")
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/labelcap"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	admissionv1 "k8s.io/api/admission/v1"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/metrics"
+)
+
+// DefaultMetricsLabelCap is the default number of distinct values the
+// namespace and task labels of the decision metrics take before further
+// values are dropped.
+const DefaultMetricsLabelCap = labelcap.DefaultLimit
+
+const (
+	decisionCountName           = "approvaltask_admission_decisions"
+	groupResolutionFailuresName = "approvaltask_group_resolution_failures"
+	webhookDriftName            = "manual_approval_webhook_drift_total"
+)
+
+var (
+	decisionCountM = stats.Int64(
+		decisionCountName,
+		"The number of ApprovalTask admission requests by outcome",
+		stats.UnitDimensionless)
+	groupResolutionFailuresM = stats.Int64(
+		groupResolutionFailuresName,
+		"The number of ApprovalTask admission requests denied because group membership could not be verified",
+		stats.UnitDimensionless)
+	webhookDriftM = stats.Int64(
+		webhookDriftName,
+		"The number of times the ValidatingWebhookConfiguration was found changed outside of the webhook",
+		stats.UnitDimensionless)
+
+	operationKey = tag.MustNewKey("operation")
+	allowedKey   = tag.MustNewKey("allowed")
+	namespaceKey = tag.MustNewKey("namespace")
+	taskKey      = tag.MustNewKey("task")
+
+	registerViewsOnce sync.Once
+)
+
+// registerDecisionViews registers the views of the decision and webhook
+// configuration metrics with the metrics exporter. It is safe to call more than once.
+func registerDecisionViews() {
+	registerViewsOnce.Do(func() {
+		if err := view.Register(&view.View{
+			Description: decisionCountM.Description(),
+			Measure:     decisionCountM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{operationKey, allowedKey, namespaceKey, taskKey},
+		}, &view.View{
+			Description: groupResolutionFailuresM.Description(),
+			Measure:     groupResolutionFailuresM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{namespaceKey},
+		}, &view.View{
+			Description: webhookDriftM.Description(),
+			Measure:     webhookDriftM,
+			Aggregation: view.Count(),
+		}); err != nil {
+			panic(err)
+		}
+	})
+}
+
+// decisionReporter counts admission decisions by operation and outcome, and
+// by namespace and, optionally, task name. The namespace and task labels are
+// dropped for values beyond their cap.
+type decisionReporter struct {
+	namespaces *labelcap.Cap
+	// tasks is nil when task names are not reported.
+	tasks *labelcap.Cap
+}
+
+func newDecisionReporter(labelLimit int, taskNames bool) *decisionReporter {
+	registerDecisionViews()
+	reporter := &decisionReporter{namespaces: labelcap.New(labelLimit)}
+	if taskNames {
+		reporter.tasks = labelcap.New(labelLimit)
+	}
+	return reporter
+}
+
+func (d *decisionReporter) report(ctx context.Context, request *admissionv1.AdmissionRequest, response *admissionv1.AdmissionResponse) {
+	if d == nil {
+		return
+	}
+	mutators := []tag.Mutator{
+		tag.Insert(operationKey, string(request.Operation)),
+		tag.Insert(allowedKey, strconv.FormatBool(response.Allowed)),
+	}
+	if d.namespaces.Admit(request.Namespace) {
+		mutators = append(mutators, tag.Insert(namespaceKey, request.Namespace))
+	}
+	if d.tasks != nil && d.tasks.Admit(request.Namespace+"/"+request.Name) {
+		mutators = append(mutators, tag.Insert(taskKey, request.Name))
+	}
+
+	tagged, err := tag.New(context.Background(), mutators...)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("Unable to tag the admission decision metric: %v", err)
+		return
+	}
+	metrics.Record(tagged, decisionCountM.M(1))
+}
+
+// groupResolutionFailed counts a request denied because the GroupResolver
+// failed, by namespace within the label cap.
+func (d *decisionReporter) groupResolutionFailed(ctx context.Context, request *admissionv1.AdmissionRequest) {
+	if d == nil {
+		return
+	}
+	var mutators []tag.Mutator
+	if d.namespaces.Admit(request.Namespace) {
+		mutators = append(mutators, tag.Insert(namespaceKey, request.Namespace))
+	}
+
+	tagged, err := tag.New(context.Background(), mutators...)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("Unable to tag the group resolution failure metric: %v", err)
+		return
+	}
+	metrics.Record(tagged, groupResolutionFailuresM.M(1))
+}
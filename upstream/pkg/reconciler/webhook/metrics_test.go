@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats/view"
+	admissionv1 "k8s.io/api/admission/v1"
+	"knative.dev/pkg/metrics"
+)
+
+func init() {
+	metrics.InitForTesting()
+}
+
+// decisionCounts returns the recorded decision counts keyed by the tags of
+// each row, formatted as "namespace/task/allowed".
+func decisionCounts(t *testing.T) map[string]int64 {
+	t.Helper()
+	rows, err := view.RetrieveData(decisionCountName)
+	assert.NoError(t, err)
+	counts := map[string]int64{}
+	for _, row := range rows {
+		tags := map[string]string{}
+		for _, tag := range row.Tags {
+			tags[tag.Key.Name()] = tag.Value
+		}
+		counts[tags["namespace"]+"/"+tags["task"]+"/"+tags["allowed"]] += row.Data.(*view.CountData).Value
+	}
+	return counts
+}
+
+func reportDecision(d *decisionReporter, namespace, name string, allowed bool) {
+	d.report(context.Background(),
+		&admissionv1.AdmissionRequest{Operation: admissionv1.Update, Namespace: namespace, Name: name},
+		&admissionv1.AdmissionResponse{Allowed: allowed})
+}
+
+func TestDecisionMetricsLabels(t *testing.T) {
+	d := newDecisionReporter(10, true)
+	reportDecision(d, "metrics-team-a", "deploy", true)
+	reportDecision(d, "metrics-team-a", "deploy", false)
+	reportDecision(d, "metrics-team-a", "deploy", false)
+
+	counts := decisionCounts(t)
+	assert.Equal(t, int64(1), counts["metrics-team-a/deploy/true"])
+	assert.Equal(t, int64(2), counts["metrics-team-a/deploy/false"])
+
+	// Without task names only the namespace is reported
+	d = newDecisionReporter(10, false)
+	reportDecision(d, "metrics-team-b", "deploy", true)
+	assert.Equal(t, int64(1), decisionCounts(t)["metrics-team-b//true"])
+}
+
+func TestDecisionMetricsLabelCap(t *testing.T) {
+	d := newDecisionReporter(2, true)
+	before := decisionCounts(t)["//true"]
+	reportDecision(d, "metrics-cap-a", "deploy", true)
+	reportDecision(d, "metrics-cap-b", "deploy", true)
+	reportDecision(d, "metrics-cap-c", "deploy", true)
+	reportDecision(d, "metrics-cap-a", "release", true)
+	reportDecision(d, "metrics-cap-a", "deploy", true)
+
+	counts := decisionCounts(t)
+	assert.Equal(t, int64(2), counts["metrics-cap-a/deploy/true"], "values seen before the cap was reached keep their labels")
+	assert.Equal(t, int64(1), counts["metrics-cap-b/deploy/true"])
+	assert.NotContains(t, counts, "metrics-cap-c/deploy/true")
+	assert.Equal(t, before+1, counts["//true"], "the third namespace and its task are recorded without labels")
+	assert.Equal(t, int64(1), counts["metrics-cap-a//true"], "a third task is recorded without its name")
+}
+
+func TestAdmitReportsDecisions(t *testing.T) {
+	r := &reconciler{privilegedGroup: DefaultPrivilegedGroup, decisions: newDecisionReporter(10, false)}
+	oldObj := withdrawableApprovalTask()
+	oldObj.Namespace = "metrics-admit"
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	resp = admitUpdateWith(t, r, oldObj, newObj, "mallory")
+	assert.False(t, resp.Allowed)
+
+	counts := decisionCounts(t)
+	assert.Equal(t, int64(1), counts["metrics-admit//true"])
+	assert.Equal(t, int64(1), counts["metrics-admit//false"])
+}
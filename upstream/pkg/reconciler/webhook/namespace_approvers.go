@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	"knative.dev/pkg/logging"
+)
+
+// namespaceApproversCache remembers the parsed approvers annotation of each
+// namespace, keyed by namespace name, until the namespace changes.
+type namespaceApproversCache struct {
+	mu      sync.Mutex
+	entries map[string]namespaceApproversEntry
+}
+
+type namespaceApproversEntry struct {
+	resourceVersion string
+	approvers       []v1alpha1.ApproverDetails
+}
+
+// namespaceApprovers returns the approvers that the namespace declares for all
+// of its ApprovalTasks through the v1alpha1.ApproversAnnotationKey annotation.
+// Malformed entries are logged and skipped.
+func (r *reconciler) namespaceApprovers(ctx context.Context, namespace string) []v1alpha1.ApproverDetails {
+	if r.nslister == nil || namespace == "" {
+		return nil
+	}
+	logger := logging.FromContext(ctx)
+
+	ns, err := r.nslister.Get(namespace)
+	if err != nil {
+		logger.Debugf("Unable to get namespace %s for approver defaults: %v", namespace, err)
+		return nil
+	}
+	value, ok := ns.Annotations[v1alpha1.ApproversAnnotationKey]
+	if !ok {
+		return nil
+	}
+
+	r.nsApprovers.mu.Lock()
+	defer r.nsApprovers.mu.Unlock()
+	if entry, ok := r.nsApprovers.entries[namespace]; ok && entry.resourceVersion == ns.ResourceVersion {
+		return entry.approvers
+	}
+
+	approvers, err := parseNamespaceApprovers(value, r.groupSeparator)
+	if err != nil {
+		logger.Warnf("Ignoring malformed entries in the %s annotation of namespace %s: %v", v1alpha1.ApproversAnnotationKey, namespace, err)
+	}
+	if r.nsApprovers.entries == nil {
+		r.nsApprovers.entries = make(map[string]namespaceApproversEntry)
+	}
+	r.nsApprovers.entries[namespace] = namespaceApproversEntry{resourceVersion: ns.ResourceVersion, approvers: approvers}
+	return approvers
+}
+
+// parseNamespaceApprovers parses a comma separated list of approvers, using the
+// same "group:" prefix as the approvers param. Valid entries are returned even
+// when others are malformed.
+func parseNamespaceApprovers(value, separator string) ([]v1alpha1.ApproverDetails, error) {
+	var (
+		approvers []v1alpha1.ApproverDetails
+		invalid   []string
+	)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		approver := v1alpha1.ApproverDetails{Name: entry, Type: "User", Input: "pending"}
+		if strings.HasPrefix(entry, "group:") {
+			approver.Name = strings.TrimPrefix(entry, "group:")
+			approver.Type = "Group"
+			if err := validateGroupName(approver.Name, separator); err != nil {
+				invalid = append(invalid, fmt.Sprintf("'%s': %v", entry, err))
+				continue
+			}
+		} else if err := validateUserName(approver.Name); err != nil {
+			invalid = append(invalid, fmt.Sprintf("'%s': %v", entry, err))
+			continue
+		}
+		approvers = append(approvers, approver)
+	}
+	if len(invalid) > 0 {
+		return approvers, fmt.Errorf("%s", strings.Join(invalid, "; "))
+	}
+	return approvers, nil
+}
+
+// effectiveApprovers returns the active approvers of the task (see
+// approval.ApproverActive) merged with the approvers declared by its
+// namespace. The task spec is left untouched.
+func (r *reconciler) effectiveApprovers(ctx context.Context, approvalTask *v1alpha1.ApprovalTask) []v1alpha1.ApproverDetails {
+	fromNamespace := r.namespaceApprovers(ctx, approvalTask.Namespace)
+	if len(fromNamespace) == 0 && !approval.HasConditionalApprovers(*approvalTask) {
+		return approvalTask.Spec.Approvers
+	}
+	approvers := make([]v1alpha1.ApproverDetails, 0, len(approvalTask.Spec.Approvers)+len(fromNamespace))
+	for _, approver := range approvalTask.Spec.Approvers {
+		if approval.ApproverActive(*approvalTask, approver) {
+			approvers = append(approvers, approver)
+		}
+	}
+	return append(approvers, fromNamespace...)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func namespaceWithApprovers(resourceVersion, approvers string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "production",
+			ResourceVersion: resourceVersion,
+			Annotations:     map[string]string{v1alpha1.ApproversAnnotationKey: approvers},
+		},
+	}
+}
+
+func newNamespaceReconciler(t *testing.T, namespaces ...*corev1.Namespace) (*reconciler, cache.Indexer) {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, ns := range namespaces {
+		assert.NoError(t, indexer.Add(ns))
+	}
+	return &reconciler{nslister: corelisters.NewNamespaceLister(indexer)}, indexer
+}
+
+func TestParseNamespaceApprovers(t *testing.T) {
+	approvers, err := parseNamespaceApprovers(" alice, group:platform ,,", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []v1alpha1.ApproverDetails{
+		{Name: "alice", Type: "User", Input: "pending"},
+		{Name: "platform", Type: "Group", Input: "pending"},
+	}, approvers)
+
+	approvers, err = parseNamespaceApprovers("alice,group:,group:sec ops", "")
+	assert.Error(t, err)
+	assert.Equal(t, []v1alpha1.ApproverDetails{{Name: "alice", Type: "User", Input: "pending"}}, approvers, "valid entries should survive malformed ones")
+}
+
+func TestEffectiveApproversMergesNamespaceApprovers(t *testing.T) {
+	r, _ := newNamespaceReconciler(t, namespaceWithApprovers("1", "group:platform"))
+	at := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			Approvers: []v1alpha1.ApproverDetails{{Name: "alice", Type: "User", Input: "pending"}},
+		},
+	}
+
+	effective := r.effectiveApprovers(context.Background(), at)
+	assert.True(t, ifUserExists(effective, admissionRequestFor("bob", "platform")))
+	assert.True(t, ifUserExists(effective, admissionRequestFor("alice")))
+	assert.False(t, ifUserExists(effective, admissionRequestFor("mallory", "dev")))
+	assert.Len(t, at.Spec.Approvers, 1, "the task spec should not be mutated")
+
+	other := at.DeepCopy()
+	other.Namespace = "staging"
+	assert.False(t, ifUserExists(r.effectiveApprovers(context.Background(), other), admissionRequestFor("bob", "platform")))
+}
+
+func TestNamespaceApproversCache(t *testing.T) {
+	r, indexer := newNamespaceReconciler(t, namespaceWithApprovers("1", "alice"))
+	assert.Equal(t, "alice", r.namespaceApprovers(context.Background(), "production")[0].Name)
+
+	// The parsed annotation is reused until the namespace's resourceVersion changes.
+	r.nsApprovers.entries["production"] = namespaceApproversEntry{
+		resourceVersion: "1",
+		approvers:       []v1alpha1.ApproverDetails{{Name: "cached", Type: "User"}},
+	}
+	assert.Equal(t, "cached", r.namespaceApprovers(context.Background(), "production")[0].Name)
+
+	assert.NoError(t, indexer.Update(namespaceWithApprovers("2", "bob")))
+	assert.Equal(t, "bob", r.namespaceApprovers(context.Background(), "production")[0].Name)
+}
+
+func TestNamespaceApproversMalformedAnnotation(t *testing.T) {
+	r, _ := newNamespaceReconciler(t, namespaceWithApprovers("1", "group:a:b, ,"))
+	assert.Empty(t, r.namespaceApprovers(context.Background(), "production"))
+	assert.Empty(t, r.namespaceApprovers(context.Background(), "missing"))
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// isOwnerReferenceRepair reports whether the update is the controller making
+// a CustomRun the controller of a task that has none, and nothing else. It is
+// admitted whoever the approvers of the task are.
+func (r *reconciler) isOwnerReferenceRepair(oldObj, newObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest) bool {
+	if r.controllerUsername == "" || request.UserInfo.Username != r.controllerUsername {
+		return false
+	}
+	if !isMetadataOnlyUpdate(oldObj, newObj) || !equality.Semantic.DeepEqual(oldObj.Labels, newObj.Labels) ||
+		!equality.Semantic.DeepEqual(oldObj.Annotations, newObj.Annotations) || !equality.Semantic.DeepEqual(oldObj.Finalizers, newObj.Finalizers) {
+		return false
+	}
+	if metav1.GetControllerOf(oldObj) != nil || len(newObj.OwnerReferences) != len(oldObj.OwnerReferences)+1 ||
+		!equality.Semantic.DeepEqual(oldObj.OwnerReferences, newObj.OwnerReferences[:len(oldObj.OwnerReferences)]) {
+		return false
+	}
+	owner := metav1.GetControllerOf(newObj)
+	return owner != nil && owner.Kind == "CustomRun" && owner.APIVersion == "tekton.dev/v1beta1"
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/ptr"
+)
+
+func repairedOwnerReference() metav1.OwnerReference {
+	return metav1.OwnerReference{APIVersion: "tekton.dev/v1beta1", Kind: "CustomRun", Name: "deploy", UID: "run-uid", Controller: ptr.Bool(true), BlockOwnerDeletion: ptr.Bool(true)}
+}
+
+func TestAdmitOwnerReferenceRepair(t *testing.T) {
+	r := &reconciler{controllerUsername: DefaultControllerServiceAccount}
+	oldObj := keyedApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.OwnerReferences = []metav1.OwnerReference{repairedOwnerReference()}
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, DefaultControllerServiceAccount)
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed, "only the controller repairs owner references")
+
+	owned := newObj.DeepCopy()
+	replaced := owned.DeepCopy()
+	replaced.OwnerReferences[0].UID = "other-uid"
+	resp = admitUpdateWith(t, r, owned, replaced, DefaultControllerServiceAccount)
+	assert.False(t, resp.Allowed, "owned tasks keep their owner")
+
+	withDecision := newObj.DeepCopy()
+	withDecision.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdateWith(t, r, oldObj, withDecision, DefaultControllerServiceAccount)
+	assert.False(t, resp.Allowed, "the repair changes nothing but the owner")
+}
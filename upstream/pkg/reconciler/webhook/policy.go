@@ -0,0 +1,48 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	"k8s.io/apimachinery/pkg/labels"
+	"knative.dev/pkg/logging"
+)
+
+// validateClusterPolicies checks that a new approval task is at least as
+// strict as the cluster approval policies selecting it require. Pending
+// tasks are held to policies created later by the controller instead.
+func (r *reconciler) validateClusterPolicies(ctx context.Context, approvalTask *v1alpha1.ApprovalTask) error {
+	if r.policylister == nil {
+		return nil
+	}
+	policies, err := r.policylister.List(labels.Everything())
+	if err != nil {
+		logging.FromContext(ctx).Errorf("Unable to list cluster approval policies: %v", err)
+		return nil
+	}
+	requirements := approval.PolicyRequirementsFor(*approvalTask, policies)
+	problems := approval.PolicyShortfalls(approvalTask.Spec, requirements)
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("required by cluster approval policies %s: %s", strings.Join(requirements.Policies, ", "), strings.Join(problems, "; "))
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	approvalpolicylisters "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newPolicyReconciler(t *testing.T, policies ...*v1alpha1.ClusterApprovalPolicy) *reconciler {
+	t.Helper()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, policy := range policies {
+		assert.NoError(t, indexer.Add(policy))
+	}
+	return &reconciler{policylister: approvalpolicylisters.NewClusterApprovalPolicyLister(indexer)}
+}
+
+func criticalPolicy() *v1alpha1.ClusterApprovalPolicy {
+	return &v1alpha1.ClusterApprovalPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "critical"},
+		Spec: v1alpha1.ClusterApprovalPolicySpec{
+			Selector:             &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "critical"}},
+			MinApprovalsRequired: 2,
+			RequiredGroups:       []string{"security"},
+		},
+	}
+}
+
+func criticalApprovalTask() *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production", Labels: map[string]string{"tier": "critical"}},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "bob", Type: "User", Input: "pending"},
+			},
+		},
+	}
+}
+
+func TestAdmitCreateEnforcesClusterPolicies(t *testing.T) {
+	r := newPolicyReconciler(t, criticalPolicy())
+
+	resp := admitCreateWith(t, r, criticalApprovalTask())
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "validation failed: required by cluster approval policies critical: "+
+		"numberOfApprovalsRequired: must be at least 2, got 1; approvers: group 'security' must be an approver", resp.Result.Message)
+
+	strict := criticalApprovalTask()
+	strict.Spec.NumberOfApprovalsRequired = 2
+	strict.Spec.Approvers = append(strict.Spec.Approvers, v1alpha1.ApproverDetails{Name: "security", Type: "Group", Input: "pending"})
+	resp = admitCreateWith(t, r, strict)
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	unselected := criticalApprovalTask()
+	unselected.Labels = nil
+	resp = admitCreateWith(t, r, unselected)
+	assert.True(t, resp.Allowed, "the policy only selects critical tasks: %v", resp.Result)
+
+	resp = admitCreateWith(t, &reconciler{}, criticalApprovalTask())
+	assert.True(t, resp.Allowed, "without policies the task is held to its own spec: %v", resp.Result)
+}
+
+func TestApprovalRequiredUntilPolicyGroupsApprove(t *testing.T) {
+	at := criticalApprovalTask()
+	at.Spec.Approvers[0].Input = "approve"
+	at.Spec.Approvers = append(at.Spec.Approvers, v1alpha1.ApproverDetails{Name: "security", Type: "Group", Input: "pending"})
+	at.Status.State = "pending"
+	assert.False(t, isApprovalRequired(*at), "alice alone reaches the quorum of the task")
+
+	at.Status.Policy = &v1alpha1.PolicyRequirements{Policies: []string{"critical"}, RequiredGroups: []string{"security"}}
+	assert.True(t, isApprovalRequired(*at), "the security group must still approve")
+}
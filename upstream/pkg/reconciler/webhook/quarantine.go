@@ -0,0 +1,272 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/clock"
+	"knative.dev/pkg/logging"
+)
+
+const (
+	// DefaultQuarantineDuration is how long a quarantined user's updates to
+	// an ApprovalTask are denied by default.
+	DefaultQuarantineDuration = 10 * time.Minute
+	// DefaultQuarantineHalfLife is how long it takes by default for the
+	// invalid changes counted against a user to weigh half as much.
+	DefaultQuarantineHalfLife = 5 * time.Minute
+
+	// auditQuarantineKey is the audit annotation recording until when the
+	// user of a request denied by the quarantine is quarantined.
+	auditQuarantineKey = "quarantined-until"
+
+	// userQuarantinedReason is the reason of the event emitted on an
+	// ApprovalTask when one of its users is quarantined.
+	userQuarantinedReason = "UserQuarantined"
+
+	// minQuarantineScore is the score below which a user's record is
+	// forgotten.
+	minQuarantineScore = 0.1
+)
+
+// quarantine denies, for a while, every update a user makes to an approval
+// task once they submitted too many invalid changes to it. Each denied
+// update adds one to the score of the user on the task, and the score halves
+// every halfLife, so that occasional mistakes never add up to a quarantine.
+// The controller and the members of the privileged group are never
+// quarantined: denying their updates would hold tasks up, not stop abuse.
+type quarantine struct {
+	clock       clock.PassiveClock
+	recorder    record.EventRecorder
+	threshold   float64
+	duration    time.Duration
+	halfLife    time.Duration
+	exemptUser  string
+	exemptGroup string
+	usernames   usernameRedactor
+	// flag, when set, is called with each user quarantined on a task.
+	flag func(namespace, name, username string, until time.Time)
+
+	mu      sync.Mutex
+	entries map[quarantineKey]*quarantineEntry
+}
+
+type quarantineKey struct {
+	namespace, name, username string
+}
+
+type quarantineEntry struct {
+	score   float64
+	updated time.Time
+	until   time.Time
+}
+
+// newQuarantine returns the quarantine configured by opts, or nil when
+// quarantining is turned off.
+func newQuarantine(opts Options, clock clock.PassiveClock, recorder record.EventRecorder) *quarantine {
+	if opts.QuarantineThreshold <= 0 {
+		return nil
+	}
+	q := &quarantine{
+		clock:     clock,
+		recorder:  recorder,
+		threshold: float64(opts.QuarantineThreshold),
+		duration:  opts.QuarantineDuration,
+		halfLife:  opts.QuarantineHalfLife,
+		entries:   map[quarantineKey]*quarantineEntry{},
+
+		exemptUser:  opts.ControllerUsername,
+		exemptGroup: opts.privilegedGroup(),
+		usernames:   usernameRedactor{mode: opts.UsernameRedaction, key: opts.UsernameRedactionKey},
+	}
+	if q.duration <= 0 {
+		q.duration = DefaultQuarantineDuration
+	}
+	if q.halfLife <= 0 {
+		q.halfLife = DefaultQuarantineHalfLife
+	}
+	return q
+}
+
+// deny returns the denial of an update by a user quarantined on the task, or
+// nil when the request may be admitted.
+func (q *quarantine) deny(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if q == nil || request.Operation != admissionv1.Update || q.exempt(request) {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entry, ok := q.entries[keyOf(request)]
+	if !ok || !q.clock.Now().Before(entry.until) {
+		return nil
+	}
+	until := entry.until.UTC().Format(time.RFC3339)
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Code:    http.StatusTooManyRequests,
+			Message: fmt.Sprintf("Too many invalid changes to this approval task, try again after %s", until),
+		},
+		AuditAnnotations: map[string]string{auditQuarantineKey: until},
+	}
+}
+
+// observe counts the response against the user when it denies one of their
+// updates, and quarantines them once their score reaches the threshold.
+// Conflicts and transient failures are not the user's fault and do not count.
+func (q *quarantine) observe(request *admissionv1.AdmissionRequest, response *admissionv1.AdmissionResponse) {
+	if q == nil || request.Operation != admissionv1.Update || response.Allowed || q.exempt(request) {
+		return
+	}
+	if response.Result != nil && (response.Result.Code == http.StatusConflict || response.Result.Code == http.StatusTooManyRequests ||
+		response.Result.Message == groupMembershipUnverifiedMsg) {
+		return
+	}
+
+	now := q.clock.Now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.prune(now)
+	key := keyOf(request)
+	entry, ok := q.entries[key]
+	if !ok {
+		entry = &quarantineEntry{}
+		q.entries[key] = entry
+	}
+	entry.score = q.decayed(entry, now) + 1
+	entry.updated = now
+	if entry.score < q.threshold {
+		return
+	}
+
+	entry.score = 0
+	entry.until = now.Add(q.duration)
+	if q.recorder != nil {
+		q.recorder.Eventf(taskReference(request), corev1.EventTypeWarning, userQuarantinedReason,
+			"Denying all updates of %s to approval task %s until %s after repeated invalid changes",
+			q.usernames.redact(request.UserInfo.Username), request.Name, entry.until.UTC().Format(time.RFC3339))
+	}
+	if q.flag != nil {
+		q.flag(request.Namespace, request.Name, request.UserInfo.Username, entry.until)
+	}
+}
+
+// exempt reports whether the user of the request is never quarantined.
+func (q *quarantine) exempt(request *admissionv1.AdmissionRequest) bool {
+	if q.exemptUser != "" && request.UserInfo.Username == q.exemptUser {
+		return true
+	}
+	return q.exemptGroup != "" && webhookContains(request.UserInfo.Groups, q.exemptGroup)
+}
+
+// decayed returns the score of the entry at now.
+func (q *quarantine) decayed(entry *quarantineEntry, now time.Time) float64 {
+	if entry.score == 0 {
+		return 0
+	}
+	return entry.score * math.Pow(0.5, float64(now.Sub(entry.updated))/float64(q.halfLife))
+}
+
+// prune forgets the users that are not quarantined and whose score decayed
+// to almost nothing.
+func (q *quarantine) prune(now time.Time) {
+	for key, entry := range q.entries {
+		if !now.Before(entry.until) && q.decayed(entry, now) < minQuarantineScore {
+			delete(q.entries, key)
+		}
+	}
+}
+
+func keyOf(request *admissionv1.AdmissionRequest) quarantineKey {
+	return quarantineKey{namespace: request.Namespace, name: request.Name, username: request.UserInfo.Username}
+}
+
+// taskReference returns the approval task of the request as an object events
+// can be emitted on.
+func taskReference(request *admissionv1.AdmissionRequest) *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: Kind},
+		ObjectMeta: metav1.ObjectMeta{Namespace: request.Namespace, Name: request.Name},
+	}
+}
+
+// statusFlagger returns the function recording, in the background, the users
+// quarantined on a task in the QuarantinedUsersAnnotationKey annotation of its
+// status. Failures are only logged: the quarantine holds regardless.
+func statusFlagger(ctx context.Context, client versioned.Interface, clock clock.PassiveClock) func(namespace, name, username string, until time.Time) {
+	return func(namespace, name, username string, until time.Time) {
+		go func() {
+			if err := flagQuarantined(ctx, client, namespace, name, username, until, clock.Now()); err != nil {
+				logging.FromContext(ctx).Warnw("Failed to flag the quarantined user on the approval task",
+					"namespace", namespace, "name", name, "error", err)
+			}
+		}()
+	}
+}
+
+// flagQuarantined adds username, quarantined until the given time, to the
+// QuarantinedUsersAnnotationKey status annotation of the task, dropping the
+// users whose quarantine ended by now.
+func flagQuarantined(ctx context.Context, client versioned.Interface, namespace, name, username string, until, now time.Time) error {
+	tasks := client.OpenshiftpipelinesV1alpha1().ApprovalTasks(namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		task, err := tasks.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if task.Status.Annotations == nil {
+			task.Status.Annotations = map[string]string{}
+		}
+		task.Status.Annotations[v1alpha1.QuarantinedUsersAnnotationKey] =
+			quarantinedUsers(task.Status.Annotations[v1alpha1.QuarantinedUsersAnnotationKey], username, until, now)
+		_, err = tasks.UpdateStatus(ctx, task, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// quarantinedUsers returns the annotation value with username quarantined
+// until the given time, without the users whose quarantine ended by now. A
+// value that does not parse is replaced.
+func quarantinedUsers(value, username string, until, now time.Time) string {
+	users := map[string]string{}
+	if value != "" && json.Unmarshal([]byte(value), &users) != nil {
+		users = map[string]string{}
+	}
+	for user, ends := range users {
+		if t, err := time.Parse(time.RFC3339, ends); err != nil || !now.Before(t) {
+			delete(users, user)
+		}
+	}
+	users[username] = until.UTC().Format(time.RFC3339)
+	// Marshalling a map of strings cannot fail
+	b, _ := json.Marshal(users)
+	return string(b)
+}
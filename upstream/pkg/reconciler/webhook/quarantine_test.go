@@ -0,0 +1,174 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	approvaltaskfake "github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func quarantineTask() *v1alpha1.ApprovalTask {
+	task := withdrawableApprovalTask()
+	task.Spec.NumberOfApprovalsRequired = 2
+	task.Spec.Approvers = append(task.Spec.Approvers, v1alpha1.ApproverDetails{Name: "bob", Type: "User", Input: "pending"})
+	return task
+}
+
+// foreignEdit returns task with the input of bob set by someone else.
+func foreignEdit(task *v1alpha1.ApprovalTask) *v1alpha1.ApprovalTask {
+	edited := task.DeepCopy()
+	edited.Spec.Approvers[1].Input = "approve"
+	return edited
+}
+
+func ownApproval(task *v1alpha1.ApprovalTask) *v1alpha1.ApprovalTask {
+	approved := task.DeepCopy()
+	approved.Spec.Approvers[0].Input = "approve"
+	return approved
+}
+
+func TestQuarantineTripsAndReleases(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := clocktesting.NewFakePassiveClock(now)
+	recorder := record.NewFakeRecorder(10)
+	r := &reconciler{quarantine: newQuarantine(Options{QuarantineThreshold: 3}, clock, recorder)}
+	task := quarantineTask()
+
+	for i := 0; i < 3; i++ {
+		resp := admitUpdateWith(t, r, task, foreignEdit(task), "alice")
+		assert.False(t, resp.Allowed, "alice may not decide for bob")
+		assert.NotEqual(t, int32(http.StatusTooManyRequests), resp.Result.Code, "alice is not quarantined before the threshold")
+	}
+	if assert.Len(t, recorder.Events, 1) {
+		assert.Contains(t, <-recorder.Events, "Warning UserQuarantined Denying all updates of alice")
+	}
+
+	resp := admitUpdateWith(t, r, task, ownApproval(task), "alice")
+	assert.False(t, resp.Allowed, "a quarantined user cannot even approve")
+	assert.Equal(t, int32(http.StatusTooManyRequests), resp.Result.Code)
+	assert.Equal(t, "2024-05-01T12:10:00Z", resp.AuditAnnotations[auditQuarantineKey])
+
+	approved := task.DeepCopy()
+	approved.Spec.Approvers[1].Input = "approve"
+	resp = admitUpdateWith(t, r, task, approved, "bob")
+	assert.True(t, resp.Allowed, "other users are not quarantined")
+
+	clock.SetTime(now.Add(DefaultQuarantineDuration))
+	resp = admitUpdateWith(t, r, task, ownApproval(task), "alice")
+	assert.True(t, resp.Allowed, "the quarantine is released after its duration")
+	assert.Empty(t, recorder.Events)
+}
+
+func TestQuarantineScoreDecays(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := clocktesting.NewFakePassiveClock(now)
+	recorder := record.NewFakeRecorder(10)
+	r := &reconciler{quarantine: newQuarantine(Options{QuarantineThreshold: 3}, clock, recorder)}
+	task := quarantineTask()
+
+	admitUpdateWith(t, r, task, foreignEdit(task), "alice")
+	admitUpdateWith(t, r, task, foreignEdit(task), "alice")
+	clock.SetTime(now.Add(2 * DefaultQuarantineHalfLife))
+	admitUpdateWith(t, r, task, foreignEdit(task), "alice")
+	assert.Empty(t, recorder.Events, "earlier invalid changes weigh less as time passes")
+
+	resp := admitUpdateWith(t, r, task, ownApproval(task), "alice")
+	assert.True(t, resp.Allowed)
+
+	r.quarantine.prune(now.Add(time.Hour))
+	assert.Empty(t, r.quarantine.entries, "users whose score decayed are forgotten")
+}
+
+func TestQuarantineIgnoresConflicts(t *testing.T) {
+	q := newQuarantine(Options{QuarantineThreshold: 1}, clocktesting.NewFakePassiveClock(time.Now()), nil)
+	request := admissionRequestFor("alice")
+	request.Operation = admissionv1.Update
+	q.observe(request, &admissionv1.AdmissionResponse{Result: &metav1.Status{Code: http.StatusConflict}})
+	q.observe(request, &admissionv1.AdmissionResponse{Result: &metav1.Status{Message: groupMembershipUnverifiedMsg}})
+	assert.Nil(t, q.deny(request), "conflicts and unverified memberships are not the user's fault")
+
+	q.observe(request, &admissionv1.AdmissionResponse{Result: &metav1.Status{Message: "User does not exist in the approval list"}})
+	assert.NotNil(t, q.deny(request))
+
+	assert.Nil(t, newQuarantine(Options{}, nil, nil), "the quarantine is off without a threshold")
+}
+
+func TestQuarantineExemptions(t *testing.T) {
+	clock := clocktesting.NewFakePassiveClock(time.Now())
+	q := newQuarantine(Options{QuarantineThreshold: 1, ControllerUsername: "system:serviceaccount:openshift-pipelines:manual-approval-gate-controller"}, clock, nil)
+	denied := &admissionv1.AdmissionResponse{Result: &metav1.Status{Message: "User does not exist in the approval list"}}
+
+	controller := admissionRequestFor("system:serviceaccount:openshift-pipelines:manual-approval-gate-controller")
+	controller.Operation = admissionv1.Update
+	q.observe(controller, denied)
+	assert.Nil(t, q.deny(controller), "the controller is never quarantined")
+
+	admin := admissionRequestFor("root", DefaultPrivilegedGroup)
+	admin.Operation = admissionv1.Update
+	q.observe(admin, denied)
+	assert.Nil(t, q.deny(admin), "members of the privileged group are never quarantined")
+	assert.Empty(t, q.entries)
+
+	alice := admissionRequestFor("alice")
+	alice.Operation = admissionv1.Update
+	q.observe(alice, denied)
+	assert.NotNil(t, q.deny(alice))
+}
+
+func TestQuarantineFlagsStatus(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	task := quarantineTask()
+	task.Status.Annotations = map[string]string{
+		v1alpha1.QuarantinedUsersAnnotationKey: `{"bob":"2024-05-01T11:55:00Z","carol":"2024-05-01T12:05:00Z"}`,
+	}
+	client := approvaltaskfake.NewSimpleClientset(task)
+
+	err := flagQuarantined(context.Background(), client, task.Namespace, task.Name, "alice", now.Add(DefaultQuarantineDuration), now)
+	assert.NoError(t, err)
+	flagged, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks(task.Namespace).Get(context.Background(), task.Name, metav1.GetOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"alice":"2024-05-01T12:10:00Z","carol":"2024-05-01T12:05:00Z"}`,
+			flagged.Status.Annotations[v1alpha1.QuarantinedUsersAnnotationKey], "users whose quarantine ended are dropped")
+	}
+
+	assert.Equal(t, `{"alice":"2024-05-01T12:10:00Z"}`, quarantinedUsers("not json", "alice", now.Add(DefaultQuarantineDuration), now))
+}
+
+func TestQuarantineCallsFlag(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := &reconciler{quarantine: newQuarantine(Options{QuarantineThreshold: 2}, clocktesting.NewFakePassiveClock(now), nil)}
+	var flagged []string
+	r.quarantine.flag = func(namespace, name, username string, until time.Time) {
+		flagged = append(flagged, namespace+"/"+name+"/"+username+"@"+until.Format(time.RFC3339))
+	}
+	task := quarantineTask()
+
+	admitUpdateWith(t, r, task, foreignEdit(task), "alice")
+	assert.Empty(t, flagged)
+	admitUpdateWith(t, r, task, foreignEdit(task), "alice")
+	assert.Equal(t, []string{task.Namespace + "/" + task.Name + "/alice@2024-05-01T12:10:00Z"}, flagged)
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/receipt"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/logging"
+)
+
+// ReceiptPath is the path the receipt endpoint is served on.
+const ReceiptPath = "/receipt"
+
+// receiptHandler returns the signed receipt of the finalized ApprovalTask named
+// by the namespace and name query parameters, to callers allowed to get it.
+type receiptHandler struct {
+	admission     *reconciler
+	approvalTasks versioned.Interface
+	key           ed25519.PrivateKey
+}
+
+func (h *receiptHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := bearerToken(req)
+	if token == "" {
+		http.Error(w, "a bearer token is required", http.StatusUnauthorized)
+		return
+	}
+
+	namespace, name := req.URL.Query().Get("namespace"), req.URL.Query().Get("name")
+	if namespace == "" || name == "" {
+		http.Error(w, "the namespace and name query parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := req.Context()
+	userInfo, err := h.admission.authenticate(ctx, token)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if allowed, err := h.admission.canGet(ctx, userInfo, namespace, name); err != nil {
+		logging.FromContext(ctx).Errorf("Error reviewing access to ApprovalTask %s/%s: %v", namespace, name, err)
+		http.Error(w, "failed to review access to the approval task", http.StatusInternalServerError)
+		return
+	} else if !allowed {
+		http.Error(w, "not allowed to get the approval task", http.StatusForbidden)
+		return
+	}
+
+	at, err := h.approvalTasks.OpenshiftpipelinesV1alpha1().ApprovalTasks(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		logging.FromContext(ctx).Errorf("Error fetching ApprovalTask %s/%s: %v", namespace, name, err)
+		http.Error(w, "failed to fetch the approval task", http.StatusInternalServerError)
+		return
+	}
+
+	signed, err := receipt.New(*at, h.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(signed); err != nil {
+		logging.FromContext(ctx).Errorf("Error writing receipt response: %v", err)
+	}
+}
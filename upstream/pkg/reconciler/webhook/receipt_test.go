@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/receipt"
+	"github.com/stretchr/testify/assert"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+)
+
+// newReceiptHandler reuses the eligibility fixtures and lets only auditors get
+// ApprovalTasks.
+func newReceiptHandler(t *testing.T, state string) (*receiptHandler, ed25519.PublicKey) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	at := eligibilityApprovalTask()
+	at.Status.State = state
+	eligibility := newEligibilityHandler(nil, at)
+	eligibility.admission.client.(*fake.Clientset).PrependReactor("create", "subjectaccessreviews", func(action ktesting.Action) (bool, runtime.Object, error) {
+		review := action.(ktesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		review.Status.Allowed = review.Spec.User == "auditor" && review.Spec.ResourceAttributes.Verb == "get"
+		return true, review, nil
+	})
+	return &receiptHandler{admission: eligibility.admission, approvalTasks: eligibility.approvalTasks, key: private}, public
+}
+
+func getReceipt(h *receiptHandler, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, ReceiptPath+"?namespace=production&name=deploy", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestReceiptHandler(t *testing.T) {
+	h, public := newReceiptHandler(t, "approved")
+
+	rec := getReceipt(h, "auditor")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var signed receipt.Signed
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &signed))
+	r, err := receipt.Verify(signed, public)
+	assert.NoError(t, err)
+	assert.Equal(t, "deploy", r.Name)
+	assert.Equal(t, "approved", r.State)
+}
+
+func TestReceiptHandlerRequiresAccess(t *testing.T) {
+	h, _ := newReceiptHandler(t, "approved")
+	assert.Equal(t, http.StatusForbidden, getReceipt(h, "mallory").Code)
+	assert.Equal(t, http.StatusUnauthorized, getReceipt(h, "invalid").Code)
+}
+
+func TestReceiptHandlerRequiresFinalTask(t *testing.T) {
+	h, _ := newReceiptHandler(t, "pending")
+	assert.Equal(t, http.StatusConflict, getReceipt(h, "auditor").Code)
+}
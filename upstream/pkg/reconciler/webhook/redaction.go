@@ -0,0 +1,155 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// Username redaction modes of the webhook logs.
+const (
+	// UsernameRedactionOff logs usernames in full.
+	UsernameRedactionOff = "off"
+	// UsernameRedactionHash logs a keyed hash of usernames, the same for a
+	// user across requests and replicas sharing the key.
+	UsernameRedactionHash = "hash"
+	// UsernameRedactionTruncate logs the first characters of usernames.
+	UsernameRedactionTruncate = "truncate"
+)
+
+// UsernameRedactions lists the valid username redaction modes.
+var UsernameRedactions = []string{UsernameRedactionOff, UsernameRedactionHash, UsernameRedactionTruncate}
+
+const (
+	// redactedHashLength is the number of hex digits of the hash logged for
+	// a username, enough to tell the users of a cluster apart.
+	redactedHashLength = 16
+	// redactedPrefixLength is the number of characters of a username kept
+	// when truncating it.
+	redactedPrefixLength = 3
+)
+
+// usernameRedactor redacts the usernames written to the webhook logs, and
+// to the denial messages, warnings and events, which are logged or readable
+// more widely. The audit annotations, only readable by those with access to
+// the audit log, always record them in full.
+type usernameRedactor struct {
+	mode string
+	key  []byte
+}
+
+// redact returns username as it is logged.
+func (u usernameRedactor) redact(username string) string {
+	switch u.mode {
+	case UsernameRedactionHash:
+		mac := hmac.New(sha256.New, u.key)
+		mac.Write([]byte(username))
+		return "sha256:" + hex.EncodeToString(mac.Sum(nil))[:redactedHashLength]
+	case UsernameRedactionTruncate:
+		runes := []rune(username)
+		if len(runes) > redactedPrefixLength {
+			runes = runes[:redactedPrefixLength]
+		}
+		return string(runes) + "***"
+	default:
+		return username
+	}
+}
+
+// redactMessage returns message with each of the identities it mentions
+// redacted. An identity is only replaced where it stands on its own, not as
+// part of a longer name.
+func (u usernameRedactor) redactMessage(message string, identities []string) string {
+	if u.mode == "" || u.mode == UsernameRedactionOff || message == "" {
+		return message
+	}
+	// Longer identities first, so that "alice@example.com" is not taken
+	// for "alice"
+	sort.Slice(identities, func(i, j int) bool { return len(identities[i]) > len(identities[j]) })
+	var b strings.Builder
+	for i := 0; i < len(message); {
+		matched := ""
+		if i == 0 || !isIdentityByte(message[i-1]) {
+			for _, identity := range identities {
+				end := i + len(identity)
+				if identity != "" && strings.HasPrefix(message[i:], identity) && (end == len(message) || !isIdentityByte(message[end])) {
+					matched = identity
+					break
+				}
+			}
+		}
+		if matched == "" {
+			b.WriteByte(message[i])
+			i++
+			continue
+		}
+		b.WriteString(u.redact(matched))
+		i += len(matched)
+	}
+	return b.String()
+}
+
+// isIdentityByte reports whether c can be part of a username or an email.
+func isIdentityByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("@._:-+", c) >= 0
+}
+
+// redactResponse redacts the usernames in the denial message and the
+// warnings of the response to request: those of the user and of the users
+// approving the task. They are returned to the user and logged along with
+// the response, while the audit annotations, recorded before, keep them in
+// full.
+func (r *reconciler) redactResponse(request *admissionv1.AdmissionRequest, response *admissionv1.AdmissionResponse) {
+	if r.usernames.mode == "" || r.usernames.mode == UsernameRedactionOff {
+		return
+	}
+	identities := append([]string{request.UserInfo.Username}, approvingUsers(request.OldObject.Raw)...)
+	identities = append(identities, approvingUsers(request.Object.Raw)...)
+	if response.Result != nil {
+		response.Result.Message = r.usernames.redactMessage(response.Result.Message, identities)
+	}
+	for i, warning := range response.Warnings {
+		response.Warnings[i] = r.usernames.redactMessage(warning, identities)
+	}
+}
+
+// approvingUsers returns the names of the User and Email approvers and of
+// the group members of the encoded ApprovalTask.
+func approvingUsers(raw []byte) []string {
+	var task v1alpha1.ApprovalTask
+	if len(raw) == 0 || json.Unmarshal(raw, &task) != nil {
+		return nil
+	}
+	var users []string
+	for _, approver := range task.Spec.Approvers {
+		if v1alpha1.IsIndividualApproverType(approver.Type) {
+			users = append(users, approver.Name)
+		}
+		for _, user := range approver.Users {
+			users = append(users, user.Name)
+		}
+	}
+	return users
+}
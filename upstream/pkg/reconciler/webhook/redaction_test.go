@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	"knative.dev/pkg/logging"
+)
+
+func TestRedactUsername(t *testing.T) {
+	hashed := usernameRedactor{mode: UsernameRedactionHash, key: []byte("secret")}
+	redacted := hashed.redact("carol@example.com")
+	assert.Regexp(t, `^sha256:[0-9a-f]{16}$`, redacted)
+	assert.Equal(t, redacted, hashed.redact("carol@example.com"), "the same user always maps to the same hash")
+	assert.NotEqual(t, redacted, hashed.redact("dave@example.com"))
+	assert.NotEqual(t, redacted, usernameRedactor{mode: UsernameRedactionHash, key: []byte("other")}.redact("carol@example.com"),
+		"the hash depends on the key")
+
+	truncated := usernameRedactor{mode: UsernameRedactionTruncate}
+	assert.Equal(t, "car***", truncated.redact("carol@example.com"))
+	assert.Equal(t, "jé***", truncated.redact("jé"))
+
+	assert.Equal(t, "carol@example.com", usernameRedactor{mode: UsernameRedactionOff}.redact("carol@example.com"))
+	assert.Equal(t, "carol@example.com", usernameRedactor{}.redact("carol@example.com"))
+}
+
+func TestAdmitRedactsUsernamesInLogsOnly(t *testing.T) {
+	var logs bytes.Buffer
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&logs), zapcore.DebugLevel)
+	ctx := logging.WithLogger(context.Background(), zap.New(core).Sugar())
+
+	r := &reconciler{
+		groupResolver: &fakeGroupResolver{err: errors.New("directory unavailable")},
+		usernames:     usernameRedactor{mode: UsernameRedactionHash, key: []byte("secret")},
+	}
+	oldObj := groupApprovalTask("groups-redacted")
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+	newObj.Spec.Approvers[1].Users = []v1alpha1.UserDetails{{Name: "carol@example.com", Input: "approve"}}
+
+	oldBytes, err := json.Marshal(oldObj)
+	assert.NoError(t, err)
+	newBytes, err := json.Marshal(newObj)
+	assert.NoError(t, err)
+	request := admissionRequestFor("carol@example.com")
+	request.Operation = admissionv1.Update
+	request.Namespace, request.Name = newObj.Namespace, newObj.Name
+	request.Kind = metav1.GroupVersionKind{Group: Group, Version: Version, Kind: Kind}
+	request.OldObject = runtime.RawExtension{Raw: oldBytes}
+	request.Object = runtime.RawExtension{Raw: newBytes}
+
+	resp := r.Admit(ctx, request)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, groupMembershipUnverifiedMsg, resp.Result.Message)
+
+	assert.Contains(t, logs.String(), r.usernames.redact("carol@example.com"))
+	assert.NotContains(t, logs.String(), "carol@example.com")
+	assert.Equal(t, "carol@example.com", resp.AuditAnnotations[auditUserKey], "the audit log keeps the full username")
+}
+
+func TestRedactMessage(t *testing.T) {
+	truncated := usernameRedactor{mode: UsernameRedactionTruncate}
+	identities := []string{"al", "alice@example.com", "bob"}
+	assert.Equal(t, "User can only record their own team, not the team of approver 'ali***'",
+		truncated.redactMessage("User can only record their own team, not the team of approver 'alice@example.com'", identities))
+	assert.Equal(t, "counted as member of group bobcats, not bob***", truncated.redactMessage("counted as member of group bobcats, not bob", identities),
+		"identities are not redacted within longer names")
+	assert.Equal(t, "al*** approves", truncated.redactMessage("al approves", identities), "redacted identities are not redacted again")
+	assert.Equal(t, "bob approves", usernameRedactor{}.redactMessage("bob approves", identities))
+}
+
+func TestAdmitRedactsUsernamesInDenials(t *testing.T) {
+	r := &reconciler{usernames: usernameRedactor{mode: UsernameRedactionTruncate}}
+	oldObj := quarantineTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Team = "payments"
+
+	oldBytes, err := json.Marshal(oldObj)
+	assert.NoError(t, err)
+	newBytes, err := json.Marshal(newObj)
+	assert.NoError(t, err)
+	request := admissionRequestFor("alice")
+	request.Operation = admissionv1.Update
+	request.Namespace, request.Name = newObj.Namespace, newObj.Name
+	request.Kind = metav1.GroupVersionKind{Group: Group, Version: Version, Kind: Kind}
+	request.OldObject = runtime.RawExtension{Raw: oldBytes}
+	request.Object = runtime.RawExtension{Raw: newBytes}
+
+	resp := r.Admit(context.Background(), request)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "User can only record their own team, not the team of approver 'bob***'", resp.Result.Message)
+	assert.Equal(t, "User can only record their own team, not the team of approver 'bob'", resp.AuditAnnotations[auditReasonKey],
+		"the audit log keeps the full usernames")
+	assert.Equal(t, "alice", resp.AuditAnnotations[auditUserKey])
+}
+
+func TestQuarantineEventRedactsUsername(t *testing.T) {
+	recorder := record.NewFakeRecorder(1)
+	q := newQuarantine(Options{QuarantineThreshold: 1, UsernameRedaction: UsernameRedactionTruncate}, clocktesting.NewFakePassiveClock(time.Now()), recorder)
+	request := admissionRequestFor("alice")
+	request.Operation = admissionv1.Update
+	q.observe(request, &admissionv1.AdmissionResponse{Result: &metav1.Status{Message: "User does not exist in the approval list"}})
+	assert.Contains(t, <-recorder.Events, "Denying all updates of ali*** to approval task")
+}
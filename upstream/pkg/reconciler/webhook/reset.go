@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"reflect"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// approverSetChanged reports whether the update adds or removes approvers.
+func approverSetChanged(oldObj, newObj *v1alpha1.ApprovalTask) bool {
+	return approval.ApproverSetHash(oldObj.Spec.Approvers) != approval.ApproverSetHash(newObj.Spec.Approvers)
+}
+
+// validateApproverSetChange checks that approvers are only added or removed
+// by the privileged group, on their own: the approvers kept and the rest of
+// the spec stay as they are. It returns the denial message, or an empty
+// string if the change is allowed.
+func (r *reconciler) validateApproverSetChange(oldObj, newObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest) string {
+	if r.privilegedGroup == "" || !webhookContains(request.UserInfo.Groups, r.privilegedGroup) {
+		return "Only members of the privileged group can add or remove approvers"
+	}
+
+	changed := newObj.Spec.DeepCopy()
+	changed.Approvers = oldObj.Spec.Approvers
+	if !reflect.DeepEqual(oldObj.Spec, *changed) {
+		return "Adding or removing approvers cannot change any other field"
+	}
+	for _, approver := range newObj.Spec.Approvers {
+		for _, oldApprover := range oldObj.Spec.Approvers {
+			if sameApprover(approver, oldApprover) && !reflect.DeepEqual(approver, oldApprover) {
+				return "Adding or removing approvers cannot change the approvers kept"
+			}
+		}
+	}
+	return ""
+}
+
+// sameApprover reports whether two approver entries are for the same
+// approver, by type and name.
+func sameApprover(a, b v1alpha1.ApproverDetails) bool {
+	return v1alpha1.DefaultedApproverType(a.Type) == v1alpha1.DefaultedApproverType(b.Type) && a.Name == b.Name
+}
+
+// isApproverChangeReset reports whether the update is the controller
+// resetting the responses of a task following ApproverChangeReset after its
+// approver set changed: every approver set back to pending and the hash of
+// the new approver set recorded in place of the previous one, changing
+// nothing else in its spec, labels or annotations.
+func (r *reconciler) isApproverChangeReset(oldObj, newObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest) bool {
+	if r.controllerUsername == "" || request.UserInfo.Username != r.controllerUsername {
+		return false
+	}
+	if !approval.ResetsOnApproverChange(*oldObj) {
+		return false
+	}
+	previous, ok := oldObj.Annotations[v1alpha1.ApproverSetHashAnnotationKey]
+	hash := approval.ApproverSetHash(oldObj.Spec.Approvers)
+	if !ok || previous == hash || newObj.Annotations[v1alpha1.ApproverSetHashAnnotationKey] != hash {
+		return false
+	}
+	if !equality.Semantic.DeepEqual(oldObj.Labels, newObj.Labels) {
+		return false
+	}
+	annotations := make(map[string]string, len(newObj.Annotations))
+	for key, value := range newObj.Annotations {
+		annotations[key] = value
+	}
+	annotations[v1alpha1.ApproverSetHashAnnotationKey] = previous
+	if !equality.Semantic.DeepEqual(oldObj.Annotations, annotations) {
+		return false
+	}
+	reset := oldObj.Spec.DeepCopy()
+	reset.Approvers = approval.ResetApprovers(oldObj.Spec.Approvers)
+	return equality.Semantic.DeepEqual(*reset, newObj.Spec)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdmitApproverSetChange(t *testing.T) {
+	r := &reconciler{privilegedGroup: DefaultPrivilegedGroup}
+	oldObj := withdrawableApprovalTask()
+	oldObj.Spec.NumberOfApprovalsRequired = 2
+	oldObj.Spec.Approvers[0].Input = "approve"
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers = append(newObj.Spec.Approvers, v1alpha1.ApproverDetails{Name: "security", Type: "Group", Input: "pending"})
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "admin", DefaultPrivilegedGroup)
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Only members of the privileged group can add or remove approvers", resp.Result.Message)
+
+	sneaky := newObj.DeepCopy()
+	sneaky.Spec.NumberOfApprovalsRequired = 3
+	resp = admitUpdateWith(t, r, oldObj, sneaky, "admin", DefaultPrivilegedGroup)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Adding or removing approvers cannot change any other field", resp.Result.Message)
+
+	sneaky = newObj.DeepCopy()
+	sneaky.Spec.Approvers[0].Input = "reject"
+	resp = admitUpdateWith(t, r, oldObj, sneaky, "admin", DefaultPrivilegedGroup)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Adding or removing approvers cannot change the approvers kept", resp.Result.Message)
+}
+
+func TestAdmitApproverChangeReset(t *testing.T) {
+	r := &reconciler{controllerUsername: testControllerUsername}
+	oldObj := withdrawableApprovalTask()
+	oldObj.Spec.NumberOfApprovalsRequired = 2
+	oldObj.Spec.ApproverChangePolicy = v1alpha1.ApproverChangeReset
+	oldObj.Annotations[v1alpha1.ApproverSetHashAnnotationKey] = approval.ApproverSetHash(oldObj.Spec.Approvers)
+	oldObj.Spec.Approvers[0].Input = "approve"
+	oldObj.Spec.Approvers = append(oldObj.Spec.Approvers, v1alpha1.ApproverDetails{Name: "security", Type: "Group", Input: "pending"})
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers = approval.ResetApprovers(oldObj.Spec.Approvers)
+	newObj.Annotations[v1alpha1.ApproverSetHashAnnotationKey] = approval.ApproverSetHash(oldObj.Spec.Approvers)
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, testControllerUsername)
+	assert.True(t, resp.Allowed, "the controller resets the responses once the approvers changed")
+
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed, "approvers cannot reset the responses of others")
+
+	sneaky := newObj.DeepCopy()
+	sneaky.Spec.NumberOfApprovalsRequired = 3
+	resp = admitUpdateWith(t, r, oldObj, sneaky, testControllerUsername)
+	assert.False(t, resp.Allowed, "nothing else may change with the reset")
+
+	preserve := oldObj.DeepCopy()
+	preserve.Spec.ApproverChangePolicy = v1alpha1.ApproverChangePreserve
+	reset := newObj.DeepCopy()
+	reset.Spec.ApproverChangePolicy = v1alpha1.ApproverChangePreserve
+	resp = admitUpdateWith(t, r, preserve, reset, testControllerUsername)
+	assert.False(t, resp.Allowed, "the responses of tasks preserving them are not reset")
+
+	unchanged := newObj.DeepCopy()
+	resp = admitUpdateWith(t, r, unchanged, unchanged.DeepCopy(), testControllerUsername)
+	assert.False(t, resp.Allowed, "tasks whose approvers did not change are not reset")
+}
+
+func TestAdmitApproverChangePolicyImmutable(t *testing.T) {
+	oldObj := withdrawableApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.ApproverChangePolicy = v1alpha1.ApproverChangeReset
+
+	resp := admitUpdateWith(t, &reconciler{privilegedGroup: DefaultPrivilegedGroup}, oldObj, newObj, "admin", DefaultPrivilegedGroup)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "The approver change policy of an ApprovalTask cannot be changed", resp.Result.Message)
+
+	newObj.Spec.ApproverChangePolicy = "Sometimes"
+	resp = admitUpdateWith(t, &reconciler{}, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "approverChangePolicy: must be one of 'Preserve' or 'Reset', got 'Sometimes'")
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// isRejectionReversal reports whether the update only sets rejecting inputs,
+// of approvers or of group members, back to pending.
+func isRejectionReversal(oldObj, newObj *v1alpha1.ApprovalTask) bool {
+	if len(oldObj.Spec.Approvers) != len(newObj.Spec.Approvers) {
+		return false
+	}
+	restored := newObj.Spec.DeepCopy()
+	reversed := false
+	for i := range restored.Approvers {
+		oldApprover, approver := oldObj.Spec.Approvers[i], &restored.Approvers[i]
+		if oldApprover.Input == "reject" && approver.Input == "pending" {
+			approver.Input = oldApprover.Input
+			reversed = true
+		}
+		if len(oldApprover.Users) != len(approver.Users) {
+			return false
+		}
+		for j := range approver.Users {
+			if oldApprover.Users[j].Input == "reject" && approver.Users[j].Input == "pending" {
+				approver.Users[j].Input = oldApprover.Users[j].Input
+				reversed = true
+			}
+		}
+	}
+	return reversed && reflect.DeepEqual(oldObj.Spec, *restored)
+}
+
+// validateRejectionReversal returns why the reversal of the rejection of the
+// task is denied, if it is. Only members of the privileged group can reverse
+// a rejection, and only within the RejectionReversalWindow of the task.
+func (r *reconciler) validateRejectionReversal(oldObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest) string {
+	if r.privilegedGroup == "" || !webhookContains(request.UserInfo.Groups, r.privilegedGroup) {
+		return "Only members of the privileged group can reverse a rejection"
+	}
+	if finalAt, _ := approval.RejectionFinalAt(*oldObj); !approval.RejectionReversibleAt(*oldObj, r.now()) {
+		return fmt.Sprintf("The rejection of this ApprovalTask became final at %s and can no longer be reversed", finalAt.UTC().Format(time.RFC3339))
+	}
+	return ""
+}
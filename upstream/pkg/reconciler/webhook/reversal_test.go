@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+var reversalRejectedAt = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+// rejectedApprovalTask returns a task bob rejected at reversalRejectedAt,
+// whose rejection can be reversed for ten minutes.
+func rejectedApprovalTask() *v1alpha1.ApprovalTask {
+	rejectedAt := metav1.NewTime(reversalRejectedAt)
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			RejectionReversalWindow:   &metav1.Duration{Duration: 10 * time.Minute},
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "bob", Type: "User", Input: "reject"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "rejected", RejectedAt: &rejectedAt},
+	}
+}
+
+func reversalReconciler(now time.Time) *reconciler {
+	return &reconciler{privilegedGroup: DefaultPrivilegedGroup, clock: clocktesting.NewFakePassiveClock(now)}
+}
+
+func TestAdmitRejectionReversalWithinWindow(t *testing.T) {
+	oldObj := rejectedApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "pending"
+	r := reversalReconciler(reversalRejectedAt.Add(5 * time.Minute))
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "admin", DefaultPrivilegedGroup)
+	assert.True(t, resp.Allowed)
+
+	resp = admitUpdateWith(t, r, oldObj, newObj, "bob")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Only members of the privileged group can reverse a rejection", resp.Result.Message)
+}
+
+func TestAdmitRejectionReversalAfterWindow(t *testing.T) {
+	oldObj := rejectedApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "pending"
+
+	resp := admitUpdateWith(t, reversalReconciler(reversalRejectedAt.Add(10*time.Minute)), oldObj, newObj, "admin", DefaultPrivilegedGroup)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "The rejection of this ApprovalTask became final at 2024-05-01T10:10:00Z and can no longer be reversed", resp.Result.Message)
+}
+
+func TestAdmitRejectionReversalOnlyResetsRejections(t *testing.T) {
+	oldObj := rejectedApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "pending"
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdateWith(t, reversalReconciler(reversalRejectedAt), oldObj, newObj, "admin", DefaultPrivilegedGroup)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "ApprovalTask has already reached it's final state", resp.Result.Message)
+
+	// Without a window, rejections are final at once
+	oldObj.Spec.RejectionReversalWindow = nil
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "pending"
+	resp = admitUpdateWith(t, reversalReconciler(reversalRejectedAt), oldObj, newObj, "admin", DefaultPrivilegedGroup)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "ApprovalTask has already reached it's final state", resp.Result.Message)
+}
+
+func TestAdmitRejectionReversalWindowImmutable(t *testing.T) {
+	oldObj := withdrawableApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.RejectionReversalWindow = &metav1.Duration{Duration: time.Hour}
+
+	resp := admitUpdate(t, oldObj, newObj, "admin", DefaultPrivilegedGroup)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "The rejection reversal window of an ApprovalTask cannot be changed", resp.Result.Message)
+}
+
+func TestValidateNegativeRejectionReversalWindow(t *testing.T) {
+	at := sizedApprovalTask(2)
+	at.Spec.RejectionReversalWindow = &metav1.Duration{Duration: -time.Minute}
+
+	resp := admitCreateWith(t, &reconciler{}, at)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "validation failed: spec validation failed: rejectionReversalWindow: must not be negative, got -1m0s", resp.Result.Message)
+}
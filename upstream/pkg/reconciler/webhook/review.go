@@ -0,0 +1,50 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// now returns the current time of the clock of the webhook, or the wall
+// clock when it has none.
+func (r *reconciler) now() time.Time {
+	if r.clock == nil {
+		return time.Now()
+	}
+	return r.clock.Now()
+}
+
+// validateMinReviewDuration denies updates submitting an approval before the
+// minimum review duration of the task has passed since its creation.
+// Rejections are always allowed. It returns the denial message, or an empty
+// string if the update is allowed.
+func (r *reconciler) validateMinReviewDuration(oldObj, newObj *v1alpha1.ApprovalTask) string {
+	minReview := oldObj.Spec.MinReviewDuration
+	if minReview == nil || minReview.Duration <= 0 || !addsApproval(oldObj.Spec.Approvers, newObj.Spec.Approvers) {
+		return ""
+	}
+	remaining := oldObj.CreationTimestamp.Add(minReview.Duration).Sub(r.now())
+	if remaining <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("Cannot approve during the minimum review duration of %s, try again in %s",
+		minReview.Duration, remaining.Round(time.Second))
+}
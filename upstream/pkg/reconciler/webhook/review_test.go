@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+var reviewCreated = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// reviewApprovalTask returns a task created at reviewCreated that must be
+// reviewed for an hour before it can be approved.
+func reviewApprovalTask() *v1alpha1.ApprovalTask {
+	at := checksApprovalTask("")
+	at.CreationTimestamp = metav1.NewTime(reviewCreated)
+	at.Spec.MinReviewDuration = &metav1.Duration{Duration: time.Hour}
+	return at
+}
+
+func TestAdmitApprovalBeforeMinReviewDuration(t *testing.T) {
+	r := &reconciler{
+		privilegedGroup: DefaultPrivilegedGroup,
+		clock:           clocktesting.NewFakePassiveClock(reviewCreated.Add(47*time.Minute + 30*time.Second)),
+	}
+	oldObj := reviewApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Cannot approve during the minimum review duration of 1h0m0s, try again in 12m30s", resp.Result.Message)
+
+	// Group members wait for the review period too
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+	newObj.Spec.Approvers[1].Users = []v1alpha1.UserDetails{{Name: "bob", Input: "approve"}}
+	assert.False(t, admitUpdateWith(t, r, oldObj, newObj, "bob", "platform").Allowed)
+
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "reject"
+	assert.True(t, admitUpdateWith(t, r, oldObj, newObj, "alice").Allowed, "rejections do not wait for the review period")
+
+	eligibility := r.eligibility(context.Background(), oldObj, authenticationv1.UserInfo{Username: "alice"})
+	assert.False(t, eligibility.CanApprove)
+	assert.True(t, eligibility.CanReject)
+}
+
+func TestAdmitApprovalAfterMinReviewDuration(t *testing.T) {
+	oldObj := reviewApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	for _, elapsed := range []time.Duration{time.Hour, 3 * time.Hour} {
+		r := &reconciler{
+			privilegedGroup: DefaultPrivilegedGroup,
+			clock:           clocktesting.NewFakePassiveClock(reviewCreated.Add(elapsed)),
+		}
+		assert.True(t, admitUpdateWith(t, r, oldObj, newObj, "alice").Allowed, "approval %s after creation", elapsed)
+	}
+}
+
+func TestAdmitMinReviewDurationCannotBeChanged(t *testing.T) {
+	r := &reconciler{
+		privilegedGroup: DefaultPrivilegedGroup,
+		clock:           clocktesting.NewFakePassiveClock(reviewCreated),
+	}
+	oldObj := reviewApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.MinReviewDuration = nil
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "The minimum review duration of an ApprovalTask cannot be changed", resp.Result.Message)
+}
+
+func TestValidateNegativeMinReviewDuration(t *testing.T) {
+	at := reviewApprovalTask()
+	at.Spec.MinReviewDuration.Duration = -time.Minute
+	assert.EqualError(t, validateApprovalTaskSpec(&at.Spec, context.Background()), "minReviewDuration: must not be negative, got -1m0s")
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+)
+
+// validateRequiredRoles checks that the required roles are named, and named
+// once.
+func validateRequiredRoles(spec *v1alpha1.ApprovalTaskSpec) error {
+	for i, role := range spec.RequiredRoles {
+		if strings.TrimSpace(role) == "" {
+			return fmt.Errorf("requiredRoles[%d]: cannot be empty", i)
+		}
+		if webhookContains(spec.RequiredRoles[:i], role) {
+			return fmt.Errorf("requiredRoles[%d]: duplicate role '%s'", i, role)
+		}
+	}
+	return nil
+}
+
+// rolesChanged reports whether the update changes the required roles of the
+// task or the role of an approver it keeps. Roles are set when the task is
+// created, so that approvers cannot cover a role by giving it to themselves.
+func rolesChanged(oldObj, newObj *v1alpha1.ApprovalTask) bool {
+	if !reflect.DeepEqual(oldObj.Spec.RequiredRoles, newObj.Spec.RequiredRoles) {
+		return true
+	}
+	for _, approver := range newObj.Spec.Approvers {
+		for _, oldApprover := range oldObj.Spec.Approvers {
+			if sameApprover(approver, oldApprover) && approver.Role != oldApprover.Role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unheldRoleProblems returns a problem for each required role no approver of
+// the spec has, which keeps the task pending until it times out.
+func unheldRoleProblems(spec *v1alpha1.ApprovalTaskSpec) []error {
+	var problems []error
+	for i, role := range spec.RequiredRoles {
+		held := false
+		for _, approver := range spec.Approvers {
+			if approver.Role == role {
+				held = true
+				break
+			}
+		}
+		if !held {
+			problems = append(problems, fmt.Errorf("requiredRoles[%d]: no approver has role '%s'", i, role))
+		}
+	}
+	return problems
+}
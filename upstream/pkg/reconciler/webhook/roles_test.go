@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func roleApprovalTask() *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			RequiredRoles:             []string{"manager", "peer"},
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve", Role: "manager"},
+				{Name: "bob", Type: "User", Input: "pending", Role: "manager"},
+				{Name: "dave", Type: "User", Input: "pending", Role: "peer"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func TestIsApprovalRequiredUntilEveryRoleApproves(t *testing.T) {
+	r := &reconciler{}
+	oldObj := roleApprovalTask()
+	assert.True(t, isApprovalRequired(*oldObj))
+
+	// Only managers approve: the count is reached, the peer requirement is not
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+	resp := admitUpdateWith(t, r, oldObj, newObj, "bob")
+	assert.True(t, resp.Allowed)
+	assert.True(t, isApprovalRequired(*newObj), "the task stays pending without a peer approval")
+
+	// So the peer can still decide
+	peerObj := newObj.DeepCopy()
+	peerObj.Spec.Approvers[2].Input = "approve"
+	resp = admitUpdateWith(t, r, newObj, peerObj, "dave")
+	assert.True(t, resp.Allowed)
+	assert.False(t, isApprovalRequired(*peerObj))
+}
+
+func TestRolesCannotBeChanged(t *testing.T) {
+	r := &reconciler{}
+	oldObj := roleApprovalTask()
+
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Role = "peer"
+	newObj.Spec.Approvers[1].Input = "approve"
+	resp := admitUpdateWith(t, r, oldObj, newObj, "bob")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "The roles of an ApprovalTask cannot be changed", resp.Result.Message)
+
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.RequiredRoles = []string{"manager"}
+	resp = admitUpdateWith(t, r, oldObj, newObj, "bob", "system:masters")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "The roles of an ApprovalTask cannot be changed", resp.Result.Message)
+}
+
+func TestValidateRequiredRoles(t *testing.T) {
+	at := roleApprovalTask()
+	at.Spec.Approvers[0].Input = "pending"
+	at.Spec.RequiredRoles = []string{"manager", " "}
+	resp := admitCreateWith(t, &reconciler{}, at)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "validation failed: spec validation failed: requiredRoles[1]: cannot be empty", resp.Result.Message)
+
+	at.Spec.RequiredRoles = []string{"manager", "peer", "manager"}
+	resp = admitCreateWith(t, &reconciler{}, at)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "validation failed: spec validation failed: requiredRoles[2]: duplicate role 'manager'", resp.Result.Message)
+}
+
+func TestLintUnheldRoles(t *testing.T) {
+	at := roleApprovalTask()
+	at.Spec.Approvers[0].Input = "pending"
+	at.Spec.RequiredRoles = []string{"manager", "security"}
+	resp := lintCreate(t, &reconciler{}, at)
+	assert.True(t, resp.Allowed)
+	assert.Equal(t, []string{"lint: requiredRoles[1]: no approver has role 'security'"}, resp.Warnings)
+}
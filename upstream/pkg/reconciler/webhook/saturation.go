@@ -0,0 +1,55 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import "errors"
+
+// webhookBusyMsg denies decisions that need a resolution while the webhook
+// already runs as many as it allows. Like groupMembershipUnverifiedMsg it
+// tells the user the failure is transient.
+const webhookBusyMsg = "The webhook is busy, retry the request"
+
+// errResolutionsSaturated is returned for resolutions that could not start
+// because the webhook already runs as many as it allows.
+var errResolutionsSaturated = errors.New("too many concurrent resolutions")
+
+// resolutionLimiter bounds the number of resolutions calling out of the
+// webhook, such as group lookups, that admissions run at once, so that a slow
+// directory cannot pile up blocked admissions. It does not queue: resolutions
+// beyond the limit fail at once, for the API server to retry the request. A
+// nil limiter does not limit.
+type resolutionLimiter struct {
+	slots chan struct{}
+}
+
+func newResolutionLimiter(limit int) *resolutionLimiter {
+	return &resolutionLimiter{slots: make(chan struct{}, limit)}
+}
+
+// acquire takes a slot for a resolution and returns the function releasing
+// it, or false when every slot is taken.
+func (l *resolutionLimiter) acquire() (func(), bool) {
+	if l == nil {
+		return func() {}, true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, true
+	default:
+		return nil, false
+	}
+}
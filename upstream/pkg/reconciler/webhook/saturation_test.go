@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// blockingGroupResolver resolves every user into the platform group once
+// released, signalling entered as lookups start and recording the most
+// lookups it saw running at once.
+type blockingGroupResolver struct {
+	entered chan struct{}
+	release chan struct{}
+
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (b *blockingGroupResolver) Groups(_ context.Context, _ string) ([]string, error) {
+	b.mu.Lock()
+	b.running++
+	b.peak = max(b.peak, b.running)
+	b.mu.Unlock()
+
+	b.entered <- struct{}{}
+	<-b.release
+
+	b.mu.Lock()
+	b.running--
+	b.mu.Unlock()
+	return []string{"platform"}, nil
+}
+
+func TestAdmitWithSaturatedResolutions(t *testing.T) {
+	const limit = 2
+	resolver := &blockingGroupResolver{entered: make(chan struct{}), release: make(chan struct{})}
+	r := &reconciler{
+		groupResolver: resolver,
+		resolutions:   newResolutionLimiter(limit),
+		decisions:     newDecisionReporter(10, false),
+	}
+	oldObj := groupApprovalTask("groups-saturated")
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+	newObj.Spec.Approvers[1].Users = []v1alpha1.UserDetails{{Name: "carol", Input: "approve"}}
+
+	responses := make(chan *admissionv1.AdmissionResponse, limit)
+	for range limit {
+		go func() { responses <- admitUpdateWith(t, r, oldObj, newObj, "carol") }()
+	}
+	for range limit {
+		<-resolver.entered
+	}
+
+	// Every slot is taken, the next decision is turned away at once
+	resp := admitUpdateWith(t, r, oldObj, newObj, "carol")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, int32(http.StatusTooManyRequests), resp.Result.Code)
+	assert.Equal(t, "The webhook is busy, retry the request", resp.Result.Message)
+	assert.Equal(t, int64(0), groupResolutionFailures(t, "groups-saturated"), "a busy webhook is not a failing directory")
+
+	// Individual approvers decide without a slot
+	approved := oldObj.DeepCopy()
+	approved.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdateWith(t, r, oldObj, approved, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	close(resolver.release)
+	for range limit {
+		resp := <-responses
+		assert.True(t, resp.Allowed, "%v", resp.Result)
+	}
+	assert.Equal(t, limit, resolver.peak)
+}
+
+func TestResolutionLimiter(t *testing.T) {
+	l := newResolutionLimiter(1)
+	release, ok := l.acquire()
+	assert.True(t, ok)
+	_, ok = l.acquire()
+	assert.False(t, ok)
+	release()
+	_, ok = l.acquire()
+	assert.True(t, ok)
+
+	var unlimited *resolutionLimiter
+	for range 3 {
+		_, ok = unlimited.acquire()
+		assert.True(t, ok, "reconcilers built without a limiter do not limit")
+	}
+}
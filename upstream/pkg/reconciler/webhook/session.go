@@ -0,0 +1,59 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"strconv"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+// DefaultTokenIssuedAtClaim is the UserInfo extra claim the time the token of
+// a user was issued at is read from by default.
+const DefaultTokenIssuedAtClaim = "iat"
+
+// staleSession reports whether r.maxTokenAge is set and the token of the user
+// was not issued within it at now. The issue time is read from the
+// r.tokenIssuedAtClaim extra claim, as Unix seconds or an RFC 3339 time.
+// Tokens without a readable issue time are stale, since their age is unknown.
+func (r *reconciler) staleSession(userInfo authenticationv1.UserInfo, now time.Time) bool {
+	if r.maxTokenAge <= 0 {
+		return false
+	}
+	claim := r.tokenIssuedAtClaim
+	if claim == "" {
+		claim = DefaultTokenIssuedAtClaim
+	}
+	values := userInfo.Extra[claim]
+	if len(values) != 1 {
+		return true
+	}
+	issuedAt, ok := parseIssuedAt(values[0])
+	return !ok || now.Sub(issuedAt) > r.maxTokenAge
+}
+
+// parseIssuedAt parses the issue time claim of a token.
+func parseIssuedAt(value string) (time.Time, bool) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
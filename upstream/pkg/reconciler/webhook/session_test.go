@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func issuedAt(claim, value string) authenticationv1.UserInfo {
+	return authenticationv1.UserInfo{
+		Username: "alice",
+		Extra:    map[string]authenticationv1.ExtraValue{claim: {value}},
+	}
+}
+
+func TestStaleSession(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := &reconciler{maxTokenAge: 15 * time.Minute}
+	tests := []struct {
+		name     string
+		userInfo authenticationv1.UserInfo
+		stale    bool
+	}{{
+		name:     "fresh unix time",
+		userInfo: issuedAt("iat", strconv.FormatInt(now.Add(-5*time.Minute).Unix(), 10)),
+	}, {
+		name:     "fresh RFC 3339 time",
+		userInfo: issuedAt("iat", "2024-05-01T11:50:00Z"),
+	}, {
+		name:     "exactly the maximum age",
+		userInfo: issuedAt("iat", "2024-05-01T11:45:00Z"),
+	}, {
+		name:     "stale",
+		userInfo: issuedAt("iat", strconv.FormatInt(now.Add(-16*time.Minute).Unix(), 10)),
+		stale:    true,
+	}, {
+		name:     "no claim",
+		userInfo: authenticationv1.UserInfo{Username: "alice"},
+		stale:    true,
+	}, {
+		name:     "unreadable claim",
+		userInfo: issuedAt("iat", "yesterday"),
+		stale:    true,
+	}, {
+		name: "several values",
+		userInfo: authenticationv1.UserInfo{Username: "alice", Extra: map[string]authenticationv1.ExtraValue{
+			"iat": {"2024-05-01T11:50:00Z", "2024-04-01T11:50:00Z"},
+		}},
+		stale: true,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.stale, r.staleSession(tc.userInfo, now))
+		})
+	}
+
+	assert.False(t, (&reconciler{}).staleSession(authenticationv1.UserInfo{Username: "alice"}, now), "the check is off by default")
+
+	r.tokenIssuedAtClaim = "auth_time"
+	assert.True(t, r.staleSession(issuedAt("iat", "2024-05-01T11:50:00Z"), now), "only the configured claim is read")
+	assert.False(t, r.staleSession(issuedAt("auth_time", "2024-05-01T11:50:00Z"), now))
+}
+
+func TestAdmitStaleSession(t *testing.T) {
+	oldObj := withdrawableApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := &reconciler{privilegedGroup: DefaultPrivilegedGroup, maxTokenAge: time.Hour, clock: clocktesting.NewFakePassiveClock(now)}
+
+	fresh := issuedAt("iat", strconv.FormatInt(now.Add(-time.Minute).Unix(), 10))
+	assert.True(t, admitUpdateAs(t, r, oldObj, newObj, fresh).Allowed)
+
+	stale := issuedAt("iat", strconv.FormatInt(now.Add(-2*time.Hour).Unix(), 10))
+	resp := admitUpdateAs(t, r, oldObj, newObj, stale)
+	assert.False(t, resp.Allowed, "decisions from long-lived tokens are denied")
+	assert.Equal(t, "User session is older than 1h0m0s, log in again to decide", resp.Result.Message)
+
+	eligibility := r.eligibility(context.Background(), oldObj, stale)
+	assert.False(t, eligibility.CanApprove)
+	assert.False(t, eligibility.CanReject)
+	assert.True(t, r.eligibility(context.Background(), oldObj, fresh).CanApprove)
+}
@@ -0,0 +1,305 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"knative.dev/pkg/logging"
+	certresources "knative.dev/pkg/webhook/certificates/resources"
+)
+
+const (
+	// signingQueueLength bounds the decisions waiting to be signed. Decisions
+	// admitted while it is full are not signed.
+	signingQueueLength = 1024
+	// maxSignedDecisions is the number of most recent signed decisions kept
+	// in the status annotation of a task.
+	maxSignedDecisions = 20
+)
+
+// SignedDecision is an entry of the DecisionSignaturesAnnotationKey status
+// annotation: the canonical statement of an admitted request and its
+// signature, both base64 encoded, as VerifyDecision takes them.
+type SignedDecision struct {
+	Statement string `json:"statement"`
+	Signature string `json:"signature"`
+}
+
+// DecisionStatement is the canonical record of an admitted request that the
+// webhook signs with its serving key.
+type DecisionStatement struct {
+	UID          string `json:"uid"`
+	Namespace    string `json:"namespace"`
+	Name         string `json:"name"`
+	User         string `json:"user"`
+	Operation    string `json:"operation"`
+	ObjectDigest string `json:"objectDigest"`
+	Instance     string `json:"instance,omitempty"`
+}
+
+// canonicalStatement returns the canonical JSON form of the statement of the
+// admitted request. The object is recorded by the sha256 digest of the bytes
+// the API server sent, which its audit log keeps along with the request.
+func canonicalStatement(request *admissionv1.AdmissionRequest, instance string) ([]byte, error) {
+	digest := sha256.Sum256(request.Object.Raw)
+	return json.Marshal(DecisionStatement{
+		UID:          string(request.UID),
+		Namespace:    request.Namespace,
+		Name:         request.Name,
+		User:         request.UserInfo.Username,
+		Operation:    string(request.Operation),
+		ObjectDigest: "sha256:" + hex.EncodeToString(digest[:]),
+		Instance:     instance,
+	})
+}
+
+// decisionSigner signs the statements of admitted requests with the private
+// key of the webhook's serving certificate, off the admission path: admitted
+// requests are queued, then signed and recorded in the status of their task
+// by run. The key is parsed once per revision of the secret holding it, and
+// follows certificate rotations.
+type decisionSigner struct {
+	secret func() (*corev1.Secret, error)
+	client versioned.Interface
+	queue  chan signingJob
+
+	mu      sync.Mutex
+	version string
+	key     crypto.Signer
+}
+
+// signingJob is an admitted request waiting to be signed.
+type signingJob struct {
+	namespace, name string
+	statement       []byte
+}
+
+func newDecisionSigner(secret func() (*corev1.Secret, error), client versioned.Interface) *decisionSigner {
+	return &decisionSigner{secret: secret, client: client, queue: make(chan signingJob, signingQueueLength)}
+}
+
+// signingKey returns the serving key of the current revision of the secret.
+func (s *decisionSigner) signingKey() (crypto.Signer, error) {
+	secret, err := s.secret()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key != nil && s.version == secret.ResourceVersion {
+		return s.key, nil
+	}
+	key, err := parseSigningKey(secret.Data[certresources.ServerKey])
+	if err != nil {
+		return nil, err
+	}
+	s.key, s.version = key, secret.ResourceVersion
+	return key, nil
+}
+
+// enqueue queues the statement of an allowed request to be signed, without
+// waiting for it. Denied requests are not signed. A decision that cannot be
+// queued is logged without affecting it.
+func (s *decisionSigner) enqueue(ctx context.Context, request *admissionv1.AdmissionRequest, response *admissionv1.AdmissionResponse, instance string) {
+	if s == nil || !response.Allowed || request.SubResource != "" {
+		return
+	}
+	statement, err := canonicalStatement(request, instance)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("Unable to sign the decision on %s/%s: %v", request.Namespace, request.Name, err)
+		return
+	}
+	select {
+	case s.queue <- signingJob{namespace: request.Namespace, name: request.Name, statement: statement}:
+	default:
+		logging.FromContext(ctx).Warnf("Unable to sign the decision on %s/%s: %d decisions are already waiting to be signed",
+			request.Namespace, request.Name, signingQueueLength)
+	}
+}
+
+// run signs and records the queued decisions until ctx is done. It then
+// waits for drain to return, so that the admissions in flight queue theirs,
+// and records what is left in the queue within flushTimeout, before calling
+// done.
+func (s *decisionSigner) run(ctx context.Context, drain func(), flushTimeout time.Duration, done func()) {
+	defer done()
+	for {
+		select {
+		case job := <-s.queue:
+			s.record(ctx, job)
+		case <-ctx.Done():
+			drain()
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flushTimeout)
+			defer cancel()
+			for {
+				select {
+				case job := <-s.queue:
+					s.record(flushCtx, job)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// record signs the statement of the job and adds it to the
+// DecisionSignaturesAnnotationKey status annotation of its task, which a
+// create may not have stored yet. Failures are logged.
+func (s *decisionSigner) record(ctx context.Context, job signingJob) {
+	signature, err := s.sign(job.statement)
+	if err == nil {
+		signed := SignedDecision{
+			Statement: base64.StdEncoding.EncodeToString(job.statement),
+			Signature: base64.StdEncoding.EncodeToString(signature),
+		}
+		tasks := s.client.OpenshiftpipelinesV1alpha1().ApprovalTasks(job.namespace)
+		retriable := func(err error) bool { return apierrors.IsConflict(err) || apierrors.IsNotFound(err) }
+		err = retry.OnError(retry.DefaultBackoff, retriable, func() error {
+			task, err := tasks.Get(ctx, job.name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if task.Status.Annotations == nil {
+				task.Status.Annotations = map[string]string{}
+			}
+			task.Status.Annotations[v1alpha1.DecisionSignaturesAnnotationKey] =
+				appendSignedDecision(task.Status.Annotations[v1alpha1.DecisionSignaturesAnnotationKey], signed)
+			_, err = tasks.UpdateStatus(ctx, task, metav1.UpdateOptions{})
+			return err
+		})
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warnf("Unable to sign the decision on %s/%s: %v", job.namespace, job.name, err)
+	}
+}
+
+// appendSignedDecision returns the annotation value with signed added, keeping
+// the maxSignedDecisions most recent entries. A value that does not parse is
+// replaced.
+func appendSignedDecision(value string, signed SignedDecision) string {
+	var decisions []SignedDecision
+	if value != "" && json.Unmarshal([]byte(value), &decisions) != nil {
+		decisions = nil
+	}
+	decisions = append(decisions, signed)
+	if len(decisions) > maxSignedDecisions {
+		decisions = decisions[len(decisions)-maxSignedDecisions:]
+	}
+	// Marshalling strings cannot fail
+	b, _ := json.Marshal(decisions)
+	return string(b)
+}
+
+// sign returns the signature of statement with the current serving key.
+func (s *decisionSigner) sign(statement []byte) ([]byte, error) {
+	key, err := s.signingKey()
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(statement)
+	return key.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+// parseSigningKey parses the PEM encoded PKCS #8, or for RSA PKCS #1, private
+// key of the serving certificate.
+func parseSigningKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded serving key found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		return key, nil
+	case *rsa.PrivateKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("decisions are signed with ECDSA or RSA keys, got %T", key)
+	}
+}
+
+// VerifyDecision checks that the base64 encoded statement and signature of
+// a decision, as found in the audit annotations of its admission, were signed
+// with the private key of the PEM encoded serving certificate, and returns
+// the decoded statement.
+func VerifyDecision(statement, signature string, certPEM []byte) (*DecisionStatement, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rawStatement, err := base64.StdEncoding.DecodeString(statement)
+	if err != nil {
+		return nil, fmt.Errorf("malformed decision statement: %w", err)
+	}
+	rawSignature, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, fmt.Errorf("malformed decision signature: %w", err)
+	}
+
+	digest := sha256.Sum256(rawStatement)
+	switch publicKey := cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(publicKey, digest[:], rawSignature) {
+			return nil, errors.New("decision signature is not valid")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], rawSignature); err != nil {
+			return nil, errors.New("decision signature is not valid")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported certificate key %T", publicKey)
+	}
+
+	decoded := &DecisionStatement{}
+	if err := json.Unmarshal(rawStatement, decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
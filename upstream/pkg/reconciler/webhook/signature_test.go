@@ -0,0 +1,194 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	approvaltaskfake "github.com/openshift-pipelines/manual-approval-gate/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	certresources "knative.dev/pkg/webhook/certificates/resources"
+)
+
+// servingSecret returns a webhook secret holding a freshly generated serving
+// key and certificate, as the certificates controller writes it.
+func servingSecret(t *testing.T, version string) *corev1.Secret {
+	t.Helper()
+	key, cert, ca, err := certresources.CreateCerts(context.Background(), "manual-approval-webhook", "openshift-pipelines", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed creating certificates: %v", err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{ResourceVersion: version},
+		Data: map[string][]byte{
+			certresources.ServerKey:  key,
+			certresources.ServerCert: cert,
+			certresources.CACert:     ca,
+		},
+	}
+}
+
+// signedDecisions returns the signed decisions recorded in the status of the
+// task.
+func signedDecisions(t *testing.T, client *approvaltaskfake.Clientset, namespace, name string) []SignedDecision {
+	t.Helper()
+	task, err := client.OpenshiftpipelinesV1alpha1().ApprovalTasks(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	var decisions []SignedDecision
+	if value := task.Status.Annotations[v1alpha1.DecisionSignaturesAnnotationKey]; value != "" {
+		assert.NoError(t, json.Unmarshal([]byte(value), &decisions))
+	}
+	return decisions
+}
+
+func TestAdmitSignsAllowedDecisions(t *testing.T) {
+	secret := servingSecret(t, "1")
+	oldObj := withdrawableApprovalTask()
+	client := approvaltaskfake.NewSimpleClientset(oldObj.DeepCopy())
+	r := &reconciler{
+		instance: "openshift-pipelines/webhook-0",
+		signer:   newDecisionSigner(func() (*corev1.Secret, error) { return secret, nil }, client),
+	}
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed)
+	assert.Len(t, r.signer.queue, 1, "admission only queues the decision")
+	assert.Empty(t, signedDecisions(t, client, oldObj.Namespace, oldObj.Name))
+
+	resp = admitUpdateWith(t, r, oldObj, newObj, "mallory")
+	assert.False(t, resp.Allowed)
+	assert.Len(t, r.signer.queue, 1, "denied requests are not signed")
+
+	r.signer.record(context.Background(), <-r.signer.queue)
+	decisions := signedDecisions(t, client, oldObj.Namespace, oldObj.Name)
+	if !assert.Len(t, decisions, 1) {
+		return
+	}
+	statement, err := VerifyDecision(decisions[0].Statement, decisions[0].Signature, secret.Data[certresources.ServerCert])
+	assert.NoError(t, err)
+	assert.Equal(t, "production", statement.Namespace)
+	assert.Equal(t, "deploy", statement.Name)
+	assert.Equal(t, "alice", statement.User)
+	assert.Equal(t, "UPDATE", statement.Operation)
+	assert.Equal(t, "openshift-pipelines/webhook-0", statement.Instance)
+	assert.Contains(t, statement.ObjectDigest, "sha256:")
+
+	other := servingSecret(t, "2")
+	_, err = VerifyDecision(decisions[0].Statement, decisions[0].Signature, other.Data[certresources.ServerCert])
+	assert.EqualError(t, err, "decision signature is not valid", "the signature only validates against the serving certificate")
+
+	forged := base64.StdEncoding.EncodeToString([]byte(`{"uid":"","namespace":"production","name":"deploy","user":"mallory","operation":"UPDATE","objectDigest":"sha256:00"}`))
+	_, err = VerifyDecision(forged, decisions[0].Signature, secret.Data[certresources.ServerCert])
+	assert.EqualError(t, err, "decision signature is not valid")
+}
+
+func TestDecisionSignerRun(t *testing.T) {
+	secret := servingSecret(t, "1")
+	task := withdrawableApprovalTask()
+	client := approvaltaskfake.NewSimpleClientset(task.DeepCopy())
+	s := newDecisionSigner(func() (*corev1.Secret, error) { return secret, nil }, client)
+	for i := 0; i < maxSignedDecisions+2; i++ {
+		s.queue <- signingJob{namespace: task.Namespace, name: task.Name, statement: []byte(fmt.Sprintf(`{"uid":"%d"}`, i))}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	drained := false
+	finished := make(chan struct{})
+	s.run(ctx, func() { drained = true }, time.Minute, func() { close(finished) })
+	<-finished
+	assert.True(t, drained, "the admissions in flight are drained before the queue is flushed")
+
+	decisions := signedDecisions(t, client, task.Namespace, task.Name)
+	assert.Len(t, decisions, maxSignedDecisions, "only the most recent decisions are kept")
+	statement, err := VerifyDecision(decisions[len(decisions)-1].Statement, decisions[len(decisions)-1].Signature, secret.Data[certresources.ServerCert])
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprint(maxSignedDecisions+1), statement.UID, "the queue is flushed on shutdown")
+}
+
+func TestDecisionSignerFollowsRotations(t *testing.T) {
+	secret := servingSecret(t, "1")
+	s := newDecisionSigner(func() (*corev1.Secret, error) { return secret, nil }, nil)
+	first, err := s.signingKey()
+	assert.NoError(t, err)
+	cached, err := s.signingKey()
+	assert.NoError(t, err)
+	assert.Same(t, first, cached, "the key is parsed once per revision of the secret")
+
+	secret = servingSecret(t, "2")
+	rotated, err := s.signingKey()
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, rotated)
+}
+
+func TestDecisionSignerFailureKeepsDecision(t *testing.T) {
+	oldObj := withdrawableApprovalTask()
+	client := approvaltaskfake.NewSimpleClientset(oldObj.DeepCopy())
+	r := &reconciler{signer: newDecisionSigner(func() (*corev1.Secret, error) { return nil, errors.New("secret not found") }, client)}
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed)
+	r.signer.record(context.Background(), <-r.signer.queue)
+	assert.Empty(t, signedDecisions(t, client, oldObj.Namespace, oldObj.Name))
+
+	// A full queue drops the decision without blocking the admission
+	for len(r.signer.queue) < signingQueueLength {
+		r.signer.queue <- signingJob{}
+	}
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed)
+}
+
+func TestVerifyDecisionRSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "webhook"}, NotAfter: time.Now().Add(time.Hour)}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	secret := &corev1.Secret{Data: map[string][]byte{
+		certresources.ServerKey: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+	}}
+	s := newDecisionSigner(func() (*corev1.Secret, error) { return secret, nil }, nil)
+
+	statement, err := canonicalStatement(admissionRequestFor("alice"), "")
+	assert.NoError(t, err)
+	signature, err := s.sign(statement)
+	assert.NoError(t, err)
+	decoded, err := VerifyDecision(base64.StdEncoding.EncodeToString(statement), base64.StdEncoding.EncodeToString(signature),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", decoded.User)
+}
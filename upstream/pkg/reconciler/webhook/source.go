@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+// DefaultSourceIPClaim is the UserInfo extra claim the address a request
+// originated from is read from by default, as set by an authenticating proxy.
+const DefaultSourceIPClaim = "source-ip"
+
+// ParseSourceRanges parses the CIDR ranges decisions are accepted from.
+func ParseSourceRanges(values []string) ([]netip.Prefix, error) {
+	var ranges []netip.Prefix
+	for _, value := range values {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid source range %q: %w", value, err)
+		}
+		ranges = append(ranges, prefix.Masked())
+	}
+	return ranges, nil
+}
+
+// outsideSourceRanges returns the denial message of a decision when
+// r.sourceRanges is set and the address the user's request originated from,
+// read from the r.sourceIPClaim extra claim, is in none of them. Requests
+// without a single readable address are denied, since their origin is
+// unknown.
+func (r *reconciler) outsideSourceRanges(userInfo authenticationv1.UserInfo) string {
+	if len(r.sourceRanges) == 0 {
+		return ""
+	}
+	claim := r.sourceIPClaim
+	if claim == "" {
+		claim = DefaultSourceIPClaim
+	}
+	values := userInfo.Extra[claim]
+	if len(values) != 1 {
+		return fmt.Sprintf("Cannot verify the source address of the request, the '%s' claim is missing", claim)
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(values[0]))
+	if err != nil {
+		return fmt.Sprintf("Cannot verify the source address of the request, the '%s' claim is not an IP address", claim)
+	}
+	addr = addr.Unmap()
+	for _, prefix := range r.sourceRanges {
+		if prefix.Contains(addr) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("Decisions are not accepted from %s, it is outside the allowed source ranges", addr)
+}
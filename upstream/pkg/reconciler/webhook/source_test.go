@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+func sourceIP(claim, value string) authenticationv1.UserInfo {
+	return authenticationv1.UserInfo{
+		Username: "alice",
+		Extra:    map[string]authenticationv1.ExtraValue{claim: {value}},
+	}
+}
+
+func TestParseSourceRanges(t *testing.T) {
+	ranges, err := ParseSourceRanges([]string{"10.0.0.0/8", " 192.168.1.7/24", "2001:db8::/32"})
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.1.0/24", ranges[1].String(), "ranges are masked")
+	assert.Len(t, ranges, 3)
+
+	_, err = ParseSourceRanges([]string{"10.0.0.0"})
+	assert.ErrorContains(t, err, `invalid source range "10.0.0.0"`)
+}
+
+func TestOutsideSourceRanges(t *testing.T) {
+	ranges, err := ParseSourceRanges([]string{"10.0.0.0/8", "2001:db8::/32"})
+	assert.NoError(t, err)
+	r := &reconciler{sourceRanges: ranges}
+	tests := []struct {
+		name     string
+		userInfo authenticationv1.UserInfo
+		want     string
+	}{{
+		name:     "in range",
+		userInfo: sourceIP("source-ip", "10.1.2.3"),
+	}, {
+		name:     "in an IPv6 range",
+		userInfo: sourceIP("source-ip", "2001:db8::1"),
+	}, {
+		name:     "IPv4-mapped IPv6 address in range",
+		userInfo: sourceIP("source-ip", "::ffff:10.1.2.3"),
+	}, {
+		name:     "out of range",
+		userInfo: sourceIP("source-ip", "192.168.1.7"),
+		want:     "Decisions are not accepted from 192.168.1.7, it is outside the allowed source ranges",
+	}, {
+		name:     "no claim",
+		userInfo: authenticationv1.UserInfo{Username: "alice"},
+		want:     "Cannot verify the source address of the request, the 'source-ip' claim is missing",
+	}, {
+		name:     "unreadable claim",
+		userInfo: sourceIP("source-ip", "office"),
+		want:     "Cannot verify the source address of the request, the 'source-ip' claim is not an IP address",
+	}, {
+		name: "several values",
+		userInfo: authenticationv1.UserInfo{Username: "alice", Extra: map[string]authenticationv1.ExtraValue{
+			"source-ip": {"10.1.2.3", "192.168.1.7"},
+		}},
+		want: "Cannot verify the source address of the request, the 'source-ip' claim is missing",
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, r.outsideSourceRanges(tc.userInfo))
+		})
+	}
+
+	assert.Empty(t, (&reconciler{}).outsideSourceRanges(authenticationv1.UserInfo{Username: "alice"}), "there is no restriction by default")
+
+	r.sourceIPClaim = "x-forwarded-for"
+	assert.NotEmpty(t, r.outsideSourceRanges(sourceIP("source-ip", "10.1.2.3")), "only the configured claim is read")
+	assert.Empty(t, r.outsideSourceRanges(sourceIP("x-forwarded-for", "10.1.2.3")))
+}
+
+func TestAdmitOutsideSourceRanges(t *testing.T) {
+	ranges, err := ParseSourceRanges([]string{"10.0.0.0/8"})
+	assert.NoError(t, err)
+	r := &reconciler{sourceRanges: ranges}
+	oldObj := groupApprovalTask("source-ranges")
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdateAs(t, r, oldObj, newObj, sourceIP("source-ip", "10.1.2.3"))
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	resp = admitUpdateAs(t, r, oldObj, newObj, sourceIP("source-ip", "203.0.113.9"))
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Decisions are not accepted from 203.0.113.9, it is outside the allowed source ranges", resp.Result.Message)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"strings"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"knative.dev/pkg/logging"
+)
+
+// unavailableApprovers returns the users the namespace marks as off duty
+// through the v1alpha1.UnavailableApproversAnnotationKey annotation.
+func (r *reconciler) unavailableApprovers(ctx context.Context, namespace string) map[string]bool {
+	if r.nslister == nil || namespace == "" {
+		return nil
+	}
+	ns, err := r.nslister.Get(namespace)
+	if err != nil {
+		logging.FromContext(ctx).Debugf("Unable to get namespace %s for approver availability: %v", namespace, err)
+		return nil
+	}
+	value, ok := ns.Annotations[v1alpha1.UnavailableApproversAnnotationKey]
+	if !ok {
+		return nil
+	}
+
+	unavailable := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			unavailable[name] = true
+		}
+	}
+	return unavailable
+}
+
+// activeApprover returns who currently decides for a User approver: the
+// approver itself or, when it is unavailable, the first available of its
+// substitutes. The approver stays active when none of them is available.
+func activeApprover(approver v1alpha1.ApproverDetails, unavailable map[string]bool) string {
+	if !unavailable[approver.Name] {
+		return approver.Name
+	}
+	for _, substitute := range approver.Substitutes {
+		if !unavailable[substitute] {
+			return substitute
+		}
+	}
+	return approver.Name
+}
+
+// resolveSubstitutes returns a copy of the approval task in which every User
+// approver with substitutes, and its recorded response, is named after the
+// user currently active for it, so the checks of Admit apply to that user.
+// The task itself is returned when there is nothing to resolve.
+func (r *reconciler) resolveSubstitutes(ctx context.Context, approvalTask *v1alpha1.ApprovalTask) *v1alpha1.ApprovalTask {
+	hasSubstitutes := false
+	for _, approver := range approvalTask.Spec.Approvers {
+		if len(approver.Substitutes) > 0 {
+			hasSubstitutes = true
+			break
+		}
+	}
+	if !hasSubstitutes {
+		return approvalTask
+	}
+
+	unavailable := r.unavailableApprovers(ctx, approvalTask.Namespace)
+	if len(unavailable) == 0 {
+		return approvalTask
+	}
+
+	resolved := approvalTask.DeepCopy()
+	for i, approver := range resolved.Spec.Approvers {
+		if v1alpha1.DefaultedApproverType(approver.Type) != "User" || len(approver.Substitutes) == 0 {
+			continue
+		}
+		active := activeApprover(approver, unavailable)
+		if active == approver.Name {
+			continue
+		}
+		resolved.Spec.Approvers[i].Name = active
+		for j, response := range resolved.Status.ApproversResponse {
+			if v1alpha1.DefaultedApproverType(response.Type) == "User" && response.Name == approver.Name {
+				resolved.Status.ApproversResponse[j].Name = active
+			}
+		}
+	}
+	return resolved
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func namespaceWithUnavailable(unavailable string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "production",
+			Annotations: map[string]string{v1alpha1.UnavailableApproversAnnotationKey: unavailable},
+		},
+	}
+}
+
+func onCallApprovalTask() *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending", Substitutes: []string{"bob", "carol"}},
+				{Name: "lead", Type: "User", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func TestActiveApprover(t *testing.T) {
+	approver := onCallApprovalTask().Spec.Approvers[0]
+	assert.Equal(t, "alice", activeApprover(approver, nil))
+	assert.Equal(t, "bob", activeApprover(approver, map[string]bool{"alice": true}))
+	assert.Equal(t, "carol", activeApprover(approver, map[string]bool{"alice": true, "bob": true}))
+	assert.Equal(t, "alice", activeApprover(approver, map[string]bool{"alice": true, "bob": true, "carol": true}),
+		"the approver stays active when nobody can stand in")
+}
+
+func TestAdmitSubstituteApproval(t *testing.T) {
+	r, _ := newNamespaceReconciler(t, namespaceWithUnavailable("alice, dave"))
+	r.privilegedGroup = DefaultPrivilegedGroup
+
+	oldObj := onCallApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	newObj.Spec.Approvers[0].DecidedBy = "bob"
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "bob")
+	assert.True(t, resp.Allowed, "the first available substitute decides for alice: %v", resp.Result)
+
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed, "an unavailable approver cannot decide")
+
+	resp = admitUpdateWith(t, r, oldObj, newObj, "carol")
+	assert.False(t, resp.Allowed, "only the active substitute can decide")
+
+	// Once the substitute has approved, the response is theirs
+	oldObj = newObj.DeepCopy()
+	oldObj.Status.ApproversResponse = []v1alpha1.ApproverState{{Name: "alice", Type: "User", Response: "approved"}}
+	resp = admitUpdateWith(t, r, oldObj, newObj.DeepCopy(), "bob")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "User has already approved", resp.Result.Message)
+}
+
+func TestAdmitSubstituteWhileApproverAvailable(t *testing.T) {
+	r, _ := newNamespaceReconciler(t, namespaceWithUnavailable("dave"))
+	r.privilegedGroup = DefaultPrivilegedGroup
+
+	oldObj := onCallApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdateWith(t, r, oldObj, newObj, "bob")
+	assert.False(t, resp.Allowed, "substitutes cannot decide while the approver is available")
+
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+}
+
+func TestEligibilityOfSubstitute(t *testing.T) {
+	r, _ := newNamespaceReconciler(t, namespaceWithUnavailable("alice"))
+	at := onCallApprovalTask()
+
+	bob := r.eligibility(context.Background(), at, admissionRequestFor("bob").UserInfo)
+	assert.True(t, bob.CanApprove)
+	assert.Equal(t, roleUser, bob.Role)
+
+	alice := r.eligibility(context.Background(), at, admissionRequestFor("alice").UserInfo)
+	assert.False(t, alice.CanApprove)
+	assert.Equal(t, roleNone, alice.Role)
+}
+
+func TestValidateApproverSubstitutes(t *testing.T) {
+	err := validateApprover(v1alpha1.ApproverDetails{Name: "alice", Type: "User", Input: "pending", Substitutes: []string{"bob", "bob"}}, "approvers[0]", "")
+	assert.EqualError(t, err, "approvers[0].substitutes[1]: duplicate user 'bob'")
+
+	err = validateApprover(v1alpha1.ApproverDetails{Name: "alice", Type: "User", Input: "pending", Substitutes: []string{"alice"}}, "approvers[0]", "")
+	assert.EqualError(t, err, "approvers[0].substitutes[0]: duplicate user 'alice'")
+
+	err = validateApprover(v1alpha1.ApproverDetails{Name: "platform", Type: "Group", Input: "pending", Substitutes: []string{"bob"}}, "approvers[0]", "")
+	assert.EqualError(t, err, "approvers[0].substitutes: only User approvers can have substitutes, got type 'Group'")
+
+	assert.NoError(t, validateApprover(v1alpha1.ApproverDetails{Name: "alice", Type: "User", Input: "pending", Substitutes: []string{"bob"}}, "approvers[0]", ""))
+}
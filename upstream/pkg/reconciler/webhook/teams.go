@@ -0,0 +1,59 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+// teamExtraKey is the UserInfo extra claim the team of an approver is taken from.
+const teamExtraKey = "team"
+
+// validateApproverTeams denies updates changing the team recorded on an
+// approver entry, or on a group member's entry, which does not belong to the
+// user, or recording a team which is not one of the values of the user's
+// team claim. Approvals towards Spec.MinApprovingTeams therefore always
+// carry the team the identity provider asserts.
+func validateApproverTeams(oldObj, newObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest) string {
+	return validateRecordedAttribute(approval.TeamAttribute, oldObj, newObj, request, teamExtraKey, "team",
+		"Team '%s' is not one of the teams of the user")
+}
+
+// validateRecordedAttribute denies updates changing the attribute recorded on
+// an entry which does not belong to the user, naming the attribute noun in
+// the denial, or recording a value which is not one of the values of the
+// user's claim, formatting invalidMsg with it.
+func validateRecordedAttribute(attribute approval.RecordedAttribute, oldObj, newObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest,
+	claim, noun, invalidMsg string) string {
+	values := request.UserInfo.Extra[claim]
+	for _, change := range attribute.Changes(*oldObj, *newObj) {
+		if change.Member == "" && !isIndividualApprover(change.Approver.Type, change.Approver.Name, request.UserInfo) {
+			return fmt.Sprintf("User can only record their own %s, not the %s of approver '%s'", noun, noun, change.Approver.Name)
+		}
+		if change.Member != "" && change.Member != request.UserInfo.Username {
+			return fmt.Sprintf("User can only record their own %s, not the %s of member '%s' of group '%s'", noun, noun, change.Member, change.Approver.Name)
+		}
+		if change.Value != "" && !webhookContains(values, change.Value) {
+			return fmt.Sprintf(invalidMsg, change.Value)
+		}
+	}
+	return ""
+}
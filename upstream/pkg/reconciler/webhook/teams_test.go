@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func teamApprovalTask() *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			MinApprovingTeams:         2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve", Team: "payments"},
+				{Name: "bob", Type: "User", Input: "pending"},
+				{Name: "platform", Type: "Group", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func teamUser(name string, teams ...string) authenticationv1.UserInfo {
+	return authenticationv1.UserInfo{Username: name, Extra: map[string]authenticationv1.ExtraValue{teamExtraKey: teams}}
+}
+
+func TestApprovalRequiredUntilTeamsApprove(t *testing.T) {
+	at := teamApprovalTask()
+	at.Spec.Approvers[1].Input = "approve"
+	at.Spec.Approvers[1].Team = "payments"
+	assert.True(t, isApprovalRequired(*at), "alice and bob share a team")
+
+	at.Spec.Approvers[1].Team = "checkout"
+	assert.False(t, isApprovalRequired(*at))
+}
+
+func TestAdmitApprovalRecordingTeam(t *testing.T) {
+	r := &reconciler{}
+	oldObj := teamApprovalTask()
+
+	approved := oldObj.DeepCopy()
+	approved.Spec.Approvers[1].Input = "approve"
+	approved.Spec.Approvers[1].Team = "checkout"
+	resp := admitUpdateAs(t, r, oldObj, approved, teamUser("bob", "checkout"))
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	resp = admitUpdateAs(t, r, oldObj, approved, teamUser("bob", "payments"))
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Team 'checkout' is not one of the teams of the user", resp.Result.Message)
+
+	resp = admitUpdateAs(t, r, oldObj, approved, teamUser("bob"))
+	assert.False(t, resp.Allowed, "bob has no team claim")
+
+	unteamed := oldObj.DeepCopy()
+	unteamed.Spec.Approvers[1].Input = "approve"
+	resp = admitUpdateAs(t, r, oldObj, unteamed, teamUser("bob"))
+	assert.True(t, resp.Allowed, "approvals need not record a team: %v", resp.Result)
+}
+
+func TestAdmitGroupMemberRecordingTeam(t *testing.T) {
+	r := &reconciler{}
+	oldObj := teamApprovalTask()
+
+	approved := oldObj.DeepCopy()
+	approved.Spec.Approvers[2].Input = "approve"
+	approved.Spec.Approvers[2].Users = []v1alpha1.UserDetails{{Name: "carol", Input: "approve", Team: "platform"}}
+	userInfo := teamUser("carol", "platform")
+	userInfo.Groups = []string{"platform"}
+	resp := admitUpdateAs(t, r, oldObj, approved, userInfo)
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	userInfo.Extra[teamExtraKey] = []string{"payments"}
+	resp = admitUpdateAs(t, r, oldObj, approved, userInfo)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Team 'platform' is not one of the teams of the user", resp.Result.Message)
+}
+
+func TestAdmitChangingAnotherApproversTeam(t *testing.T) {
+	oldObj := teamApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Team = "checkout"
+	newObj.Spec.Approvers[1].Input = "approve"
+
+	resp := admitUpdateAs(t, &reconciler{}, oldObj, newObj, teamUser("bob", "checkout"))
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "User can only record their own team, not the team of approver 'alice'", resp.Result.Message)
+}
+
+func TestAdmitLoweringMinApprovingTeams(t *testing.T) {
+	oldObj := teamApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.MinApprovingTeams = 1
+	newObj.Spec.Approvers[1].Input = "approve"
+	newObj.Spec.Approvers[1].Team = "payments"
+
+	resp := admitUpdateAs(t, &reconciler{}, oldObj, newObj, teamUser("bob", "payments"))
+	assert.False(t, resp.Allowed, "lowering the teams along with an approval would reach quorum without them")
+	assert.Equal(t, "The minimum number of approving teams of an ApprovalTask cannot be changed", resp.Result.Message)
+
+	newObj.Spec.MinApprovingTeams = 0
+	resp = admitUpdateAs(t, &reconciler{}, oldObj, newObj, teamUser("bob", "payments"))
+	assert.False(t, resp.Allowed)
+}
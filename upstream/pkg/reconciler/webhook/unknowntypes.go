@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+)
+
+const (
+	// UnknownApproverTypesDeny denies ApprovalTasks with approvers of a type
+	// that is not one of v1alpha1.KnownApproverTypes.
+	UnknownApproverTypesDeny = "deny"
+	// UnknownApproverTypesWarn admits them with a warning for each such
+	// approver, for migrations introducing a type that not every version of
+	// the webhook knows yet.
+	UnknownApproverTypesWarn = "warn"
+	// UnknownApproverTypesDefault admits them without a warning, as before
+	// approver types were validated.
+	UnknownApproverTypesDefault = "default"
+)
+
+// UnknownApproverTypeModes lists the accepted values of
+// Options.UnknownApproverTypes.
+var UnknownApproverTypeModes = []string{UnknownApproverTypesDeny, UnknownApproverTypesWarn, UnknownApproverTypesDefault}
+
+type unknownApproverTypesKey struct{}
+
+// withUnknownApproverTypes returns ctx carrying how approvers of unknown
+// types are handled, for the validation of the approvers of a task.
+func withUnknownApproverTypes(ctx context.Context, mode string) context.Context {
+	return context.WithValue(ctx, unknownApproverTypesKey{}, mode)
+}
+
+// unknownApproverTypesFrom returns how approvers of unknown types are handled
+// according to ctx, denied unless set otherwise.
+func unknownApproverTypesFrom(ctx context.Context) string {
+	if mode, _ := ctx.Value(unknownApproverTypesKey{}).(string); mode != "" {
+		return mode
+	}
+	return UnknownApproverTypesDeny
+}
+
+// validateApproverIn validates a single approver entry with the settings
+// carried by ctx. Approvers of an unknown type are only checked for a name
+// and a valid input unless they are denied: they never count towards the
+// quorum, so there is nothing else their type could be validated against.
+func validateApproverIn(ctx context.Context, approver v1alpha1.ApproverDetails, fieldPath string) error {
+	if v1alpha1.IsKnownApproverType(approver.Type) || unknownApproverTypesFrom(ctx) == UnknownApproverTypesDeny {
+		return validateApprover(approver, fieldPath, groupSeparatorFrom(ctx))
+	}
+	if strings.TrimSpace(approver.Name) == "" {
+		return fmt.Errorf("%s.name: required field is missing", fieldPath)
+	}
+	validInputs := []string{"pending", "approve", "reject", "request-changes"}
+	if !webhookContains(validInputs, approver.Input) {
+		return fmt.Errorf("%s.input: must be one of: %s, got '%s'", fieldPath, strings.Join(validInputs, ", "), approver.Input)
+	}
+	return nil
+}
+
+// unknownApproverTypeWarnings returns a warning for each approver of an
+// unknown type of the ApprovalTask admitted by the request, when such
+// approvers are admitted with a warning.
+func (r *reconciler) unknownApproverTypeWarnings(request *admissionv1.AdmissionRequest, response *admissionv1.AdmissionResponse) []string {
+	if r.unknownApproverTypes != UnknownApproverTypesWarn || !response.Allowed {
+		return nil
+	}
+	if request.Operation != admissionv1.Create && request.Operation != admissionv1.Update {
+		return nil
+	}
+	approvalTask, err := r.decodeNewObject(request.Object.Raw)
+	if err != nil {
+		return nil
+	}
+	var warnings []string
+	for i, approver := range approvalTask.Spec.Approvers {
+		if !v1alpha1.IsKnownApproverType(approver.Type) {
+			warnings = append(warnings, fmt.Sprintf("approvers[%d].type: unknown approver type '%s', the approver does not count towards the quorum until its type is one of %s",
+				i, approver.Type, quotedList(v1alpha1.KnownApproverTypes)))
+		}
+	}
+	return warnings
+}
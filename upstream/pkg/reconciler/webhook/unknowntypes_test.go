@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+const robotWarning = "approvers[1].type: unknown approver type 'Robot', the approver does not count towards the quorum until its type is one of 'User', 'Group' or 'Email'"
+
+// robotApprovalTask has an approver of a type the webhook does not know.
+func robotApprovalTask() *v1alpha1.ApprovalTask {
+	at := withdrawableApprovalTask()
+	at.Spec.Approvers = append(at.Spec.Approvers, v1alpha1.ApproverDetails{Name: "deploy-bot", Type: "Robot", Input: "pending"})
+	return at
+}
+
+func TestUnknownApproverTypesDeny(t *testing.T) {
+	for _, mode := range []string{"", UnknownApproverTypesDeny} {
+		resp := admitCreateWith(t, &reconciler{unknownApproverTypes: mode}, robotApprovalTask())
+		assert.False(t, resp.Allowed)
+		assert.Equal(t, "validation failed: spec validation failed: approvers[1].type: must be one of 'User', 'Group' or 'Email', got 'Robot'", resp.Result.Message)
+	}
+}
+
+func TestUnknownApproverTypesWarn(t *testing.T) {
+	r := &reconciler{privilegedGroup: DefaultPrivilegedGroup, unknownApproverTypes: UnknownApproverTypesWarn}
+
+	resp := admitCreateWith(t, r, robotApprovalTask())
+	assert.True(t, resp.Allowed)
+	assert.Equal(t, []string{robotWarning}, resp.Warnings)
+
+	oldObj := robotApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed, "approvers of a known type can still decide")
+	assert.Contains(t, resp.Warnings, robotWarning)
+
+	invalid := robotApprovalTask()
+	invalid.Spec.Approvers[1].Input = "maybe"
+	resp = admitCreateWith(t, r, invalid)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "validation failed: spec validation failed: approvers[1].input: must be one of: pending, approve, reject, request-changes, got 'maybe'", resp.Result.Message)
+}
+
+func TestUnknownApproverTypesDefault(t *testing.T) {
+	resp := admitCreateWith(t, &reconciler{unknownApproverTypes: UnknownApproverTypesDefault}, robotApprovalTask())
+	assert.True(t, resp.Allowed)
+	assert.Empty(t, resp.Warnings)
+}
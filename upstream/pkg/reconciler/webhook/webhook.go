@@ -4,19 +4,35 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/netip"
+	"reflect"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/approval"
+	approvalpolicylisters "github.com/openshift-pipelines/manual-approval-gate/pkg/client/listers/approvaltask/v1alpha1"
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/correlation"
 	"go.uber.org/zap"
 	admissionv1 "k8s.io/api/admission/v1"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	admissionlisters "k8s.io/client-go/listers/admissionregistration/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/kmp"
 	"knative.dev/pkg/logging"
@@ -46,9 +62,53 @@ type reconciler struct {
 	client       kubernetes.Interface
 	vwhlister    admissionlisters.ValidatingWebhookConfigurationLister
 	secretlister corelisters.SecretLister
+	nslister     corelisters.NamespaceLister
+	policylister approvalpolicylisters.ClusterApprovalPolicyLister
+	tasklister   approvalpolicylisters.ApprovalTaskLister
+
+	nsApprovers namespaceApproversCache
+	clock       clock.PassiveClock
 
 	disallowUnknownFields bool
 	secretName            string
+	caBundleKey           string
+	privilegedGroup       string
+	enforceApprovalOrder  bool
+	allowFinalMetadata    bool
+	maxApprovers          int
+	rules                 []admissionregistrationv1.RuleWithOperations
+	requiredClaim         string
+	requiredClaimValue    string
+	maxTokenAge           time.Duration
+	tokenIssuedAtClaim    string
+	sourceRanges          []netip.Prefix
+	sourceIPClaim         string
+	allowedImpersonations []string
+	impersonatorClaim     string
+	checksAnnotation      string
+	checksGating          string
+	ciUsers               []string
+	ciGroups              []string
+	requireChangeTicket   bool
+	changeTicketPattern   *regexp.Regexp
+	requireCurrentVersion bool
+	explicitGroupMembers  bool
+	forbidGroupSelfAdd    bool
+	blocklist             []string
+	usernames             usernameRedactor
+	groupSeparator        string
+	unknownApproverTypes  string
+	groupResolver         GroupResolver
+	interceptors          func(defaults []DecisionInterceptor) []DecisionInterceptor
+	instance              string
+	controllerUsername    string
+	decisions             *decisionReporter
+	resolutions           *resolutionLimiter
+	quarantine            *quarantine
+	signer                *decisionSigner
+	recorder              record.EventRecorder
+	admissions            *admissionTracker
+	drift                 webhookDrift
 }
 
 var _ controller.Reconciler = (*reconciler)(nil)
@@ -71,20 +131,79 @@ func (r *reconciler) Reconcile(ctx context.Context, key string) error {
 		return err
 	}
 
+	caCert, err := r.caBundle(ctx, secret)
+	if err != nil {
+		return err
+	}
+
+	// Reconcile the webhook configuration.
+	_, err = r.reconcileValidatingWebhook(ctx, caCert)
+	return err
+}
+
+// caBundle returns the CA bundle to publish in the webhook configuration.
+// While a CA is being rotated the configured bundle key concatenates the old
+// and new CAs, so connections validated against either keep working. When the
+// secret does not carry the bundle key, the single CA cert is used instead.
+func (r *reconciler) caBundle(ctx context.Context, secret *corev1.Secret) ([]byte, error) {
+	if r.caBundleKey != "" && r.caBundleKey != certresources.CACert {
+		if bundle, ok := secret.Data[r.caBundleKey]; ok {
+			count := countPEMCertificates(bundle)
+			if count == 0 {
+				return nil, fmt.Errorf("secret %q key %q does not contain any PEM encoded certificate", r.secretName, r.caBundleKey)
+			}
+			logging.FromContext(ctx).Debugf("Using CA bundle %q with %d certificates", r.caBundleKey, count)
+			return bundle, nil
+		}
+	}
+
 	caCert, ok := secret.Data[certresources.CACert]
 	if !ok {
-		return fmt.Errorf("secret %q is missing %q key", r.secretName, certresources.CACert)
+		return nil, fmt.Errorf("secret %q is missing %q key", r.secretName, certresources.CACert)
 	}
+	return caCert, nil
+}
 
-	// Reconcile the webhook configuration.
-	return r.reconcileValidatingWebhook(ctx, caCert)
+func countPEMCertificates(bundle []byte) int {
+	count := 0
+	for {
+		var block *pem.Block
+		block, bundle = pem.Decode(bundle)
+		if block == nil {
+			return count
+		}
+		if block.Type == "CERTIFICATE" {
+			count++
+		}
+	}
 }
 
 func (r *reconciler) Admit(ctx context.Context, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	defer r.admissions.begin()()
 	if r.withContext != nil {
 		ctx = r.withContext(ctx)
 	}
+	correlationID := requestCorrelationID(request)
+	ctx = correlation.WithLogger(ctx, correlationID)
+	ctx = withGroupSeparator(ctx, r.groupSeparator)
+	ctx = withUnknownApproverTypes(ctx, r.unknownApproverTypes)
+	response := r.quarantine.deny(request)
+	if response == nil {
+		response = r.admit(ctx, request)
+		response.Warnings = append(response.Warnings, r.unknownApproverTypeWarnings(request, response)...)
+		response.Warnings = append(response.Warnings, r.lint(ctx, request)...)
+		r.quarantine.observe(request, response)
+	}
+	annotateAudit(request, response, r.instance, correlationID)
+	r.annotateDelegation(request, response)
+	r.signer.enqueue(ctx, request, response, r.instance)
+	r.decisions.report(ctx, request, response)
+	r.redactResponse(request, response)
+	return response
+}
 
+// admit decides on the admission request on behalf of Admit.
+func (r *reconciler) admit(ctx context.Context, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
 	logger := logging.FromContext(ctx)
 	kind := request.Kind
 
@@ -110,11 +229,29 @@ func (r *reconciler) Admit(ctx context.Context, request *admissionv1.AdmissionRe
 		return webhook.MakeErrorStatus("validation failed: %v", err)
 	}
 
+	// Subresources such as status are only sent here when the webhook rules
+	// are configured to include them. They cannot carry approver decisions,
+	// so the structural validation above is all they need.
+	if request.SubResource != "" {
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+
 	if request.Operation == "CREATE" {
 		// For CREATE operations, ensure all approver inputs are set to "pending"
 		if err := validateApproverInputsForCreate(newObj); err != nil {
 			return webhook.MakeErrorStatus("validation failed: %v", err)
 		}
+		if err := r.validateApproverCount(nil, newObj); err != nil {
+			return webhook.MakeErrorStatus("validation failed: %v", err)
+		}
+		if err := validateApproverIdentities(&newObj.Spec); err != nil {
+			return webhook.MakeErrorStatus("validation failed: %v", err)
+		}
+		if err := r.validateClusterPolicies(ctx, newObj); err != nil {
+			return webhook.MakeErrorStatus("validation failed: %v", err)
+		}
 		return &admissionv1.AdmissionResponse{
 			Allowed: true,
 		}
@@ -124,14 +261,103 @@ func (r *reconciler) Admit(ctx context.Context, request *admissionv1.AdmissionRe
 		return webhook.MakeErrorStatus("unsupported operation: %s", request.Operation)
 	}
 
+	// Without the prior state no decision can be checked against it
+	if isEmptyObject(request.OldObject.Raw) {
+		return webhook.MakeErrorStatus("missing prior state: the update does not carry the old ApprovalTask")
+	}
+
 	// Decode old object for UPDATE operations
 	oldObj, err := r.decodeOldObject(request.OldObject.Raw)
 	if err != nil {
 		return webhook.MakeErrorStatus("cannot decode incoming old object: %v", err)
 	}
 
+	return r.decide(ctx, &Decision{Request: request, Old: oldObj, New: newObj})
+}
+
+// interceptStructure admits deleted tasks releasing their finalizers and the
+// controller indexing the approvers tasks await, and denies updates based on
+// a stale copy or exceeding the approver cap.
+func (r *reconciler) interceptStructure(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse {
+	oldObj, newObj, request := d.Old, d.New, d.Request
+
+	// Releasing the finalizers of a task being deleted only lets the deletion finish
+	if newObj.DeletionTimestamp != nil && isMetadataOnlyUpdate(oldObj, newObj) {
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+
+	// The index follows the task into its final state
+	if r.isAwaitingIndexUpdate(oldObj, newObj, request) {
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+
+	// Tasks that lost their owner get their CustomRun back as owner
+	if r.isOwnerReferenceRepair(oldObj, newObj, request) {
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+
+	if resp := r.checkResourceVersion(oldObj, newObj); resp != nil {
+		return resp
+	}
+
+	// CI reports on the change the task gates in its annotations
+	if r.isCIAnnotationUpdate(oldObj, newObj, request) {
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+
+	if err := r.validateApproverCount(oldObj, newObj); err != nil {
+		return webhook.MakeErrorStatus("validation failed: %v", err)
+	}
+	return nil
+}
+
+// interceptReplay admits decisions retried after they were applied.
+func (r *reconciler) interceptReplay(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse {
+	oldObj, newObj, request := d.Old, d.New, d.Request
+
+	// A retried decision is admitted without effect, even once it made the task final
+	if r.isDecisionReplay(ctx, oldObj, newObj, request) {
+		return &admissionv1.AdmissionResponse{
+			Allowed:  true,
+			Warnings: []string{alreadyAppliedWarning},
+		}
+	}
+	return nil
+}
+
+// interceptFinalState denies updates to tasks that reached a final state.
+func (r *reconciler) interceptFinalState(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse {
+	oldObj, newObj, request := d.Old, d.New, d.Request
+
 	// Check if approval is required by the approver
 	if !isApprovalRequired(*oldObj) {
+		// Rejections with a reversal window are not final until it has passed
+		if _, reversible := approval.RejectionFinalAt(*oldObj); reversible && isRejectionReversal(oldObj, newObj) {
+			if denyMsg := r.validateRejectionReversal(oldObj, request); denyMsg != "" {
+				return &admissionv1.AdmissionResponse{
+					Allowed: false,
+					Result: &metav1.Status{
+						Message: denyMsg,
+					},
+				}
+			}
+			return &admissionv1.AdmissionResponse{
+				Allowed: true,
+			}
+		}
+		if r.allowFinalMetadata && isMetadataOnlyUpdate(oldObj, newObj) {
+			return &admissionv1.AdmissionResponse{
+				Allowed: true,
+			}
+		}
 		return &admissionv1.AdmissionResponse{
 			Allowed: false,
 			Result: &metav1.Status{
@@ -139,9 +365,372 @@ func (r *reconciler) Admit(ctx context.Context, request *admissionv1.AdmissionRe
 			},
 		}
 	}
+	return nil
+}
+
+// interceptAdministrative decides on the changes made by the controller, the
+// privileged group and the creator of the task rather than by its approvers.
+func (r *reconciler) interceptAdministrative(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse {
+	oldObj, newObj, request := d.Old, d.New, d.Request
+
+	if r.isApprovalsRequiredBackfill(oldObj, newObj, request) || r.isApproverChangeReset(oldObj, newObj, request) {
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+
+	if oldObj.Annotations[v1alpha1.CreatedByAnnotationKey] != newObj.Annotations[v1alpha1.CreatedByAnnotationKey] {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "The creator of an ApprovalTask cannot be changed",
+			},
+		}
+	}
+
+	// Tasks sharing a correlation ID must be approved by different users
+	if correlation.Of(oldObj) != correlation.Of(newObj) {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "The correlation ID of an ApprovalTask cannot be changed",
+			},
+		}
+	}
+
+	if oldObj.Spec.ExpectedDigest != newObj.Spec.ExpectedDigest {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "The expected digest of an ApprovalTask cannot be changed",
+			},
+		}
+	}
+
+	if oldObj.Spec.RequireDigestAcknowledgment != newObj.Spec.RequireDigestAcknowledgment {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "The digest acknowledgment requirement of an ApprovalTask cannot be changed",
+			},
+		}
+	}
+
+	if oldObj.Spec.ChangeTicket != newObj.Spec.ChangeTicket ||
+		oldObj.Annotations[v1alpha1.ChangeTicketAnnotationKey] != newObj.Annotations[v1alpha1.ChangeTicketAnnotationKey] {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "The change ticket of an ApprovalTask cannot be changed",
+			},
+		}
+	}
+
+	if oldObj.Spec.MixedResolution != newObj.Spec.MixedResolution {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "The mixed resolution of an ApprovalTask cannot be changed",
+			},
+		}
+	}
+
+	if oldObj.Spec.MinApproverLevel != newObj.Spec.MinApproverLevel {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "The minimum approver level of an ApprovalTask cannot be changed",
+			},
+		}
+	}
+
+	if oldObj.Spec.MinApprovingTeams != newObj.Spec.MinApprovingTeams {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "The minimum number of approving teams of an ApprovalTask cannot be changed",
+			},
+		}
+	}
+
+	if !reflect.DeepEqual(oldObj.Spec.QuorumSchedule, newObj.Spec.QuorumSchedule) {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "The quorum schedule of an ApprovalTask cannot be changed",
+			},
+		}
+	}
+
+	if oldObj.Spec.MaxApprovalsPerGroup != newObj.Spec.MaxApprovalsPerGroup {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "The maximum approvals per group of an ApprovalTask cannot be changed",
+			},
+		}
+	}
+
+	if oldObj.Spec.ApprovalPercentage != newObj.Spec.ApprovalPercentage {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "The approval percentage of an ApprovalTask cannot be changed",
+			},
+		}
+	}
+
+	if oldObj.Spec.EscrowGroup != newObj.Spec.EscrowGroup {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "The escrow group of an ApprovalTask cannot be changed",
+			},
+		}
+	}
+
+	if !reflect.DeepEqual(oldObj.Spec.MinReviewDuration, newObj.Spec.MinReviewDuration) {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "The minimum review duration of an ApprovalTask cannot be changed",
+			},
+		}
+	}
+
+	if oldObj.Spec.ApproverChangePolicy != newObj.Spec.ApproverChangePolicy {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "The approver change policy of an ApprovalTask cannot be changed",
+			},
+		}
+	}
+
+	if oldObj.Spec.CountRequesterApproval != newObj.Spec.CountRequesterApproval {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "Whether the requester's approval counts cannot be changed",
+			},
+		}
+	}
+
+	if !reflect.DeepEqual(oldObj.Spec.RejectionReversalWindow, newObj.Spec.RejectionReversalWindow) {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "The rejection reversal window of an ApprovalTask cannot be changed",
+			},
+		}
+	}
+
+	if rolesChanged(oldObj, newObj) {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "The roles of an ApprovalTask cannot be changed",
+			},
+		}
+	}
+
+	if lifecycleChanged(oldObj, newObj) {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "The escalation and the maximum pending lifetime of an ApprovalTask cannot be changed",
+			},
+		}
+	}
+
+	// Pausing and unpausing is reserved to the privileged group
+	if oldObj.Spec.Paused != newObj.Spec.Paused {
+		if denyMsg := r.validatePauseChange(oldObj, newObj, request); denyMsg != "" {
+			return &admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Message: denyMsg,
+				},
+			}
+		}
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+
+	// So is the blocklist, the kill-switch for compromised accounts
+	if !reflect.DeepEqual(oldObj.Spec.Blocklist, newObj.Spec.Blocklist) {
+		if denyMsg := r.validateBlocklistChange(oldObj, newObj, request); denyMsg != "" {
+			return &admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Message: denyMsg,
+				},
+			}
+		}
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+
+	// And so is who the approvers are
+	if approverSetChanged(oldObj, newObj) {
+		if denyMsg := r.validateApproverSetChange(oldObj, newObj, request); denyMsg != "" {
+			return &admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Message: denyMsg,
+				},
+			}
+		}
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+
+	// Labels deciding which approvers are required are administrative too
+	if conditionLabelsChanged(oldObj, newObj) {
+		if denyMsg := r.validateConditionLabelChange(oldObj, newObj, request); denyMsg != "" {
+			return &admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Message: denyMsg,
+				},
+			}
+		}
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+
+	if oldObj.Spec.Paused {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: "approvals are paused for this task",
+			},
+		}
+	}
+
+	// Withdrawing is done by the creator, who need not be an approver
+	if oldObj.Spec.RequesterInput != newObj.Spec.RequesterInput {
+		if denyMsg := validateWithdrawal(oldObj, newObj, request); denyMsg != "" {
+			return &admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result: &metav1.Status{
+					Message: denyMsg,
+				},
+			}
+		}
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+
+	if approver, changed := inactiveApproverChanged(oldObj, newObj); changed {
+		message := fmt.Sprintf("Approver '%s' is not required for this task: its whenLabels do not match the task labels", approver.Name)
+		if approver.Backup && !approval.Escalated(*oldObj) {
+			message = fmt.Sprintf("Approver '%s' is a backup approver: it cannot decide until the task escalates", approver.Name)
+		}
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: message,
+			},
+		}
+	}
+	return nil
+}
+
+// interceptIdentity denies decisions from identities that may not decide,
+// whatever lists they are on.
+func (r *reconciler) interceptIdentity(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse {
+	oldObj, request := d.Old, d.Request
+
+	// Blocklisted identities never decide, whatever lists they are on
+	if response := r.denyBlocklisted(oldObj, request); response != nil {
+		return response
+	}
+
+	// Only identities carrying the required claim may decide, whatever lists they are on
+	if claim, ok := r.missingClaim(request.UserInfo); ok {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: fmt.Sprintf("User lacks the required '%s' claim", claim),
+			},
+		}
+	}
+
+	// Sensitive gates only take decisions from recently authenticated sessions
+	if r.staleSession(request.UserInfo, r.now()) {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: fmt.Sprintf("User session is older than %s, log in again to decide", r.maxTokenAge),
+			},
+		}
+	}
+
+	// Decisions are only accepted from the allowed networks
+	if denyMsg := r.outsideSourceRanges(request.UserInfo); denyMsg != "" {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: denyMsg,
+			},
+		}
+	}
+
+	// Only the permitted identities decide through impersonation
+	if denyMsg := r.forbiddenImpersonation(request.UserInfo); denyMsg != "" {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: denyMsg,
+			},
+		}
+	}
+	return nil
+}
+
+// interceptExistence denies decisions from users who are not approvers of the
+// task. The interceptors after it see the request with the groups of the
+// user resolved, and the tasks with the approvers on duty substituted.
+func (r *reconciler) interceptExistence(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse {
+	oldObj, newObj, request := d.Old, d.New, d.Request
+
+	request = r.approverRequest(request)
+
+	// Approvers with substitutes decide through whoever is currently on duty
+	oldObj, newObj = r.resolveSubstitutes(ctx, oldObj), r.resolveSubstitutes(ctx, newObj)
+
+	approvers := r.effectiveApprovers(ctx, oldObj)
+	resolved, err := r.resolveGroups(ctx, request, approvers)
+	if errors.Is(err, errResolutionsSaturated) {
+		logging.FromContext(ctx).Warnf("Unable to verify group membership: %v", err)
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Code:    http.StatusTooManyRequests,
+				Message: webhookBusyMsg,
+			},
+		}
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warnf("Unable to verify group membership: %v", err)
+		r.decisions.groupResolutionFailed(ctx, request)
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: groupMembershipUnverifiedMsg,
+			},
+		}
+	}
+	request = resolved
 
 	// Check if username is mentioned in the approval task
-	if !ifUserExists(oldObj.Spec.Approvers, request) {
+	if !ifUserExists(approvers, request) {
 		return &admissionv1.AdmissionResponse{
 			Allowed: false,
 			Result: &metav1.Status{
@@ -150,9 +739,26 @@ func (r *reconciler) Admit(ctx context.Context, request *admissionv1.AdmissionRe
 		}
 	}
 
-	// Check if user is updating the input for his name only
-	var userApprovalChanged bool
-	errMsg := fmt.Errorf("User can only update their own approval input")
+	d.Request, d.Old, d.New = request, oldObj, newObj
+	return nil
+}
+
+// interceptRenewal admits the renewal of an expiring approval.
+func (r *reconciler) interceptRenewal(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse {
+	oldObj, newObj, request := d.Old, d.New, d.Request
+
+	// Renewing an expiring approval only bumps the user's own renewTime
+	if isApprovalRenewal(oldObj, newObj, request) {
+		return &admissionv1.AdmissionResponse{
+			Allowed: true,
+		}
+	}
+	return nil
+}
+
+// interceptDecisionRules denies decisions breaking the rules of the task.
+func (r *reconciler) interceptDecisionRules(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse {
+	oldObj, newObj, request := d.Old, d.New, d.Request
 
 	// First check if user is trying to re-approve/re-reject their own already-decided task
 	if alreadyDecidedMsg := checkIfUserAlreadyDecided(oldObj, newObj, request); alreadyDecidedMsg != "" {
@@ -164,15 +770,125 @@ func (r *reconciler) Admit(ctx context.Context, request *admissionv1.AdmissionRe
 		}
 	}
 
-	changed, err := IsUserApprovalChanged(oldObj.Spec.Approvers, newObj.Spec.Approvers, request)
+	// Approvals must be for the artifact the task was created for
+	if denyMsg := validateDigest(oldObj, newObj); denyMsg != "" {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: denyMsg,
+			},
+		}
+	}
+
+	// Approvers must have reviewed that artifact
+	if denyMsg := validateDigestAcknowledgments(oldObj, newObj); denyMsg != "" {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: denyMsg,
+			},
+		}
+	}
+
+	// Approvals wait for the mandatory review period of the task
+	if denyMsg := r.validateMinReviewDuration(oldObj, newObj); denyMsg != "" {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: denyMsg,
+			},
+		}
+	}
+
+	// Known-broken changes cannot be approved
+	if denyMsg := r.validateChecks(oldObj, newObj); denyMsg != "" {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: denyMsg,
+			},
+		}
+	}
+
+	// Approvals are tied to a tracked change
+	if denyMsg := r.validateChangeTicket(oldObj, newObj); denyMsg != "" {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: denyMsg,
+			},
+		}
+	}
+
+	// Substitutes are recorded along with the decision they make
+	if denyMsg := validateDelegation(oldObj, newObj); denyMsg != "" {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: denyMsg,
+			},
+		}
+	}
+
+	if denyMsg := validateApproverTeams(oldObj, newObj, request); denyMsg != "" {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: denyMsg,
+			},
+		}
+	}
+
+	if denyMsg := validateApproverLevels(oldObj, newObj, request); denyMsg != "" {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: denyMsg,
+			},
+		}
+	}
+
+	if denyMsg := r.validateApprovalOrder(oldObj, newObj); denyMsg != "" {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: denyMsg,
+			},
+		}
+	}
+
+	// Linked tasks need a different approver each
+	if denyMsg := r.validateLinkedApprovals(ctx, oldObj, newObj, request); denyMsg != "" {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Message: denyMsg,
+			},
+		}
+	}
+	return nil
+}
+
+// interceptForeignChange admits the update when it only records the decision
+// of the user, and denies it otherwise. It always decides.
+func (r *reconciler) interceptForeignChange(ctx context.Context, d *Decision) *admissionv1.AdmissionResponse {
+	oldObj, newObj, request := d.Old, d.New, d.Request
+
+	// Check if user is updating the input for his name only
+	var userApprovalChanged bool
+	errMsg := fmt.Errorf("User can only update their own approval input")
+
+	changed, err := isUserApprovalChanged(oldObj.Spec.Approvers, newObj.Spec.Approvers, request, !r.forbidGroupSelfAdd)
 	if err != nil {
 		userApprovalChanged = false
 		errMsg = fmt.Errorf("Invalid input change: %v", err)
 	} else if changed {
-		if CheckOtherUsersForInvalidChanges(oldObj.Spec.Approvers, newObj.Spec.Approvers, request) {
+		// The user's own change is only admitted together with the rest of the update
+		if field := foreignChange(oldObj.Spec.Approvers, newObj.Spec.Approvers, request, !r.forbidGroupSelfAdd); field == "" {
 			userApprovalChanged = true
 		} else {
 			userApprovalChanged = false
+			errMsg = fmt.Errorf("User can only update their own approval input: the update also changes %s", field)
 		}
 	} else {
 		userApprovalChanged = false
@@ -188,29 +904,101 @@ func (r *reconciler) Admit(ctx context.Context, request *admissionv1.AdmissionRe
 	}
 
 	return &admissionv1.AdmissionResponse{
-		Allowed: true,
+		Allowed:  true,
+		Warnings: decisionAttribution(oldObj, newObj, request, r.now()),
+	}
+}
+
+// decisionAttribution tells the user, as admission warnings, which approver
+// entries their decision was recorded on, and whether approvals are held back
+// by approvers of higher priority or, for the requester of the task, not
+// counted at all, as of now.
+func decisionAttribution(oldObj, newObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest, now time.Time) []string {
+	var warnings []string
+	approving := false
+	for i, approver := range newObj.Spec.Approvers {
+		if i >= len(oldObj.Spec.Approvers) {
+			break
+		}
+		oldApprover := oldObj.Spec.Approvers[i]
+
+		var attribution string
+		switch v1alpha1.DefaultedApproverType(approver.Type) {
+		case "User", "Email":
+			if isIndividualApprover(approver.Type, approver.Name, request.UserInfo) && approver.Input != oldApprover.Input {
+				attribution = fmt.Sprintf("counted as %s approver %s", v1alpha1.DefaultedApproverType(approver.Type), approver.Name)
+			}
+		case "Group":
+			if memberInputChanged(oldApprover, approver, request.UserInfo.Username) {
+				attribution = fmt.Sprintf("counted as member of group %s", approver.Name)
+			}
+		}
+		if attribution == "" {
+			continue
+		}
+		warnings = append(warnings, attribution)
+
+		if approver.Input == "approve" {
+			approving = true
+			if blocking, blocked := approval.BlockingApproverAt(*newObj, approver, now); blocked {
+				warnings = append(warnings, fmt.Sprintf("approval through %s does not count until approver %s with priority %d approves", approver.Name, blocking.Name, blocking.Priority))
+			}
+		}
+	}
+	if approving && approval.RequesterExcluded(*newObj, request.UserInfo.Username) {
+		warnings = append(warnings, "you requested this approval: your approval is recorded but does not count towards the quorum")
+	}
+	return warnings
+}
+
+// memberInputChanged reports whether the entry of the user in the users of a
+// Group approver was added or had its input changed.
+func memberInputChanged(oldApprover, newApprover v1alpha1.ApproverDetails, username string) bool {
+	for _, user := range newApprover.Users {
+		if user.Name != username {
+			continue
+		}
+		for _, oldUser := range oldApprover.Users {
+			if oldUser.Name == username {
+				return oldUser.Input != user.Input
+			}
+		}
+		return true
 	}
+	return false
 }
 
-func (ac *reconciler) reconcileValidatingWebhook(ctx context.Context, caCert []byte) error {
+// WebhookResult describes what reconciling the ValidatingWebhookConfiguration
+// of the webhook did. The configuration is installed along with the webhook,
+// so reconciling never creates it.
+type WebhookResult string
+
+const (
+	// WebhookUnchanged means the configuration was already up to date.
+	WebhookUnchanged WebhookResult = "Unchanged"
+	// WebhookUpdated means the CA bundle, rules or service path of the
+	// configuration were written.
+	WebhookUpdated WebhookResult = "Updated"
+	// WebhookFailed means the configuration could not be reconciled, as
+	// told by the accompanying error.
+	WebhookFailed WebhookResult = "Failed"
+)
+
+func (ac *reconciler) reconcileValidatingWebhook(ctx context.Context, caCert []byte) (WebhookResult, error) {
 	logger := logging.FromContext(ctx)
-	rules := []admissionregistrationv1.RuleWithOperations{
-		{
-			Operations: []admissionregistrationv1.OperationType{
-				admissionregistrationv1.Create,
-				admissionregistrationv1.Update,
-			},
-			Rule: admissionregistrationv1.Rule{
-				APIGroups:   []string{"openshift-pipelines.org"},
-				APIVersions: []string{"v1alpha1"},
-				Resources:   []string{"approvaltask", "approvaltasks"},
-			},
-		},
+	rules := ac.rules
+	if len(rules) == 0 {
+		rules = Options{}.rules()
 	}
+	rules = canonicalRules(rules)
 
 	configuredWebhook, err := ac.vwhlister.Get(ac.key.Name)
 	if err != nil {
-		return err
+		return WebhookFailed, err
+	}
+
+	if ac.drift.drifted(configuredWebhook) {
+		reportDrift(ctx, ac.recorder, configuredWebhook)
 	}
 
 	webhook := configuredWebhook.DeepCopy()
@@ -221,31 +1009,95 @@ func (ac *reconciler) reconcileValidatingWebhook(ctx context.Context, caCert []b
 		if wh.Name != webhook.Name {
 			continue
 		}
-		webhook.Webhooks[i].Rules = rules
+		// Rules only differing in their order are left alone
+		if !equality.Semantic.DeepEqual(canonicalRules(wh.Rules), rules) {
+			webhook.Webhooks[i].Rules = rules
+		}
 		webhook.Webhooks[i].ClientConfig.CABundle = caCert
 		if webhook.Webhooks[i].ClientConfig.Service == nil {
-			return fmt.Errorf("missing service reference for webhook: %s", wh.Name)
+			return WebhookFailed, fmt.Errorf("missing service reference for webhook: %s", wh.Name)
+		}
+		webhook.Webhooks[i].ClientConfig.Service.Path = ptr.String(ac.Path())
+	}
+
+	if ok, err := kmp.SafeEqual(configuredWebhook, webhook); err != nil {
+		return WebhookFailed, fmt.Errorf("error diffing webhooks: %w", err)
+	} else if ok {
+		logger.Info("Webhook is valid")
+		ac.drift.observe(configuredWebhook, "")
+		return WebhookUnchanged, nil
+	}
+
+	logger.Info("Updating webhook")
+	vwhclient := ac.client.AdmissionregistrationV1().ValidatingWebhookConfigurations()
+	updated, err := vwhclient.Update(ctx, webhook, metav1.UpdateOptions{})
+	if err != nil {
+		return WebhookFailed, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	// The stored webhooks, as defaulted by the API server
+	ac.drift.observe(updated, configuredWebhook.ResourceVersion)
+	return WebhookUpdated, nil
+}
+
+// Path implements AdmissionController
+func (ac *reconciler) Path() string {
+	return ac.path
+}
+
+// emailExtraKey is the UserInfo extra claim Email approvers are matched against.
+const emailExtraKey = "email"
+
+// isIndividualApprover reports whether the approver is a User approver named
+// after the user, or an Email approver naming one of the emails the identity
+// provider put into the user's extra claims. Emails compare case-insensitively.
+func isIndividualApprover(approverType, name string, userInfo authenticationv1.UserInfo) bool {
+	switch v1alpha1.DefaultedApproverType(approverType) {
+	case "User":
+		return name == userInfo.Username
+	case "Email":
+		for _, email := range userInfo.Extra[emailExtraKey] {
+			if strings.EqualFold(name, email) {
+				return true
+			}
 		}
-		webhook.Webhooks[i].ClientConfig.Service.Path = ptr.String(ac.Path())
 	}
+	return false
+}
 
-	if ok, err := kmp.SafeEqual(configuredWebhook, webhook); err != nil {
-		return fmt.Errorf("error diffing webhooks: %w", err)
-	} else if !ok {
-		logger.Info("Updating webhook")
-		vwhclient := ac.client.AdmissionregistrationV1().ValidatingWebhookConfigurations()
-		if _, err := vwhclient.Update(ctx, webhook, metav1.UpdateOptions{}); err != nil {
-			return fmt.Errorf("failed to update webhook: %w", err)
+// missingClaim returns the claim the user lacks when r.requiredClaim is set
+// and the user's extra claims do not carry it, with r.requiredClaimValue among
+// its values when that is set too.
+func (r *reconciler) missingClaim(userInfo authenticationv1.UserInfo) (string, bool) {
+	if r.requiredClaim == "" {
+		return "", false
+	}
+	values, ok := userInfo.Extra[r.requiredClaim]
+	if !ok || len(values) == 0 {
+		return r.requiredClaim, true
+	}
+	if r.requiredClaimValue == "" {
+		return "", false
+	}
+	for _, value := range values {
+		if value == r.requiredClaimValue {
+			return "", false
 		}
-	} else {
-		logger.Info("Webhook is valid")
 	}
-	return nil
+	return r.requiredClaim + "=" + r.requiredClaimValue, true
 }
 
-// Path implements AdmissionController
-func (ac *reconciler) Path() string {
-	return ac.path
+// approverRequest returns the request the approver checks are run against.
+// When explicit group membership is required, the groups asserted by the
+// user's token are dropped, so that only the users listed in the users of a
+// Group approver count as its members. Otherwise the ancestors of hierarchical
+// groups are added to them.
+func (r *reconciler) approverRequest(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionRequest {
+	if !r.explicitGroupMembers || len(request.UserInfo.Groups) == 0 {
+		return r.withAncestorGroups(request)
+	}
+	explicit := request.DeepCopy()
+	explicit.UserInfo.Groups = nil
+	return explicit
 }
 
 func ifUserExists(approvals []v1alpha1.ApproverDetails, request *admissionv1.AdmissionRequest) bool {
@@ -254,8 +1106,8 @@ func ifUserExists(approvals []v1alpha1.ApproverDetails, request *admissionv1.Adm
 	}
 	for _, approval := range approvals {
 		switch v1alpha1.DefaultedApproverType(approval.Type) {
-		case "User":
-			if approval.Name == request.UserInfo.Username {
+		case "User", "Email":
+			if isIndividualApprover(approval.Type, approval.Name, request.UserInfo) {
 				return true
 			}
 		case "Group":
@@ -278,50 +1130,40 @@ func ifUserExists(approvals []v1alpha1.ApproverDetails, request *admissionv1.Adm
 
 func isApprovalRequired(approvaltask v1alpha1.ApprovalTask) bool {
 	// If the task has reached a final state, no more approvals are needed
-	if approvaltask.Status.State == "rejected" || approvaltask.Status.State == "approved" {
+	if approvaltask.Status.State == "rejected" || approvaltask.Status.State == "approved" || approvaltask.Status.State == "withdrawn" {
 		return false
 	}
-	
-	// Use the same logic as the controller to count approvals
-	approvedUsers := make(map[string]bool)
-	
-	for _, approver := range approvaltask.Spec.Approvers {
-		if approver.Input != "approve" {
-			continue
-		}
-		
-		if v1alpha1.DefaultedApproverType(approver.Type) == "User" {
-			approvedUsers[approver.Name] = true
-		} else if v1alpha1.DefaultedApproverType(approver.Type) == "Group" {
-			for _, user := range approver.Users {
-				if user.Input == "approve" {
-					approvedUsers[user.Name] = true
-				}
-			}
-		}
-	}
-	
-	// If we have enough approvals, the task should be approved (final state)
-	if len(approvedUsers) >= approvaltask.Spec.NumberOfApprovalsRequired {
-		return false
+
+	// Escrowed tasks stay pending until the controller releases the whole
+	// escrow group, even if this task has already reached its own quorum
+	if approvaltask.Spec.EscrowGroup != "" {
+		return true
 	}
 	
-	return true
+	// Use the same logic as the controller to count approvals
+	// If we have enough approvals, covering every required role, the task
+	// should be approved (final state)
+	return !approval.QuorumReached(approvaltask)
 }
 
-// hasValidInputValue checks if the input value is either "approve" or "reject".
-func hasValidInputValue(input string) error {
-	if input == "approve" || input == "reject" {
-		return nil
+// hasValidInputValue checks if the input value is "approve", "reject" or
+// "request-changes", and that it is one of the inputs the approver is allowed
+// to submit.
+func hasValidInputValue(approver v1alpha1.ApproverDetails, input string) error {
+	if input != "approve" && input != "reject" && input != "request-changes" {
+		return fmt.Errorf("invalid input value: '%s'. Supported values are 'approve', 'reject' or 'request-changes'", input)
+	}
+	if len(approver.AllowedInputs) > 0 && !webhookContains(approver.AllowedInputs, input) {
+		return fmt.Errorf("input value '%s' is not allowed for approver '%s'. Allowed values are: %s", input, approver.Name, strings.Join(approver.AllowedInputs, ", "))
 	}
-	return fmt.Errorf("invalid input value: '%s'. Supported values are 'approve' or 'reject'", input)
+	return nil
 }
 
 // hasOnlyInputChanged checks if only the input field has changed for the current approver
 // and if the new input value is valid
 func hasOnlyInputChanged(oldObjApprover, newObjApprover v1alpha1.ApproverDetails) (bool, error) {
 	if oldObjApprover.Name == newObjApprover.Name && oldObjApprover.Input != newObjApprover.Input {
-		if err := hasValidInputValue(newObjApprover.Input); err != nil {
+		if err := hasValidInputValue(oldObjApprover, newObjApprover.Input); err != nil {
 			return false, err
 		}
 		return true, nil
@@ -331,9 +1173,16 @@ func hasOnlyInputChanged(oldObjApprover, newObjApprover v1alpha1.ApproverDetails
 
 // IsUserApprovalChanged checks if there is a valid input change for the current user.
 func IsUserApprovalChanged(oldObjApprovers, newObjApprovers []v1alpha1.ApproverDetails, request *admissionv1.AdmissionRequest) (bool, error) {
+	return isUserApprovalChanged(oldObjApprovers, newObjApprovers, request, true)
+}
+
+// isUserApprovalChanged is IsUserApprovalChanged, only letting members of a
+// group asserted by their token add themselves to its users when selfAdd is
+// set.
+func isUserApprovalChanged(oldObjApprovers, newObjApprovers []v1alpha1.ApproverDetails, request *admissionv1.AdmissionRequest, selfAdd bool) (bool, error) {
 	currentUser := request.UserInfo.Username
 	for i, approver := range oldObjApprovers {
-		if approver.Name == currentUser && v1alpha1.DefaultedApproverType(approver.Type) == "User" {
+		if isIndividualApprover(approver.Type, approver.Name, request.UserInfo) {
 			return hasOnlyInputChanged(approver, newObjApprovers[i])
 		}
 
@@ -358,10 +1207,14 @@ func IsUserApprovalChanged(oldObjApprovers, newObjApprovers []v1alpha1.ApproverD
 			}
 
 			if isUserInGroup {
+				if !selfAdd && addsSelf(approver, newObjApprovers, i, currentUser) {
+					return false, fmt.Errorf("users cannot add themselves to group '%s', its users are managed by administrators", approver.Name)
+				}
+
 				// Allow changes to group-level input if user is in the group
 				if i < len(newObjApprovers) {
 					if approver.Input != newObjApprovers[i].Input {
-						if err := hasValidInputValue(newObjApprovers[i].Input); err != nil {
+						if err := hasValidInputValue(approver, newObjApprovers[i].Input); err != nil {
 							return false, err
 						}
 						return true, nil
@@ -394,7 +1247,7 @@ func IsUserApprovalChanged(oldObjApprovers, newObjApprovers []v1alpha1.ApproverD
 					if i < len(newObjApprovers) {
 						for _, user := range newObjApprovers[i].Users {
 							if user.Name == currentUser {
-								if err := hasValidInputValue(user.Input); err != nil {
+								if err := hasValidInputValue(approver, user.Input); err != nil {
 									return false, err
 								}
 								return true, nil
@@ -431,7 +1284,7 @@ func IsUserApprovalChanged(oldObjApprovers, newObjApprovers []v1alpha1.ApproverD
 
 				// Allow user to change their input if they're in both old and new lists
 				if userFoundInOld && userFoundInNew && oldUserInput != newUserInput {
-					if err := hasValidInputValue(newUserInput); err != nil {
+					if err := hasValidInputValue(approver, newUserInput); err != nil {
 						return false, err
 					}
 					return true, nil
@@ -451,7 +1304,7 @@ func checkIfUserAlreadyDecided(oldObj *v1alpha1.ApprovalTask, newObj *v1alpha1.A
 	
 	// First check if user is an individual approver
 	for _, approver := range newObj.Spec.Approvers {
-		if v1alpha1.DefaultedApproverType(approver.Type) == "User" && approver.Name == currentUser {
+		if isIndividualApprover(approver.Type, approver.Name, request.UserInfo) {
 			desiredInput = approver.Input
 			break
 		}
@@ -488,7 +1341,7 @@ func checkIfUserAlreadyDecided(oldObj *v1alpha1.ApprovalTask, newObj *v1alpha1.A
 	
 	// Check status.approversResponse to see if user has already made a decision
 	for _, approverResponse := range oldObj.Status.ApproversResponse {
-		if v1alpha1.DefaultedApproverType(approverResponse.Type) == "User" && approverResponse.Name == currentUser {
+		if isIndividualApprover(approverResponse.Type, approverResponse.Name, request.UserInfo) {
 			// Block duplicate approvals and any action after rejection
 			if approverResponse.Response == "approved" && desiredInput == "approve" {
 				return "User has already approved"
@@ -515,13 +1368,245 @@ func checkIfUserAlreadyDecided(oldObj *v1alpha1.ApprovalTask, newObj *v1alpha1.A
 	return "" // No issue found
 }
 
+// validatePauseChange checks that only members of the privileged group pause
+// or unpause a task, and that nothing else is changed along the way. It
+// returns the denial message, or an empty string if the change is allowed.
+func (r *reconciler) validatePauseChange(oldObj, newObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest) string {
+	if r.privilegedGroup == "" || !webhookContains(request.UserInfo.Groups, r.privilegedGroup) {
+		return "Only members of the privileged group can pause or unpause an approval task"
+	}
+
+	toggled := newObj.Spec.DeepCopy()
+	toggled.Paused = oldObj.Spec.Paused
+	if !reflect.DeepEqual(oldObj.Spec, *toggled) {
+		return "Pausing or unpausing an approval task cannot change any other field"
+	}
+	return ""
+}
+
+// conditionLabelsChanged reports whether the update changes a label that the
+// whenLabels of an approver refer to.
+func conditionLabelsChanged(oldObj, newObj *v1alpha1.ApprovalTask) bool {
+	for _, approver := range oldObj.Spec.Approvers {
+		for key := range approver.WhenLabels {
+			oldValue, oldOk := oldObj.Labels[key]
+			newValue, newOk := newObj.Labels[key]
+			if oldOk != newOk || oldValue != newValue {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateConditionLabelChange checks that labels approvers are conditional on
+// are only changed by the privileged group, on their own. It returns the
+// denial message, or an empty string if the change is allowed.
+func (r *reconciler) validateConditionLabelChange(oldObj, newObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest) string {
+	if r.privilegedGroup == "" || !webhookContains(request.UserInfo.Groups, r.privilegedGroup) {
+		return "Only members of the privileged group can change the labels approvers are conditional on"
+	}
+	if !isMetadataOnlyUpdate(oldObj, newObj) {
+		return "Changing the labels approvers are conditional on cannot change any other field"
+	}
+	return ""
+}
+
+// inactiveApproverChanged returns the first approver that is not active on
+// the task (see approval.ApproverActive) and that the update changes, e.g. by
+// deciding on its behalf.
+func inactiveApproverChanged(oldObj, newObj *v1alpha1.ApprovalTask) (v1alpha1.ApproverDetails, bool) {
+	for i, approver := range oldObj.Spec.Approvers {
+		if i >= len(newObj.Spec.Approvers) || approval.ApproverActive(*oldObj, approver) {
+			continue
+		}
+		if !reflect.DeepEqual(approver, newObj.Spec.Approvers[i]) {
+			return approver, true
+		}
+	}
+	return v1alpha1.ApproverDetails{}, false
+}
+
+// validateWithdrawal checks that only the creator of a pending task withdraws
+// it, and that nothing else is changed along the way. It returns the denial
+// message, or an empty string if the withdrawal is allowed.
+func validateWithdrawal(oldObj, newObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest) string {
+	createdBy := oldObj.Annotations[v1alpha1.CreatedByAnnotationKey]
+	if createdBy == "" || createdBy != request.UserInfo.Username {
+		return "Only the creator of the approval task can withdraw it"
+	}
+	if oldObj.Status.State != "pending" {
+		return "ApprovalTask can only be withdrawn while it is pending"
+	}
+
+	withdrawn := newObj.Spec.DeepCopy()
+	withdrawn.RequesterInput = oldObj.Spec.RequesterInput
+	if !reflect.DeepEqual(oldObj.Spec, *withdrawn) {
+		return "Withdrawing an approval task cannot change any other field"
+	}
+	return ""
+}
+
+// validateDigest denies updates submitting an approval while the task claims,
+// before or after the update, a current digest other than the expected one.
+// Rejections are always allowed. It returns the denial message, or an empty
+// string if the update is allowed.
+func validateDigest(oldObj, newObj *v1alpha1.ApprovalTask) string {
+	expected := oldObj.Spec.ExpectedDigest
+	if expected == "" || !addsApproval(oldObj.Spec.Approvers, newObj.Spec.Approvers) {
+		return ""
+	}
+	for _, obj := range []*v1alpha1.ApprovalTask{oldObj, newObj} {
+		if current, ok := obj.Annotations[v1alpha1.CurrentDigestAnnotationKey]; ok && current != expected {
+			return fmt.Sprintf("Cannot approve: the current digest '%s' does not match the expected digest '%s'", current, expected)
+		}
+	}
+	return ""
+}
+
+// isMetadataOnlyUpdate reports whether the update leaves the spec and status
+// untouched. The creator annotation is not metadata in this sense: it decides
+// who may withdraw the task and can never change.
+func isMetadataOnlyUpdate(oldObj, newObj *v1alpha1.ApprovalTask) bool {
+	return reflect.DeepEqual(oldObj.Spec, newObj.Spec) &&
+		reflect.DeepEqual(oldObj.Status, newObj.Status) &&
+		oldObj.Annotations[v1alpha1.CreatedByAnnotationKey] == newObj.Annotations[v1alpha1.CreatedByAnnotationKey]
+}
+
+// validateApprovalOrder denies, when approval order is enforced, updates
+// approving on behalf of an approver that ranks behind one that has not
+// approved yet.
+func (r *reconciler) validateApprovalOrder(oldObj, newObj *v1alpha1.ApprovalTask) string {
+	if !r.enforceApprovalOrder {
+		return ""
+	}
+	now := r.now()
+	for i, approver := range newObj.Spec.Approvers {
+		if i >= len(oldObj.Spec.Approvers) || !addsApproval(oldObj.Spec.Approvers[i:i+1], newObj.Spec.Approvers[i:i+1]) {
+			continue
+		}
+		if blocking, blocked := approval.BlockingApproverAt(*newObj, approver, now); blocked {
+			return fmt.Sprintf("Cannot approve yet: approver '%s' with priority %d must approve first", blocking.Name, blocking.Priority)
+		}
+	}
+	return ""
+}
+
+// addsApproval reports whether the update sets an approver, or a group member,
+// input to "approve".
+func addsApproval(oldObjApprovers, newObjApprovers []v1alpha1.ApproverDetails) bool {
+	for i, approver := range newObjApprovers {
+		oldInput := ""
+		oldUsers := map[string]string{}
+		if i < len(oldObjApprovers) {
+			oldInput = oldObjApprovers[i].Input
+			for _, user := range oldObjApprovers[i].Users {
+				oldUsers[user.Name] = user.Input
+			}
+		}
+		if approver.Input == "approve" && oldInput != "approve" {
+			return true
+		}
+		for _, user := range approver.Users {
+			if user.Input == "approve" && oldUsers[user.Name] != "approve" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isApprovalRenewal reports whether the update does nothing but move the
+// renewTime of the current user's own approval forward, for an approver whose
+// approvals expire.
+func isApprovalRenewal(oldObj, newObj *v1alpha1.ApprovalTask, request *admissionv1.AdmissionRequest) bool {
+	currentUser := request.UserInfo.Username
+	if len(oldObj.Spec.Approvers) != len(newObj.Spec.Approvers) {
+		return false
+	}
+
+	renewed := newObj.Spec.DeepCopy()
+	renewals := 0
+	for i, approver := range oldObj.Spec.Approvers {
+		if approver.ApprovalExpiresAfter == nil {
+			continue
+		}
+		newApprover := &renewed.Approvers[i]
+		switch v1alpha1.DefaultedApproverType(approver.Type) {
+		case "User", "Email":
+			if isIndividualApprover(approver.Type, approver.Name, request.UserInfo) && approver.Input == "approve" && renewTimeAdvanced(approver.RenewTime, newApprover.RenewTime) {
+				newApprover.RenewTime = approver.RenewTime
+				renewals++
+			}
+		case "Group":
+			if len(approver.Users) != len(newApprover.Users) {
+				return false
+			}
+			for j, user := range approver.Users {
+				if user.Name == currentUser && user.Input == "approve" && renewTimeAdvanced(user.RenewTime, newApprover.Users[j].RenewTime) {
+					newApprover.Users[j].RenewTime = user.RenewTime
+					renewals++
+				}
+			}
+		}
+	}
+
+	// Once the renewals are undone nothing else may differ
+	return renewals > 0 && reflect.DeepEqual(oldObj.Spec, *renewed)
+}
+
+// renewTimeAdvanced reports whether a renewTime was set or moved forward.
+func renewTimeAdvanced(oldTime, newTime *metav1.Time) bool {
+	if newTime == nil {
+		return false
+	}
+	return oldTime == nil || newTime.After(oldTime.Time)
+}
+
 // CheckOtherUsersForInvalidChanges validates that no other approvers inputs have been changed
 func CheckOtherUsersForInvalidChanges(oldObjApprovers, newObjApprover []v1alpha1.ApproverDetails, request *admissionv1.AdmissionRequest) bool {
+	return foreignChange(oldObjApprovers, newObjApprover, request, true) == ""
+}
+
+// addsSelf reports whether the update lists username in the users of the
+// Group approver at index i when it did not before.
+func addsSelf(approver v1alpha1.ApproverDetails, newObjApprovers []v1alpha1.ApproverDetails, i int, username string) bool {
+	if i >= len(newObjApprovers) {
+		return false
+	}
+	for _, user := range approver.Users {
+		if user.Name == username {
+			return false
+		}
+	}
+	for _, user := range newObjApprovers[i].Users {
+		if user.Name == username {
+			return true
+		}
+	}
+	return false
+}
+
+// foreignChange returns the path of the first field of another approver that
+// the update changes, such as "approvers[1].input", or "" when the update
+// only touches what belongs to the requesting user. The user adding
+// themselves to the users of a Group approver counts as a foreign change
+// unless selfAdd is set.
+func foreignChange(oldObjApprovers, newObjApprover []v1alpha1.ApproverDetails, request *admissionv1.AdmissionRequest, selfAdd bool) string {
 	currentUser := request.UserInfo.Username
 	for i, approver := range oldObjApprovers {
-		if v1alpha1.DefaultedApproverType(approver.Type) == "User" && approver.Name != currentUser {
+		if v1alpha1.IsIndividualApproverType(approver.Type) && !isIndividualApprover(approver.Type, approver.Name, request.UserInfo) {
 			if oldObjApprovers[i].Input != newObjApprover[i].Input {
-				return false
+				return fmt.Sprintf("approvers[%d].input", i)
+			}
+			if !oldObjApprovers[i].RenewTime.Equal(newObjApprover[i].RenewTime) {
+				return fmt.Sprintf("approvers[%d].renewTime", i) // Someone else's approval was renewed
+			}
+			if oldObjApprovers[i].DecidedBy != newObjApprover[i].DecidedBy {
+				return fmt.Sprintf("approvers[%d].decidedBy", i)
+			}
+			if oldObjApprovers[i].AcknowledgedDigest != newObjApprover[i].AcknowledgedDigest {
+				return fmt.Sprintf("approvers[%d].acknowledgedDigest", i)
 			}
 		}
 
@@ -548,52 +1633,57 @@ func CheckOtherUsersForInvalidChanges(oldObjApprovers, newObjApprover []v1alpha1
 			// If current user is not in this group, they shouldn't be able to change the group-level input
 			if !isUserInGroup {
 				if i < len(newObjApprover) && approver.Input != newObjApprover[i].Input {
-					return false
+					return fmt.Sprintf("approvers[%d].input", i)
 				}
 			}
 
 			// Check that only current user's input has changed in group users
 			// Build maps of existing users for easier comparison
-			oldUsers := make(map[string]string) // name -> input
-			newUsers := make(map[string]string) // name -> input
+			oldUsers := make(map[string]v1alpha1.UserDetails)
+			newUsers := make(map[string]v1alpha1.UserDetails)
 
 			for _, user := range approver.Users {
-				oldUsers[user.Name] = user.Input
+				oldUsers[user.Name] = user
 			}
 
+			var addedUsers []v1alpha1.UserDetails
 			if i < len(newObjApprover) {
 				for _, user := range newObjApprover[i].Users {
-					newUsers[user.Name] = user.Input
+					newUsers[user.Name] = user
+					if _, existedBefore := oldUsers[user.Name]; !existedBefore {
+						addedUsers = append(addedUsers, user)
+					}
 				}
 			}
 
 			// Check that existing users (other than current user) haven't changed their input
-			for userName, oldInput := range oldUsers {
-				if userName != currentUser {
-					if newInput, exists := newUsers[userName]; exists {
-						if oldInput != newInput {
-							return false // Someone else's input changed
+			for j, oldUser := range approver.Users {
+				if oldUser.Name != currentUser {
+					if newUser, exists := newUsers[oldUser.Name]; exists {
+						if oldUser.Input != newUser.Input {
+							return fmt.Sprintf("approvers[%d].users[%d].input", i, j) // Someone else's input changed
+						}
+						if !oldUser.RenewTime.Equal(newUser.RenewTime) {
+							return fmt.Sprintf("approvers[%d].users[%d].renewTime", i, j) // Someone else's approval was renewed
+						}
+						if oldUser.AcknowledgedDigest != newUser.AcknowledgedDigest {
+							return fmt.Sprintf("approvers[%d].users[%d].acknowledgedDigest", i, j)
 						}
 					}
 				}
 			}
 
 			// Check that no unauthorized users were added to the group
-			for userName := range newUsers {
-				if _, existedBefore := oldUsers[userName]; !existedBefore {
-					// Someone new was added - only allow if it's the current user and they're a group member
-					if userName != currentUser {
-						return false // Someone other than current user was added
-					}
-					if !isUserInGroup {
-						return false // Current user is not a member of this group
-					}
+			for _, user := range addedUsers {
+				// Someone new was added - only allow if it's the current user and they're a group member
+				if user.Name != currentUser || !isUserInGroup || !selfAdd {
+					return fmt.Sprintf("approvers[%d].users", i)
 				}
 			}
 		}
 	}
 
-	return true
+	return ""
 }
 
 // validateApprovalTask validates the complete ApprovalTask resource 
@@ -606,13 +1696,96 @@ func validateApprovalTask(approvalTask *v1alpha1.ApprovalTask, ctx context.Conte
 	return nil
 }
 
+// validateApproverCount denies specs with more approvers than the configured
+// maximum. On update only growing lists are denied, so tasks created before
+// the limit was lowered can still be decided.
+func (r *reconciler) validateApproverCount(oldObj, newObj *v1alpha1.ApprovalTask) error {
+	if r.maxApprovers <= 0 {
+		return nil
+	}
+	count := countApprovers(newObj.Spec.Approvers)
+	if count <= r.maxApprovers {
+		return nil
+	}
+	if oldObj != nil && count <= countApprovers(oldObj.Spec.Approvers) {
+		return nil
+	}
+	return fmt.Errorf("approvers: %d approvers and group members exceed the maximum of %d", count, r.maxApprovers)
+}
+
+// countApprovers counts approver entries along with the members listed in
+// the users of Group approvers.
+func countApprovers(approvers []v1alpha1.ApproverDetails) int {
+	count := len(approvers)
+	for _, approver := range approvers {
+		count += len(approver.Users)
+	}
+	return count
+}
+
 // validateApprovalTaskSpec validates the ApprovalTaskSpec
 func validateApprovalTaskSpec(spec *v1alpha1.ApprovalTaskSpec, ctx context.Context) error {
-	// Validate numberOfApprovalsRequired bounds
-	if spec.NumberOfApprovalsRequired <= 0 {
+	// Validate numberOfApprovalsRequired bounds, or approvalPercentage instead
+	if spec.ApprovalPercentage != 0 {
+		if spec.ApprovalPercentage < 1 || spec.ApprovalPercentage > 100 {
+			return fmt.Errorf("approvalPercentage: must be between 1 and 100, got %d", spec.ApprovalPercentage)
+		}
+		if spec.NumberOfApprovalsRequired != 0 {
+			return fmt.Errorf("approvalPercentage: cannot be combined with numberOfApprovalsRequired")
+		}
+		if len(spec.QuorumSchedule) > 0 {
+			return fmt.Errorf("quorumSchedule: cannot be combined with approvalPercentage")
+		}
+	} else if spec.NumberOfApprovalsRequired <= 0 {
 		return fmt.Errorf("numberOfApprovalsRequired: must be greater than 0, got %d", spec.NumberOfApprovalsRequired)
 	}
 
+	if spec.MaxApprovalsPerGroup < 0 {
+		return fmt.Errorf("maxApprovalsPerGroup: must not be negative, got %d", spec.MaxApprovalsPerGroup)
+	}
+
+	if spec.MixedResolution != "" && !webhookContains(v1alpha1.KnownMixedResolutions, spec.MixedResolution) {
+		return fmt.Errorf("mixedResolution: must be one of %s, got '%s'", quotedList(v1alpha1.KnownMixedResolutions), spec.MixedResolution)
+	}
+
+	for i, identity := range spec.Blocklist {
+		if strings.TrimSpace(identity) == "" {
+			return fmt.Errorf("blocklist[%d]: cannot be empty", i)
+		}
+	}
+
+	if spec.ApproverChangePolicy != "" && !webhookContains(v1alpha1.KnownApproverChangePolicies, spec.ApproverChangePolicy) {
+		return fmt.Errorf("approverChangePolicy: must be one of %s, got '%s'", quotedList(v1alpha1.KnownApproverChangePolicies), spec.ApproverChangePolicy)
+	}
+
+	if spec.MinReviewDuration != nil && spec.MinReviewDuration.Duration < 0 {
+		return fmt.Errorf("minReviewDuration: must not be negative, got %s", spec.MinReviewDuration.Duration)
+	}
+
+	if spec.RejectionReversalWindow != nil && spec.RejectionReversalWindow.Duration < 0 {
+		return fmt.Errorf("rejectionReversalWindow: must not be negative, got %s", spec.RejectionReversalWindow.Duration)
+	}
+
+	if spec.RequireDigestAcknowledgment && spec.ExpectedDigest == "" {
+		return fmt.Errorf("requireDigestAcknowledgment: requires expectedDigest to be set")
+	}
+
+	if err := validateQuorumSchedule(spec); err != nil {
+		return err
+	}
+
+	if err := validateRequiredRoles(spec); err != nil {
+		return err
+	}
+
+	if err := validateLifecycle(spec); err != nil {
+		return err
+	}
+
+	if spec.RequesterInput != "" && spec.RequesterInput != "withdraw" {
+		return fmt.Errorf("requesterInput: must be 'withdraw' when set, got '%s'", spec.RequesterInput)
+	}
+
 	// Validate approvers list
 	if len(spec.Approvers) == 0 {
 		return fmt.Errorf("approvers: required field is missing")
@@ -623,27 +1796,60 @@ func validateApprovalTaskSpec(spec *v1alpha1.ApprovalTaskSpec, ctx context.Conte
 	for i, approver := range spec.Approvers {
 		fieldPath := fmt.Sprintf("approvers[%d]", i)
 		
-		if err := validateApprover(approver, fieldPath); err != nil {
+		if err := validateApproverIn(ctx, approver, fieldPath); err != nil {
 			return err
 		}
 		
 		// Check for duplicate approver names
-		approverKey := fmt.Sprintf("%s:%s", v1alpha1.DefaultedApproverType(approver.Type), approver.Name)
-		if existingIndex, exists := approverNames[approverKey]; exists {
-			return fmt.Errorf("%s.name: duplicate approver '%s' (also found at approvers[%d])", fieldPath, approver.Name, existingIndex)
+		key := approverKey(approver)
+		if existingIndex, exists := approverNames[key]; exists {
+			return duplicateApproverError(fieldPath, approver, existingIndex)
 		}
-		approverNames[approverKey] = i
+		approverNames[key] = i
+	}
+
+	return nil
+}
+
+// approverKey identifies an approver entry by type and name, emails compared
+// case-insensitively, to detect duplicates.
+func approverKey(approver v1alpha1.ApproverDetails) string {
+	key := fmt.Sprintf("%s:%s", v1alpha1.DefaultedApproverType(approver.Type), approver.Name)
+	if v1alpha1.DefaultedApproverType(approver.Type) == "Email" {
+		key = strings.ToLower(key)
 	}
+	return key
+}
+
+func duplicateApproverError(fieldPath string, approver v1alpha1.ApproverDetails, existingIndex int) error {
+	return fmt.Errorf("%s.name: duplicate approver '%s' (also found at approvers[%d])", fieldPath, approver.Name, existingIndex)
+}
 
+// validateQuorumSchedule checks that the steps of the quorum schedule come in
+// order and each relaxes the requirement further.
+func validateQuorumSchedule(spec *v1alpha1.ApprovalTaskSpec) error {
+	var after time.Duration
+	required := spec.NumberOfApprovalsRequired
+	for i, step := range spec.QuorumSchedule {
+		if step.After.Duration <= after {
+			return fmt.Errorf("quorumSchedule[%d].after: must be later than %s, got %s", i, after, step.After.Duration)
+		}
+		if step.NumberOfApprovalsRequired <= 0 || step.NumberOfApprovalsRequired >= required {
+			return fmt.Errorf("quorumSchedule[%d].numberOfApprovalsRequired: must be between 1 and %d, got %d", i, required-1, step.NumberOfApprovalsRequired)
+		}
+		after, required = step.After.Duration, step.NumberOfApprovalsRequired
+	}
 	return nil
 }
 
 // validateApprover validates a single approver entry
-func validateApprover(approver v1alpha1.ApproverDetails, fieldPath string) error {
+func validateApprover(approver v1alpha1.ApproverDetails, fieldPath, separator string) error {
 	// Validate approver type first to determine validation rules
+	// Unknown types are rejected rather than defaulted, so that typos such
+	// as "user " or "Grp" do not go unnoticed; only an empty type means User
 	approverType := v1alpha1.DefaultedApproverType(approver.Type)
-	if approverType != "User" && approverType != "Group" {
-		return fmt.Errorf("%s.type: must be either 'User' or 'Group', got '%s'", fieldPath, approver.Type)
+	if !v1alpha1.IsKnownApproverType(approver.Type) {
+		return fmt.Errorf("%s.type: must be one of %s, got '%s'", fieldPath, quotedList(v1alpha1.KnownApproverTypes), approver.Type)
 	}
 
 	// Validate name format based on type (includes empty check via validateNameFormat)
@@ -652,43 +1858,79 @@ func validateApprover(approver v1alpha1.ApproverDetails, fieldPath string) error
 			return fmt.Errorf("%s.name: %w", fieldPath, err)
 		}
 	} else if approverType == "Group" {
-		if err := validateGroupName(approver.Name); err != nil {
+		if err := validateGroupName(approver.Name, separator); err != nil {
+			return fmt.Errorf("%s.name: %w", fieldPath, err)
+		}
+	} else if approverType == "Email" {
+		if err := validateEmail(approver.Name); err != nil {
 			return fmt.Errorf("%s.name: %w", fieldPath, err)
 		}
 	}
 
 	// Validate input value
-	validInputs := []string{"pending", "approve", "reject"}
+	validInputs := []string{"pending", "approve", "reject", "request-changes"}
 	if !webhookContains(validInputs, approver.Input) {
 		return fmt.Errorf("%s.input: must be one of: %s, got '%s'", fieldPath, strings.Join(validInputs, ", "), approver.Input)
 	}
 
-	// Validate users for group type
-	if approverType == "Group" {
-		
-		// Track duplicate users within the group
-		groupUsers := make(map[string]int) // username -> index
-		for j, user := range approver.Users {
-			userFieldPath := fmt.Sprintf("%s.users[%d]", fieldPath, j)
-			
-			if strings.TrimSpace(user.Name) == "" {
-				return fmt.Errorf("%s.name: required field is missing", userFieldPath)
-			} else if err := validateUserName(user.Name); err != nil {
-				return fmt.Errorf("%s.name: %w", userFieldPath, err)
-			}
-			
-			// Check for duplicate users within the group
-			if existingIndex, exists := groupUsers[user.Name]; exists {
-				return fmt.Errorf("%s.name: duplicate user '%s' within group (also found at %s.users[%d])", userFieldPath, user.Name, fieldPath, existingIndex)
-			}
-			groupUsers[user.Name] = j
+	if approver.Priority < 0 {
+		return fmt.Errorf("%s.priority: must not be negative, got %d", fieldPath, approver.Priority)
+	}
 
-			if !webhookContains(validInputs, user.Input) {
-				return fmt.Errorf("%s.input: must be one of: %s, got '%s'", userFieldPath, strings.Join(validInputs, ", "), user.Input)
-			}
+	if len(approver.Substitutes) > 0 && approverType != "User" {
+		return fmt.Errorf("%s.substitutes: only User approvers can have substitutes, got type '%s'", fieldPath, approverType)
+	}
+	for j, substitute := range approver.Substitutes {
+		if err := validateUserName(substitute); err != nil {
+			return fmt.Errorf("%s.substitutes[%d]: %w", fieldPath, j, err)
+		}
+		if substitute == approver.Name || webhookContains(approver.Substitutes[:j], substitute) {
+			return fmt.Errorf("%s.substitutes[%d]: duplicate user '%s'", fieldPath, j, substitute)
 		}
 	}
 
+	for j, allowed := range approver.AllowedInputs {
+		if allowed != "approve" && allowed != "reject" && allowed != "request-changes" {
+			return fmt.Errorf("%s.allowedInputs[%d]: must be one of: approve, reject, request-changes, got '%s'", fieldPath, j, allowed)
+		}
+	}
+
+	if approverType == "Group" {
+		return validateGroupUsers(approver, fieldPath)
+	}
+
+	return nil
+}
+
+// validateGroupUsers checks the users recorded under a Group approver. Each
+// needs a name, free of surrounding spaces so that it cannot pass for another
+// member, listed once, and either no decision yet or one the group accepts.
+// Malformed entries would otherwise be miscounted towards the quorum.
+func validateGroupUsers(approver v1alpha1.ApproverDetails, fieldPath string) error {
+	groupUsers := make(map[string]int) // username -> index
+	for j, user := range approver.Users {
+		userFieldPath := fmt.Sprintf("%s.users[%d]", fieldPath, j)
+
+		if strings.TrimSpace(user.Name) == "" {
+			return fmt.Errorf("%s.name: required field is missing", userFieldPath)
+		} else if err := validateUserName(user.Name); err != nil {
+			return fmt.Errorf("%s.name: %w", userFieldPath, err)
+		} else if strings.TrimSpace(user.Name) != user.Name {
+			return fmt.Errorf("%s.name: username cannot start or end with spaces, got '%s'", userFieldPath, user.Name)
+		}
+
+		if existingIndex, exists := groupUsers[user.Name]; exists {
+			return fmt.Errorf("%s.name: duplicate user '%s' within group (also found at %s.users[%d])", userFieldPath, user.Name, fieldPath, existingIndex)
+		}
+		groupUsers[user.Name] = j
+
+		if user.Input == "pending" {
+			continue
+		}
+		if err := hasValidInputValue(approver, user.Input); err != nil {
+			return fmt.Errorf("%s.input: %w", userFieldPath, err)
+		}
+	}
 	return nil
 }
 
@@ -720,20 +1962,50 @@ func validateUserName(name string) error {
 	return nil
 }
 
+// validateEmail validates the name of an Email approver
+func validateEmail(name string) error {
+	if err := validateNameFormat(name, "email"); err != nil {
+		return err
+	}
+
+	local, domain, found := strings.Cut(name, "@")
+	if !found || local == "" || domain == "" || strings.Contains(domain, "@") {
+		return fmt.Errorf("email must be of the form 'name@domain', got '%s'", name)
+	}
+
+	return nil
+}
+
 // validateGroupName validates group name format
-func validateGroupName(name string) error {
+func validateGroupName(name, separator string) error {
 	if err := validateNameFormat(name, "group name"); err != nil {
 		return err
 	}
 	
-	// Group names should not contain colons to avoid confusion with user prefixes
-	if strings.Contains(name, ":") {
+	// Group names should not contain colons to avoid confusion with user
+	// prefixes, unless they separate the levels of hierarchical groups
+	if strings.Contains(name, ":") && separator != ":" {
 		return fmt.Errorf("group name cannot contain colons")
 	}
+	if separator != "" && slices.Contains(strings.Split(name, separator), "") {
+		return fmt.Errorf("group name cannot have empty levels, got '%s'", name)
+	}
 	
 	return nil
 }
 
+// quotedList formats values as "'a', 'b' or 'c'".
+func quotedList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = "'" + value + "'"
+	}
+	if len(quoted) < 2 {
+		return strings.Join(quoted, "")
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
+
 // webhookContains checks if a slice contains a string
 func webhookContains(slice []string, item string) bool {
 	for _, s := range slice {
@@ -753,12 +2025,19 @@ func (r *reconciler) decodeNewObject(newBytes []byte) (*v1alpha1.ApprovalTask, e
 			newDecoder.DisallowUnknownFields()
 		}
 		if err := newDecoder.Decode(&newObj); err != nil {
-			return nil, err
+			return nil, describeDecodeError(newBytes, err)
 		}
 	}
 	return &newObj, nil
 }
 
+// isEmptyObject reports whether the raw object of an admission request is
+// missing, which the API server may send as nothing or as JSON null.
+func isEmptyObject(raw []byte) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
+}
+
 // decodeOldObject decodes the incoming old object
 func (r *reconciler) decodeOldObject(oldBytes []byte) (*v1alpha1.ApprovalTask, error) {
 	var oldObj v1alpha1.ApprovalTask
@@ -768,27 +2047,78 @@ func (r *reconciler) decodeOldObject(oldBytes []byte) (*v1alpha1.ApprovalTask, e
 			oldDecoder.DisallowUnknownFields()
 		}
 		if err := oldDecoder.Decode(&oldObj); err != nil {
-			return nil, err
+			return nil, describeDecodeError(oldBytes, err)
 		}
 	}
 	return &oldObj, nil
 }
 
+// validateApproverIdentities rejects specs in which the same name refers both
+// to a User approver and to a Group approver, or to a standalone User approver
+// and a member of a group. Either would make it ambiguous which entry a
+// user's decision belongs to.
+func validateApproverIdentities(spec *v1alpha1.ApprovalTaskSpec) error {
+	if problems := approverIdentityProblems(spec); len(problems) > 0 {
+		return problems[0]
+	}
+	return nil
+}
+
+// approverIdentityProblems returns every ambiguity validateApproverIdentities
+// denies, in order.
+func approverIdentityProblems(spec *v1alpha1.ApprovalTaskSpec) []error {
+	var problems []error
+	users := make(map[string]int)
+	for i, approver := range spec.Approvers {
+		if v1alpha1.DefaultedApproverType(approver.Type) == "User" {
+			users[approver.Name] = i
+		}
+	}
+
+	for i, approver := range spec.Approvers {
+		if v1alpha1.DefaultedApproverType(approver.Type) != "Group" {
+			continue
+		}
+		if j, ok := users[approver.Name]; ok {
+			problems = append(problems, fmt.Errorf("approvers[%d].name: group '%s' has the same name as the User approver at approvers[%d]", i, approver.Name, j))
+		}
+		for k, user := range approver.Users {
+			if j, ok := users[user.Name]; ok {
+				problems = append(problems, fmt.Errorf("approvers[%d].users[%d].name: '%s' is already a User approver at approvers[%d]", i, k, user.Name, j))
+			}
+		}
+	}
+	return problems
+}
+
 // validateApproverInputsForCreate ensures all approver inputs are set to "pending" for new ApprovalTask resources
 func validateApproverInputsForCreate(approvalTask *v1alpha1.ApprovalTask) error {
+	if problems := approverInputProblems(approvalTask); len(problems) > 0 {
+		return problems[0]
+	}
+	return nil
+}
+
+// approverInputProblems returns every input validateApproverInputsForCreate
+// denies, in order.
+func approverInputProblems(approvalTask *v1alpha1.ApprovalTask) []error {
+	var problems []error
+	if approvalTask.Spec.RequesterInput != "" {
+		problems = append(problems, fmt.Errorf("requesterInput: must be empty for new ApprovalTask, got '%s'", approvalTask.Spec.RequesterInput))
+	}
 	for i, approver := range approvalTask.Spec.Approvers {
 		if approver.Input != "pending" {
-			return fmt.Errorf("approvers[%d].input: must be 'pending' for new ApprovalTask, got '%s'", i, approver.Input)
+			problems = append(problems, fmt.Errorf("approvers[%d].input: must be 'pending' for new ApprovalTask, got '%s'", i, approver.Input))
 		}
 		
 		// For group approvers, also validate that all users within the group have pending input
 		if v1alpha1.DefaultedApproverType(approver.Type) == "Group" {
 			for j, user := range approver.Users {
 				if user.Input != "pending" {
-					return fmt.Errorf("approvers[%d].users[%d].input: must be 'pending' for new ApprovalTask, got '%s'", i, j, user.Input)
+					problems = append(problems, fmt.Errorf("approvers[%d].users[%d].input: must be 'pending' for new ApprovalTask, got '%s'", i, j, user.Input))
 				}
 			}
 		}
 	}
-	return nil
+	return problems
 }
@@ -0,0 +1,1335 @@
+/*
+Copyright 2024 The OpenShift Pipelines Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/manual-approval-gate/pkg/apis/approvaltask/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func admissionRequestFor(username string, groups ...string) *admissionv1.AdmissionRequest {
+	return &admissionv1.AdmissionRequest{
+		UserInfo: authenticationv1.UserInfo{
+			Username: username,
+			Groups:   groups,
+		},
+	}
+}
+
+// admitUpdate runs an UPDATE of oldObj to newObj through Admit on behalf of the given user.
+func admitUpdate(t *testing.T, oldObj, newObj *v1alpha1.ApprovalTask, username string, groups ...string) *admissionv1.AdmissionResponse {
+	t.Helper()
+	return admitUpdateWith(t, &reconciler{privilegedGroup: DefaultPrivilegedGroup}, oldObj, newObj, username, groups...)
+}
+
+// admitUpdateWith is admitUpdate against a specific reconciler.
+func admitUpdateWith(t *testing.T, r *reconciler, oldObj, newObj *v1alpha1.ApprovalTask, username string, groups ...string) *admissionv1.AdmissionResponse {
+	t.Helper()
+	return admitUpdateAs(t, r, oldObj, newObj, admissionRequestFor(username, groups...).UserInfo)
+}
+
+// admitUpdateAs is admitUpdateWith on behalf of a user with arbitrary UserInfo.
+func admitUpdateAs(t *testing.T, r *reconciler, oldObj, newObj *v1alpha1.ApprovalTask, userInfo authenticationv1.UserInfo) *admissionv1.AdmissionResponse {
+	t.Helper()
+	oldBytes, err := json.Marshal(oldObj)
+	if err != nil {
+		t.Fatalf("failed marshaling old object: %v", err)
+	}
+	newBytes, err := json.Marshal(newObj)
+	if err != nil {
+		t.Fatalf("failed marshaling new object: %v", err)
+	}
+	request := &admissionv1.AdmissionRequest{UserInfo: userInfo}
+	request.Operation = admissionv1.Update
+	request.Namespace, request.Name = newObj.Namespace, newObj.Name
+	request.Kind = metav1.GroupVersionKind{Group: Group, Version: Version, Kind: Kind}
+	request.OldObject = runtime.RawExtension{Raw: oldBytes}
+	request.Object = runtime.RawExtension{Raw: newBytes}
+	return r.Admit(context.Background(), request)
+}
+
+func TestIsApprovalRequiredEscrowGroup(t *testing.T) {
+	at := v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+			},
+			NumberOfApprovalsRequired: 1,
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+	assert.False(t, isApprovalRequired(at), "quorum reached without escrow should be final")
+
+	at.Spec.EscrowGroup = "release"
+	assert.True(t, isApprovalRequired(at), "escrowed task should stay pending until the group is released")
+
+	at.Status.State = "approved"
+	assert.False(t, isApprovalRequired(at))
+}
+
+func TestAdmitEscrowGroupChange(t *testing.T) {
+	oldObj := withdrawableApprovalTask()
+	oldObj.Spec.EscrowGroup = "release"
+
+	for _, group := range []string{"", "other-release"} {
+		newObj := oldObj.DeepCopy()
+		newObj.Spec.EscrowGroup = group
+		newObj.Spec.Approvers[0].Input = "approve"
+		resp := admitUpdate(t, oldObj, newObj, "alice")
+		assert.False(t, resp.Allowed, "the escrow group cannot be changed to %q", group)
+		assert.Equal(t, "The escrow group of an ApprovalTask cannot be changed", resp.Result.Message)
+	}
+
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	resp := admitUpdate(t, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+}
+
+func TestIsUserApprovalChangedRejectOnlyApprover(t *testing.T) {
+	oldApprovers := []v1alpha1.ApproverDetails{
+		{Name: "compliance", Type: "User", Input: "pending", AllowedInputs: []string{"reject"}},
+	}
+
+	approve := []v1alpha1.ApproverDetails{
+		{Name: "compliance", Type: "User", Input: "approve", AllowedInputs: []string{"reject"}},
+	}
+	changed, err := IsUserApprovalChanged(oldApprovers, approve, admissionRequestFor("compliance"))
+	assert.False(t, changed)
+	assert.EqualError(t, err, "input value 'approve' is not allowed for approver 'compliance'. Allowed values are: reject")
+
+	reject := []v1alpha1.ApproverDetails{
+		{Name: "compliance", Type: "User", Input: "reject", AllowedInputs: []string{"reject"}},
+	}
+	changed, err = IsUserApprovalChanged(oldApprovers, reject, admissionRequestFor("compliance"))
+	assert.NoError(t, err)
+	assert.True(t, changed)
+}
+
+func TestIsUserApprovalChangedRejectOnlyGroupMember(t *testing.T) {
+	oldApprovers := []v1alpha1.ApproverDetails{
+		{
+			Name: "security", Type: "Group", Input: "pending", AllowedInputs: []string{"reject"},
+			Users: []v1alpha1.UserDetails{{Name: "alice", Input: "pending"}},
+		},
+	}
+	newApprovers := []v1alpha1.ApproverDetails{
+		{
+			Name: "security", Type: "Group", Input: "pending", AllowedInputs: []string{"reject"},
+			Users: []v1alpha1.UserDetails{{Name: "alice", Input: "approve"}},
+		},
+	}
+	changed, err := IsUserApprovalChanged(oldApprovers, newApprovers, admissionRequestFor("alice"))
+	assert.False(t, changed)
+	assert.Error(t, err)
+}
+
+func TestValidateApproverAllowedInputs(t *testing.T) {
+	approver := v1alpha1.ApproverDetails{Name: "alice", Type: "User", Input: "pending", AllowedInputs: []string{"reject"}}
+	assert.NoError(t, validateApprover(approver, "approvers[0]", ""))
+
+	approver.AllowedInputs = []string{"pending"}
+	assert.EqualError(t, validateApprover(approver, "approvers[0]", ""), "approvers[0].allowedInputs[0]: must be one of: approve, reject, request-changes, got 'pending'")
+}
+
+func TestIsApprovalRenewal(t *testing.T) {
+	expiresAfter := &metav1.Duration{Duration: time.Hour}
+	approvedAt := metav1.NewTime(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	renewedAt := metav1.NewTime(approvedAt.Add(30 * time.Minute))
+	oldObj := &v1alpha1.ApprovalTask{
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve", ApprovalExpiresAfter: expiresAfter},
+				{
+					Name: "platform", Type: "Group", Input: "approve", ApprovalExpiresAfter: expiresAfter,
+					Users: []v1alpha1.UserDetails{{Name: "bob", Input: "approve", RenewTime: &approvedAt}},
+				},
+			},
+		},
+	}
+
+	userRenewal := oldObj.DeepCopy()
+	userRenewal.Spec.Approvers[0].RenewTime = &renewedAt
+	assert.True(t, isApprovalRenewal(oldObj, userRenewal, admissionRequestFor("alice")))
+	assert.False(t, isApprovalRenewal(oldObj, userRenewal, admissionRequestFor("bob", "platform")), "bob cannot renew alice's approval")
+
+	memberRenewal := oldObj.DeepCopy()
+	memberRenewal.Spec.Approvers[1].Users[0].RenewTime = &renewedAt
+	assert.True(t, isApprovalRenewal(oldObj, memberRenewal, admissionRequestFor("bob", "platform")))
+	assert.False(t, CheckOtherUsersForInvalidChanges(oldObj.Spec.Approvers, memberRenewal.Spec.Approvers, admissionRequestFor("alice")))
+
+	backwards := oldObj.DeepCopy()
+	earlier := metav1.NewTime(approvedAt.Add(-time.Minute))
+	backwards.Spec.Approvers[1].Users[0].RenewTime = &earlier
+	assert.False(t, isApprovalRenewal(oldObj, backwards, admissionRequestFor("bob", "platform")))
+
+	smuggled := userRenewal.DeepCopy()
+	smuggled.Spec.NumberOfApprovalsRequired = 1
+	assert.False(t, isApprovalRenewal(oldObj, smuggled, admissionRequestFor("alice")), "renewals cannot carry other spec changes")
+
+	notExpiring := oldObj.DeepCopy()
+	notExpiring.Spec.Approvers[0].ApprovalExpiresAfter = nil
+	renewed := notExpiring.DeepCopy()
+	renewed.Spec.Approvers[0].RenewTime = &renewedAt
+	assert.False(t, isApprovalRenewal(notExpiring, renewed, admissionRequestFor("alice")))
+}
+
+func withdrawableApprovalTask() *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "deploy",
+			Namespace:   "production",
+			Annotations: map[string]string{v1alpha1.CreatedByAnnotationKey: "carol"},
+		},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			Approvers:                 []v1alpha1.ApproverDetails{{Name: "alice", Type: "User", Input: "pending"}},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func TestAdmitWithdrawalByCreator(t *testing.T) {
+	oldObj := withdrawableApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.RequesterInput = "withdraw"
+
+	resp := admitUpdate(t, oldObj, newObj, "carol")
+	assert.True(t, resp.Allowed, "creator should be able to withdraw a pending task")
+}
+
+func TestAdmitWithdrawalByNonCreator(t *testing.T) {
+	oldObj := withdrawableApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.RequesterInput = "withdraw"
+
+	resp := admitUpdate(t, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Only the creator of the approval task can withdraw it", resp.Result.Message)
+
+	delete(oldObj.Annotations, v1alpha1.CreatedByAnnotationKey)
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.RequesterInput = "withdraw"
+	resp = admitUpdate(t, oldObj, newObj, "carol")
+	assert.False(t, resp.Allowed, "tasks without a recorded creator cannot be withdrawn")
+}
+
+func TestAdmitWithdrawalRequiresPendingTask(t *testing.T) {
+	for _, state := range []string{"approved", "rejected", "withdrawn"} {
+		oldObj := withdrawableApprovalTask()
+		oldObj.Status.State = state
+		newObj := oldObj.DeepCopy()
+		newObj.Spec.RequesterInput = "withdraw"
+
+		resp := admitUpdate(t, oldObj, newObj, "carol")
+		assert.False(t, resp.Allowed, "withdrawing a %s task should be denied", state)
+	}
+}
+
+func TestAdmitWithdrawalCannotChangeOtherFields(t *testing.T) {
+	oldObj := withdrawableApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.RequesterInput = "withdraw"
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdate(t, oldObj, newObj, "carol")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Withdrawing an approval task cannot change any other field", resp.Result.Message)
+}
+
+func TestAdmitCreatorCannotBeChanged(t *testing.T) {
+	oldObj := withdrawableApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Annotations[v1alpha1.CreatedByAnnotationKey] = "alice"
+	newObj.Spec.RequesterInput = "withdraw"
+
+	resp := admitUpdate(t, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+}
+
+func pausableApprovalTask() *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "approve"},
+				{Name: "bob", Type: "User", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{
+			State:             "pending",
+			ApproversResponse: []v1alpha1.ApproverState{{Name: "alice", Type: "User", Response: "approved"}},
+		},
+	}
+}
+
+func TestAdmitPauseRequiresPrivilegedGroup(t *testing.T) {
+	oldObj := pausableApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Paused = true
+
+	resp := admitUpdate(t, oldObj, newObj, "bob")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Only members of the privileged group can pause or unpause an approval task", resp.Result.Message)
+
+	resp = admitUpdate(t, oldObj, newObj, "admin", DefaultPrivilegedGroup)
+	assert.True(t, resp.Allowed)
+
+	custom := &reconciler{privilegedGroup: "release-admins"}
+	assert.False(t, admitUpdateWith(t, custom, oldObj, newObj, "admin", DefaultPrivilegedGroup).Allowed)
+	assert.True(t, admitUpdateWith(t, custom, oldObj, newObj, "carol", "release-admins").Allowed)
+
+	newObj.Spec.Approvers[1].Input = "approve"
+	resp = admitUpdate(t, oldObj, newObj, "admin", DefaultPrivilegedGroup)
+	assert.False(t, resp.Allowed, "pausing cannot carry other changes")
+}
+
+func TestAdmitPausedTaskDeniesInputChanges(t *testing.T) {
+	oldObj := pausableApprovalTask()
+	oldObj.Spec.Paused = true
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+
+	resp := admitUpdate(t, oldObj, newObj, "bob")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "approvals are paused for this task", resp.Result.Message)
+
+	// Unpausing leaves the existing responses in place and approvals resume.
+	unpaused := oldObj.DeepCopy()
+	unpaused.Spec.Paused = false
+	assert.True(t, admitUpdate(t, oldObj, unpaused, "admin", DefaultPrivilegedGroup).Allowed)
+	assert.Equal(t, "approve", unpaused.Spec.Approvers[0].Input)
+
+	approved := unpaused.DeepCopy()
+	approved.Spec.Approvers[1].Input = "approve"
+	assert.True(t, admitUpdate(t, unpaused, approved, "bob").Allowed)
+}
+
+func TestValidateApproverIdentities(t *testing.T) {
+	tests := []struct {
+		name      string
+		approvers []v1alpha1.ApproverDetails
+		errorMsg  string
+	}{{
+		name: "distinct users and groups",
+		approvers: []v1alpha1.ApproverDetails{
+			{Name: "alice", Type: "User", Input: "pending"},
+			{Name: "platform", Type: "Group", Input: "pending", Users: []v1alpha1.UserDetails{{Name: "bob", Input: "pending"}}},
+		},
+	}, {
+		name: "group named like a user",
+		approvers: []v1alpha1.ApproverDetails{
+			{Name: "release", Type: "User", Input: "pending"},
+			{Name: "release", Type: "Group", Input: "pending"},
+		},
+		errorMsg: "approvers[1].name: group 'release' has the same name as the User approver at approvers[0]",
+	}, {
+		name: "defaulted user type collides too",
+		approvers: []v1alpha1.ApproverDetails{
+			{Name: "release", Type: "Group", Input: "pending"},
+			{Name: "release", Input: "pending"},
+		},
+		errorMsg: "approvers[0].name: group 'release' has the same name as the User approver at approvers[1]",
+	}, {
+		name: "group member duplicating a standalone user",
+		approvers: []v1alpha1.ApproverDetails{
+			{Name: "platform", Type: "Group", Input: "pending", Users: []v1alpha1.UserDetails{{Name: "bob", Input: "pending"}, {Name: "alice", Input: "pending"}}},
+			{Name: "alice", Type: "User", Input: "pending"},
+		},
+		errorMsg: "approvers[0].users[1].name: 'alice' is already a User approver at approvers[1]",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateApproverIdentities(&v1alpha1.ApprovalTaskSpec{Approvers: tt.approvers})
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestValidateGroupUsers(t *testing.T) {
+	tests := []struct {
+		name     string
+		approver v1alpha1.ApproverDetails
+		errorMsg string
+	}{{
+		name: "pending and decided members",
+		approver: v1alpha1.ApproverDetails{Name: "platform", Type: "Group", Input: "approve", Users: []v1alpha1.UserDetails{
+			{Name: "alice", Input: "pending"}, {Name: "bob", Input: "approve"}, {Name: "carol", Input: "request-changes"},
+		}},
+	}, {
+		name:     "empty name",
+		approver: v1alpha1.ApproverDetails{Name: "platform", Type: "Group", Input: "pending", Users: []v1alpha1.UserDetails{{Name: "alice", Input: "pending"}, {Name: " ", Input: "approve"}}},
+		errorMsg: "approvers[0].users[1].name: required field is missing",
+	}, {
+		name:     "name padded with spaces",
+		approver: v1alpha1.ApproverDetails{Name: "platform", Type: "Group", Input: "approve", Users: []v1alpha1.UserDetails{{Name: "alice ", Input: "approve"}}},
+		errorMsg: "approvers[0].users[0].name: username cannot start or end with spaces, got 'alice '",
+	}, {
+		name:     "unknown input",
+		approver: v1alpha1.ApproverDetails{Name: "platform", Type: "Group", Input: "approve", Users: []v1alpha1.UserDetails{{Name: "alice", Input: "lgtm"}}},
+		errorMsg: "approvers[0].users[0].input: invalid input value: 'lgtm'. Supported values are 'approve', 'reject' or 'request-changes'",
+	}, {
+		name: "input the group does not allow",
+		approver: v1alpha1.ApproverDetails{Name: "platform", Type: "Group", Input: "reject", AllowedInputs: []string{"approve"}, Users: []v1alpha1.UserDetails{
+			{Name: "alice", Input: "reject"},
+		}},
+		errorMsg: "approvers[0].users[0].input: input value 'reject' is not allowed for approver 'platform'. Allowed values are: approve",
+	}, {
+		name: "duplicate member",
+		approver: v1alpha1.ApproverDetails{Name: "platform", Type: "Group", Input: "approve", Users: []v1alpha1.UserDetails{
+			{Name: "alice", Input: "approve"}, {Name: "bob", Input: "pending"}, {Name: "alice", Input: "reject"},
+		}},
+		errorMsg: "approvers[0].users[2].name: duplicate user 'alice' within group (also found at approvers[0].users[0])",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateGroupUsers(tt.approver, "approvers[0]")
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestAdmitMalformedGroupUsers(t *testing.T) {
+	oldObj := digestApprovalTask("")
+	oldObj.Spec.ExpectedDigest = ""
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+	newObj.Spec.Approvers[1].Users = []v1alpha1.UserDetails{{Name: "bob", Input: "approve"}, {Name: "bob", Input: "approve"}}
+
+	resp := admitUpdate(t, oldObj, newObj, "bob", "platform")
+	assert.False(t, resp.Allowed, "a member listed twice would be counted twice")
+	assert.Contains(t, resp.Result.Message, "duplicate user 'bob' within group")
+}
+
+func digestApprovalTask(currentDigest string) *v1alpha1.ApprovalTask {
+	at := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			ExpectedDigest:            "sha256:aaaa",
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "platform", Type: "Group", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+	if currentDigest != "" {
+		at.Annotations = map[string]string{v1alpha1.CurrentDigestAnnotationKey: currentDigest}
+	}
+	return at
+}
+
+func TestAdmitApprovalMatchingDigest(t *testing.T) {
+	for _, current := range []string{"", "sha256:aaaa"} {
+		oldObj := digestApprovalTask(current)
+		newObj := oldObj.DeepCopy()
+		newObj.Spec.Approvers[0].Input = "approve"
+
+		resp := admitUpdate(t, oldObj, newObj, "alice")
+		assert.True(t, resp.Allowed, "approval should be allowed with current digest %q", current)
+	}
+}
+
+func TestAdmitApprovalMismatchingDigest(t *testing.T) {
+	oldObj := digestApprovalTask("sha256:bbbb")
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdate(t, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Cannot approve: the current digest 'sha256:bbbb' does not match the expected digest 'sha256:aaaa'", resp.Result.Message)
+
+	// Group members cannot approve a different artifact either
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+	newObj.Spec.Approvers[1].Users = []v1alpha1.UserDetails{{Name: "bob", Input: "approve"}}
+	resp = admitUpdate(t, oldObj, newObj, "bob", "platform")
+	assert.False(t, resp.Allowed)
+
+	// Rejecting a mismatching artifact is always possible
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "reject"
+	resp = admitUpdate(t, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed, "rejection should be allowed regardless of the digest")
+}
+
+func TestAdmitDigestReportedByCI(t *testing.T) {
+	r := &reconciler{ciUsers: []string{"system:serviceaccount:ci:reporter"}}
+	created := digestApprovalTask("sha256:aaaa")
+	ci := authenticationv1.UserInfo{Username: "system:serviceaccount:ci:reporter"}
+
+	rebuilt := created.DeepCopy()
+	rebuilt.Annotations[v1alpha1.CurrentDigestAnnotationKey] = "sha256:bbbb"
+	resp := admitUpdateWith(t, r, created, rebuilt, "alice")
+	assert.False(t, resp.Allowed, "approvers cannot report the digest")
+	resp = admitUpdateAs(t, r, created, rebuilt, ci)
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	approved := rebuilt.DeepCopy()
+	approved.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdateWith(t, r, rebuilt, approved, "alice")
+	assert.False(t, resp.Allowed, "the artifact changed after the task was created")
+	assert.Equal(t, "Cannot approve: the current digest 'sha256:bbbb' does not match the expected digest 'sha256:aaaa'", resp.Result.Message)
+
+	// The expected digest stays out of reach of CI
+	retargeted := rebuilt.DeepCopy()
+	retargeted.Spec.ExpectedDigest = "sha256:bbbb"
+	resp = admitUpdateAs(t, r, rebuilt, retargeted, ci)
+	assert.False(t, resp.Allowed)
+}
+
+func TestAdmitApprovalCannotFixDigest(t *testing.T) {
+	oldObj := digestApprovalTask("sha256:bbbb")
+	newObj := oldObj.DeepCopy()
+	newObj.Annotations[v1alpha1.CurrentDigestAnnotationKey] = "sha256:aaaa"
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdate(t, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed, "approver should not be able to claim the expected digest while approving")
+}
+
+func TestAdmitExpectedDigestCannotBeChanged(t *testing.T) {
+	oldObj := digestApprovalTask("sha256:bbbb")
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.ExpectedDigest = "sha256:bbbb"
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdate(t, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "The expected digest of an ApprovalTask cannot be changed", resp.Result.Message)
+}
+
+func emailUser(username string, emails ...string) authenticationv1.UserInfo {
+	return authenticationv1.UserInfo{
+		Username: username,
+		Extra:    map[string]authenticationv1.ExtraValue{emailExtraKey: emails},
+	}
+}
+
+func emailApprovalTask() *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice@example.com", Type: "Email", Input: "pending"},
+				{Name: "bob@example.com", Type: "Email", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func TestAdmitEmailApprover(t *testing.T) {
+	r := &reconciler{privilegedGroup: DefaultPrivilegedGroup}
+	oldObj := emailApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+
+	resp := admitUpdateAs(t, r, oldObj, newObj, emailUser("u-1234", "Alice@Example.com"))
+	assert.True(t, resp.Allowed, "the user with the matching email claim should be able to approve")
+
+	resp = admitUpdateAs(t, r, oldObj, newObj, emailUser("u-5678", "bob@example.com"))
+	assert.False(t, resp.Allowed, "another approver should not be able to set alice's input")
+
+	resp = admitUpdateAs(t, r, oldObj, newObj, emailUser("alice@example.com"))
+	assert.False(t, resp.Allowed, "a username equal to the email should not match without the claim")
+}
+
+func TestAdmitRequiredExtraClaim(t *testing.T) {
+	oldObj := withdrawableApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	verified := authenticationv1.UserInfo{
+		Username: oldObj.Spec.Approvers[0].Name,
+		Extra:    map[string]authenticationv1.ExtraValue{"email_verified": {"true"}},
+	}
+	unverified := authenticationv1.UserInfo{Username: oldObj.Spec.Approvers[0].Name}
+
+	// Off by default
+	r := &reconciler{privilegedGroup: DefaultPrivilegedGroup}
+	assert.True(t, admitUpdateAs(t, r, oldObj, newObj, unverified).Allowed)
+
+	r.requiredClaim = "email_verified"
+	assert.True(t, admitUpdateAs(t, r, oldObj, newObj, verified).Allowed)
+	resp := admitUpdateAs(t, r, oldObj, newObj, unverified)
+	assert.False(t, resp.Allowed, "approvers without the claim are denied")
+	assert.Equal(t, "User lacks the required 'email_verified' claim", resp.Result.Message)
+
+	r.requiredClaimValue = "true"
+	assert.True(t, admitUpdateAs(t, r, oldObj, newObj, verified).Allowed)
+	verified.Extra["email_verified"] = authenticationv1.ExtraValue{"false"}
+	resp = admitUpdateAs(t, r, oldObj, newObj, verified)
+	assert.False(t, resp.Allowed, "the claim must carry the required value")
+	assert.Equal(t, "User lacks the required 'email_verified=true' claim", resp.Result.Message)
+}
+
+func conditionalApprovalTask() *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production", Labels: map[string]string{"risk": "low"}},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "security", Type: "User", Input: "pending", WhenLabels: map[string]string{"risk": "high"}},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func TestAdmitInactiveApprover(t *testing.T) {
+	oldObj := conditionalApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+
+	resp := admitUpdate(t, oldObj, newObj, "security")
+	assert.False(t, resp.Allowed, "an approver whose conditions do not match cannot decide")
+	assert.Equal(t, "Approver 'security' is not required for this task: its whenLabels do not match the task labels", resp.Result.Message)
+
+	oldObj.Labels["risk"] = "high"
+	newObj.Labels["risk"] = "high"
+	resp = admitUpdate(t, oldObj, newObj, "security")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+}
+
+func TestAdmitConditionLabelChange(t *testing.T) {
+	oldObj := conditionalApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Labels["risk"] = "high"
+
+	resp := admitUpdate(t, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed, "approvers must not choose who else has to approve")
+	assert.Equal(t, "Only members of the privileged group can change the labels approvers are conditional on", resp.Result.Message)
+
+	resp = admitUpdate(t, oldObj, newObj, "admin", DefaultPrivilegedGroup)
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	newObj.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdate(t, oldObj, newObj, "admin", DefaultPrivilegedGroup)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Changing the labels approvers are conditional on cannot change any other field", resp.Result.Message)
+
+	// Other labels are not affected
+	newObj = oldObj.DeepCopy()
+	newObj.Labels["team"] = "payments"
+	newObj.Spec.Approvers[0].Input = "approve"
+	assert.True(t, admitUpdate(t, oldObj, newObj, "alice").Allowed)
+}
+
+func TestEmailApproverValidationFunctions(t *testing.T) {
+	oldObj := emailApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	request := &admissionv1.AdmissionRequest{UserInfo: emailUser("u-1234", "alice@example.com")}
+
+	assert.True(t, ifUserExists(oldObj.Spec.Approvers, request))
+	changed, err := IsUserApprovalChanged(oldObj.Spec.Approvers, newObj.Spec.Approvers, request)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, CheckOtherUsersForInvalidChanges(oldObj.Spec.Approvers, newObj.Spec.Approvers, request))
+
+	// Changing the other Email approver is caught as a change to another user
+	newObj.Spec.Approvers[1].Input = "approve"
+	assert.False(t, CheckOtherUsersForInvalidChanges(oldObj.Spec.Approvers, newObj.Spec.Approvers, request))
+
+	// A recorded approval of the email blocks approving again
+	oldObj.Status.ApproversResponse = []v1alpha1.ApproverState{{Name: "alice@example.com", Type: "Email", Response: "approved"}}
+	assert.Equal(t, "User has already approved", checkIfUserAlreadyDecided(oldObj, newObj, request))
+}
+
+func TestAdmitOwnChangeWithForeignChange(t *testing.T) {
+	oldObj := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "bob", Type: "User", Input: "pending"},
+				{Name: "platform", Type: "Group", Input: "approve", Users: []v1alpha1.UserDetails{{Name: "carol", Input: "approve"}}},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	assert.True(t, admitUpdate(t, oldObj, newObj, "alice").Allowed)
+
+	// alice's own approval does not carry bob's through with it
+	newObj.Spec.Approvers[1].Input = "approve"
+	resp := admitUpdate(t, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "User can only update their own approval input: the update also changes approvers[1].input", resp.Result.Message)
+
+	// nor does it let her withdraw a group member's approval
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	newObj.Spec.Approvers[2].Users[0].Input = "reject"
+	resp = admitUpdate(t, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "User can only update their own approval input: the update also changes approvers[2].users[0].input", resp.Result.Message)
+}
+
+func TestValidateApprovalPercentage(t *testing.T) {
+	spec := withdrawableApprovalTask().Spec
+	spec.NumberOfApprovalsRequired = 0
+	spec.ApprovalPercentage = 60
+	assert.NoError(t, validateApprovalTaskSpec(&spec, context.Background()))
+
+	spec.ApprovalPercentage = 101
+	assert.EqualError(t, validateApprovalTaskSpec(&spec, context.Background()), "approvalPercentage: must be between 1 and 100, got 101")
+	spec.ApprovalPercentage = -5
+	assert.EqualError(t, validateApprovalTaskSpec(&spec, context.Background()), "approvalPercentage: must be between 1 and 100, got -5")
+
+	spec.ApprovalPercentage = 60
+	spec.NumberOfApprovalsRequired = 2
+	assert.EqualError(t, validateApprovalTaskSpec(&spec, context.Background()), "approvalPercentage: cannot be combined with numberOfApprovalsRequired")
+
+	spec.NumberOfApprovalsRequired = 0
+	spec.QuorumSchedule = []v1alpha1.QuorumStep{{After: metav1.Duration{Duration: time.Hour}, NumberOfApprovalsRequired: 1}}
+	assert.EqualError(t, validateApprovalTaskSpec(&spec, context.Background()), "quorumSchedule: cannot be combined with approvalPercentage")
+}
+
+func TestAdmitApprovalPercentageChange(t *testing.T) {
+	oldObj := withdrawableApprovalTask()
+	oldObj.Spec.NumberOfApprovalsRequired = 0
+	oldObj.Spec.ApprovalPercentage = 100
+	oldObj.Spec.Approvers = append(oldObj.Spec.Approvers,
+		v1alpha1.ApproverDetails{Name: "bob", Type: "User", Input: "pending"},
+		v1alpha1.ApproverDetails{Name: "dave", Type: "User", Input: "pending"})
+
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.ApprovalPercentage = 1
+	newObj.Spec.Approvers[0].Input = "approve"
+	resp := admitUpdate(t, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed, "lowering the percentage along with an approval would reach quorum with a single approver")
+	assert.Equal(t, "The approval percentage of an ApprovalTask cannot be changed", resp.Result.Message)
+
+	newObj.Spec.ApprovalPercentage = 100
+	resp = admitUpdate(t, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+}
+
+func TestAdmitMixedResolution(t *testing.T) {
+	spec := withdrawableApprovalTask().Spec
+	spec.MixedResolution = v1alpha1.MixedResolutionWaitForAll
+	assert.NoError(t, validateApprovalTaskSpec(&spec, context.Background()))
+	spec.MixedResolution = "Majority"
+	assert.EqualError(t, validateApprovalTaskSpec(&spec, context.Background()), "mixedResolution: must be one of 'WaitForAll' or 'FailFast', got 'Majority'")
+
+	oldObj := withdrawableApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.MixedResolution = v1alpha1.MixedResolutionFailFast
+	newObj.Spec.Approvers[0].Input = "reject"
+	resp := admitUpdate(t, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "The mixed resolution of an ApprovalTask cannot be changed", resp.Result.Message)
+}
+
+func TestValidateEmailApprover(t *testing.T) {
+	assert.NoError(t, validateApprover(v1alpha1.ApproverDetails{Name: "alice@example.com", Type: "Email", Input: "pending"}, "approvers[0]", ""))
+
+	for _, name := range []string{"alice", "@example.com", "alice@", "alice@example@com", "alice @example.com"} {
+		err := validateApprover(v1alpha1.ApproverDetails{Name: name, Type: "Email", Input: "pending"}, "approvers[0]", "")
+		assert.Error(t, err, "email %q should be invalid", name)
+	}
+
+	err := validateApprover(v1alpha1.ApproverDetails{Name: "alice", Type: "Robot", Input: "pending"}, "approvers[0]", "")
+	assert.EqualError(t, err, "approvers[0].type: must be one of 'User', 'Group' or 'Email', got 'Robot'")
+}
+
+func TestAdmitFinalizerReleaseOnDeletion(t *testing.T) {
+	deletionTimestamp := metav1.Now()
+	oldObj := withdrawableApprovalTask()
+	oldObj.DeletionTimestamp = &deletionTimestamp
+	oldObj.Finalizers = []string{v1alpha1.CleanupFinalizer}
+	newObj := oldObj.DeepCopy()
+	newObj.Finalizers = nil
+
+	controllerUser := "system:serviceaccount:openshift-pipelines:manual-approval-gate-controller"
+	resp := admitUpdate(t, oldObj, newObj, controllerUser)
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	newObj.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdate(t, oldObj, newObj, controllerUser)
+	assert.False(t, resp.Allowed, "a task being deleted still cannot be decided by anyone")
+}
+
+func TestAdmitUnknownApproverType(t *testing.T) {
+	for _, approverType := range []string{"user ", "Grp", "group", "USER"} {
+		at := withdrawableApprovalTask()
+		at.Spec.Approvers[0].Type = approverType
+		resp := admitCreateWith(t, &reconciler{}, at)
+		assert.False(t, resp.Allowed, "type %q should be rejected on create", approverType)
+		assert.Contains(t, resp.Result.Message, fmt.Sprintf("approvers[0].type: must be one of 'User', 'Group' or 'Email', got '%s'", approverType))
+
+		oldObj := withdrawableApprovalTask()
+		newObj := at.DeepCopy()
+		newObj.Spec.Approvers[0].Input = "approve"
+		resp = admitUpdate(t, oldObj, newObj, "alice")
+		assert.False(t, resp.Allowed, "type %q should be rejected on update", approverType)
+	}
+
+	// An empty type still means User
+	at := withdrawableApprovalTask()
+	at.Spec.Approvers[0].Type = ""
+	resp := admitCreateWith(t, &reconciler{}, at)
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+}
+
+func orderedApprovalTask() *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "lead", Type: "User", Input: "pending", Priority: 1},
+				{Name: "alice", Type: "User", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func TestAdmitOutOfOrderApproval(t *testing.T) {
+	oldObj := orderedApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+
+	// By default the approval is accepted, the controller just does not count it yet
+	resp := admitUpdate(t, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed)
+
+	enforcing := &reconciler{privilegedGroup: DefaultPrivilegedGroup, enforceApprovalOrder: true}
+	resp = admitUpdateWith(t, enforcing, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Cannot approve yet: approver 'lead' with priority 1 must approve first", resp.Result.Message)
+
+	// Rejecting is never held back
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "reject"
+	resp = admitUpdateWith(t, enforcing, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed)
+
+	// Nor is approving after the lead
+	oldObj.Spec.Approvers[0].Input = "approve"
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+	resp = admitUpdateWith(t, enforcing, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed)
+}
+
+func TestAdmitApprovalOrderLapsedApproval(t *testing.T) {
+	respondedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	oldObj := orderedApprovalTask()
+	oldObj.Spec.Approvers[0].Input = "approve"
+	oldObj.Spec.Approvers[0].ApprovalExpiresAfter = &metav1.Duration{Duration: time.Hour}
+	oldObj.Status.ApproversResponse = []v1alpha1.ApproverState{{Name: "lead", Type: "User", Response: "approved", RespondedAt: &metav1.Time{Time: respondedAt}}}
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+
+	enforcing := &reconciler{privilegedGroup: DefaultPrivilegedGroup, enforceApprovalOrder: true, clock: clocktesting.NewFakePassiveClock(respondedAt.Add(30 * time.Minute))}
+	resp := admitUpdateWith(t, enforcing, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	// The approval of the lead lapsed by the time of the webhook clock
+	enforcing.clock = clocktesting.NewFakePassiveClock(respondedAt.Add(2 * time.Hour))
+	resp = admitUpdateWith(t, enforcing, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Cannot approve yet: approver 'lead' with priority 1 must approve first", resp.Result.Message)
+}
+
+func TestValidateApproverPriority(t *testing.T) {
+	err := validateApprover(v1alpha1.ApproverDetails{Name: "alice", Type: "User", Input: "pending", Priority: -1}, "approvers[0]", "")
+	assert.EqualError(t, err, "approvers[0].priority: must not be negative, got -1")
+}
+
+func finalApprovalTask() *v1alpha1.ApprovalTask {
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "deploy",
+			Namespace:   "production",
+			Annotations: map[string]string{v1alpha1.CreatedByAnnotationKey: "ci-bot"},
+		},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			Approvers:                 []v1alpha1.ApproverDetails{{Name: "alice", Type: "User", Input: "approve"}},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "approved"},
+	}
+}
+
+func TestAdmitMetadataOnlyUpdateOnFinalTask(t *testing.T) {
+	oldObj := finalApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Labels = map[string]string{"archived": "true"}
+	newObj.Annotations["example.com/report"] = "https://example.com/reports/1"
+
+	resp := admitUpdate(t, oldObj, newObj, "automation")
+	assert.False(t, resp.Allowed, "final tasks are frozen by default")
+
+	r := &reconciler{privilegedGroup: DefaultPrivilegedGroup, allowFinalMetadata: true}
+	resp = admitUpdateWith(t, r, oldObj, newObj, "automation")
+	assert.True(t, resp.Allowed, "metadata-only updates should be allowed")
+}
+
+func TestAdmitSpecAndStatusChangesOnFinalTask(t *testing.T) {
+	r := &reconciler{privilegedGroup: DefaultPrivilegedGroup, allowFinalMetadata: true}
+	oldObj := finalApprovalTask()
+
+	newObj := oldObj.DeepCopy()
+	newObj.Labels = map[string]string{"archived": "true"}
+	newObj.Spec.Approvers[0].Input = "reject"
+	resp := admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "ApprovalTask has already reached it's final state", resp.Result.Message)
+
+	newObj = oldObj.DeepCopy()
+	newObj.Status.State = "pending"
+	resp = admitUpdateWith(t, r, oldObj, newObj, "automation")
+	assert.False(t, resp.Allowed)
+
+	newObj = oldObj.DeepCopy()
+	newObj.Annotations[v1alpha1.CreatedByAnnotationKey] = "mallory"
+	resp = admitUpdateWith(t, r, oldObj, newObj, "mallory")
+	assert.False(t, resp.Allowed, "the creator annotation must stay immutable")
+}
+
+func admitCreateWith(t *testing.T, r *reconciler, obj *v1alpha1.ApprovalTask) *admissionv1.AdmissionResponse {
+	t.Helper()
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("failed marshaling object: %v", err)
+	}
+	return r.Admit(context.Background(), &admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Kind:      metav1.GroupVersionKind{Group: Group, Version: Version, Kind: Kind},
+		Object:    runtime.RawExtension{Raw: raw},
+	})
+}
+
+// sizedApprovalTask has a User approver and a Group approver listing members,
+// for a total of 2+members approvers.
+func sizedApprovalTask(members int) *v1alpha1.ApprovalTask {
+	group := v1alpha1.ApproverDetails{Name: "platform", Type: "Group", Input: "pending"}
+	for i := 0; i < members; i++ {
+		group.Users = append(group.Users, v1alpha1.UserDetails{Name: fmt.Sprintf("member-%d", i), Input: "pending"})
+	}
+	return &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			Approvers:                 []v1alpha1.ApproverDetails{{Name: "alice", Type: "User", Input: "pending"}, group},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+}
+
+func TestAdmitCreateMaxApprovers(t *testing.T) {
+	r := &reconciler{privilegedGroup: DefaultPrivilegedGroup, maxApprovers: 5}
+
+	resp := admitCreateWith(t, r, sizedApprovalTask(3))
+	assert.True(t, resp.Allowed, "a task at the limit should be allowed")
+
+	resp = admitCreateWith(t, r, sizedApprovalTask(4))
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "validation failed: approvers: 6 approvers and group members exceed the maximum of 5", resp.Result.Message)
+
+	resp = admitCreateWith(t, &reconciler{privilegedGroup: DefaultPrivilegedGroup}, sizedApprovalTask(100))
+	assert.True(t, resp.Allowed, "there is no limit by default")
+}
+
+func TestAdmitUpdateMaxApprovers(t *testing.T) {
+	r := &reconciler{privilegedGroup: DefaultPrivilegedGroup, maxApprovers: 5}
+
+	// A member deciding adds their entry to the group, past the limit
+	oldObj := sizedApprovalTask(3)
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+	newObj.Spec.Approvers[1].Users = append(newObj.Spec.Approvers[1].Users, v1alpha1.UserDetails{Name: "bob", Input: "approve"})
+	resp := admitUpdateWith(t, r, oldObj, newObj, "bob", "platform")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "validation failed: approvers: 6 approvers and group members exceed the maximum of 5", resp.Result.Message)
+
+	// Tasks already over the limit can still be decided
+	oldObj = sizedApprovalTask(4)
+	newObj = oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed)
+}
+
+func TestAdmitDecisionAttributionWarnings(t *testing.T) {
+	oldObj := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "platform", Type: "Group", Input: "pending"},
+				{Name: "sre", Type: "Group", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+
+	// alice approves through her own entry
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Input = "approve"
+	resp := admitUpdate(t, oldObj, newObj, "alice", "platform")
+	assert.True(t, resp.Allowed)
+	assert.Equal(t, []string{"counted as User approver alice"}, resp.Warnings)
+
+	// bob belongs to both groups and is recorded on both
+	newObj = oldObj.DeepCopy()
+	for _, i := range []int{1, 2} {
+		newObj.Spec.Approvers[i].Input = "approve"
+		newObj.Spec.Approvers[i].Users = []v1alpha1.UserDetails{{Name: "bob", Input: "approve"}}
+	}
+	resp = admitUpdate(t, oldObj, newObj, "bob", "platform", "sre")
+	assert.True(t, resp.Allowed)
+	assert.Equal(t, []string{"counted as member of group platform", "counted as member of group sre"}, resp.Warnings)
+}
+
+func TestAdmitDecisionAttributionHeldBackByPriority(t *testing.T) {
+	oldObj := orderedApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+
+	resp := admitUpdate(t, oldObj, newObj, "alice")
+	assert.True(t, resp.Allowed)
+	assert.Equal(t, []string{
+		"counted as User approver alice",
+		"approval through alice does not count until approver lead with priority 1 approves",
+	}, resp.Warnings)
+}
+
+func TestAdmitDecisionAttributionLapsedPriority(t *testing.T) {
+	respondedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	oldObj := orderedApprovalTask()
+	oldObj.Spec.Approvers[0].Input = "approve"
+	oldObj.Spec.Approvers[0].ApprovalExpiresAfter = &metav1.Duration{Duration: time.Hour}
+	oldObj.Status.ApproversResponse = []v1alpha1.ApproverState{{Name: "lead", Type: "User", Response: "approved", RespondedAt: &metav1.Time{Time: respondedAt}}}
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+
+	r := &reconciler{privilegedGroup: DefaultPrivilegedGroup, clock: clocktesting.NewFakePassiveClock(respondedAt.Add(30 * time.Minute))}
+	resp := admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.Equal(t, []string{"counted as User approver alice"}, resp.Warnings)
+
+	r.clock = clocktesting.NewFakePassiveClock(respondedAt.Add(2 * time.Hour))
+	resp = admitUpdateWith(t, r, oldObj, newObj, "alice")
+	assert.Equal(t, []string{
+		"counted as User approver alice",
+		"approval through alice does not count until approver lead with priority 1 approves",
+	}, resp.Warnings, "the approval of the lead lapsed by the time of the webhook clock")
+}
+
+func TestAdmitDecisionAttributionRequester(t *testing.T) {
+	oldObj := withdrawableApprovalTask()
+	oldObj.Spec.Approvers = append(oldObj.Spec.Approvers, v1alpha1.ApproverDetails{Name: "carol", Type: "User", Input: "pending"})
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[1].Input = "approve"
+
+	resp := admitUpdate(t, oldObj, newObj, "carol")
+	assert.True(t, resp.Allowed, "the requester may still record an approval")
+	assert.Equal(t, []string{
+		"counted as User approver carol",
+		"you requested this approval: your approval is recorded but does not count towards the quorum",
+	}, resp.Warnings)
+
+	newObj.Spec.Approvers[1].Input = "reject"
+	resp = admitUpdate(t, oldObj, newObj, "carol")
+	assert.True(t, resp.Allowed)
+	assert.Equal(t, []string{"counted as User approver carol"}, resp.Warnings)
+
+	counting := oldObj.DeepCopy()
+	counting.Spec.CountRequesterApproval = true
+	resp = admitUpdateWith(t, &reconciler{privilegedGroup: DefaultPrivilegedGroup}, oldObj, counting, "admin", DefaultPrivilegedGroup)
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Whether the requester's approval counts cannot be changed", resp.Result.Message)
+}
+
+func TestAdmitStatusSubresourceOnlyValidatesStructure(t *testing.T) {
+	oldObj := finalApprovalTask()
+	newObj := oldObj.DeepCopy()
+	newObj.Status.ApprovalsReceived = 1
+	raw, err := json.Marshal(newObj)
+	assert.NoError(t, err)
+	oldRaw, err := json.Marshal(oldObj)
+	assert.NoError(t, err)
+
+	request := &admissionv1.AdmissionRequest{
+		Operation:   admissionv1.Update,
+		SubResource: "status",
+		Kind:        metav1.GroupVersionKind{Group: Group, Version: Version, Kind: Kind},
+		UserInfo:    authenticationv1.UserInfo{Username: "system:serviceaccount:openshift-pipelines:manual-approval-gate-controller"},
+		Object:      runtime.RawExtension{Raw: raw},
+		OldObject:   runtime.RawExtension{Raw: oldRaw},
+	}
+	r := &reconciler{privilegedGroup: DefaultPrivilegedGroup}
+	assert.True(t, r.Admit(context.Background(), request).Allowed, "the controller must still be able to write status")
+
+	newObj.Spec.NumberOfApprovalsRequired = 0
+	request.Object.Raw, err = json.Marshal(newObj)
+	assert.NoError(t, err)
+	assert.False(t, r.Admit(context.Background(), request).Allowed, "malformed objects are still denied")
+}
+
+func TestValidateQuorumSchedule(t *testing.T) {
+	step := func(after time.Duration, required int) v1alpha1.QuorumStep {
+		return v1alpha1.QuorumStep{After: metav1.Duration{Duration: after}, NumberOfApprovalsRequired: required}
+	}
+	spec := func(steps ...v1alpha1.QuorumStep) *v1alpha1.ApprovalTaskSpec {
+		return &v1alpha1.ApprovalTaskSpec{NumberOfApprovalsRequired: 3, QuorumSchedule: steps}
+	}
+
+	assert.NoError(t, validateQuorumSchedule(spec(step(time.Hour, 2), step(4*time.Hour, 1))))
+	assert.EqualError(t, validateQuorumSchedule(spec(step(0, 2))),
+		"quorumSchedule[0].after: must be later than 0s, got 0s")
+	assert.EqualError(t, validateQuorumSchedule(spec(step(time.Hour, 2), step(time.Hour, 1))),
+		"quorumSchedule[1].after: must be later than 1h0m0s, got 1h0m0s")
+	assert.EqualError(t, validateQuorumSchedule(spec(step(time.Hour, 3))),
+		"quorumSchedule[0].numberOfApprovalsRequired: must be between 1 and 2, got 3")
+	assert.EqualError(t, validateQuorumSchedule(spec(step(time.Hour, 2), step(2*time.Hour, 0))),
+		"quorumSchedule[1].numberOfApprovalsRequired: must be between 1 and 1, got 0")
+}
+
+func TestAdmitQuorumScheduleChange(t *testing.T) {
+	oldObj := withdrawableApprovalTask()
+	oldObj.Spec.NumberOfApprovalsRequired = 2
+	oldObj.Spec.Approvers = append(oldObj.Spec.Approvers, v1alpha1.ApproverDetails{Name: "bob", Type: "User", Input: "pending"})
+
+	added := oldObj.DeepCopy()
+	added.Spec.QuorumSchedule = []v1alpha1.QuorumStep{{After: metav1.Duration{Duration: time.Nanosecond}, NumberOfApprovalsRequired: 1}}
+	added.Spec.Approvers[0].Input = "approve"
+	resp := admitUpdate(t, oldObj, added, "alice")
+	assert.False(t, resp.Allowed, "adding a step along with an approval would lower the quorum to the approver")
+	assert.Equal(t, "The quorum schedule of an ApprovalTask cannot be changed", resp.Result.Message)
+
+	oldObj.Spec.QuorumSchedule = []v1alpha1.QuorumStep{{After: metav1.Duration{Duration: 4 * time.Hour}, NumberOfApprovalsRequired: 1}}
+	edited := oldObj.DeepCopy()
+	edited.Spec.QuorumSchedule[0].After = metav1.Duration{Duration: time.Nanosecond}
+	edited.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdate(t, oldObj, edited, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "The quorum schedule of an ApprovalTask cannot be changed", resp.Result.Message)
+
+	approved := oldObj.DeepCopy()
+	approved.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdate(t, oldObj, approved, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+}
+
+func TestAdmitMaxApprovalsPerGroupChange(t *testing.T) {
+	oldObj := withdrawableApprovalTask()
+	oldObj.Spec.NumberOfApprovalsRequired = 2
+	oldObj.Spec.MaxApprovalsPerGroup = 1
+	oldObj.Spec.Approvers = []v1alpha1.ApproverDetails{
+		{Name: "platform", Type: "Group", Input: "approve", Users: []v1alpha1.UserDetails{{Name: "bob", Input: "approve"}}},
+	}
+	userInfo := authenticationv1.UserInfo{Username: "carol", Groups: []string{"platform"}}
+
+	for _, limit := range []int{2, 0} {
+		newObj := oldObj.DeepCopy()
+		newObj.Spec.MaxApprovalsPerGroup = limit
+		newObj.Spec.Approvers[0].Users = append(newObj.Spec.Approvers[0].Users, v1alpha1.UserDetails{Name: "carol", Input: "approve"})
+		resp := admitUpdateAs(t, &reconciler{}, oldObj, newObj, userInfo)
+		assert.False(t, resp.Allowed, "lifting the cap to %d along with an approval would let the group reach quorum alone", limit)
+		assert.Equal(t, "The maximum approvals per group of an ApprovalTask cannot be changed", resp.Result.Message)
+	}
+
+	newObj := oldObj.DeepCopy()
+	newObj.Spec.Approvers[0].Users = append(newObj.Spec.Approvers[0].Users, v1alpha1.UserDetails{Name: "carol", Input: "approve"})
+	resp := admitUpdateAs(t, &reconciler{}, oldObj, newObj, userInfo)
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+}
+
+func TestAdmitExplicitGroupMembership(t *testing.T) {
+	oldObj := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 2,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "platform", Type: "Group", Input: "pending", Users: []v1alpha1.UserDetails{{Name: "bob", Input: "pending"}}},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+	tokenGroups := &reconciler{}
+	explicit := &reconciler{explicitGroupMembers: true}
+
+	// carol's token claims the group, but carol is not listed in its users
+	selfAdded := oldObj.DeepCopy()
+	selfAdded.Spec.Approvers[0].Input = "approve"
+	selfAdded.Spec.Approvers[0].Users = append(selfAdded.Spec.Approvers[0].Users, v1alpha1.UserDetails{Name: "carol", Input: "approve"})
+	resp := admitUpdateWith(t, tokenGroups, oldObj, selfAdded, "carol", "platform")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	resp = admitUpdateWith(t, explicit, oldObj, selfAdded, "carol", "platform")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "User does not exist in the approval list", resp.Result.Message)
+
+	// bob is listed, so bob decides in both modes, with or without the group in the token
+	listed := oldObj.DeepCopy()
+	listed.Spec.Approvers[0].Input = "approve"
+	listed.Spec.Approvers[0].Users[0].Input = "approve"
+	for _, r := range []*reconciler{tokenGroups, explicit} {
+		resp = admitUpdateWith(t, r, oldObj, listed, "bob")
+		assert.True(t, resp.Allowed, "%v", resp.Result)
+		resp = admitUpdateWith(t, r, oldObj, listed, "bob", "platform")
+		assert.True(t, resp.Allowed, "%v", resp.Result)
+	}
+
+	// A listed member's token groups do not let them decide for other groups
+	withSRE := oldObj.DeepCopy()
+	withSRE.Spec.Approvers = append(withSRE.Spec.Approvers, v1alpha1.ApproverDetails{Name: "sre", Type: "Group", Input: "pending"})
+	sreApproved := withSRE.DeepCopy()
+	sreApproved.Spec.Approvers[1].Input = "approve"
+	sreApproved.Spec.Approvers[1].Users = []v1alpha1.UserDetails{{Name: "bob", Input: "approve"}}
+	resp = admitUpdateWith(t, tokenGroups, withSRE, sreApproved, "bob", "sre")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	resp = admitUpdateWith(t, explicit, withSRE, sreApproved, "bob", "sre")
+	assert.False(t, resp.Allowed)
+}
+
+func TestIsUserApprovalChangedExplicitGroupMembership(t *testing.T) {
+	oldApprovers := []v1alpha1.ApproverDetails{{Name: "platform", Type: "Group", Input: "pending"}}
+	newApprovers := []v1alpha1.ApproverDetails{{Name: "platform", Type: "Group", Input: "approve"}}
+	request := admissionRequestFor("carol", "platform")
+
+	changed, err := IsUserApprovalChanged(oldApprovers, newApprovers, request)
+	assert.NoError(t, err)
+	assert.True(t, changed, "the token makes carol a member of the group")
+
+	changed, err = IsUserApprovalChanged(oldApprovers, newApprovers, (&reconciler{explicitGroupMembers: true}).approverRequest(request))
+	assert.NoError(t, err)
+	assert.False(t, changed, "carol is not listed in the users of the group")
+	assert.Equal(t, []string{"platform"}, request.UserInfo.Groups, "the original request is left alone")
+}
+
+func TestAdmitGroupSelfAdd(t *testing.T) {
+	oldObj := groupApprovalTask("production")
+	oldObj.Spec.Approvers[1].Users = []v1alpha1.UserDetails{{Name: "bob", Input: "pending"}}
+	selfAdded := oldObj.DeepCopy()
+	selfAdded.Spec.Approvers[1].Input = "approve"
+	selfAdded.Spec.Approvers[1].Users = append(selfAdded.Spec.Approvers[1].Users, v1alpha1.UserDetails{Name: "carol", Input: "approve"})
+
+	resp := admitUpdate(t, oldObj, selfAdded, "carol", "platform")
+	assert.True(t, resp.Allowed, "token group members add themselves by default: %v", resp.Result)
+
+	forbidden := &reconciler{forbidGroupSelfAdd: true}
+	resp = admitUpdateWith(t, forbidden, oldObj, selfAdded, "carol", "platform")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "Invalid input change: users cannot add themselves to group 'platform', its users are managed by administrators", resp.Result.Message)
+
+	// Listed users still decide, and token members may set the group-level input
+	listed := oldObj.DeepCopy()
+	listed.Spec.Approvers[1].Input = "approve"
+	listed.Spec.Approvers[1].Users[0].Input = "approve"
+	resp = admitUpdateWith(t, forbidden, oldObj, listed, "bob")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	groupInput := oldObj.DeepCopy()
+	groupInput.Spec.Approvers[1].Input = "approve"
+	resp = admitUpdateWith(t, forbidden, oldObj, groupInput, "carol", "platform")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+
+	eligibility := forbidden.eligibility(context.Background(), oldObj, authenticationv1.UserInfo{Username: "carol", Groups: []string{"platform"}})
+	assert.False(t, eligibility.CanApprove, "the approval of an unlisted member could not be recorded")
+	assert.True(t, forbidden.eligibility(context.Background(), oldObj, authenticationv1.UserInfo{Username: "bob"}).CanApprove)
+}
+
+func TestForeignChangeGroupSelfAdd(t *testing.T) {
+	oldApprovers := []v1alpha1.ApproverDetails{{Name: "platform", Type: "Group", Input: "pending"}}
+	newApprovers := []v1alpha1.ApproverDetails{{Name: "platform", Type: "Group", Input: "approve", Users: []v1alpha1.UserDetails{{Name: "carol", Input: "approve"}}}}
+	request := admissionRequestFor("carol", "platform")
+
+	assert.True(t, CheckOtherUsersForInvalidChanges(oldApprovers, newApprovers, request))
+	assert.Equal(t, "approvers[0].users", foreignChange(oldApprovers, newApprovers, request, false))
+}
+
+func TestAdmitRequestChangesThenApprove(t *testing.T) {
+	pending := &v1alpha1.ApprovalTask{
+		ObjectMeta: metav1.ObjectMeta{Name: "deploy", Namespace: "production"},
+		Spec: v1alpha1.ApprovalTaskSpec{
+			NumberOfApprovalsRequired: 1,
+			Approvers: []v1alpha1.ApproverDetails{
+				{Name: "alice", Type: "User", Input: "pending"},
+				{Name: "platform", Type: "Group", Input: "pending"},
+			},
+		},
+		Status: v1alpha1.ApprovalTaskStatus{State: "pending"},
+	}
+
+	changesRequested := pending.DeepCopy()
+	changesRequested.Spec.Approvers[0].Input = "request-changes"
+	changesRequested.Spec.Approvers[0].Message = "pin the image digest"
+	resp := admitUpdate(t, pending, changesRequested, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	assert.True(t, isApprovalRequired(*changesRequested))
+
+	// The controller records the request without finalizing the task
+	changesRequested.Status.ApproversResponse = []v1alpha1.ApproverState{{Name: "alice", Type: "User", Response: "changes-requested"}}
+
+	// A group member approving does not override alice's request
+	memberApproved := changesRequested.DeepCopy()
+	memberApproved.Spec.Approvers[1].Input = "approve"
+	memberApproved.Spec.Approvers[1].Users = []v1alpha1.UserDetails{{Name: "bob", Input: "approve"}}
+	resp = admitUpdate(t, changesRequested, memberApproved, "bob", "platform")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	assert.True(t, isApprovalRequired(*memberApproved), "alice still requests changes")
+
+	approved := memberApproved.DeepCopy()
+	approved.Spec.Approvers[0].Input = "approve"
+	resp = admitUpdate(t, memberApproved, approved, "alice")
+	assert.True(t, resp.Allowed, "%v", resp.Result)
+	assert.False(t, isApprovalRequired(*approved))
+
+	// Requesting changes is open to approvers only, under its exact input
+	resp = admitUpdate(t, pending, changesRequested, "mallory")
+	assert.False(t, resp.Allowed)
+	invalid := pending.DeepCopy()
+	invalid.Spec.Approvers[0].Input = "changes"
+	resp = admitUpdate(t, pending, invalid, "alice")
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "validation failed: spec validation failed: approvers[0].input: must be one of: pending, approve, reject, request-changes, got 'changes'", resp.Result.Message)
+}